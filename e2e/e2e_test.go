@@ -0,0 +1,185 @@
+//go:build e2e
+
+// Package e2e runs the app, store and HTTP API together against the fake
+// WhatsApp client. Run with: go test -tags e2e ./e2e/...
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/api"
+	"github.com/vicentereig/whatsapp-cli/internal/client/fakeclient"
+	"github.com/vicentereig/whatsapp-cli/internal/commands"
+	"go.mau.fi/whatsmeow/types"
+)
+
+const apiKey = "e2e-key"
+
+type harness struct {
+	fake *fakeclient.Client
+	srv  *api.Server
+	http *httptest.Server
+}
+
+func newHarness(t *testing.T, fake *fakeclient.Client) *harness {
+	t.Helper()
+
+	app, err := commands.NewAppWithClient(t.TempDir(), "e2e", fake)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := api.NewServer(api.Config{APIKey: apiKey, MaxMessages: 100}, app)
+	if app.IsAuthenticated() {
+		srv.SetAuthenticated(true)
+	} else {
+		srv.StartQRAuth(ctx, app)
+	}
+	srv.StartBackgroundSync(ctx)
+
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		cancel()
+		ts.Close()
+		app.Close()
+	})
+	return &harness{fake: fake, srv: srv, http: ts}
+}
+
+func (h *harness) do(t *testing.T, method, path, body string) (int, []byte) {
+	t.Helper()
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, h.http.URL+path, r)
+	require.NoError(t, err)
+	req.Header.Set("X-API-Key", apiKey)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, data
+}
+
+func (h *harness) data(t *testing.T, path string) any {
+	t.Helper()
+	code, body := h.do(t, http.MethodGet, path, "")
+	require.Equal(t, http.StatusOK, code, string(body))
+	var env struct {
+		Success bool   `json:"success"`
+		Data    any    `json:"data"`
+		Error   string `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(body, &env))
+	require.True(t, env.Success, env.Error)
+	return env.Data
+}
+
+func (h *harness) waitReady(t *testing.T) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		code, _ := h.do(t, http.MethodGet, "/readyz", "")
+		return code == http.StatusOK
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestPairingFlow(t *testing.T) {
+	h := newHarness(t, fakeclient.New())
+
+	code, _ := h.do(t, http.MethodGet, "/readyz", "")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	require.Eventually(t, func() bool {
+		return h.srv.GetCurrentQR() != ""
+	}, 5*time.Second, 20*time.Millisecond)
+
+	code, body := h.do(t, http.MethodGet, "/api/v1/auth/qr/image", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "\x89PNG", string(body[:4]))
+
+	h.fake.Pair()
+	h.waitReady(t)
+
+	status := h.data(t, "/api/v1/auth/status").(map[string]any)
+	assert.Equal(t, true, status["authenticated"])
+	assert.Equal(t, true, status["connected"])
+	assert.Empty(t, h.srv.GetCurrentQR())
+}
+
+func TestIncomingMessagesAreStoredAndServed(t *testing.T) {
+	h := newHarness(t, fakeclient.NewPaired())
+	h.waitReady(t)
+
+	alice := types.NewJID("4915112345678", types.DefaultUserServer)
+	h.fake.SetChatName(alice.String(), "Alice")
+	h.fake.EmitText(alice, alice, "MSG1", "hello from alice", time.Now())
+	h.fake.EmitText(alice, alice, "MSG2", "lunch tomorrow?", time.Now())
+
+	messages := h.data(t, "/api/v1/messages?chat_jid="+alice.String()).([]any)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "lunch tomorrow?", messages[0].(map[string]any)["content"])
+	assert.Equal(t, "Alice", messages[0].(map[string]any)["chat_name"])
+
+	found := h.data(t, "/api/v1/messages/search?query=hello").([]any)
+	require.Len(t, found, 1)
+	assert.Equal(t, "MSG1", found[0].(map[string]any)["id"])
+
+	chats := h.data(t, "/api/v1/chats").([]any)
+	require.Len(t, chats, 1)
+	assert.Equal(t, "Alice", chats[0].(map[string]any)["name"])
+
+	contacts := h.data(t, "/api/v1/contacts?query=ali").([]any)
+	require.Len(t, contacts, 1)
+	assert.Equal(t, "4915112345678", contacts[0].(map[string]any)["phone_number"])
+
+	syncStatus := h.data(t, "/api/v1/sync/status").(map[string]any)
+	assert.Equal(t, float64(2), syncStatus["messages_synced"])
+}
+
+func TestSendMessageReachesClientAndStore(t *testing.T) {
+	h := newHarness(t, fakeclient.NewPaired())
+	h.waitReady(t)
+
+	code, body := h.do(t, http.MethodPost, "/api/v1/messages/send", `{"to":"4915187654321","message":"hi bob"}`)
+	require.Equal(t, http.StatusOK, code, string(body))
+
+	sent := h.fake.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "4915187654321@s.whatsapp.net", sent[0].Recipient)
+	assert.Equal(t, "hi bob", sent[0].Message)
+
+	messages := h.data(t, "/api/v1/messages?chat_jid=4915187654321@s.whatsapp.net").([]any)
+	require.Len(t, messages, 1)
+	assert.Equal(t, true, messages[0].(map[string]any)["is_from_me"])
+}
+
+func TestSentMessageCanBeRevoked(t *testing.T) {
+	h := newHarness(t, fakeclient.NewPaired())
+	h.waitReady(t)
+
+	code, body := h.do(t, http.MethodPost, "/api/v1/messages/send", `{"to":"4915187654321","message":"wrong chat"}`)
+	require.Equal(t, http.StatusOK, code, string(body))
+	var sent struct {
+		Data struct {
+			MessageID string `json:"message_id"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &sent))
+	require.Len(t, h.fake.Sent(), 1)
+	require.Equal(t, h.fake.Sent()[0].ID, sent.Data.MessageID)
+
+	code, body = h.do(t, http.MethodDelete, "/api/v1/messages/"+sent.Data.MessageID, "")
+	require.Equal(t, http.StatusOK, code, string(body))
+	assert.Contains(t, string(body), `"revoked":true`)
+	assert.Equal(t, []fakeclient.Revoke{{ChatJID: "4915187654321@s.whatsapp.net", MessageID: sent.Data.MessageID}}, h.fake.Revokes())
+}
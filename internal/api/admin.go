@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (s *Server) handleAdminQuery(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	var req struct {
+		SQL   string `json:"sql"`
+		Limit int    `json:"limit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if strings.TrimSpace(req.SQL) == "" {
+		writeError(w, http.StatusBadRequest, "'sql' field is required")
+		return
+	}
+	writeResult(w, s.app.AdminQuery(r.Context(), req.SQL, req.Limit))
+}
+
+func (s *Server) handleAdminSchema(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	writeResult(w, s.app.AdminSchema())
+}
+
+// handleDBSnapshot streams a consistent copy of messages.db. The snapshot is
+// taken into a temporary file first so the live database is never read
+// mid-transaction, and removed once sent.
+func (s *Server) handleDBSnapshot(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	path, err := s.app.DatabaseSnapshot(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	streamDatabase(w, path, "messages")
+}
+
+// handleDBAnonymize streams a snapshot with phone numbers and names replaced
+// by pseudonyms, safe to share for debugging or analysis.
+func (s *Server) handleDBAnonymize(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	var req struct {
+		Salt        string `json:"salt"`
+		DropContent bool   `json:"drop_content"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+	}
+	path, err := s.app.AnonymizedSnapshot(r.Context(), req.Salt, req.DropContent)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	streamDatabase(w, path, "messages-anonymized")
+}
+
+// streamDatabase sends the database file at path as a download and removes it.
+func streamDatabase(w http.ResponseWriter, path, prefix string) {
+	defer os.Remove(path)
+
+	f, err := os.Open(path)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer f.Close()
+
+	filename := fmt.Sprintf("%s-%s.db", prefix, time.Now().UTC().Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if info, err := f.Stat(); err == nil {
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, f)
+}
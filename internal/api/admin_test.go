@@ -0,0 +1,91 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleAdminQuery(t *testing.T) {
+	mock := &mockApp{adminResult: `{"success":true,"data":{"columns":["n"],"rows":[[3]],"truncated":false}}`}
+	srv := newUsageTestServer(mock)
+
+	w := doRequest(srv, http.MethodPost, "/api/v1/admin/query", "admin-key", `{"sql":"SELECT COUNT(*) AS n FROM messages","limit":5}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, mock.adminResult, w.Body.String())
+	assert.Equal(t, "SELECT COUNT(*) AS n FROM messages", mock.lastAdminQuery)
+	assert.Equal(t, 5, mock.lastAdminLimit)
+}
+
+func TestHandleAdminQuery_Validation(t *testing.T) {
+	mock := &mockApp{}
+	srv := newUsageTestServer(mock)
+
+	assert.Equal(t, http.StatusForbidden, doRequest(srv, http.MethodPost, "/api/v1/admin/query", "crm-key", `{"sql":"SELECT 1"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, doRequest(srv, http.MethodPost, "/api/v1/admin/query", "admin-key", `{`).Code)
+	assert.Equal(t, http.StatusBadRequest, doRequest(srv, http.MethodPost, "/api/v1/admin/query", "admin-key", `{"sql":"  "}`).Code)
+	assert.Empty(t, mock.lastAdminQuery)
+}
+
+func TestHandleAdminSchema(t *testing.T) {
+	mock := &mockApp{adminResult: `{"success":true,"data":[]}`}
+	srv := newUsageTestServer(mock)
+
+	assert.Equal(t, http.StatusForbidden, doRequest(srv, http.MethodGet, "/api/v1/admin/schema", "crm-key", "").Code)
+	w := doRequest(srv, http.MethodGet, "/api/v1/admin/schema", "admin-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, mock.adminResult, w.Body.String())
+}
+
+func TestHandleDBSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot-1.db")
+	require.NoError(t, os.WriteFile(path, []byte("SQLite format 3\x00"), 0644))
+	srv := newUsageTestServer(&mockApp{snapshotPath: path})
+
+	assert.Equal(t, http.StatusForbidden, doRequest(srv, http.MethodGet, "/api/v1/admin/db/snapshot", "crm-key", "").Code)
+	_, err := os.Stat(path)
+	require.NoError(t, err, "forbidden requests must not take a snapshot")
+
+	w := doRequest(srv, http.MethodGet, "/api/v2/admin/db/snapshot", "admin-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/vnd.sqlite3", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment; filename=\"messages-")
+	assert.Equal(t, "SQLite format 3\x00", w.Body.String())
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "snapshot file is removed after streaming")
+}
+
+func TestHandleDBSnapshot_Error(t *testing.T) {
+	srv := newUsageTestServer(&mockApp{snapshotErr: errors.New("disk full")})
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/admin/db/snapshot", "admin-key", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "disk full")
+}
+
+func TestHandleDBAnonymize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anonymized-1.db")
+	require.NoError(t, os.WriteFile(path, []byte("SQLite format 3\x00"), 0644))
+	mock := &mockApp{snapshotPath: path}
+	srv := newUsageTestServer(mock)
+
+	assert.Equal(t, http.StatusForbidden, doRequest(srv, http.MethodPost, "/api/v1/admin/db/anonymize", "crm-key", "").Code)
+
+	w := doRequest(srv, http.MethodPost, "/api/v1/admin/db/anonymize", "admin-key", `{"salt":"s3cret","drop_content":true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment; filename=\"messages-anonymized-")
+	assert.Equal(t, "SQLite format 3\x00", w.Body.String())
+	assert.Equal(t, "s3cret", mock.anonymizeSalt)
+	assert.True(t, mock.anonymizeDropContent)
+
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "anonymized file is removed after streaming")
+
+	assert.Equal(t, http.StatusBadRequest, doRequest(srv, http.MethodPost, "/api/v1/admin/db/anonymize", "admin-key", `{`).Code)
+}
@@ -0,0 +1,11 @@
+package api
+
+import "net/http"
+
+// handleListAccountAlerts lists the latest account alerts, resolved or not.
+func (s *Server) handleListAccountAlerts(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	writeResult(w, s.app.ListAccountAlerts(parseIntParam(r, "limit", 50)))
+}
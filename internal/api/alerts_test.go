@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/canary"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+func TestReadyz_NotReady_AccountAlert(t *testing.T) {
+	mock := &mockApp{accountAlerts: []store.AccountAlert{
+		{Kind: "temporary_ban", Severity: store.AlertCritical, Reason: "You've been temporarily banned", CreatedAt: time.Now()},
+	}}
+	srv := newTestServer(mock)
+	srv.SetAuthenticated(true)
+	srv.SetSyncing(true)
+
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "account temporary ban", body["reason"])
+	assert.Equal(t, "You've been temporarily banned", body["alert"])
+
+	// Warnings do not take the daemon out of rotation
+	mock.accountAlerts = []store.AccountAlert{{Kind: "rate_limited", Severity: store.AlertWarning}}
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, w.Body.String(), `whatsapp_account_alerts{severity="warning"} 1`)
+}
+
+func TestHandleListAccountAlerts(t *testing.T) {
+	mock := &mockApp{alertsResult: `{"success":true,"data":[],"error":null}`}
+	srv := NewServer(Config{APIKey: "test-key", APIKeys: map[string]string{"crm": "crm-key"}}, mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/admin/alerts?limit=5", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, mock.alertsResult, w.Body.String())
+	assert.Equal(t, 5, mock.lastLimit)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/admin/alerts", "crm-key", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestReadyz_NotReady_CanaryFailed(t *testing.T) {
+	mock := &mockApp{canaryStatus: &canary.Status{OK: false, LastError: "canary was not acknowledged within 30s", Checks: 3, Failures: 1}}
+	srv := newTestServer(mock)
+	srv.SetAuthenticated(true)
+	srv.SetSyncing(true)
+
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "canary failed", body["reason"])
+	assert.Equal(t, "canary was not acknowledged within 30s", body["error"])
+
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, w.Body.String(), "whatsapp_canary_ok 0\n")
+	assert.Contains(t, w.Body.String(), "whatsapp_canary_failures_total 1\n")
+
+	mock.canaryStatus = &canary.Status{OK: true, LatencySeconds: 1.5}
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
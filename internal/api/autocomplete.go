@@ -0,0 +1,22 @@
+package api
+
+import "net/http"
+
+// maxAutocompleteLimit caps the suggestions of one autocomplete request.
+const maxAutocompleteLimit = 50
+
+// handleAutocomplete suggests contacts and group chats for the prefix in q,
+// for send-to pickers.
+func (s *Server) handleAutocomplete(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		writeError(w, http.StatusBadRequest, "q parameter required")
+		return
+	}
+	limit := parseIntParam(r, "limit", 10)
+	if limit > maxAutocompleteLimit {
+		limit = maxAutocompleteLimit
+	}
+	includeJIDs, excludeJIDs := s.phoneFilter.JIDSuffixes()
+	writeResult(w, s.app.Autocomplete(q, limit, includeJIDs, excludeJIDs))
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleAutocomplete(t *testing.T) {
+	mock := &mockApp{autocompleteResult: `{"success":true,"data":[{"jid":"34600111222@s.whatsapp.net","name":"John","type":"contact","phone":"34600111222"}]}`}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/autocomplete", "test-key", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/autocomplete?q=jo", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"John"`)
+	assert.Equal(t, "jo", mock.lastPrefix)
+	assert.Equal(t, 10, mock.lastLimit)
+	assert.Equal(t, "private, max-age=5", w.Header().Get("Cache-Control"))
+
+	doRequest(srv, http.MethodGet, "/api/v1/autocomplete?q=jo&limit=500", "test-key", "")
+	assert.Equal(t, maxAutocompleteLimit, mock.lastLimit)
+}
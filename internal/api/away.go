@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+func (s *Server) handleGetAway(w http.ResponseWriter, r *http.Request) {
+	writeResult(w, s.app.GetAwayConfig())
+}
+
+func (s *Server) handleSetAway(w http.ResponseWriter, r *http.Request) {
+	var cfg store.AwayConfig
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	writeResult(w, s.app.SetAwayConfig(cfg))
+}
+
+func (s *Server) handleListAwayOptOuts(w http.ResponseWriter, r *http.Request) {
+	writeResult(w, s.app.ListAwayOptOuts())
+}
+
+func (s *Server) handleAwayOptOut(w http.ResponseWriter, r *http.Request) {
+	s.setAwayOptOut(w, r, true)
+}
+
+func (s *Server) handleAwayOptIn(w http.ResponseWriter, r *http.Request) {
+	s.setAwayOptOut(w, r, false)
+}
+
+func (s *Server) setAwayOptOut(w http.ResponseWriter, r *http.Request, optOut bool) {
+	jid, ok := chatJIDPath(w, r)
+	if !ok {
+		return
+	}
+	writeResult(w, s.app.SetAwayOptOut(jid, optOut))
+}
+
+// chatJIDPath reads the {jid} path value, treating a bare phone number as an
+// individual chat. It writes a 400 and returns false if the value is empty.
+func chatJIDPath(w http.ResponseWriter, r *http.Request) (string, bool) {
+	chatJID := jid.Normalize(r.PathValue("jid"))
+	if chatJID == "" {
+		writeError(w, http.StatusBadRequest, "chat JID required")
+		return "", false
+	}
+	if !strings.Contains(chatJID, "@") {
+		chatJID += "@s.whatsapp.net"
+	}
+	return chatJID, true
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleSetAway(t *testing.T) {
+	mock := &mockApp{awayResult: `{"success":true}`}
+	srv := newTestServer(mock)
+
+	body := `{"enabled":true,"message":"Back at 9","timezone":"Europe/Berlin","windows":[{"days":["mon","fri"],"start":"18:00","end":"09:00"}]}`
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/away", strings.NewReader(body))
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, mock.lastAwayConfig)
+	assert.True(t, mock.lastAwayConfig.Enabled)
+	assert.Equal(t, "Europe/Berlin", mock.lastAwayConfig.Timezone)
+	require.Len(t, mock.lastAwayConfig.Windows, 1)
+	assert.Equal(t, []string{"mon", "fri"}, mock.lastAwayConfig.Windows[0].Days)
+}
+
+func TestHandleSetAway_InvalidJSON(t *testing.T) {
+	mock := &mockApp{}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/away", strings.NewReader(`{`))
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Nil(t, mock.lastAwayConfig)
+}
+
+func TestHandleAwayOptOut(t *testing.T) {
+	mock := &mockApp{awayResult: `{"success":true}`}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/away/optouts/1234567890", nil)
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1234567890@s.whatsapp.net", mock.lastOptOutJID)
+	assert.True(t, mock.lastOptOut)
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/away/optouts/1234567890@s.whatsapp.net", nil)
+	req.Header.Set("X-API-Key", "test-key")
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, mock.lastOptOut)
+}
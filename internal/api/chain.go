@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// maxChainExportBody bounds an export posted to POST /chain/verify.
+const maxChainExportBody = 256 << 20
+
+// handleExportChain returns the hash chain of a chat with the messages it
+// covers, for archiving next to the conversation.
+func (s *Server) handleExportChain(w http.ResponseWriter, r *http.Request) {
+	chatJID, ok := chatJIDPath(w, r)
+	if !ok {
+		return
+	}
+	if !s.phoneFilter.IsAllowed(chatJID) {
+		writeError(w, http.StatusForbidden, "chat not allowed")
+		return
+	}
+	writeResult(w, s.app.ExportChain(chatJID))
+}
+
+// handleVerifyChain checks the hash chain of a chat against the messages
+// stored now.
+func (s *Server) handleVerifyChain(w http.ResponseWriter, r *http.Request) {
+	chatJID, ok := chatJIDPath(w, r)
+	if !ok {
+		return
+	}
+	if !s.phoneFilter.IsAllowed(chatJID) {
+		writeError(w, http.StatusForbidden, "chat not allowed")
+		return
+	}
+	writeResult(w, s.app.VerifyChain(chatJID))
+}
+
+// handleVerifyChainExport checks a chain exported earlier, as returned by
+// GET /chats/{jid}/chain (the "data" object), against the store.
+func (s *Server) handleVerifyChainExport(w http.ResponseWriter, r *http.Request) {
+	var e store.ChainExport
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxChainExportBody)).Decode(&e); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if e.ChatJID == "" {
+		writeError(w, http.StatusBadRequest, "'chat_jid' is required")
+		return
+	}
+	e.ChatJID = jid.Normalize(e.ChatJID)
+	if !s.phoneFilter.IsAllowed(e.ChatJID) {
+		writeError(w, http.StatusForbidden, "chat not allowed")
+		return
+	}
+	writeResult(w, s.app.VerifyChainExport(e))
+}
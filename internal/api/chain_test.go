@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleChain(t *testing.T) {
+	mock := &mockApp{}
+	srv := NewServer(Config{APIKey: "test-key", PhoneBlacklist: []string{"222"}}, mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/chats/34600111/chain", "test-key", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "34600111@s.whatsapp.net", *mock.lastChatJID)
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats/34600222/chain/verify", "test-key", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "34600222@s.whatsapp.net", *mock.lastChatJID)
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats/222/chain", "test-key", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = doRequest(srv, http.MethodPost, "/api/v1/chain/verify", "test-key",
+		`{"chat_jid":"34600111","algorithm":"sha256-chain-v1","length":1,"head":"ab","links":[{"seq":1,"message_id":"M1","content":"hi","hash":"ab"}]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NotNil(t, mock.lastChainExport)
+	assert.Equal(t, "34600111@s.whatsapp.net", mock.lastChainExport.ChatJID)
+	require.Len(t, mock.lastChainExport.Links, 1)
+	assert.Equal(t, "hi", mock.lastChainExport.Links[0].Content)
+
+	w = doRequest(srv, http.MethodPost, "/api/v1/chain/verify", "test-key", `{"links":[]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(srv, http.MethodPost, "/api/v1/chain/verify", "test-key", `{"chat_jid":"222"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	replica := NewServer(Config{APIKey: "test-key", Replica: true}, mock)
+	w = doRequest(replica, http.MethodPost, "/api/v1/chain/verify", "test-key", `{"chat_jid":"34600111"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
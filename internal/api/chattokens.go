@@ -0,0 +1,279 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+)
+
+// Chat tokens give a third party access to a single chat. They are signed
+// with a key derived from API_KEY rather than stored, so they survive
+// restarts; changing API_KEY revokes all of them.
+const (
+	chatTokenPrefix  = "wct_"
+	defaultTokenTTL  = 24 * time.Hour
+	maxTokenTTL      = 90 * 24 * time.Hour
+	chatTokenKeyInfo = "whatsapp-cli chat token v1"
+)
+
+// chatTokenKeyPrefix marks the key IDs of chat tokens, e.g. "token:1a2b3c".
+const chatTokenKeyPrefix = "token:"
+
+// maxScopedSendBody bounds the send request body read to check its recipient.
+const maxScopedSendBody = 1 << 20
+
+// chatToken is the signed payload of a chat token.
+type chatToken struct {
+	ID        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	ChatJID   string `json:"chat"`
+	ExpiresAt int64  `json:"exp"`
+	// NoMedia withholds the media scope; tokens minted before it existed
+	// keep it.
+	NoMedia bool `json:"nomedia,omitempty"`
+}
+
+type chatScopeContextKey struct{}
+
+// chatTokenSecret derives the token signing key from the admin key.
+func (s *Server) chatTokenSecret() []byte {
+	mac := hmac.New(sha256.New, []byte(s.Config.APIKey))
+	mac.Write([]byte(chatTokenKeyInfo))
+	return mac.Sum(nil)
+}
+
+// signChatToken encodes t as "wct_<payload>.<signature>".
+func (s *Server) signChatToken(t chatToken) string {
+	payload, _ := json.Marshal(t)
+	enc := base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, s.chatTokenSecret())
+	mac.Write([]byte(enc))
+	return chatTokenPrefix + enc + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyChatToken returns the payload of a valid, unexpired chat token.
+func (s *Server) verifyChatToken(token string, now time.Time) (chatToken, bool) {
+	if s.Config.APIKey == "" || !strings.HasPrefix(token, chatTokenPrefix) {
+		return chatToken{}, false
+	}
+	enc, sig, ok := strings.Cut(strings.TrimPrefix(token, chatTokenPrefix), ".")
+	if !ok {
+		return chatToken{}, false
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return chatToken{}, false
+	}
+	mac := hmac.New(sha256.New, s.chatTokenSecret())
+	mac.Write([]byte(enc))
+	if subtle.ConstantTimeCompare(got, mac.Sum(nil)) != 1 {
+		return chatToken{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(enc)
+	if err != nil {
+		return chatToken{}, false
+	}
+	var t chatToken
+	if json.Unmarshal(payload, &t) != nil || t.ChatJID == "" || now.Unix() >= t.ExpiresAt {
+		return chatToken{}, false
+	}
+	return t, true
+}
+
+// chatScopeFromContext returns the chat a request's token is limited to.
+func chatScopeFromContext(ctx context.Context) (string, bool) {
+	chat, ok := ctx.Value(chatScopeContextKey{}).(string)
+	return chat, ok
+}
+
+// handleCreateChatToken mints a token limited to one chat, e.g. for an
+// external bot that should only see and post to one group.
+func (s *Server) handleCreateChatToken(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	var req struct {
+		ChatJID string `json:"chat_jid"`
+		Name    string `json:"name"`
+		TTL     string `json:"ttl"`
+		// Media grants the media scope; true unless set to false.
+		Media *bool `json:"media"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if strings.TrimSpace(req.ChatJID) == "" {
+		writeError(w, http.StatusBadRequest, "'chat_jid' field is required")
+		return
+	}
+	ttl := defaultTokenTTL
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 || d > maxTokenTTL {
+			writeError(w, http.StatusBadRequest, "'ttl' must be a duration between 1s and 2160h")
+			return
+		}
+		ttl = d
+	}
+	chat := jid.Normalize(req.ChatJID)
+	if !s.phoneFilter.IsAllowed(chat) {
+		writeError(w, http.StatusForbidden, "chat not allowed")
+		return
+	}
+
+	var id [6]byte
+	rand.Read(id[:])
+	expires := time.Now().UTC().Add(ttl).Truncate(time.Second)
+	t := chatToken{
+		ID:        hex.EncodeToString(id[:]),
+		Name:      req.Name,
+		ChatJID:   chat,
+		ExpiresAt: expires.Unix(),
+		NoMedia:   req.Media != nil && !*req.Media,
+	}
+	writeJSON(w, map[string]any{
+		"token":      s.signChatToken(t),
+		"id":         t.ID,
+		"key_id":     chatTokenKeyPrefix + t.ID,
+		"name":       t.Name,
+		"chat_jid":   chat,
+		"media":      !t.NoMedia,
+		"expires_at": expires,
+	})
+}
+
+// chatPin is how a route a chat token may use is kept to the token's chat.
+type chatPin int
+
+const (
+	// pinQuery sets the chat_jid query parameter to the token's chat.
+	pinQuery chatPin = iota
+	// pinPath requires the route to be under /chats/{jid} of the chat.
+	pinPath
+	// pinBody requires the "to" field of the JSON body to be the chat.
+	pinBody
+	// pinNone is for routes that name no chat.
+	pinNone
+)
+
+// chatScopeRoute is a route chat tokens may use. path is matched exactly,
+// below it if it ends in "/", or, for pinPath, after /chats/{jid}.
+type chatScopeRoute struct {
+	method string
+	path   string
+	pin    chatPin
+}
+
+// chatScopeRoutes are the routes chat tokens may use.
+var chatScopeRoutes = []chatScopeRoute{
+	{http.MethodGet, "/messages", pinQuery},
+	{http.MethodGet, "/messages/search", pinQuery},
+	{http.MethodGet, "/messages/semantic-search", pinQuery},
+	{http.MethodGet, "/triggers/new-messages", pinQuery},
+	{http.MethodGet, "/media/", pinQuery},
+	{http.MethodGet, "/context", pinPath},
+	{http.MethodGet, "/media.zip", pinPath},
+	{http.MethodGet, "/read", pinPath},
+	{http.MethodPost, "/typing", pinPath},
+	{http.MethodPost, "/read", pinPath},
+	{http.MethodPost, "/messages/send", pinBody},
+	{http.MethodGet, "/limits", pinNone},
+}
+
+// chatScopeRouteFor finds the route of a request made with a token for chat.
+func chatScopeRouteFor(r *http.Request, chat string) (chatScopeRoute, bool) {
+	for _, rt := range chatScopeRoutes {
+		if rt.matches(r, chat) {
+			return rt, true
+		}
+	}
+	return chatScopeRoute{}, false
+}
+
+func (rt chatScopeRoute) matches(r *http.Request, chat string) bool {
+	switch {
+	case r.Method != rt.method:
+		return false
+	case rt.pin == pinPath:
+		return scopedChatPath(r.URL.Path, rt.path, chat)
+	case strings.HasSuffix(rt.path, "/"):
+		return strings.HasPrefix(r.URL.Path, rt.path)
+	}
+	return r.URL.Path == rt.path
+}
+
+// chatScopeMiddleware limits requests made with a chat token to
+// chatScopeRoutes, kept to the token's chat:
+//   - listing, searching, polling and media downloads get the chat as
+//     chat_jid; asking for another chat is refused
+//   - routes under /chats/{jid} must name the chat
+//   - sends must go to the chat
+//
+// Every other request is refused.
+func (s *Server) chatScopeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chat, ok := chatScopeFromContext(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rt, ok := chatScopeRouteFor(r, chat)
+		if !ok {
+			writeError(w, http.StatusForbidden, "token is limited to chat "+chat)
+			return
+		}
+		switch rt.pin {
+		case pinQuery:
+			q := r.URL.Query()
+			if v := q.Get("chat_jid"); v != "" && jid.Normalize(v) != chat {
+				writeError(w, http.StatusForbidden, "token is limited to chat "+chat)
+				return
+			}
+			q.Set("chat_jid", chat)
+			r.URL.RawQuery = q.Encode()
+		case pinBody:
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxScopedSendBody))
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid JSON body")
+				return
+			}
+			// Decode the way handleSendMessage does, so a body it accepts
+			// cannot carry a recipient this check did not see
+			var req sendRequest
+			if err := json.NewDecoder(bytes.NewReader(body)).Decode(&req); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid JSON body")
+				return
+			}
+			if req.To != "" && jid.Normalize(req.To) != chat {
+				writeError(w, http.StatusForbidden, "token is limited to chat "+chat)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// scopedChatPath reports whether path is the route of chat ending in
+// suffix, e.g. its context window.
+func scopedChatPath(path, suffix, chat string) bool {
+	rest, ok := strings.CutPrefix(path, "/chats/")
+	if !ok {
+		return false
+	}
+	c, ok := strings.CutSuffix(rest, suffix)
+	return ok && !strings.Contains(c, "/") && jid.Normalize(c) == chat
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tokenGroup = "120363012345678901@g.us"
+
+// mintChatToken creates a chat token through the admin endpoint.
+func mintChatToken(t *testing.T, srv *Server, body string) string {
+	t.Helper()
+	w := doRequest(srv, http.MethodPost, "/api/v1/admin/tokens", "test-key", body)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Data struct {
+			Token     string    `json:"token"`
+			KeyID     string    `json:"key_id"`
+			ChatJID   string    `json:"chat_jid"`
+			ExpiresAt time.Time `json:"expires_at"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, tokenGroup, resp.Data.ChatJID)
+	assert.True(t, strings.HasPrefix(resp.Data.KeyID, "token:"))
+	return resp.Data.Token
+}
+
+func TestChatToken_ScopesReads(t *testing.T) {
+	mock := &mockApp{listMessagesResult: `{"success":true,"data":[]}`}
+	srv := newTestServer(mock)
+	token := mintChatToken(t, srv, `{"chat_jid":"`+tokenGroup+`","name":"contractor","ttl":"48h"}`)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/messages", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, mock.lastChatJID)
+	assert.Equal(t, tokenGroup, *mock.lastChatJID)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/messages/search?query=invoice", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, mock.lastChatJID)
+	assert.Equal(t, tokenGroup, *mock.lastChatJID)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/messages?chat_jid=34600111222@s.whatsapp.net", token, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = doRequest(srv, http.MethodPost, "/api/v1/chats/"+tokenGroup+"/typing", token, `{"state":"composing"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(srv, http.MethodPost, "/api/v1/chats/"+tokenGroup+"/read", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(srv, http.MethodPost, "/api/v1/chats/34600111222/read", token, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	mock.mediaZip = []byte("PK")
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats/"+tokenGroup+"/media.zip", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, tokenGroup, mock.lastMediaParams.ChatJID)
+
+	for _, path := range []string{"/api/v1/chats", "/api/v1/contacts?query=a", "/api/v1/admin/keys", "/api/v2/chats", "/api/v1/chats/34600111222/media.zip"} {
+		w = doRequest(srv, http.MethodGet, path, token, "")
+		assert.Equal(t, http.StatusForbidden, w.Code, path)
+	}
+}
+
+func TestChatToken_ScopesSends(t *testing.T) {
+	mock := &mockApp{sendMessageResult: `{"success":true,"data":{}}`}
+	srv := newTestServer(mock)
+	token := mintChatToken(t, srv, `{"chat_jid":"`+tokenGroup+`"}`)
+
+	w := doRequest(srv, http.MethodPost, "/api/v1/messages/send", token, `{"to":"34600111222","message":"hi"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.False(t, mock.sendMessageCalled)
+
+	w = doRequest(srv, http.MethodPost, "/api/v1/messages/send", token, `{"to":"`+tokenGroup+`","message":"hi"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, tokenGroup, mock.lastSendRecipient)
+	assert.Equal(t, "hi", mock.lastSendMessage)
+}
+
+func TestChatToken_RefusesSendsItCannotParse(t *testing.T) {
+	mock := &mockApp{sendMessageResult: `{"success":true,"data":{}}`}
+	srv := newTestServer(mock)
+	token := mintChatToken(t, srv, `{"chat_jid":"`+tokenGroup+`"}`)
+
+	// Trailing bytes fail json.Unmarshal but not the handler's decoder
+	for _, body := range []string{`{"to":"34600111222","message":"hi"} x`, `{"to":`} {
+		w := doRequest(srv, http.MethodPost, "/api/v1/messages/send", token, body)
+		assert.Contains(t, []int{http.StatusBadRequest, http.StatusForbidden}, w.Code, body)
+		assert.False(t, mock.sendMessageCalled, body)
+	}
+}
+
+func TestChatToken_Invalid(t *testing.T) {
+	srv := newTestServer(&mockApp{listMessagesResult: `{"success":true,"data":[]}`})
+	token := mintChatToken(t, srv, `{"chat_jid":"`+tokenGroup+`"}`)
+
+	// Tampered payload
+	w := doRequest(srv, http.MethodGet, "/api/v1/messages", token[:len(token)-2]+"xx", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Signed with another API_KEY
+	other := NewServer(Config{APIKey: "other-key", MaxMessages: 100}, &mockApp{})
+	w = doRequest(other, http.MethodGet, "/api/v1/messages", token, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Expired
+	expired := srv.signChatToken(chatToken{ID: "old", ChatJID: tokenGroup, ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+	w = doRequest(srv, http.MethodGet, "/api/v1/messages", expired, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestCreateChatToken_Validation(t *testing.T) {
+	srv := NewServer(Config{
+		APIKey:         "test-key",
+		APIKeys:        map[string]string{"crm": "crm-key"},
+		PhoneBlacklist: []string{"34600111222"},
+	}, &mockApp{})
+
+	w := doRequest(srv, http.MethodPost, "/api/v1/admin/tokens", "crm-key", `{"chat_jid":"`+tokenGroup+`"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(srv, http.MethodPost, "/api/v1/admin/tokens", "test-key", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(srv, http.MethodPost, "/api/v1/admin/tokens", "test-key", `{"chat_jid":"`+tokenGroup+`","ttl":"9999h"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(srv, http.MethodPost, "/api/v1/admin/tokens", "test-key", `{"chat_jid":"34600111222"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,403 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/reqid"
+)
+
+// The Cloud API shim accepts the message-send endpoint of Meta's WhatsApp
+// Business Cloud API, so software built against the official API can send
+// through this server:
+//
+//	POST /v21.0/{phone_number_id}/messages
+//	{"messaging_product":"whatsapp","to":"34600111222","type":"text","text":{"body":"Hello"}}
+//
+// Text, media by link and templates are supported. Templates are not
+// approved by Meta: they are rendered from a local file of template texts.
+// Requests are authenticated with the API key as the bearer token and go
+// through the same limits, maintenance mode and phone filtering as
+// POST /messages/send.
+
+// Graph API versions served by the shim. Each is registered as a literal
+// path prefix, since a {version} wildcard would overlap /api/v1/.
+const (
+	cloudAPIMinVersion = 13
+	cloudAPIMaxVersion = 30
+)
+
+// maxCloudMediaBytes bounds media fetched by link; WhatsApp documents go up
+// to 100 MB.
+const maxCloudMediaBytes = 100 << 20
+
+// cloudRequest is the body of a Cloud API message send.
+type cloudRequest struct {
+	MessagingProduct string         `json:"messaging_product"`
+	RecipientType    string         `json:"recipient_type"`
+	To               string         `json:"to"`
+	Type             string         `json:"type"`
+	Text             *cloudText     `json:"text"`
+	Image            *cloudMedia    `json:"image"`
+	Video            *cloudMedia    `json:"video"`
+	Audio            *cloudMedia    `json:"audio"`
+	Document         *cloudMedia    `json:"document"`
+	Template         *cloudTemplate `json:"template"`
+	Context          *cloudContext  `json:"context"`
+}
+
+// cloudContext makes a message a reply to the message it names.
+type cloudContext struct {
+	MessageID string `json:"message_id"`
+}
+
+type cloudText struct {
+	Body string `json:"body"`
+}
+
+type cloudMedia struct {
+	ID       string `json:"id"`
+	Link     string `json:"link"`
+	Caption  string `json:"caption"`
+	Filename string `json:"filename"`
+}
+
+type cloudTemplate struct {
+	Name     string `json:"name"`
+	Language struct {
+		Code string `json:"code"`
+	} `json:"language"`
+	Components []struct {
+		Type       string `json:"type"`
+		Parameters []struct {
+			Type          string `json:"type"`
+			ParameterName string `json:"parameter_name"`
+			Text          string `json:"text"`
+			Currency      *struct {
+				FallbackValue string `json:"fallback_value"`
+			} `json:"currency"`
+			DateTime *struct {
+				FallbackValue string `json:"fallback_value"`
+			} `json:"date_time"`
+		} `json:"parameters"`
+	} `json:"components"`
+}
+
+// cloudResponse is the Cloud API's response to a send.
+type cloudResponse struct {
+	MessagingProduct string         `json:"messaging_product"`
+	Contacts         []cloudContact `json:"contacts"`
+	Messages         []cloudMessage `json:"messages"`
+}
+
+type cloudContact struct {
+	Input string `json:"input"`
+	WAID  string `json:"wa_id"`
+}
+
+type cloudMessage struct {
+	ID string `json:"id"`
+}
+
+// Cloud API error codes returned by the shim.
+const (
+	cloudErrAuth       = 190
+	cloudErrParam      = 100
+	cloudErrPermission = 10
+	cloudErrRateLimit  = 130429
+	cloudErrGeneric    = 131000
+	cloudErrUnavail    = 131016
+	cloudErrParamCount = 132000
+	cloudErrTemplate   = 132001
+)
+
+// cloudError is the Cloud API's error body.
+type cloudError struct {
+	Error struct {
+		Message   string `json:"message"`
+		Type      string `json:"type"`
+		Code      int    `json:"code"`
+		FBTraceID string `json:"fbtrace_id,omitempty"`
+	} `json:"error"`
+}
+
+// SetCloudTemplates loads the templates the Cloud API shim renders, from a
+// JSON file mapping template names to their text per language code:
+//
+//	{"order_shipped": {"en_US": "Hi {{1}}, order {{2}} has shipped."}}
+//
+// An empty path removes them.
+func (s *Server) SetCloudTemplates(path string) error {
+	if path == "" {
+		s.cloudTemplates = nil
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var templates map[string]map[string]string
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return fmt.Errorf("invalid templates file %s: %v", path, err)
+	}
+	s.cloudTemplates = templates
+	return nil
+}
+
+// registerCloudAPI serves the Cloud API shim under every supported version.
+func (s *Server) registerCloudAPI() {
+	handler := s.requestIDMiddleware(s.recordMiddleware(s.cloudShim(s.recoverMiddleware(s.authMiddleware(s.limitMiddleware(s.maintenanceMiddleware(s.replicaMiddleware(s.chatScopeMiddleware(http.HandlerFunc(s.handleCloudSend))))))))))
+	for v := cloudAPIMinVersion; v <= cloudAPIMaxVersion; v++ {
+		s.mux.Handle(fmt.Sprintf("POST /v%d.0/{phone_number_id}/messages", v), handler)
+	}
+}
+
+// cloudShim rewrites the v1 error envelopes written by the middleware in
+// next, e.g. for a wrong key, into Cloud API errors.
+func (s *Server) cloudShim(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := &bufferedResponse{w: w}
+		next.ServeHTTP(buf, r)
+		if buf.passthrough {
+			return
+		}
+		if buf.status == 0 {
+			buf.status = http.StatusOK
+		}
+		var env v1Envelope
+		if json.Unmarshal(buf.body.Bytes(), &env) != nil || env.Success == nil || *env.Success {
+			w.WriteHeader(buf.status)
+			w.Write(buf.body.Bytes())
+			return
+		}
+		msg := ""
+		if env.Error != nil {
+			msg = *env.Error
+		}
+		code := cloudErrGeneric
+		switch buf.status {
+		case http.StatusUnauthorized:
+			code = cloudErrAuth
+		case http.StatusForbidden:
+			code = cloudErrPermission
+		case http.StatusTooManyRequests:
+			code = cloudErrRateLimit
+		case http.StatusServiceUnavailable:
+			code = cloudErrUnavail
+		}
+		w.Header().Del("Content-Length")
+		writeCloudError(w, buf.status, code, msg)
+	})
+}
+
+// handleCloudSend sends a Cloud API message: text, media fetched from its
+// link, or a template rendered from the local templates file.
+func (s *Server) handleCloudSend(w http.ResponseWriter, r *http.Request) {
+	var req cloudRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeCloudError(w, http.StatusBadRequest, cloudErrParam, "Invalid parameter: request body is not valid JSON")
+		return
+	}
+	if req.MessagingProduct != "" && req.MessagingProduct != "whatsapp" {
+		writeCloudError(w, http.StatusBadRequest, cloudErrParam, "Invalid parameter: messaging_product must be whatsapp")
+		return
+	}
+	if req.To == "" {
+		writeCloudError(w, http.StatusBadRequest, cloudErrParam, "The parameter to is required.")
+		return
+	}
+	recipient, err := jid.ParseRecipient(req.To)
+	if err != nil {
+		writeCloudError(w, http.StatusBadRequest, cloudErrParam, "Invalid parameter: "+err.Error())
+		return
+	}
+	if !s.phoneFilter.IsAllowed(recipient) {
+		writeCloudError(w, http.StatusForbidden, cloudErrPermission, "recipient not allowed")
+		return
+	}
+	if req.Type == "" {
+		req.Type = "text"
+	}
+
+	var result string
+	switch req.Type {
+	case "text":
+		if req.Text == nil || req.Text.Body == "" {
+			writeCloudError(w, http.StatusBadRequest, cloudErrParam, "The parameter text['body'] is required.")
+			return
+		}
+		var quoted string
+		if req.Context != nil {
+			quoted = req.Context.MessageID
+		}
+		result = s.app.SendMessage(r.Context(), req.To, req.Text.Body, client.SendOptions{QuotedMessageID: quoted})
+	case "template":
+		text, status, code, err := s.renderCloudTemplate(req.Template)
+		if err != nil {
+			writeCloudError(w, status, code, err.Error())
+			return
+		}
+		result = s.app.SendMessage(r.Context(), req.To, text, client.SendOptions{})
+	case "image", "video", "audio", "document":
+		media := map[string]*cloudMedia{"image": req.Image, "video": req.Video, "audio": req.Audio, "document": req.Document}[req.Type]
+		if media == nil || media.Link == "" {
+			msg := fmt.Sprintf("The parameter %s['link'] is required.", req.Type)
+			if media != nil && media.ID != "" {
+				msg = "Uploaded media IDs are not supported; send the media by link."
+			}
+			writeCloudError(w, http.StatusBadRequest, cloudErrParam, msg)
+			return
+		}
+		out, err := s.fetchCloudMedia(r, req.Type, media)
+		if err != nil {
+			writeCloudError(w, http.StatusBadRequest, cloudErrParam, "Media download error: "+err.Error())
+			return
+		}
+		result = s.app.SendMedia(r.Context(), req.To, out)
+	default:
+		writeCloudError(w, http.StatusBadRequest, cloudErrParam, fmt.Sprintf("Invalid parameter: message type %q is not supported", req.Type))
+		return
+	}
+
+	if !resultSucceeded(result) {
+		var env v1Envelope
+		json.Unmarshal([]byte(result), &env)
+		msg := "send failed"
+		if env.Error != nil {
+			msg = *env.Error
+		}
+		writeCloudError(w, http.StatusInternalServerError, cloudErrGeneric, msg)
+		return
+	}
+	s.usage.recordSend(keyIDFromContext(r.Context()))
+
+	waID := req.To
+	if user, server, _ := strings.Cut(recipient, "@"); server == "s.whatsapp.net" {
+		waID = user
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cloudResponse{
+		MessagingProduct: "whatsapp",
+		Contacts:         []cloudContact{{Input: req.To, WAID: waID}},
+		Messages:         []cloudMessage{{ID: "wamid." + strings.ToUpper(hex.EncodeToString(id))}},
+	})
+}
+
+var cloudPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+// renderCloudTemplate renders a template from the templates file with the
+// text parameters of its body component: positional parameters fill {{1}},
+// {{2}}, …, and named ones fill {{parameter_name}}. It returns the status
+// and error code to answer with if it cannot.
+func (s *Server) renderCloudTemplate(t *cloudTemplate) (string, int, int, error) {
+	if t == nil || t.Name == "" {
+		return "", http.StatusBadRequest, cloudErrParam, fmt.Errorf("The parameter template['name'] is required.")
+	}
+	langs, ok := s.cloudTemplates[t.Name]
+	if !ok {
+		return "", http.StatusNotFound, cloudErrTemplate, fmt.Errorf("template name (%s) does not exist", t.Name)
+	}
+	code := t.Language.Code
+	text, ok := langs[code]
+	if !ok {
+		base, _, _ := strings.Cut(code, "_")
+		if text, ok = langs[base]; !ok {
+			return "", http.StatusNotFound, cloudErrTemplate, fmt.Errorf("template name (%s) does not exist in %s", t.Name, code)
+		}
+	}
+
+	values := make(map[string]string)
+	n := 0
+	for _, c := range t.Components {
+		if !strings.EqualFold(c.Type, "body") {
+			continue
+		}
+		for _, p := range c.Parameters {
+			v := p.Text
+			if p.Currency != nil {
+				v = p.Currency.FallbackValue
+			} else if p.DateTime != nil {
+				v = p.DateTime.FallbackValue
+			}
+			if p.ParameterName != "" {
+				values[p.ParameterName] = v
+				continue
+			}
+			n++
+			values[fmt.Sprint(n)] = v
+		}
+	}
+	var missing []string
+	text = cloudPlaceholder.ReplaceAllStringFunc(text, func(m string) string {
+		name := cloudPlaceholder.FindStringSubmatch(m)[1]
+		v, ok := values[name]
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", http.StatusBadRequest, cloudErrParamCount, fmt.Errorf("Number of parameters does not match the expected number of params: missing %s", strings.Join(missing, ", "))
+	}
+	return text, 0, 0, nil
+}
+
+// fetchCloudMedia downloads media sent by link.
+func (s *Server) fetchCloudMedia(r *http.Request, mediaType string, m *cloudMedia) (client.OutgoingMedia, error) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, m.Link, nil)
+	if err != nil {
+		return client.OutgoingMedia{}, err
+	}
+	resp, err := s.cloudMedia.Do(req)
+	if err != nil {
+		return client.OutgoingMedia{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return client.OutgoingMedia{}, fmt.Errorf("%s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCloudMediaBytes+1))
+	if err != nil {
+		return client.OutgoingMedia{}, err
+	}
+	if len(data) > maxCloudMediaBytes {
+		return client.OutgoingMedia{}, fmt.Errorf("larger than %d MB", maxCloudMediaBytes>>20)
+	}
+	mimeType := resp.Header.Get("Content-Type")
+	if mimeType == "" || strings.HasPrefix(mimeType, "application/octet-stream") {
+		mimeType = http.DetectContentType(data)
+	}
+	// Formats WhatsApp cannot show inline are sent as documents
+	if mediaType != "document" {
+		mediaType = client.MediaTypeForMIME(mimeType)
+	}
+	return client.OutgoingMedia{
+		Type:     mediaType,
+		Data:     data,
+		MimeType: mimeType,
+		Filename: m.Filename,
+		Caption:  m.Caption,
+	}, nil
+}
+
+func writeCloudError(w http.ResponseWriter, status, code int, msg string) {
+	var e cloudError
+	e.Error.Message = msg
+	e.Error.Type = "OAuthException"
+	e.Error.Code = code
+	e.Error.FBTraceID = w.Header().Get(reqid.Header)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(e)
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/netguard"
+)
+
+func newCloudServer(t *testing.T, mock *mockApp) *Server {
+	t.Helper()
+	srv := NewServer(Config{APIKey: "test-key", CloudAPICompat: true, PhoneBlacklist: []string{"34600999999"}}, mock)
+	path := filepath.Join(t.TempDir(), "templates.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"order_shipped": {"en_US": "Hi {{1}}, order {{2}} has shipped.", "es": "Hola {{1}}, el pedido {{2}} ha salido."},
+		"appointment": {"en": "See you on {{date}}, {{ name }}."}
+	}`), 0o644))
+	require.NoError(t, srv.SetCloudTemplates(path))
+	return srv
+}
+
+func doCloudRequest(srv *Server, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v21.0/1234567890/messages", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+key)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+	return w
+}
+
+func TestCloudAPI_Text(t *testing.T) {
+	mock := &mockApp{sendMessageResult: `{"success":true,"data":{"sent":true},"error":null}`}
+	srv := newCloudServer(t, mock)
+
+	w := doCloudRequest(srv, "test-key", `{"messaging_product":"whatsapp","recipient_type":"individual","to":"+34 600 111 222","type":"text","text":{"preview_url":false,"body":"Hello"}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "+34 600 111 222", mock.lastSendRecipient)
+	assert.Equal(t, "Hello", mock.lastSendMessage)
+
+	var resp cloudResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "whatsapp", resp.MessagingProduct)
+	assert.Equal(t, []cloudContact{{Input: "+34 600 111 222", WAID: "34600111222"}}, resp.Contacts)
+	require.Len(t, resp.Messages, 1)
+	assert.Regexp(t, `^wamid\.[0-9A-F]{32}$`, resp.Messages[0].ID)
+}
+
+func TestCloudAPI_Template(t *testing.T) {
+	mock := &mockApp{sendMessageResult: `{"success":true,"data":{"sent":true},"error":null}`}
+	srv := newCloudServer(t, mock)
+
+	w := doCloudRequest(srv, "test-key", `{"messaging_product":"whatsapp","to":"34600111222","type":"template","template":{
+		"name":"order_shipped","language":{"code":"es_ES"},
+		"components":[{"type":"header","parameters":[{"type":"image","image":{"link":"https://example.com/a.png"}}]},
+		              {"type":"body","parameters":[{"type":"text","text":"Ana"},{"type":"currency","currency":{"fallback_value":"#42","code":"EUR","amount_1000":1}}]}]}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "Hola Ana, el pedido #42 ha salido.", mock.lastSendMessage)
+
+	w = doCloudRequest(srv, "test-key", `{"to":"34600111222","type":"template","template":{
+		"name":"appointment","language":{"code":"en"},
+		"components":[{"type":"body","parameters":[{"type":"text","parameter_name":"name","text":"Bo"},{"type":"text","parameter_name":"date","text":"Friday"}]}]}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "See you on Friday, Bo.", mock.lastSendMessage)
+
+	for name, tc := range map[string]struct {
+		body   string
+		status int
+		code   int
+	}{
+		"unknown template": {`{"to":"34600111222","type":"template","template":{"name":"nope","language":{"code":"en_US"}}}`, http.StatusNotFound, cloudErrTemplate},
+		"unknown language": {`{"to":"34600111222","type":"template","template":{"name":"order_shipped","language":{"code":"fr"}}}`, http.StatusNotFound, cloudErrTemplate},
+		"missing params":   {`{"to":"34600111222","type":"template","template":{"name":"order_shipped","language":{"code":"en_US"},"components":[{"type":"body","parameters":[{"type":"text","text":"Ana"}]}]}}`, http.StatusBadRequest, cloudErrParamCount},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := doCloudRequest(srv, "test-key", tc.body)
+			assertCloudError(t, w, tc.status, tc.code)
+		})
+	}
+}
+
+func TestCloudAPI_MediaByLink(t *testing.T) {
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/photo.jpg":
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write([]byte("\xff\xd8\xff\xe0"))
+		case "/anim.gif":
+			w.Write([]byte("GIF89a"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer files.Close()
+
+	mock := &mockApp{sendMessageResult: `{"success":true,"data":{"sent":true},"error":null}`}
+	srv := newCloudServer(t, mock)
+
+	// Links to private addresses are refused unless allowed
+	assertCloudError(t, doCloudRequest(srv, "test-key", `{"to":"34600111222","type":"image","image":{"link":"`+files.URL+`/photo.jpg"}}`), http.StatusBadRequest, cloudErrParam)
+	assert.Empty(t, mock.sentMedia)
+	srv.cloudMedia = netguard.Client(time.Second, []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")})
+
+	w := doCloudRequest(srv, "test-key", `{"to":"34600111222","type":"image","image":{"link":"`+files.URL+`/photo.jpg","caption":"Look"}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, mock.sentMedia, 1)
+	assert.Equal(t, "image", mock.sentMedia[0].Type)
+	assert.Equal(t, "Look", mock.sentMedia[0].Caption)
+
+	// Images WhatsApp cannot show inline go out as documents
+	w = doCloudRequest(srv, "test-key", `{"to":"34600111222","type":"image","image":{"link":"`+files.URL+`/anim.gif"}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, mock.sentMedia, 2)
+	assert.Equal(t, "document", mock.sentMedia[1].Type)
+	assert.Equal(t, "image/gif", mock.sentMedia[1].MimeType)
+
+	assertCloudError(t, doCloudRequest(srv, "test-key", `{"to":"34600111222","type":"document","document":{"link":"`+files.URL+`/missing.pdf"}}`), http.StatusBadRequest, cloudErrParam)
+	assertCloudError(t, doCloudRequest(srv, "test-key", `{"to":"34600111222","type":"image","image":{"id":"1234"}}`), http.StatusBadRequest, cloudErrParam)
+	assert.Len(t, mock.sentMedia, 2)
+}
+
+func TestCloudAPI_Errors(t *testing.T) {
+	mock := &mockApp{sendMessageResult: `{"success":false,"data":null,"error":"not connected"}`}
+	srv := newCloudServer(t, mock)
+
+	assertCloudError(t, doCloudRequest(srv, "wrong", `{"to":"34600111222","text":{"body":"hi"}}`), http.StatusUnauthorized, cloudErrAuth)
+	assertCloudError(t, doCloudRequest(srv, "test-key", `{"to":"34600999999","text":{"body":"hi"}}`), http.StatusForbidden, cloudErrPermission)
+	assertCloudError(t, doCloudRequest(srv, "test-key", `{"to":"alice","text":{"body":"hi"}}`), http.StatusBadRequest, cloudErrParam)
+	assertCloudError(t, doCloudRequest(srv, "test-key", `{"to":"34600111222","type":"sticker","sticker":{}}`), http.StatusBadRequest, cloudErrParam)
+	assertCloudError(t, doCloudRequest(srv, "test-key", `{"to":"34600111222","text":{"body":"hi"}}`), http.StatusInternalServerError, cloudErrGeneric)
+
+	disabled := newTestServer(mock)
+	w := doCloudRequest(disabled, "test-key", `{"to":"34600111222","text":{"body":"hi"}}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func assertCloudError(t *testing.T, w *httptest.ResponseRecorder, status, code int) {
+	t.Helper()
+	require.Equal(t, status, w.Code, w.Body.String())
+	var e cloudError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &e))
+	assert.Equal(t, code, e.Error.Code)
+	assert.NotEmpty(t, e.Error.Message)
+}
@@ -1,37 +1,272 @@
 package api
 
 import (
+	"crypto/ed25519"
 	"fmt"
+	"net/netip"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/health"
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/logging"
+	"github.com/vicentereig/whatsapp-cli/internal/netguard"
+	"github.com/vicentereig/whatsapp-cli/internal/sentry"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
 )
 
 type Config struct {
-	APIKey         string
+	APIKey string
+	// APIKeys holds additional named keys (ID -> key) so usage can be
+	// attributed per integration. APIKey is always accepted as "default".
+	APIKeys map[string]string
+	// MediaKeys are the IDs of the keys with the media scope, which may
+	// download media; nil grants it to every key. APIKey always has it.
+	MediaKeys      []string
 	Port           int
 	StoreDir       string
 	MaxMessages    int
 	MaxHours       int
 	PhoneWhitelist []string
 	PhoneBlacklist []string
-	LogLevel       string
+	// LogLevel is the minimum level logged (debug, info, warn or error);
+	// LogLevelWhatsApp and LogLevelStore override it for the whatsmeow client
+	// and its session database. LogFormat is "text" or "json".
+	LogLevel         string
+	LogLevelWhatsApp string
+	LogLevelStore    string
+	LogFormat        string
+	// CredentialBackend is where the WhatsApp device keys are kept: "file"
+	// (whatsapp.db) or "keychain".
+	CredentialBackend string
+	EnablePprof       bool
+	// DebugRecordRequests is how many recent API requests, with their
+	// responses, are kept for GET /admin/debug/requests; 0 disables
+	// recording.
+	DebugRecordRequests int
+	// UpdateCheck enables looking up the latest GitHub release for /version.
+	UpdateCheck bool
+	// Replica serves the read endpoints from a copy of messages.db without
+	// connecting to WhatsApp; writes are rejected.
+	Replica bool
+	// Concurrent request limits for /api/v1; 0 disables a limit. Requests over
+	// a limit wait up to MaxQueueWait for a slot before getting a 503.
+	MaxInflight       int
+	MaxInflightPerKey int
+	MaxInflightPerIP  int
+	MaxQueueWait      time.Duration
+	// RateLimitPerMinute caps the requests of each API key per minute;
+	// further requests get a 429 until the minute ends. 0 disables it.
+	RateLimitPerMinute int
+	// V1Sunset, when set, marks /api/v1 responses as deprecated in favour of
+	// /api/v2 and announces the date v1 will be removed.
+	V1Sunset time.Time
+	// SpamThreshold is the spam score (0-100) from which incoming messages
+	// are quarantined. 0 disables quarantine.
+	SpamThreshold int
+	// GreetingMessage is a text/template sent to JIDs messaging the account
+	// for the first time; empty disables the auto-responder.
+	GreetingMessage    string
+	GreetingWebhookURL string
+	// Bot command router; disabled unless BotAllowedChats is set.
+	BotPrefix       string
+	BotAllowedChats []string
+	BotCommands     map[string]string // command name -> webhook URL
+	// ChatOpsAdmins are the phone numbers or JIDs allowed to control the
+	// daemon with admin commands sent from their own chat.
+	ChatOpsAdmins []string
+	// EventBus replicates messages, receipts and presence to "nats",
+	// "kafka" (through a REST Proxy at EventBusURL) or "mqtt"; empty
+	// disables it.
+	EventBus            string
+	EventBusURL         string
+	EventBusFormat      string // json or protobuf
+	EventBusTopicPrefix string
+	// EventBusCommandTopic, with EventBus "mqtt", is the topic sends are
+	// received on, as bodies of POST /messages/send.
+	EventBusCommandTopic string
+	// RedisURL enables publishing new messages to Redis pub/sub, on
+	// RedisChannel or on one "<RedisChannel>:<chat JID>" channel per chat.
+	RedisURL            string
+	RedisChannel        string
+	RedisChannelPerChat bool
+	// HistorySyncMode is how much history is requested when pairing: "recent"
+	// or "full", optionally limited to the last HistorySyncDays days.
+	HistorySyncMode string
+	HistorySyncDays int
+	// OutboxMaxAge is how long notifications a webhook or broker did not
+	// accept are queued for replay; zero disables the queue.
+	OutboxMaxAge time.Duration
+	// OutboundAllowedNetworks are the private networks that calendar feeds
+	// and Cloud API media links may still be fetched from.
+	OutboundAllowedNetworks []netip.Prefix
+	// SendDeliveryReceipts shows senders their messages as delivered once the
+	// daemon has them; SendReadReceipts marks incoming messages read.
+	SendDeliveryReceipts bool
+	SendReadReceipts     bool
+	// SimulateTyping shows the typing indicator before every send, for a
+	// time proportional to the message length.
+	SimulateTyping bool
+	// HashChain chains every stored message into a per-chat hash chain.
+	HashChain bool
+	// HashChainSigningKey signs the head of every chain export, and exports
+	// checked later must carry its signature.
+	HashChainSigningKey ed25519.PrivateKey
+	// ReconcileChats creates the chats the phone knows of in the store on
+	// connect, even those without new messages.
+	ReconcileChats bool
+	// SendDelayMin and SendDelayMax bound the random gap between sends,
+	// SendPerMinute caps sends per rolling minute, and messages sent during
+	// QuietHours ("HH:MM-HH:MM" in QuietHoursTimezone) are queued.
+	SendDelayMin       time.Duration
+	SendDelayMax       time.Duration
+	SendPerMinute      int
+	QuietHours         string
+	QuietHoursTimezone string
+	// SendDedupWindow treats sends with the same recipient and content
+	// within it as duplicates, rejected or, with SendDedupMode "flag",
+	// marked. Zero disables it.
+	SendDedupWindow time.Duration
+	SendDedupMode   string
+	// SentryDSN enables reporting API handler panics and sync crashes to a
+	// Sentry-compatible server, tagged with SentryEnvironment.
+	SentryDSN         string
+	SentryEnvironment string
+	// EmbeddingsURL enables the semantic search index, vectorizing message
+	// text with EmbeddingsModel through an OpenAI-compatible embeddings API
+	// (or Ollama's /api/embed), authenticated with EmbeddingsAPIKey if set.
+	EmbeddingsURL    string
+	EmbeddingsModel  string
+	EmbeddingsAPIKey string
+	// DigestChats are summarized every DigestPeriods ("daily", "weekly") at
+	// DigestTime ("HH:MM" in DigestTimezone); digests are pushed to the Redis
+	// notifier and DigestWebhookURL if set.
+	DigestChats      []string
+	DigestPeriods    []string
+	DigestTime       string
+	DigestTimezone   string
+	DigestWebhookURL string
+	// FollowupChats are tracked for incoming messages left without a reply
+	// for FollowupAfter; "*" tracks every one-to-one chat. Overdue chats are
+	// listed by GET /followups and pushed to the Redis notifier and
+	// FollowupWebhookURL if set.
+	FollowupChats      []string
+	FollowupAfter      time.Duration
+	FollowupWebhookURL string
+	// WebhookURL receives every message received while syncing as a JSON
+	// POST, retried and then queued in the outbox on failure.
+	WebhookURL string
+	// PublicURL is the address the API is reachable at from outside, e.g.
+	// https://wa.example.com. With it, webhook payloads referencing media
+	// carry URLs signed to download it without an API key for MediaURLTTL.
+	PublicURL   string
+	MediaURLTTL time.Duration
+	// MediaBlockedTypes are the MIME types, or "type/*" for all of a kind,
+	// GET /media/{message_id} never serves, judged by the content rather
+	// than the declared type. MediaRejectMismatch also refuses media whose
+	// content is not of its declared type.
+	MediaBlockedTypes   []string
+	MediaRejectMismatch bool
+	// WebhookSecret signs the message, greeting, digest, follow-up, bot command and recipe webhooks
+	// (see pkg/webhookverify); empty sends them unsigned.
+	WebhookSecret string
+	// RecipesFile is a JSON file of automation recipes, reloaded when it
+	// changes; empty disables recipes.
+	RecipesFile string
+	// HomeAssistantMQTTURL publishes a sensor and a notify entity per chat
+	// to Home Assistant through MQTT discovery under
+	// HomeAssistantDiscoveryPrefix, with state and commands under
+	// HomeAssistantTopic. HomeAssistantChats limits the chats exposed.
+	HomeAssistantMQTTURL         string
+	HomeAssistantDiscoveryPrefix string
+	HomeAssistantTopic           string
+	HomeAssistantChats           []string
+	// Feeds maps RSS/Atom feed URLs to the chats their new items are posted
+	// to, polled every FeedInterval; FeedImages also sends item images.
+	Feeds        map[string][]string
+	FeedInterval time.Duration
+	FeedImages   bool
+	// EmailRoutes maps the lower-cased addresses of inbound email to the
+	// chats it is forwarded to; "*" catches the other addresses.
+	EmailRoutes map[string]string
+	// TwilioCompat serves Twilio's Messages API for sends; see twilio.go.
+	TwilioCompat bool
+	// CloudAPICompat serves the message sends of Meta's WhatsApp Cloud API,
+	// rendering templates from CloudAPITemplates; see cloudapi.go.
+	CloudAPICompat    bool
+	CloudAPITemplates string
+	// SendMediaDir is the directory POST /messages/send-media may read
+	// files from by path; empty only accepts uploads.
+	SendMediaDir string
+	// FFmpegPath is the ffmpeg binary POST /messages/send-voice transcodes
+	// with; empty looks up "ffmpeg" in PATH.
+	FFmpegPath string
+	// CanaryInterval sends a message to the account's own chat that the
+	// phone must acknowledge within CanaryTimeout; 0 disables the canary.
+	CanaryInterval time.Duration
+	CanaryTimeout  time.Duration
+	// ReadyStates are the states in which /readyz answers 200; empty means
+	// health.DefaultReady.
+	ReadyStates []health.State
 }
 
+// minWebhookSecretLen rejects secrets short enough to guess.
+const minWebhookSecretLen = 16
+
 func ParseConfig() (Config, error) {
 	c := Config{
-		APIKey:   os.Getenv("API_KEY"),
-		Port:     8080,
-		StoreDir: "/data/store",
-		MaxMessages: 100,
-		MaxHours:    48,
-		LogLevel:    "info",
+		APIKey:              os.Getenv("API_KEY"),
+		Port:                8080,
+		StoreDir:            "/data/store",
+		MaxMessages:         100,
+		MaxHours:            48,
+		LogLevel:            "info",
+		LogFormat:           "text",
+		CredentialBackend:   "file",
+		BotPrefix:           "!",
+		SendDedupMode:       "reject",
+		MaxQueueWait:        5 * time.Second,
+		UpdateCheck:         true,
+		EventBusFormat:      "json",
+		EventBusTopicPrefix: "whatsapp",
+		RedisChannel:        "whatsapp:messages",
+		HistorySyncMode:     "recent",
+		OutboxMaxAge:        24 * time.Hour,
 	}
 
 	if c.APIKey == "" {
 		return Config{}, fmt.Errorf("API_KEY environment variable is required")
 	}
 
+	if v := os.Getenv("API_KEYS"); v != "" {
+		c.APIKeys = make(map[string]string)
+		for _, entry := range splitAndTrim(v) {
+			id, key, ok := strings.Cut(entry, ":")
+			id = strings.TrimSpace(id)
+			key = strings.TrimSpace(key)
+			if !ok || !validKeyID(id) || key == "" || id == DefaultKeyID {
+				return Config{}, fmt.Errorf("invalid API_KEYS entry: %s (expected id:key)", entry)
+			}
+			if _, dup := c.APIKeys[id]; dup {
+				return Config{}, fmt.Errorf("duplicate API_KEYS id: %s", id)
+			}
+			c.APIKeys[id] = key
+		}
+	}
+
+	if v := os.Getenv("MEDIA_KEYS"); v != "" {
+		c.MediaKeys = []string{}
+		for _, id := range splitAndTrim(v) {
+			if _, ok := c.APIKeys[id]; !ok && id != DefaultKeyID {
+				return Config{}, fmt.Errorf("invalid MEDIA_KEYS entry: %s (not an API_KEYS id)", id)
+			}
+			c.MediaKeys = append(c.MediaKeys, id)
+		}
+	}
+
 	if v := os.Getenv("PORT"); v != "" {
 		port, err := strconv.Atoi(v)
 		if err != nil {
@@ -68,13 +303,537 @@ func ParseConfig() (Config, error) {
 		c.PhoneBlacklist = splitAndTrim(v)
 	}
 
-	if v := os.Getenv("LOG_LEVEL"); v != "" {
-		c.LogLevel = v
+	for _, level := range []struct {
+		env string
+		dst *string
+	}{
+		{"LOG_LEVEL", &c.LogLevel},
+		{"LOG_LEVEL_WHATSAPP", &c.LogLevelWhatsApp},
+		{"LOG_LEVEL_STORE", &c.LogLevelStore},
+	} {
+		if v := os.Getenv(level.env); v != "" {
+			if _, err := logging.ParseLevel(v); err != nil {
+				return Config{}, fmt.Errorf("invalid %s value: %s (must be debug, info, warn or error)", level.env, v)
+			}
+			*level.dst = strings.ToLower(v)
+		}
+	}
+
+	if v := os.Getenv("LOG_FORMAT"); v != "" {
+		if v != logging.FormatText && v != logging.FormatJSON {
+			return Config{}, fmt.Errorf("invalid LOG_FORMAT value: %s (must be text or json)", v)
+		}
+		c.LogFormat = v
+	}
+
+	if v := os.Getenv("CREDENTIAL_BACKEND"); v != "" {
+		if v != "file" && v != "keychain" {
+			return Config{}, fmt.Errorf("invalid CREDENTIAL_BACKEND value: %s (must be file or keychain)", v)
+		}
+		c.CredentialBackend = v
+	}
+
+	if v := os.Getenv("ENABLE_PPROF"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid ENABLE_PPROF value: %s", v)
+		}
+		c.EnablePprof = b
+	}
+
+	if v := os.Getenv("DEBUG_RECORD_REQUESTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			b, berr := strconv.ParseBool(v)
+			if berr != nil {
+				return Config{}, fmt.Errorf("invalid DEBUG_RECORD_REQUESTS value: %s", v)
+			}
+			if n = 0; b {
+				n = defaultDebugRecordRequests
+			}
+		}
+		if n < 0 || n > maxDebugRecordRequests {
+			return Config{}, fmt.Errorf("DEBUG_RECORD_REQUESTS must be between 0 and %d, got %s", maxDebugRecordRequests, v)
+		}
+		c.DebugRecordRequests = n
+	}
+
+	if v := os.Getenv("UPDATE_CHECK"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid UPDATE_CHECK value: %s", v)
+		}
+		c.UpdateCheck = b
+	}
+
+	if v := os.Getenv("REPLICA"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid REPLICA value: %s", v)
+		}
+		c.Replica = b
+	}
+
+	for _, limit := range []struct {
+		env string
+		dst *int
+	}{
+		{"MAX_INFLIGHT", &c.MaxInflight},
+		{"MAX_INFLIGHT_PER_KEY", &c.MaxInflightPerKey},
+		{"MAX_INFLIGHT_PER_IP", &c.MaxInflightPerIP},
+		{"SEND_PER_MINUTE", &c.SendPerMinute},
+		{"RATE_LIMIT_PER_MINUTE", &c.RateLimitPerMinute},
+	} {
+		if v := os.Getenv(limit.env); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return Config{}, fmt.Errorf("invalid %s value: %s", limit.env, v)
+			}
+			*limit.dst = n
+		}
+	}
+
+	if v := os.Getenv("MAX_QUEUE_WAIT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return Config{}, fmt.Errorf("invalid MAX_QUEUE_WAIT value: %s", v)
+		}
+		c.MaxQueueWait = d
+	}
+
+	if v := os.Getenv("API_V1_SUNSET"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid API_V1_SUNSET value: %s (expected YYYY-MM-DD)", v)
+		}
+		c.V1Sunset = t
+	}
+
+	if v := os.Getenv("SPAM_QUARANTINE_THRESHOLD"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 100 {
+			return Config{}, fmt.Errorf("invalid SPAM_QUARANTINE_THRESHOLD value: %s (must be 0-100)", v)
+		}
+		c.SpamThreshold = n
+	}
+
+	c.GreetingMessage = os.Getenv("GREETING_MESSAGE")
+	if v := os.Getenv("GREETING_WEBHOOK_URL"); v != "" {
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return Config{}, fmt.Errorf("invalid GREETING_WEBHOOK_URL value: %s", v)
+		}
+		c.GreetingWebhookURL = v
+	}
+
+	if v := os.Getenv("BOT_PREFIX"); v != "" {
+		c.BotPrefix = v
+	}
+
+	if v := os.Getenv("BOT_ALLOWED_CHATS"); v != "" {
+		c.BotAllowedChats = splitAndTrim(v)
+	}
+
+	if v := os.Getenv("BOT_COMMANDS"); v != "" {
+		c.BotCommands = make(map[string]string)
+		for _, entry := range splitAndTrim(v) {
+			name, target, ok := strings.Cut(entry, "=")
+			name = strings.TrimSpace(name)
+			u, err := url.Parse(strings.TrimSpace(target))
+			if !ok || name == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return Config{}, fmt.Errorf("invalid BOT_COMMANDS entry: %s (expected name=https://...)", entry)
+			}
+			c.BotCommands[strings.ToLower(name)] = u.String()
+		}
+	}
+
+	if v := os.Getenv("CHATOPS_ADMINS"); v != "" {
+		for _, admin := range splitAndTrim(v) {
+			if admin == "*" || jid.IsGroup(admin) {
+				return Config{}, fmt.Errorf("invalid CHATOPS_ADMINS entry: %s (must be a phone number or contact JID)", admin)
+			}
+			c.ChatOpsAdmins = append(c.ChatOpsAdmins, admin)
+		}
+	}
+
+	if v := os.Getenv("EVENT_BUS"); v != "" {
+		if v != "nats" && v != "kafka" && v != "mqtt" {
+			return Config{}, fmt.Errorf("invalid EVENT_BUS value: %s (must be nats, kafka or mqtt)", v)
+		}
+		c.EventBus = v
+		c.EventBusURL = os.Getenv("EVENT_BUS_URL")
+		if c.EventBusURL == "" {
+			return Config{}, fmt.Errorf("EVENT_BUS_URL is required when EVENT_BUS is set")
+		}
+	}
+
+	if v := os.Getenv("EVENT_BUS_FORMAT"); v != "" {
+		if v != "json" && v != "protobuf" {
+			return Config{}, fmt.Errorf("invalid EVENT_BUS_FORMAT value: %s (must be json or protobuf)", v)
+		}
+		c.EventBusFormat = v
+	}
+
+	if v := os.Getenv("EVENT_BUS_TOPIC_PREFIX"); v != "" {
+		c.EventBusTopicPrefix = v
+	}
+	if c.EventBus == "mqtt" && strings.ContainsAny(c.EventBusTopicPrefix, "+#") {
+		return Config{}, fmt.Errorf("invalid EVENT_BUS_TOPIC_PREFIX value: %s (MQTT topics cannot contain wildcards)", c.EventBusTopicPrefix)
+	}
+
+	if v := os.Getenv("EVENT_BUS_COMMAND_TOPIC"); v != "" {
+		if c.EventBus != "mqtt" {
+			return Config{}, fmt.Errorf("EVENT_BUS_COMMAND_TOPIC requires EVENT_BUS=mqtt")
+		}
+		if strings.ContainsAny(v, "+#") {
+			return Config{}, fmt.Errorf("invalid EVENT_BUS_COMMAND_TOPIC value: %s (must not contain wildcards)", v)
+		}
+		c.EventBusCommandTopic = v
+	}
+
+	if v := os.Getenv("REDIS_URL"); v != "" {
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") || u.Host == "" {
+			return Config{}, fmt.Errorf("invalid REDIS_URL value: %s", v)
+		}
+		c.RedisURL = v
+	}
+
+	if v := os.Getenv("REDIS_CHANNEL"); v != "" {
+		c.RedisChannel = v
+	}
+
+	if v := os.Getenv("REDIS_CHANNEL_PER_CHAT"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid REDIS_CHANNEL_PER_CHAT value: %s", v)
+		}
+		c.RedisChannelPerChat = b
+	}
+
+	if v := os.Getenv("HISTORY_SYNC_MODE"); v != "" {
+		if v != "recent" && v != "full" {
+			return Config{}, fmt.Errorf("invalid HISTORY_SYNC_MODE value: %s (must be recent or full)", v)
+		}
+		c.HistorySyncMode = v
+	}
+
+	if v := os.Getenv("HISTORY_SYNC_DAYS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return Config{}, fmt.Errorf("invalid HISTORY_SYNC_DAYS value: %s", v)
+		}
+		c.HistorySyncDays = n
+	}
+
+	for _, flag := range []struct {
+		env string
+		dst *bool
+	}{
+		{"SEND_DELIVERY_RECEIPTS", &c.SendDeliveryReceipts},
+		{"SEND_READ_RECEIPTS", &c.SendReadReceipts},
+		{"SIMULATE_TYPING", &c.SimulateTyping},
+		{"HASH_CHAIN", &c.HashChain},
+		{"RECONCILE_CHATS", &c.ReconcileChats},
+		{"FEED_IMAGES", &c.FeedImages},
+		{"TWILIO_COMPAT", &c.TwilioCompat},
+		{"CLOUD_API_COMPAT", &c.CloudAPICompat},
+	} {
+		if v := os.Getenv(flag.env); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return Config{}, fmt.Errorf("invalid %s value: %s", flag.env, v)
+			}
+			*flag.dst = b
+		}
+	}
+
+	key, err := store.ParseChainSigningKey(os.Getenv("HASH_CHAIN_SIGNING_KEY"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid HASH_CHAIN_SIGNING_KEY: %w", err)
+	}
+	c.HashChainSigningKey = key
+
+	if v := os.Getenv("SEND_DELAY"); v != "" {
+		lo, hi, isRange := strings.Cut(v, "-")
+		if !isRange {
+			hi = lo
+		}
+		minDelay, err1 := time.ParseDuration(strings.TrimSpace(lo))
+		maxDelay, err2 := time.ParseDuration(strings.TrimSpace(hi))
+		if err1 != nil || err2 != nil || minDelay < 0 || maxDelay < minDelay {
+			return Config{}, fmt.Errorf("invalid SEND_DELAY value: %s (use a duration or MIN-MAX, e.g. 2s-8s)", v)
+		}
+		c.SendDelayMin, c.SendDelayMax = minDelay, maxDelay
+	}
+
+	c.QuietHours = strings.TrimSpace(os.Getenv("QUIET_HOURS"))
+	c.QuietHoursTimezone = os.Getenv("QUIET_HOURS_TZ")
+	if c.QuietHoursTimezone == "" {
+		c.QuietHoursTimezone = "UTC"
+	}
+	if _, err := time.LoadLocation(c.QuietHoursTimezone); err != nil {
+		return Config{}, fmt.Errorf("invalid QUIET_HOURS_TZ value: %s", c.QuietHoursTimezone)
+	}
+
+	if v := os.Getenv("SEND_DEDUP_WINDOW"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return Config{}, fmt.Errorf("invalid SEND_DEDUP_WINDOW value: %s (expected a duration, e.g. 30s)", v)
+		}
+		c.SendDedupWindow = d
+	}
+	if v := os.Getenv("SEND_DEDUP_MODE"); v != "" {
+		if v != "reject" && v != "flag" {
+			return Config{}, fmt.Errorf("invalid SEND_DEDUP_MODE value: %s (must be reject or flag)", v)
+		}
+		c.SendDedupMode = v
+	}
+
+	if v := os.Getenv("SENTRY_DSN"); v != "" {
+		if _, err := sentry.New(v, "", ""); err != nil {
+			return Config{}, fmt.Errorf("invalid SENTRY_DSN value (expected https://<key>@<host>/<project>)")
+		}
+		c.SentryDSN = v
+	}
+	c.SentryEnvironment = os.Getenv("SENTRY_ENVIRONMENT")
+
+	if v := os.Getenv("EMBEDDINGS_URL"); v != "" {
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return Config{}, fmt.Errorf("invalid EMBEDDINGS_URL value: %s", v)
+		}
+		c.EmbeddingsURL = v
+		c.EmbeddingsModel = strings.TrimSpace(os.Getenv("EMBEDDINGS_MODEL"))
+		if c.EmbeddingsModel == "" {
+			return Config{}, fmt.Errorf("EMBEDDINGS_MODEL is required with EMBEDDINGS_URL")
+		}
+		c.EmbeddingsAPIKey = os.Getenv("EMBEDDINGS_API_KEY")
+	}
+
+	if v := os.Getenv("DIGEST_CHATS"); v != "" {
+		c.DigestChats = splitAndTrim(v)
+	}
+	for _, p := range splitAndTrim(os.Getenv("DIGEST_PERIODS")) {
+		p = strings.ToLower(p)
+		if p != "daily" && p != "weekly" {
+			return Config{}, fmt.Errorf("invalid DIGEST_PERIODS value: %s (must be daily, weekly or both)", p)
+		}
+		c.DigestPeriods = append(c.DigestPeriods, p)
+	}
+	if v := strings.TrimSpace(os.Getenv("DIGEST_TIME")); v != "" {
+		if _, err := time.Parse("15:04", v); err != nil {
+			return Config{}, fmt.Errorf("invalid DIGEST_TIME value: %s (expected HH:MM)", v)
+		}
+		c.DigestTime = v
+	}
+	c.DigestTimezone = os.Getenv("DIGEST_TZ")
+	if c.DigestTimezone == "" {
+		c.DigestTimezone = "UTC"
+	}
+	if _, err := time.LoadLocation(c.DigestTimezone); err != nil {
+		return Config{}, fmt.Errorf("invalid DIGEST_TZ value: %s", c.DigestTimezone)
+	}
+	if v := os.Getenv("DIGEST_WEBHOOK_URL"); v != "" {
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return Config{}, fmt.Errorf("invalid DIGEST_WEBHOOK_URL value: %s", v)
+		}
+		c.DigestWebhookURL = v
+	}
+
+	if v := os.Getenv("FOLLOWUP_CHATS"); v != "" {
+		c.FollowupChats = splitAndTrim(v)
+	}
+	c.FollowupAfter = 24 * time.Hour
+	if v := os.Getenv("FOLLOWUP_AFTER"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Minute {
+			return Config{}, fmt.Errorf("invalid FOLLOWUP_AFTER value: %s (expected a duration of at least 1m)", v)
+		}
+		c.FollowupAfter = d
+	}
+	if v := os.Getenv("FOLLOWUP_WEBHOOK_URL"); v != "" {
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return Config{}, fmt.Errorf("invalid FOLLOWUP_WEBHOOK_URL value: %s", v)
+		}
+		c.FollowupWebhookURL = v
+	}
+
+	if v := os.Getenv("OUTBOX_MAX_AGE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return Config{}, fmt.Errorf("invalid OUTBOX_MAX_AGE value: %s", v)
+		}
+		c.OutboxMaxAge = d
+	}
+
+	if v := os.Getenv("OUTBOUND_ALLOWED_NETWORKS"); v != "" {
+		nets, err := netguard.ParseNetworks(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid OUTBOUND_ALLOWED_NETWORKS value: %w", err)
+		}
+		c.OutboundAllowedNetworks = nets
+	}
+
+	if v := os.Getenv("WEBHOOK_URL"); v != "" {
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return Config{}, fmt.Errorf("invalid WEBHOOK_URL value: %s", v)
+		}
+		c.WebhookURL = v
+	}
+	if v := os.Getenv("PUBLIC_URL"); v != "" {
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return Config{}, fmt.Errorf("invalid PUBLIC_URL value: %s", v)
+		}
+		c.PublicURL = strings.TrimRight(v, "/")
+	}
+	c.MediaURLTTL = 15 * time.Minute
+	if v := os.Getenv("MEDIA_URL_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Minute || d > 24*time.Hour {
+			return Config{}, fmt.Errorf("invalid MEDIA_URL_TTL value: %s (expected a duration between 1m and 24h)", v)
+		}
+		c.MediaURLTTL = d
+	}
+	c.MediaBlockedTypes = defaultMediaBlockedTypes
+	if v := strings.TrimSpace(os.Getenv("MEDIA_BLOCKED_TYPES")); v != "" {
+		c.MediaBlockedTypes = nil
+		if v != "none" {
+			for _, t := range splitAndTrim(strings.ToLower(v)) {
+				if kind, sub, ok := strings.Cut(t, "/"); !ok || kind == "" || sub == "" || kind == "*" {
+					return Config{}, fmt.Errorf("invalid MEDIA_BLOCKED_TYPES entry: %s (expected a MIME type or type/*)", t)
+				}
+				c.MediaBlockedTypes = append(c.MediaBlockedTypes, t)
+			}
+		}
+	}
+	if v := os.Getenv("MEDIA_TYPE_MISMATCH"); v != "" {
+		if v != "serve" && v != "reject" {
+			return Config{}, fmt.Errorf("invalid MEDIA_TYPE_MISMATCH value: %s (must be serve or reject)", v)
+		}
+		c.MediaRejectMismatch = v == "reject"
+	}
+	if v := os.Getenv("WEBHOOK_SECRET"); v != "" {
+		if len(v) < minWebhookSecretLen {
+			return Config{}, fmt.Errorf("invalid WEBHOOK_SECRET: must be at least %d characters", minWebhookSecretLen)
+		}
+		c.WebhookSecret = v
+	}
+
+	c.RecipesFile = strings.TrimSpace(os.Getenv("RECIPES_FILE"))
+	c.CloudAPITemplates = strings.TrimSpace(os.Getenv("CLOUD_API_TEMPLATES"))
+	c.SendMediaDir = strings.TrimSpace(os.Getenv("SEND_MEDIA_DIR"))
+	c.FFmpegPath = strings.TrimSpace(os.Getenv("FFMPEG_PATH"))
+
+	if v := os.Getenv("HOMEASSISTANT_MQTT_URL"); v != "" {
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "mqtt" && u.Scheme != "mqtts") || u.Host == "" {
+			return Config{}, fmt.Errorf("invalid HOMEASSISTANT_MQTT_URL value: %s", v)
+		}
+		c.HomeAssistantMQTTURL = v
+	}
+	for key, dst := range map[string]*string{
+		"HOMEASSISTANT_DISCOVERY_PREFIX": &c.HomeAssistantDiscoveryPrefix,
+		"HOMEASSISTANT_TOPIC":            &c.HomeAssistantTopic,
+	} {
+		v := strings.TrimSpace(os.Getenv(key))
+		if strings.ContainsAny(v, "+#") || strings.HasSuffix(v, "/") {
+			return Config{}, fmt.Errorf("invalid %s value: %s (must be a topic without wildcards or a trailing slash)", key, v)
+		}
+		*dst = v
+	}
+	if v := os.Getenv("HOMEASSISTANT_CHATS"); v != "" {
+		c.HomeAssistantChats = splitAndTrim(v)
+	}
+
+	// FEEDS is a comma-separated list of chat=url entries; a feed posted to
+	// several chats is listed once per chat
+	for _, entry := range splitAndTrim(os.Getenv("FEEDS")) {
+		chat, feedURL, ok := strings.Cut(entry, "=")
+		chat, feedURL = strings.TrimSpace(chat), strings.TrimSpace(feedURL)
+		u, err := url.Parse(feedURL)
+		if !ok || chat == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return Config{}, fmt.Errorf("invalid FEEDS entry: %s (must be chat=http(s) URL)", entry)
+		}
+		if c.Feeds == nil {
+			c.Feeds = make(map[string][]string)
+		}
+		c.Feeds[feedURL] = append(c.Feeds[feedURL], chat)
+	}
+	if v := os.Getenv("FEED_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Minute {
+			return Config{}, fmt.Errorf("invalid FEED_INTERVAL value: %s (must be at least 1m)", v)
+		}
+		c.FeedInterval = d
+	}
+
+	// EMAIL_ROUTES is a comma-separated list of address=chat entries
+	for _, entry := range splitAndTrim(os.Getenv("EMAIL_ROUTES")) {
+		addr, chat, ok := strings.Cut(entry, "=")
+		addr, chat = strings.ToLower(strings.TrimSpace(addr)), strings.TrimSpace(chat)
+		if !ok || (addr != "*" && !strings.Contains(addr, "@")) {
+			return Config{}, fmt.Errorf("invalid EMAIL_ROUTES entry: %s (must be address=chat)", entry)
+		}
+		if _, err := jid.ParseRecipient(chat); err != nil {
+			return Config{}, fmt.Errorf("invalid EMAIL_ROUTES entry: %s: %v", entry, err)
+		}
+		if c.EmailRoutes == nil {
+			c.EmailRoutes = make(map[string]string)
+		}
+		c.EmailRoutes[addr] = chat
+	}
+
+	if v := os.Getenv("CANARY_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || (d != 0 && d < time.Minute) {
+			return Config{}, fmt.Errorf("invalid CANARY_INTERVAL value: %s (must be 0 or at least 1m)", v)
+		}
+		c.CanaryInterval = d
+	}
+	if v := os.Getenv("CANARY_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid CANARY_TIMEOUT value: %s (must be a positive duration)", v)
+		}
+		c.CanaryTimeout = d
+	}
+
+	if v := os.Getenv("READY_STATES"); v != "" {
+		states, err := health.ParseStates(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid READY_STATES value: %v", err)
+		}
+		c.ReadyStates = states
 	}
 
 	return c, nil
 }
 
+// validKeyID reports whether id is usable as a key ID in URLs and metric labels.
+func validKeyID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultMediaBlockedTypes are the types browsers render as active content
+// or that run as programs.
+var defaultMediaBlockedTypes = []string{
+	"text/html", "application/xhtml+xml", "image/svg+xml", "text/xml", "application/xml",
+	"text/javascript", "application/javascript", "application/x-msdownload", "application/x-sh",
+}
+
 func splitAndTrim(s string) []string {
 	parts := strings.Split(s, ",")
 	var result []string
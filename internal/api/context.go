@@ -0,0 +1,16 @@
+package api
+
+import "net/http"
+
+// handleChatContext returns the latest messages of a chat cut to a token
+// budget, for agents assembling an LLM prompt.
+func (s *Server) handleChatContext(w http.ResponseWriter, r *http.Request) {
+	jid, ok := chatJIDPath(w, r)
+	if !ok {
+		return
+	}
+	// 0 lets the app pick its default budget
+	tokens := parseIntParam(r, "tokens", 0)
+	includeJIDs, excludeJIDs := s.phoneFilter.JIDSuffixes()
+	writeResult(w, s.app.ChatContext(jid, tokens, includeJIDs, excludeJIDs, s.computeAfter()))
+}
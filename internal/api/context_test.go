@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleChatContext(t *testing.T) {
+	mock := &mockApp{contextResult: `{"success":true,"data":{}}`}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/chats/1234567890/context?tokens=4000", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, mock.lastContext)
+	assert.Equal(t, "1234567890@s.whatsapp.net", mock.lastContext.chatJID)
+	assert.Equal(t, 4000, mock.lastContext.tokens)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats/120363012345678901@g.us/context", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 0, mock.lastContext.tokens)
+}
+
+func TestChatToken_ScopesContext(t *testing.T) {
+	mock := &mockApp{contextResult: `{"success":true,"data":{}}`}
+	srv := newTestServer(mock)
+	token := mintChatToken(t, srv, `{"chat_jid":"`+tokenGroup+`"}`)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/chats/"+tokenGroup+"/context", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, mock.lastContext)
+	assert.Equal(t, tokenGroup, mock.lastContext.chatJID)
+
+	mock.lastContext = nil
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats/34600111222/context", token, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Nil(t, mock.lastContext)
+}
@@ -0,0 +1,19 @@
+package api
+
+import "net/http"
+
+func (s *Server) handleListDigests(w http.ResponseWriter, r *http.Request) {
+	jid, ok := chatJIDPath(w, r)
+	if !ok {
+		return
+	}
+	if !s.phoneFilter.IsAllowed(jid) {
+		writeError(w, http.StatusForbidden, "chat not allowed")
+		return
+	}
+	limit := parseIntParam(r, "limit", 10)
+	if limit > s.Config.MaxMessages {
+		limit = s.Config.MaxMessages
+	}
+	writeResult(w, s.app.ListDigests(jid, r.URL.Query().Get("period"), limit))
+}
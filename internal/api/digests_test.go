@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleListDigests(t *testing.T) {
+	mock := &mockApp{digestsResult: `{"success":true,"data":[]}`}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/chats/120363012345678901@g.us/digests?period=weekly&limit=500", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "120363012345678901@g.us", mock.lastDigestsJID)
+	assert.Equal(t, "weekly", mock.lastDigestPeriod)
+	assert.Equal(t, 100, mock.lastLimit)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats/1234567890/digests", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1234567890@s.whatsapp.net", mock.lastDigestsJID)
+	assert.Empty(t, mock.lastDigestPeriod)
+	assert.Equal(t, 10, mock.lastLimit)
+}
+
+func TestHandleListDigests_FilteredChat(t *testing.T) {
+	mock := &mockApp{digestsResult: `{"success":true,"data":[]}`}
+	srv := NewServer(Config{APIKey: "test-key", MaxMessages: 100, PhoneBlacklist: []string{"111222"}}, mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/chats/111222/digests", "test-key", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, mock.lastDigestsJID)
+}
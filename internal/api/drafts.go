@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func (s *Server) handleGetDraft(w http.ResponseWriter, r *http.Request) {
+	jid, ok := chatJIDPath(w, r)
+	if !ok {
+		return
+	}
+	if !s.phoneFilter.IsAllowed(jid) {
+		writeError(w, http.StatusForbidden, "chat not allowed")
+		return
+	}
+	writeResult(w, s.app.GetDraft(jid))
+}
+
+func (s *Server) handleSetDraft(w http.ResponseWriter, r *http.Request) {
+	jid, ok := chatJIDPath(w, r)
+	if !ok {
+		return
+	}
+	if !s.phoneFilter.IsAllowed(jid) {
+		writeError(w, http.StatusForbidden, "chat not allowed")
+		return
+	}
+	var req struct {
+		Text *string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if req.Text == nil {
+		writeError(w, http.StatusBadRequest, "'text' field is required")
+		return
+	}
+	writeResult(w, s.app.SetDraft(jid, *req.Text))
+}
+
+func (s *Server) handleDeleteDraft(w http.ResponseWriter, r *http.Request) {
+	jid, ok := chatJIDPath(w, r)
+	if !ok {
+		return
+	}
+	if !s.phoneFilter.IsAllowed(jid) {
+		writeError(w, http.StatusForbidden, "chat not allowed")
+		return
+	}
+	writeResult(w, s.app.SetDraft(jid, ""))
+}
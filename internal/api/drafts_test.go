@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleDrafts(t *testing.T) {
+	mock := &mockApp{draftResult: `{"success":true}`}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodPut, "/api/v1/chats/1234567890/draft", "test-key", `{"text":"half a thought"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1234567890@s.whatsapp.net", mock.lastDraftJID)
+	require.NotNil(t, mock.lastDraftText)
+	assert.Equal(t, "half a thought", *mock.lastDraftText)
+
+	mock.lastDraftText = nil
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats/120363012345678901@g.us/draft", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "120363012345678901@g.us", mock.lastDraftJID)
+	assert.Nil(t, mock.lastDraftText)
+
+	w = doRequest(srv, http.MethodDelete, "/api/v1/chats/1234567890/draft", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, mock.lastDraftText)
+	assert.Empty(t, *mock.lastDraftText)
+}
+
+func TestHandleSetDraft_Validation(t *testing.T) {
+	mock := &mockApp{}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodPut, "/api/v1/chats/1234567890/draft", "test-key", `{`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(srv, http.MethodPut, "/api/v1/chats/1234567890/draft", "test-key", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Nil(t, mock.lastDraftText)
+}
+
+func TestHandleDrafts_FilteredChat(t *testing.T) {
+	mock := &mockApp{draftResult: `{"success":true}`}
+	srv := NewServer(Config{APIKey: "test-key", MaxMessages: 100, PhoneBlacklist: []string{"111222"}}, mock)
+
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		w := doRequest(srv, method, "/api/v1/chats/111222/draft", "test-key", `{"text":"overwritten"}`)
+		assert.Equal(t, http.StatusForbidden, w.Code, method)
+	}
+	assert.Empty(t, mock.lastDraftJID)
+	assert.Nil(t, mock.lastDraftText)
+}
@@ -0,0 +1,304 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+)
+
+// Inbound email bounds. SendGrid and Mailgun post at most 30 and 25 MB.
+const (
+	maxEmailBody = 40 << 20
+	// maxEmailTextLen is the longest email text forwarded, in characters.
+	maxEmailTextLen = 4096
+)
+
+// emailResult is the response to an inbound email.
+type emailResult struct {
+	Forwarded   bool              `json:"forwarded"`
+	Chats       []string          `json:"chats"`
+	Attachments int               `json:"attachments"`
+	Reason      string            `json:"reason,omitempty"`
+	Results     []emailChatResult `json:"results,omitempty"`
+}
+
+// emailChatResult is how far an email got to one of its chats.
+type emailChatResult struct {
+	Chat        string `json:"chat"`
+	Sent        bool   `json:"sent"`
+	Attachments int    `json:"attachments"`
+	Error       string `json:"error,omitempty"`
+}
+
+// handleInboundEmail forwards an email posted by SendGrid's Inbound Parse or
+// a Mailgun route to the chats its recipients are routed to: its subject,
+// sender and text as a message, then each attachment as media. An email
+// without a route is acknowledged and dropped, so the provider does not
+// retry it. Once any chat has the email, the response is a success listing
+// what reached each chat, since a retry would send it to them again.
+func (s *Server) handleInboundEmail(w http.ResponseWriter, r *http.Request) {
+	if len(s.Config.EmailRoutes) == 0 {
+		writeError(w, http.StatusNotFound, "email forwarding is not configured (set EMAIL_ROUTES)")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxEmailBody)
+	if err := r.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+		writeError(w, http.StatusBadRequest, "invalid form body")
+		return
+	}
+
+	chats := s.emailChats(r)
+	if len(chats) == 0 {
+		writeJSON(w, emailResult{Chats: []string{}, Reason: "no route for the recipients"})
+		return
+	}
+	for _, chat := range chats {
+		recipient, err := jid.ParseRecipient(chat)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !s.phoneFilter.IsAllowed(recipient) {
+			writeError(w, http.StatusForbidden, "recipient not allowed")
+			return
+		}
+	}
+	attachments, err := emailAttachments(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	text := emailText(r)
+	keyID := keyIDFromContext(r.Context())
+	results := make([]emailChatResult, 0, len(chats))
+	var firstError string
+	sent, complete := 0, 0
+	for _, chat := range chats {
+		res := emailChatResult{Chat: chat}
+		result := s.app.SendMessage(r.Context(), chat, text, client.SendOptions{})
+		if !resultSucceeded(result) {
+			res.Error = resultErrorMessage(result)
+			if firstError == "" {
+				firstError = res.Error
+			}
+			results = append(results, res)
+			continue
+		}
+		res.Sent = true
+		sent++
+		s.usage.recordSend(keyID)
+		for _, a := range attachments {
+			result := s.app.SendMedia(r.Context(), chat, a)
+			if !resultSucceeded(result) {
+				res.Error = resultErrorMessage(result)
+				break
+			}
+			res.Attachments++
+			s.usage.recordSend(keyID)
+		}
+		if res.Error == "" {
+			complete++
+		}
+		results = append(results, res)
+	}
+	if sent == 0 {
+		// Nothing went out; a 5xx makes the provider retry
+		writeError(w, http.StatusBadGateway, firstError)
+		return
+	}
+	out := emailResult{Forwarded: complete == len(chats), Chats: chats, Attachments: len(attachments), Results: results}
+	if !out.Forwarded {
+		out.Reason = "not every chat received the whole email"
+	}
+	writeJSON(w, out)
+}
+
+// resultErrorMessage returns the error of a failed App result.
+func resultErrorMessage(result string) string {
+	var env v1Envelope
+	if json.Unmarshal([]byte(result), &env) != nil || env.Error == nil {
+		return "send failed"
+	}
+	return *env.Error
+}
+
+// emailChats returns the chats routed from the recipients of an email: the
+// envelope recipients Mailgun ("recipient") and SendGrid ("envelope") give,
+// then the To and Cc headers. The "*" route is used if none matches.
+func (s *Server) emailChats(r *http.Request) []string {
+	addrs := emailAddresses(r.FormValue("recipient"))
+	var envelope struct {
+		To []string `json:"to"`
+	}
+	if json.Unmarshal([]byte(r.FormValue("envelope")), &envelope) == nil {
+		for _, to := range envelope.To {
+			addrs = append(addrs, emailAddresses(to)...)
+		}
+	}
+	addrs = append(addrs, emailAddresses(r.FormValue("to"))...)
+	addrs = append(addrs, emailAddresses(r.FormValue("cc"))...)
+
+	var chats []string
+	seen := make(map[string]bool)
+	for _, addr := range addrs {
+		if chat, ok := s.Config.EmailRoutes[addr]; ok && !seen[chat] {
+			seen[chat] = true
+			chats = append(chats, chat)
+		}
+	}
+	if chat, ok := s.Config.EmailRoutes["*"]; ok && len(chats) == 0 {
+		chats = append(chats, chat)
+	}
+	return chats
+}
+
+// emailAddresses returns the lower-cased addresses of an address list.
+func emailAddresses(list string) []string {
+	if strings.TrimSpace(list) == "" {
+		return nil
+	}
+	var addrs []string
+	if parsed, err := mail.ParseAddressList(list); err == nil {
+		for _, a := range parsed {
+			addrs = append(addrs, strings.ToLower(a.Address))
+		}
+		return addrs
+	}
+	for _, a := range strings.Split(list, ",") {
+		if a = strings.ToLower(strings.Trim(strings.TrimSpace(a), "<>")); a != "" {
+			addrs = append(addrs, a)
+		}
+	}
+	return addrs
+}
+
+var headerDecoder = new(mime.WordDecoder)
+
+// emailText is the message an email is forwarded as: its bold subject, its
+// sender and its text. Mailgun's text without quoted replies is preferred,
+// and HTML-only emails are reduced to text.
+func emailText(r *http.Request) string {
+	subject := r.FormValue("subject")
+	if decoded, err := headerDecoder.DecodeHeader(subject); err == nil {
+		subject = decoded
+	}
+	from := strings.TrimSpace(r.FormValue("from"))
+	if addr, err := mail.ParseAddress(from); err == nil {
+		from = addr.Address
+		if addr.Name != "" {
+			from = addr.Name + " <" + addr.Address + ">"
+		}
+	}
+	body := firstNonBlank(r.FormValue("stripped-text"), r.FormValue("body-plain"), r.FormValue("text"))
+	if body == "" {
+		body = htmlToText(firstNonBlank(r.FormValue("stripped-html"), r.FormValue("body-html"), r.FormValue("html")))
+	}
+	body = strings.TrimSpace(strings.ReplaceAll(body, "\r\n", "\n"))
+	if runes := []rune(body); len(runes) > maxEmailTextLen {
+		body = strings.TrimSpace(string(runes[:maxEmailTextLen])) + "…"
+	}
+
+	var b strings.Builder
+	if subject = strings.TrimSpace(subject); subject != "" {
+		b.WriteString("*" + subject + "*\n")
+	}
+	if from != "" {
+		b.WriteString("From: " + from + "\n")
+	}
+	if body != "" {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(body)
+	}
+	if b.Len() == 0 {
+		return "(empty email)"
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+var (
+	htmlBreak  = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|tr|h[1-6])>`)
+	htmlTag    = regexp.MustCompile(`(?s)<[^>]*>`)
+	htmlHidden = regexp.MustCompile(`(?is)<(style|script|head)[^>]*>.*?</(style|script|head)>`)
+	blankLines = regexp.MustCompile(`\n[ \t]*\n(\s*\n)+`)
+	spaceRuns  = regexp.MustCompile(`[ \t]+`)
+)
+
+// htmlToText reduces an HTML email to its text, keeping paragraphs apart.
+func htmlToText(s string) string {
+	s = htmlHidden.ReplaceAllString(s, "")
+	s = htmlBreak.ReplaceAllString(s, "\n")
+	s = htmlTag.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = spaceRuns.ReplaceAllString(s, " ")
+	return blankLines.ReplaceAllString(s, "\n\n")
+}
+
+// emailAttachments reads the files of an email: "attachment1", … from
+// SendGrid and "attachment-1", … from Mailgun, in order.
+func emailAttachments(r *http.Request) ([]client.OutgoingMedia, error) {
+	if r.MultipartForm == nil {
+		return nil, nil
+	}
+	var fields []string
+	for field := range r.MultipartForm.File {
+		if strings.HasPrefix(field, "attachment") {
+			fields = append(fields, field)
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return attachmentIndex(fields[i]) < attachmentIndex(fields[j]) })
+
+	var attachments []client.OutgoingMedia
+	for _, field := range fields {
+		for _, fh := range r.MultipartForm.File[field] {
+			f, err := fh.Open()
+			if err != nil {
+				return nil, fmt.Errorf("reading %s: %v", field, err)
+			}
+			data, err := io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				return nil, fmt.Errorf("reading %s: %v", field, err)
+			}
+			if len(data) == 0 {
+				continue
+			}
+			mimeType := mediaMIMEType(fh.Header.Get("Content-Type"), fh.Filename, data)
+			attachments = append(attachments, client.OutgoingMedia{
+				Type:     client.MediaTypeForMIME(mimeType),
+				Data:     data,
+				MimeType: mimeType,
+				Filename: fh.Filename,
+			})
+		}
+	}
+	return attachments, nil
+}
+
+// attachmentIndex is the number ending an attachment field name.
+func attachmentIndex(field string) int {
+	n, _ := strconv.Atoi(strings.TrimLeft(strings.TrimPrefix(field, "attachment"), "-"))
+	return n
+}
+
+func firstNonBlank(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEmailServer(mock *mockApp) *Server {
+	return NewServer(Config{
+		APIKey: "test-key",
+		EmailRoutes: map[string]string{
+			"support@in.example.com": "34600111222",
+			"*":                      "120363012345678901@g.us",
+		},
+	}, mock)
+}
+
+func TestHandleInboundEmail_SendGrid(t *testing.T) {
+	mock := &mockApp{sendMessageResult: `{"success":true,"data":{"sent":true},"error":null}`}
+	srv := newEmailServer(mock)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("from", `"Alice Doe" <alice@example.com>`)
+	mw.WriteField("to", "Support <support@in.example.com>")
+	mw.WriteField("envelope", `{"to":["support@in.example.com"],"from":"alice@example.com"}`)
+	mw.WriteField("subject", "=?UTF-8?Q?Order_n=C2=BA_42?=")
+	mw.WriteField("text", "Hello,\r\nthe parcel never arrived.\r\n")
+	mw.WriteField("html", "<p>ignored</p>")
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="attachment2"; filename="invoice.pdf"`)
+	h.Set("Content-Type", "application/pdf")
+	part, _ := mw.CreatePart(h)
+	part.Write([]byte("%PDF-1.4"))
+	h = make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="attachment1"; filename="photo.jpg"`)
+	h.Set("Content-Type", "image/jpeg")
+	part, _ = mw.CreatePart(h)
+	part.Write([]byte("\xff\xd8\xff\xe0"))
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/inbound/email", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.SetBasicAuth("sendgrid", "test-key")
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Data emailResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, emailResult{
+		Forwarded:   true,
+		Chats:       []string{"34600111222"},
+		Attachments: 2,
+		Results:     []emailChatResult{{Chat: "34600111222", Sent: true, Attachments: 2}},
+	}, resp.Data)
+
+	assert.Equal(t, "34600111222", mock.lastSendRecipient)
+	assert.Equal(t, []string{"*Order nº 42*\nFrom: Alice Doe <alice@example.com>\n\nHello,\nthe parcel never arrived."}, mock.sentMessages)
+	require.Len(t, mock.sentMedia, 2)
+	assert.Equal(t, "photo.jpg", mock.sentMedia[0].Filename)
+	assert.Equal(t, "image", mock.sentMedia[0].Type)
+	assert.Equal(t, "invoice.pdf", mock.sentMedia[1].Filename)
+	assert.Equal(t, "document", mock.sentMedia[1].Type)
+	assert.Equal(t, []byte("%PDF-1.4"), mock.sentMedia[1].Data)
+}
+
+func TestHandleInboundEmail_Mailgun(t *testing.T) {
+	mock := &mockApp{sendMessageResult: `{"success":true,"data":{"sent":true},"error":null}`}
+	srv := newEmailServer(mock)
+
+	// Without attachments, Mailgun posts a URL-encoded form; the catch-all
+	// route takes addresses without their own
+	form := url.Values{
+		"recipient":     {"sales@in.example.com"},
+		"from":          {"bob@example.com"},
+		"subject":       {"Quote"},
+		"body-plain":    {"Hi\n\n> quoted reply"},
+		"stripped-text": {"Hi"},
+	}
+	w := doEmailRequest(srv, form.Encode(), "application/x-www-form-urlencoded")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "120363012345678901@g.us", mock.lastSendRecipient)
+	assert.Equal(t, []string{"*Quote*\nFrom: bob@example.com\n\nHi"}, mock.sentMessages)
+	assert.Empty(t, mock.sentMedia)
+}
+
+func TestHandleInboundEmail_HTMLOnly(t *testing.T) {
+	mock := &mockApp{sendMessageResult: `{"success":true,"data":{"sent":true},"error":null}`}
+	srv := newEmailServer(mock)
+
+	form := url.Values{
+		"to":   {"support@in.example.com"},
+		"html": {"<html><head><style>p{}</style></head><body><p>First &amp; foremost</p><p>Second<br>line</p></body></html>"},
+	}
+	w := doEmailRequest(srv, form.Encode(), "application/x-www-form-urlencoded")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"First & foremost\nSecond\nline"}, mock.sentMessages)
+}
+
+func TestHandleInboundEmail_PartialFailure(t *testing.T) {
+	mock := &mockApp{
+		sendMessageResult: `{"success":true,"data":{"sent":true},"error":null}`,
+		failRecipients:    map[string]bool{"120363012345678901@g.us": true},
+	}
+	srv := NewServer(Config{APIKey: "test-key", EmailRoutes: map[string]string{
+		"support@in.example.com": "34600111222",
+		"team@in.example.com":    "120363012345678901@g.us",
+	}}, mock)
+
+	// One chat got it, so a retry would duplicate it there: report per chat
+	form := url.Values{"to": {"support@in.example.com, team@in.example.com"}, "text": {"hi"}}
+	w := doEmailRequest(srv, form.Encode(), "application/x-www-form-urlencoded")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Data emailResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.False(t, resp.Data.Forwarded)
+	assert.Equal(t, []emailChatResult{
+		{Chat: "34600111222", Sent: true},
+		{Chat: "120363012345678901@g.us", Error: "not connected"},
+	}, resp.Data.Results)
+
+	// Nothing went out: fail, so the provider retries
+	mock.failRecipients["34600111222"] = true
+	w = doEmailRequest(srv, form.Encode(), "application/x-www-form-urlencoded")
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), "not connected")
+}
+
+func TestHandleInboundEmail_Unrouted(t *testing.T) {
+	mock := &mockApp{sendMessageResult: `{"success":true,"data":{"sent":true},"error":null}`}
+	srv := NewServer(Config{APIKey: "test-key", EmailRoutes: map[string]string{"support@in.example.com": "34600111222"}}, mock)
+
+	w := doEmailRequest(srv, url.Values{"to": {"other@in.example.com"}, "text": {"hi"}}.Encode(), "application/x-www-form-urlencoded")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"forwarded":false`)
+	assert.Empty(t, mock.sentMessages)
+
+	srv = newTestServer(mock)
+	w = doEmailRequest(srv, "", "application/x-www-form-urlencoded")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandleInboundEmail_PhoneFilter(t *testing.T) {
+	mock := &mockApp{sendMessageResult: `{"success":true,"data":{"sent":true},"error":null}`}
+	srv := NewServer(Config{
+		APIKey:         "test-key",
+		PhoneBlacklist: []string{"34600111222"},
+		EmailRoutes:    map[string]string{"*": "34600111222"},
+	}, mock)
+
+	w := doEmailRequest(srv, url.Values{"to": {"x@in.example.com"}, "text": {"hi"}}.Encode(), "application/x-www-form-urlencoded")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, mock.sentMessages)
+}
+
+func doEmailRequest(srv *Server, body, contentType string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/inbound/email", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+	return w
+}
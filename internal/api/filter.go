@@ -1,12 +1,15 @@
 package api
 
-import "strings"
+import "github.com/vicentereig/whatsapp-cli/internal/jid"
 
 // PhoneFilter enforces phone number whitelist/blacklist rules on JIDs.
 // Matching uses the last 6 digits of the phone portion (before the @ sign).
+// Linked identifiers (@lid) carry no phone number; they are matched on the
+// phone-number JID returned by the resolver set with SetLIDResolver.
 type PhoneFilter struct {
-	whitelist []string
-	blacklist []string
+	whitelist  []string
+	blacklist  []string
+	resolveLID func(jid string) string
 }
 
 // NewPhoneFilter creates a PhoneFilter from config whitelist/blacklist entries.
@@ -17,18 +20,39 @@ func NewPhoneFilter(whitelist, blacklist []string) *PhoneFilter {
 	}
 }
 
+// SetLIDResolver sets the function mapping a @lid JID to its phone-number
+// JID; it returns the JID unchanged when the mapping is unknown.
+func (f *PhoneFilter) SetLIDResolver(resolve func(jid string) string) {
+	f.resolveLID = resolve
+}
+
 // IsAllowed returns true if the JID passes the filter rules.
 // Group JIDs (@g.us) always pass.
 // If whitelist is non-empty, only matching JIDs are allowed (blacklist ignored).
 // If only blacklist is set, matching JIDs are blocked.
 // If neither is set, all JIDs are allowed.
-func (f *PhoneFilter) IsAllowed(jid string) bool {
+// A @lid JID whose phone number is unknown fails a whitelist and passes a
+// blacklist, as it cannot match either.
+func (f *PhoneFilter) IsAllowed(chatJID string) bool {
 	// Group JIDs always pass
-	if strings.HasSuffix(jid, "@g.us") {
+	if jid.IsGroup(chatJID) {
+		return true
+	}
+
+	if len(f.whitelist) == 0 && len(f.blacklist) == 0 {
 		return true
 	}
 
-	suffix := extractSuffix(jid)
+	if jid.IsLID(chatJID) {
+		if f.resolveLID != nil {
+			chatJID = f.resolveLID(chatJID)
+		}
+		if jid.IsLID(chatJID) {
+			return len(f.whitelist) == 0
+		}
+	}
+
+	suffix := extractSuffix(chatJID)
 
 	if len(f.whitelist) > 0 {
 		return matchesAny(suffix, f.whitelist)
@@ -41,13 +65,15 @@ func (f *PhoneFilter) IsAllowed(jid string) bool {
 	return true
 }
 
+// Filtering reports whether any rule is set; without one every JID passes.
+func (f *PhoneFilter) Filtering() bool {
+	return len(f.whitelist) > 0 || len(f.blacklist) > 0
+}
+
 // extractSuffix returns the last 6 digits of the phone portion of a JID.
-// For "1234567890@s.whatsapp.net", it returns "567890".
-func extractSuffix(jid string) string {
-	phone := jid
-	if at := strings.Index(jid, "@"); at >= 0 {
-		phone = jid[:at]
-	}
+// For "1234567890:12@s.whatsapp.net", it returns "567890".
+func extractSuffix(chatJID string) string {
+	phone := jid.User(chatJID)
 
 	if len(phone) > 6 {
 		return phone[len(phone)-6:]
@@ -124,3 +124,25 @@ func TestPhoneFilter_JIDSuffixes_Multiple(t *testing.T) {
 	// Blacklist entries
 	assert.Equal(t, []string{"543210@"}, exclude)
 }
+
+func TestPhoneFilter_LIDs(t *testing.T) {
+	lids := map[string]string{"98765432109876@lid": "1234567890@s.whatsapp.net"}
+	resolve := func(jid string) string {
+		if pn, ok := lids[jid]; ok {
+			return pn
+		}
+		return jid
+	}
+
+	f := NewPhoneFilter([]string{"1234567890"}, nil)
+	f.SetLIDResolver(resolve)
+	// Known LID is matched on its phone number
+	assert.True(t, f.IsAllowed("98765432109876@lid"))
+	// Unknown LID cannot be shown to be whitelisted, even if its digits match
+	assert.False(t, f.IsAllowed("11111234567890@lid"))
+
+	f2 := NewPhoneFilter(nil, []string{"1234567890"})
+	f2.SetLIDResolver(resolve)
+	assert.False(t, f2.IsAllowed("98765432109876@lid"))
+	assert.True(t, f2.IsAllowed("11111234567890@lid"))
+}
@@ -0,0 +1,18 @@
+package api
+
+import "net/http"
+
+// handleListFollowups lists the chats left waiting for a reply longer than
+// FOLLOWUP_AFTER, longest waiting first.
+func (s *Server) handleListFollowups(w http.ResponseWriter, r *http.Request) {
+	if len(s.Config.FollowupChats) == 0 {
+		writeError(w, http.StatusNotImplemented, "follow-ups are not enabled (set FOLLOWUP_CHATS)")
+		return
+	}
+	limit := parseIntParam(r, "limit", 20)
+	if limit > s.Config.MaxMessages {
+		limit = s.Config.MaxMessages
+	}
+	includeJIDs, excludeJIDs := s.phoneFilter.JIDSuffixes()
+	writeResult(w, s.app.ListFollowups(limit, includeJIDs, excludeJIDs))
+}
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleListFollowups(t *testing.T) {
+	mock := &mockApp{followupsResult: `{"success":true,"data":[{"chat_jid":"34600111222@s.whatsapp.net","unanswered":2}]}`}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/followups", "test-key", "")
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+	assert.Contains(t, w.Body.String(), "FOLLOWUP_CHATS")
+
+	srv = NewServer(Config{APIKey: "test-key", MaxMessages: 100, FollowupChats: []string{"*"}}, mock)
+	w = doRequest(srv, http.MethodGet, "/api/v1/followups?limit=500", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"unanswered":2`)
+	assert.Equal(t, 100, mock.lastLimit)
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+func (s *Server) handleInteractionGraph(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	params := store.GraphParams{
+		Bucket:    q.Get("bucket"),
+		MinWeight: parseIntParam(r, "min_weight", 1),
+		After:     s.computeAfter(),
+	}
+	if params.Bucket == "" {
+		params.Bucket = store.GraphBucketMonth
+	}
+	if v := q.Get("chat_jid"); v != "" {
+		params.ChatJID = &v
+	}
+	if v := q.Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid window: use a duration like 10m")
+			return
+		}
+		params.Window = d
+	}
+	if v := q.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid since: use RFC 3339, e.g. 2026-01-02T15:04:05Z")
+			return
+		}
+		if params.After == nil || t.After(*params.After) {
+			params.After = &t
+		}
+	}
+	format := q.Get("format")
+	if format != "" && format != "json" && format != "dot" {
+		writeError(w, http.StatusBadRequest, "invalid format: must be json or dot")
+		return
+	}
+	switch params.Bucket {
+	case store.GraphBucketDay, store.GraphBucketWeek, store.GraphBucketMonth:
+	default:
+		writeError(w, http.StatusBadRequest, "invalid bucket: must be day, week or month")
+		return
+	}
+	params.IncludeJIDs, params.ExcludeJIDs = s.phoneFilter.JIDSuffixes()
+
+	g, err := s.app.InteractionGraph(params)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if format == "dot" {
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		w.Write([]byte(g.DOT()))
+		return
+	}
+	writeJSON(w, g)
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+func TestHandleInteractionGraph(t *testing.T) {
+	mock := &mockApp{graphResult: &store.Graph{
+		Bucket: "week",
+		Nodes:  []store.GraphNode{{ID: "111@s.whatsapp.net", Name: "Alice", Messages: 3, Groups: 1}, {ID: "me", Messages: 2, Groups: 1}},
+		Edges:  []store.GraphEdge{{From: "me", To: "111@s.whatsapp.net", Weight: 2}},
+	}}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/stats/graph?chat_jid=120363012345678901@g.us&bucket=week&window=5m&min_weight=2&since=2026-01-02T00:00:00Z", "test-key", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"from":"me"`)
+	require.NotNil(t, mock.lastGraph)
+	assert.Equal(t, "120363012345678901@g.us", *mock.lastGraph.ChatJID)
+	assert.Equal(t, "week", mock.lastGraph.Bucket)
+	assert.Equal(t, 5*time.Minute, mock.lastGraph.Window)
+	assert.Equal(t, 2, mock.lastGraph.MinWeight)
+	require.NotNil(t, mock.lastGraph.After)
+	assert.Equal(t, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), *mock.lastGraph.After)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/stats/graph?format=dot", "test-key", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/vnd.graphviz; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `"me" -> "111@s.whatsapp.net"`)
+	assert.Equal(t, "month", mock.lastGraph.Bucket)
+	assert.Nil(t, mock.lastGraph.ChatJID)
+}
+
+func TestHandleInteractionGraph_InvalidParams(t *testing.T) {
+	srv := newTestServer(&mockApp{})
+	for _, q := range []string{"bucket=year", "window=soon", "window=-1m", "since=yesterday", "format=svg"} {
+		w := doRequest(srv, http.MethodGet, "/api/v1/stats/graph?"+q, "test-key", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, q)
+	}
+}
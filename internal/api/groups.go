@@ -0,0 +1,208 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+)
+
+type updateGroupRequest struct {
+	Subject     *string `json:"subject"`
+	Description *string `json:"description"`
+	Announce    *bool   `json:"announce"`
+	Locked      *bool   `json:"locked"`
+}
+
+func (s *Server) handleUpdateGroup(w http.ResponseWriter, r *http.Request) {
+	groupJID, ok := groupJIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req updateGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if req.Subject == nil && req.Description == nil && req.Announce == nil && req.Locked == nil {
+		writeError(w, http.StatusBadRequest, "at least one of 'subject', 'description', 'announce' or 'locked' is required")
+		return
+	}
+	if req.Subject != nil && strings.TrimSpace(*req.Subject) == "" {
+		writeError(w, http.StatusBadRequest, "'subject' cannot be empty")
+		return
+	}
+
+	result := s.app.UpdateGroup(r.Context(), groupJID, req.Subject, req.Description, req.Announce, req.Locked)
+	writeResult(w, result)
+}
+
+type createGroupRequest struct {
+	Subject      string   `json:"subject"`
+	Participants []string `json:"participants"`
+}
+
+// handleCreateGroup creates a group with the account as its admin and
+// returns its JID with the outcome for each participant.
+func (s *Server) handleCreateGroup(w http.ResponseWriter, r *http.Request) {
+	var req createGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if strings.TrimSpace(req.Subject) == "" {
+		writeError(w, http.StatusBadRequest, "'subject' is required")
+		return
+	}
+	participants, ok := participantsParam(w, req.Participants)
+	if !ok {
+		return
+	}
+	if !s.participantsAllowed(w, participants) {
+		return
+	}
+
+	result := s.app.CreateGroup(r.Context(), req.Subject, participants)
+	writeResult(w, result)
+}
+
+type groupParticipantsRequest struct {
+	Participants []string `json:"participants"`
+	Action       string   `json:"action"`
+}
+
+// handleUpdateGroupParticipants adds participants to a group, or promotes
+// or demotes them with "action": "promote" or "demote".
+func (s *Server) handleUpdateGroupParticipants(w http.ResponseWriter, r *http.Request) {
+	s.changeGroupParticipants(w, r, false)
+}
+
+// handleRemoveGroupParticipants removes participants from a group.
+func (s *Server) handleRemoveGroupParticipants(w http.ResponseWriter, r *http.Request) {
+	s.changeGroupParticipants(w, r, true)
+}
+
+func (s *Server) changeGroupParticipants(w http.ResponseWriter, r *http.Request, remove bool) {
+	groupJID, ok := groupJIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req groupParticipantsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	action := req.Action
+	switch {
+	case remove:
+		action = "remove"
+	case action == "":
+		action = "add"
+	case action != "add" && action != "promote" && action != "demote":
+		writeError(w, http.StatusBadRequest, "'action' must be add, promote or demote")
+		return
+	}
+	if len(req.Participants) == 0 {
+		writeError(w, http.StatusBadRequest, "'participants' is required")
+		return
+	}
+	participants, ok := participantsParam(w, req.Participants)
+	if !ok {
+		return
+	}
+	if action == "add" && !s.participantsAllowed(w, participants) {
+		return
+	}
+
+	result := s.app.UpdateGroupParticipants(r.Context(), groupJID, participants, action)
+	writeResult(w, result)
+}
+
+type groupJoinRequestsRequest struct {
+	Participants []string `json:"participants"`
+}
+
+func (s *Server) handleListGroupJoinRequests(w http.ResponseWriter, r *http.Request) {
+	groupJID, ok := groupJIDParam(w, r)
+	if !ok {
+		return
+	}
+	writeResult(w, s.app.ListGroupJoinRequests(r.Context(), groupJID))
+}
+
+func (s *Server) handleApproveGroupJoinRequests(w http.ResponseWriter, r *http.Request) {
+	s.handleUpdateGroupJoinRequests(w, r, true)
+}
+
+func (s *Server) handleRejectGroupJoinRequests(w http.ResponseWriter, r *http.Request) {
+	s.handleUpdateGroupJoinRequests(w, r, false)
+}
+
+func (s *Server) handleUpdateGroupJoinRequests(w http.ResponseWriter, r *http.Request, approve bool) {
+	groupJID, ok := groupJIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req groupJoinRequestsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if len(req.Participants) == 0 {
+		writeError(w, http.StatusBadRequest, "'participants' is required")
+		return
+	}
+	participants, ok := participantsParam(w, req.Participants)
+	if !ok {
+		return
+	}
+
+	result := s.app.UpdateGroupJoinRequests(r.Context(), groupJID, participants, approve)
+	writeResult(w, result)
+}
+
+// participantsParam normalizes the JIDs or phone numbers of a participants
+// list. It writes a 400 response and returns false if one is empty.
+func participantsParam(w http.ResponseWriter, list []string) ([]string, bool) {
+	participants := make([]string, len(list))
+	for i, p := range list {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			writeError(w, http.StatusBadRequest, "'participants' cannot contain empty values")
+			return nil, false
+		}
+		participants[i] = jid.Normalize(p)
+	}
+	return participants, true
+}
+
+// participantsAllowed checks participants being added to a group against
+// the phone whitelist/blacklist. It writes a 403 response and returns false
+// for the first one that is not allowed.
+func (s *Server) participantsAllowed(w http.ResponseWriter, participants []string) bool {
+	for _, p := range participants {
+		if !s.phoneFilter.IsAllowed(p) {
+			writeError(w, http.StatusForbidden, "participant not allowed: "+p)
+			return false
+		}
+	}
+	return true
+}
+
+// groupJIDParam reads the {jid} path value, appending @g.us to bare group IDs.
+// It writes a 400 response and returns false if the value is not a group JID.
+func groupJIDParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	jid := r.PathValue("jid")
+	if jid != "" && !strings.Contains(jid, "@") {
+		jid += "@g.us"
+	}
+	if !strings.HasSuffix(jid, "@g.us") || len(jid) == len("@g.us") {
+		writeError(w, http.StatusBadRequest, "a group JID (…@g.us) is required")
+		return "", false
+	}
+	return jid, true
+}
@@ -0,0 +1,290 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleUpdateGroup_Success(t *testing.T) {
+	mock := &mockApp{updateGroupResult: `{"success":true,"data":{"jid":"123@g.us"}}`}
+	srv := newTestServer(mock)
+
+	body := `{"subject":"New subject","announce":true}`
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/groups/123@g.us", strings.NewReader(body))
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"success":true,"data":{"jid":"123@g.us"}}`, w.Body.String())
+	assert.True(t, mock.updateGroupCalled)
+	assert.Equal(t, "123@g.us", mock.lastGroupJID)
+	require.NotNil(t, mock.lastGroupSubject)
+	assert.Equal(t, "New subject", *mock.lastGroupSubject)
+	require.NotNil(t, mock.lastGroupAnnounce)
+	assert.True(t, *mock.lastGroupAnnounce)
+	assert.Nil(t, mock.lastGroupDesc)
+	assert.Nil(t, mock.lastGroupLocked)
+}
+
+func TestHandleUpdateGroup_BareGroupID(t *testing.T) {
+	mock := &mockApp{updateGroupResult: `{"success":true}`}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/groups/123", strings.NewReader(`{"locked":false}`))
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "123@g.us", mock.lastGroupJID)
+	require.NotNil(t, mock.lastGroupLocked)
+	assert.False(t, *mock.lastGroupLocked)
+}
+
+func TestHandleUpdateGroup_RejectsNonGroupJID(t *testing.T) {
+	mock := &mockApp{}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/groups/123@s.whatsapp.net", strings.NewReader(`{"subject":"x"}`))
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.False(t, mock.updateGroupCalled)
+}
+
+func TestHandleUpdateGroup_RequiresAChange(t *testing.T) {
+	mock := &mockApp{}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/groups/123@g.us", strings.NewReader(`{}`))
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "at least one of")
+	assert.False(t, mock.updateGroupCalled)
+}
+
+func TestHandleUpdateGroup_EmptySubject(t *testing.T) {
+	mock := &mockApp{}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/groups/123@g.us", strings.NewReader(`{"subject":"  "}`))
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.False(t, mock.updateGroupCalled)
+}
+
+func TestHandleUpdateGroup_InvalidJSON(t *testing.T) {
+	mock := &mockApp{}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/groups/123@g.us", strings.NewReader(`{`))
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "invalid JSON body")
+}
+
+func TestHandleUpdateGroup_RequiresAuth(t *testing.T) {
+	mock := &mockApp{}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/groups/123@g.us", strings.NewReader(`{"subject":"x"}`))
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.False(t, mock.updateGroupCalled)
+}
+
+func TestHandleCreateGroup(t *testing.T) {
+	mock := &mockApp{createGroupResult: `{"success":true,"data":{"group":{"jid":"120363000000000001@g.us"}}}`}
+	srv := newTestServer(mock)
+
+	body := `{"subject":"Onboarding","participants":["+34 600 111 222","34600333444@c.us"]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/groups", strings.NewReader(body))
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "120363000000000001@g.us")
+	assert.True(t, mock.createGroupCalled)
+	assert.Equal(t, "Onboarding", mock.lastCreateSubject)
+	assert.Equal(t, []string{"34600111222@s.whatsapp.net", "34600333444@s.whatsapp.net"}, mock.lastCreateParticipants)
+}
+
+func TestHandleCreateGroup_Validation(t *testing.T) {
+	for name, body := range map[string]string{
+		"invalid JSON":      `{`,
+		"missing subject":   `{"participants":["34600111222"]}`,
+		"blank subject":     `{"subject":" "}`,
+		"empty participant": `{"subject":"x","participants":[""]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			mock := &mockApp{}
+			srv := newTestServer(mock)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/groups", strings.NewReader(body))
+			req.Header.Set("X-API-Key", "test-key")
+			w := httptest.NewRecorder()
+			srv.mux.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.False(t, mock.createGroupCalled)
+		})
+	}
+}
+
+func TestHandleCreateGroup_BlockedParticipant(t *testing.T) {
+	mock := &mockApp{}
+	srv := NewServer(Config{APIKey: "test-key", PhoneBlacklist: []string{"34600111222"}}, mock)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/groups", strings.NewReader(`{"subject":"x","participants":["34600111222"]}`))
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.False(t, mock.createGroupCalled)
+}
+
+func TestHandleListGroupJoinRequests(t *testing.T) {
+	mock := &mockApp{joinRequestsResult: `{"success":true,"data":{"requests":[]}}`}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/groups/123/requests", nil)
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "123@g.us", mock.lastGroupJID)
+	assert.Equal(t, `{"success":true,"data":{"requests":[]}}`, w.Body.String())
+}
+
+func TestHandleApproveGroupJoinRequests(t *testing.T) {
+	mock := &mockApp{updateJoinRequestsResult: `{"success":true}`}
+	srv := newTestServer(mock)
+
+	body := `{"participants":["111"," 222@s.whatsapp.net"]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/groups/123@g.us/requests/approve", strings.NewReader(body))
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, mock.updateJoinRequestsCalled)
+	assert.True(t, mock.lastJoinApprove)
+	assert.Equal(t, []string{"111@s.whatsapp.net", "222@s.whatsapp.net"}, mock.lastJoinParticipants)
+}
+
+func TestHandleRejectGroupJoinRequests(t *testing.T) {
+	mock := &mockApp{updateJoinRequestsResult: `{"success":true}`}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/groups/123@g.us/requests/reject", strings.NewReader(`{"participants":["111"]}`))
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, mock.lastJoinApprove)
+}
+
+func TestHandleUpdateGroupJoinRequests_RequiresParticipants(t *testing.T) {
+	mock := &mockApp{}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/groups/123@g.us/requests/approve", strings.NewReader(`{"participants":[]}`))
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.False(t, mock.updateJoinRequestsCalled)
+}
+
+func TestHandleGroupParticipants(t *testing.T) {
+	for _, tc := range []struct {
+		method, body, action string
+	}{
+		{http.MethodPost, `{"participants":["34600111222"]}`, "add"},
+		{http.MethodPost, `{"participants":["34600111222"],"action":"promote"}`, "promote"},
+		{http.MethodPost, `{"participants":["34600111222"],"action":"demote"}`, "demote"},
+		{http.MethodDelete, `{"participants":["34600111222"]}`, "remove"},
+	} {
+		t.Run(tc.action, func(t *testing.T) {
+			mock := &mockApp{participantsResult: `{"success":true}`}
+			srv := newTestServer(mock)
+
+			req := httptest.NewRequest(tc.method, "/api/v1/groups/123/participants", strings.NewReader(tc.body))
+			req.Header.Set("X-API-Key", "test-key")
+			w := httptest.NewRecorder()
+			srv.mux.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.True(t, mock.participantsCalled)
+			assert.Equal(t, "123@g.us", mock.lastGroupJID)
+			assert.Equal(t, []string{"34600111222@s.whatsapp.net"}, mock.lastParticipants)
+			assert.Equal(t, tc.action, mock.lastParticipantsAction)
+		})
+	}
+}
+
+func TestHandleGroupParticipants_Validation(t *testing.T) {
+	for name, body := range map[string]string{
+		"invalid JSON":      `{`,
+		"no participants":   `{"participants":[]}`,
+		"empty participant": `{"participants":[" "]}`,
+		"unknown action":    `{"participants":["34600111222"],"action":"remove"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			mock := &mockApp{}
+			srv := newTestServer(mock)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/groups/123@g.us/participants", strings.NewReader(body))
+			req.Header.Set("X-API-Key", "test-key")
+			w := httptest.NewRecorder()
+			srv.mux.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.False(t, mock.participantsCalled)
+		})
+	}
+}
+
+func TestHandleGroupParticipants_BlockedOnlyWhenAdding(t *testing.T) {
+	mock := &mockApp{participantsResult: `{"success":true}`}
+	srv := NewServer(Config{APIKey: "test-key", PhoneBlacklist: []string{"34600111222"}}, mock)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/groups/123@g.us/participants", strings.NewReader(`{"participants":["34600111222"]}`))
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.False(t, mock.participantsCalled)
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/groups/123@g.us/participants", strings.NewReader(`{"participants":["34600111222"]}`))
+	req.Header.Set("X-API-Key", "test-key")
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, mock.participantsCalled)
+}
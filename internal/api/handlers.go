@@ -2,10 +2,17 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"io/fs"
+	"mime"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/reqid"
 )
 
 func (s *Server) handleListMessages(w http.ResponseWriter, r *http.Request) {
@@ -24,7 +31,7 @@ func (s *Server) handleListMessages(w http.ResponseWriter, r *http.Request) {
 	includeJIDs, excludeJIDs := s.phoneFilter.JIDSuffixes()
 	after := s.computeAfter()
 
-	result := s.app.ListMessages(chatJID, nil, limit, page, includeJIDs, excludeJIDs, after)
+	result := s.app.ListMessages(chatJID, nil, limit, page, includeJIDs, excludeJIDs, after, langParam(r), tagParam(r))
 	w.Header().Set("Content-Type", "application/json")
 	w.Write([]byte(result))
 }
@@ -32,9 +39,7 @@ func (s *Server) handleListMessages(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleSearchMessages(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("query")
 	if query == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(`{"success":false,"data":null,"error":"query parameter required"}`))
+		writeError(w, http.StatusBadRequest, "query parameter required")
 		return
 	}
 
@@ -45,14 +50,89 @@ func (s *Server) handleSearchMessages(w http.ResponseWriter, r *http.Request) {
 		limit = s.Config.MaxMessages
 	}
 
+	var chatJID *string
+	if v := r.URL.Query().Get("chat_jid"); v != "" {
+		chatJID = &v
+	}
+
 	includeJIDs, excludeJIDs := s.phoneFilter.JIDSuffixes()
 	after := s.computeAfter()
 
-	result := s.app.ListMessages(nil, &query, limit, page, includeJIDs, excludeJIDs, after)
+	result := s.app.ListMessages(chatJID, &query, limit, page, includeJIDs, excludeJIDs, after, langParam(r), tagParam(r))
 	w.Header().Set("Content-Type", "application/json")
 	w.Write([]byte(result))
 }
 
+// handleSemanticSearch ranks messages by meaning rather than by the words
+// they contain.
+func (s *Server) handleSemanticSearch(w http.ResponseWriter, r *http.Request) {
+	if s.Config.EmbeddingsURL == "" {
+		writeError(w, http.StatusNotImplemented, "semantic search is not enabled (set EMBEDDINGS_URL and EMBEDDINGS_MODEL)")
+		return
+	}
+	query := r.URL.Query().Get("query")
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "query parameter required")
+		return
+	}
+
+	limit := parseIntParam(r, "limit", 20)
+	if limit > s.Config.MaxMessages {
+		limit = s.Config.MaxMessages
+	}
+
+	var chatJID *string
+	if v := r.URL.Query().Get("chat_jid"); v != "" {
+		chatJID = &v
+	}
+
+	includeJIDs, excludeJIDs := s.phoneFilter.JIDSuffixes()
+	after := s.computeAfter()
+
+	writeResult(w, s.app.SemanticSearch(r.Context(), query, chatJID, limit, includeJIDs, excludeJIDs, after))
+}
+
+func (s *Server) handleMessageDuplicates(w http.ResponseWriter, r *http.Request) {
+	limit := parseIntParam(r, "limit", 20)
+	if limit > s.Config.MaxMessages {
+		limit = s.Config.MaxMessages
+	}
+
+	var chatJID *string
+	if v := r.URL.Query().Get("chat_jid"); v != "" {
+		chatJID = &v
+	}
+
+	includeJIDs, excludeJIDs := s.phoneFilter.JIDSuffixes()
+
+	result := s.app.MessageDuplicates(r.PathValue("id"), chatJID, limit, includeJIDs, excludeJIDs)
+	writeResult(w, result)
+}
+
+func (s *Server) handleListQuarantine(w http.ResponseWriter, r *http.Request) {
+	limit := parseIntParam(r, "limit", 20)
+	page := parseIntParam(r, "page", 0)
+
+	if limit > s.Config.MaxMessages {
+		limit = s.Config.MaxMessages
+	}
+
+	includeJIDs, excludeJIDs := s.phoneFilter.JIDSuffixes()
+
+	result := s.app.ListQuarantine(limit, page, includeJIDs, excludeJIDs)
+	writeResult(w, result)
+}
+
+func (s *Server) handleReleaseQuarantined(w http.ResponseWriter, r *http.Request) {
+	chatJID, ok := s.messageChatParam(w, r, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	result := s.app.ReleaseQuarantined(r.PathValue("id"), chatJID)
+	writeResult(w, result)
+}
+
 func (s *Server) handleListChats(w http.ResponseWriter, r *http.Request) {
 	limit := parseIntParam(r, "limit", 20)
 	page := parseIntParam(r, "page", 0)
@@ -76,9 +156,7 @@ func (s *Server) handleListChats(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleSearchContacts(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("query")
 	if query == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(`{"success":false,"data":null,"error":"query parameter required"}`))
+		writeError(w, http.StatusBadRequest, "query parameter required")
 		return
 	}
 
@@ -92,73 +170,126 @@ func (s *Server) handleSearchContacts(w http.ResponseWriter, r *http.Request) {
 type sendRequest struct {
 	To      string `json:"to"`
 	Message string `json:"message"`
+	// QuotedMessageID makes the message a reply to a message of the chat.
+	QuotedMessageID string `json:"quoted_message_id,omitempty"`
+	// Mentions are the phone numbers or JIDs of group members to mention.
+	Mentions []string `json:"mentions,omitempty"`
+	// SimulateTyping overrides SIMULATE_TYPING for this message.
+	SimulateTyping *bool `json:"simulate_typing,omitempty"`
 }
 
 func (s *Server) handleSendMessage(w http.ResponseWriter, r *http.Request) {
 	var req sendRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(`{"success":false,"data":null,"error":"invalid JSON body"}`))
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
 		return
 	}
 
 	if req.To == "" || req.Message == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(`{"success":false,"data":null,"error":"'to' and 'message' fields are required"}`))
+		writeError(w, http.StatusBadRequest, "'to' and 'message' fields are required")
 		return
 	}
 
-	// Auto-append @s.whatsapp.net if no @ sign (matching CLI behavior)
-	recipient := req.To
-	if !strings.Contains(recipient, "@") {
-		recipient = recipient + "@s.whatsapp.net"
+	// Bare phone numbers address individual chats (matching CLI behavior)
+	recipient, err := jid.ParseRecipient(req.To)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
 	// Check phone filter
 	if !s.phoneFilter.IsAllowed(recipient) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusForbidden)
-		w.Write([]byte(`{"success":false,"data":null,"error":"recipient not allowed"}`))
+		writeError(w, http.StatusForbidden, "recipient not allowed")
 		return
 	}
 
-	result := s.app.SendMessage(r.Context(), req.To, req.Message)
+	result := s.app.SendMessage(r.Context(), req.To, req.Message, client.SendOptions{
+		QuotedMessageID: req.QuotedMessageID,
+		Mentions:        req.Mentions,
+		SimulateTyping:  req.SimulateTyping,
+	})
+	if resultSucceeded(result) {
+		s.usage.recordSend(keyIDFromContext(r.Context()))
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.Write([]byte(result))
 }
 
+// handleRevokeMessage deletes a message the account sent for everyone.
+// ?chat_jid= picks the chat when the ID is not unique.
+func (s *Server) handleRevokeMessage(w http.ResponseWriter, r *http.Request) {
+	var chatJID *string
+	if v := r.URL.Query().Get("chat_jid"); v != "" {
+		if !s.phoneFilter.IsAllowed(v) {
+			writeError(w, http.StatusForbidden, "chat not allowed")
+			return
+		}
+		chatJID = &v
+	}
+	includeJIDs, excludeJIDs := s.phoneFilter.JIDSuffixes()
+	writeResult(w, s.app.RevokeMessage(r.Context(), r.PathValue("id"), chatJID, includeJIDs, excludeJIDs))
+}
+
+// handleMediaDownload streams the media of a message, downloading it from
+// WhatsApp first if it was not downloaded yet.
 func (s *Server) handleMediaDownload(w http.ResponseWriter, r *http.Request) {
+	if !s.requireMediaScope(w, r) {
+		return
+	}
 	messageID := r.PathValue("message_id")
 	if messageID == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(`{"success":false,"data":null,"error":"message_id required"}`))
+		writeError(w, http.StatusBadRequest, "message_id required")
 		return
 	}
 
-	var chatJID *string
-	if v := r.URL.Query().Get("chat_jid"); v != "" {
-		chatJID = &v
+	chatJID, ok := s.messageChatParam(w, r, messageID)
+	if !ok {
+		return
 	}
 
-	filePath, mimeType, err := s.app.GetMediaFile(messageID, chatJID)
+	file, name, mimeType, err := s.app.OpenMediaFile(r.Context(), messageID, chatJID)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(map[string]any{
-			"success": false,
-			"data":    nil,
-			"error":   err.Error(),
-		})
+		status := http.StatusInternalServerError
+		if errors.Is(err, fs.ErrNotExist) {
+			status = http.StatusNotFound
+		} else if errors.Is(err, fs.ErrPermission) {
+			status = http.StatusForbidden
+		}
+		writeError(w, status, err.Error())
 		return
 	}
 
+	defer file.Close()
+
 	if mimeType != "" {
 		w.Header().Set("Content-Type", mimeType)
 	}
-	http.ServeFile(w, r, filePath)
+	// Clients must not second-guess the type the content was verified as
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": name}))
+	http.ServeContent(w, r, name, time.Time{}, file)
+}
+
+// writeResult writes a JSON envelope produced by the app layer.
+func writeResult(w http.ResponseWriter, result string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(result))
+}
+
+// writeError writes a JSON error envelope with the given status code,
+// including the request ID when one was assigned.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	body := map[string]any{
+		"success": false,
+		"data":    nil,
+		"error":   msg,
+	}
+	if id := w.Header().Get(reqid.Header); id != "" {
+		body["request_id"] = id
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
 }
 
 // computeAfter returns a *time.Time representing the earliest allowed message time
@@ -182,3 +313,21 @@ func parseIntParam(r *http.Request, name string, defaultVal int) int {
 	}
 	return n
 }
+
+// langParam returns the lowercased ?lang= filter, or nil when absent.
+func langParam(r *http.Request) *string {
+	v := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("lang")))
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+// tagParam returns the ?tag= filter, or nil when absent.
+func tagParam(r *http.Request) *string {
+	v := strings.TrimSpace(r.URL.Query().Get("tag"))
+	if v == "" {
+		return nil
+	}
+	return &v
+}
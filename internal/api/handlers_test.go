@@ -3,8 +3,14 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -12,10 +18,17 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/canary"
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/eventbus"
+	"github.com/vicentereig/whatsapp-cli/internal/health"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
 )
 
 // mockApp implements AppService for testing.
 type mockApp struct {
+	messageChats map[string]string
+
 	listMessagesResult string
 	listMessagesCalled bool
 	lastChatJID        *string
@@ -25,6 +38,8 @@ type mockApp struct {
 	lastIncludeJIDs    []string
 	lastExcludeJIDs    []string
 	lastAfter          *time.Time
+	lastLang           *string
+	lastListTag        *string
 
 	listChatsResult     string
 	listChatsCalled     bool
@@ -40,13 +55,17 @@ type mockApp struct {
 	lastContactsIncludeJIDs  []string
 	lastContactsExcludeJIDs  []string
 
-	sendMessageResult string
-	sendMessageCalled bool
-	lastSendRecipient string
-	lastSendMessage   string
+	sendMessageResult  string
+	sendMessageCalled  bool
+	lastSendRecipient  string
+	lastSendMessage    string
+	lastQuotedID       string
+	lastMentions       []string
+	lastSimulateTyping *bool
 
 	authenticated bool
 	connected     bool
+	connection    health.Connection
 
 	syncResult string
 	syncCalled bool
@@ -55,9 +74,143 @@ type mockApp struct {
 	mediaFilePath     string
 	mediaFileMimeType string
 	mediaFileErr      error
+	mediaZip          []byte
+	mediaZipErr       error
+	lastMediaParams   store.ChatMediaParams
+	lastTyping        *bool
+	lastPresence      *bool
+	lastReadIDs       []string
+	lastChainExport   *store.ChainExport
+	lastStatusID      string
+
+	storeHealth *store.Health
+	outboxStats *store.OutboxStats
+	accountAlerts []store.AccountAlert
+	alertsResult  string
+	canaryStatus  *canary.Status
+	quality       health.QualityReport
+
+	updateGroupResult string
+	updateGroupCalled bool
+	lastGroupJID      string
+	lastGroupSubject  *string
+	lastGroupDesc     *string
+	lastGroupAnnounce *bool
+	lastGroupLocked   *bool
+
+	duplicatesResult   string
+	lastDuplicatesID   string
+	lastDuplicatesChat *string
+	lastDuplicatesLim  int
+
+	quarantineResult  string
+	quarantineCalled  bool
+	releaseResult     string
+	lastReleaseID     string
+	lastReleaseChat   *string
+
+	awayResult     string
+	lastAwayConfig *store.AwayConfig
+	lastOptOutJID  string
+	lastOptOut     bool
+
+	createGroupResult      string
+	createGroupCalled      bool
+	lastCreateSubject      string
+	lastCreateParticipants []string
+
+	participantsResult     string
+	participantsCalled     bool
+	lastParticipants       []string
+	lastParticipantsAction string
+
+	joinRequestsResult       string
+	updateJoinRequestsResult string
+	updateJoinRequestsCalled bool
+	lastJoinParticipants     []string
+	lastJoinApprove          bool
+
+	chatModeResult   string
+	lastModeJID      string
+	lastMode         string
+	humanChatsCalled bool
+
+	draftResult   string
+	lastDraftJID  string
+	lastDraftText *string
+
+	contextResult string
+	lastContext   *contextCall
+
+	semanticResult string
+	lastSemantic   string
+
+	digestsResult    string
+	lastDigestsJID   string
+	lastDigestPeriod string
+
+	graphResult *store.Graph
+	lastGraph   *store.GraphParams
+
+	identityResult  string
+	lastIdentityJID string
+	lastVerified    *bool
+
+	triggerMessages    []store.TriggerMessage
+	lastTriggerParams  *store.NewMessagesParams
+	triggerChats       []store.TriggerChat
+	lastTriggerSinceID int64
+
+	tagsResult string
+	lastTag    string
+	lastTags   []string
+
+	lastTagMessageID string
+
+	metaResult     string
+	lastMetaKind   string
+	lastMetaTarget string
+	lastMetaKey    string
+	lastMetaVal    *string
+
+	revokeResult    string
+	pollResult      string
+	lastPoll        []string
+	lastPollID      string
+	lastRevokeID    string
+
+	followupsResult string
+
+	autocompleteResult string
+	lastPrefix         string
+
+	sentMessages []string
+	sentMedia    []client.OutgoingMedia
+	// failRecipients are the recipients SendMessage and SendMedia fail for
+	failRecipients map[string]bool
+
+	remindersResult string
+	lastReminder    *store.Reminder
+	lastReminderID  int64
+
+	mergeResult string
+	lastMerge   [2]string
+	lids        map[string]string
+
+	adminResult    string
+	lastAdminQuery string
+	lastAdminLimit int
+
+	snapshotPath string
+	snapshotErr  error
+
+	anonymizeSalt        string
+	anonymizeDropContent bool
+
+	versionResult string
 }
 
-func (m *mockApp) ListMessages(chatJID *string, query *string, limit, page int, includeJIDs, excludeJIDs []string, after *time.Time) string {
+func (m *mockApp) ListMessages(chatJID *string, query *string, limit, page int, includeJIDs, excludeJIDs []string, after *time.Time, lang, tag *string) string {
 	m.listMessagesCalled = true
 	m.lastChatJID = chatJID
 	m.lastQuery = query
@@ -66,6 +219,8 @@ func (m *mockApp) ListMessages(chatJID *string, query *string, limit, page int,
 	m.lastIncludeJIDs = includeJIDs
 	m.lastExcludeJIDs = excludeJIDs
 	m.lastAfter = after
+	m.lastLang = lang
+	m.lastListTag = tag
 	return m.listMessagesResult
 }
 
@@ -77,14 +232,30 @@ func (m *mockApp) SearchContacts(query string, includeJIDs, excludeJIDs []string
 	return m.searchContactsResult
 }
 
-func (m *mockApp) SendMessage(_ context.Context, recipient, message string) string {
+func (m *mockApp) SendMessage(_ context.Context, recipient, message string, opts client.SendOptions) string {
 	m.sendMessageCalled = true
 	m.lastSendRecipient = recipient
 	m.lastSendMessage = message
+	m.lastQuotedID = opts.QuotedMessageID
+	m.lastMentions = opts.Mentions
+	m.lastSimulateTyping = opts.SimulateTyping
+	m.sentMessages = append(m.sentMessages, message)
+	if m.failRecipients[recipient] {
+		return `{"success":false,"data":null,"error":"not connected"}`
+	}
 	return m.sendMessageResult
 }
 
-func (m *mockApp) Sync(ctx context.Context, onMessage func()) string {
+func (m *mockApp) SendMedia(_ context.Context, recipient string, media client.OutgoingMedia) string {
+	m.lastSendRecipient = recipient
+	m.sentMedia = append(m.sentMedia, media)
+	if m.failRecipients[recipient] {
+		return `{"success":false,"data":null,"error":"not connected"}`
+	}
+	return m.sendMessageResult
+}
+
+func (m *mockApp) Sync(ctx context.Context, onEvent func(eventbus.Event)) string {
 	m.syncCalled = true
 	m.syncCtx = ctx
 	// Block until context is cancelled to mimic real Sync behavior
@@ -100,8 +271,441 @@ func (m *mockApp) IsConnected() bool {
 	return m.connected
 }
 
-func (m *mockApp) GetMediaFile(messageID string, chatJID *string) (string, string, error) {
-	return m.mediaFilePath, m.mediaFileMimeType, m.mediaFileErr
+func (m *mockApp) Connection() health.Connection {
+	return m.connection
+}
+
+func (m *mockApp) OpenMediaFile(_ context.Context, messageID string, chatJID *string) (io.ReadSeekCloser, string, string, error) {
+	if m.mediaFileErr != nil {
+		return nil, "", "", m.mediaFileErr
+	}
+	f, err := os.Open(m.mediaFilePath)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return f, filepath.Base(m.mediaFilePath), m.mediaFileMimeType, nil
+}
+
+func (m *mockApp) SendTyping(_ context.Context, chatJID string, typing bool) string {
+	m.lastChatJID = &chatJID
+	m.lastTyping = &typing
+	return `{"success":true,"data":{}}`
+}
+
+func (m *mockApp) SendPresence(_ context.Context, available bool) string {
+	m.lastPresence = &available
+	return `{"success":true,"data":{}}`
+}
+
+func (m *mockApp) MarkChatRead(_ context.Context, chatJID string, ids []string) string {
+	m.lastChatJID = &chatJID
+	m.lastReadIDs = ids
+	return `{"success":true,"data":{}}`
+}
+
+func (m *mockApp) ChatReadState(chatJID string) string {
+	m.lastChatJID = &chatJID
+	return `{"success":true,"data":{}}`
+}
+
+func (m *mockApp) ExportChain(chatJID string) string {
+	m.lastChatJID = &chatJID
+	return `{"success":true,"data":{}}`
+}
+
+func (m *mockApp) VerifyChain(chatJID string) string {
+	m.lastChatJID = &chatJID
+	return `{"success":true,"data":{}}`
+}
+
+func (m *mockApp) VerifyChainExport(e store.ChainExport) string {
+	m.lastChainExport = &e
+	return `{"success":true,"data":{}}`
+}
+
+func (m *mockApp) MessageStatus(messageID string, chatJID *string) string {
+	m.lastStatusID = messageID
+	m.lastChatJID = chatJID
+	return `{"success":true,"data":{}}`
+}
+
+func (m *mockApp) WriteChatMediaZip(_ context.Context, p store.ChatMediaParams, w io.Writer) error {
+	m.lastMediaParams = p
+	if len(m.mediaZip) > 0 {
+		w.Write(m.mediaZip)
+	}
+	return m.mediaZipErr
+}
+
+func (m *mockApp) StoreHealth() store.Health {
+	if m.storeHealth != nil {
+		return *m.storeHealth
+	}
+	return store.Health{Healthy: true}
+}
+
+func (m *mockApp) AccountAlerts() []store.AccountAlert {
+	return m.accountAlerts
+}
+
+func (m *mockApp) CanaryStatus() *canary.Status {
+	return m.canaryStatus
+}
+
+func (m *mockApp) ConnectionQuality() health.QualityReport {
+	return m.quality
+}
+
+func (m *mockApp) ListAccountAlerts(limit int) string {
+	m.lastLimit = limit
+	return m.alertsResult
+}
+
+func (m *mockApp) OutboxStats() store.OutboxStats {
+	if m.outboxStats != nil {
+		return *m.outboxStats
+	}
+	return store.OutboxStats{Pending: map[string]int64{}}
+}
+
+func (m *mockApp) UpdateGroup(_ context.Context, groupJID string, subject, description *string, announce, locked *bool) string {
+	m.updateGroupCalled = true
+	m.lastGroupJID = groupJID
+	m.lastGroupSubject = subject
+	m.lastGroupDesc = description
+	m.lastGroupAnnounce = announce
+	m.lastGroupLocked = locked
+	return m.updateGroupResult
+}
+
+func (m *mockApp) CreateGroup(_ context.Context, subject string, participants []string) string {
+	m.createGroupCalled = true
+	m.lastCreateSubject = subject
+	m.lastCreateParticipants = participants
+	return m.createGroupResult
+}
+
+func (m *mockApp) MessageDuplicates(messageID string, chatJID *string, limit int, includeJIDs, excludeJIDs []string) string {
+	m.lastDuplicatesID = messageID
+	m.lastDuplicatesChat = chatJID
+	m.lastDuplicatesLim = limit
+	m.lastIncludeJIDs = includeJIDs
+	m.lastExcludeJIDs = excludeJIDs
+	return m.duplicatesResult
+}
+
+func (m *mockApp) ListQuarantine(limit, page int, includeJIDs, excludeJIDs []string) string {
+	m.quarantineCalled = true
+	m.lastLimit = limit
+	m.lastPage = page
+	return m.quarantineResult
+}
+
+func (m *mockApp) MessageChat(messageID string, chatJID *string) (string, error) {
+	if chat, ok := m.messageChats[messageID]; ok && (chatJID == nil || *chatJID == chat) {
+		return chat, nil
+	}
+	return "", fmt.Errorf("message %s not found", messageID)
+}
+
+func (m *mockApp) ReleaseQuarantined(messageID string, chatJID *string) string {
+	m.lastReleaseID = messageID
+	m.lastReleaseChat = chatJID
+	return m.releaseResult
+}
+
+func (m *mockApp) GetAwayConfig() string {
+	return m.awayResult
+}
+
+func (m *mockApp) SetAwayConfig(cfg store.AwayConfig) string {
+	m.lastAwayConfig = &cfg
+	return m.awayResult
+}
+
+func (m *mockApp) ListAwayOptOuts() string {
+	return m.awayResult
+}
+
+func (m *mockApp) SetAwayOptOut(chatJID string, optOut bool) string {
+	m.lastOptOutJID = chatJID
+	m.lastOptOut = optOut
+	return m.awayResult
+}
+
+func (m *mockApp) GetChatMode(chatJID string) string {
+	m.lastModeJID = chatJID
+	return m.chatModeResult
+}
+
+func (m *mockApp) SetChatMode(chatJID, mode string) string {
+	m.lastModeJID = chatJID
+	m.lastMode = mode
+	return m.chatModeResult
+}
+
+func (m *mockApp) GetDraft(chatJID string) string {
+	m.lastDraftJID = chatJID
+	return m.draftResult
+}
+
+func (m *mockApp) SetDraft(chatJID, text string) string {
+	m.lastDraftJID = chatJID
+	m.lastDraftText = &text
+	return m.draftResult
+}
+
+type contextCall struct {
+	chatJID     string
+	tokens      int
+	includeJIDs []string
+}
+
+func (m *mockApp) ChatContext(chatJID string, tokens int, includeJIDs, excludeJIDs []string, after *time.Time) string {
+	m.lastContext = &contextCall{chatJID: chatJID, tokens: tokens, includeJIDs: includeJIDs}
+	return m.contextResult
+}
+
+func (m *mockApp) SemanticSearch(ctx context.Context, query string, chatJID *string, limit int, includeJIDs, excludeJIDs []string, after *time.Time) string {
+	m.lastSemantic = query
+	m.lastChatJID = chatJID
+	m.lastLimit = limit
+	return m.semanticResult
+}
+
+func (m *mockApp) ListDigests(chatJID, period string, limit int) string {
+	m.lastDigestsJID = chatJID
+	m.lastDigestPeriod = period
+	m.lastLimit = limit
+	return m.digestsResult
+}
+
+func (m *mockApp) NewMessages(params store.NewMessagesParams) ([]store.TriggerMessage, error) {
+	m.lastTriggerParams = &params
+	if m.triggerMessages == nil {
+		return []store.TriggerMessage{}, nil
+	}
+	return m.triggerMessages, nil
+}
+
+func (m *mockApp) MessagesSince(since time.Time, limit int, includeJIDs, excludeJIDs []string) ([]store.TriggerMessage, error) {
+	messages := []store.TriggerMessage{}
+	for _, msg := range m.triggerMessages {
+		if !msg.Timestamp.Before(since) && len(messages) < limit {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
+
+func (m *mockApp) ContactIdentity(_ context.Context, contact string) string {
+	m.lastIdentityJID = contact
+	return m.identityResult
+}
+
+func (m *mockApp) VerifyContactIdentity(_ context.Context, contact string, verified bool) string {
+	m.lastIdentityJID = contact
+	m.lastVerified = &verified
+	return m.identityResult
+}
+
+func (m *mockApp) ListTags(includeJIDs, excludeJIDs []string) string {
+	m.lastIncludeJIDs = includeJIDs
+	return m.tagsResult
+}
+
+func (m *mockApp) ListTaggedMessages(tag string, limit, page int, includeJIDs, excludeJIDs []string, after *time.Time) string {
+	m.lastTag = tag
+	m.lastLimit = limit
+	m.lastPage = page
+	m.lastIncludeJIDs = includeJIDs
+	m.lastAfter = after
+	return m.tagsResult
+}
+
+func (m *mockApp) TagMessage(messageID string, chatJID *string, tags []string) string {
+	m.lastTagMessageID = messageID
+	m.lastChatJID = chatJID
+	m.lastTags = tags
+	return m.tagsResult
+}
+
+func (m *mockApp) UntagMessage(messageID string, chatJID *string, tag string) string {
+	m.lastTagMessageID = messageID
+	m.lastChatJID = chatJID
+	m.lastTag = tag
+	return m.tagsResult
+}
+
+func (m *mockApp) GetMeta(kind, target string, chatJID *string) string {
+	m.lastMetaKind = kind
+	m.lastMetaTarget = target
+	m.lastChatJID = chatJID
+	return m.metaResult
+}
+
+func (m *mockApp) SetMeta(kind, target string, chatJID *string, key, value string) string {
+	m.lastMetaKind = kind
+	m.lastMetaTarget = target
+	m.lastChatJID = chatJID
+	m.lastMetaKey = key
+	m.lastMetaVal = &value
+	return m.metaResult
+}
+
+func (m *mockApp) DeleteMeta(kind, target string, chatJID *string, key string) string {
+	m.lastMetaKind = kind
+	m.lastMetaTarget = target
+	m.lastChatJID = chatJID
+	m.lastMetaKey = key
+	return m.metaResult
+}
+
+func (m *mockApp) FindMeta(kind, key string, value *string, limit, page int, includeJIDs, excludeJIDs []string) string {
+	m.lastMetaKind = kind
+	m.lastMetaKey = key
+	m.lastMetaVal = value
+	m.lastLimit = limit
+	m.lastPage = page
+	m.lastIncludeJIDs = includeJIDs
+	m.lastExcludeJIDs = excludeJIDs
+	return m.metaResult
+}
+
+func (m *mockApp) Autocomplete(prefix string, limit int, includeJIDs, excludeJIDs []string) string {
+	m.lastPrefix = prefix
+	m.lastLimit = limit
+	m.lastIncludeJIDs = includeJIDs
+	return m.autocompleteResult
+}
+
+func (m *mockApp) ListFollowups(limit int, includeJIDs, excludeJIDs []string) string {
+	m.lastLimit = limit
+	m.lastIncludeJIDs = includeJIDs
+	return m.followupsResult
+}
+
+func (m *mockApp) GetMessageTags(messageID string, chatJID *string) string {
+	m.lastTagMessageID = messageID
+	m.lastChatJID = chatJID
+	return m.tagsResult
+}
+
+func (m *mockApp) SendPoll(_ context.Context, recipient, question string, options []string, selectable int) string {
+	m.lastPoll = append([]string{recipient, question}, options...)
+	return m.pollResult
+}
+
+func (m *mockApp) PollResults(messageID string, chatJID *string) string {
+	m.lastPollID = messageID
+	m.lastChatJID = chatJID
+	return m.pollResult
+}
+
+func (m *mockApp) RevokeMessage(_ context.Context, messageID string, chatJID *string, includeJIDs, excludeJIDs []string) string {
+	m.lastRevokeID = messageID
+	m.lastChatJID = chatJID
+	m.lastExcludeJIDs = excludeJIDs
+	return m.revokeResult
+}
+
+func (m *mockApp) ListReminders() string {
+	return m.remindersResult
+}
+
+func (m *mockApp) GetReminder(id int64) string {
+	m.lastReminderID = id
+	return m.remindersResult
+}
+
+func (m *mockApp) CreateReminder(r store.Reminder) string {
+	m.lastReminder = &r
+	return m.remindersResult
+}
+
+func (m *mockApp) DeleteReminder(id int64) string {
+	m.lastReminderID = id
+	return m.remindersResult
+}
+
+func (m *mockApp) NewChats(sinceID int64, limit int, includeJIDs, excludeJIDs []string) ([]store.TriggerChat, error) {
+	m.lastTriggerSinceID = sinceID
+	m.lastLimit = limit
+	if m.triggerChats == nil {
+		return []store.TriggerChat{}, nil
+	}
+	return m.triggerChats, nil
+}
+
+func (m *mockApp) InteractionGraph(params store.GraphParams) (*store.Graph, error) {
+	m.lastGraph = &params
+	if m.graphResult == nil {
+		return &store.Graph{}, nil
+	}
+	return m.graphResult, nil
+}
+
+func (m *mockApp) ListHumanChats(includeJIDs, excludeJIDs []string) string {
+	m.humanChatsCalled = true
+	m.lastExcludeJIDs = excludeJIDs
+	return m.chatModeResult
+}
+
+func (m *mockApp) AdminQuery(_ context.Context, query string, limit int) string {
+	m.lastAdminQuery = query
+	m.lastAdminLimit = limit
+	return m.adminResult
+}
+
+func (m *mockApp) ResolvePhoneJID(jid string) string {
+	if pn, ok := m.lids[jid]; ok {
+		return pn
+	}
+	return jid
+}
+
+func (m *mockApp) MergeChats(_ context.Context, from, into string) string {
+	m.lastMerge = [2]string{from, into}
+	return m.mergeResult
+}
+
+func (m *mockApp) DatabaseSnapshot(_ context.Context) (string, error) {
+	return m.snapshotPath, m.snapshotErr
+}
+
+func (m *mockApp) AnonymizedSnapshot(_ context.Context, salt string, dropContent bool) (string, error) {
+	m.anonymizeSalt = salt
+	m.anonymizeDropContent = dropContent
+	return m.snapshotPath, m.snapshotErr
+}
+
+func (m *mockApp) VersionInfo(_ context.Context) string {
+	return m.versionResult
+}
+
+func (m *mockApp) AdminSchema() string {
+	return m.adminResult
+}
+
+func (m *mockApp) ListGroupJoinRequests(_ context.Context, groupJID string) string {
+	m.lastGroupJID = groupJID
+	return m.joinRequestsResult
+}
+
+func (m *mockApp) UpdateGroupJoinRequests(_ context.Context, groupJID string, participants []string, approve bool) string {
+	m.updateJoinRequestsCalled = true
+	m.lastGroupJID = groupJID
+	m.lastJoinParticipants = participants
+	m.lastJoinApprove = approve
+	return m.updateJoinRequestsResult
+}
+
+func (m *mockApp) UpdateGroupParticipants(_ context.Context, groupJID string, participants []string, action string) string {
+	m.participantsCalled = true
+	m.lastGroupJID = groupJID
+	m.lastParticipants = participants
+	m.lastParticipantsAction = action
+	return m.participantsResult
 }
 
 func (m *mockApp) ListChats(query *string, limit, page int, includeJIDs, excludeJIDs []string) string {
@@ -223,6 +827,126 @@ func TestHandleSearchMessages_Success(t *testing.T) {
 	assert.Equal(t, 0, mock.lastPage)
 }
 
+func TestHandleSearchMessages_LangFilter(t *testing.T) {
+	mock := &mockApp{listMessagesResult: `{"success":true,"data":[]}`}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages/search?query=hallo&lang=DE", nil)
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, mock.lastLang)
+	assert.Equal(t, "de", *mock.lastLang)
+}
+
+func TestHandleSemanticSearch(t *testing.T) {
+	mock := &mockApp{semanticResult: `{"success":true,"data":{"messages":[]}}`}
+	srv := NewServer(Config{APIKey: "test-key", MaxMessages: 100, EmbeddingsURL: "http://localhost:11434/api/embed"}, mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/messages/semantic-search?query=where+do+we+meet&chat_jid=123@s.whatsapp.net&limit=500", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "where do we meet", mock.lastSemantic)
+	require.NotNil(t, mock.lastChatJID)
+	assert.Equal(t, "123@s.whatsapp.net", *mock.lastChatJID)
+	assert.Equal(t, 100, mock.lastLimit)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/messages/semantic-search", "test-key", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandleSemanticSearch_Disabled(t *testing.T) {
+	mock := &mockApp{}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/messages/semantic-search?query=hello", "test-key", "")
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+	assert.Contains(t, w.Body.String(), "EMBEDDINGS_URL")
+	assert.Empty(t, mock.lastSemantic)
+}
+
+func TestHandleListMessages_NoLangFilter(t *testing.T) {
+	mock := &mockApp{listMessagesResult: `{"success":true,"data":[]}`}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages", nil)
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, mock.lastLang)
+}
+
+func TestHandleMessageDuplicates(t *testing.T) {
+	mock := &mockApp{duplicatesResult: `{"success":true,"data":{"duplicates":[]}}`}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages/ABC123/duplicates?chat_jid=123@g.us&limit=5", nil)
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"success":true,"data":{"duplicates":[]}}`, w.Body.String())
+	assert.Equal(t, "ABC123", mock.lastDuplicatesID)
+	require.NotNil(t, mock.lastDuplicatesChat)
+	assert.Equal(t, "123@g.us", *mock.lastDuplicatesChat)
+	assert.Equal(t, 5, mock.lastDuplicatesLim)
+}
+
+func TestHandleListQuarantine(t *testing.T) {
+	mock := &mockApp{quarantineResult: `{"success":true,"data":[]}`}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/quarantine?limit=500&page=2", nil)
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, mock.quarantineCalled)
+	assert.Equal(t, 100, mock.lastLimit) // capped at MaxMessages
+	assert.Equal(t, 2, mock.lastPage)
+}
+
+func TestHandleReleaseQuarantined(t *testing.T) {
+	mock := &mockApp{releaseResult: `{"success":true,"data":{"released":true}}`}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/quarantine/ABC/release?chat_jid=123@g.us", nil)
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ABC", mock.lastReleaseID)
+	require.NotNil(t, mock.lastReleaseChat)
+	assert.Equal(t, "123@g.us", *mock.lastReleaseChat)
+}
+
+func TestHandleReleaseQuarantined_FilteredChat(t *testing.T) {
+	mock := &mockApp{
+		releaseResult: `{"success":true,"data":{"released":true}}`,
+		messageChats:  map[string]string{"HIDDEN": "34600111222@s.whatsapp.net", "SHOWN": "34600333444@s.whatsapp.net"},
+	}
+	srv := NewServer(Config{APIKey: "test-key", MaxMessages: 100, PhoneBlacklist: []string{"111222"}}, mock)
+
+	w := doRequest(srv, http.MethodPost, "/api/v1/quarantine/HIDDEN/release", "test-key", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, mock.lastReleaseID)
+
+	w = doRequest(srv, http.MethodPost, "/api/v1/quarantine/HIDDEN/release?chat_jid=34600111222@s.whatsapp.net", "test-key", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, mock.lastReleaseID)
+
+	w = doRequest(srv, http.MethodPost, "/api/v1/quarantine/SHOWN/release", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, mock.lastReleaseChat)
+	assert.Equal(t, "34600333444@s.whatsapp.net", *mock.lastReleaseChat)
+}
+
 func TestHandleSearchMessages_MissingQuery(t *testing.T) {
 	mock := &mockApp{}
 	srv := newTestServer(mock)
@@ -501,6 +1225,38 @@ func TestHandleSendMessage_Success(t *testing.T) {
 	assert.True(t, mock.sendMessageCalled)
 	assert.Equal(t, "1234567890", mock.lastSendRecipient)
 	assert.Equal(t, "Hello!", mock.lastSendMessage)
+	assert.Nil(t, mock.lastSimulateTyping, "config default applies")
+}
+
+func TestHandleSendMessage_SimulateTyping(t *testing.T) {
+	mock := &mockApp{sendMessageResult: `{"success":true,"data":{}}`}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodPost, "/api/v1/messages/send", "test-key", `{"to":"1234567890","message":"Hi","simulate_typing":false}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, mock.lastSimulateTyping)
+	assert.False(t, *mock.lastSimulateTyping)
+}
+
+func TestHandleSendMessage_QuotedMessage(t *testing.T) {
+	mock := &mockApp{sendMessageResult: `{"success":true,"data":{}}`}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodPost, "/api/v1/messages/send", "test-key", `{"to":"1234567890","message":"Sure","quoted_message_id":"ABC123"}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ABC123", mock.lastQuotedID)
+}
+
+func TestHandleSendMessage_Mentions(t *testing.T) {
+	mock := &mockApp{sendMessageResult: `{"success":true,"data":{}}`}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodPost, "/api/v1/messages/send", "test-key", `{"to":"120363000000000001@g.us","message":"Standup in 5","mentions":["1234567890","4915112345678@s.whatsapp.net"]}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"1234567890", "4915112345678@s.whatsapp.net"}, mock.lastMentions)
 }
 
 func TestHandleSendMessage_MissingTo(t *testing.T) {
@@ -703,6 +1459,29 @@ func TestHandleSendMessage_WithFullJID(t *testing.T) {
 	assert.Equal(t, "1234567890@s.whatsapp.net", mock.lastSendRecipient)
 }
 
+func TestHandleSendMessage_InvalidRecipient(t *testing.T) {
+	for _, to := range []string{"alice", "12345", "family@g.us", "1234567890@broadcast", "1234567890@example.com"} {
+		mock := &mockApp{}
+		srv := newTestServer(mock)
+
+		w := doRequest(srv, http.MethodPost, "/api/v1/messages/send", "test-key", `{"to":"`+to+`","message":"Hello!"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, to)
+		assert.Contains(t, w.Body.String(), "invalid recipient", to)
+		assert.False(t, mock.sendMessageCalled, to)
+	}
+}
+
+func TestHandleSendMessage_OtherJIDTypes(t *testing.T) {
+	for _, to := range []string{"98765432109876@lid", "120363123456789012@newsletter", "status@broadcast"} {
+		mock := &mockApp{sendMessageResult: `{"success":true,"data":{}}`}
+		srv := newTestServer(mock)
+
+		w := doRequest(srv, http.MethodPost, "/api/v1/messages/send", "test-key", `{"to":"`+to+`","message":"Hello!"}`)
+		assert.Equal(t, http.StatusOK, w.Code, to)
+		assert.Equal(t, to, mock.lastSendRecipient)
+	}
+}
+
 // --- Auth Status Tests ---
 
 func TestHandleAuthStatus_Authenticated(t *testing.T) {
@@ -1080,6 +1859,61 @@ func TestHandleSyncStatus_Running(t *testing.T) {
 	assert.Equal(t, float64(42), data["messages_synced"])
 }
 
+func TestHandleSyncStatus_IncludesStoreHealth(t *testing.T) {
+	mock := &mockApp{storeHealth: &store.Health{Healthy: false, Buffered: 5, LastError: "database is locked"}}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/sync/status", nil)
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	storeHealth := body["data"].(map[string]any)["store"].(map[string]any)
+	assert.Equal(t, false, storeHealth["healthy"])
+	assert.Equal(t, float64(5), storeHealth["buffered"])
+	assert.Equal(t, "database is locked", storeHealth["last_error"])
+}
+
+func TestHandleSyncStatus_IncludesConnectionQuality(t *testing.T) {
+	mock := &mockApp{quality: health.QualityReport{
+		WindowSeconds: health.QualityWindow.Seconds(),
+		Ping:          health.Latency{Samples: 2, Last: 0.12},
+		Disconnects:   1,
+	}}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/sync/status", nil)
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	quality := body["data"].(map[string]any)["connection_quality"].(map[string]any)
+	assert.Equal(t, float64(86400), quality["window_seconds"])
+	assert.Equal(t, float64(1), quality["disconnects"])
+	assert.Equal(t, 0.12, quality["ping"].(map[string]any)["last_seconds"])
+}
+
+func TestHandleSyncStatus_IncludesHistorySyncMode(t *testing.T) {
+	srv := NewServer(Config{APIKey: "test-key", HistorySyncMode: "full", HistorySyncDays: 90}, &mockApp{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/sync/status", nil)
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	historySync := body["data"].(map[string]any)["history_sync"].(map[string]any)
+	assert.Equal(t, "full", historySync["mode"])
+	assert.Equal(t, float64(90), historySync["days"])
+}
+
 func TestHandleSyncStatus_RequiresAuth(t *testing.T) {
 	mock := &mockApp{}
 	srv := newTestServer(mock)
@@ -1112,7 +1946,7 @@ func TestStartBackgroundSync_WaitsForAuth(t *testing.T) {
 	assert.Eventually(t, func() bool {
 		return srv.syncRunning.Load() && mock.syncCalled
 	}, 3*time.Second, 50*time.Millisecond)
-	assert.True(t, srv.syncing.Load())
+	assert.True(t, srv.health.Signals().Syncing)
 
 	// Cancel context — sync should stop
 	cancel()
@@ -1148,14 +1982,15 @@ func TestStartBackgroundSync_MessageCounter(t *testing.T) {
 
 	// Wait for sync to start and get the callback
 	assert.Eventually(t, func() bool {
-		return customMock.getOnMessage() != nil
+		return customMock.getOnEvent() != nil
 	}, 3*time.Second, 50*time.Millisecond)
 
-	// Call the callback and check counter
-	cb := customMock.getOnMessage()
-	cb()
-	cb()
-	cb()
+	// Call the callback and check counter; only messages are counted
+	cb := customMock.getOnEvent()
+	cb(eventbus.Event{Type: eventbus.TypeMessage, Message: &eventbus.Message{ID: "1"}})
+	cb(eventbus.Event{Type: eventbus.TypeMessage, Message: &eventbus.Message{ID: "2"}})
+	cb(eventbus.Event{Type: eventbus.TypeReceipt, Receipt: &eventbus.Receipt{MessageIDs: []string{"1"}, Type: "read"}})
+	cb(eventbus.Event{Type: eventbus.TypeMessage, Message: &eventbus.Message{ID: "3", History: true}})
 	assert.Equal(t, int64(3), srv.messagesSynced.Load())
 
 	cancel()
@@ -1164,22 +1999,22 @@ func TestStartBackgroundSync_MessageCounter(t *testing.T) {
 // syncCallbackMock captures the onMessage callback from Sync
 type syncCallbackMock struct {
 	mockApp
-	onMessageMu sync.Mutex
-	onMessageCb func()
+	onEventMu sync.Mutex
+	onEventCb func(eventbus.Event)
 }
 
-func (m *syncCallbackMock) Sync(ctx context.Context, onMessage func()) string {
-	m.onMessageMu.Lock()
-	m.onMessageCb = onMessage
-	m.onMessageMu.Unlock()
+func (m *syncCallbackMock) Sync(ctx context.Context, onEvent func(eventbus.Event)) string {
+	m.onEventMu.Lock()
+	m.onEventCb = onEvent
+	m.onEventMu.Unlock()
 	<-ctx.Done()
 	return `{"success":true,"data":{"synced":true}}`
 }
 
-func (m *syncCallbackMock) getOnMessage() func() {
-	m.onMessageMu.Lock()
-	defer m.onMessageMu.Unlock()
-	return m.onMessageCb
+func (m *syncCallbackMock) getOnEvent() func(eventbus.Event) {
+	m.onEventMu.Lock()
+	defer m.onEventMu.Unlock()
+	return m.onEventCb
 }
 
 // --- StartQRAuth Tests ---
@@ -1222,12 +2057,12 @@ func TestStartQRAuth_UpdatesCurrentQRAndAuthenticated(t *testing.T) {
 
 	// Wait for auth to complete
 	assert.Eventually(t, func() bool {
-		return srv.authenticated.Load()
+		return srv.health.Signals().Paired
 	}, 3*time.Second, 50*time.Millisecond)
 
 	// After success, currentQR should be cleared
 	assert.Equal(t, "", srv.GetCurrentQR())
-	assert.True(t, srv.authenticated.Load())
+	assert.True(t, srv.health.Signals().Paired)
 }
 
 func TestStartQRAuth_SetsCurrentQRDuringAuth(t *testing.T) {
@@ -1255,7 +2090,7 @@ func TestStartQRAuth_SetsCurrentQRDuringAuth(t *testing.T) {
 	// Signal success
 	close(done)
 	assert.Eventually(t, func() bool {
-		return srv.authenticated.Load()
+		return srv.health.Signals().Paired
 	}, 3*time.Second, 50*time.Millisecond)
 }
 
@@ -1277,3 +2112,54 @@ func (m *blockingQRAuth) AuthWithQRCallback(ctx context.Context, onQR func(code
 	}
 	return nil
 }
+
+func TestHandleMediaDownload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	require.NoError(t, os.WriteFile(path, []byte("jpeg-bytes"), 0644))
+	mock := &mockApp{mediaFilePath: path, mediaFileMimeType: "image/jpeg"}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/media/abc", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, `inline; filename=photo.jpg`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "jpeg-bytes", w.Body.String())
+
+	mock.mediaFileErr = fmt.Errorf("message abc has no downloadable media: %w", fs.ErrNotExist)
+	w = doRequest(srv, http.MethodGet, "/api/v1/media/abc", "test-key", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "no downloadable media")
+
+	mock.mediaFileErr = fmt.Errorf("media of message abc is quarantined: Eicar found: %w", fs.ErrPermission)
+	w = doRequest(srv, http.MethodGet, "/api/v1/media/abc", "test-key", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "quarantined")
+
+	mock.mediaFileErr = errors.New("downloading media: connection reset")
+	w = doRequest(srv, http.MethodGet, "/api/v1/media/abc", "test-key", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "connection reset")
+}
+
+func TestHandleMediaDownload_FilteredChat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	require.NoError(t, os.WriteFile(path, []byte("jpeg-bytes"), 0644))
+	mock := &mockApp{
+		mediaFilePath:     path,
+		mediaFileMimeType: "image/jpeg",
+		messageChats:      map[string]string{"HIDDEN": "34600111222@s.whatsapp.net", "SHOWN": "34600333444@s.whatsapp.net"},
+	}
+	srv := NewServer(Config{APIKey: "test-key", MaxMessages: 100, PhoneBlacklist: []string{"111222"}}, mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/media/HIDDEN", "test-key", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NotContains(t, w.Body.String(), "jpeg-bytes")
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/media/HIDDEN?chat_jid=34600111222@s.whatsapp.net", "test-key", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/media/SHOWN", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "jpeg-bytes", w.Body.String())
+}
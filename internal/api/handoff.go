@@ -0,0 +1,42 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func (s *Server) handleListHumanChats(w http.ResponseWriter, r *http.Request) {
+	includeJIDs, excludeJIDs := s.phoneFilter.JIDSuffixes()
+	writeResult(w, s.app.ListHumanChats(includeJIDs, excludeJIDs))
+}
+
+func (s *Server) handleGetChatMode(w http.ResponseWriter, r *http.Request) {
+	jid, ok := chatJIDPath(w, r)
+	if !ok {
+		return
+	}
+	if !s.phoneFilter.IsAllowed(jid) {
+		writeError(w, http.StatusForbidden, "chat not allowed")
+		return
+	}
+	writeResult(w, s.app.GetChatMode(jid))
+}
+
+func (s *Server) handleSetChatMode(w http.ResponseWriter, r *http.Request) {
+	jid, ok := chatJIDPath(w, r)
+	if !ok {
+		return
+	}
+	if !s.phoneFilter.IsAllowed(jid) {
+		writeError(w, http.StatusForbidden, "chat not allowed")
+		return
+	}
+	var req struct {
+		Mode string `json:"mode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	writeResult(w, s.app.SetChatMode(jid, req.Mode))
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleSetChatMode(t *testing.T) {
+	mock := &mockApp{chatModeResult: `{"success":true}`}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/chats/1234567890/mode", strings.NewReader(`{"mode":"human"}`))
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1234567890@s.whatsapp.net", mock.lastModeJID)
+	assert.Equal(t, "human", mock.lastMode)
+}
+
+func TestHandleSetChatMode_InvalidJSON(t *testing.T) {
+	mock := &mockApp{}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/chats/1234567890/mode", strings.NewReader(`{`))
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, mock.lastMode)
+}
+
+func TestHandleChatModeReads(t *testing.T) {
+	mock := &mockApp{chatModeResult: `{"success":true}`}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/chats/123@g.us/mode", nil)
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "123@g.us", mock.lastModeJID)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/chats/handoff", nil)
+	req.Header.Set("X-API-Key", "test-key")
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, mock.humanChatsCalled)
+}
+
+func TestHandleChatMode_FilteredChat(t *testing.T) {
+	mock := &mockApp{chatModeResult: `{"success":true}`}
+	srv := NewServer(Config{APIKey: "test-key", MaxMessages: 100, PhoneBlacklist: []string{"111222"}}, mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/chats/111222/mode", "test-key", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(srv, http.MethodPut, "/api/v1/chats/111222/mode", "test-key", `{"mode":"human"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, mock.lastModeJID)
+	assert.Empty(t, mock.lastMode)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats/handoff", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"111222@"}, mock.lastExcludeJIDs)
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/health"
+	"github.com/vicentereig/whatsapp-cli/internal/logging"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// healthInterval is how often the sync daemon re-derives its state, so
+// changes are logged when they happen rather than when next probed.
+const healthInterval = 5 * time.Second
+
+// refreshHealth feeds what the app knows — account alerts, the canary and
+// the connection — into the state machine and returns the resulting state.
+func (s *Server) refreshHealth() health.Status {
+	if s.app == nil {
+		return s.health.Status()
+	}
+	var alert, alertReason, canaryError string
+	if alerts := s.app.AccountAlerts(); len(alerts) > 0 && alerts[0].Severity == store.AlertCritical {
+		alert, alertReason = alerts[0].Kind, alerts[0].Reason
+	}
+	if c := s.app.CanaryStatus(); c != nil && !c.OK {
+		canaryError = c.LastError
+	}
+	conn := s.app.Connection()
+	return s.health.Update(func(sig *health.Signals) {
+		sig.AccountAlert, sig.AlertReason = alert, alertReason
+		sig.CanaryError = canaryError
+		sig.Connection = conn
+	})
+}
+
+// watchHealth refreshes the state every healthInterval until ctx is done.
+func (s *Server) watchHealth(ctx context.Context) {
+	ticker := time.NewTicker(healthInterval)
+	defer ticker.Stop()
+	for {
+		s.refreshHealth()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Ready reports whether the daemon is in one of the states configured as
+// ready, as /readyz does.
+func (s *Server) Ready() bool {
+	return s.refreshHealth().Ready
+}
+
+// logStateChange logs a change of state; leaving the ready states is a
+// warning.
+func logStateChange(from, to health.Status) {
+	level := slog.LevelInfo
+	if from.Ready && !to.Ready {
+		level = slog.LevelWarn
+	}
+	logging.Logger(logging.SubsystemHealth).Log(context.Background(), level, "state changed",
+		"from", from.State, "to", to.State, "reason", to.Reason, "ready", to.Ready)
+}
+
+// statusResponse is the body of GET /api/v1/status.
+type statusResponse struct {
+	health.Status
+	ReadyStates []health.State `json:"ready_states"`
+	// Transitions counts how often each state was entered since startup.
+	Transitions map[health.State]int64 `json:"transitions"`
+}
+
+// handleStatus reports the state of the daemon, when it entered it and
+// whether it counts as ready.
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	res := statusResponse{
+		Status:      s.refreshHealth(),
+		ReadyStates: s.health.ReadyStates(),
+		Transitions: s.health.Transitions(),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    res,
+	})
+}
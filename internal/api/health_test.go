@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/health"
+)
+
+func TestReadyz_ReportsState(t *testing.T) {
+	mock := &mockApp{connection: health.ConnectionCatchingUp}
+	srv := newTestServer(mock)
+	srv.SetAuthenticated(true)
+	srv.SetSyncing(true)
+
+	readyz := func() (int, map[string]string) {
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var body map[string]string
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body
+	}
+	// Catching up is ready by default
+	code, body := readyz()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]string{"status": "ready", "state": "backfilling", "reason": "catching up"}, body)
+
+	mock.connection = health.ConnectionOnline
+	code, body = readyz()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]string{"status": "ready", "state": "live"}, body)
+
+	mock.connection = health.ConnectionOffline
+	code, body = readyz()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "degraded", body["state"])
+	assert.Equal(t, "disconnected", body["reason"])
+}
+
+func TestReadyz_ConfiguredReadyStates(t *testing.T) {
+	mock := &mockApp{connection: health.ConnectionOffline}
+	srv := NewServer(Config{APIKey: "test-key", ReadyStates: []health.State{health.Live, health.Degraded}}, mock)
+	srv.SetAuthenticated(true)
+	srv.SetSyncing(true)
+
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ready","state":"degraded","reason":"disconnected"}`, w.Body.String())
+	assert.True(t, srv.Ready())
+
+	mock = &mockApp{connection: health.ConnectionCatchingUp}
+	srv = NewServer(Config{APIKey: "test-key", ReadyStates: []health.State{health.Live}}, mock)
+	srv.SetAuthenticated(true)
+	srv.SetSyncing(true)
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"status":"not_ready","state":"backfilling","reason":"catching up"}`, w.Body.String())
+}
+
+func TestHandleStatus(t *testing.T) {
+	mock := &mockApp{}
+	srv := newTestServer(mock)
+
+	var res struct {
+		Success bool `json:"success"`
+		Data    struct {
+			statusResponse
+		} `json:"data"`
+	}
+	w := doRequest(srv, http.MethodGet, "/api/v1/status", "test-key", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, health.Starting, res.Data.State)
+	assert.Equal(t, "not authenticated", res.Data.Reason)
+	assert.False(t, res.Data.Ready)
+	assert.Equal(t, []health.State{health.Backfilling, health.Live}, res.Data.ReadyStates)
+
+	srv.SetAuthenticated(true)
+	srv.SetSyncing(true)
+	w = doRequest(srv, http.MethodGet, "/api/v1/status", "test-key", "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, health.Live, res.Data.State)
+	assert.True(t, res.Data.Ready)
+	assert.Equal(t, int64(1), res.Data.Transitions[health.Live])
+	assert.False(t, res.Data.Since.IsZero())
+
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, w.Body.String(), "whatsapp_state{state=\"live\"} 1\n")
+	assert.Contains(t, w.Body.String(), "whatsapp_state{state=\"starting\"} 0\n")
+	assert.Contains(t, w.Body.String(), "whatsapp_ready 1\n")
+	assert.Contains(t, w.Body.String(), "whatsapp_state_transitions_total{state=\"live\"} 1\n")
+}
+
+func TestStartQRAuth_Pairing(t *testing.T) {
+	auth := &blockingQRAuth{code: "qr", ready: make(chan struct{}), done: make(chan struct{}), succeed: true}
+	srv := newTestServer(&mockApp{})
+	srv.StartQRAuth(t.Context(), auth)
+	<-auth.ready
+	assert.Equal(t, health.Pairing, srv.health.Status().State)
+
+	close(auth.done)
+	assert.Eventually(t, func() bool {
+		status := srv.health.Status()
+		return status.State == health.Starting && status.Reason == "not syncing"
+	}, time.Second, 10*time.Millisecond)
+}
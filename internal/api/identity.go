@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+)
+
+// contactIdentityPath returns the contact JID of an identity route, answering
+// the request itself when the phone filters hide the contact.
+func (s *Server) contactIdentityPath(w http.ResponseWriter, r *http.Request) (string, bool) {
+	contact, ok := chatJIDPath(w, r)
+	if !ok {
+		return "", false
+	}
+	if !s.phoneFilter.IsAllowed(contact) {
+		writeError(w, http.StatusForbidden, "contact not allowed")
+		return "", false
+	}
+	return contact, true
+}
+
+func (s *Server) handleGetContactIdentity(w http.ResponseWriter, r *http.Request) {
+	contact, ok := s.contactIdentityPath(w, r)
+	if !ok {
+		return
+	}
+	writeResult(w, s.app.ContactIdentity(r.Context(), contact))
+}
+
+// handleVerifyContactIdentity records that the contact's security code was
+// compared out of band and matched.
+func (s *Server) handleVerifyContactIdentity(w http.ResponseWriter, r *http.Request) {
+	contact, ok := s.contactIdentityPath(w, r)
+	if !ok {
+		return
+	}
+	writeResult(w, s.app.VerifyContactIdentity(r.Context(), contact, true))
+}
+
+func (s *Server) handleUnverifyContactIdentity(w http.ResponseWriter, r *http.Request) {
+	contact, ok := s.contactIdentityPath(w, r)
+	if !ok {
+		return
+	}
+	writeResult(w, s.app.VerifyContactIdentity(r.Context(), contact, false))
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContactIdentityRoutes(t *testing.T) {
+	mock := &mockApp{identityResult: `{"success":true,"data":{"verification":"unverified"}}`}
+	srv := NewServer(Config{APIKey: "test-key", MaxMessages: 100, PhoneBlacklist: []string{"222"}}, mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/contacts/34600111/identity", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "34600111@s.whatsapp.net", mock.lastIdentityJID)
+	assert.Nil(t, mock.lastVerified)
+
+	w = doRequest(srv, http.MethodPut, "/api/v1/contacts/34600111@s.whatsapp.net/identity/verified", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, mock.lastVerified)
+	assert.True(t, *mock.lastVerified)
+
+	w = doRequest(srv, http.MethodDelete, "/api/v1/contacts/34600111@s.whatsapp.net/identity/verified", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, *mock.lastVerified)
+
+	mock.lastIdentityJID = ""
+	w = doRequest(srv, http.MethodGet, "/api/v1/contacts/222@s.whatsapp.net/identity", "test-key", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, mock.lastIdentityJID)
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// concurrencyLimiter caps in-flight requests globally, per API key and per
+// client IP. A request over a limit waits up to maxWait for a slot to free up.
+// A limit of 0 disables that dimension.
+type concurrencyLimiter struct {
+	global, perKey, perIP int
+	maxWait               time.Duration
+
+	mu       sync.Mutex
+	inflight int
+	keys     map[string]int
+	ips      map[string]int
+	released chan struct{} // closed and replaced whenever a slot is freed
+
+	rejected atomic.Int64
+}
+
+func newConcurrencyLimiter(global, perKey, perIP int, maxWait time.Duration) *concurrencyLimiter {
+	return &concurrencyLimiter{
+		global:   global,
+		perKey:   perKey,
+		perIP:    perIP,
+		maxWait:  maxWait,
+		keys:     make(map[string]int),
+		ips:      make(map[string]int),
+		released: make(chan struct{}),
+	}
+}
+
+func (l *concurrencyLimiter) enabled() bool {
+	return l.global > 0 || l.perKey > 0 || l.perIP > 0
+}
+
+// acquire takes a slot for key and ip, waiting up to maxWait. It returns
+// false if no slot became available in time or ctx was cancelled.
+func (l *concurrencyLimiter) acquire(ctx context.Context, key, ip string) bool {
+	var timeout <-chan time.Time
+	for {
+		l.mu.Lock()
+		if l.fits(key, ip) {
+			l.inflight++
+			l.keys[key]++
+			l.ips[ip]++
+			l.mu.Unlock()
+			return true
+		}
+		released := l.released
+		l.mu.Unlock()
+
+		if timeout == nil {
+			if l.maxWait <= 0 {
+				l.rejected.Add(1)
+				return false
+			}
+			timer := time.NewTimer(l.maxWait)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case <-released:
+		case <-timeout:
+			l.rejected.Add(1)
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+func (l *concurrencyLimiter) fits(key, ip string) bool {
+	return (l.global <= 0 || l.inflight < l.global) &&
+		(l.perKey <= 0 || l.keys[key] < l.perKey) &&
+		(l.perIP <= 0 || l.ips[ip] < l.perIP)
+}
+
+func (l *concurrencyLimiter) release(key, ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inflight--
+	if l.keys[key]--; l.keys[key] <= 0 {
+		delete(l.keys, key)
+	}
+	if l.ips[ip]--; l.ips[ip] <= 0 {
+		delete(l.ips, ip)
+	}
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+// Inflight returns the number of requests currently holding a slot.
+func (l *concurrencyLimiter) Inflight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inflight
+}
+
+// rateWindow is the length of a rate limit window.
+const rateWindow = time.Minute
+
+// rateLimiter caps the requests of each API key per fixed one-minute window.
+// A limit of 0 disables it.
+type rateLimiter struct {
+	perMinute int
+
+	mu      sync.Mutex
+	windows map[string]*keyWindow
+
+	rejected atomic.Int64
+}
+
+type keyWindow struct {
+	start time.Time
+	count int
+}
+
+// rateBudget is what is left of a key's budget in the current window.
+type rateBudget struct {
+	Limit     int
+	Remaining int
+	Reset     time.Duration // until the window ends
+}
+
+func newRateLimiter(perMinute int) *rateLimiter {
+	return &rateLimiter{perMinute: perMinute, windows: make(map[string]*keyWindow)}
+}
+
+func (l *rateLimiter) enabled() bool {
+	return l.perMinute > 0
+}
+
+// take spends one request of key's budget. It returns false, without
+// spending, if the budget of the window is used up.
+func (l *rateLimiter) take(key string, now time.Time) (rateBudget, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	win := l.window(key, now)
+	if win.count >= l.perMinute {
+		l.rejected.Add(1)
+		return l.budget(win, now), false
+	}
+	win.count++
+	return l.budget(win, now), true
+}
+
+// peek returns key's budget without spending any of it.
+func (l *rateLimiter) peek(key string, now time.Time) rateBudget {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.budget(l.window(key, now), now)
+}
+
+// window returns key's current window, starting a new one if the last
+// ended. Ended windows of other keys are dropped.
+func (l *rateLimiter) window(key string, now time.Time) *keyWindow {
+	win, ok := l.windows[key]
+	if ok && now.Sub(win.start) < rateWindow {
+		return win
+	}
+	for k, w := range l.windows {
+		if now.Sub(w.start) >= rateWindow {
+			delete(l.windows, k)
+		}
+	}
+	win = &keyWindow{start: now}
+	l.windows[key] = win
+	return win
+}
+
+func (l *rateLimiter) budget(win *keyWindow, now time.Time) rateBudget {
+	return rateBudget{
+		Limit:     l.perMinute,
+		Remaining: max(l.perMinute-win.count, 0),
+		Reset:     win.start.Add(rateWindow).Sub(now),
+	}
+}
+
+// setRateLimitHeaders describes a budget with the RateLimit header fields
+// of the IETF draft, so clients can pace themselves before getting a 429.
+func setRateLimitHeaders(h http.Header, b rateBudget) {
+	h.Set("RateLimit-Limit", strconv.Itoa(b.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(b.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(b.Reset)))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", b.Limit, int(rateWindow.Seconds())))
+}
+
+// ceilSeconds rounds d up to whole seconds, so a client waiting that long
+// is never early.
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// limitMiddleware applies the rate and concurrency limiters to authenticated
+// requests. GET /limits does not spend the rate limit budget, so clients can
+// poll it while backing off.
+func (s *Server) limitMiddleware(next http.Handler) http.Handler {
+	if !s.limiter.enabled() && !s.rateLimiter.enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := keyIDFromContext(r.Context())
+		if s.rateLimiter.enabled() {
+			if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/limits") {
+				setRateLimitHeaders(w.Header(), s.rateLimiter.peek(key, time.Now()))
+			} else {
+				budget, ok := s.rateLimiter.take(key, time.Now())
+				setRateLimitHeaders(w.Header(), budget)
+				if !ok {
+					w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(budget.Reset)))
+					writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+					return
+				}
+			}
+		}
+		// Event streams would hold their slot for as long as they stay open
+		if !s.limiter.enabled() || strings.HasSuffix(r.URL.Path, "/ws") || strings.HasSuffix(r.URL.Path, "/events") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ip := clientIP(r)
+		if !s.limiter.acquire(r.Context(), key, ip) {
+			w.Header().Set("Retry-After", strconv.Itoa(1))
+			writeError(w, http.StatusServiceUnavailable, "too many concurrent requests")
+			return
+		}
+		defer s.limiter.release(key, ip)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP returns the host part of the request's remote address.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
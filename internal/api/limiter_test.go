@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	l := newConcurrencyLimiter(3, 2, 0, 0)
+	ctx := context.Background()
+
+	require.True(t, l.acquire(ctx, "crm", "10.0.0.1"))
+	require.True(t, l.acquire(ctx, "crm", "10.0.0.2"))
+	assert.False(t, l.acquire(ctx, "crm", "10.0.0.3"), "per-key limit")
+	require.True(t, l.acquire(ctx, "default", "10.0.0.3"))
+	assert.False(t, l.acquire(ctx, "other", "10.0.0.4"), "global limit")
+	assert.EqualValues(t, 2, l.rejected.Load())
+
+	l.release("crm", "10.0.0.1")
+	assert.True(t, l.acquire(ctx, "other", "10.0.0.4"))
+	assert.Equal(t, 3, l.Inflight())
+}
+
+func TestConcurrencyLimiter_Queueing(t *testing.T) {
+	l := newConcurrencyLimiter(0, 0, 1, 500*time.Millisecond)
+	ctx := context.Background()
+	require.True(t, l.acquire(ctx, "crm", "10.0.0.1"))
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		l.release("crm", "10.0.0.1")
+	}()
+	assert.True(t, l.acquire(ctx, "crm", "10.0.0.1"), "waits for the slot to free up")
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.False(t, l.acquire(cancelled, "crm", "10.0.0.1"))
+}
+
+func TestLimitMiddleware_Returns503(t *testing.T) {
+	srv := NewServer(Config{APIKey: "test-key", MaxInflight: 1, MaxQueueWait: 10 * time.Millisecond}, nil)
+
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	srv.apiMux.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-unblock
+	})
+	srv.apiMux.HandleFunc("GET /fast", func(w http.ResponseWriter, r *http.Request) {})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		doRequest(srv, http.MethodGet, "/api/v1/slow", "test-key", "")
+	}()
+	<-entered
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/fast", "test-key", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	close(unblock)
+	wg.Wait()
+	assert.Equal(t, http.StatusOK, doRequest(srv, http.MethodGet, "/api/v1/fast", "test-key", "").Code)
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(2)
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	b, ok := l.take("crm", start)
+	require.True(t, ok)
+	assert.Equal(t, rateBudget{Limit: 2, Remaining: 1, Reset: time.Minute}, b)
+	_, ok = l.take("crm", start.Add(10*time.Second))
+	require.True(t, ok)
+	b, ok = l.take("crm", start.Add(20*time.Second))
+	assert.False(t, ok)
+	assert.Equal(t, 0, b.Remaining)
+	assert.Equal(t, 40*time.Second, b.Reset)
+	assert.EqualValues(t, 1, l.rejected.Load())
+
+	_, ok = l.take("default", start.Add(20*time.Second))
+	assert.True(t, ok, "keys have separate budgets")
+	assert.Equal(t, 1, l.peek("default", start.Add(30*time.Second)).Remaining)
+
+	b, ok = l.take("crm", start.Add(time.Minute))
+	assert.True(t, ok, "a new window starts")
+	assert.Equal(t, 1, b.Remaining)
+}
+
+func TestLimitMiddleware_Returns429(t *testing.T) {
+	srv := NewServer(Config{APIKey: "test-key", RateLimitPerMinute: 2}, nil)
+	srv.apiMux.HandleFunc("GET /fast", func(w http.ResponseWriter, r *http.Request) {})
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/fast", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"))
+
+	doRequest(srv, http.MethodGet, "/api/v1/fast", "test-key", "")
+	w = doRequest(srv, http.MethodGet, "/api/v1/fast", "test-key", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "rate limit exceeded")
+
+	// Checking the budget does not spend it
+	w = doRequest(srv, http.MethodGet, "/api/v1/limits", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+}
+
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.0.2.7:51234"
+	assert.Equal(t, "192.0.2.7", clientIP(r))
+}
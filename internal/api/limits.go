@@ -0,0 +1,132 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// listCacheTTLs are the max-ages list endpoints advertise in Cache-Control.
+// Messages and chats change with every sync, contacts and digests rarely.
+var listCacheTTLs = map[string]time.Duration{
+	"GET /messages":            5 * time.Second,
+	"GET /messages/search":     5 * time.Second,
+	"GET /chats":               5 * time.Second,
+	"GET /chats/handoff":       5 * time.Second,
+	"GET /chats/{jid}/digests": 5 * time.Minute,
+	"GET /quarantine":          5 * time.Second,
+	"GET /tags":                30 * time.Second,
+	"GET /tags/{tag}/messages": 5 * time.Second,
+	"GET /followups":           30 * time.Second,
+	"GET /contacts":            time.Minute,
+	"GET /autocomplete":        5 * time.Second,
+	"GET /away/optouts":        30 * time.Second,
+	"GET /reminders":           5 * time.Second,
+	"GET /meta":                5 * time.Second,
+}
+
+// credentialVary names the request headers a key can be sent in, for the
+// Vary header of responses that depend on the key.
+const credentialVary = "Authorization, X-API-Key"
+
+// cacheFor lets clients cache the successful responses of next for maxAge.
+// Responses are private, since they depend on the API key, and failures,
+// which v1 may report with 200 OK, are never cached.
+func cacheFor(maxAge time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		buf := &bufferedResponse{w: w}
+		next(buf, r)
+		if buf.passthrough {
+			return
+		}
+		if buf.status == 0 {
+			buf.status = http.StatusOK
+		}
+		w.Header().Add("Vary", credentialVary)
+		if buf.status == http.StatusOK && resultSucceeded(buf.body.String()) {
+			w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds())))
+		} else {
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		w.WriteHeader(buf.status)
+		w.Write(buf.body.Bytes())
+	}
+}
+
+// limitsResult documents the budgets that apply to the calling key.
+type limitsResult struct {
+	KeyID string `json:"key_id"`
+	// RateLimit is nil when requests are not rate limited.
+	RateLimit   *rateLimitInfo  `json:"rate_limit"`
+	Concurrency concurrencyInfo `json:"concurrency"`
+	Sends       sendLimitsInfo  `json:"sends"`
+	Cache       []cacheTTLInfo  `json:"cache"`
+}
+
+type rateLimitInfo struct {
+	Limit         int `json:"limit"`
+	Remaining     int `json:"remaining"`
+	ResetSeconds  int `json:"reset_seconds"`
+	WindowSeconds int `json:"window_seconds"`
+}
+
+type concurrencyInfo struct {
+	MaxInflight         int     `json:"max_inflight"`
+	MaxInflightPerKey   int     `json:"max_inflight_per_key"`
+	MaxInflightPerIP    int     `json:"max_inflight_per_ip"`
+	MaxQueueWaitSeconds float64 `json:"max_queue_wait_seconds"`
+	Inflight            int     `json:"inflight"`
+}
+
+type sendLimitsInfo struct {
+	PerMinute       int     `json:"per_minute"`
+	DelayMinSeconds float64 `json:"delay_min_seconds"`
+	DelayMaxSeconds float64 `json:"delay_max_seconds"`
+	QuietHours      string  `json:"quiet_hours,omitempty"`
+}
+
+type cacheTTLInfo struct {
+	Endpoint      string `json:"endpoint"`
+	MaxAgeSeconds int    `json:"max_age_seconds"`
+}
+
+// handleLimits reports the rate limit budget left to the calling key and
+// the other limits requests and sends are subject to. A limit of 0 means
+// unlimited.
+func (s *Server) handleLimits(w http.ResponseWriter, r *http.Request) {
+	keyID := keyIDFromContext(r.Context())
+	res := limitsResult{
+		KeyID: keyID,
+		Concurrency: concurrencyInfo{
+			MaxInflight:         s.Config.MaxInflight,
+			MaxInflightPerKey:   s.Config.MaxInflightPerKey,
+			MaxInflightPerIP:    s.Config.MaxInflightPerIP,
+			MaxQueueWaitSeconds: s.Config.MaxQueueWait.Seconds(),
+			Inflight:            s.limiter.Inflight(),
+		},
+		Sends: sendLimitsInfo{
+			PerMinute:       s.Config.SendPerMinute,
+			DelayMinSeconds: s.Config.SendDelayMin.Seconds(),
+			DelayMaxSeconds: s.Config.SendDelayMax.Seconds(),
+			QuietHours:      s.Config.QuietHours,
+		},
+		Cache: []cacheTTLInfo{},
+	}
+	if s.rateLimiter.enabled() {
+		b := s.rateLimiter.peek(keyID, time.Now())
+		res.RateLimit = &rateLimitInfo{
+			Limit:         b.Limit,
+			Remaining:     b.Remaining,
+			ResetSeconds:  ceilSeconds(b.Reset),
+			WindowSeconds: int(rateWindow.Seconds()),
+		}
+	}
+	for endpoint, ttl := range listCacheTTLs {
+		res.Cache = append(res.Cache, cacheTTLInfo{Endpoint: endpoint, MaxAgeSeconds: int(ttl.Seconds())})
+	}
+	sort.Slice(res.Cache, func(i, j int) bool { return res.Cache[i].Endpoint < res.Cache[j].Endpoint })
+	w.Header().Add("Vary", credentialVary)
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, res)
+}
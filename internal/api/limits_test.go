@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheFor_ListEndpoints(t *testing.T) {
+	mock := &mockApp{
+		listChatsResult:    `{"success":true,"data":[]}`,
+		listMessagesResult: `{"success":false,"data":null,"error":"database is locked"}`,
+	}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/chats", "test-key", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "private, max-age=5", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "Authorization, X-API-Key", w.Header().Get("Vary"))
+	assert.JSONEq(t, mock.listChatsResult, w.Body.String())
+
+	// v1 reports the failure with 200 OK; it must not be cached
+	w = doRequest(srv, http.MethodGet, "/api/v1/messages", "test-key", "")
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/sync/status", "test-key", "")
+	assert.Empty(t, w.Header().Get("Cache-Control"), "only list endpoints are cacheable")
+}
+
+func TestHandleLimits(t *testing.T) {
+	srv := NewServer(Config{
+		APIKey:             "test-key",
+		RateLimitPerMinute: 10,
+		MaxInflightPerKey:  4,
+		MaxQueueWait:       5 * time.Second,
+		SendPerMinute:      20,
+		QuietHours:         "22:00-08:00",
+	}, &mockApp{listChatsResult: `{"success":true,"data":[]}`})
+	doRequest(srv, http.MethodGet, "/api/v1/chats", "test-key", "")
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/limits", "test-key", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Authorization, X-API-Key", w.Header().Get("Vary"))
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	var resp struct {
+		Data limitsResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, DefaultKeyID, resp.Data.KeyID)
+	require.NotNil(t, resp.Data.RateLimit)
+	assert.Equal(t, 10, resp.Data.RateLimit.Limit)
+	assert.Equal(t, 9, resp.Data.RateLimit.Remaining)
+	assert.Equal(t, 60, resp.Data.RateLimit.WindowSeconds)
+	assert.Equal(t, 4, resp.Data.Concurrency.MaxInflightPerKey)
+	assert.Equal(t, 5.0, resp.Data.Concurrency.MaxQueueWaitSeconds)
+	assert.Equal(t, 20, resp.Data.Sends.PerMinute)
+	assert.Equal(t, "22:00-08:00", resp.Data.Sends.QuietHours)
+	assert.Contains(t, resp.Data.Cache, cacheTTLInfo{Endpoint: "GET /contacts", MaxAgeSeconds: 60})
+
+	srv = newTestServer(&mockApp{})
+	w = doRequest(srv, http.MethodGet, "/api/v1/limits", "test-key", "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Nil(t, resp.Data.RateLimit, "no rate limit configured")
+	assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Chat lock lease bounds: a lock is held for defaultLockTTL unless the
+// request asks for up to maxLockTTL.
+const (
+	defaultLockTTL = 30 * time.Second
+	maxLockTTL     = 10 * time.Minute
+)
+
+// chatLock is a lease on a chat held by one automation worker.
+type chatLock struct {
+	ChatJID    string     `json:"chat_jid"`
+	Locked     bool       `json:"locked"`
+	Holder     string     `json:"holder,omitempty"`
+	AcquiredAt *time.Time `json:"acquired_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	// TTLSeconds is the time left on the lease.
+	TTLSeconds float64 `json:"ttl_seconds,omitempty"`
+}
+
+type lease struct {
+	holder     string
+	acquiredAt time.Time
+	expiresAt  time.Time
+}
+
+// chatLocks are the leases workers take on chats so that only one of them
+// replies to a message. They live in memory; a restart releases them all.
+type chatLocks struct {
+	mu     sync.Mutex
+	leases map[string]lease
+}
+
+func newChatLocks() *chatLocks {
+	return &chatLocks{leases: map[string]lease{}}
+}
+
+// acquire takes or renews the lock on chat for holder until now+ttl. If
+// another holder has it, the lock is left alone and false is returned with
+// its state.
+func (l *chatLocks) acquire(chat, holder string, ttl time.Duration, now time.Time) (chatLock, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for c, ls := range l.leases {
+		if !now.Before(ls.expiresAt) {
+			delete(l.leases, c)
+		}
+	}
+	cur, held := l.leases[chat]
+	if held && cur.holder != holder {
+		return cur.state(chat, now), false
+	}
+	if !held {
+		cur = lease{holder: holder, acquiredAt: now}
+	}
+	cur.expiresAt = now.Add(ttl)
+	l.leases[chat] = cur
+	return cur.state(chat, now), true
+}
+
+// release drops holder's lock on chat. It fails, returning the state, if
+// another holder has it; releasing a free chat succeeds.
+func (l *chatLocks) release(chat, holder string, now time.Time) (chatLock, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cur, held := l.leases[chat]
+	if held && now.Before(cur.expiresAt) && cur.holder != holder {
+		return cur.state(chat, now), false
+	}
+	delete(l.leases, chat)
+	return chatLock{ChatJID: chat}, true
+}
+
+// get returns the state of the lock on chat.
+func (l *chatLocks) get(chat string, now time.Time) chatLock {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cur, held := l.leases[chat]
+	if !held || !now.Before(cur.expiresAt) {
+		return chatLock{ChatJID: chat}
+	}
+	return cur.state(chat, now)
+}
+
+func (ls lease) state(chat string, now time.Time) chatLock {
+	acquired, expires := ls.acquiredAt.UTC(), ls.expiresAt.UTC()
+	return chatLock{
+		ChatJID:    chat,
+		Locked:     true,
+		Holder:     ls.holder,
+		AcquiredAt: &acquired,
+		ExpiresAt:  &expires,
+		TTLSeconds: ls.expiresAt.Sub(now).Seconds(),
+	}
+}
+
+// lockChatPath reads the chat of a lock route and checks it against the
+// phone filters.
+func (s *Server) lockChatPath(w http.ResponseWriter, r *http.Request) (string, bool) {
+	chat, ok := chatJIDPath(w, r)
+	if !ok {
+		return "", false
+	}
+	if !s.phoneFilter.IsAllowed(chat) {
+		writeError(w, http.StatusForbidden, "chat not allowed")
+		return "", false
+	}
+	return chat, true
+}
+
+// lockHolder reads the worker a lock request names. Workers may share an
+// API key, so the key cannot stand in for a missing holder; it answers the
+// request itself with a 400 then.
+func lockHolder(w http.ResponseWriter, holder string) (string, bool) {
+	holder = strings.TrimSpace(holder)
+	if holder == "" {
+		writeError(w, http.StatusBadRequest, "'holder' is required")
+		return "", false
+	}
+	return holder, true
+}
+
+// writeLockConflict answers a request for a lock another worker holds.
+func writeLockConflict(w http.ResponseWriter, state chatLock) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": false,
+		"data":    state,
+		"error":   "chat is locked by " + state.Holder,
+	})
+}
+
+func (s *Server) handleGetChatLock(w http.ResponseWriter, r *http.Request) {
+	chat, ok := s.lockChatPath(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, s.locks.get(chat, time.Now()))
+}
+
+// handleLockChat takes the lock on a chat, or renews it for its holder, so
+// that workers sharing the server do not answer the same message twice.
+// Another worker's lock is answered with a 409 and its state.
+func (s *Server) handleLockChat(w http.ResponseWriter, r *http.Request) {
+	chat, ok := s.lockChatPath(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Holder     string  `json:"holder"`
+		TTLSeconds float64 `json:"ttl_seconds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	ttl := defaultLockTTL
+	if req.TTLSeconds != 0 {
+		ttl = time.Duration(req.TTLSeconds * float64(time.Second))
+		if ttl < time.Second || ttl > maxLockTTL {
+			writeError(w, http.StatusBadRequest, "'ttl_seconds' must be between 1 and 600")
+			return
+		}
+	}
+	holder, ok := lockHolder(w, req.Holder)
+	if !ok {
+		return
+	}
+	state, ok := s.locks.acquire(chat, holder, ttl, time.Now())
+	if !ok {
+		writeLockConflict(w, state)
+		return
+	}
+	writeJSON(w, state)
+}
+
+// handleUnlockChat releases the lock on a chat; the required ?holder= names
+// the worker as for taking it.
+func (s *Server) handleUnlockChat(w http.ResponseWriter, r *http.Request) {
+	chat, ok := s.lockChatPath(w, r)
+	if !ok {
+		return
+	}
+	holder, ok := lockHolder(w, r.URL.Query().Get("holder"))
+	if !ok {
+		return
+	}
+	state, ok := s.locks.release(chat, holder, time.Now())
+	if !ok {
+		writeLockConflict(w, state)
+		return
+	}
+	writeJSON(w, state)
+}
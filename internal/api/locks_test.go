@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatLocks(t *testing.T) {
+	l := newChatLocks()
+	now := time.Now()
+	chat := "111@s.whatsapp.net"
+
+	state, ok := l.acquire(chat, "w1", 30*time.Second, now)
+	require.True(t, ok)
+	assert.Equal(t, "w1", state.Holder)
+	assert.Equal(t, 30.0, state.TTLSeconds)
+
+	// Another worker is refused until the lease expires
+	state, ok = l.acquire(chat, "w2", 30*time.Second, now.Add(10*time.Second))
+	assert.False(t, ok)
+	assert.Equal(t, "w1", state.Holder)
+	assert.Equal(t, 20.0, state.TTLSeconds)
+	_, ok = l.release(chat, "w2", now.Add(10*time.Second))
+	assert.False(t, ok)
+
+	// The holder renews it, keeping the acquisition time
+	state, ok = l.acquire(chat, "w1", time.Minute, now.Add(20*time.Second))
+	require.True(t, ok)
+	assert.True(t, now.UTC().Equal(*state.AcquiredAt))
+	assert.True(t, now.Add(80*time.Second).UTC().Equal(*state.ExpiresAt))
+
+	assert.False(t, l.get(chat, now.Add(80*time.Second)).Locked, "expired")
+	state, ok = l.acquire(chat, "w2", time.Minute, now.Add(80*time.Second))
+	require.True(t, ok)
+	assert.Equal(t, "w2", state.Holder)
+
+	_, ok = l.release(chat, "w2", now.Add(90*time.Second))
+	assert.True(t, ok)
+	assert.False(t, l.get(chat, now.Add(90*time.Second)).Locked)
+}
+
+func TestChatLockRoutes(t *testing.T) {
+	srv := NewServer(Config{APIKey: "test-key", MaxMessages: 100, PhoneBlacklist: []string{"222"}}, &mockApp{})
+	decode := func(body []byte) chatLock {
+		var resp struct {
+			Data chatLock `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &resp))
+		return resp.Data
+	}
+
+	w := doRequest(srv, http.MethodPost, "/api/v1/chats/111/lock", "test-key", `{"holder":"w1","ttl_seconds":60}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	state := decode(w.Body.Bytes())
+	assert.True(t, state.Locked)
+	assert.Equal(t, "111@s.whatsapp.net", state.ChatJID)
+	assert.InDelta(t, 60, state.TTLSeconds, 1)
+
+	w = doRequest(srv, http.MethodPost, "/api/v1/chats/111@s.whatsapp.net/lock", "test-key", `{"holder":"w2"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "w1", decode(w.Body.Bytes()).Holder)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats/111/lock", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "w1", decode(w.Body.Bytes()).Holder)
+
+	w = doRequest(srv, http.MethodDelete, "/api/v1/chats/111/lock?holder=w2", "test-key", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doRequest(srv, http.MethodDelete, "/api/v1/chats/111/lock?holder=w1", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, decode(w.Body.Bytes()).Locked)
+
+	// Workers sharing a key must name themselves
+	w = doRequest(srv, http.MethodPost, "/api/v1/chats/111/lock", "test-key", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(srv, http.MethodPost, "/api/v1/chats/111/lock", "test-key", `{"holder":"  "}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(srv, http.MethodDelete, "/api/v1/chats/111/lock", "test-key", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doRequest(srv, http.MethodPost, "/api/v1/chats/111/lock", "test-key", `{"holder":"w1"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.InDelta(t, defaultLockTTL.Seconds(), decode(w.Body.Bytes()).TTLSeconds, 1)
+
+	w = doRequest(srv, http.MethodPost, "/api/v1/chats/333/lock", "test-key", `{"holder":"w1","ttl_seconds":3600}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(srv, http.MethodPost, "/api/v1/chats/222/lock", "test-key", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMaintenanceMessage is returned while in maintenance mode when no
+// message was given.
+const defaultMaintenanceMessage = "server is in maintenance mode"
+
+// maintenanceRetryAfter is the Retry-After hint sent with maintenance 503s.
+const maintenanceRetryAfter = 60 * time.Second
+
+// maintenanceState is the server's maintenance mode; nil when it is off.
+type maintenanceState struct {
+	Enabled bool       `json:"enabled"`
+	Message string     `json:"message,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`
+}
+
+// maintenanceMiddleware answers every non-admin request with a 503 while
+// maintenance mode is on. The sync daemon keeps running.
+func (s *Server) maintenanceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := s.maintenance.Load()
+		if m == nil || strings.HasPrefix(r.URL.Path, "/admin/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(int(maintenanceRetryAfter.Seconds())))
+		writeError(w, http.StatusServiceUnavailable, m.Message)
+	})
+}
+
+func (s *Server) handleGetMaintenance(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	writeJSON(w, s.maintenanceStatus())
+}
+
+// handleSetMaintenance turns maintenance mode on or off, e.g. around store
+// migrations and backups.
+func (s *Server) handleSetMaintenance(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	var req struct {
+		Enabled *bool  `json:"enabled"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if req.Enabled == nil {
+		writeError(w, http.StatusBadRequest, "'enabled' field is required")
+		return
+	}
+	if *req.Enabled {
+		msg := strings.TrimSpace(req.Message)
+		if msg == "" {
+			msg = defaultMaintenanceMessage
+		}
+		since := time.Now().UTC()
+		if cur := s.maintenance.Load(); cur != nil {
+			since = *cur.Since
+		}
+		s.maintenance.Store(&maintenanceState{Enabled: true, Message: msg, Since: &since})
+	} else {
+		s.maintenance.Store(nil)
+	}
+	writeJSON(w, s.maintenanceStatus())
+}
+
+func (s *Server) maintenanceStatus() maintenanceState {
+	if m := s.maintenance.Load(); m != nil {
+		return *m
+	}
+	return maintenanceState{}
+}
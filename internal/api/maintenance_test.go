@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	mock := &mockApp{listChatsResult: `{"success":true,"data":[]}`}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodPost, "/api/v1/admin/maintenance", "test-key", `{"enabled":true,"message":"migrating store, back at 10:00"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data maintenanceState `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Data.Enabled)
+	require.NotNil(t, resp.Data.Since)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats", "test-key", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "migrating store, back at 10:00")
+
+	w = doRequest(srv, http.MethodGet, "/api/v2/chats", "test-key", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"service_unavailable"`)
+
+	// Admin endpoints and health checks stay available
+	w = doRequest(srv, http.MethodGet, "/api/v1/admin/keys", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(srv, http.MethodGet, "/healthz", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doRequest(srv, http.MethodPost, "/api/v1/admin/maintenance", "test-key", `{"enabled":false}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"success":true,"data":{"enabled":false},"error":null}`, w.Body.String())
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestMaintenanceMode_DefaultMessage(t *testing.T) {
+	srv := newTestServer(&mockApp{})
+
+	w := doRequest(srv, http.MethodPost, "/api/v1/admin/maintenance", "test-key", `{"enabled":true}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(srv, http.MethodGet, "/api/v1/admin/maintenance", "test-key", "")
+	assert.Contains(t, w.Body.String(), `"message":"server is in maintenance mode"`)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/auth/status", "test-key", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestMaintenanceMode_Validation(t *testing.T) {
+	srv := NewServer(Config{APIKey: "test-key", APIKeys: map[string]string{"crm": "crm-key"}}, &mockApp{})
+
+	w := doRequest(srv, http.MethodPost, "/api/v1/admin/maintenance", "crm-key", `{"enabled":true}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(srv, http.MethodPost, "/api/v1/admin/maintenance", "test-key", `{"message":"x"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(srv, http.MethodPost, "/api/v1/admin/maintenance", "test-key", `not json`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// noMediaContextKey marks requests made with a chat token minted without
+// the media scope.
+type noMediaContextKey struct{}
+
+// hasMediaScope reports whether the request's credentials may download
+// media. Text reads do not need it; media is more sensitive.
+func (s *Server) hasMediaScope(ctx context.Context) bool {
+	if noMedia, _ := ctx.Value(noMediaContextKey{}).(bool); noMedia {
+		return false
+	}
+	switch keyID := keyIDFromContext(ctx); {
+	case keyID == DefaultKeyID, keyID == mediaURLKeyID, s.Config.MediaKeys == nil:
+		return true
+	case strings.HasPrefix(keyID, chatTokenKeyPrefix):
+		// Chat tokens are minted by the admin, who chose their scope
+		return true
+	default:
+		return slices.Contains(s.Config.MediaKeys, keyID)
+	}
+}
+
+// requireMediaScope writes 403 and returns false unless the request may
+// download media.
+func (s *Server) requireMediaScope(w http.ResponseWriter, r *http.Request) bool {
+	if !s.hasMediaScope(r.Context()) {
+		writeError(w, http.StatusForbidden, "media scope required")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMediaScopeTestServer(t *testing.T) (*Server, *mockApp) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	require.NoError(t, os.WriteFile(path, []byte("jpeg"), 0644))
+	mock := &mockApp{
+		listMessagesResult: `{"success":true,"data":[]}`,
+		mediaFilePath:      path,
+		mediaFileMimeType:  "image/jpeg",
+		mediaZip:           []byte("PK"),
+	}
+	srv := NewServer(Config{
+		APIKey:      "test-key",
+		APIKeys:     map[string]string{"crm": "crm-key", "archive": "archive-key"},
+		MediaKeys:   []string{"archive"},
+		MaxMessages: 100,
+	}, mock)
+	return srv, mock
+}
+
+func TestMediaScope_KeysWithoutItReadTextOnly(t *testing.T) {
+	srv, _ := newMediaScopeTestServer(t)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/messages", "crm-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	for _, path := range []string{"/api/v1/media/ABC", "/api/v2/media/ABC", "/api/v1/chats/34600111222/media.zip"} {
+		w = doRequest(srv, http.MethodGet, path, "crm-key", "")
+		assert.Equal(t, http.StatusForbidden, w.Code, path)
+		assert.Contains(t, w.Body.String(), "media scope required", path)
+	}
+
+	for _, key := range []string{"archive-key", "test-key"} {
+		w = doRequest(srv, http.MethodGet, "/api/v1/media/ABC", key, "")
+		assert.Equal(t, http.StatusOK, w.Code, key)
+		assert.Equal(t, "jpeg", w.Body.String(), key)
+		w = doRequest(srv, http.MethodGet, "/api/v1/chats/34600111222/media.zip", key, "")
+		assert.Equal(t, http.StatusOK, w.Code, key)
+	}
+}
+
+func TestMediaScope_EveryKeyWithoutMediaKeys(t *testing.T) {
+	_, mock := newMediaScopeTestServer(t)
+	srv := NewServer(Config{APIKey: "test-key", APIKeys: map[string]string{"crm": "crm-key"}}, mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/media/ABC", "crm-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestMediaScope_ChatTokens(t *testing.T) {
+	srv, _ := newMediaScopeTestServer(t)
+	token := mintChatToken(t, srv, `{"chat_jid":"`+tokenGroup+`"}`)
+	textOnly := mintChatToken(t, srv, `{"chat_jid":"`+tokenGroup+`","media":false}`)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/media/ABC", token, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/messages", textOnly, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(srv, http.MethodGet, "/api/v1/media/ABC", textOnly, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats/"+tokenGroup+"/media.zip", textOnly, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// handleChatMediaZip streams the media of a chat that was downloaded as a
+// ZIP archive with a manifest.json, optionally limited to a time range and
+// a media type.
+func (s *Server) handleChatMediaZip(w http.ResponseWriter, r *http.Request) {
+	if !s.requireMediaScope(w, r) {
+		return
+	}
+	chatJID, ok := chatJIDPath(w, r)
+	if !ok {
+		return
+	}
+	if !s.phoneFilter.IsAllowed(chatJID) {
+		writeError(w, http.StatusForbidden, "chat not allowed")
+		return
+	}
+	q := r.URL.Query()
+	p := store.ChatMediaParams{ChatJID: chatJID, After: s.computeAfter(), MediaType: q.Get("type")}
+	for _, param := range []string{"after", "before"} {
+		v := q.Get(param)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid %s: use RFC 3339, e.g. 2026-01-02T15:04:05Z", param))
+			return
+		}
+		if param == "before" {
+			p.Before = &t
+		} else if p.After == nil || t.After(*p.After) {
+			p.After = &t
+		}
+	}
+	switch p.MediaType {
+	case "", "image", "video", "audio", "document", "sticker":
+	default:
+		writeError(w, http.StatusBadRequest, "invalid type: must be image, video, audio, document or sticker")
+		return
+	}
+
+	name := fmt.Sprintf("%s-media-%s.zip", strings.SplitN(chatJID, "@", 2)[0], time.Now().UTC().Format("20060102T150405Z"))
+	zw := &zipResponse{w: w, name: name}
+	if err := s.app.WriteChatMediaZip(r.Context(), p, zw); err != nil {
+		if zw.started {
+			// The archive is cut short; clients see an invalid ZIP
+			slog.Warn("media bundle aborted", "chat_jid", chatJID, "error", err)
+			return
+		}
+		status := http.StatusInternalServerError
+		if errors.Is(err, fs.ErrNotExist) {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err.Error())
+	}
+}
+
+// zipResponse sends the headers of a ZIP download on the first write, so
+// errors before it can still be answered as JSON.
+type zipResponse struct {
+	w       http.ResponseWriter
+	name    string
+	started bool
+}
+
+func (z *zipResponse) Write(p []byte) (int, error) {
+	if !z.started {
+		z.started = true
+		z.w.Header().Set("Content-Type", "application/zip")
+		z.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", z.name))
+		z.w.WriteHeader(http.StatusOK)
+	}
+	return z.w.Write(p)
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleChatMediaZip(t *testing.T) {
+	mock := &mockApp{mediaZip: []byte("PK\x03\x04")}
+	srv := NewServer(Config{APIKey: "test-key", MaxMessages: 100, MaxHours: 48, PhoneBlacklist: []string{"222"}}, mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/chats/34600111/media.zip?after=2099-01-02T15:04:05Z&type=image", "test-key", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	assert.Regexp(t, `^attachment; filename="34600111-media-\d{8}T\d{6}Z\.zip"$`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "PK\x03\x04", w.Body.String())
+	assert.Equal(t, "34600111@s.whatsapp.net", mock.lastMediaParams.ChatJID)
+	assert.Equal(t, "image", mock.lastMediaParams.MediaType)
+	require.NotNil(t, mock.lastMediaParams.After)
+	assert.Equal(t, time.Date(2099, 1, 2, 15, 4, 5, 0, time.UTC), *mock.lastMediaParams.After)
+	assert.Nil(t, mock.lastMediaParams.Before)
+
+	// MAX_HOURS wins over an earlier after
+	doRequest(srv, http.MethodGet, "/api/v1/chats/34600111/media.zip?after=2000-01-01T00:00:00Z&before=2099-01-01T00:00:00Z", "test-key", "")
+	require.NotNil(t, mock.lastMediaParams.After)
+	assert.WithinDuration(t, time.Now().Add(-48*time.Hour), *mock.lastMediaParams.After, time.Minute)
+	require.NotNil(t, mock.lastMediaParams.Before)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats/34600111/media.zip?after=yesterday", "test-key", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats/34600111/media.zip?type=gif", "test-key", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats/222/media.zip", "test-key", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	mock.mediaZip = nil
+	mock.mediaZipErr = fmt.Errorf("chat 34600111@s.whatsapp.net has no downloaded media: %w", fs.ErrNotExist)
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats/34600111/media.zip", "test-key", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "no downloaded media")
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+)
+
+// handleMergeChats folds one chat's history into another, for contacts who
+// changed phone numbers. It rewrites history across chats, so it is for the
+// admin key only.
+func (s *Server) handleMergeChats(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	var req struct {
+		From string `json:"from"`
+		Into string `json:"into"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if strings.TrimSpace(req.From) == "" || strings.TrimSpace(req.Into) == "" {
+		writeError(w, http.StatusBadRequest, "'from' and 'into' fields are required")
+		return
+	}
+	for _, chatJID := range []string{req.From, req.Into} {
+		if !s.phoneFilter.IsAllowed(jid.Normalize(chatJID)) {
+			writeError(w, http.StatusForbidden, "chat not allowed")
+			return
+		}
+	}
+	writeResult(w, s.app.MergeChats(r.Context(), req.From, req.Into))
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleMergeChats(t *testing.T) {
+	mock := &mockApp{mergeResult: `{"success":true}`}
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chats/merge", strings.NewReader(`{"from":"111","into":"222@s.whatsapp.net"}`))
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, [2]string{"111", "222@s.whatsapp.net"}, mock.lastMerge)
+}
+
+func TestHandleMergeChats_Validation(t *testing.T) {
+	mock := &mockApp{}
+	srv := newTestServer(mock)
+	srv.phoneFilter = NewPhoneFilter(nil, []string{"999"})
+
+	for body, want := range map[string]int{
+		`{`:                         http.StatusBadRequest,
+		`{"from":"111"}`:            http.StatusBadRequest,
+		`{"from":"999","into":"1"}`: http.StatusForbidden,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/chats/merge", strings.NewReader(body))
+		req.Header.Set("X-API-Key", "test-key")
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		assert.Equal(t, want, w.Code, body)
+	}
+	assert.Empty(t, mock.lastMerge)
+}
+
+func TestHandleMergeChats_AdminOnly(t *testing.T) {
+	mock := &mockApp{mergeResult: `{"success":true}`}
+	srv := NewServer(Config{APIKey: "test-key", APIKeys: map[string]string{"crm": "crm-key"}}, mock)
+
+	w := doRequest(srv, http.MethodPost, "/api/v1/chats/merge", "crm-key", `{"from":"111","into":"222"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, mock.lastMerge)
+}
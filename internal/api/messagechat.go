@@ -0,0 +1,33 @@
+package api
+
+import "net/http"
+
+// messageChatParam returns the chat_jid to pass on for a route naming a
+// message by ID, once the message's chat passed the phone filters: the
+// chat_jid query parameter if given, and otherwise the chat looked up from
+// the ID. It answers the request itself when the chat is hidden; a chat
+// looked up from the ID gets a 404, so the route does not reveal that the
+// message exists. IDs that are unknown or in several chats are passed on
+// for the handler to report.
+func (s *Server) messageChatParam(w http.ResponseWriter, r *http.Request, messageID string) (*string, bool) {
+	var chatJID *string
+	if v := r.URL.Query().Get("chat_jid"); v != "" {
+		if !s.phoneFilter.IsAllowed(v) {
+			writeError(w, http.StatusForbidden, "chat not allowed")
+			return nil, false
+		}
+		chatJID = &v
+	}
+	if !s.phoneFilter.Filtering() {
+		return chatJID, true
+	}
+	chat, err := s.app.MessageChat(messageID, chatJID)
+	if err != nil {
+		return chatJID, true
+	}
+	if !s.phoneFilter.IsAllowed(chat) {
+		writeError(w, http.StatusNotFound, "message "+messageID+" not found")
+		return nil, false
+	}
+	return &chat, true
+}
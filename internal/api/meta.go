@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// setMetaRequest is the body of PUT /{chats,contacts,messages}/{id}/meta/{key}.
+type setMetaRequest struct {
+	Value *string `json:"value"`
+}
+
+// metaPath returns the record of a metadata route: its kind, named after the
+// collection ("chats" holds chats), and the chat or contact JID or the
+// message ID with its chat as messageChatParam finds it. It answers the
+// request itself when the phone filters hide the record.
+func (s *Server) metaPath(w http.ResponseWriter, r *http.Request) (kind, target string, chatJID *string, ok bool) {
+	collection, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	kind = strings.TrimSuffix(collection, "s")
+	if kind == "message" {
+		chatJID, ok = s.messageChatParam(w, r, r.PathValue("id"))
+		if !ok {
+			return "", "", nil, false
+		}
+		return kind, r.PathValue("id"), chatJID, true
+	}
+	target, ok = chatJIDPath(w, r)
+	if !ok {
+		return "", "", nil, false
+	}
+	if !s.phoneFilter.IsAllowed(target) {
+		writeError(w, http.StatusForbidden, kind+" not allowed")
+		return "", "", nil, false
+	}
+	return kind, target, nil, true
+}
+
+func (s *Server) handleGetMeta(w http.ResponseWriter, r *http.Request) {
+	kind, target, chatJID, ok := s.metaPath(w, r)
+	if !ok {
+		return
+	}
+	writeResult(w, s.app.GetMeta(kind, target, chatJID))
+}
+
+// handleSetMeta stores a value under a key of a chat, contact or message,
+// e.g. the CRM ID of a customer's chat.
+func (s *Server) handleSetMeta(w http.ResponseWriter, r *http.Request) {
+	var req setMetaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if req.Value == nil {
+		writeError(w, http.StatusBadRequest, "'value' field is required")
+		return
+	}
+	kind, target, chatJID, ok := s.metaPath(w, r)
+	if !ok {
+		return
+	}
+	writeResult(w, s.app.SetMeta(kind, target, chatJID, r.PathValue("key"), *req.Value))
+}
+
+func (s *Server) handleDeleteMeta(w http.ResponseWriter, r *http.Request) {
+	kind, target, chatJID, ok := s.metaPath(w, r)
+	if !ok {
+		return
+	}
+	writeResult(w, s.app.DeleteMeta(kind, target, chatJID, r.PathValue("key")))
+}
+
+// handleFindMeta lists metadata across records, narrowed by kind, key and
+// value, e.g. GET /meta?key=crm_id&value=C-42 finds the chat of a customer.
+func (s *Server) handleFindMeta(w http.ResponseWriter, r *http.Request) {
+	limit := parseIntParam(r, "limit", 100)
+	page := parseIntParam(r, "page", 0)
+	if limit > s.Config.MaxMessages {
+		limit = s.Config.MaxMessages
+	}
+	q := r.URL.Query()
+	var value *string
+	if q.Has("value") {
+		v := q.Get("value")
+		value = &v
+	}
+	includeJIDs, excludeJIDs := s.phoneFilter.JIDSuffixes()
+	writeResult(w, s.app.FindMeta(q.Get("kind"), q.Get("key"), value, limit, page, includeJIDs, excludeJIDs))
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetaRoutes(t *testing.T) {
+	mock := &mockApp{metaResult: `{"success":true,"data":{"meta":{}}}`}
+	srv := NewServer(Config{APIKey: "test-key", MaxMessages: 100, PhoneBlacklist: []string{"222"}}, mock)
+
+	w := doRequest(srv, http.MethodPut, "/api/v1/chats/34600111/meta/crm_id", "test-key", `{"value":"C-42"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "chat", mock.lastMetaKind)
+	assert.Equal(t, "34600111@s.whatsapp.net", mock.lastMetaTarget)
+	assert.Equal(t, "crm_id", mock.lastMetaKey)
+	require.NotNil(t, mock.lastMetaVal)
+	assert.Equal(t, "C-42", *mock.lastMetaVal)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/contacts/34600111@s.whatsapp.net/meta", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "contact", mock.lastMetaKind)
+
+	w = doRequest(srv, http.MethodDelete, "/api/v1/messages/m1/meta/ticket?chat_jid=34600111@s.whatsapp.net", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "message", mock.lastMetaKind)
+	assert.Equal(t, "m1", mock.lastMetaTarget)
+	require.NotNil(t, mock.lastChatJID)
+	assert.Equal(t, "34600111@s.whatsapp.net", *mock.lastChatJID)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/meta?kind=chat&key=crm_id&value=", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "crm_id", mock.lastMetaKey)
+	require.NotNil(t, mock.lastMetaVal, "an empty value is a filter")
+	assert.Equal(t, "", *mock.lastMetaVal)
+	assert.Equal(t, []string{"222@"}, mock.lastExcludeJIDs)
+	doRequest(srv, http.MethodGet, "/api/v1/meta", "test-key", "")
+	assert.Nil(t, mock.lastMetaVal)
+
+	mock.lastMetaKind = ""
+	w = doRequest(srv, http.MethodPut, "/api/v1/chats/34600111/meta/crm_id", "test-key", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats/222@s.whatsapp.net/meta", "test-key", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(srv, http.MethodGet, "/api/v1/messages/m1/meta?chat_jid=222@s.whatsapp.net", "test-key", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, mock.lastMetaKind)
+
+	// Without chat_jid the message's own chat is checked
+	mock.messageChats = map[string]string{"m2": "222@s.whatsapp.net"}
+	w = doRequest(srv, http.MethodGet, "/api/v1/messages/m2/meta", "test-key", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(srv, http.MethodPut, "/api/v1/messages/m2/meta/ticket", "test-key", `{"value":"T-1"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(srv, http.MethodDelete, "/api/v1/messages/m2/meta/ticket", "test-key", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, mock.lastMetaKind)
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/vicentereig/whatsapp-cli/internal/health"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// handleMetrics exposes daemon counters in the Prometheus text exposition format.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+
+	status := s.refreshHealth()
+	writeGauge(w, "whatsapp_authenticated", "Whether the WhatsApp session is authenticated.", boolToFloat(s.health.Signals().Paired))
+	writeStateGauge(w, status.State)
+	writeGauge(w, "whatsapp_ready", "Whether the daemon is in a state configured as ready.", boolToFloat(status.Ready))
+	writeGauge(w, "whatsapp_state_since_seconds", "Unix time the daemon entered its current state.", float64(status.Since.Unix()))
+	writeStateCounter(w, "whatsapp_state_transitions_total", "Times each state was entered since startup.", s.health.Transitions())
+	writeGauge(w, "whatsapp_sync_running", "Whether the background sync daemon is running.", boolToFloat(s.syncRunning.Load()))
+	writeCounter(w, "whatsapp_messages_synced_total", "Messages synced since startup.", float64(s.messagesSynced.Load()))
+
+	if s.app != nil {
+		health := s.app.StoreHealth()
+		writeGauge(w, "whatsapp_store_healthy", "Whether writes to the message store are succeeding.", boolToFloat(health.Healthy))
+		writeGauge(w, "whatsapp_store_buffered_writes", "Writes held in memory until the message store recovers.", float64(health.Buffered))
+		writeCounter(w, "whatsapp_store_dropped_writes_total", "Buffered writes dropped because the buffer was full.", float64(health.Dropped))
+
+		outbox := s.app.OutboxStats()
+		writeSinkGauge(w, "whatsapp_outbox_pending", "Undelivered notifications queued per sink until the backend recovers.", outbox.Pending)
+		writeCounter(w, "whatsapp_outbox_replayed_total", "Queued notifications delivered on replay.", float64(outbox.Replayed))
+		writeCounter(w, "whatsapp_outbox_expired_total", "Queued notifications dropped after exceeding the maximum age.", float64(outbox.Expired))
+
+		var critical, warning float64
+		for _, a := range s.app.AccountAlerts() {
+			if a.Severity == store.AlertCritical {
+				critical++
+			} else {
+				warning++
+			}
+		}
+		fmt.Fprintf(w, "# HELP whatsapp_account_alerts Active account alerts (bans, logouts, rate limits) by severity.\n# TYPE whatsapp_account_alerts gauge\n")
+		fmt.Fprintf(w, "whatsapp_account_alerts{severity=%q} %g\n", store.AlertCritical, critical)
+		fmt.Fprintf(w, "whatsapp_account_alerts{severity=%q} %g\n", store.AlertWarning, warning)
+
+		q := s.app.ConnectionQuality()
+		writeLatencyGauge(w, "whatsapp_send_latency_seconds", "Round trip of text message sends over the last 24h.", q.Send)
+		writeGauge(w, "whatsapp_send_failures", "Text message sends that failed over the last 24h.", float64(q.Send.Failures))
+		writeLatencyGauge(w, "whatsapp_ping_latency_seconds", "Round trip of pings to WhatsApp's servers over the last 24h.", q.Ping)
+		writeGauge(w, "whatsapp_ping_failures", "Pings to WhatsApp's servers that went unanswered over the last 24h.", float64(q.Ping.Failures))
+		writeGauge(w, "whatsapp_keepalive_timeouts", "Keepalives WhatsApp did not answer over the last 24h.", float64(q.KeepaliveTimeouts))
+		writeGauge(w, "whatsapp_disconnects", "Times the connection to WhatsApp went down over the last 24h.", float64(q.Disconnects))
+		writeGauge(w, "whatsapp_offline_seconds", "Time disconnected from WhatsApp over the last 24h.", q.OfflineSeconds)
+
+		if c := s.app.CanaryStatus(); c != nil {
+			writeGauge(w, "whatsapp_canary_ok", "Whether the last canary message came back through sync in time.", boolToFloat(c.OK))
+			writeGauge(w, "whatsapp_canary_latency_seconds", "Round trip of the last successful canary message.", c.LatencySeconds)
+			writeCounter(w, "whatsapp_canary_checks_total", "Canary messages sent since startup.", float64(c.Checks))
+			writeCounter(w, "whatsapp_canary_failures_total", "Canary messages that failed to send or were not acknowledged in time.", float64(c.Failures))
+		}
+	}
+
+	writeGauge(w, "whatsapp_api_inflight_requests", "API requests currently holding a concurrency slot.", float64(s.limiter.Inflight()))
+	writeCounter(w, "whatsapp_api_rejected_requests_total", "API requests rejected with 503 by the concurrency limiter.", float64(s.limiter.rejected.Load()))
+
+	usage := s.usage.all()
+	writeKeyCounters(w, "whatsapp_api_requests_total", "API requests per key.", usage, func(u KeyUsage) int64 { return u.Requests })
+	writeKeyCounters(w, "whatsapp_api_errors_total", "API requests per key that returned a 4xx or 5xx status.", usage, func(u KeyUsage) int64 { return u.Errors })
+	writeKeyCounters(w, "whatsapp_api_messages_sent_total", "Messages sent through the API per key.", usage, func(u KeyUsage) int64 { return u.MessagesSent })
+	writeKeyCounters(w, "whatsapp_api_received_bytes_total", "Request body bytes received per key.", usage, func(u KeyUsage) int64 { return u.BytesReceived })
+	writeKeyCounters(w, "whatsapp_api_sent_bytes_total", "Response body bytes sent per key.", usage, func(u KeyUsage) int64 { return u.BytesSent })
+}
+
+// writeKeyCounters writes one counter series per API key, labelled key="<id>".
+func writeKeyCounters(w http.ResponseWriter, name, help string, usage []KeyUsage, value func(KeyUsage) int64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	for _, u := range usage {
+		fmt.Fprintf(w, "%s{key=%q} %d\n", name, u.KeyID, value(u))
+	}
+}
+
+// writeSinkGauge writes one gauge series per outbox sink, labelled sink="<name>".
+func writeSinkGauge(w http.ResponseWriter, name, help string, values map[string]int64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	sinks := make([]string, 0, len(values))
+	for sink := range values {
+		sinks = append(sinks, sink)
+	}
+	sort.Strings(sinks)
+	for _, sink := range sinks {
+		fmt.Fprintf(w, "%s{sink=%q} %d\n", name, sink, values[sink])
+	}
+}
+
+// writeLatencyGauge writes the median and 95th percentile of l, labelled
+// quantile="0.5" and quantile="0.95".
+func writeLatencyGauge(w http.ResponseWriter, name, help string, l health.Latency) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	fmt.Fprintf(w, "%s{quantile=\"0.5\"} %g\n", name, l.P50)
+	fmt.Fprintf(w, "%s{quantile=\"0.95\"} %g\n", name, l.P95)
+}
+
+// writeStateGauge writes whatsapp_state, 1 for the current state and 0 for
+// the others, labelled state="<name>".
+func writeStateGauge(w http.ResponseWriter, current health.State) {
+	fmt.Fprintf(w, "# HELP whatsapp_state Current state of the daemon (starting, pairing, backfilling, live, degraded, logged_out).\n# TYPE whatsapp_state gauge\n")
+	for _, state := range health.States {
+		fmt.Fprintf(w, "whatsapp_state{state=%q} %g\n", state, boolToFloat(state == current))
+	}
+}
+
+// writeStateCounter writes one counter series per state, labelled state="<name>".
+func writeStateCounter(w http.ResponseWriter, name, help string, values map[health.State]int64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	for _, state := range health.States {
+		fmt.Fprintf(w, "%s{state=%q} %d\n", name, state, values[state])
+	}
+}
+
+func writeGauge(w http.ResponseWriter, name, help string, value float64) {
+	writeMetric(w, name, "gauge", help, value)
+}
+
+func writeCounter(w http.ResponseWriter, name, help string, value float64) {
+	writeMetric(w, name, "counter", help, value)
+}
+
+func writeMetric(w http.ResponseWriter, name, kind, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", name, help, name, kind, name, value)
+}
+
+func boolToFloat(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
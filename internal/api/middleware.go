@@ -1,10 +1,13 @@
 package api
 
 import (
+	"context"
 	"crypto/subtle"
-	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/mediaurl"
 )
 
 func (s *Server) authMiddleware(next http.Handler) http.Handler {
@@ -13,20 +16,72 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 		if key == "" {
 			if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
 				key = strings.TrimPrefix(auth, "Bearer ")
+			} else if _, password, ok := r.BasicAuth(); ok {
+				// For webhooks that can only authenticate with credentials in
+				// the URL; the user name is ignored
+				key = password
 			}
 		}
 
-		if key == "" || subtle.ConstantTimeCompare([]byte(key), []byte(s.Config.APIKey)) != 1 {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]any{
-				"success": false,
-				"data":    nil,
-				"error":   "unauthorized",
-			})
-			return
+		ctx := r.Context()
+		keyID := s.lookupKey(key)
+		if keyID == "" {
+			if t, ok := s.verifyChatToken(key, time.Now()); ok {
+				keyID = chatTokenKeyPrefix + t.ID
+				ctx = context.WithValue(ctx, chatScopeContextKey{}, t.ChatJID)
+				ctx = context.WithValue(ctx, noMediaContextKey{}, t.NoMedia)
+			} else if chat, ok := s.verifyMediaURL(r, time.Now()); ok && key == "" {
+				// Limited to the chat like a chat token, and valid for
+				// nothing but this download
+				keyID = mediaURLKeyID
+				ctx = context.WithValue(ctx, chatScopeContextKey{}, chat)
+			} else {
+				writeError(w, http.StatusUnauthorized, "unauthorized")
+				return
+			}
 		}
 
-		next.ServeHTTP(w, r)
+		body := &countingReader{ReadCloser: r.Body}
+		if r.Body != nil {
+			r.Body = body
+		}
+		cw := &countingWriter{ResponseWriter: w}
+		next.ServeHTTP(cw, r.WithContext(context.WithValue(ctx, keyIDContextKey{}, keyID)))
+		s.usage.recordRequest(keyID, cw.status, body.n, cw.n, time.Now().UTC())
 	})
 }
+
+// mediaURLKeyID is the key ID of requests authenticated by a signed media
+// URL.
+const mediaURLKeyID = "media_url"
+
+// verifyMediaURL checks the signature of a media download made with a URL
+// from a webhook payload, returning the chat it is limited to.
+func (s *Server) verifyMediaURL(r *http.Request, now time.Time) (string, bool) {
+	if s.mediaURLs == nil || r.Method != http.MethodGet {
+		return "", false
+	}
+	messageID, ok := strings.CutPrefix(r.URL.Path, mediaurl.PathPrefix)
+	if !ok || messageID == "" || strings.Contains(messageID, "/") {
+		return "", false
+	}
+	return s.mediaURLs.Verify(messageID, r.URL.Query(), now)
+}
+
+// lookupKey returns the ID of the configured key matching key, or "" if none
+// does. Every key is compared so the timing does not reveal which one matched.
+func (s *Server) lookupKey(key string) string {
+	if key == "" {
+		return ""
+	}
+	match := ""
+	if subtle.ConstantTimeCompare([]byte(key), []byte(s.Config.APIKey)) == 1 {
+		match = DefaultKeyID
+	}
+	for id, k := range s.Config.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+			match = id
+		}
+	}
+	return match
+}
@@ -4,10 +4,16 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/mediaurl"
 )
 
 func TestAuthMiddleware_ValidKey_XAPIKey(t *testing.T) {
@@ -45,6 +51,26 @@ func TestAuthMiddleware_ValidKey_BearerToken(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+func TestAuthMiddleware_ValidKey_BasicAuth(t *testing.T) {
+	srv := NewServer(Config{APIKey: "test-secret-key"}, nil)
+
+	srv.apiMux.HandleFunc("GET /test", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/test", nil)
+	req.SetBasicAuth("sendgrid", "test-secret-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/test", nil)
+	req.SetBasicAuth("test-secret-key", "wrong-key")
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
 func TestAuthMiddleware_InvalidKey(t *testing.T) {
 	srv := NewServer(Config{APIKey: "test-secret-key"}, nil)
 
@@ -127,3 +153,37 @@ func TestAuthMiddleware_ReadyzNoAuth(t *testing.T) {
 	// 503 because not authenticated/syncing, but NOT 401
 	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
 }
+
+func TestAuthMiddleware_SignedMediaURL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	require.NoError(t, os.WriteFile(path, []byte("jpeg-bytes"), 0644))
+	mock := &mockApp{
+		mediaFilePath:      path,
+		listMessagesResult: `{"success":true,"data":[]}`,
+	}
+	srv := NewServer(Config{APIKey: "test-key", MaxMessages: 100, PublicURL: "https://wa.example.com"}, mock)
+	signer := mediaurl.New("test-key", "https://wa.example.com", time.Minute)
+	get := func(rawURL string) int {
+		u, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		return doRequest(srv, http.MethodGet, u.RequestURI(), "", "").Code
+	}
+
+	signed, _ := signer.URL("abc", tokenGroup, time.Now())
+	assert.Equal(t, http.StatusOK, get(signed))
+
+	expired, _ := signer.URL("abc", tokenGroup, time.Now().Add(-time.Hour))
+	assert.Equal(t, http.StatusUnauthorized, get(expired))
+	assert.Equal(t, http.StatusUnauthorized, get(strings.Replace(signed, "/media/abc", "/media/other", 1)))
+	assert.Equal(t, http.StatusUnauthorized, get(strings.Replace(signed, "chat_jid=", "chat_jid=1", 1)))
+	other, _ := mediaurl.New("other-key", "https://wa.example.com", time.Minute).URL("abc", tokenGroup, time.Now())
+	assert.Equal(t, http.StatusUnauthorized, get(other))
+
+	// The signature only opens the download it was made for
+	u, _ := url.Parse(signed)
+	assert.Equal(t, http.StatusUnauthorized, get("/api/v1/messages?"+u.RawQuery))
+
+	// Without PUBLIC_URL, signed URLs are not accepted
+	srv = newTestServer(mock)
+	assert.Equal(t, http.StatusUnauthorized, get(signed))
+}
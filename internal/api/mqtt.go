@@ -0,0 +1,77 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/mqtt"
+)
+
+// MQTTKeyID identifies the sends received over MQTT in key usage and
+// concurrency limits, as if they were made with an API key of that ID.
+const MQTTKeyID = "mqtt"
+
+// mqttCommand is the payload of the MQTT command topic: the body of
+// POST /messages/send, plus an optional ID echoed in the result.
+type mqttCommand struct {
+	ID string `json:"id,omitempty"`
+}
+
+// RunMQTTCommands sends the messages published to topic until ctx is
+// cancelled. Each command goes through the same limits, maintenance mode and
+// phone filtering as POST /api/v1/messages/send, and its response is
+// published to "<topic>/result".
+func (s *Server) RunMQTTCommands(ctx context.Context, client *mqtt.Client, topic string) {
+	s.usage.add(MQTTKeyID)
+	handler := s.limitMiddleware(s.maintenanceMiddleware(s.replicaMiddleware(s.apiMux)))
+	err := client.Subscribe(topic, func(_ string, payload []byte) {
+		go s.runMQTTCommand(ctx, client, handler, topic, payload)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠ MQTT commands: %v\n", err)
+		return
+	}
+	client.Run(ctx)
+}
+
+func (s *Server) runMQTTCommand(ctx context.Context, client *mqtt.Client, handler http.Handler, topic string, payload []byte) {
+	var cmd mqttCommand
+	json.Unmarshal(payload, &cmd)
+
+	ctx = context.WithValue(ctx, keyIDContextKey{}, MQTTKeyID)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "/messages/send", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "mqtt"
+	w := &mqttResponse{header: make(http.Header), status: http.StatusOK}
+	handler.ServeHTTP(w, req)
+	s.usage.recordRequest(MQTTKeyID, w.status, int64(len(payload)), int64(w.body.Len()), time.Now())
+
+	var result map[string]any
+	if err := json.Unmarshal(w.body.Bytes(), &result); err != nil {
+		result = map[string]any{"success": false, "data": nil, "error": w.body.String()}
+	}
+	result["status"] = w.status
+	if cmd.ID != "" {
+		result["id"] = cmd.ID
+	}
+	out, _ := json.Marshal(result)
+	if err := client.Publish(ctx, topic+"/result", out, false); err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "⚠ MQTT commands: %v\n", err)
+	}
+}
+
+// mqttResponse captures the response to an MQTT command.
+type mqttResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (m *mqttResponse) Header() http.Header         { return m.header }
+func (m *mqttResponse) WriteHeader(status int)      { m.status = status }
+func (m *mqttResponse) Write(p []byte) (int, error) { return m.body.Write(p) }
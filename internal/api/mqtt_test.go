@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/mqtt"
+	"github.com/vicentereig/whatsapp-cli/internal/mqtt/mqtttest"
+)
+
+func TestMQTTCommands(t *testing.T) {
+	broker := mqtttest.New(t)
+	mock := &mockApp{sendMessageResult: `{"success":true,"data":{"id":"M1"}}`}
+	srv := NewServer(Config{APIKey: "test-key", MaxMessages: 100, PhoneWhitelist: []string{"1234567890"}}, mock)
+
+	client, err := mqtt.New(broker.URL(), "test-client")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		srv.RunMQTTCommands(ctx, client, "wa/send")
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+		client.Close()
+	}()
+	require.Eventually(t, func() bool { return broker.Subscribed("wa/send") }, time.Second, 5*time.Millisecond)
+
+	results := func(n int) []map[string]any {
+		var out []map[string]any
+		require.Eventually(t, func() bool {
+			out = nil
+			for _, m := range broker.Messages() {
+				if m.Topic == "wa/send/result" {
+					var r map[string]any
+					require.NoError(t, json.Unmarshal([]byte(m.Payload), &r))
+					out = append(out, r)
+				}
+			}
+			return len(out) == n
+		}, time.Second, 5*time.Millisecond)
+		return out
+	}
+
+	broker.Publish("wa/send", `{"id":"c1","to":"1234567890","message":"hi"}`, false)
+	res := results(1)[0]
+	assert.Equal(t, true, res["success"])
+	assert.Equal(t, "c1", res["id"])
+	assert.EqualValues(t, 200, res["status"])
+	assert.Equal(t, "1234567890", mock.lastSendRecipient)
+
+	// The phone filter applies as it does over HTTP
+	broker.Publish("wa/send", `{"id":"c2","to":"5550001111","message":"hi"}`, false)
+	res = results(2)[1]
+	assert.Equal(t, false, res["success"])
+	assert.Equal(t, "c2", res["id"])
+	assert.EqualValues(t, 403, res["status"])
+
+	usage, ok := srv.usage.get(MQTTKeyID)
+	require.True(t, ok)
+	assert.EqualValues(t, 2, usage.Requests)
+	assert.EqualValues(t, 1, usage.Errors)
+	assert.EqualValues(t, 1, usage.MessagesSent)
+}
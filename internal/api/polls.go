@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+)
+
+type sendPollRequest struct {
+	To       string   `json:"to"`
+	Question string   `json:"question"`
+	Options  []string `json:"options"`
+	// SelectableCount is how many options a voter may pick; 0 or omitted
+	// allows any number.
+	SelectableCount int `json:"selectable_count,omitempty"`
+}
+
+// handleSendPoll sends a poll. The result includes the ID of its message,
+// under which GET /messages/{id}/poll reports the votes.
+func (s *Server) handleSendPoll(w http.ResponseWriter, r *http.Request) {
+	var req sendPollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if req.To == "" || req.Question == "" || len(req.Options) == 0 {
+		writeError(w, http.StatusBadRequest, "'to', 'question' and 'options' fields are required")
+		return
+	}
+	recipient, err := jid.ParseRecipient(req.To)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !s.phoneFilter.IsAllowed(recipient) {
+		writeError(w, http.StatusForbidden, "recipient not allowed")
+		return
+	}
+
+	result := s.app.SendPoll(r.Context(), req.To, req.Question, req.Options, req.SelectableCount)
+	if resultSucceeded(result) {
+		s.usage.recordSend(keyIDFromContext(r.Context()))
+	}
+	writeResult(w, result)
+}
+
+// handlePollResults reports the votes of a poll by option. ?chat_jid= picks
+// the chat when the ID is not unique.
+func (s *Server) handlePollResults(w http.ResponseWriter, r *http.Request) {
+	chatJID, ok := s.messageChatParam(w, r, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	writeResult(w, s.app.PollResults(r.PathValue("id"), chatJID))
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleSendPoll(t *testing.T) {
+	mock := &mockApp{pollResult: `{"success":true,"data":{"message_id":"P1"}}`}
+	srv := NewServer(Config{APIKey: "test-key", MaxMessages: 100, PhoneBlacklist: []string{"2225550123"}}, mock)
+
+	w := doRequest(srv, http.MethodPost, "/api/v1/messages/send-poll", "test-key",
+		`{"to":"1234567890","question":"Lunch?","options":["Pizza","Sushi"],"selectable_count":1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"message_id":"P1"`)
+	assert.Equal(t, []string{"1234567890", "Lunch?", "Pizza", "Sushi"}, mock.lastPoll)
+
+	w = doRequest(srv, http.MethodPost, "/api/v1/messages/send-poll", "test-key", `{"to":"1234567890","question":"Lunch?"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(srv, http.MethodPost, "/api/v1/messages/send-poll", "test-key", `{"to":"2225550123","question":"Lunch?","options":["a","b"]}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestHandlePollResults(t *testing.T) {
+	mock := &mockApp{pollResult: `{"success":true,"data":{"question":"Lunch?"}}`}
+	srv := NewServer(Config{APIKey: "test-key", MaxMessages: 100, PhoneBlacklist: []string{"222"}}, mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/messages/P1/poll?chat_jid=111@g.us", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "P1", mock.lastPollID)
+	require.NotNil(t, mock.lastChatJID)
+	assert.Equal(t, "111@g.us", *mock.lastChatJID)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/messages/P1/poll?chat_jid=222@s.whatsapp.net", "test-key", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Without chat_jid the poll's own chat is checked
+	mock.messageChats = map[string]string{"P2": "222@s.whatsapp.net", "P3": "111@g.us"}
+	mock.lastPollID = ""
+	w = doRequest(srv, http.MethodGet, "/api/v1/messages/P2/poll", "test-key", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, mock.lastPollID)
+	w = doRequest(srv, http.MethodGet, "/api/v1/messages/P3/poll", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, mock.lastChatJID)
+	assert.Equal(t, "111@g.us", *mock.lastChatJID)
+}
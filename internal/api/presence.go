@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// presenceRequest is the body of POST /chats/{jid}/typing, with state
+// "composing" or "paused", and of POST /presence, with "available" or
+// "unavailable".
+type presenceRequest struct {
+	State string `json:"state"`
+}
+
+// handleTyping shows or clears the "typing…" indicator in a chat, so bots
+// can appear to type before they answer.
+func (s *Server) handleTyping(w http.ResponseWriter, r *http.Request) {
+	chatJID, ok := chatJIDPath(w, r)
+	if !ok {
+		return
+	}
+	if !s.phoneFilter.IsAllowed(chatJID) {
+		writeError(w, http.StatusForbidden, "chat not allowed")
+		return
+	}
+	var req presenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if req.State != "composing" && req.State != "paused" {
+		writeError(w, http.StatusBadRequest, "'state' must be composing or paused")
+		return
+	}
+	writeResult(w, s.app.SendTyping(r.Context(), chatJID, req.State == "composing"))
+}
+
+// handlePresence shows the account as online or offline to its contacts.
+func (s *Server) handlePresence(w http.ResponseWriter, r *http.Request) {
+	var req presenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if req.State != "available" && req.State != "unavailable" {
+		writeError(w, http.StatusBadRequest, "'state' must be available or unavailable")
+		return
+	}
+	writeResult(w, s.app.SendPresence(r.Context(), req.State == "available"))
+}
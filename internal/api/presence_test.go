@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleTypingAndPresence(t *testing.T) {
+	mock := &mockApp{}
+	srv := NewServer(Config{APIKey: "test-key", PhoneBlacklist: []string{"222"}}, mock)
+
+	w := doRequest(srv, http.MethodPost, "/api/v1/chats/34600111/typing", "test-key", `{"state":"composing"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NotNil(t, mock.lastTyping)
+	assert.True(t, *mock.lastTyping)
+	assert.Equal(t, "34600111@s.whatsapp.net", *mock.lastChatJID)
+
+	w = doRequest(srv, http.MethodPost, "/api/v1/chats/34600111/typing", "test-key", `{"state":"paused"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, *mock.lastTyping)
+
+	w = doRequest(srv, http.MethodPost, "/api/v1/chats/34600111/typing", "test-key", `{"state":"recording"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(srv, http.MethodPost, "/api/v1/chats/222/typing", "test-key", `{"state":"composing"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = doRequest(srv, http.MethodPost, "/api/v1/presence", "test-key", `{"state":"available"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, mock.lastPresence)
+	assert.True(t, *mock.lastPresence)
+	w = doRequest(srv, http.MethodPost, "/api/v1/presence", "test-key", `{"state":"unavailable"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, *mock.lastPresence)
+	w = doRequest(srv, http.MethodPost, "/api/v1/presence", "test-key", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// readRequest is the optional body of POST /chats/{jid}/read. Without
+// message_ids, the chat's unread messages are marked read.
+type readRequest struct {
+	MessageIDs []string `json:"message_ids"`
+}
+
+// handleMarkChatRead sends read receipts for a chat's messages, which clears
+// its unread badge on the phone.
+func (s *Server) handleMarkChatRead(w http.ResponseWriter, r *http.Request) {
+	chatJID, ok := chatJIDPath(w, r)
+	if !ok {
+		return
+	}
+	if !s.phoneFilter.IsAllowed(chatJID) {
+		writeError(w, http.StatusForbidden, "chat not allowed")
+		return
+	}
+	var req readRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	writeResult(w, s.app.MarkChatRead(r.Context(), chatJID, req.MessageIDs))
+}
+
+// handleChatReadState reports how far a chat was read and how many of its
+// messages are unread.
+func (s *Server) handleChatReadState(w http.ResponseWriter, r *http.Request) {
+	chatJID, ok := chatJIDPath(w, r)
+	if !ok {
+		return
+	}
+	if !s.phoneFilter.IsAllowed(chatJID) {
+		writeError(w, http.StatusForbidden, "chat not allowed")
+		return
+	}
+	writeResult(w, s.app.ChatReadState(chatJID))
+}
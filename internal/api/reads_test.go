@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleChatRead(t *testing.T) {
+	mock := &mockApp{}
+	srv := NewServer(Config{APIKey: "test-key", PhoneBlacklist: []string{"222"}}, mock)
+
+	w := doRequest(srv, http.MethodPost, "/api/v1/chats/34600111/read", "test-key", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "34600111@s.whatsapp.net", *mock.lastChatJID)
+	assert.Nil(t, mock.lastReadIDs)
+
+	w = doRequest(srv, http.MethodPost, "/api/v1/chats/34600111/read", "test-key", `{"message_ids":["M1","M2"]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"M1", "M2"}, mock.lastReadIDs)
+
+	w = doRequest(srv, http.MethodPost, "/api/v1/chats/34600111/read", "test-key", `{"message_ids":`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(srv, http.MethodPost, "/api/v1/chats/222/read", "test-key", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats/34600222/read", "test-key", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "34600222@s.whatsapp.net", *mock.lastChatJID)
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats/222/read", "test-key", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,13 @@
+package api
+
+import "net/http"
+
+// handleMessageStatus reports whether a message the account sent was
+// delivered, read or played, and by whom.
+func (s *Server) handleMessageStatus(w http.ResponseWriter, r *http.Request) {
+	chatJID, ok := s.messageChatParam(w, r, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	writeResult(w, s.app.MessageStatus(r.PathValue("id"), chatJID))
+}
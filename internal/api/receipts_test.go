@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleMessageStatus(t *testing.T) {
+	mock := &mockApp{}
+	srv := NewServer(Config{APIKey: "test-key", PhoneBlacklist: []string{"222"}}, mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/messages/M1/status", "test-key", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "M1", mock.lastStatusID)
+	assert.Nil(t, mock.lastChatJID)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/messages/M1/status?chat_jid=34600111@s.whatsapp.net", "test-key", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, mock.lastChatJID)
+	assert.Equal(t, "34600111@s.whatsapp.net", *mock.lastChatJID)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/messages/M1/status?chat_jid=222@s.whatsapp.net", "test-key", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Without chat_jid the message's own chat is checked
+	mock.messageChats = map[string]string{"M2": "222@s.whatsapp.net", "M3": "111@g.us"}
+	mock.lastStatusID = ""
+	w = doRequest(srv, http.MethodGet, "/api/v1/messages/M2/status", "test-key", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, mock.lastStatusID)
+	w = doRequest(srv, http.MethodGet, "/api/v1/messages/M3/status", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, mock.lastChatJID)
+	assert.Equal(t, "111@g.us", *mock.lastChatJID)
+}
@@ -0,0 +1,383 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vicentereig/whatsapp-cli/internal/reqid"
+)
+
+const (
+	// defaultDebugRecordRequests is how many requests DEBUG_RECORD_REQUESTS=true
+	// keeps.
+	defaultDebugRecordRequests = 100
+	maxDebugRecordRequests     = 10000
+	// maxRecordedBody is how much of each request and response body is kept.
+	maxRecordedBody = 64 << 10
+	// maxRecordedBytes bounds the memory all recordings take; the oldest are
+	// dropped to stay under it.
+	maxRecordedBytes = 16 << 20
+	redacted         = "[REDACTED]"
+)
+
+// recordedExchange is an API request and the response it got, with secrets
+// redacted.
+type recordedExchange struct {
+	RequestID  string           `json:"request_id"`
+	Time       time.Time        `json:"time"`
+	DurationMS int64            `json:"duration_ms"`
+	RemoteAddr string           `json:"remote_addr"`
+	Request    recordedRequest  `json:"request"`
+	Response   recordedResponse `json:"response"`
+}
+
+type recordedRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers"`
+	recordedBody
+}
+
+type recordedResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers"`
+	recordedBody
+}
+
+// recordedBody is the start of a body. Binary bodies are only measured.
+type recordedBody struct {
+	Body      string `json:"body,omitempty"`
+	Size      int64  `json:"size"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Binary    bool   `json:"binary,omitempty"`
+}
+
+// size estimates the memory an exchange takes.
+func (e *recordedExchange) size() int {
+	n := len(e.RequestID) + len(e.RemoteAddr) + len(e.Request.Method) + len(e.Request.URL) +
+		len(e.Request.Body) + len(e.Response.Body)
+	for _, h := range []http.Header{e.Request.Headers, e.Response.Headers} {
+		for name, values := range h {
+			n += len(name)
+			for _, v := range values {
+				n += len(v)
+			}
+		}
+	}
+	return n
+}
+
+// requestRecorder keeps the last exchanges, at most max of them and
+// maxRecordedBytes in all.
+type requestRecorder struct {
+	mu      sync.Mutex
+	max     int
+	entries []recordedExchange // oldest first
+	bytes   int
+}
+
+func newRequestRecorder(size int) *requestRecorder {
+	if size <= 0 {
+		return nil
+	}
+	return &requestRecorder{max: size}
+}
+
+func (rr *requestRecorder) add(e recordedExchange) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	rr.entries = append(rr.entries, e)
+	rr.bytes += e.size()
+	for len(rr.entries) > 1 && (len(rr.entries) > rr.max || rr.bytes > maxRecordedBytes) {
+		rr.bytes -= rr.entries[0].size()
+		rr.entries[0] = recordedExchange{}
+		rr.entries = rr.entries[1:]
+	}
+}
+
+// recent returns up to limit exchanges, newest first; limit <= 0 returns
+// them all.
+func (rr *requestRecorder) recent(limit int) []recordedExchange {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	n := len(rr.entries)
+	if limit <= 0 || limit > n {
+		limit = n
+	}
+	out := make([]recordedExchange, 0, limit)
+	for i := 1; i <= limit; i++ {
+		out = append(out, rr.entries[n-i])
+	}
+	return out
+}
+
+// recordMiddleware records every request and its response when
+// DEBUG_RECORD_REQUESTS is set. It runs inside requestIDMiddleware, before
+// authentication, so rejected requests are recorded too.
+func (s *Server) recordMiddleware(next http.Handler) http.Handler {
+	if s.recorder == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Reading the recordings must not push them out
+		if strings.HasSuffix(r.URL.Path, "/admin/debug/requests") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		body := &capturingBody{ReadCloser: r.Body}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = body
+		}
+		rw := &recordingWriter{ResponseWriter: w}
+		next.ServeHTTP(rw, r)
+
+		status := rw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		respHeaders := rw.header
+		if respHeaders == nil {
+			respHeaders = w.Header()
+		}
+		s.recorder.add(recordedExchange{
+			RequestID:  reqid.FromContext(r.Context()),
+			Time:       start.UTC(),
+			DurationMS: time.Since(start).Milliseconds(),
+			RemoteAddr: r.RemoteAddr,
+			Request: recordedRequest{
+				Method:       r.Method,
+				URL:          redactURL(r.URL),
+				Headers:      redactHeaders(r.Header),
+				recordedBody: body.buf.record(r.Header.Get("Content-Type")),
+			},
+			Response: recordedResponse{
+				Status:       status,
+				Headers:      redactHeaders(respHeaders),
+				recordedBody: rw.buf.record(respHeaders.Get("Content-Type")),
+			},
+		})
+	})
+}
+
+// bodyBuffer keeps the first maxRecordedBody bytes written to it and counts
+// the rest.
+type bodyBuffer struct {
+	data bytes.Buffer
+	size int64
+}
+
+func (b *bodyBuffer) write(p []byte) {
+	b.size += int64(len(p))
+	if room := maxRecordedBody - b.data.Len(); room > 0 {
+		b.data.Write(p[:min(room, len(p))])
+	}
+}
+
+func (b *bodyBuffer) record(contentType string) recordedBody {
+	rb := recordedBody{Size: b.size, Truncated: b.size > int64(b.data.Len())}
+	data := b.data.Bytes()
+	if len(data) == 0 {
+		return rb
+	}
+	// A truncated body may end in the middle of a character
+	for i := 1; rb.Truncated && i < utf8.UTFMax && !utf8.Valid(data); i++ {
+		data = data[:len(data)-1]
+	}
+	if !utf8.Valid(data) {
+		rb.Binary = true
+		return rb
+	}
+	rb.Body = redactBody(contentType, string(data), rb.Truncated)
+	return rb
+}
+
+// capturingBody copies what the handler reads of a request body.
+type capturingBody struct {
+	io.ReadCloser
+	buf bodyBuffer
+}
+
+func (c *capturingBody) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.buf.write(p[:n])
+	return n, err
+}
+
+// recordingWriter copies the status, headers and body of a response.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	buf    bodyBuffer
+}
+
+func (rw *recordingWriter) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+		rw.header = rw.ResponseWriter.Header().Clone()
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recordingWriter) Write(p []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+		rw.header = rw.ResponseWriter.Header().Clone()
+	}
+	n, err := rw.ResponseWriter.Write(p)
+	rw.buf.write(p[:n])
+	return n, err
+}
+
+// Flush keeps streaming handlers working behind the recorder.
+func (rw *recordingWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets handlers hijack the connection, e.g. for WebSockets.
+func (rw *recordingWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// secretName reports whether a header, query parameter or body field of
+// this name holds a credential.
+func secretName(name string) bool {
+	name = strings.ToLower(name)
+	switch name {
+	case "key", "sig", "cookie", "set-cookie":
+		return true
+	}
+	for _, s := range []string{"token", "secret", "password", "passwd", "authorization", "api_key", "apikey", "api-key", "signature", "credential"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactHeaders(h http.Header) http.Header {
+	out := h.Clone()
+	for name := range out {
+		if secretName(name) {
+			out[name] = []string{redacted}
+		}
+	}
+	return out
+}
+
+func redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Path
+	}
+	return u.Path + "?" + redactValues(u.Query()).Encode()
+}
+
+func redactValues(v url.Values) url.Values {
+	for name, values := range v {
+		if secretName(name) {
+			v[name] = []string{redacted}
+			continue
+		}
+		for i := range values {
+			values[i] = redactLink(values[i])
+		}
+	}
+	return v
+}
+
+// redactLink blanks the credentials a URL passed as a value may carry: its
+// userinfo, e.g. a calendar's basic auth, and every query value, e.g. a
+// webhook's token or a signed media link's signature. Other values are
+// returned as they are.
+func redactLink(s string) string {
+	if !strings.Contains(s, "://") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" || (u.User == nil && u.RawQuery == "") {
+		return s
+	}
+	if u.User != nil {
+		u.User = url.User(redacted)
+	}
+	if u.RawQuery != "" {
+		q := u.Query()
+		for name := range q {
+			q[name] = []string{redacted}
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
+// redactBody blanks the secret fields of JSON and form bodies. Truncated JSON
+// cannot be searched for them and is left out; other bodies are kept as they
+// are.
+func redactBody(contentType, body string, truncated bool) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded" && !truncated:
+		if v, err := url.ParseQuery(body); err == nil {
+			return redactValues(v).Encode()
+		}
+	// Clients often leave out the Content-Type of JSON requests
+	case mediaType == "" || mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var v any
+		if json.Unmarshal([]byte(body), &v) == nil {
+			var out strings.Builder
+			enc := json.NewEncoder(&out)
+			enc.SetEscapeHTML(false)
+			if enc.Encode(redactJSON(v)) == nil {
+				return strings.TrimSuffix(out.String(), "\n")
+			}
+		}
+		if truncated && (mediaType != "" || strings.HasPrefix(strings.TrimSpace(body), "{")) {
+			return redacted + " (truncated JSON)"
+		}
+	}
+	return body
+}
+
+func redactJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if secretName(k) {
+				if _, isString := val.(string); isString {
+					v[k] = redacted
+					continue
+				}
+			}
+			v[k] = redactJSON(val)
+		}
+	case []any:
+		for i := range v {
+			v[i] = redactJSON(v[i])
+		}
+	case string:
+		return redactLink(v)
+	}
+	return v
+}
+
+func (s *Server) handleDebugRequests(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	if s.recorder == nil {
+		writeError(w, http.StatusNotFound, "request recording is disabled; set DEBUG_RECORD_REQUESTS")
+		return
+	}
+	writeJSON(w, s.recorder.recent(parseIntParam(r, "limit", 0)))
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"runtime/debug"
+
+	"github.com/vicentereig/whatsapp-cli/internal/reqid"
+)
+
+// recoverMiddleware turns a handler panic into a JSON 500 response instead
+// of a dropped connection. The panic is logged to stderr with its stack and
+// request ID, and reported to the error reporter if one is configured. When
+// the handler had already started its response the status can no longer be
+// changed, so the response is aborted rather than left looking complete.
+func (s *Server) recoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cw := &countingWriter{ResponseWriter: w}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				// Deliberate abort, which net/http handles quietly
+				panic(v)
+			}
+			id := reqid.FromContext(r.Context())
+			fmt.Fprintf(os.Stderr, "panic: request_id=%s method=%s path=%s: %v\n%s",
+				id, r.Method, r.URL.Path, v, debug.Stack())
+			s.reporter.CapturePanic(v, map[string]string{
+				"component":  "api",
+				"method":     r.Method,
+				"path":       r.URL.Path,
+				"request_id": id,
+			})
+			if cw.status != 0 {
+				panic(http.ErrAbortHandler)
+			}
+			writeError(w, http.StatusInternalServerError, "internal server error")
+		}()
+		next.ServeHTTP(cw, r)
+	})
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vicentereig/whatsapp-cli/internal/sentry"
+)
+
+func TestRecoverMiddleware_ReportsPanic(t *testing.T) {
+	reported := make(chan string, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		reported <- string(body)
+	}))
+	defer collector.Close()
+	reporter, err := sentry.New("http://key@"+collector.Listener.Addr().String()+"/1", "test", "")
+	require.NoError(t, err)
+
+	s := &Server{reporter: reporter}
+	h := s.requestIDMiddleware(s.recoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler bug")
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/chats/34600111222@s.whatsapp.net/mode", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.JSONEq(t, `{"success":false,"data":null,"error":"internal server error","request_id":"req-1"}`, rec.Body.String())
+
+	require.True(t, reporter.Flush(5*time.Second))
+	body := <-reported
+	assert.Contains(t, body, "handler bug")
+	assert.Contains(t, body, `"request_id":"req-1"`)
+	assert.Contains(t, body, "/chats/[phone]@s.whatsapp.net/mode")
+	assert.False(t, strings.Contains(body, "34600111222"))
+}
+
+func TestRecoverMiddleware_V1AndV2(t *testing.T) {
+	srv := newTestServer(&mockApp{})
+	srv.apiMux.HandleFunc("GET /boom", func(w http.ResponseWriter, r *http.Request) {
+		var m map[string]int
+		m["x"] = 1
+	})
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/boom", "test-key", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	id := w.Header().Get("X-Request-ID")
+	assert.NotEmpty(t, id)
+	assert.JSONEq(t, `{"success":false,"data":null,"error":"internal server error","request_id":"`+id+`"}`, w.Body.String())
+
+	w = doRequest(srv, http.MethodGet, "/api/v2/boom", "test-key", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	var resp v2Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, "internal_server_error", resp.Error.Code)
+	assert.Equal(t, w.Header().Get("X-Request-ID"), resp.Meta.RequestID)
+
+	// The server keeps serving after a panic
+	w = doRequest(srv, http.MethodGet, "/api/v1/auth/status", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRecoverMiddleware_AbortsStartedResponse(t *testing.T) {
+	s := &Server{}
+	h := s.recoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success":true,"data":[`))
+		panic("half way")
+	}))
+	rec := httptest.NewRecorder()
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/messages", nil))
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "internal server error")
+}
+
+func TestRecoverMiddleware_ErrAbortHandler(t *testing.T) {
+	s := &Server{}
+	h := s.recoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
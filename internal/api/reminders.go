@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+func (s *Server) handleListReminders(w http.ResponseWriter, r *http.Request) {
+	writeResult(w, s.app.ListReminders())
+}
+
+func (s *Server) handleCreateReminder(w http.ResponseWriter, r *http.Request) {
+	var req store.Reminder
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	for _, to := range req.Recipients {
+		recipient, err := jid.ParseRecipient(to)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !s.phoneFilter.IsAllowed(recipient) {
+			writeError(w, http.StatusForbidden, "recipient not allowed")
+			return
+		}
+	}
+	writeResult(w, s.app.CreateReminder(req))
+}
+
+func (s *Server) handleGetReminder(w http.ResponseWriter, r *http.Request) {
+	id, ok := reminderIDPath(w, r)
+	if !ok {
+		return
+	}
+	writeResult(w, s.app.GetReminder(id))
+}
+
+func (s *Server) handleDeleteReminder(w http.ResponseWriter, r *http.Request) {
+	id, ok := reminderIDPath(w, r)
+	if !ok {
+		return
+	}
+	writeResult(w, s.app.DeleteReminder(id))
+}
+
+// reminderIDPath reads the {id} path value. It writes a 400 and returns
+// false if it is not a number.
+func reminderIDPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid reminder ID")
+		return 0, false
+	}
+	return id, true
+}
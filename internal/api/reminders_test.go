@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleCreateReminder(t *testing.T) {
+	mock := &mockApp{remindersResult: `{"success":true,"data":{"id":1}}`}
+	srv := NewServer(Config{APIKey: "test-key", MaxMessages: 100, PhoneWhitelist: []string{"34600111222"}}, mock)
+
+	body := `{"name":"Salon","calendar_url":"https://cal.example.com/a.ics","recipients":["34600111222"],"lead_minutes":30}`
+	w := doRequest(srv, http.MethodPost, "/api/v1/reminders", "test-key", body)
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, mock.lastReminder)
+	assert.Equal(t, "Salon", mock.lastReminder.Name)
+	assert.Equal(t, 30, mock.lastReminder.LeadMinutes)
+
+	// Recipients go through the phone filter, like sends
+	mock.lastReminder = nil
+	w = doRequest(srv, http.MethodPost, "/api/v1/reminders", "test-key", `{"name":"x","recipients":["34600999999"]}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Nil(t, mock.lastReminder)
+
+	w = doRequest(srv, http.MethodPost, "/api/v1/reminders", "test-key", `{"name":"x","recipients":["12"]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(srv, http.MethodPost, "/api/v1/reminders", "test-key", `{`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandleReminderByID(t *testing.T) {
+	mock := &mockApp{remindersResult: `{"success":true,"data":{"deleted":true}}`}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodDelete, "/api/v1/reminders/7", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.EqualValues(t, 7, mock.lastReminderID)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/reminders/3", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.EqualValues(t, 3, mock.lastReminderID)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/reminders/abc", "test-key", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// v2 maps a missing reminder to 404
+	mock.remindersResult = `{"success":false,"data":null,"error":"reminder 9 not found"}`
+	w = doRequest(srv, http.MethodGet, "/api/v2/reminders/9", "test-key", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
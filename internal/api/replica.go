@@ -0,0 +1,25 @@
+package api
+
+import "net/http"
+
+// replicaWrites are the non-GET routes a read-only replica still serves:
+// they read the store or only change the server's own state.
+var replicaWrites = map[string]bool{
+	"/admin/query":        true,
+	"/admin/db/anonymize": true,
+	"/admin/tokens":       true,
+	"/admin/maintenance":  true,
+	"/chain/verify":       true,
+}
+
+// replicaMiddleware rejects requests that would write to the store or talk
+// to WhatsApp when the server is a read-only replica.
+func (s *Server) replicaMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Config.Replica && r.Method != http.MethodGet && r.Method != http.MethodHead && !replicaWrites[r.URL.Path] {
+			writeError(w, http.StatusForbidden, "this server is a read-only replica; send writes to the primary")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicaMode(t *testing.T) {
+	mock := &mockApp{
+		listChatsResult: `{"success":true,"data":[]}`,
+		adminResult:     `{"success":true,"data":{"rows":[]}}`,
+	}
+	srv := NewServer(Config{APIKey: "test-key", MaxMessages: 100, Replica: true}, mock)
+
+	w := doRequest(srv, http.MethodGet, "/readyz", "", "")
+	assert.Equal(t, http.StatusOK, w.Code, "ready without a WhatsApp session")
+	assert.Contains(t, w.Body.String(), `"mode":"replica"`)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(srv, http.MethodPost, "/api/v1/admin/query", "test-key", `{"sql":"SELECT 1"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	for _, req := range []struct{ method, path, body string }{
+		{http.MethodPost, "/api/v1/messages/send", `{"to":"1234567890","message":"hi"}`},
+		{http.MethodPut, "/api/v1/chats/1234567890/draft", `{"text":"hi"}`},
+		{http.MethodPost, "/api/v1/chats/merge", `{"from":"1","into":"2"}`},
+		{http.MethodPost, "/api/v2/messages/send", `{"to":"1234567890","message":"hi"}`},
+	} {
+		w := doRequest(srv, req.method, req.path, "test-key", req.body)
+		assert.Equal(t, http.StatusForbidden, w.Code, req.path)
+		assert.Contains(t, w.Body.String(), "read-only replica", req.path)
+	}
+	assert.Empty(t, mock.lastSendRecipient)
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/reqid"
+)
+
+// requestIDMiddleware accepts a valid X-Request-ID from the client or
+// generates one, echoes it on the response and stores it in the request
+// context. Server errors, and every request at LOG_LEVEL=debug, are logged
+// to stderr with the ID.
+func (s *Server) requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(reqid.Header)
+		if !reqid.Valid(id) {
+			id = reqid.New()
+		}
+		w.Header().Set(reqid.Header, id)
+
+		start := time.Now()
+		cw := &countingWriter{ResponseWriter: w}
+		next.ServeHTTP(cw, r.WithContext(reqid.NewContext(r.Context(), id)))
+
+		status := cw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status >= 500 || s.Config.LogLevel == "debug" {
+			fmt.Fprintf(os.Stderr, "request_id=%s method=%s path=%s status=%d duration=%s\n",
+				id, r.Method, r.URL.Path, status, time.Since(start).Round(time.Millisecond))
+		}
+	})
+}
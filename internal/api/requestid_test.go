@@ -0,0 +1,49 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/reqid"
+)
+
+func TestRequestID_Generated(t *testing.T) {
+	srv := NewServer(Config{APIKey: "test-key"}, nil)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/chats", "wrong-key", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	id := w.Header().Get("X-Request-ID")
+	require.Len(t, id, 32)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, id, body["request_id"])
+}
+
+func TestRequestID_PropagatesClientID(t *testing.T) {
+	srv := NewServer(Config{APIKey: "test-key"}, nil)
+	var seen string
+	srv.apiMux.HandleFunc("GET /test", func(w http.ResponseWriter, r *http.Request) {
+		seen = reqid.FromContext(r.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/test", nil)
+	req.Header.Set("X-API-Key", "test-key")
+	req.Header.Set("X-Request-ID", "client-abc-123")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, "client-abc-123", seen)
+	assert.Equal(t, "client-abc-123", w.Header().Get("X-Request-ID"))
+
+	// Unsafe IDs are replaced rather than echoed
+	req.Header.Set("X-Request-ID", "bad id\r\n")
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	assert.NotEqual(t, "bad id\r\n", seen)
+	assert.Equal(t, seen, w.Header().Get("X-Request-ID"))
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleRevokeMessage(t *testing.T) {
+	mock := &mockApp{revokeResult: `{"success":true,"data":{"id":"ABC","revoked":true}}`}
+	srv := NewServer(Config{APIKey: "test-key", MaxMessages: 100, PhoneBlacklist: []string{"222"}}, mock)
+
+	w := doRequest(srv, http.MethodDelete, "/api/v1/messages/ABC", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"revoked":true`)
+	assert.Equal(t, "ABC", mock.lastRevokeID)
+	assert.Nil(t, mock.lastChatJID)
+	assert.Equal(t, []string{"222@"}, mock.lastExcludeJIDs)
+
+	w = doRequest(srv, http.MethodDelete, "/api/v1/messages/ABC?chat_jid=111@s.whatsapp.net", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, mock.lastChatJID)
+	assert.Equal(t, "111@s.whatsapp.net", *mock.lastChatJID)
+
+	mock.lastRevokeID = ""
+	w = doRequest(srv, http.MethodDelete, "/api/v1/messages/ABC?chat_jid=222@s.whatsapp.net", "test-key", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, mock.lastRevokeID)
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+)
+
+// maxSendMediaBytes bounds an attachment sent through the API; WhatsApp
+// documents go up to 100 MB.
+const maxSendMediaBytes = 100 << 20
+
+// sendMediaRequest is the JSON form of a media send. The attachment is
+// either Data, base64-encoded, or Path, a file in SEND_MEDIA_DIR.
+type sendMediaRequest struct {
+	To       string `json:"to"`
+	Data     string `json:"data,omitempty"`
+	Path     string `json:"path,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	Caption  string `json:"caption,omitempty"`
+	// Type overrides how the attachment is sent: "image", "video", "audio"
+	// or "document". By default it follows the MIME type. send-sticker and
+	// send-voice ignore it.
+	Type string `json:"type,omitempty"`
+}
+
+// handleSendMedia sends an image, video, audio or document, uploaded as the
+// "file" field of a multipart form or given in a JSON body. The result
+// includes the ID of the sent message.
+func (s *Server) handleSendMedia(w http.ResponseWriter, r *http.Request) {
+	s.sendMedia(w, r, "")
+}
+
+// handleSendSticker sends a PNG, JPEG or WebP image as a sticker, converted
+// to 512x512 WebP. It takes the same bodies as send-media; captions do not
+// apply.
+func (s *Server) handleSendSticker(w http.ResponseWriter, r *http.Request) {
+	s.sendMedia(w, r, "sticker")
+}
+
+// handleSendVoice sends audio as a voice note, transcoded to Ogg/Opus with
+// its duration and waveform. It takes the same bodies as send-media;
+// captions do not apply.
+func (s *Server) handleSendVoice(w http.ResponseWriter, r *http.Request) {
+	s.sendMedia(w, r, "voice")
+}
+
+// sendMedia sends the attachment of the request as mediaType, or, when it
+// is empty, as the type the request asks for.
+func (s *Server) sendMedia(w http.ResponseWriter, r *http.Request, mediaType string) {
+	// Base64 takes four bytes for every three, plus the other fields
+	r.Body = http.MaxBytesReader(w, r.Body, maxSendMediaBytes/3*4+1<<20)
+
+	var req sendMediaRequest
+	var media client.OutgoingMedia
+	var err error
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		req, media, err = readMultipartMedia(r)
+	} else {
+		req, media, err = s.readJSONMedia(r)
+	}
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status, err = http.StatusRequestEntityTooLarge, fmt.Errorf("attachment is larger than %d MB", maxSendMediaBytes>>20)
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+
+	if req.To == "" {
+		writeError(w, http.StatusBadRequest, "'to' field is required")
+		return
+	}
+	recipient, err := jid.ParseRecipient(req.To)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !s.phoneFilter.IsAllowed(recipient) {
+		writeError(w, http.StatusForbidden, "recipient not allowed")
+		return
+	}
+	switch {
+	case len(media.Data) == 0:
+		writeError(w, http.StatusBadRequest, "attachment is empty")
+		return
+	case len(media.Data) > maxSendMediaBytes:
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("attachment is larger than %d MB", maxSendMediaBytes>>20))
+		return
+	}
+	switch {
+	case mediaType != "":
+		media.Type = mediaType
+	case req.Type == "":
+		media.Type = client.MediaTypeForMIME(media.MimeType)
+	case req.Type == "image", req.Type == "video", req.Type == "audio", req.Type == "document":
+		media.Type = req.Type
+	default:
+		writeError(w, http.StatusBadRequest, "'type' must be image, video, audio or document")
+		return
+	}
+	media.Caption = req.Caption
+
+	result := s.app.SendMedia(r.Context(), req.To, media)
+	if resultSucceeded(result) {
+		s.usage.recordSend(keyIDFromContext(r.Context()))
+	}
+	writeResult(w, result)
+}
+
+// readMultipartMedia reads a media send from a multipart form: the "file"
+// field and the other fields of sendMediaRequest.
+func readMultipartMedia(r *http.Request) (sendMediaRequest, client.OutgoingMedia, error) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return sendMediaRequest{}, client.OutgoingMedia{}, err
+		}
+		return sendMediaRequest{}, client.OutgoingMedia{}, fmt.Errorf("invalid form body")
+	}
+	req := sendMediaRequest{
+		To:       r.FormValue("to"),
+		MimeType: r.FormValue("mime_type"),
+		Filename: r.FormValue("filename"),
+		Caption:  r.FormValue("caption"),
+		Type:     r.FormValue("type"),
+	}
+	f, fh, err := r.FormFile("file")
+	if err != nil {
+		return req, client.OutgoingMedia{}, fmt.Errorf("'file' field is required")
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return req, client.OutgoingMedia{}, fmt.Errorf("reading file: %v", err)
+	}
+	if req.Filename == "" {
+		req.Filename = fh.Filename
+	}
+	if req.MimeType == "" {
+		req.MimeType = fh.Header.Get("Content-Type")
+	}
+	return req, client.OutgoingMedia{
+		Data:     data,
+		MimeType: mediaMIMEType(req.MimeType, req.Filename, data),
+		Filename: req.Filename,
+	}, nil
+}
+
+// readJSONMedia reads a media send from a JSON body, with the attachment
+// base64-encoded or as a path in SEND_MEDIA_DIR.
+func (s *Server) readJSONMedia(r *http.Request) (sendMediaRequest, client.OutgoingMedia, error) {
+	var req sendMediaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return req, client.OutgoingMedia{}, err
+		}
+		return req, client.OutgoingMedia{}, fmt.Errorf("invalid JSON body")
+	}
+
+	var data []byte
+	var err error
+	switch {
+	case req.Data != "" && req.Path != "":
+		return req, client.OutgoingMedia{}, fmt.Errorf("give either 'data' or 'path', not both")
+	case req.Data != "":
+		data, err = base64.StdEncoding.DecodeString(req.Data)
+		if err != nil {
+			return req, client.OutgoingMedia{}, fmt.Errorf("'data' is not valid base64")
+		}
+	case req.Path != "":
+		data, err = s.readSendMediaFile(req.Path)
+		if err != nil {
+			return req, client.OutgoingMedia{}, err
+		}
+		if req.Filename == "" {
+			req.Filename = filepath.Base(req.Path)
+		}
+	default:
+		return req, client.OutgoingMedia{}, fmt.Errorf("'data' or 'path' field is required")
+	}
+	return req, client.OutgoingMedia{
+		Data:     data,
+		MimeType: mediaMIMEType(req.MimeType, req.Filename, data),
+		Filename: req.Filename,
+	}, nil
+}
+
+// readSendMediaFile reads a file of SEND_MEDIA_DIR. Paths are relative to
+// it and cannot leave it, through ".." or symlinks.
+func (s *Server) readSendMediaFile(path string) ([]byte, error) {
+	if s.Config.SendMediaDir == "" {
+		return nil, fmt.Errorf("sending files by path is not enabled (set SEND_MEDIA_DIR)")
+	}
+	f, err := os.OpenInRoot(s.Config.SendMediaDir, path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open %s in SEND_MEDIA_DIR", path)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxSendMediaBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	return data, nil
+}
+
+// mediaMIMEType is the MIME type of an attachment: the declared one, else
+// the one of its file extension, else the one its content is sniffed as.
+func mediaMIMEType(declared, filename string, data []byte) string {
+	if declared != "" && declared != "application/octet-stream" {
+		return declared
+	}
+	if byExt := mime.TypeByExtension(filepath.Ext(filename)); byExt != "" {
+		return byExt
+	}
+	return http.DetectContentType(data)
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sentMediaResult = `{"success":true,"data":{"sent":true,"message_id":"3EB0C767D26A1D8B"},"error":null}`
+
+func TestHandleSendMedia_Multipart(t *testing.T) {
+	mock := &mockApp{sendMessageResult: sentMediaResult}
+	srv := NewServer(Config{APIKey: "test-key"}, mock)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("to", "34600111222")
+	mw.WriteField("caption", "The invoice")
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="file"; filename="invoice.pdf"`)
+	h.Set("Content-Type", "application/octet-stream")
+	part, _ := mw.CreatePart(h)
+	part.Write([]byte("%PDF-1.4"))
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/messages/send-media", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, sentMediaResult, w.Body.String())
+	assert.Equal(t, "34600111222", mock.lastSendRecipient)
+	require.Len(t, mock.sentMedia, 1)
+	assert.Equal(t, "document", mock.sentMedia[0].Type)
+	assert.Equal(t, "application/pdf", mock.sentMedia[0].MimeType)
+	assert.Equal(t, "invoice.pdf", mock.sentMedia[0].Filename)
+	assert.Equal(t, "The invoice", mock.sentMedia[0].Caption)
+	assert.Equal(t, []byte("%PDF-1.4"), mock.sentMedia[0].Data)
+}
+
+func TestHandleSendMedia_Base64(t *testing.T) {
+	mock := &mockApp{sendMessageResult: sentMediaResult}
+	srv := NewServer(Config{APIKey: "test-key"}, mock)
+
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	body := `{"to":"34600111222","data":"` + base64.StdEncoding.EncodeToString([]byte(png)) + `","caption":"Look"}`
+	w := doRequest(srv, http.MethodPost, "/api/v1/messages/send-media", "test-key", body)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, mock.sentMedia, 1)
+	assert.Equal(t, "image", mock.sentMedia[0].Type)
+	assert.Equal(t, "image/png", mock.sentMedia[0].MimeType)
+	assert.Equal(t, "Look", mock.sentMedia[0].Caption)
+
+	// The type can be overridden, e.g. to keep an image's quality
+	body = `{"to":"34600111222","data":"` + base64.StdEncoding.EncodeToString([]byte(png)) + `","filename":"scan.png","type":"document"}`
+	w = doRequest(srv, http.MethodPost, "/api/v1/messages/send-media", "test-key", body)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "document", mock.sentMedia[1].Type)
+	assert.Equal(t, "scan.png", mock.sentMedia[1].Filename)
+}
+
+func TestHandleSendMedia_Path(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "note.ogg"), []byte("OggS"), 0o600))
+	mock := &mockApp{sendMessageResult: sentMediaResult}
+	srv := NewServer(Config{APIKey: "test-key", SendMediaDir: dir}, mock)
+
+	w := doRequest(srv, http.MethodPost, "/api/v1/messages/send-media", "test-key", `{"to":"34600111222","path":"note.ogg"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, mock.sentMedia, 1)
+	assert.Equal(t, "audio", mock.sentMedia[0].Type)
+	assert.Equal(t, "note.ogg", mock.sentMedia[0].Filename)
+
+	// Paths cannot leave the directory
+	w = doRequest(srv, http.MethodPost, "/api/v1/messages/send-media", "test-key", `{"to":"34600111222","path":"../etc/passwd"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	srv = NewServer(Config{APIKey: "test-key"}, mock)
+	w = doRequest(srv, http.MethodPost, "/api/v1/messages/send-media", "test-key", `{"to":"34600111222","path":"note.ogg"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "SEND_MEDIA_DIR")
+	assert.Len(t, mock.sentMedia, 1)
+}
+
+func TestHandleSendMedia_Rejects(t *testing.T) {
+	mock := &mockApp{sendMessageResult: sentMediaResult}
+	srv := NewServer(Config{APIKey: "test-key", PhoneWhitelist: []string{"34600111222"}}, mock)
+	data := base64.StdEncoding.EncodeToString([]byte("hello"))
+
+	for name, tc := range map[string]struct {
+		body   string
+		status int
+	}{
+		"invalid JSON":    {`{`, http.StatusBadRequest},
+		"no recipient":    {`{"data":"` + data + `"}`, http.StatusBadRequest},
+		"no attachment":   {`{"to":"34600111222"}`, http.StatusBadRequest},
+		"invalid base64":  {`{"to":"34600111222","data":"%%%"}`, http.StatusBadRequest},
+		"data and path":   {`{"to":"34600111222","data":"` + data + `","path":"a.txt"}`, http.StatusBadRequest},
+		"unknown type":    {`{"to":"34600111222","data":"` + data + `","type":"sticker"}`, http.StatusBadRequest},
+		"filtered number": {`{"to":"34600999999","data":"` + data + `"}`, http.StatusForbidden},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := doRequest(srv, http.MethodPost, "/api/v1/messages/send-media", "test-key", tc.body)
+			assert.Equal(t, tc.status, w.Code, w.Body.String())
+		})
+	}
+	assert.Empty(t, mock.sentMedia)
+
+	w := doRequest(srv, http.MethodPost, "/api/v1/messages/send-media", "wrong-key", `{}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestHandleSendSticker(t *testing.T) {
+	mock := &mockApp{sendMessageResult: sentMediaResult}
+	srv := NewServer(Config{APIKey: "test-key"}, mock)
+
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	body := `{"to":"34600111222","data":"` + base64.StdEncoding.EncodeToString([]byte(png)) + `","type":"document"}`
+	w := doRequest(srv, http.MethodPost, "/api/v1/messages/send-sticker", "test-key", body)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, mock.sentMedia, 1)
+	assert.Equal(t, "sticker", mock.sentMedia[0].Type)
+	assert.Equal(t, []byte(png), mock.sentMedia[0].Data)
+}
+
+func TestHandleSendVoice(t *testing.T) {
+	mock := &mockApp{sendMessageResult: sentMediaResult}
+	srv := NewServer(Config{APIKey: "test-key"}, mock)
+
+	body := `{"to":"34600111222","data":"` + base64.StdEncoding.EncodeToString([]byte("ID3 audio")) + `","filename":"memo.mp3","type":"document"}`
+	w := doRequest(srv, http.MethodPost, "/api/v1/messages/send-voice", "test-key", body)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, mock.sentMedia, 1)
+	assert.Equal(t, "voice", mock.sentMedia[0].Type)
+	assert.Equal(t, "audio/mpeg", mock.sentMedia[0].MimeType)
+}
@@ -4,40 +4,154 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"sync/atomic"
 	"time"
 
 	"github.com/mdp/qrterminal"
+	"github.com/vicentereig/whatsapp-cli/internal/canary"
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/eventbus"
+	"github.com/vicentereig/whatsapp-cli/internal/health"
+	"github.com/vicentereig/whatsapp-cli/internal/mediaurl"
+	"github.com/vicentereig/whatsapp-cli/internal/netguard"
+	"github.com/vicentereig/whatsapp-cli/internal/sentry"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
 )
 
 // AppService defines the interface for the application layer used by API handlers.
 type AppService interface {
-	ListMessages(chatJID *string, query *string, limit, page int, includeJIDs, excludeJIDs []string, after *time.Time) string
+	ListMessages(chatJID *string, query *string, limit, page int, includeJIDs, excludeJIDs []string, after *time.Time, lang, tag *string) string
 	ListChats(query *string, limit, page int, includeJIDs, excludeJIDs []string) string
 	SearchContacts(query string, includeJIDs, excludeJIDs []string) string
-	SendMessage(ctx context.Context, recipient, message string) string
-	GetMediaFile(messageID string, chatJID *string) (path string, mimeType string, err error)
+	Autocomplete(prefix string, limit int, includeJIDs, excludeJIDs []string) string
+	SendMessage(ctx context.Context, recipient, message string, opts client.SendOptions) string
+	SendMedia(ctx context.Context, recipient string, media client.OutgoingMedia) string
+	SendPoll(ctx context.Context, recipient, question string, options []string, selectable int) string
+	SendTyping(ctx context.Context, chatJID string, typing bool) string
+	SendPresence(ctx context.Context, available bool) string
+	MarkChatRead(ctx context.Context, chatJID string, ids []string) string
+	ChatReadState(chatJID string) string
+	ExportChain(chatJID string) string
+	VerifyChain(chatJID string) string
+	VerifyChainExport(e store.ChainExport) string
+	MessageStatus(messageID string, chatJID *string) string
+	PollResults(messageID string, chatJID *string) string
+	// OpenMediaFile downloads the media of a message if needed and opens it.
+	// Errors matching fs.ErrNotExist mean there is no such message or media,
+	// fs.ErrPermission that the media is quarantined.
+	OpenMediaFile(ctx context.Context, messageID string, chatJID *string) (file io.ReadSeekCloser, name, mimeType string, err error)
+	// WriteChatMediaZip writes the downloaded media of a chat as a ZIP
+	// archive. Errors matching fs.ErrNotExist, returned before anything is
+	// written, mean no media matches.
+	WriteChatMediaZip(ctx context.Context, p store.ChatMediaParams, w io.Writer) error
 	IsAuthenticated() bool
 	IsConnected() bool
-	Sync(ctx context.Context, onMessage func()) string
+	// Connection reports the sync loop's connection to WhatsApp, including
+	// whether it is catching up on what it missed.
+	Connection() health.Connection
+	// ConnectionQuality reports round trips and outages of the connection
+	// to WhatsApp over the last day.
+	ConnectionQuality() health.QualityReport
+	StoreHealth() store.Health
+	OutboxStats() store.OutboxStats
+	AccountAlerts() []store.AccountAlert
+	ListAccountAlerts(limit int) string
+	CanaryStatus() *canary.Status
+	UpdateGroup(ctx context.Context, groupJID string, subject, description *string, announce, locked *bool) string
+	CreateGroup(ctx context.Context, subject string, participants []string) string
+	// MessageChat returns the chat of a message given by ID; chatJID picks
+	// one when the ID is not unique.
+	MessageChat(messageID string, chatJID *string) (string, error)
+	MessageDuplicates(messageID string, chatJID *string, limit int, includeJIDs, excludeJIDs []string) string
+	ListQuarantine(limit, page int, includeJIDs, excludeJIDs []string) string
+	ReleaseQuarantined(messageID string, chatJID *string) string
+	GetAwayConfig() string
+	SetAwayConfig(cfg store.AwayConfig) string
+	ListAwayOptOuts() string
+	SetAwayOptOut(chatJID string, optOut bool) string
+	GetChatMode(chatJID string) string
+	SetChatMode(chatJID, mode string) string
+	GetDraft(chatJID string) string
+	SetDraft(chatJID, text string) string
+	ChatContext(chatJID string, tokens int, includeJIDs, excludeJIDs []string, after *time.Time) string
+	SemanticSearch(ctx context.Context, query string, chatJID *string, limit int, includeJIDs, excludeJIDs []string, after *time.Time) string
+	ListDigests(chatJID, period string, limit int) string
+	InteractionGraph(params store.GraphParams) (*store.Graph, error)
+	NewMessages(params store.NewMessagesParams) ([]store.TriggerMessage, error)
+	MessagesSince(since time.Time, limit int, includeJIDs, excludeJIDs []string) ([]store.TriggerMessage, error)
+	ContactIdentity(ctx context.Context, contact string) string
+	VerifyContactIdentity(ctx context.Context, contact string, verified bool) string
+	NewChats(sinceID int64, limit int, includeJIDs, excludeJIDs []string) ([]store.TriggerChat, error)
+	ListTags(includeJIDs, excludeJIDs []string) string
+	ListTaggedMessages(tag string, limit, page int, includeJIDs, excludeJIDs []string, after *time.Time) string
+	TagMessage(messageID string, chatJID *string, tags []string) string
+	UntagMessage(messageID string, chatJID *string, tag string) string
+	GetMessageTags(messageID string, chatJID *string) string
+	GetMeta(kind, target string, chatJID *string) string
+	SetMeta(kind, target string, chatJID *string, key, value string) string
+	DeleteMeta(kind, target string, chatJID *string, key string) string
+	FindMeta(kind, key string, value *string, limit, page int, includeJIDs, excludeJIDs []string) string
+	RevokeMessage(ctx context.Context, messageID string, chatJID *string, includeJIDs, excludeJIDs []string) string
+	ListFollowups(limit int, includeJIDs, excludeJIDs []string) string
+	ListHumanChats(includeJIDs, excludeJIDs []string) string
+	ListReminders() string
+	GetReminder(id int64) string
+	CreateReminder(r store.Reminder) string
+	DeleteReminder(id int64) string
+	ResolvePhoneJID(jid string) string
+	MergeChats(ctx context.Context, from, into string) string
+	AdminQuery(ctx context.Context, query string, limit int) string
+	AdminSchema() string
+	DatabaseSnapshot(ctx context.Context) (path string, err error)
+	AnonymizedSnapshot(ctx context.Context, salt string, dropContent bool) (path string, err error)
+	ListGroupJoinRequests(ctx context.Context, groupJID string) string
+	UpdateGroupJoinRequests(ctx context.Context, groupJID string, participants []string, approve bool) string
+	UpdateGroupParticipants(ctx context.Context, groupJID string, participants []string, action string) string
+	Sync(ctx context.Context, onEvent func(eventbus.Event)) string
+	VersionInfo(ctx context.Context) string
 }
 
 type Server struct {
-	mux           *http.ServeMux
-	apiMux        *http.ServeMux
-	Config        Config
-	app           AppService
-	phoneFilter   *PhoneFilter
-	authenticated atomic.Bool
-	syncing       atomic.Bool
-	currentQR     atomic.Value // stores string
+	mux         *http.ServeMux
+	apiMux      *http.ServeMux
+	Config      Config
+	app         AppService
+	phoneFilter *PhoneFilter
+	// health derives the state of the daemon (pairing, backfilling, live,
+	// …) reported by /readyz, /api/v1/status and the metrics
+	health    *health.Machine
+	currentQR atomic.Value // stores string
+	usage     *usageTracker
+	// recorder keeps recent requests for debugging; nil unless
+	// DEBUG_RECORD_REQUESTS is set
+	recorder    *requestRecorder
+	limiter     *concurrencyLimiter
+	rateLimiter *rateLimiter
+	reporter    *sentry.Client
+	maintenance atomic.Pointer[maintenanceState]
+	// cloudTemplates are the template texts of the Cloud API shim, by name
+	// and language code.
+	cloudTemplates map[string]map[string]string
+	// cloudMedia fetches the media links of Cloud API sends, refusing
+	// private addresses
+	cloudMedia *http.Client
 
 	// Sync daemon fields
 	syncRunning    atomic.Bool
 	messagesSynced atomic.Int64
+	// events streams the events of the sync loop to WebSocket and SSE
+	// clients
+	events *eventHub
+	// mediaURLs verifies the signed media URLs of webhook payloads; nil
+	// without PUBLIC_URL
+	mediaURLs *mediaurl.Signer
+	// locks are the leases automation workers take on chats
+	locks *chatLocks
 }
 
 func NewServer(cfg Config, app AppService) *Server {
@@ -46,36 +160,186 @@ func NewServer(cfg Config, app AppService) *Server {
 		Config:      cfg,
 		app:         app,
 		phoneFilter: NewPhoneFilter(cfg.PhoneWhitelist, cfg.PhoneBlacklist),
+		events:      newEventHub(),
+		locks:       newChatLocks(),
+		recorder:    newRequestRecorder(cfg.DebugRecordRequests),
+		cloudMedia:  netguard.Client(2*time.Minute, cfg.OutboundAllowedNetworks),
 	}
+	readyStates := cfg.ReadyStates
+	if len(readyStates) == 0 {
+		readyStates = health.DefaultReady
+	}
+	s.health = health.NewMachine(readyStates)
+	s.health.OnChange(logStateChange)
+	if cfg.Replica {
+		// A replica has no WhatsApp session; it is live as soon as it serves
+		s.health.Update(func(sig *health.Signals) { sig.Paired, sig.Syncing = true, true })
+	}
+	if app != nil {
+		s.phoneFilter.SetLIDResolver(app.ResolvePhoneJID)
+	}
+	if cfg.PublicURL != "" && cfg.APIKey != "" {
+		s.mediaURLs = mediaurl.New(cfg.APIKey, cfg.PublicURL, cfg.MediaURLTTL)
+	}
+	keyIDs := []string{DefaultKeyID}
+	for id := range cfg.APIKeys {
+		keyIDs = append(keyIDs, id)
+	}
+	s.usage = newUsageTracker(keyIDs)
+	s.limiter = newConcurrencyLimiter(cfg.MaxInflight, cfg.MaxInflightPerKey, cfg.MaxInflightPerIP, cfg.MaxQueueWait)
+	s.rateLimiter = newRateLimiter(cfg.RateLimitPerMinute)
 	s.registerRoutes()
 	return s
 }
 
+// Handler returns the root HTTP handler, including health and API routes.
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// SetErrorReporter reports API handler panics and sync crashes to r.
+func (s *Server) SetErrorReporter(r *sentry.Client) {
+	s.reporter = r
+}
+
 func (s *Server) SetAuthenticated(v bool) {
-	s.authenticated.Store(v)
+	s.health.Update(func(sig *health.Signals) { sig.Paired = v })
 }
 
 func (s *Server) SetSyncing(v bool) {
-	s.syncing.Store(v)
+	s.health.Update(func(sig *health.Signals) { sig.Syncing = v })
 }
 
 func (s *Server) registerRoutes() {
 	// Health endpoints — no auth required
 	s.mux.HandleFunc("GET /healthz", s.handleHealthz)
 	s.mux.HandleFunc("GET /readyz", s.handleReadyz)
+	s.mux.HandleFunc("GET /metrics", s.handleMetrics)
 
 	// API v1 routes — protected by auth middleware
 	apiMux := http.NewServeMux()
-	apiMux.HandleFunc("GET /messages", s.handleListMessages)
-	apiMux.HandleFunc("GET /messages/search", s.handleSearchMessages)
-	apiMux.HandleFunc("GET /chats", s.handleListChats)
-	apiMux.HandleFunc("GET /contacts", s.handleSearchContacts)
+	// handleList registers a list endpoint with its Cache-Control max-age
+	handleList := func(pattern string, h http.HandlerFunc) {
+		apiMux.HandleFunc(pattern, cacheFor(listCacheTTLs[pattern], h))
+	}
+	handleList("GET /messages", s.handleListMessages)
+	handleList("GET /messages/search", s.handleSearchMessages)
+	apiMux.HandleFunc("GET /messages/semantic-search", s.handleSemanticSearch)
+	apiMux.HandleFunc("GET /messages/{id}/duplicates", s.handleMessageDuplicates)
+	apiMux.HandleFunc("GET /messages/{id}/tags", s.handleGetMessageTags)
+	apiMux.HandleFunc("POST /messages/{id}/tags", s.handleTagMessage)
+	apiMux.HandleFunc("DELETE /messages/{id}/tags/{tag}", s.handleUntagMessage)
+	apiMux.HandleFunc("GET /messages/{id}/meta", s.handleGetMeta)
+	apiMux.HandleFunc("PUT /messages/{id}/meta/{key}", s.handleSetMeta)
+	apiMux.HandleFunc("DELETE /messages/{id}/meta/{key}", s.handleDeleteMeta)
+	apiMux.HandleFunc("GET /messages/{id}/poll", s.handlePollResults)
+	apiMux.HandleFunc("GET /messages/{id}/status", s.handleMessageStatus)
+	apiMux.HandleFunc("DELETE /messages/{id}", s.handleRevokeMessage)
+	handleList("GET /quarantine", s.handleListQuarantine)
+	apiMux.HandleFunc("POST /quarantine/{id}/release", s.handleReleaseQuarantined)
+	handleList("GET /chats", s.handleListChats)
+	handleList("GET /chats/handoff", s.handleListHumanChats)
+	apiMux.HandleFunc("GET /chats/{jid}/mode", s.handleGetChatMode)
+	apiMux.HandleFunc("PUT /chats/{jid}/mode", s.handleSetChatMode)
+	apiMux.HandleFunc("GET /chats/{jid}/draft", s.handleGetDraft)
+	apiMux.HandleFunc("PUT /chats/{jid}/draft", s.handleSetDraft)
+	apiMux.HandleFunc("DELETE /chats/{jid}/draft", s.handleDeleteDraft)
+	apiMux.HandleFunc("GET /chats/{jid}/context", s.handleChatContext)
+	apiMux.HandleFunc("GET /chats/{jid}/lock", s.handleGetChatLock)
+	apiMux.HandleFunc("POST /chats/{jid}/lock", s.handleLockChat)
+	apiMux.HandleFunc("DELETE /chats/{jid}/lock", s.handleUnlockChat)
+	handleList("GET /chats/{jid}/digests", s.handleListDigests)
+	apiMux.HandleFunc("GET /chats/{jid}/meta", s.handleGetMeta)
+	apiMux.HandleFunc("GET /chats/{jid}/media.zip", s.handleChatMediaZip)
+	apiMux.HandleFunc("POST /chats/{jid}/typing", s.handleTyping)
+	apiMux.HandleFunc("POST /presence", s.handlePresence)
+	apiMux.HandleFunc("GET /chats/{jid}/read", s.handleChatReadState)
+	apiMux.HandleFunc("POST /chats/{jid}/read", s.handleMarkChatRead)
+	apiMux.HandleFunc("GET /chats/{jid}/chain", s.handleExportChain)
+	apiMux.HandleFunc("GET /chats/{jid}/chain/verify", s.handleVerifyChain)
+	apiMux.HandleFunc("POST /chain/verify", s.handleVerifyChainExport)
+	apiMux.HandleFunc("PUT /chats/{jid}/meta/{key}", s.handleSetMeta)
+	apiMux.HandleFunc("DELETE /chats/{jid}/meta/{key}", s.handleDeleteMeta)
+	apiMux.HandleFunc("POST /chats/merge", s.handleMergeChats)
+	apiMux.HandleFunc("GET /stats/graph", s.handleInteractionGraph)
+	apiMux.HandleFunc("GET /triggers/new-messages", s.handleNewMessagesTrigger)
+	apiMux.HandleFunc("GET /triggers/new-chats", s.handleNewChatsTrigger)
+	handleList("GET /tags", s.handleListTags)
+	handleList("GET /tags/{tag}/messages", s.handleListTaggedMessages)
+	handleList("GET /followups", s.handleListFollowups)
+	handleList("GET /contacts", s.handleSearchContacts)
+	apiMux.HandleFunc("GET /contacts/{jid}/identity", s.handleGetContactIdentity)
+	apiMux.HandleFunc("PUT /contacts/{jid}/identity/verified", s.handleVerifyContactIdentity)
+	apiMux.HandleFunc("DELETE /contacts/{jid}/identity/verified", s.handleUnverifyContactIdentity)
+	apiMux.HandleFunc("GET /contacts/{jid}/meta", s.handleGetMeta)
+	apiMux.HandleFunc("PUT /contacts/{jid}/meta/{key}", s.handleSetMeta)
+	apiMux.HandleFunc("DELETE /contacts/{jid}/meta/{key}", s.handleDeleteMeta)
+	handleList("GET /meta", s.handleFindMeta)
+	handleList("GET /autocomplete", s.handleAutocomplete)
 	apiMux.HandleFunc("POST /messages/send", s.handleSendMessage)
+	apiMux.HandleFunc("POST /messages/send-media", s.handleSendMedia)
+	apiMux.HandleFunc("POST /messages/send-sticker", s.handleSendSticker)
+	apiMux.HandleFunc("POST /messages/send-voice", s.handleSendVoice)
+	apiMux.HandleFunc("POST /messages/send-poll", s.handleSendPoll)
+	apiMux.HandleFunc("POST /inbound/email", s.handleInboundEmail)
 	apiMux.HandleFunc("GET /media/{message_id}", s.handleMediaDownload)
 	apiMux.HandleFunc("GET /auth/status", s.handleAuthStatus)
 	apiMux.HandleFunc("GET /auth/qr/image", s.handleQRImage)
 	apiMux.HandleFunc("GET /sync/status", s.handleSyncStatus)
-	s.mux.Handle("/api/v1/", s.authMiddleware(http.StripPrefix("/api/v1", apiMux)))
+	apiMux.HandleFunc("GET /status", s.handleStatus)
+	apiMux.HandleFunc("GET /ws", s.handleWebSocket)
+	apiMux.HandleFunc("GET /events", s.handleEvents)
+	apiMux.HandleFunc("GET /version", s.handleVersion)
+	apiMux.HandleFunc("GET /limits", s.handleLimits)
+	apiMux.HandleFunc("GET /admin/keys", s.handleListKeyUsage)
+	apiMux.HandleFunc("GET /admin/keys/{id}/usage", s.handleKeyUsage)
+	apiMux.HandleFunc("POST /admin/query", s.handleAdminQuery)
+	apiMux.HandleFunc("GET /admin/schema", s.handleAdminSchema)
+	apiMux.HandleFunc("GET /admin/db/snapshot", s.handleDBSnapshot)
+	apiMux.HandleFunc("POST /admin/db/anonymize", s.handleDBAnonymize)
+	apiMux.HandleFunc("POST /admin/tokens", s.handleCreateChatToken)
+	apiMux.HandleFunc("GET /admin/maintenance", s.handleGetMaintenance)
+	apiMux.HandleFunc("POST /admin/maintenance", s.handleSetMaintenance)
+	apiMux.HandleFunc("GET /admin/alerts", s.handleListAccountAlerts)
+	apiMux.HandleFunc("GET /admin/debug/requests", s.handleDebugRequests)
+	apiMux.HandleFunc("POST /groups", s.handleCreateGroup)
+	apiMux.HandleFunc("PUT /groups/{jid}", s.handleUpdateGroup)
+	apiMux.HandleFunc("POST /groups/{jid}/participants", s.handleUpdateGroupParticipants)
+	apiMux.HandleFunc("DELETE /groups/{jid}/participants", s.handleRemoveGroupParticipants)
+	apiMux.HandleFunc("GET /groups/{jid}/requests", s.handleListGroupJoinRequests)
+	apiMux.HandleFunc("POST /groups/{jid}/requests/approve", s.handleApproveGroupJoinRequests)
+	apiMux.HandleFunc("POST /groups/{jid}/requests/reject", s.handleRejectGroupJoinRequests)
+	apiMux.HandleFunc("GET /away", s.handleGetAway)
+	apiMux.HandleFunc("PUT /away", s.handleSetAway)
+	handleList("GET /away/optouts", s.handleListAwayOptOuts)
+	apiMux.HandleFunc("PUT /away/optouts/{jid}", s.handleAwayOptOut)
+	apiMux.HandleFunc("DELETE /away/optouts/{jid}", s.handleAwayOptIn)
+	handleList("GET /reminders", s.handleListReminders)
+	apiMux.HandleFunc("POST /reminders", s.handleCreateReminder)
+	apiMux.HandleFunc("GET /reminders/{id}", s.handleGetReminder)
+	apiMux.HandleFunc("DELETE /reminders/{id}", s.handleDeleteReminder)
+	if s.Config.EnablePprof {
+		// Profiling endpoints live under the authenticated API prefix, for
+		// the admin key only: cmdline shows the process arguments
+		apiMux.HandleFunc("GET /debug/pprof/", s.adminOnly(pprof.Index))
+		apiMux.HandleFunc("GET /debug/pprof/cmdline", s.adminOnly(pprof.Cmdline))
+		apiMux.HandleFunc("GET /debug/pprof/profile", s.adminOnly(pprof.Profile))
+		apiMux.HandleFunc("GET /debug/pprof/symbol", s.adminOnly(pprof.Symbol))
+		apiMux.HandleFunc("GET /debug/pprof/trace", s.adminOnly(pprof.Trace))
+	}
+	var v1 http.Handler = s.requestIDMiddleware(s.recordMiddleware(s.recoverMiddleware(s.authMiddleware(s.limitMiddleware(http.StripPrefix("/api/v1", s.maintenanceMiddleware(s.replicaMiddleware(s.chatScopeMiddleware(apiMux)))))))))
+	if !s.Config.V1Sunset.IsZero() {
+		v1 = deprecationMiddleware("/api/v1", "/api/v2", s.Config.V1Sunset, v1)
+	}
+	s.mux.Handle("/api/v1/", v1)
+	// v2 serves the same handlers with typed responses and real status codes
+	s.mux.Handle("/api/v2/", s.requestIDMiddleware(s.recordMiddleware(s.v2Shim(s.recoverMiddleware(s.authMiddleware(s.limitMiddleware(http.StripPrefix("/api/v2", s.maintenanceMiddleware(s.replicaMiddleware(s.chatScopeMiddleware(apiMux)))))))))))
+	if s.Config.CloudAPICompat {
+		s.registerCloudAPI()
+	}
+	if s.Config.TwilioCompat {
+		s.mux.Handle("POST /"+twilioAPIVersion+"/Accounts/{AccountSid}/Messages.json", s.requestIDMiddleware(s.recordMiddleware(s.twilioShim(s.recoverMiddleware(s.authMiddleware(s.limitMiddleware(s.maintenanceMiddleware(s.replicaMiddleware(s.chatScopeMiddleware(apiMux))))))))))
+	}
 	s.apiMux = apiMux
 }
 
@@ -85,28 +349,42 @@ func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
+// handleReadyz answers 200 in the states configured as ready (READY_STATES,
+// live by default) and 503 in the others, with the reason.
 func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	authenticated := s.authenticated.Load()
-	syncing := s.syncing.Load()
-
-	if authenticated && syncing {
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+	status := s.refreshHealth()
+	sig := s.health.Signals()
+	body := map[string]string{"state": string(status.State)}
+	if s.Config.Replica {
+		body["mode"] = "replica"
+	}
+	if !status.Ready {
+		body["status"] = "not_ready"
+		body["reason"] = status.Reason
+		switch {
+		case status.State == health.LoggedOut:
+			body["alert"] = sig.AlertReason
+		case status.Reason == "canary failed":
+			body["error"] = sig.CanaryError
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(body)
 		return
 	}
 
-	reason := "not authenticated"
-	if authenticated {
-		reason = "not syncing"
+	// A failing store does not change the state: sends and auth keep
+	// working while writes are buffered in memory.
+	body["status"] = "ready"
+	if status.Reason != "" {
+		body["reason"] = status.Reason
 	}
-
-	w.WriteHeader(http.StatusServiceUnavailable)
-	json.NewEncoder(w).Encode(map[string]string{
-		"status": "not_ready",
-		"reason": reason,
-	})
+	if s.app != nil && !s.app.StoreHealth().Healthy {
+		body["store"] = "degraded"
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(body)
 }
 
 func (s *Server) Start(ctx context.Context) error {
@@ -134,17 +412,39 @@ func (s *Server) Start(ctx context.Context) error {
 }
 
 func (s *Server) handleSyncStatus(w http.ResponseWriter, r *http.Request) {
+	data := map[string]any{
+		"running":         s.syncRunning.Load(),
+		"messages_synced": s.messagesSynced.Load(),
+		"ws_clients":      s.events.len(),
+		"history_sync":    s.historySyncStatus(),
+	}
+	if s.app != nil {
+		data["store"] = s.app.StoreHealth()
+		data["outbox"] = s.app.OutboxStats()
+		data["account_alerts"] = s.app.AccountAlerts()
+		data["connection_quality"] = s.app.ConnectionQuality()
+		if c := s.app.CanaryStatus(); c != nil {
+			data["canary"] = c
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]any{
 		"success": true,
-		"data": map[string]any{
-			"running":         s.syncRunning.Load(),
-			"messages_synced": s.messagesSynced.Load(),
-		},
+		"data":    data,
 	})
 }
 
+// historySyncStatus describes the history requested when pairing.
+func (s *Server) historySyncStatus() map[string]any {
+	status := map[string]any{"mode": s.Config.HistorySyncMode}
+	if s.Config.HistorySyncDays > 0 {
+		status["days"] = s.Config.HistorySyncDays
+	}
+	return status
+}
+
 // QRAuthProvider is implemented by types that can perform QR-based authentication.
 // This is separate from AppService to avoid coupling the API package to whatsmeow types.
 type QRAuthProvider interface {
@@ -155,7 +455,9 @@ type QRAuthProvider interface {
 // QR codes are printed to stderr as ASCII art (for docker logs) and stored
 // in Server.currentQR for the HTTP QR image endpoint.
 func (s *Server) StartQRAuth(ctx context.Context, auth QRAuthProvider) {
+	s.health.Update(func(sig *health.Signals) { sig.Pairing = true })
 	go func() {
+		defer s.health.Update(func(sig *health.Signals) { sig.Pairing = false })
 		err := auth.AuthWithQRCallback(ctx,
 			func(code string) {
 				s.SetCurrentQR(code)
@@ -175,12 +477,13 @@ func (s *Server) StartQRAuth(ctx context.Context, auth QRAuthProvider) {
 }
 
 // StartBackgroundSync launches the sync daemon in a background goroutine.
-// It waits for authentication (polling Server.authenticated), then starts App.Sync.
-// The goroutine is cancelled when ctx is cancelled.
+// It waits for authentication (polling the health signals), then starts App.Sync,
+// streaming its events to WebSocket and SSE clients. The goroutine is cancelled when ctx
+// is cancelled.
 func (s *Server) StartBackgroundSync(ctx context.Context) {
 	go func() {
 		// Wait for authentication before starting sync
-		for !s.authenticated.Load() {
+		for !s.health.Signals().Paired {
 			select {
 			case <-ctx.Done():
 				return
@@ -191,17 +494,43 @@ func (s *Server) StartBackgroundSync(ctx context.Context) {
 		fmt.Fprintln(os.Stderr, "Starting background sync...")
 		s.syncRunning.Store(true)
 		s.SetSyncing(true)
+		go s.watchHealth(ctx)
 		defer func() {
 			s.syncRunning.Store(false)
 			s.SetSyncing(false)
 		}()
+		defer func() {
+			if v := recover(); v != nil {
+				s.reporter.CapturePanic(v, map[string]string{"component": "sync"})
+				s.reporter.Flush(5 * time.Second)
+				panic(v)
+			}
+		}()
 
-		s.app.Sync(ctx, func() {
-			s.messagesSynced.Add(1)
+		result := s.app.Sync(ctx, func(e eventbus.Event) {
+			if e.Type == eventbus.TypeMessage {
+				s.messagesSynced.Add(1)
+			}
+			s.streamEvent(e)
 		})
+		if ctx.Err() == nil {
+			s.reporter.CaptureError(syncError(result), map[string]string{"component": "sync"})
+		}
 	}()
 }
 
+// syncError returns the error a Sync result reports, or a generic one when
+// sync stopped without saying why.
+func syncError(result string) error {
+	var r struct {
+		Error *string `json:"error"`
+	}
+	if json.Unmarshal([]byte(result), &r) == nil && r.Error != nil {
+		return fmt.Errorf("sync stopped: %s", *r.Error)
+	}
+	return fmt.Errorf("sync stopped unexpectedly")
+}
+
 func printQRToStderr(code string) {
 	qrterminal.GenerateHalfBlock(code, qrterminal.M, os.Stderr)
 }
@@ -11,6 +11,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/health"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
 )
 
 func TestNewServer(t *testing.T) {
@@ -127,3 +129,121 @@ func TestReadyz_Ready(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "ready", body["status"])
 }
+
+func TestReadyz_ReadyWithDegradedStore(t *testing.T) {
+	mock := &mockApp{storeHealth: &store.Health{Healthy: false, Buffered: 3}}
+	srv := NewServer(Config{APIKey: "test-key"}, mock)
+	srv.SetAuthenticated(true)
+	srv.SetSyncing(true)
+
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var body map[string]string
+	err := json.Unmarshal(w.Body.Bytes(), &body)
+	require.NoError(t, err)
+	assert.Equal(t, "ready", body["status"])
+	assert.Equal(t, "degraded", body["store"])
+}
+
+func TestMetrics_NoAuthRequired(t *testing.T) {
+	mock := &mockApp{storeHealth: &store.Health{Healthy: false, Buffered: 7, Dropped: 2}}
+	srv := NewServer(Config{APIKey: "test-key"}, mock)
+	srv.SetAuthenticated(true)
+	srv.messagesSynced.Store(12)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "whatsapp_authenticated 1\n")
+	assert.Contains(t, body, "whatsapp_messages_synced_total 12\n")
+	assert.Contains(t, body, "whatsapp_store_healthy 0\n")
+	assert.Contains(t, body, "whatsapp_store_buffered_writes 7\n")
+	assert.Contains(t, body, "whatsapp_store_dropped_writes_total 2\n")
+}
+
+func TestMetrics_ConnectionQuality(t *testing.T) {
+	mock := &mockApp{quality: health.QualityReport{
+		Send:              health.Latency{Samples: 4, Failures: 1, P50: 0.25, P95: 1.5},
+		Ping:              health.Latency{Samples: 60, P50: 0.08, P95: 0.2},
+		KeepaliveTimeouts: 3,
+		Disconnects:       2,
+		OfflineSeconds:    95,
+	}}
+	srv := NewServer(Config{APIKey: "test-key"}, mock)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Contains(t, body, "whatsapp_send_latency_seconds{quantile=\"0.5\"} 0.25\nwhatsapp_send_latency_seconds{quantile=\"0.95\"} 1.5\n")
+	assert.Contains(t, body, "whatsapp_send_failures 1\n")
+	assert.Contains(t, body, "whatsapp_ping_latency_seconds{quantile=\"0.95\"} 0.2\n")
+	assert.Contains(t, body, "whatsapp_keepalive_timeouts 3\n")
+	assert.Contains(t, body, "whatsapp_disconnects 2\n")
+	assert.Contains(t, body, "whatsapp_offline_seconds 95\n")
+}
+
+func TestMetrics_OutboxDepthPerSink(t *testing.T) {
+	mock := &mockApp{outboxStats: &store.OutboxStats{
+		Pending:  map[string]int64{"notifier": 3, "event_bus": 40},
+		Replayed: 9,
+		Expired:  1,
+	}}
+	srv := NewServer(Config{APIKey: "test-key"}, mock)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Contains(t, body, "whatsapp_outbox_pending{sink=\"event_bus\"} 40\nwhatsapp_outbox_pending{sink=\"notifier\"} 3\n")
+	assert.Contains(t, body, "whatsapp_outbox_replayed_total 9\n")
+	assert.Contains(t, body, "whatsapp_outbox_expired_total 1\n")
+}
+
+func TestPprof_DisabledByDefault(t *testing.T) {
+	srv := NewServer(Config{APIKey: "test-key"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/debug/pprof/", nil)
+	req.Header.Set("X-API-Key", "test-key")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestPprof_EnabledRequiresAuth(t *testing.T) {
+	srv := NewServer(Config{APIKey: "test-key", EnablePprof: true}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/debug/pprof/heap?debug=1", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/debug/pprof/heap?debug=1", nil)
+	req.Header.Set("X-API-Key", "test-key")
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "heap profile")
+}
+
+func TestPprof_AdminKeyOnly(t *testing.T) {
+	srv := NewServer(Config{APIKey: "test-key", APIKeys: map[string]string{"crm": "crm-key"}, EnablePprof: true}, nil)
+
+	for _, path := range []string{"/api/v1/debug/pprof/", "/api/v1/debug/pprof/cmdline"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-API-Key", "crm-key")
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code, path)
+	}
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/eventbus"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// SSE stream bounds: a reconnecting client is sent at most sseReplayLimit
+// missed messages, and idle streams get a comment every sseKeepAlive so
+// proxies keep them open.
+const (
+	sseReplayLimit = 1000
+	sseKeepAlive   = 30 * time.Second
+)
+
+// handleEvents streams live messages as Server-Sent Events, for clients
+// behind proxies that block WebSockets. Each event is named "message",
+// carries the JSON of the WebSocket stream and has the ID
+// <unix timestamp>-<message ID>. A client reconnecting with Last-Event-ID
+// is first sent the stored messages it missed, oldest first.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	var replay []eventbus.Event
+	c := s.events.subscribe(map[string]bool{eventbus.TypeMessage: true})
+	defer s.events.unsubscribe(c)
+	// Messages stored after subscribing are both replayed and streamed;
+	// replayed ones are skipped when they come in live
+	replayed := map[string]bool{}
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		since, lastID, ok := parseSSEEventID(v)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "invalid Last-Event-ID: expected <unix timestamp>-<message ID>")
+			return
+		}
+		from := since
+		if after := s.computeAfter(); after != nil && after.After(from) {
+			from = *after
+		}
+		includeJIDs, excludeJIDs := s.phoneFilter.JIDSuffixes()
+		messages, err := s.app.MessagesSince(from, sseReplayLimit, includeJIDs, excludeJIDs)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		// Messages of the last event's second up to that event were sent
+		for i, m := range messages {
+			if m.Timestamp.Unix() != since.Unix() {
+				break
+			}
+			if m.MessageID == lastID {
+				messages = messages[i+1:]
+				break
+			}
+		}
+		for _, m := range messages {
+			replay = append(replay, triggerMessageEvent(m))
+			replayed[m.MessageID] = true
+		}
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	// Keeps nginx from buffering the stream
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	for _, e := range replay {
+		if writeSSEEvent(w, e, nil) != nil {
+			return
+		}
+	}
+	if rc.Flush() != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-c.slow:
+			// The client reconnects and catches up from the store
+			return
+		case e := <-c.events:
+			if replayed[e.Message.ID] {
+				continue
+			}
+			err = writeSSEEvent(w, e.Event, e.data)
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// writeSSEEvent writes a message event; data is its JSON, marshalled if nil.
+func writeSSEEvent(w http.ResponseWriter, e eventbus.Event, data []byte) error {
+	if data == nil {
+		var err error
+		if data, err = eventbus.Encode(e, eventbus.FormatJSON); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", sseEventID(e), e.Type, data)
+	return err
+}
+
+func sseEventID(e eventbus.Event) string {
+	return strconv.FormatInt(e.Timestamp.Unix(), 10) + "-" + e.Message.ID
+}
+
+// parseSSEEventID splits an event ID into the message timestamp and ID.
+func parseSSEEventID(v string) (time.Time, string, bool) {
+	ts, id, ok := strings.Cut(v, "-")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if !ok || err != nil || id == "" {
+		return time.Time{}, "", false
+	}
+	return time.Unix(sec, 0), id, true
+}
+
+// triggerMessageEvent shapes a stored message like a live message event.
+func triggerMessageEvent(m store.TriggerMessage) eventbus.Event {
+	return eventbus.Event{
+		Type:      eventbus.TypeMessage,
+		ChatJID:   m.ChatJID,
+		Sender:    m.Sender,
+		Timestamp: m.Timestamp,
+		Message: &eventbus.Message{
+			ID:        m.MessageID,
+			Content:   m.Content,
+			IsFromMe:  m.IsFromMe,
+			MediaType: m.MediaType,
+		},
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/eventbus"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+type sseEvent struct {
+	id, name string
+	event    eventbus.Event
+}
+
+// readSSEEvent reads the next event of a stream, skipping comments.
+func readSSEEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var e sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && e.id != "":
+			return e
+		case strings.HasPrefix(line, "id: "):
+			e.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			e.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e.event))
+		}
+	}
+}
+
+func TestEventsStreamResumesFromLastEventID(t *testing.T) {
+	t0 := time.Unix(1712345678, 0)
+	mock := &mockApp{triggerMessages: []store.TriggerMessage{
+		{MessageID: "M1", ChatJID: "111@s.whatsapp.net", Content: "one", Timestamp: t0},
+		{MessageID: "M2", ChatJID: "111@s.whatsapp.net", Content: "two", Timestamp: t0},
+		{MessageID: "M3", ChatJID: "111@s.whatsapp.net", Content: "three", Timestamp: t0.Add(time.Second)},
+	}}
+	srv := newTestServer(mock)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-API-Key", "test-key")
+	req.Header.Set("Last-Event-ID", strconv.FormatInt(t0.Unix(), 10)+"-M1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	body := bufio.NewReader(resp.Body)
+
+	// The messages after M1 are replayed from the store
+	e := readSSEEvent(t, body)
+	assert.Equal(t, "1712345678-M2", e.id)
+	assert.Equal(t, eventbus.TypeMessage, e.name)
+	assert.Equal(t, "two", e.event.Message.Content)
+	assert.Equal(t, "1712345679-M3", readSSEEvent(t, body).id)
+
+	// then live ones, without repeating those replayed
+	require.Equal(t, 1, srv.events.len())
+	srv.streamEvent(eventbus.Event{Type: eventbus.TypeMessage, ChatJID: "111@s.whatsapp.net", Timestamp: t0.Add(time.Second), Message: &eventbus.Message{ID: "M3"}})
+	srv.streamEvent(eventbus.Event{Type: eventbus.TypeReceipt, ChatJID: "111@s.whatsapp.net", Receipt: &eventbus.Receipt{MessageIDs: []string{"M3"}, Type: "read"}})
+	srv.streamEvent(eventbus.Event{Type: eventbus.TypeMessage, ChatJID: "111@s.whatsapp.net", Timestamp: t0.Add(2 * time.Second), Message: &eventbus.Message{ID: "M4", Content: "four"}})
+	e = readSSEEvent(t, body)
+	assert.Equal(t, "1712345680-M4", e.id)
+	assert.Equal(t, "four", e.event.Message.Content)
+}
+
+func TestEventsStreamRejectsBadRequests(t *testing.T) {
+	srv := newTestServer(&mockApp{})
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/events", nil)
+	req.Header.Set("X-API-Key", "test-key")
+	req.Header.Set("Last-Event-ID", "yesterday")
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, 0, srv.events.len())
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/events", "", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
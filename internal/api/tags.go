@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func (s *Server) handleListTags(w http.ResponseWriter, r *http.Request) {
+	includeJIDs, excludeJIDs := s.phoneFilter.JIDSuffixes()
+	writeResult(w, s.app.ListTags(includeJIDs, excludeJIDs))
+}
+
+func (s *Server) handleListTaggedMessages(w http.ResponseWriter, r *http.Request) {
+	limit := parseIntParam(r, "limit", 20)
+	page := parseIntParam(r, "page", 0)
+	if limit > s.Config.MaxMessages {
+		limit = s.Config.MaxMessages
+	}
+	includeJIDs, excludeJIDs := s.phoneFilter.JIDSuffixes()
+	writeResult(w, s.app.ListTaggedMessages(r.PathValue("tag"), limit, page, includeJIDs, excludeJIDs, s.computeAfter()))
+}
+
+// tagMessageRequest is the body of POST /messages/{id}/tags.
+type tagMessageRequest struct {
+	Tags []string `json:"tags"`
+}
+
+func (s *Server) handleGetMessageTags(w http.ResponseWriter, r *http.Request) {
+	chatJID, ok := s.messageChatParam(w, r, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	writeResult(w, s.app.GetMessageTags(r.PathValue("id"), chatJID))
+}
+
+// handleTagMessage adds user-defined tags to a message, e.g. "needs-reply".
+// Tagged messages are listed by GET /messages?tag= and GET /tags/{tag}/messages.
+func (s *Server) handleTagMessage(w http.ResponseWriter, r *http.Request) {
+	var req tagMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if len(req.Tags) == 0 {
+		writeError(w, http.StatusBadRequest, "'tags' field is required")
+		return
+	}
+	chatJID, ok := s.messageChatParam(w, r, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	writeResult(w, s.app.TagMessage(r.PathValue("id"), chatJID, req.Tags))
+}
+
+func (s *Server) handleUntagMessage(w http.ResponseWriter, r *http.Request) {
+	chatJID, ok := s.messageChatParam(w, r, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	writeResult(w, s.app.UntagMessage(r.PathValue("id"), chatJID, r.PathValue("tag")))
+}
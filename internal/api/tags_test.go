@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleListTags(t *testing.T) {
+	mock := &mockApp{tagsResult: `{"success":true,"data":[{"tag":"invoice","messages":2}]}`}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/tags", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"invoice"`)
+}
+
+func TestHandleListTaggedMessages(t *testing.T) {
+	mock := &mockApp{tagsResult: `{"success":true,"data":[]}`}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/tags/invoice/messages?limit=500&page=2", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "invoice", mock.lastTag)
+	assert.Equal(t, 100, mock.lastLimit)
+	assert.Equal(t, 2, mock.lastPage)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/tags/invoice/messages", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 20, mock.lastLimit)
+}
+
+func TestHandleTagMessage(t *testing.T) {
+	mock := &mockApp{tagsResult: `{"success":true,"data":{"id":"ABC","tags":["needs-reply"]}}`}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodPost, "/api/v1/messages/ABC/tags?chat_jid=123@s.whatsapp.net", "test-key", `{"tags":["needs-reply"]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ABC", mock.lastTagMessageID)
+	assert.Equal(t, []string{"needs-reply"}, mock.lastTags)
+	if assert.NotNil(t, mock.lastChatJID) {
+		assert.Equal(t, "123@s.whatsapp.net", *mock.lastChatJID)
+	}
+
+	w = doRequest(srv, http.MethodPost, "/api/v1/messages/ABC/tags", "test-key", `{"tags":[]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(srv, http.MethodPost, "/api/v1/messages/ABC/tags", "test-key", `not json`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doRequest(srv, http.MethodDelete, "/api/v1/messages/ABC/tags/needs-reply", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "needs-reply", mock.lastTag)
+	assert.Nil(t, mock.lastChatJID)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/messages/XYZ/tags", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "XYZ", mock.lastTagMessageID)
+}
+
+func TestHandleListMessages_TagFilter(t *testing.T) {
+	mock := &mockApp{listMessagesResult: `{"success":true,"data":[]}`}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/messages?tag=needs-reply", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.NotNil(t, mock.lastListTag) {
+		assert.Equal(t, "needs-reply", *mock.lastListTag)
+	}
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/messages/search?query=x", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, mock.lastListTag)
+}
+
+func TestHandleMessageTags_FilteredChat(t *testing.T) {
+	mock := &mockApp{
+		tagsResult:   `{"success":true,"data":{"id":"SHOWN","tags":["x"]}}`,
+		messageChats: map[string]string{"HIDDEN": "34600111222@s.whatsapp.net", "SHOWN": "34600333444@s.whatsapp.net"},
+	}
+	srv := NewServer(Config{APIKey: "test-key", MaxMessages: 100, PhoneBlacklist: []string{"111222"}}, mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/messages/HIDDEN/tags", "test-key", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(srv, http.MethodPost, "/api/v1/messages/HIDDEN/tags", "test-key", `{"tags":["x"]}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(srv, http.MethodDelete, "/api/v1/messages/HIDDEN/tags/x", "test-key", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, mock.lastTagMessageID)
+	assert.Empty(t, mock.lastTag)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/messages/HIDDEN/tags?chat_jid=34600111222@s.whatsapp.net", "test-key", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = doRequest(srv, http.MethodPost, "/api/v1/messages/SHOWN/tags", "test-key", `{"tags":["x"]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.NotNil(t, mock.lastChatJID) {
+		assert.Equal(t, "34600333444@s.whatsapp.net", *mock.lastChatJID)
+	}
+}
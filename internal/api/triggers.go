@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// defaultTriggerLimit is how many items a trigger poll returns by default.
+const defaultTriggerLimit = 50
+
+// Trigger endpoints answer polls of no-code platforms such as Zapier and
+// Make. Unlike the rest of the API they return a bare JSON array, newest
+// first, whose items have a numeric "id" the platforms deduplicate on.
+
+func (s *Server) handleNewMessagesTrigger(w http.ResponseWriter, r *http.Request) {
+	sinceID, ok := sinceIDParam(w, r)
+	if !ok {
+		return
+	}
+	params := store.NewMessagesParams{
+		SinceID: sinceID,
+		Limit:   s.triggerLimit(r),
+		After:   s.computeAfter(),
+	}
+	q := r.URL.Query()
+	if v := q.Get("chat_jid"); v != "" {
+		params.ChatJID = &v
+	}
+	if v := q.Get("from_me"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid from_me: must be true or false")
+			return
+		}
+		params.FromMe = &b
+	}
+	params.IncludeJIDs, params.ExcludeJIDs = s.phoneFilter.JIDSuffixes()
+
+	messages, err := s.app.NewMessages(params)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeTriggerItems(w, messages)
+}
+
+func (s *Server) handleNewChatsTrigger(w http.ResponseWriter, r *http.Request) {
+	sinceID, ok := sinceIDParam(w, r)
+	if !ok {
+		return
+	}
+	includeJIDs, excludeJIDs := s.phoneFilter.JIDSuffixes()
+	chats, err := s.app.NewChats(sinceID, s.triggerLimit(r), includeJIDs, excludeJIDs)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeTriggerItems(w, chats)
+}
+
+// sinceIDParam parses ?since_id=, answering 400 if it is invalid.
+func sinceIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	v := r.URL.Query().Get("since_id")
+	if v == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || id < 0 {
+		writeError(w, http.StatusBadRequest, "invalid since_id: must be the id of a previous item")
+		return 0, false
+	}
+	return id, true
+}
+
+func (s *Server) triggerLimit(r *http.Request) int {
+	limit := parseIntParam(r, "limit", defaultTriggerLimit)
+	if limit == 0 || limit > s.Config.MaxMessages {
+		limit = s.Config.MaxMessages
+	}
+	return limit
+}
+
+func writeTriggerItems(w http.ResponseWriter, items interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
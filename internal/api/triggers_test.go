@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+func TestHandleNewMessagesTrigger(t *testing.T) {
+	mock := &mockApp{triggerMessages: []store.TriggerMessage{
+		{ID: 42, MessageID: "M2", ChatJID: "111@s.whatsapp.net", Content: "second"},
+		{ID: 41, MessageID: "M1", ChatJID: "111@s.whatsapp.net", Content: "first"},
+	}}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/triggers/new-messages?since_id=40&chat_jid=111@s.whatsapp.net&from_me=false&limit=500", "test-key", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var items []map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &items), "trigger responses are bare arrays")
+	require.Len(t, items, 2)
+	assert.Equal(t, float64(42), items[0]["id"])
+	require.NotNil(t, mock.lastTriggerParams)
+	assert.Equal(t, int64(40), mock.lastTriggerParams.SinceID)
+	assert.Equal(t, "111@s.whatsapp.net", *mock.lastTriggerParams.ChatJID)
+	assert.False(t, *mock.lastTriggerParams.FromMe)
+	assert.Equal(t, 100, mock.lastTriggerParams.Limit, "limit capped at MAX_MESSAGES")
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/triggers/new-messages", "test-key", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(0), mock.lastTriggerParams.SinceID)
+	assert.Equal(t, defaultTriggerLimit, mock.lastTriggerParams.Limit)
+	assert.Nil(t, mock.lastTriggerParams.FromMe)
+
+	// The v2 envelope is not applied to trigger arrays either
+	w = doRequest(srv, http.MethodGet, "/api/v2/triggers/new-messages", "test-key", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+
+	for _, query := range []string{"since_id=abc", "since_id=-1", "from_me=maybe"} {
+		w = doRequest(srv, http.MethodGet, "/api/v1/triggers/new-messages?"+query, "test-key", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestHandleNewMessagesTrigger_Empty(t *testing.T) {
+	srv := newTestServer(&mockApp{})
+	w := doRequest(srv, http.MethodGet, "/api/v1/triggers/new-messages?since_id=99", "test-key", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+}
+
+func TestHandleNewChatsTrigger(t *testing.T) {
+	mock := &mockApp{triggerChats: []store.TriggerChat{{ID: 7, JID: tokenGroup, Name: "Team", IsGroup: true}}}
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/triggers/new-chats?since_id=3&limit=10", "test-key", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"jid":"`+tokenGroup+`"`)
+	assert.Equal(t, int64(3), mock.lastTriggerSinceID)
+	assert.Equal(t, 10, mock.lastLimit)
+}
+
+func TestChatToken_ScopesTriggers(t *testing.T) {
+	mock := &mockApp{}
+	srv := newTestServer(mock)
+	token := mintChatToken(t, srv, `{"chat_jid":"`+tokenGroup+`"}`)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/triggers/new-messages", token, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, tokenGroup, *mock.lastTriggerParams.ChatJID)
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/triggers/new-chats", token, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
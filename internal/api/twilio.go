@@ -0,0 +1,189 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// The Twilio shim accepts the Messages resource of Twilio's REST API, so
+// tools written for Twilio's WhatsApp sender can be pointed at this server:
+//
+//	POST /2010-04-01/Accounts/{AccountSid}/Messages.json
+//	To=whatsapp:+34600111222&From=whatsapp:+14155238886&Body=Hello
+//
+// The send is served by POST /messages/send, with the same authentication
+// (the API key as the Basic auth password), limits and phone filtering, and
+// its result is returned as a Twilio message resource or error.
+
+// twilioAPIVersion is the version of Twilio's REST API the shim mimics.
+const twilioAPIVersion = "2010-04-01"
+
+// twilioMessage is the subset of Twilio's message resource the shim returns.
+type twilioMessage struct {
+	SID          string  `json:"sid"`
+	AccountSID   string  `json:"account_sid"`
+	From         string  `json:"from"`
+	To           string  `json:"to"`
+	Body         string  `json:"body"`
+	Status       string  `json:"status"`
+	Direction    string  `json:"direction"`
+	APIVersion   string  `json:"api_version"`
+	NumSegments  string  `json:"num_segments"`
+	NumMedia     string  `json:"num_media"`
+	DateCreated  string  `json:"date_created"`
+	DateUpdated  string  `json:"date_updated"`
+	DateSent     *string `json:"date_sent"`
+	ErrorCode    *int    `json:"error_code"`
+	ErrorMessage *string `json:"error_message"`
+	Price        *string `json:"price"`
+	PriceUnit    *string `json:"price_unit"`
+	URI          string  `json:"uri"`
+}
+
+// twilioError is Twilio's error body.
+type twilioError struct {
+	Code     int    `json:"code"`
+	Message  string `json:"message"`
+	MoreInfo string `json:"more_info"`
+	Status   int    `json:"status"`
+}
+
+// Twilio error codes returned by the shim.
+const (
+	twilioErrInvalidTo   = 21211
+	twilioErrMissingBody = 21602
+	twilioErrMissingTo   = 21604
+	twilioErrMedia       = 21620
+)
+
+// twilioShim serves Twilio message sends with next, the handler chain of
+// POST /messages/send.
+func (s *Server) twilioShim(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			writeTwilioError(w, http.StatusBadRequest, 0, "invalid form body")
+			return
+		}
+		to, from, body := r.PostForm.Get("To"), r.PostForm.Get("From"), r.PostForm.Get("Body")
+		switch {
+		case to == "":
+			writeTwilioError(w, http.StatusBadRequest, twilioErrMissingTo, "A 'To' phone number is required.")
+			return
+		case r.PostForm.Get("MediaUrl") != "":
+			writeTwilioError(w, http.StatusBadRequest, twilioErrMedia, "MediaUrl is not supported.")
+			return
+		case body == "":
+			writeTwilioError(w, http.StatusBadRequest, twilioErrMissingBody, "Message body is required.")
+			return
+		}
+
+		payload, _ := json.Marshal(sendRequest{To: twilioAddress(to), Message: body})
+		req := r.Clone(r.Context())
+		req.URL.Path = "/messages/send"
+		req.Body = io.NopCloser(bytes.NewReader(payload))
+		req.ContentLength = int64(len(payload))
+		req.Header.Set("Content-Type", "application/json")
+		buf := &bufferedResponse{w: w}
+		next.ServeHTTP(buf, req)
+		if buf.passthrough {
+			return
+		}
+		if buf.status == 0 {
+			buf.status = http.StatusOK
+		}
+
+		var env v1Envelope
+		if json.Unmarshal(buf.body.Bytes(), &env) != nil || env.Success == nil {
+			writeTwilioError(w, http.StatusInternalServerError, 0, strings.TrimSpace(buf.body.String()))
+			return
+		}
+		if !*env.Success {
+			msg := ""
+			if env.Error != nil {
+				msg = *env.Error
+			}
+			status, code := buf.status, 0
+			if strings.Contains(msg, "invalid recipient") || strings.Contains(msg, "not supported") {
+				status, code = http.StatusBadRequest, twilioErrInvalidTo
+			} else if status < 400 {
+				// v1 reports send failures with 200 OK
+				status = http.StatusInternalServerError
+			}
+			writeTwilioError(w, status, code, msg)
+			return
+		}
+
+		var sent struct {
+			Queued bool `json:"queued"`
+		}
+		json.Unmarshal(env.Data, &sent)
+		now := time.Now().UTC().Format(time.RFC1123Z)
+		accountSID := r.PathValue("AccountSid")
+		msg := twilioMessage{
+			SID:         newTwilioSID(),
+			AccountSID:  accountSID,
+			From:        from,
+			To:          to,
+			Body:        body,
+			Status:      "sent",
+			Direction:   "outbound-api",
+			APIVersion:  twilioAPIVersion,
+			NumSegments: "1",
+			NumMedia:    "0",
+			DateCreated: now,
+			DateUpdated: now,
+			DateSent:    &now,
+		}
+		if sent.Queued {
+			msg.Status, msg.DateSent = "queued", nil
+		}
+		msg.URI = fmt.Sprintf("/%s/Accounts/%s/Messages/%s.json", twilioAPIVersion, accountSID, msg.SID)
+		w.Header().Del("Content-Length")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(msg)
+	})
+}
+
+// twilioAddress strips the channel prefix of a Twilio address, e.g.
+// "whatsapp:+34600111222".
+func twilioAddress(addr string) string {
+	if channel, rest, ok := strings.Cut(addr, ":"); ok && strings.EqualFold(channel, "whatsapp") {
+		return strings.TrimSpace(rest)
+	}
+	return strings.TrimSpace(addr)
+}
+
+// newTwilioSID returns a random message SID: "SM" and 32 hex digits.
+func newTwilioSID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "SM" + hex.EncodeToString(b)
+}
+
+// writeTwilioError writes a Twilio error body. Without a specific code, the
+// generic Twilio code for the status is used, e.g. 20404.
+func writeTwilioError(w http.ResponseWriter, status, code int, msg string) {
+	if code == 0 {
+		code = 20000 + status
+		if status == http.StatusUnauthorized {
+			code = 20003
+		}
+	}
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(twilioError{
+		Code:     code,
+		Message:  msg,
+		MoreInfo: fmt.Sprintf("https://www.twilio.com/docs/errors/%d", code),
+		Status:   status,
+	})
+}
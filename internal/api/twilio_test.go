@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const twilioPath = "/2010-04-01/Accounts/AC123/Messages.json"
+
+func doTwilioRequest(srv *Server, password string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, twilioPath, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("AC123", password)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+	return w
+}
+
+func TestTwilioShim_Send(t *testing.T) {
+	mock := &mockApp{sendMessageResult: `{"success":true,"data":{"sent":true,"recipient":"34600111222@s.whatsapp.net","message":"Hello"},"error":null}`}
+	srv := NewServer(Config{APIKey: "test-key", TwilioCompat: true}, mock)
+
+	w := doTwilioRequest(srv, "test-key", url.Values{
+		"To":   {"whatsapp:+34600111222"},
+		"From": {"whatsapp:+14155238886"},
+		"Body": {"Hello"},
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, "+34600111222", mock.lastSendRecipient)
+	assert.Equal(t, "Hello", mock.lastSendMessage)
+
+	var msg twilioMessage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &msg))
+	assert.Regexp(t, `^SM[0-9a-f]{32}$`, msg.SID)
+	assert.Equal(t, "AC123", msg.AccountSID)
+	assert.Equal(t, "whatsapp:+34600111222", msg.To)
+	assert.Equal(t, "whatsapp:+14155238886", msg.From)
+	assert.Equal(t, "sent", msg.Status)
+	assert.NotNil(t, msg.DateSent)
+	assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages/"+msg.SID+".json", msg.URI)
+
+	// Sends held for quiet hours are queued
+	mock.sendMessageResult = `{"success":true,"data":{"sent":false,"queued":true,"queue_id":1},"error":null}`
+	w = doTwilioRequest(srv, "test-key", url.Values{"To": {"whatsapp:+34600111222"}, "Body": {"Later"}})
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &msg))
+	assert.Equal(t, "queued", msg.Status)
+	assert.Nil(t, msg.DateSent)
+}
+
+func TestTwilioShim_Errors(t *testing.T) {
+	mock := &mockApp{sendMessageResult: `{"success":false,"data":null,"error":"not connected"}`}
+	srv := NewServer(Config{APIKey: "test-key", TwilioCompat: true, PhoneBlacklist: []string{"34600999999"}}, mock)
+
+	for name, tc := range map[string]struct {
+		password string
+		form     url.Values
+		status   int
+		code     int
+	}{
+		"bad credentials": {"wrong", url.Values{"To": {"whatsapp:+34600111222"}, "Body": {"hi"}}, http.StatusUnauthorized, 20003},
+		"missing To":      {"test-key", url.Values{"Body": {"hi"}}, http.StatusBadRequest, 21604},
+		"missing Body":    {"test-key", url.Values{"To": {"whatsapp:+34600111222"}}, http.StatusBadRequest, 21602},
+		"media":           {"test-key", url.Values{"To": {"whatsapp:+34600111222"}, "MediaUrl": {"https://example.com/a.jpg"}}, http.StatusBadRequest, 21620},
+		"invalid To":      {"test-key", url.Values{"To": {"whatsapp:alice"}, "Body": {"hi"}}, http.StatusBadRequest, 21211},
+		"filtered":        {"test-key", url.Values{"To": {"whatsapp:+34600999999"}, "Body": {"hi"}}, http.StatusForbidden, 20403},
+		"send failure":    {"test-key", url.Values{"To": {"whatsapp:+34600111222"}, "Body": {"hi"}}, http.StatusInternalServerError, 20500},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := doTwilioRequest(srv, tc.password, tc.form)
+			require.Equal(t, tc.status, w.Code, w.Body.String())
+			var e twilioError
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &e))
+			assert.Equal(t, tc.code, e.Code)
+			assert.Equal(t, tc.status, e.Status)
+			assert.NotEmpty(t, e.Message)
+		})
+	}
+}
+
+func TestTwilioShim_Disabled(t *testing.T) {
+	srv := newTestServer(&mockApp{})
+	w := doTwilioRequest(srv, "test-key", url.Values{"To": {"whatsapp:+34600111222"}, "Body": {"hi"}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultKeyID identifies the key configured via API_KEY. It is the only key
+// allowed to use the admin endpoints.
+const DefaultKeyID = "default"
+
+type keyIDContextKey struct{}
+
+// KeyUsage holds the request counters of one API key since startup.
+type KeyUsage struct {
+	KeyID         string     `json:"key_id"`
+	Requests      int64      `json:"requests"`
+	Errors        int64      `json:"errors"`
+	MessagesSent  int64      `json:"messages_sent"`
+	BytesReceived int64      `json:"bytes_received"`
+	BytesSent     int64      `json:"bytes_sent"`
+	LastUsedAt    *time.Time `json:"last_used_at"`
+}
+
+// usageTracker aggregates KeyUsage for every configured key.
+type usageTracker struct {
+	mu   sync.Mutex
+	keys map[string]*KeyUsage
+}
+
+func newUsageTracker(keyIDs []string) *usageTracker {
+	t := &usageTracker{keys: make(map[string]*KeyUsage)}
+	for _, id := range keyIDs {
+		t.keys[id] = &KeyUsage{KeyID: id}
+	}
+	return t
+}
+
+// add starts tracking a key that is not configured, e.g. MQTTKeyID.
+func (t *usageTracker) add(keyID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.keys[keyID]; !ok {
+		t.keys[keyID] = &KeyUsage{KeyID: keyID}
+	}
+}
+
+func (t *usageTracker) recordRequest(keyID string, status int, bytesIn, bytesOut int64, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u, ok := t.keys[keyID]
+	if !ok {
+		return
+	}
+	u.Requests++
+	if status >= 400 {
+		u.Errors++
+	}
+	u.BytesReceived += bytesIn
+	u.BytesSent += bytesOut
+	u.LastUsedAt = &at
+}
+
+func (t *usageTracker) recordSend(keyID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if u, ok := t.keys[keyID]; ok {
+		u.MessagesSent++
+	}
+}
+
+// get returns a copy of the usage of one key.
+func (t *usageTracker) get(keyID string) (KeyUsage, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u, ok := t.keys[keyID]
+	if !ok {
+		return KeyUsage{}, false
+	}
+	return *u, true
+}
+
+// all returns a copy of the usage of every key, sorted by key ID.
+func (t *usageTracker) all() []KeyUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]KeyUsage, 0, len(t.keys))
+	for _, u := range t.keys {
+		out = append(out, *u)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].KeyID < out[j].KeyID })
+	return out
+}
+
+// keyIDFromContext returns the ID of the API key that authenticated the request.
+func keyIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(keyIDContextKey{}).(string)
+	return id
+}
+
+// countingReader counts the bytes read from a request body.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// countingWriter records the status code and bytes written to a response.
+type countingWriter struct {
+	http.ResponseWriter
+	status int
+	n      int64
+}
+
+func (c *countingWriter) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	n, err := c.ResponseWriter.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Flush keeps streaming handlers working behind the usage wrapper.
+func (c *countingWriter) Flush() {
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets handlers hijack the connection, e.g. for WebSockets.
+func (c *countingWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// resultSucceeded reports whether an App result envelope has success=true.
+func resultSucceeded(result string) bool {
+	var env struct {
+		Success bool `json:"success"`
+	}
+	return json.Unmarshal([]byte(result), &env) == nil && env.Success
+}
+
+func (s *Server) handleListKeyUsage(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	writeJSON(w, s.usage.all())
+}
+
+func (s *Server) handleKeyUsage(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	u, ok := s.usage.get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown key ID")
+		return
+	}
+	writeJSON(w, u)
+}
+
+// requireAdmin rejects requests not authenticated with the default key.
+func (s *Server) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if keyIDFromContext(r.Context()) != DefaultKeyID {
+		writeError(w, http.StatusForbidden, "admin key required")
+		return false
+	}
+	return true
+}
+
+// adminOnly serves h to the admin key only.
+func (s *Server) adminOnly(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.requireAdmin(w, r) {
+			h(w, r)
+		}
+	}
+}
+
+// writeJSON writes data in the success envelope.
+func writeJSON(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    data,
+		"error":   nil,
+	})
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newUsageTestServer(mock *mockApp) *Server {
+	return NewServer(Config{
+		APIKey:      "admin-key",
+		APIKeys:     map[string]string{"crm": "crm-key"},
+		MaxMessages: 100,
+	}, mock)
+}
+
+func doRequest(srv *Server, method, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("X-API-Key", key)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	return w
+}
+
+func TestKeyUsage(t *testing.T) {
+	mock := &mockApp{
+		sendMessageResult:  `{"success":true,"data":{}}`,
+		listMessagesResult: `{"success":true,"data":[]}`,
+	}
+	srv := newUsageTestServer(mock)
+
+	body := `{"to":"1234567890","message":"hi"}`
+	assert.Equal(t, http.StatusOK, doRequest(srv, http.MethodPost, "/api/v1/messages/send", "crm-key", body).Code)
+	assert.Equal(t, http.StatusOK, doRequest(srv, http.MethodGet, "/api/v1/messages", "crm-key", "").Code)
+	assert.Equal(t, http.StatusBadRequest, doRequest(srv, http.MethodPost, "/api/v1/messages/send", "crm-key", "{").Code)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/admin/keys/crm/usage", "admin-key", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data KeyUsage `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "crm", resp.Data.KeyID)
+	assert.EqualValues(t, 3, resp.Data.Requests)
+	assert.EqualValues(t, 1, resp.Data.Errors)
+	assert.EqualValues(t, 1, resp.Data.MessagesSent)
+	assert.EqualValues(t, len(body)+1, resp.Data.BytesReceived)
+	assert.Positive(t, resp.Data.BytesSent)
+	assert.NotNil(t, resp.Data.LastUsedAt)
+
+	// The admin's own request is attributed to the default key
+	usage, _ := srv.usage.get(DefaultKeyID)
+	assert.EqualValues(t, 1, usage.Requests)
+}
+
+func TestKeyUsage_AdminOnly(t *testing.T) {
+	srv := newUsageTestServer(&mockApp{})
+
+	assert.Equal(t, http.StatusForbidden, doRequest(srv, http.MethodGet, "/api/v1/admin/keys/crm/usage", "crm-key", "").Code)
+	assert.Equal(t, http.StatusForbidden, doRequest(srv, http.MethodGet, "/api/v1/admin/keys", "crm-key", "").Code)
+	assert.Equal(t, http.StatusNotFound, doRequest(srv, http.MethodGet, "/api/v1/admin/keys/nope/usage", "admin-key", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, doRequest(srv, http.MethodGet, "/api/v1/admin/keys", "wrong", "").Code)
+}
+
+func TestMetrics_KeyLabels(t *testing.T) {
+	srv := newUsageTestServer(&mockApp{listChatsResult: `{"success":true,"data":[]}`})
+	doRequest(srv, http.MethodGet, "/api/v1/chats", "crm-key", "")
+
+	w := doRequest(srv, http.MethodGet, "/metrics", "", "")
+	assert.Contains(t, w.Body.String(), `whatsapp_api_requests_total{key="crm"} 1`)
+	assert.Contains(t, w.Body.String(), `whatsapp_api_requests_total{key="default"} 0`)
+}
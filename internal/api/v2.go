@@ -0,0 +1,161 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// API v2 serves the v1 handlers through a shim that rewrites their
+// {"success","data","error"} envelope into typed responses with meaningful
+// status codes:
+//
+//	{"data": ..., "meta": {"api_version": "v2", "request_id": "..."}}
+//	{"error": {"code": "not_found", "message": "..."}, "meta": {...}}
+//
+// Non-JSON responses such as media downloads pass through unchanged.
+
+type v2Response struct {
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error *v2Error        `json:"error,omitempty"`
+	Meta  v2Meta          `json:"meta"`
+}
+
+type v2Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type v2Meta struct {
+	APIVersion string `json:"api_version"`
+	RequestID  string `json:"request_id,omitempty"`
+}
+
+// v1Envelope is the response shape produced by the v1 handlers.
+type v1Envelope struct {
+	Success *bool           `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Error   *string         `json:"error"`
+}
+
+// bufferedResponse holds a handler's JSON response so the v2 shim can
+// rewrite it. Any other content type is streamed straight through.
+type bufferedResponse struct {
+	w           http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	passthrough bool
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.w.Header() }
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status != 0 {
+		return
+	}
+	b.status = status
+	if !strings.HasPrefix(b.w.Header().Get("Content-Type"), "application/json") {
+		b.passthrough = true
+		b.w.WriteHeader(status)
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.WriteHeader(http.StatusOK)
+	}
+	if b.passthrough {
+		return b.w.Write(p)
+	}
+	return b.body.Write(p)
+}
+
+// Flush keeps streamed (non-JSON) responses flowing.
+func (b *bufferedResponse) Flush() {
+	if f, ok := b.w.(http.Flusher); ok && b.passthrough {
+		f.Flush()
+	}
+}
+
+// Unwrap hands the response to a handler that takes over the connection,
+// e.g. for WebSockets, so nothing is buffered or rewritten.
+func (b *bufferedResponse) Unwrap() http.ResponseWriter {
+	b.passthrough = true
+	return b.w
+}
+
+// v2Shim translates v1 envelopes written by next into the v2 format.
+func (s *Server) v2Shim(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := &bufferedResponse{w: w}
+		next.ServeHTTP(buf, r)
+		if buf.passthrough {
+			return
+		}
+		if buf.status == 0 {
+			buf.status = http.StatusOK
+		}
+
+		var env v1Envelope
+		if json.Unmarshal(buf.body.Bytes(), &env) != nil || env.Success == nil {
+			w.WriteHeader(buf.status)
+			w.Write(buf.body.Bytes())
+			return
+		}
+
+		resp := v2Response{Meta: v2Meta{APIVersion: "v2", RequestID: w.Header().Get("X-Request-ID")}}
+		status := buf.status
+		if *env.Success {
+			resp.Data = env.Data
+			if len(resp.Data) == 0 {
+				resp.Data = json.RawMessage("null")
+			}
+		} else {
+			msg := ""
+			if env.Error != nil {
+				msg = *env.Error
+			}
+			if status < 400 {
+				// v1 reports application errors with 200 OK
+				status = v2ErrorStatus(msg)
+			}
+			resp.Error = &v2Error{Code: v2ErrorCode(status), Message: msg}
+		}
+
+		w.Header().Del("Content-Length")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// v2ErrorStatus picks a status for an application error reported with 200 OK.
+func v2ErrorStatus(msg string) int {
+	if strings.Contains(strings.ToLower(msg), "not found") {
+		return http.StatusNotFound
+	}
+	return http.StatusUnprocessableEntity
+}
+
+// v2ErrorCode derives a snake_case error code from a status, e.g. "not_found".
+func v2ErrorCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
+
+// deprecationMiddleware marks every response as deprecated in favour of the
+// same path under successorPrefix, with the Deprecation, Sunset and Link
+// headers (RFC 9745, RFC 8594).
+func deprecationMiddleware(prefix, successorPrefix string, sunset time.Time, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+		successor := successorPrefix + strings.TrimPrefix(r.URL.Path, prefix)
+		w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type v2TestBody struct {
+	Data  json.RawMessage `json:"data"`
+	Error *v2Error        `json:"error"`
+	Meta  v2Meta          `json:"meta"`
+}
+
+func decodeV2(t *testing.T, body []byte) v2TestBody {
+	t.Helper()
+	var b v2TestBody
+	require.NoError(t, json.Unmarshal(body, &b), string(body))
+	return b
+}
+
+func TestV2_Success(t *testing.T) {
+	srv := newTestServer(&mockApp{listChatsResult: `{"success":true,"data":[{"jid":"1@s.whatsapp.net"}],"error":null}`})
+
+	w := doRequest(srv, http.MethodGet, "/api/v2/chats", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	b := decodeV2(t, w.Body.Bytes())
+	assert.JSONEq(t, `[{"jid":"1@s.whatsapp.net"}]`, string(b.Data))
+	assert.Nil(t, b.Error)
+	assert.Equal(t, "v2", b.Meta.APIVersion)
+	assert.Equal(t, w.Header().Get("X-Request-ID"), b.Meta.RequestID)
+}
+
+func TestV2_ApplicationErrors(t *testing.T) {
+	srv := newTestServer(&mockApp{
+		listChatsResult:  `{"success":false,"data":null,"error":"database is locked"}`,
+		duplicatesResult: `{"success":false,"data":null,"error":"message abc not found"}`,
+	})
+
+	w := doRequest(srv, http.MethodGet, "/api/v2/chats", "test-key", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	b := decodeV2(t, w.Body.Bytes())
+	require.NotNil(t, b.Error)
+	assert.Equal(t, "unprocessable_entity", b.Error.Code)
+	assert.Equal(t, "database is locked", b.Error.Message)
+	assert.Empty(t, b.Data)
+
+	w = doRequest(srv, http.MethodGet, "/api/v2/messages/abc/duplicates", "test-key", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "not_found", decodeV2(t, w.Body.Bytes()).Error.Code)
+
+	// v1 keeps reporting application errors with 200 OK
+	w = doRequest(srv, http.MethodGet, "/api/v1/chats", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"success":false,"data":null,"error":"database is locked"}`, w.Body.String())
+}
+
+func TestV2_HandlerErrors(t *testing.T) {
+	srv := newTestServer(&mockApp{})
+
+	w := doRequest(srv, http.MethodGet, "/api/v2/chats", "wrong-key", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "unauthorized", decodeV2(t, w.Body.Bytes()).Error.Code)
+
+	w = doRequest(srv, http.MethodPost, "/api/v2/messages/send", "test-key", "{")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	b := decodeV2(t, w.Body.Bytes())
+	assert.Equal(t, "bad_request", b.Error.Code)
+	assert.Equal(t, "invalid JSON body", b.Error.Message)
+}
+
+func TestV2_NonJSONPassesThrough(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	require.NoError(t, os.WriteFile(path, []byte("jpeg-bytes"), 0644))
+	srv := newTestServer(&mockApp{mediaFilePath: path, mediaFileMimeType: "image/jpeg"})
+
+	w := doRequest(srv, http.MethodGet, "/api/v2/media/abc", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "jpeg-bytes", w.Body.String())
+}
+
+func TestV1_DeprecationHeaders(t *testing.T) {
+	sunset := time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC)
+	srv := NewServer(Config{APIKey: "test-key", V1Sunset: sunset}, &mockApp{listChatsResult: `{"success":true,"data":[]}`})
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/chats", "test-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "true", w.Header().Get("Deprecation"))
+	assert.Equal(t, "Wed, 30 Jun 2027 00:00:00 GMT", w.Header().Get("Sunset"))
+	assert.Equal(t, `</api/v2/chats>; rel="successor-version"`, w.Header().Get("Link"))
+
+	w = doRequest(srv, http.MethodGet, "/api/v2/chats", "test-key", "")
+	assert.Empty(t, w.Header().Get("Deprecation"))
+
+	// Without a sunset date v1 is not marked deprecated
+	w = doRequest(newTestServer(&mockApp{listChatsResult: `{"success":true,"data":[]}`}), http.MethodGet, "/api/v1/chats", "test-key", "")
+	assert.Empty(t, w.Header().Get("Deprecation"))
+}
@@ -0,0 +1,9 @@
+package api
+
+import "net/http"
+
+// handleVersion reports the running version, build commit and whether a
+// newer release is available.
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	writeResult(w, s.app.VersionInfo(r.Context()))
+}
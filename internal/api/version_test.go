@@ -0,0 +1,19 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleVersion(t *testing.T) {
+	mock := &mockApp{versionResult: `{"success":true,"data":{"version":"v1.3.1","commit":"abc123","update_available":false},"error":null}`}
+	srv := newUsageTestServer(mock)
+
+	assert.Equal(t, http.StatusUnauthorized, doRequest(srv, http.MethodGet, "/api/v1/version", "", "").Code)
+
+	w := doRequest(srv, http.MethodGet, "/api/v1/version", "crm-key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, mock.versionResult, w.Body.String())
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/vicentereig/whatsapp-cli/internal/eventbus"
+)
+
+// WebSocket stream bounds: a client may fall wsClientBuffer events behind
+// before it is disconnected, and idle connections are pinged every
+// wsPingInterval so proxies keep them open.
+const (
+	wsClientBuffer = 256
+	wsPingInterval = 30 * time.Second
+	wsWriteTimeout = 10 * time.Second
+)
+
+// wsEventTypes are the event types a WebSocket client can subscribe to.
+var wsEventTypes = map[string]bool{
+	eventbus.TypeMessage:      true,
+	eventbus.TypeReceipt:      true,
+	eventbus.TypePresence:     true,
+	eventbus.TypeAccountAlert: true,
+}
+
+// eventHub fans the events of the sync loop out to WebSocket and
+// Server-Sent Events clients.
+type eventHub struct {
+	mu      sync.Mutex
+	clients map[*wsClient]struct{}
+}
+
+type wsClient struct {
+	types  map[string]bool // nil for every type
+	events chan hubEvent
+	slow   chan struct{} // closed once the client fell behind
+}
+
+// hubEvent is an event with its JSON encoding, marshalled once for all
+// clients.
+type hubEvent struct {
+	eventbus.Event
+	data []byte
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{clients: map[*wsClient]struct{}{}}
+}
+
+func (h *eventHub) subscribe(types map[string]bool) *wsClient {
+	c := &wsClient{types: types, events: make(chan hubEvent, wsClientBuffer), slow: make(chan struct{})}
+	h.mu.Lock()
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+	return c
+}
+
+func (h *eventHub) unsubscribe(c *wsClient) {
+	h.mu.Lock()
+	delete(h.clients, c)
+	h.mu.Unlock()
+}
+
+// publish queues e for every client subscribed to its type, without ever
+// blocking the sync loop: a client whose buffer is full is dropped.
+func (h *eventHub) publish(e eventbus.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var data []byte
+	for c := range h.clients {
+		if c.types != nil && !c.types[e.Type] {
+			continue
+		}
+		if data == nil {
+			var err error
+			if data, err = json.Marshal(e); err != nil {
+				return
+			}
+		}
+		select {
+		case c.events <- hubEvent{Event: e, data: data}:
+		default:
+			close(c.slow)
+			delete(h.clients, c)
+		}
+	}
+}
+
+// len returns the number of connected clients.
+func (h *eventHub) len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// streamEvent passes an event of the sync loop to the streaming clients
+// unless it is history backfill or concerns a chat the phone filters hide.
+func (s *Server) streamEvent(e eventbus.Event) {
+	if e.Message != nil && e.Message.History {
+		return
+	}
+	chat := e.ChatJID
+	if chat == "" {
+		chat = e.Sender
+	}
+	if chat != "" && !s.phoneFilter.IsAllowed(chat) {
+		return
+	}
+	s.events.publish(e)
+}
+
+// handleWebSocket upgrades to a WebSocket and streams live events as JSON
+// text messages, shaped like event bus payloads, until the client goes
+// away. ?types=message,receipt limits the stream to those event types.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	var types map[string]bool
+	if v := r.URL.Query().Get("types"); v != "" {
+		types = map[string]bool{}
+		for _, t := range splitAndTrim(v) {
+			if !wsEventTypes[t] {
+				writeError(w, http.StatusBadRequest, "unknown event type: "+t)
+				return
+			}
+			types[t] = true
+		}
+	}
+
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		// Accept has answered the failed handshake
+		return
+	}
+	defer conn.CloseNow()
+
+	c := s.events.subscribe(types)
+	defer s.events.unsubscribe(c)
+	// Clients only listen; CloseRead handles their pings and close frames
+	ctx := conn.CloseRead(r.Context())
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.slow:
+			conn.Close(websocket.StatusPolicyViolation, "client fell behind")
+			return
+		case e := <-c.events:
+			wctx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+			err := conn.Write(wctx, websocket.MessageText, e.data)
+			cancel()
+			if err != nil {
+				return
+			}
+		case <-ping.C:
+			pctx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+			err := conn.Ping(pctx)
+			cancel()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
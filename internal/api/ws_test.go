@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/eventbus"
+)
+
+func dialWS(t *testing.T, url string) *websocket.Conn {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, url, &websocket.DialOptions{
+		HTTPHeader: http.Header{"Authorization": {"Bearer test-key"}},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.CloseNow() })
+	return conn
+}
+
+func readWSEvent(t *testing.T, conn *websocket.Conn) eventbus.Event {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	typ, data, err := conn.Read(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, websocket.MessageText, typ)
+	var e eventbus.Event
+	require.NoError(t, json.Unmarshal(data, &e))
+	return e
+}
+
+func TestWebSocketStreamsSyncEvents(t *testing.T) {
+	mock := &syncCallbackMock{}
+	srv := NewServer(Config{APIKey: "test-key", MaxMessages: 100, PhoneBlacklist: []string{"222"}}, mock)
+	srv.SetAuthenticated(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv.StartBackgroundSync(ctx)
+	require.Eventually(t, func() bool { return mock.getOnEvent() != nil }, 3*time.Second, 10*time.Millisecond)
+
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/v1/ws"
+	all := dialWS(t, url)
+	receipts := dialWS(t, url+"?types=receipt")
+	v2 := dialWS(t, strings.Replace(url, "/v1/", "/v2/", 1)+"?types=presence")
+	require.Eventually(t, func() bool { return srv.events.len() == 3 }, time.Second, 5*time.Millisecond)
+
+	emit := mock.getOnEvent()
+	// History backfill and blacklisted chats are not streamed
+	emit(eventbus.Event{Type: eventbus.TypeMessage, ChatJID: "111@s.whatsapp.net", Message: &eventbus.Message{ID: "H1", History: true}})
+	emit(eventbus.Event{Type: eventbus.TypeMessage, ChatJID: "222@s.whatsapp.net", Message: &eventbus.Message{ID: "B1"}})
+	emit(eventbus.Event{Type: eventbus.TypeMessage, ChatJID: "111@s.whatsapp.net", Message: &eventbus.Message{ID: "M1", Content: "hi"}})
+	emit(eventbus.Event{Type: eventbus.TypeReceipt, ChatJID: "111@s.whatsapp.net", Receipt: &eventbus.Receipt{MessageIDs: []string{"M1"}, Type: "read"}})
+	emit(eventbus.Event{Type: eventbus.TypePresence, Sender: "111@s.whatsapp.net", Presence: &eventbus.Presence{Available: true}})
+
+	e := readWSEvent(t, all)
+	assert.Equal(t, eventbus.TypeMessage, e.Type)
+	assert.Equal(t, "M1", e.Message.ID)
+	assert.Equal(t, eventbus.TypeReceipt, readWSEvent(t, all).Type)
+	assert.Equal(t, eventbus.TypePresence, readWSEvent(t, all).Type)
+
+	e = readWSEvent(t, receipts)
+	assert.Equal(t, eventbus.TypeReceipt, e.Type)
+	assert.Equal(t, []string{"M1"}, e.Receipt.MessageIDs)
+
+	assert.Equal(t, eventbus.TypePresence, readWSEvent(t, v2).Type)
+
+	// Disconnected clients are unsubscribed
+	all.Close(websocket.StatusNormalClosure, "")
+	require.Eventually(t, func() bool { return srv.events.len() == 2 }, time.Second, 5*time.Millisecond)
+}
+
+func TestWebSocketRejectsUnknownTypes(t *testing.T) {
+	srv := newTestServer(&mockApp{})
+	w := doRequest(srv, http.MethodGet, "/api/v1/ws?types=message,typing", "test-key", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "typing")
+
+	w = doRequest(srv, http.MethodGet, "/api/v1/ws", "", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestEventHubDropsSlowClients(t *testing.T) {
+	h := newEventHub()
+	c := h.subscribe(nil)
+	for i := 0; i <= wsClientBuffer; i++ {
+		h.publish(eventbus.Event{Type: eventbus.TypePresence})
+	}
+	select {
+	case <-c.slow:
+	default:
+		t.Fatal("slow client was not dropped")
+	}
+	assert.Equal(t, 0, h.len())
+}
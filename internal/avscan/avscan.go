@@ -0,0 +1,96 @@
+// Package avscan scans media for malware before it is served, with ClamAV's
+// clamd daemon or with an external command.
+package avscan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// Verdict is the outcome of scanning a file.
+type Verdict struct {
+	Infected bool
+	// Threat names what was found in an infected file.
+	Threat string
+}
+
+// Scanner scans content for malware.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (Verdict, error)
+}
+
+// FromEnv returns the scanner configured by the environment, read through
+// getenv, or nil if media scanning is not enabled:
+//
+//	MEDIA_SCAN_CLAMD    clamd socket: a Unix socket path, or HOST:PORT for TCP
+//	MEDIA_SCAN_COMMAND  command that reads the content on stdin and exits 0
+//	                    if it is clean and 1 if it is infected
+func FromEnv(getenv func(string) string) (Scanner, error) {
+	clamd, command := strings.TrimSpace(getenv("MEDIA_SCAN_CLAMD")), strings.TrimSpace(getenv("MEDIA_SCAN_COMMAND"))
+	switch {
+	case clamd != "" && command != "":
+		return nil, fmt.Errorf("set either MEDIA_SCAN_CLAMD or MEDIA_SCAN_COMMAND, not both")
+	case clamd != "":
+		return NewClamd(clamd), nil
+	case command != "":
+		return NewCommand(command), nil
+	}
+	return nil, nil
+}
+
+// Command scans content by running a command with the content on its
+// standard input, following clamscan's exit codes: 0 means clean and 1
+// infected, with the threat named on the last line of its output.
+type Command struct {
+	name string
+	args []string
+}
+
+// NewCommand returns a scanner running the command line, split on spaces,
+// e.g. "clamdscan --no-summary -".
+func NewCommand(commandLine string) *Command {
+	fields := strings.Fields(commandLine)
+	return &Command{name: fields[0], args: fields[1:]}
+}
+
+func (c *Command) Scan(ctx context.Context, r io.Reader) (Verdict, error) {
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.name, c.args...)
+	cmd.Stdin = r
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return Verdict{}, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		return Verdict{Infected: true, Threat: threat(out.String())}, nil
+	}
+	if msg := strings.TrimSpace(out.String()); msg != "" {
+		return Verdict{}, fmt.Errorf("%s: %w: %s", c.name, err, msg)
+	}
+	return Verdict{}, fmt.Errorf("%s: %w", c.name, err)
+}
+
+// threat picks the threat name out of a scanner's output, whose last line
+// reads "stdin: NAME FOUND" for ClamAV.
+func threat(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	if name, ok := strings.CutSuffix(last, " FOUND"); ok {
+		if i := strings.LastIndex(name, ": "); i >= 0 {
+			name = name[i+2:]
+		}
+		last = name
+	}
+	if last == "" {
+		return "unknown"
+	}
+	return last
+}
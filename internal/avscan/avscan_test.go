@@ -0,0 +1,131 @@
+package avscan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClamd answers INSTREAM requests on a Unix socket, finding "EICAR" in
+// the streamed content.
+func fakeClamd(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "clamd.sock")
+	ln, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				if cmd, err := r.ReadString(0); err != nil || cmd != "zINSTREAM\x00" {
+					io.WriteString(conn, "UNKNOWN COMMAND\x00")
+					return
+				}
+				var content []byte
+				for {
+					var size uint32
+					if binary.Read(r, binary.BigEndian, &size) != nil {
+						return
+					}
+					if size == 0 {
+						break
+					}
+					chunk := make([]byte, size)
+					if _, err := io.ReadFull(r, chunk); err != nil {
+						return
+					}
+					content = append(content, chunk...)
+				}
+				if strings.Contains(string(content), "EICAR") {
+					io.WriteString(conn, "stream: Eicar-Test-Signature FOUND\x00")
+					return
+				}
+				io.WriteString(conn, "stream: OK\x00")
+			}()
+		}
+	}()
+	return path
+}
+
+func TestClamd(t *testing.T) {
+	ctx := context.Background()
+	c := NewClamd("unix:" + fakeClamd(t))
+
+	v, err := c.Scan(ctx, strings.NewReader(strings.Repeat("x", 3*clamdChunkSize+7)))
+	require.NoError(t, err)
+	assert.False(t, v.Infected)
+
+	v, err = c.Scan(ctx, strings.NewReader(strings.Repeat("x", clamdChunkSize)+"EICAR"))
+	require.NoError(t, err)
+	assert.Equal(t, Verdict{Infected: true, Threat: "Eicar-Test-Signature"}, v)
+
+	_, err = NewClamd(filepath.Join(t.TempDir(), "missing.sock")).Scan(ctx, strings.NewReader("x"))
+	assert.ErrorContains(t, err, "connecting to clamd")
+}
+
+func TestParseClamdReply(t *testing.T) {
+	v, err := parseClamdReply("stream: OK\x00")
+	require.NoError(t, err)
+	assert.False(t, v.Infected)
+	_, err = parseClamdReply("INSTREAM size limit exceeded. ERROR\x00")
+	assert.ErrorContains(t, err, "size limit exceeded")
+}
+
+func TestCommand(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "scan.sh")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+if grep -q EICAR; then echo "stdin: Eicar-Test-Signature FOUND"; exit 1; fi
+if [ "$1" = "--broken" ]; then echo "database missing" >&2; exit 2; fi
+echo "stdin: OK"
+`), 0755))
+	ctx := context.Background()
+
+	v, err := NewCommand(script).Scan(ctx, strings.NewReader("hello"))
+	require.NoError(t, err)
+	assert.False(t, v.Infected)
+
+	v, err = NewCommand(script).Scan(ctx, strings.NewReader("EICAR"))
+	require.NoError(t, err)
+	assert.Equal(t, Verdict{Infected: true, Threat: "Eicar-Test-Signature"}, v)
+
+	_, err = NewCommand(script+" --broken").Scan(ctx, strings.NewReader("hello"))
+	assert.ErrorContains(t, err, "database missing")
+}
+
+func TestFromEnv(t *testing.T) {
+	env := map[string]string{}
+	getenv := func(k string) string { return env[k] }
+
+	s, err := FromEnv(getenv)
+	require.NoError(t, err)
+	assert.Nil(t, s)
+
+	env["MEDIA_SCAN_CLAMD"] = "127.0.0.1:3310"
+	s, err = FromEnv(getenv)
+	require.NoError(t, err)
+	assert.Equal(t, &Clamd{network: "tcp", address: "127.0.0.1:3310"}, s)
+
+	env["MEDIA_SCAN_COMMAND"] = "clamdscan --no-summary -"
+	_, err = FromEnv(getenv)
+	assert.ErrorContains(t, err, "not both")
+
+	delete(env, "MEDIA_SCAN_CLAMD")
+	s, err = FromEnv(getenv)
+	require.NoError(t, err)
+	assert.Equal(t, &Command{name: "clamdscan", args: []string{"--no-summary", "-"}}, s)
+}
@@ -0,0 +1,101 @@
+package avscan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// clamdChunkSize is the size of the chunks content is streamed to clamd in,
+// well below its default StreamMaxLength.
+const clamdChunkSize = 64 << 10
+
+// Clamd scans content with a clamd daemon through its INSTREAM command.
+type Clamd struct {
+	network string
+	address string
+}
+
+// NewClamd returns a scanner talking to clamd at addr: a Unix socket path,
+// optionally prefixed with "unix:", or HOST:PORT.
+func NewClamd(addr string) *Clamd {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		return &Clamd{network: "unix", address: path}
+	}
+	if strings.HasPrefix(addr, "/") {
+		return &Clamd{network: "unix", address: addr}
+	}
+	return &Clamd{network: "tcp", address: strings.TrimPrefix(addr, "tcp://")}
+}
+
+func (c *Clamd) Scan(ctx context.Context, r io.Reader) (Verdict, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("connecting to clamd: %w", err)
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := clamdStream(conn, r); err != nil {
+		if ctx.Err() != nil {
+			return Verdict{}, ctx.Err()
+		}
+		return Verdict{}, fmt.Errorf("streaming to clamd: %w", err)
+	}
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		if ctx.Err() != nil {
+			return Verdict{}, ctx.Err()
+		}
+		return Verdict{}, fmt.Errorf("reading clamd reply: %w", err)
+	}
+	return parseClamdReply(reply)
+}
+
+// clamdStream sends r to clamd as INSTREAM chunks, each prefixed with its
+// length, ended by an empty chunk.
+func clamdStream(w io.Writer, r io.Reader) error {
+	if _, err := io.WriteString(w, "zINSTREAM\x00"); err != nil {
+		return err
+	}
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, err := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, werr := w.Write(buf[:4+n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := w.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// parseClamdReply reads clamd's "stream: OK" or "stream: NAME FOUND".
+func parseClamdReply(reply string) (Verdict, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	result := reply
+	if i := strings.Index(reply, ": "); i >= 0 {
+		result = reply[i+2:]
+	}
+	if result == "OK" {
+		return Verdict{}, nil
+	}
+	if name, ok := strings.CutSuffix(result, " FOUND"); ok {
+		return Verdict{Infected: true, Threat: name}, nil
+	}
+	return Verdict{}, fmt.Errorf("clamd: %s", reply)
+}
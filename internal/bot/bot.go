@@ -0,0 +1,200 @@
+// Package bot routes inbound chat commands such as "!ping" to handlers and
+// returns the reply to send back to the chat.
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/pkg/webhookverify"
+)
+
+// Request is a parsed command message.
+type Request struct {
+	ChatJID   string   `json:"chat_jid"`
+	Sender    string   `json:"sender"`
+	MessageID string   `json:"message_id"`
+	Text      string   `json:"text"`
+	Command   string   `json:"command"`
+	Args      []string `json:"args"`
+}
+
+// Handler answers a command. An empty reply sends nothing.
+type Handler interface {
+	Handle(ctx context.Context, req Request) (string, error)
+}
+
+// HandlerFunc adapts a function to Handler.
+type HandlerFunc func(ctx context.Context, req Request) (string, error)
+
+func (f HandlerFunc) Handle(ctx context.Context, req Request) (string, error) {
+	return f(ctx, req)
+}
+
+// Router parses messages starting with a prefix in allowed chats and
+// dispatches them to registered handlers.
+type Router struct {
+	prefix   string
+	allowAll bool
+	mu       sync.RWMutex // guards allowed
+	allowed  map[string]bool
+	handlers map[string]Handler
+	help     map[string]string
+}
+
+// NewRouter creates a router for messages starting with prefix. allowedChats
+// lists chat JIDs (or bare phone numbers) commands are accepted from; "*"
+// allows every chat.
+func NewRouter(prefix string, allowedChats []string) *Router {
+	r := &Router{
+		prefix:   prefix,
+		allowed:  make(map[string]bool),
+		handlers: make(map[string]Handler),
+		help:     make(map[string]string),
+	}
+	for _, c := range allowedChats {
+		if c == "*" {
+			r.allowAll = true
+			continue
+		}
+		r.allowed[jid.Normalize(c)] = true
+	}
+	return r
+}
+
+// Register adds a handler for a command name (without prefix, case-insensitive).
+func (r *Router) Register(name, description string, h Handler) {
+	name = strings.ToLower(name)
+	r.handlers[name] = h
+	r.help[name] = description
+}
+
+// Commands returns the registered commands with their descriptions, sorted by name.
+func (r *Router) Commands() [][2]string {
+	names := make([]string, 0, len(r.handlers))
+	for name := range r.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([][2]string, len(names))
+	for i, name := range names {
+		out[i] = [2]string{r.prefix + name, r.help[name]}
+	}
+	return out
+}
+
+// Allow accepts commands from a chat JID or phone number from now on.
+func (r *Router) Allow(chat string) {
+	r.mu.Lock()
+	r.allowed[jid.Normalize(chat)] = true
+	r.mu.Unlock()
+}
+
+// Disallow stops accepting commands from a chat JID or phone number. It
+// reports whether the chat was allowed.
+func (r *Router) Disallow(chat string) bool {
+	chat = jid.Normalize(chat)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ok := r.allowed[chat]
+	delete(r.allowed, chat)
+	return ok
+}
+
+// Parse turns a message into a Request if it is a command for a registered
+// handler in an allowed chat.
+func (r *Router) Parse(chatJID, text string) (Request, bool) {
+	r.mu.RLock()
+	allowed := r.allowAll || r.allowed[jid.Normalize(chatJID)]
+	r.mu.RUnlock()
+	if !allowed {
+		return Request{}, false
+	}
+	text = strings.TrimSpace(text)
+	if r.prefix == "" || !strings.HasPrefix(text, r.prefix) {
+		return Request{}, false
+	}
+	fields := strings.Fields(strings.TrimPrefix(text, r.prefix))
+	if len(fields) == 0 {
+		return Request{}, false
+	}
+	name := strings.ToLower(fields[0])
+	if _, ok := r.handlers[name]; !ok {
+		return Request{}, false
+	}
+	return Request{ChatJID: chatJID, Text: text, Command: name, Args: fields[1:]}, true
+}
+
+// Dispatch runs the handler for req and returns its reply.
+func (r *Router) Dispatch(ctx context.Context, req Request) (string, error) {
+	h, ok := r.handlers[req.Command]
+	if !ok {
+		return "", fmt.Errorf("unknown command %q", req.Command)
+	}
+	return h.Handle(ctx, req)
+}
+
+// maxReplyBytes caps the reply read from a webhook handler.
+const maxReplyBytes = 64 << 10
+
+// WebhookHandler forwards commands to an HTTP endpoint as a JSON POST of the
+// Request. The reply is the "reply" field of a JSON response, or the plain
+// text body otherwise.
+type WebhookHandler struct {
+	URL    string
+	Client *http.Client
+	// Secret, if set, signs requests; see package webhookverify.
+	Secret []byte
+}
+
+func (h WebhookHandler) Handle(ctx context.Context, req Request) (string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if len(h.Secret) > 0 {
+		webhookverify.SignRequest(httpReq, h.Secret, webhookverify.DeliveryID(body), time.Now(), body)
+	}
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxReplyBytes))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("command webhook returned status %d", resp.StatusCode)
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		var out struct {
+			Reply string `json:"reply"`
+		}
+		if err := json.Unmarshal(data, &out); err != nil {
+			return "", fmt.Errorf("invalid command webhook response: %w", err)
+		}
+		return out.Reply, nil
+	}
+	return strings.TrimSpace(string(data)), nil
+}
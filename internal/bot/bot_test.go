@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/pkg/webhookverify"
+)
+
+func echo(ctx context.Context, req Request) (string, error) {
+	return req.Command, nil
+}
+
+func TestRouterParse(t *testing.T) {
+	r := NewRouter("!", []string{"123@g.us", "4915112345678"})
+	r.Register("Ping", "", HandlerFunc(echo))
+
+	req, ok := r.Parse("123@g.us", "  !PING now please ")
+	require.True(t, ok)
+	assert.Equal(t, "ping", req.Command)
+	assert.Equal(t, []string{"now", "please"}, req.Args)
+
+	_, ok = r.Parse("4915112345678@s.whatsapp.net", "!ping")
+	assert.True(t, ok, "bare phone numbers are allowed as individual chats")
+
+	_, ok = r.Parse("999@g.us", "!ping")
+	assert.False(t, ok, "chat not allowed")
+	_, ok = r.Parse("123@g.us", "ping")
+	assert.False(t, ok, "no prefix")
+	_, ok = r.Parse("123@g.us", "!unknown")
+	assert.False(t, ok, "unregistered command")
+	_, ok = r.Parse("123@g.us", "!")
+	assert.False(t, ok, "empty command")
+}
+
+func TestRouterAllowAll(t *testing.T) {
+	r := NewRouter("/", []string{"*"})
+	r.Register("ping", "", HandlerFunc(echo))
+
+	req, ok := r.Parse("any@g.us", "/ping")
+	require.True(t, ok)
+	reply, err := r.Dispatch(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", reply)
+}
+
+func TestRouterAllowAtRuntime(t *testing.T) {
+	r := NewRouter("!", nil)
+	r.Register("ping", "", HandlerFunc(echo))
+
+	_, ok := r.Parse("4915112345678@s.whatsapp.net", "!ping")
+	assert.False(t, ok)
+	r.Allow("+49 151 12345678")
+	_, ok = r.Parse("4915112345678@s.whatsapp.net", "!ping")
+	assert.True(t, ok)
+
+	assert.True(t, r.Disallow("4915112345678"))
+	assert.False(t, r.Disallow("4915112345678"), "already disallowed")
+	_, ok = r.Parse("4915112345678@s.whatsapp.net", "!ping")
+	assert.False(t, ok)
+}
+
+func TestWebhookHandler(t *testing.T) {
+	var got Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		if got.Args[0] == "json" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"reply":"sunny"}`))
+			return
+		}
+		if got.Args[0] == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("  cloudy\n"))
+	}))
+	defer srv.Close()
+
+	h := WebhookHandler{URL: srv.URL}
+	reply, err := h.Handle(context.Background(), Request{ChatJID: "123@g.us", Command: "weather", Args: []string{"json"}})
+	require.NoError(t, err)
+	assert.Equal(t, "sunny", reply)
+	assert.Equal(t, "123@g.us", got.ChatJID)
+
+	reply, err = h.Handle(context.Background(), Request{Command: "weather", Args: []string{"text"}})
+	require.NoError(t, err)
+	assert.Equal(t, "cloudy", reply)
+
+	_, err = h.Handle(context.Background(), Request{Command: "weather", Args: []string{"fail"}})
+	assert.ErrorContains(t, err, "status 500")
+}
+
+func TestWebhookHandlerSigns(t *testing.T) {
+	secret := []byte("0123456789abcdef")
+	verifier := webhookverify.NewVerifier(secret)
+	srv := httptest.NewServer(verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("verified"))
+	})))
+	defer srv.Close()
+
+	reply, err := WebhookHandler{URL: srv.URL, Secret: secret}.Handle(context.Background(), Request{MessageID: "M1", Command: "weather"})
+	require.NoError(t, err)
+	assert.Equal(t, "verified", reply)
+
+	_, err = WebhookHandler{URL: srv.URL}.Handle(context.Background(), Request{MessageID: "M2", Command: "weather"})
+	assert.ErrorContains(t, err, "status 401")
+}
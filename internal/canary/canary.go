@@ -0,0 +1,156 @@
+// Package canary tracks an end-to-end self-test: a message sent to the
+// account's own chat that the phone must acknowledge in time.
+package canary
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults and bounds.
+const (
+	DefaultTimeout = 30 * time.Second
+	MinInterval    = time.Minute
+)
+
+// Prefix starts the text of every canary message, so it is recognised on
+// the phone.
+const Prefix = "whatsapp-cli canary "
+
+// maxIssued is how many canary message IDs are remembered to keep copies of
+// them out of the store.
+const maxIssued = 128
+
+// Status is the outcome of the checks so far.
+type Status struct {
+	// OK is whether the last check passed; true before the first one.
+	OK          bool       `json:"ok"`
+	LastCheck   *time.Time `json:"last_check,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	// LatencySeconds is how long the last successful round trip took.
+	LatencySeconds float64 `json:"latency_seconds"`
+	LastError      string  `json:"last_error,omitempty"`
+	Checks         int64   `json:"checks"`
+	Failures       int64   `json:"failures"`
+}
+
+// Canary matches receipts of canary messages to the checks waiting for them
+// and records the outcome of each check.
+type Canary struct {
+	Interval time.Duration
+	Timeout  time.Duration
+
+	mu      sync.Mutex
+	pending map[string]chan struct{} // by message ID
+	// issued are the IDs of the latest canary messages, oldest first
+	issued []string
+	status Status
+}
+
+// New returns a Canary checking every interval, failing checks that take
+// longer than timeout (DefaultTimeout if 0).
+func New(interval, timeout time.Duration) (*Canary, error) {
+	if interval < MinInterval {
+		return nil, fmt.Errorf("canary interval must be at least %s", MinInterval)
+	}
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	if timeout <= 0 || timeout >= interval {
+		return nil, fmt.Errorf("canary timeout must be positive and shorter than its interval")
+	}
+	return &Canary{
+		Interval: interval,
+		Timeout:  timeout,
+		pending:  make(map[string]chan struct{}),
+		status:   Status{OK: true},
+	}, nil
+}
+
+// Begin starts a check. It returns the ID and text of the message to send,
+// a channel closed when a receipt for it arrives, and a function to call
+// once the check is over.
+func (c *Canary) Begin() (id, message string, acked <-chan struct{}, end func()) {
+	// Shaped like the IDs WhatsApp clients generate
+	b := make([]byte, 9)
+	rand.Read(b)
+	id = "3EB0" + strings.ToUpper(hex.EncodeToString(b))
+	ch := make(chan struct{})
+	c.mu.Lock()
+	c.pending[id] = ch
+	c.issued = append(c.issued, id)
+	if len(c.issued) > maxIssued {
+		c.issued = c.issued[len(c.issued)-maxIssued:]
+	}
+	c.mu.Unlock()
+	return id, Prefix + id, ch, func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}
+}
+
+// Acknowledge completes the checks waiting for any of ids, which a receipt
+// acknowledged. A nil Canary ignores it.
+func (c *Canary) Acknowledge(ids ...string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range ids {
+		if ch, ok := c.pending[id]; ok {
+			close(ch)
+			delete(c.pending, id)
+		}
+	}
+}
+
+// Issued reports whether id is one of the latest canary messages. A nil
+// Canary issued none.
+func (c *Canary) Issued(id string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, issued := range c.issued {
+		if issued == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Record stores the outcome of a check that ran from start to end. It
+// reports whether the canary's state changed, from passing to failing or
+// back.
+func (c *Canary) Record(start, end time.Time, err error) (changed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status.Checks++
+	c.status.LastCheck = &end
+	changed = c.status.OK != (err == nil)
+	if err != nil {
+		c.status.OK = false
+		c.status.Failures++
+		c.status.LastError = err.Error()
+		return changed
+	}
+	c.status.OK = true
+	c.status.LastSuccess = &end
+	c.status.LatencySeconds = end.Sub(start).Seconds()
+	c.status.LastError = ""
+	return changed
+}
+
+// Status returns a copy of the outcome so far.
+func (c *Canary) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status
+}
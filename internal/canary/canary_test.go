@@ -0,0 +1,91 @@
+package canary
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	c, err := New(5*time.Minute, 0)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultTimeout, c.Timeout)
+	assert.True(t, c.Status().OK)
+
+	_, err = New(30*time.Second, 0)
+	assert.ErrorContains(t, err, "interval")
+	_, err = New(time.Minute, time.Minute)
+	assert.ErrorContains(t, err, "timeout")
+}
+
+func TestAcknowledgeCompletesPendingCheck(t *testing.T) {
+	c, err := New(time.Minute, time.Second)
+	require.NoError(t, err)
+
+	id, message, acked, end := c.Begin()
+	defer end()
+	assert.Equal(t, Prefix+id, message)
+	assert.True(t, c.Issued(id))
+
+	c.Acknowledge("3EB0OTHER")
+	select {
+	case <-acked:
+		t.Fatal("completed by another message")
+	default:
+	}
+
+	c.Acknowledge("3EB0OTHER", id)
+	select {
+	case <-acked:
+	default:
+		t.Fatal("not completed")
+	}
+	// A duplicate receipt is ignored, and the ID still recognised
+	c.Acknowledge(id)
+	assert.True(t, c.Issued(id))
+
+	// Only IDs the canary issued are its messages, whatever their text
+	assert.False(t, c.Issued("3EB0OTHER"))
+
+	var none *Canary
+	none.Acknowledge(id)
+	assert.False(t, none.Issued(id))
+}
+
+func TestIssuedIsBounded(t *testing.T) {
+	c, err := New(time.Minute, time.Second)
+	require.NoError(t, err)
+	first, _, _, end := c.Begin()
+	end()
+	for i := 0; i < maxIssued; i++ {
+		_, _, _, end := c.Begin()
+		end()
+	}
+	assert.False(t, c.Issued(first))
+}
+
+func TestRecord(t *testing.T) {
+	c, err := New(time.Minute, time.Second)
+	require.NoError(t, err)
+	start := time.Now()
+
+	assert.False(t, c.Record(start, start.Add(800*time.Millisecond), nil))
+	status := c.Status()
+	assert.True(t, status.OK)
+	assert.InDelta(t, 0.8, status.LatencySeconds, 0.001)
+	require.NotNil(t, status.LastSuccess)
+
+	assert.True(t, c.Record(start, start.Add(time.Second), errors.New("timed out")))
+	assert.False(t, c.Record(start, start.Add(time.Second), errors.New("timed out")))
+	status = c.Status()
+	assert.False(t, status.OK)
+	assert.Equal(t, "timed out", status.LastError)
+	assert.Equal(t, int64(3), status.Checks)
+	assert.Equal(t, int64(2), status.Failures)
+
+	assert.True(t, c.Record(start, start.Add(time.Second), nil))
+	assert.Empty(t, c.Status().LastError)
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mau.fi/whatsmeow/appstate"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// KnownChat is a chat the phone knows of, whether or not it has messages:
+// a group the account is in, or a contact saved in its address book.
+type KnownChat struct {
+	JID  string
+	Name string
+}
+
+// KnownChats lists the groups the account is in and the contacts in its
+// address book, refreshing the contact list from app state first.
+func (w *WAClient) KnownChats(ctx context.Context) ([]KnownChat, error) {
+	if !w.client.IsConnected() {
+		return nil, fmt.Errorf("not connected to WhatsApp")
+	}
+	if err := w.client.FetchAppState(ctx, appstate.WAPatchCriticalUnblockLow, false, false); err != nil {
+		return nil, fmt.Errorf("failed to fetch contacts: %w", err)
+	}
+	groups, err := w.client.GetJoinedGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+	contacts, err := w.client.Store.Contacts.GetAllContacts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contacts: %w", err)
+	}
+
+	chats := make([]KnownChat, 0, len(groups)+len(contacts))
+	for _, g := range groups {
+		chats = append(chats, KnownChat{JID: g.JID.String(), Name: strings.TrimSpace(g.GroupName.Name)})
+	}
+	for jid, info := range contacts {
+		// Push names alone are people who wrote in a group, not contacts
+		name := strings.TrimSpace(info.FullName)
+		if name == "" {
+			name = strings.TrimSpace(info.FirstName)
+		}
+		if jid.Server != types.DefaultUserServer || name == "" {
+			continue
+		}
+		chats = append(chats, KnownChat{JID: jid.ToNonAD().String(), Name: name})
+	}
+	sort.Slice(chats, func(i, j int) bool { return chats[i].JID < chats[j].JID })
+	return chats, nil
+}
@@ -12,17 +12,77 @@ import (
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/mdp/qrterminal"
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/keychain"
+	"github.com/vicentereig/whatsapp-cli/internal/logging"
 	"go.mau.fi/whatsmeow"
 	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/store"
 	"go.mau.fi/whatsmeow/store/sqlstore"
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
-	waLog "go.mau.fi/whatsmeow/util/log"
 	"google.golang.org/protobuf/proto"
 )
 
+// Client is the WhatsApp connection used by the application layer. WAClient
+// implements it on top of whatsmeow; fakeclient provides a scripted stand-in
+// so the app and HTTP API can be exercised without a live account.
+type Client interface {
+	IsAuthenticated() bool
+	IsConnected() bool
+	// OwnJID is the account's phone JID, empty before pairing.
+	OwnJID() string
+	GetQRChannel(ctx context.Context) (<-chan whatsmeow.QRChannelItem, error)
+	Authenticate(ctx context.Context) error
+	Connect(ctx context.Context) error
+	Disconnect()
+	// SendMessage returns the ID of the message the text was sent as.
+	SendMessage(ctx context.Context, recipient, message string) (string, error)
+	// SendExtendedText sends message with what it says about other messages
+	// and users: the message it quotes and the users it mentions. It returns
+	// the ID the message was sent as.
+	SendExtendedText(ctx context.Context, recipient, message string, ext TextContext) (string, error)
+	// SendMedia returns the ID of the message the attachment was sent as.
+	SendMedia(ctx context.Context, recipient string, media OutgoingMedia) (string, error)
+	SendTyping(ctx context.Context, recipient string, typing bool) error
+	// SendPresence shows the account as online to its contacts, or stops.
+	SendPresence(ctx context.Context, available bool) error
+	// RevokeMessage deletes a message the account sent for everyone in
+	// the chat.
+	RevokeMessage(ctx context.Context, chatJID, messageID string) error
+	ResolveChatName(ctx context.Context, chatJID string, msg *events.Message) string
+	PhoneJID(ctx context.Context, jid string) string
+	SetActiveDeliveryReceipts(active bool)
+	MarkRead(ctx context.Context, chatJID, sender string, ids []string, ts time.Time) error
+	StartSync(ctx context.Context, eventHandler func(interface{})) error
+	DownloadMediaToFile(ctx context.Context, req MediaDownloadRequest, targetPath string) (int64, error)
+	UpdateGroup(ctx context.Context, groupJID string, upd GroupUpdate) (GroupSettings, error)
+	CreateGroup(ctx context.Context, subject string, participants []string) (GroupSettings, []GroupParticipantResult, error)
+	GetGroupJoinRequests(ctx context.Context, groupJID string) ([]GroupJoinRequest, error)
+	UpdateGroupJoinRequests(ctx context.Context, groupJID string, participants []string, approve bool) ([]GroupParticipantResult, error)
+	// UpdateGroupParticipants applies one of the Participant* changes.
+	UpdateGroupParticipants(ctx context.Context, groupJID string, participants []string, action string) ([]GroupParticipantResult, error)
+	// GroupParticipants lists the members of a group by phone JID where
+	// WhatsApp reveals it.
+	GroupParticipants(ctx context.Context, groupJID string) ([]string, error)
+	// SendPoll returns the ID of the message the poll was sent as.
+	SendPoll(ctx context.Context, recipient string, poll Poll) (string, error)
+	// DecryptPollVote reads the vote of a poll update message.
+	DecryptPollVote(ctx context.Context, msg *events.Message) (PollVote, error)
+	Identity(ctx context.Context, contact string) (Identity, error)
+	// KnownChats lists the chats the phone knows of, including those
+	// without recent messages.
+	KnownChats(ctx context.Context) ([]KnownChat, error)
+	// Ping sends a keepalive query to WhatsApp's servers and returns how
+	// long the answer took.
+	Ping(ctx context.Context) (time.Duration, error)
+}
+
+var _ Client = (*WAClient)(nil)
+
 type WAClient struct {
 	client          *whatsmeow.Client
+	db              *sql.DB // whatsapp.db
 	storeDir        string
 	eventHandler    func(interface{})
 	contactLookup   func(ctx context.Context, user types.JID) (types.ContactInfo, error)
@@ -50,6 +110,83 @@ type MessageDetails struct {
 	Timestamp time.Time
 	IsFromMe  bool
 	Media     *MediaInfo
+	// SenderDevice is the device the message was sent from, 0 being the
+	// sender's phone.
+	SenderDevice uint16
+	// ForwardingScore counts how many times the message has been forwarded;
+	// WhatsApp labels it "forwarded many times" from 5 on.
+	ForwardingScore uint32
+	// ChatLID is the @lid JID the chat was addressed by when ChatJID has been
+	// replaced by the phone-number JID carried alongside it.
+	ChatLID string
+	// Poll is the poll the message creates; its question is the content.
+	Poll *Poll
+}
+
+// Quote is the message a reply quotes.
+type Quote struct {
+	MessageID string
+	// Sender is the JID of the quoted message's author.
+	Sender string
+	// Content is the text shown in the quote.
+	Content string
+}
+
+// TextContext is the context a text message is sent with.
+type TextContext struct {
+	// Quote makes the message a reply.
+	Quote *Quote
+	// Mentions are the JIDs of the users the message mentions, which the
+	// text names as "@" followed by their user part.
+	Mentions []string
+	// ID sends the message under this ID instead of a generated one.
+	ID string
+}
+
+// SendOptions shape how a text message is sent.
+type SendOptions struct {
+	// QuotedMessageID makes the message a reply to a message of the chat.
+	QuotedMessageID string
+	// Mentions are the group members the message mentions, as phone
+	// numbers or JIDs.
+	Mentions []string
+	// SimulateTyping overrides whether the typing indicator is shown
+	// before the message; nil keeps the default.
+	SimulateTyping *bool
+}
+
+// OutgoingMedia is an attachment to send.
+type OutgoingMedia struct {
+	// Type is "image", "video", "audio", "document", "sticker" or "voice";
+	// see MediaTypeForMIME.
+	Type     string
+	Data     []byte
+	MimeType string
+	// Filename is shown for documents.
+	Filename string
+	// Caption is sent with images, videos and documents.
+	Caption string
+	// Seconds and Waveform describe voice notes: their duration and the
+	// amplitudes, from 0 to 100, the client draws.
+	Seconds  uint32
+	Waveform []byte
+}
+
+// MediaTypeForMIME returns how an attachment of the given MIME type is
+// sent: the image, video and audio formats WhatsApp plays inline as such,
+// anything else as a document.
+func MediaTypeForMIME(mimeType string) string {
+	mimeType, _, _ = strings.Cut(strings.ToLower(mimeType), ";")
+	switch strings.TrimSpace(mimeType) {
+	case "image/jpeg", "image/png":
+		return "image"
+	case "video/mp4", "video/3gpp":
+		return "video"
+	case "audio/aac", "audio/mp4", "audio/mpeg", "audio/amr", "audio/ogg":
+		return "audio"
+	default:
+		return "document"
+	}
 }
 
 type MediaDownloadRequest struct {
@@ -62,33 +199,56 @@ type MediaDownloadRequest struct {
 	MimeType      string
 }
 
-func NewWAClient(storeDir string) (*WAClient, error) {
+// NewWAClient opens the whatsmeow session in storeDir. credentialBackend
+// selects where the device's private keys live: CredentialBackendFile (or "")
+// keeps them in whatsapp.db, CredentialBackendKeychain in the OS keychain.
+func NewWAClient(storeDir, credentialBackend string) (*WAClient, error) {
 	// Create store directory
 	if err := os.MkdirAll(storeDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create store directory: %v", err)
 	}
 
-	dbLog := waLog.Stdout("Database", "ERROR", true)
+	dbLog := logging.WhatsMeow(logging.SubsystemStore, "Database")
 	ctx := context.Background()
-	container, err := sqlstore.New(ctx, "sqlite3", fmt.Sprintf("file:%s/whatsapp.db?_foreign_keys=on", storeDir), dbLog)
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s/whatsapp.db?_foreign_keys=on", storeDir))
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %v", err)
 	}
+	container := sqlstore.NewWithDB(db, "sqlite3", dbLog)
+	if err := container.Upgrade(ctx); err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
 
-	deviceStore, err := container.GetFirstDevice(ctx)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			deviceStore = container.NewDevice()
-		} else {
-			return nil, fmt.Errorf("failed to get device: %v", err)
+	var deviceStore *store.Device
+	switch credentialBackend {
+	case "", CredentialBackendFile:
+		deviceStore, err = container.GetFirstDevice(ctx)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				deviceStore = container.NewDevice()
+			} else {
+				return nil, fmt.Errorf("failed to get device: %v", err)
+			}
+		}
+	case CredentialBackendKeychain:
+		kc, err := keychain.Open(keychainService)
+		if err != nil {
+			return nil, fmt.Errorf("credential backend %q: %w", credentialBackend, err)
 		}
+		kcc := &keychainContainer{Container: container, db: db, kc: kc}
+		if deviceStore, err = kcc.loadDevice(ctx); err != nil {
+			return nil, fmt.Errorf("failed to get device: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown credential backend %q (expected %q or %q)", credentialBackend, CredentialBackendFile, CredentialBackendKeychain)
 	}
 
-	logger := waLog.Stdout("Client", "ERROR", true)
+	logger := logging.WhatsMeow(logging.SubsystemWhatsApp, "Client")
 	client := whatsmeow.NewClient(deviceStore, logger)
 
 	return &WAClient{
 		client:          client,
+		db:              db,
 		storeDir:        storeDir,
 		contactLookup:   contactLookupFunc(client),
 		groupInfoLookup: groupInfoLookupFunc(client),
@@ -103,6 +263,27 @@ func (w *WAClient) IsConnected() bool {
 	return w.client.IsConnected()
 }
 
+// Ping uses whatsmeow's keepalive query, the lightest request WhatsApp
+// answers, which whatsmeow only exposes through its internals. It gives up
+// after whatsmeow.KeepAliveResponseDeadline.
+func (w *WAClient) Ping(ctx context.Context) (time.Duration, error) {
+	if !w.client.IsConnected() {
+		return 0, fmt.Errorf("not connected to WhatsApp")
+	}
+	start := time.Now()
+	if ok, _ := w.client.DangerousInternals().SendKeepAlive(ctx); !ok {
+		return 0, fmt.Errorf("ping went unanswered")
+	}
+	return time.Since(start), nil
+}
+
+func (w *WAClient) OwnJID() string {
+	if w.client.Store.ID == nil {
+		return ""
+	}
+	return w.client.Store.ID.ToNonAD().String()
+}
+
 // GetQRChannel returns a channel that receives QR code events for authentication.
 // The caller is responsible for reading from the channel and calling Connect() has
 // already been triggered internally. This is used by the API server for HTTP-based
@@ -150,6 +331,9 @@ func (w *WAClient) Connect(ctx context.Context) error {
 	if w.client.IsConnected() {
 		return nil
 	}
+	if err := checkSessionFence(w.storeDir); err != nil {
+		return err
+	}
 
 	if err := w.client.Connect(); err != nil {
 		return fmt.Errorf("failed to connect: %v", err)
@@ -164,24 +348,182 @@ func (w *WAClient) Disconnect() {
 	}
 }
 
-func (w *WAClient) SendMessage(ctx context.Context, recipient, message string) error {
+func (w *WAClient) SendMessage(ctx context.Context, recipient, message string) (string, error) {
 	if !w.client.IsConnected() {
-		return fmt.Errorf("not connected to WhatsApp")
+		return "", fmt.Errorf("not connected to WhatsApp")
 	}
 
 	recipientJID, err := parseJID(recipient)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	msg := &waProto.Message{
 		Conversation: proto.String(message),
 	}
 
-	_, err = w.client.SendMessage(ctx, recipientJID, msg)
+	resp, err := w.client.SendMessage(ctx, recipientJID, msg)
+	if err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+func (w *WAClient) SendExtendedText(ctx context.Context, recipient, message string, ext TextContext) (string, error) {
+	if !w.client.IsConnected() {
+		return "", fmt.Errorf("not connected to WhatsApp")
+	}
+	recipientJID, err := parseJID(recipient)
+	if err != nil {
+		return "", err
+	}
+
+	info := &waProto.ContextInfo{MentionedJID: ext.Mentions}
+	if q := ext.Quote; q != nil {
+		info.StanzaID = proto.String(q.MessageID)
+		info.Participant = proto.String(q.Sender)
+		info.QuotedMessage = &waProto.Message{Conversation: proto.String(q.Content)}
+	}
+	msg := &waProto.Message{
+		ExtendedTextMessage: &waProto.ExtendedTextMessage{
+			Text:        proto.String(message),
+			ContextInfo: info,
+		},
+	}
+
+	resp, err := w.client.SendMessage(ctx, recipientJID, msg, whatsmeow.SendRequestExtra{ID: types.MessageID(ext.ID)})
+	if err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+func (w *WAClient) RevokeMessage(ctx context.Context, chatJID, messageID string) error {
+	if !w.client.IsConnected() {
+		return fmt.Errorf("not connected to WhatsApp")
+	}
+	chat, err := parseJID(chatJID)
+	if err != nil {
+		return err
+	}
+	_, err = w.client.SendMessage(ctx, chat, w.client.BuildRevoke(chat, types.EmptyJID, messageID))
 	return err
 }
 
+// SendMedia uploads an attachment and sends it. Channels get it unencrypted,
+// as WhatsApp requires.
+func (w *WAClient) SendMedia(ctx context.Context, recipient string, media OutgoingMedia) (string, error) {
+	if !w.client.IsConnected() {
+		return "", fmt.Errorf("not connected to WhatsApp")
+	}
+	recipientJID, err := parseJID(recipient)
+	if err != nil {
+		return "", err
+	}
+	mediaType, err := mediaTypeFromString(media.Type)
+	if err != nil {
+		return "", err
+	}
+
+	newsletter := recipientJID.Server == types.NewsletterServer
+	var up whatsmeow.UploadResponse
+	if newsletter {
+		up, err = w.client.UploadNewsletter(ctx, media.Data, mediaType)
+	} else {
+		up, err = w.client.Upload(ctx, media.Data, mediaType)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to upload media: %w", err)
+	}
+
+	msg := &waProto.Message{}
+	switch {
+	case media.Type == "sticker":
+		// Stickers upload as images; WhatsApp takes them as 512x512 WebP
+		msg.StickerMessage = &waProto.StickerMessage{
+			Mimetype: proto.String(media.MimeType), Width: proto.Uint32(512), Height: proto.Uint32(512),
+			URL: &up.URL, DirectPath: &up.DirectPath, MediaKey: up.MediaKey,
+			FileSHA256: up.FileSHA256, FileEncSHA256: up.FileEncSHA256, FileLength: &up.FileLength,
+		}
+	case media.Type == "voice":
+		// PTT ("push to talk") makes it a voice note rather than a file
+		msg.AudioMessage = &waProto.AudioMessage{
+			Mimetype: proto.String(media.MimeType), PTT: proto.Bool(true),
+			Seconds: proto.Uint32(media.Seconds), Waveform: media.Waveform,
+			URL: &up.URL, DirectPath: &up.DirectPath, MediaKey: up.MediaKey,
+			FileSHA256: up.FileSHA256, FileEncSHA256: up.FileEncSHA256, FileLength: &up.FileLength,
+		}
+	case mediaType == whatsmeow.MediaImage:
+		msg.ImageMessage = &waProto.ImageMessage{
+			Caption: optionalString(media.Caption), Mimetype: proto.String(media.MimeType),
+			URL: &up.URL, DirectPath: &up.DirectPath, MediaKey: up.MediaKey,
+			FileSHA256: up.FileSHA256, FileEncSHA256: up.FileEncSHA256, FileLength: &up.FileLength,
+		}
+	case mediaType == whatsmeow.MediaVideo:
+		msg.VideoMessage = &waProto.VideoMessage{
+			Caption: optionalString(media.Caption), Mimetype: proto.String(media.MimeType),
+			URL: &up.URL, DirectPath: &up.DirectPath, MediaKey: up.MediaKey,
+			FileSHA256: up.FileSHA256, FileEncSHA256: up.FileEncSHA256, FileLength: &up.FileLength,
+		}
+	case mediaType == whatsmeow.MediaAudio:
+		msg.AudioMessage = &waProto.AudioMessage{
+			Mimetype: proto.String(media.MimeType),
+			URL:      &up.URL, DirectPath: &up.DirectPath, MediaKey: up.MediaKey,
+			FileSHA256: up.FileSHA256, FileEncSHA256: up.FileEncSHA256, FileLength: &up.FileLength,
+		}
+	default:
+		msg.DocumentMessage = &waProto.DocumentMessage{
+			Caption: optionalString(media.Caption), Mimetype: proto.String(media.MimeType),
+			FileName: optionalString(media.Filename), Title: optionalString(media.Filename),
+			URL: &up.URL, DirectPath: &up.DirectPath, MediaKey: up.MediaKey,
+			FileSHA256: up.FileSHA256, FileEncSHA256: up.FileEncSHA256, FileLength: &up.FileLength,
+		}
+	}
+
+	var extra []whatsmeow.SendRequestExtra
+	if newsletter {
+		extra = append(extra, whatsmeow.SendRequestExtra{MediaHandle: up.Handle})
+	}
+	resp, err := w.client.SendMessage(ctx, recipientJID, msg, extra...)
+	if err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return proto.String(s)
+}
+
+// SendTyping shows or clears the "typing…" indicator in the recipient's chat.
+func (w *WAClient) SendTyping(ctx context.Context, recipient string, typing bool) error {
+	recipientJID, err := parseJID(recipient)
+	if err != nil {
+		return err
+	}
+	state := types.ChatPresencePaused
+	if typing {
+		state = types.ChatPresenceComposing
+	}
+	return w.client.SendChatPresence(ctx, recipientJID, state, types.ChatPresenceMediaText)
+}
+
+// SendPresence marks the account available ("online") or unavailable.
+// WhatsApp only delivers it once the account has a push name.
+func (w *WAClient) SendPresence(ctx context.Context, available bool) error {
+	if !w.client.IsConnected() {
+		return fmt.Errorf("not connected to WhatsApp")
+	}
+	state := types.PresenceUnavailable
+	if available {
+		state = types.PresenceAvailable
+	}
+	return w.client.SendPresence(ctx, state)
+}
+
 func (w *WAClient) AddEventHandler(handler func(interface{})) {
 	w.client.AddEventHandler(handler)
 }
@@ -264,6 +606,43 @@ func (w *WAClient) ResolveChatName(ctx context.Context, chatJID string, msg *eve
 	return fallback
 }
 
+// PhoneJID returns the phone-number JID of a linked identifier (@lid) JID when
+// the mapping is known to the session, and jid unchanged otherwise.
+func (w *WAClient) PhoneJID(ctx context.Context, jid string) string {
+	parsed, err := types.ParseJID(jid)
+	if err != nil || parsed.Server != types.HiddenUserServer || w.client == nil || w.client.Store == nil || w.client.Store.LIDs == nil {
+		return jid
+	}
+	pn, err := w.client.Store.LIDs.GetPNForLID(ctx, parsed.ToNonAD())
+	if err != nil || pn.IsEmpty() {
+		return jid
+	}
+	return pn.ToNonAD().String()
+}
+
+// SetActiveDeliveryReceipts selects how incoming messages are acknowledged:
+// active receipts show senders the message as delivered, inactive ones (the
+// whatsmeow default while not marked online) are not rendered by WhatsApp.
+func (w *WAClient) SetActiveDeliveryReceipts(active bool) {
+	w.client.SetForceActiveDeliveryReceipts(active)
+}
+
+// MarkRead sends a read receipt for messages from sender in chatJID. The
+// sender is needed in group chats.
+func (w *WAClient) MarkRead(ctx context.Context, chatJID, sender string, ids []string, ts time.Time) error {
+	chat, err := types.ParseJID(chatJID)
+	if err != nil {
+		return err
+	}
+	var senderJID types.JID
+	if sender != "" {
+		if senderJID, err = types.ParseJID(sender); err != nil {
+			return err
+		}
+	}
+	return w.client.MarkRead(ctx, ids, ts, chat, senderJID)
+}
+
 // StartSync connects to WhatsApp and registers event handlers for syncing messages
 func (w *WAClient) StartSync(ctx context.Context, eventHandler func(interface{})) error {
 	// Add event handler before connecting
@@ -277,26 +656,13 @@ func (w *WAClient) StartSync(ctx context.Context, eventHandler func(interface{})
 	return nil
 }
 
+// parseJID validates a recipient, a phone number or JID, and parses it.
 func parseJID(recipient string) (types.JID, error) {
-	// If already a JID, parse it
-	if contains(recipient, "@") {
-		return types.ParseJID(recipient)
-	}
-
-	// Otherwise, assume it's a phone number
-	return types.JID{
-		User:   recipient,
-		Server: "s.whatsapp.net",
-	}, nil
-}
-
-func contains(s, substr string) bool {
-	for i := 0; i < len(s); i++ {
-		if s[i] == substr[0] {
-			return true
-		}
+	normalized, err := jid.ParseRecipient(recipient)
+	if err != nil {
+		return types.JID{}, err
 	}
-	return false
+	return types.ParseJID(normalized)
 }
 
 func (w *WAClient) DownloadMediaToFile(ctx context.Context, req MediaDownloadRequest, targetPath string) (int64, error) {
@@ -357,7 +723,7 @@ func mediaTypeFromString(mediaType string) (whatsmeow.MediaType, error) {
 		return whatsmeow.MediaImage, nil
 	case "video":
 		return whatsmeow.MediaVideo, nil
-	case "audio":
+	case "audio", "voice":
 		return whatsmeow.MediaAudio, nil
 	case "document":
 		return whatsmeow.MediaDocument, nil
@@ -371,6 +737,9 @@ func mediaTypeFromString(mediaType string) (whatsmeow.MediaType, error) {
 // Helper to handle incoming messages
 func HandleMessage(msg *events.Message) MessageDetails {
 	sender := msg.Info.Sender.User
+	if msg.Info.Sender.Server == types.HiddenUserServer && msg.Info.SenderAlt.Server == types.DefaultUserServer {
+		sender = msg.Info.SenderAlt.User
+	}
 	if sender == "" {
 		if s := msg.Info.Sender.String(); s != "" {
 			sender = s
@@ -378,20 +747,41 @@ func HandleMessage(msg *events.Message) MessageDetails {
 	}
 
 	details := MessageDetails{
-		ID:        msg.Info.ID,
-		ChatJID:   msg.Info.Chat.String(),
-		Sender:    sender,
-		Timestamp: msg.Info.Timestamp,
-		IsFromMe:  msg.Info.IsFromMe,
+		ID:           msg.Info.ID,
+		ChatJID:      msg.Info.Chat.String(),
+		Sender:       sender,
+		Timestamp:    msg.Info.Timestamp,
+		IsFromMe:     msg.Info.IsFromMe,
+		SenderDevice: msg.Info.Sender.Device,
+	}
+
+	// Direct chats with LID-addressed accounts carry the phone number of the
+	// other side as an alternative address; prefer it so the chat is stored
+	// under one identifier.
+	if msg.Info.Chat.Server == types.HiddenUserServer && !msg.Info.IsGroup {
+		alt := msg.Info.SenderAlt
+		if msg.Info.IsFromMe {
+			alt = msg.Info.RecipientAlt
+		}
+		if alt.Server == types.DefaultUserServer {
+			details.ChatLID = msg.Info.Chat.ToNonAD().String()
+			details.ChatJID = alt.ToNonAD().String()
+		}
 	}
 
 	if msg.Message != nil {
+		details.ForwardingScore = ForwardingScore(msg.Message)
+
 		switch {
 		case msg.Message.GetConversation() != "":
 			details.Content = msg.Message.GetConversation()
 		case msg.Message.GetExtendedTextMessage() != nil:
 			details.Content = msg.Message.GetExtendedTextMessage().GetText()
 		}
+		if poll := PollCreation(msg.Message); poll != nil {
+			details.Poll = poll
+			details.Content = poll.Question
+		}
 
 		if img := msg.Message.GetImageMessage(); img != nil {
 			if details.Content == "" {
@@ -464,6 +854,34 @@ func HandleMessage(msg *events.Message) MessageDetails {
 	return details
 }
 
+// ForwardingScore returns how many times a message has been forwarded, or 0
+// for messages that carry no context info.
+func ForwardingScore(m *waProto.Message) uint32 {
+	var ctx *waProto.ContextInfo
+	switch {
+	case m.GetExtendedTextMessage() != nil:
+		ctx = m.GetExtendedTextMessage().GetContextInfo()
+	case m.GetImageMessage() != nil:
+		ctx = m.GetImageMessage().GetContextInfo()
+	case m.GetVideoMessage() != nil:
+		ctx = m.GetVideoMessage().GetContextInfo()
+	case m.GetAudioMessage() != nil:
+		ctx = m.GetAudioMessage().GetContextInfo()
+	case m.GetDocumentMessage() != nil:
+		ctx = m.GetDocumentMessage().GetContextInfo()
+	}
+	if ctx == nil {
+		return 0
+	}
+	if score := ctx.GetForwardingScore(); score > 0 {
+		return score
+	}
+	if ctx.GetIsForwarded() {
+		return 1
+	}
+	return 0
+}
+
 func cloneBytes(b []byte) []byte {
 	if len(b) == 0 {
 		return nil
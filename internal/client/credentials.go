@@ -0,0 +1,175 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/vicentereig/whatsapp-cli/internal/keychain"
+	"go.mau.fi/whatsmeow/store"
+	"go.mau.fi/whatsmeow/store/sqlstore"
+	"go.mau.fi/whatsmeow/util/keys"
+)
+
+// Credential backends for the linked device's private keys.
+const (
+	// CredentialBackendFile keeps the keys in whatsapp.db (the default).
+	CredentialBackendFile = "file"
+	// CredentialBackendKeychain keeps the keys in the OS keychain and only
+	// zeroed placeholders in whatsapp.db.
+	CredentialBackendKeychain = "keychain"
+)
+
+// keychainService names the keychain items holding device keys.
+const keychainService = "whatsapp-cli"
+
+// deviceSecrets are the private keys that let a device act as the linked
+// WhatsApp session. Everything else in whatsapp.db is useless without them.
+type deviceSecrets struct {
+	NoiseKey     []byte `json:"noise_key"`
+	IdentityKey  []byte `json:"identity_key"`
+	SignedPreKey []byte `json:"signed_pre_key"`
+	AdvSecretKey []byte `json:"adv_secret_key"`
+}
+
+func secretsOf(device *store.Device) deviceSecrets {
+	return deviceSecrets{
+		NoiseKey:     device.NoiseKey.Priv[:],
+		IdentityKey:  device.IdentityKey.Priv[:],
+		SignedPreKey: device.SignedPreKey.Priv[:],
+		AdvSecretKey: device.AdvSecretKey,
+	}
+}
+
+func (s deviceSecrets) valid() bool {
+	return len(s.NoiseKey) == 32 && len(s.IdentityKey) == 32 && len(s.SignedPreKey) == 32
+}
+
+// apply installs the secrets into a device loaded with placeholder keys.
+func (s deviceSecrets) apply(device *store.Device) {
+	device.NoiseKey = keys.NewKeyPairFromPrivateKey(*(*[32]byte)(s.NoiseKey))
+	device.IdentityKey = keys.NewKeyPairFromPrivateKey(*(*[32]byte)(s.IdentityKey))
+	device.SignedPreKey.KeyPair = *keys.NewKeyPairFromPrivateKey(*(*[32]byte)(s.SignedPreKey))
+	device.AdvSecretKey = s.AdvSecretKey
+}
+
+// keychainContainer stores device private keys in the OS keychain. Rows in
+// whatsapp.db keep zeroed keys so the schema stays valid for sqlstore.
+type keychainContainer struct {
+	*sqlstore.Container
+	db *sql.DB
+	kc keychain.Keychain
+}
+
+// PutDevice saves the keys to the keychain, then the device with its private
+// keys zeroed to whatsapp.db.
+func (c *keychainContainer) PutDevice(ctx context.Context, device *store.Device) error {
+	if device.ID == nil {
+		return sqlstore.ErrDeviceIDMustBeSet
+	}
+	secrets := secretsOf(device)
+	if err := c.saveSecrets(device, secrets); err != nil {
+		return err
+	}
+
+	var zero [32]byte
+	scrubbed := *device
+	scrubbed.NoiseKey = keys.NewKeyPairFromPrivateKey(zero)
+	scrubbed.IdentityKey = keys.NewKeyPairFromPrivateKey(zero)
+	scrubbed.SignedPreKey = &keys.PreKey{
+		KeyPair:   *keys.NewKeyPairFromPrivateKey(zero),
+		KeyID:     device.SignedPreKey.KeyID,
+		Signature: device.SignedPreKey.Signature,
+	}
+	scrubbed.AdvSecretKey = make([]byte, 32)
+	if err := c.Container.PutDevice(ctx, &scrubbed); err != nil {
+		return err
+	}
+
+	// Keep the stores sqlstore attached to the copy, but the real keys
+	*device = scrubbed
+	secrets.apply(device)
+	device.Container = c
+	return nil
+}
+
+// DeleteDevice removes the device from whatsapp.db and the keychain.
+func (c *keychainContainer) DeleteDevice(ctx context.Context, device *store.Device) error {
+	if err := c.Container.DeleteDevice(ctx, device); err != nil {
+		return err
+	}
+	return c.kc.Delete(device.ID.String())
+}
+
+func (c *keychainContainer) saveSecrets(device *store.Device, secrets deviceSecrets) error {
+	blob, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	if err := c.kc.Set(device.ID.String(), blob); err != nil {
+		return fmt.Errorf("failed to store device keys in keychain: %w", err)
+	}
+	return nil
+}
+
+// loadDevice returns the first device with its keys restored from the
+// keychain. A device paired with the file backend has its keys moved into
+// the keychain and zeroed in whatsapp.db.
+func (c *keychainContainer) loadDevice(ctx context.Context) (*store.Device, error) {
+	device, err := c.Container.GetFirstDevice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	device.Container = c
+	if device.ID == nil {
+		return device, nil
+	}
+
+	blob, err := c.kc.Get(device.ID.String())
+	switch {
+	case errors.Is(err, keychain.ErrNotFound):
+		secrets := secretsOf(device)
+		if isZero(secrets.NoiseKey) {
+			return nil, fmt.Errorf("device keys for %s are missing from the keychain; re-run auth", device.ID)
+		}
+		if err := c.saveSecrets(device, secrets); err != nil {
+			return nil, err
+		}
+		if err := c.scrub(ctx, device); err != nil {
+			return nil, err
+		}
+		return device, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read device keys from keychain: %w", err)
+	}
+
+	var secrets deviceSecrets
+	if err := json.Unmarshal(blob, &secrets); err != nil || !secrets.valid() {
+		return nil, fmt.Errorf("invalid device keys in keychain for %s", device.ID)
+	}
+	secrets.apply(device)
+	return device, nil
+}
+
+// scrub zeroes the private keys of a device row in whatsapp.db.
+func (c *keychainContainer) scrub(ctx context.Context, device *store.Device) error {
+	_, err := c.db.ExecContext(ctx,
+		`UPDATE whatsmeow_device SET noise_key = zeroblob(32), identity_key = zeroblob(32),
+			signed_pre_key = zeroblob(32), adv_key = zeroblob(32) WHERE jid = ?`,
+		device.ID.String())
+	if err != nil {
+		return fmt.Errorf("failed to remove device keys from whatsapp.db: %w", err)
+	}
+	return nil
+}
+
+func isZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/keychain"
+	"go.mau.fi/whatsmeow/proto/waAdv"
+	"go.mau.fi/whatsmeow/store/sqlstore"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func openTestContainer(t *testing.T, dbPath string) (*sqlstore.Container, *sql.DB) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on", dbPath))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	container := sqlstore.NewWithDB(db, "sqlite3", nil)
+	require.NoError(t, container.Upgrade(context.Background()))
+	return container, db
+}
+
+func storedNoiseKey(t *testing.T, db *sql.DB) []byte {
+	t.Helper()
+	var key []byte
+	require.NoError(t, db.QueryRow(`SELECT noise_key FROM whatsmeow_device`).Scan(&key))
+	return key
+}
+
+func TestKeychainContainerKeepsKeysOutOfDatabase(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "whatsapp.db")
+	container, db := openTestContainer(t, dbPath)
+	kc := keychain.Memory{}
+	kcc := &keychainContainer{Container: container, db: db, kc: kc}
+
+	device, err := kcc.loadDevice(ctx)
+	require.NoError(t, err)
+	require.Nil(t, device.ID)
+
+	// Pairing assigns the JID and saves the device
+	jid := types.NewADJID("4915112345678", 0, 1)
+	device.ID = &jid
+	device.Account = &waAdv.ADVSignedDeviceIdentity{Details: []byte{1}, AccountSignature: make([]byte, 64), AccountSignatureKey: make([]byte, 32), DeviceSignature: make([]byte, 64)}
+	noise := device.NoiseKey.Priv
+	require.NoError(t, device.Save(ctx))
+	assert.True(t, device.Initialized)
+	assert.Equal(t, noise, device.NoiseKey.Priv, "in-memory device keeps its real keys")
+
+	assert.Equal(t, make([]byte, 32), storedNoiseKey(t, db))
+	require.Contains(t, kc, jid.String())
+
+	// A fresh process restores the keys from the keychain
+	container2, db2 := openTestContainer(t, dbPath)
+	reloaded, err := (&keychainContainer{Container: container2, db: db2, kc: kc}).loadDevice(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, noise, reloaded.NoiseKey.Priv)
+	assert.Equal(t, device.IdentityKey.Pub, reloaded.IdentityKey.Pub)
+
+	require.NoError(t, reloaded.Delete(ctx))
+	assert.NotContains(t, kc, jid.String())
+}
+
+func TestKeychainContainerMigratesFileBackedDevice(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "whatsapp.db")
+	container, db := openTestContainer(t, dbPath)
+
+	// Paired with the file backend: keys in whatsapp.db
+	device := container.NewDevice()
+	jid := types.NewADJID("4915112345678", 0, 1)
+	device.ID = &jid
+	device.Account = &waAdv.ADVSignedDeviceIdentity{Details: []byte{1}, AccountSignature: make([]byte, 64), AccountSignatureKey: make([]byte, 32), DeviceSignature: make([]byte, 64)}
+	require.NoError(t, device.Save(ctx))
+	noise := device.NoiseKey.Priv
+	assert.Equal(t, noise[:], storedNoiseKey(t, db))
+
+	kc := keychain.Memory{}
+	migrated, err := (&keychainContainer{Container: container, db: db, kc: kc}).loadDevice(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, noise, migrated.NoiseKey.Priv)
+	assert.Equal(t, make([]byte, 32), storedNoiseKey(t, db))
+	assert.Contains(t, kc, jid.String())
+
+	// Losing the keychain entry afterwards is reported, not silently re-paired
+	delete(kc, jid.String())
+	_, err = (&keychainContainer{Container: container, db: db, kc: kc}).loadDevice(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing from the keychain")
+}
@@ -0,0 +1,809 @@
+// Package fakeclient provides a scripted, in-memory implementation of
+// client.Client. It pairs deterministically, delivers events pushed by the
+// test, records sent messages and can echo them back through the sync
+// handler.
+package fakeclient
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// OwnJID is the account JID the fake client is paired as.
+var OwnJID = types.NewJID("10000000000", types.DefaultUserServer)
+
+// OwnIdentityKey is the public identity key the fake client is paired with.
+var OwnIdentityKey = [32]byte{1}
+
+// SentMessage records a message passed to SendMessage.
+type SentMessage struct {
+	ID        string
+	Recipient string
+	Message   string
+	Timestamp time.Time
+	// Media is set for attachments passed to SendMedia; Message is then
+	// their caption.
+	Media *client.OutgoingMedia
+	// Quote is set for replies passed to SendExtendedText.
+	Quote *client.Quote
+	// Mentions are the JIDs passed to SendExtendedText as mentioned.
+	Mentions []string
+	// Poll is set for polls passed to SendPoll; Message is then their
+	// question.
+	Poll *client.Poll
+}
+
+// Revoke records a call to RevokeMessage.
+type Revoke struct {
+	ChatJID   string
+	MessageID string
+}
+
+// TypingUpdate records a call to SendTyping.
+type TypingUpdate struct {
+	Recipient string
+	Typing    bool
+	Timestamp time.Time
+}
+
+// ReadReceipt records a call to MarkRead.
+type ReadReceipt struct {
+	ChatJID   string
+	Sender    string
+	IDs       []string
+	Timestamp time.Time
+}
+
+type Client struct {
+	mu            sync.Mutex
+	authenticated bool
+	connected     bool
+	qrCodes       []string
+	pairCh        chan struct{}
+	handlers      []func(interface{})
+	sent          []SentMessage
+	names         map[string]string
+	lids          map[string]string
+	reads         []ReadReceipt
+	revokes       []Revoke
+	typing        []TypingUpdate
+	presence      []bool
+	activeReceipt bool
+	media         map[string][]byte
+	groups        map[string]client.GroupSettings
+	joinRequests  map[string][]client.GroupJoinRequest
+	participants  map[string][]string
+	addErrors     map[string]int
+	identities    map[string]map[uint16][32]byte
+	echo          bool
+	receipts      bool
+	pingRTT       time.Duration
+	nextID        int
+	now           func() time.Time
+}
+
+var _ client.Client = (*Client)(nil)
+
+// New returns an unpaired fake client that offers the QR codes "fake-qr-1"
+// and "fake-qr-2" until Pair is called.
+func New() *Client {
+	return &Client{
+		qrCodes:      []string{"fake-qr-1", "fake-qr-2"},
+		pairCh:       make(chan struct{}),
+		names:        make(map[string]string),
+		lids:         make(map[string]string),
+		media:        make(map[string][]byte),
+		groups:       make(map[string]client.GroupSettings),
+		joinRequests: make(map[string][]client.GroupJoinRequest),
+		participants: make(map[string][]string),
+		addErrors:    make(map[string]int),
+		identities:   make(map[string]map[uint16][32]byte),
+		now:          time.Now,
+	}
+}
+
+// NewPaired returns a fake client that is already authenticated.
+func NewPaired() *Client {
+	c := New()
+	c.authenticated = true
+	return c
+}
+
+// SetEcho controls whether SendMessage delivers the sent message back to
+// sync handlers as an *events.Message from the own account, as happens when
+// a message is sent from another linked device.
+func (c *Client) SetEcho(echo bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.echo = echo
+}
+
+// SetDeliveryReceipts controls whether each text sent is acknowledged with
+// a delivery receipt from the recipient, as the phone does for the own chat.
+func (c *Client) SetDeliveryReceipts(receipts bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.receipts = receipts
+}
+
+// SetChatName sets the name returned by ResolveChatName for jid.
+func (c *Client) SetChatName(jid, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.names[jid] = name
+}
+
+// MapLID makes PhoneJID resolve the @lid JID lid to the phone JID pn.
+func (c *Client) MapLID(lid, pn string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lids[lid] = pn
+}
+
+// SetIdentityKey makes Identity report key as the identity key of the given
+// device of the contact with phone JID pn.
+func (c *Client) SetIdentityKey(pn string, device uint16, key [32]byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.identities[pn] == nil {
+		c.identities[pn] = map[uint16][32]byte{}
+	}
+	c.identities[pn][device] = key
+}
+
+// SetMedia registers the decrypted content served for a media direct path.
+func (c *Client) SetMedia(directPath string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.media[directPath] = data
+}
+
+// AddGroup registers a group the account is a member of.
+func (c *Client) AddGroup(g client.GroupSettings) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.groups[g.JID] = g
+}
+
+// Group returns the current settings of a registered group.
+func (c *Client) Group(jid string) (client.GroupSettings, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	g, ok := c.groups[jid]
+	return g, ok
+}
+
+// AddJoinRequest queues a pending join request for a group.
+func (c *Client) AddJoinRequest(groupJID string, req client.GroupJoinRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.joinRequests[groupJID] = append(c.joinRequests[groupJID], req)
+}
+
+// AddGroupParticipants adds members to a group registered with AddGroup.
+func (c *Client) AddGroupParticipants(groupJID string, jids ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.participants[groupJID] = append(c.participants[groupJID], jids...)
+}
+
+// SetAddError makes adding jid to a group fail with the WhatsApp error code,
+// e.g. 403 when their privacy settings forbid it.
+func (c *Client) SetAddError(jid string, code int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.addErrors[jid] = code
+}
+
+// Pair completes a pending QR pairing as if the code had been scanned.
+func (c *Client) Pair() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.pairCh:
+	default:
+		close(c.pairCh)
+	}
+}
+
+// Sent returns the messages passed to SendMessage so far.
+func (c *Client) Sent() []SentMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]SentMessage, len(c.sent))
+	copy(out, c.sent)
+	return out
+}
+
+// Emit delivers evt to every registered sync handler.
+func (c *Client) Emit(evt interface{}) {
+	c.mu.Lock()
+	handlers := make([]func(interface{}), len(c.handlers))
+	copy(handlers, c.handlers)
+	c.mu.Unlock()
+
+	for _, h := range handlers {
+		h(evt)
+	}
+}
+
+// EmitText delivers an incoming text message from sender in chat.
+func (c *Client) EmitText(chat, sender types.JID, id, text string, ts time.Time) {
+	c.Emit(TextMessage(chat, sender, id, text, ts, false))
+}
+
+// TextMessage builds an *events.Message carrying a plain text conversation.
+func TextMessage(chat, sender types.JID, id, text string, ts time.Time, fromMe bool) *events.Message {
+	return &events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{
+				Chat:     chat,
+				Sender:   sender,
+				IsFromMe: fromMe,
+				IsGroup:  chat.Server == types.GroupServer,
+			},
+			ID:        id,
+			Timestamp: ts,
+		},
+		Message: &waProto.Message{Conversation: proto.String(text)},
+	}
+}
+
+// PollMessage builds an *events.Message creating a poll.
+func PollMessage(chat, sender types.JID, id string, poll client.Poll, ts time.Time, fromMe bool) *events.Message {
+	evt := TextMessage(chat, sender, id, "", ts, fromMe)
+	pc := &waProto.PollCreationMessage{
+		Name:                   proto.String(poll.Question),
+		SelectableOptionsCount: proto.Uint32(uint32(poll.SelectableCount)),
+	}
+	for _, o := range poll.Options {
+		pc.Options = append(pc.Options, &waProto.PollCreationMessage_Option{OptionName: proto.String(o)})
+	}
+	evt.Message = &waProto.Message{PollCreationMessage: pc}
+	return evt
+}
+
+// PollVoteMessage builds an *events.Message voting for options in the poll
+// sent as pollID. Unlike real votes it is not encrypted, which only the fake
+// client's DecryptPollVote understands.
+func PollVoteMessage(chat, voter types.JID, id, pollID string, options []string, ts time.Time) *events.Message {
+	vote := &waProto.PollVoteMessage{SelectedOptions: whatsmeow.HashPollOptions(options)}
+	payload, _ := proto.Marshal(vote)
+	evt := TextMessage(chat, voter, id, "", ts, voter == OwnJID)
+	evt.Message = &waProto.Message{PollUpdateMessage: &waProto.PollUpdateMessage{
+		PollCreationMessageKey: &waProto.MessageKey{ID: proto.String(pollID)},
+		Vote:                   &waProto.PollEncValue{EncPayload: payload},
+	}}
+	return evt
+}
+
+func (c *Client) IsAuthenticated() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.authenticated
+}
+
+func (c *Client) IsConnected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.connected
+}
+
+// OwnJID returns OwnJID once paired.
+func (c *Client) OwnJID() string {
+	if !c.IsAuthenticated() {
+		return ""
+	}
+	return OwnJID.String()
+}
+
+// GetQRChannel emits the configured QR codes, then blocks until Pair is
+// called (emitting "success") or ctx is cancelled (emitting "timeout").
+func (c *Client) GetQRChannel(ctx context.Context) (<-chan whatsmeow.QRChannelItem, error) {
+	c.mu.Lock()
+	if c.authenticated {
+		c.mu.Unlock()
+		return nil, whatsmeow.ErrQRStoreContainsID
+	}
+	codes := append([]string(nil), c.qrCodes...)
+	pairCh := c.pairCh
+	c.connected = true
+	c.mu.Unlock()
+
+	ch := make(chan whatsmeow.QRChannelItem, len(codes)+1)
+	go func() {
+		defer close(ch)
+		for _, code := range codes {
+			ch <- whatsmeow.QRChannelItem{Event: whatsmeow.QRChannelEventCode, Code: code, Timeout: time.Minute}
+		}
+		select {
+		case <-pairCh:
+			c.mu.Lock()
+			c.authenticated = true
+			c.mu.Unlock()
+			ch <- whatsmeow.QRChannelSuccess
+		case <-ctx.Done():
+			ch <- whatsmeow.QRChannelTimeout
+		}
+	}()
+	return ch, nil
+}
+
+func (c *Client) Authenticate(ctx context.Context) error {
+	if c.IsAuthenticated() {
+		return nil
+	}
+	qrChan, err := c.GetQRChannel(ctx)
+	if err != nil {
+		return err
+	}
+	for evt := range qrChan {
+		if evt.Event == "success" {
+			return nil
+		}
+	}
+	return fmt.Errorf("authentication failed")
+}
+
+func (c *Client) Connect(ctx context.Context) error {
+	if !c.IsAuthenticated() {
+		return c.Authenticate(ctx)
+	}
+	c.mu.Lock()
+	alreadyConnected := c.connected
+	c.connected = true
+	c.mu.Unlock()
+
+	if !alreadyConnected {
+		// WhatsApp then delivers what was queued while offline; the fake
+		// has nothing queued.
+		c.Emit(&events.Connected{})
+		c.Emit(&events.OfflineSyncCompleted{})
+	}
+	return nil
+}
+
+func (c *Client) Disconnect() {
+	c.mu.Lock()
+	c.connected = false
+	c.mu.Unlock()
+}
+
+func (c *Client) SendMessage(ctx context.Context, recipient, message string) (string, error) {
+	return c.sendText(recipient, message, client.TextContext{})
+}
+
+// SendExtendedText records the message like SendMessage, with its quote and
+// mentions.
+func (c *Client) SendExtendedText(ctx context.Context, recipient, message string, ext client.TextContext) (string, error) {
+	return c.sendText(recipient, message, ext)
+}
+
+func (c *Client) sendText(recipient, message string, ext client.TextContext) (string, error) {
+	if !c.IsConnected() {
+		return "", fmt.Errorf("not connected to WhatsApp")
+	}
+	jid := recipient
+	if !strings.Contains(jid, "@") {
+		jid += "@" + types.DefaultUserServer
+	}
+	chat, err := types.ParseJID(jid)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.nextID++
+	sent := SentMessage{
+		ID:        fmt.Sprintf("FAKE%06d", c.nextID),
+		Recipient: chat.String(),
+		Message:   message,
+		Timestamp: c.now(),
+		Quote:     ext.Quote,
+		Mentions:  ext.Mentions,
+	}
+	if ext.ID != "" {
+		sent.ID = ext.ID
+	}
+	c.sent = append(c.sent, sent)
+	echo, receipts := c.echo, c.receipts
+	c.mu.Unlock()
+
+	if echo {
+		c.Emit(TextMessage(chat, OwnJID, sent.ID, message, sent.Timestamp, true))
+	}
+	if receipts {
+		c.Emit(&events.Receipt{
+			MessageSource: types.MessageSource{Chat: chat, Sender: chat},
+			MessageIDs:    []types.MessageID{sent.ID},
+			Timestamp:     sent.Timestamp,
+			Type:          types.ReceiptTypeDelivered,
+		})
+	}
+	return sent.ID, nil
+}
+
+// SendMedia records the attachment like SendMessage, without echoing it.
+func (c *Client) SendMedia(ctx context.Context, recipient string, media client.OutgoingMedia) (string, error) {
+	if !c.IsConnected() {
+		return "", fmt.Errorf("not connected to WhatsApp")
+	}
+	chat, err := types.ParseJID(recipient)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextID++
+	sent := SentMessage{
+		ID:        fmt.Sprintf("FAKE%06d", c.nextID),
+		Recipient: chat.String(),
+		Message:   media.Caption,
+		Timestamp: c.now(),
+		Media:     &media,
+	}
+	c.sent = append(c.sent, sent)
+	return sent.ID, nil
+}
+
+// SendPoll records the poll like SendMessage, without echoing it.
+func (c *Client) SendPoll(ctx context.Context, recipient string, poll client.Poll) (string, error) {
+	if !c.IsConnected() {
+		return "", fmt.Errorf("not connected to WhatsApp")
+	}
+	chat, err := types.ParseJID(recipient)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextID++
+	sent := SentMessage{
+		ID:        fmt.Sprintf("FAKE%06d", c.nextID),
+		Recipient: chat.String(),
+		Message:   poll.Question,
+		Timestamp: c.now(),
+		Poll:      &poll,
+	}
+	c.sent = append(c.sent, sent)
+	return sent.ID, nil
+}
+
+// DecryptPollVote reads votes built by PollVoteMessage, which are not
+// encrypted.
+func (c *Client) DecryptPollVote(ctx context.Context, msg *events.Message) (client.PollVote, error) {
+	update := msg.Message.GetPollUpdateMessage()
+	if update == nil {
+		return client.PollVote{}, fmt.Errorf("message %s is not a poll vote", msg.Info.ID)
+	}
+	var vote waProto.PollVoteMessage
+	if err := proto.Unmarshal(update.GetVote().GetEncPayload(), &vote); err != nil {
+		return client.PollVote{}, err
+	}
+	v := client.PollVote{PollID: update.GetPollCreationMessageKey().GetID(), OptionHashes: []string{}}
+	for _, hash := range vote.GetSelectedOptions() {
+		v.OptionHashes = append(v.OptionHashes, fmt.Sprintf("%x", hash))
+	}
+	return v, nil
+}
+
+func (c *Client) SendTyping(ctx context.Context, recipient string, typing bool) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("not connected to WhatsApp")
+	}
+	c.mu.Lock()
+	c.typing = append(c.typing, TypingUpdate{Recipient: recipient, Typing: typing, Timestamp: c.now()})
+	c.mu.Unlock()
+	return nil
+}
+
+// Typing returns the updates passed to SendTyping so far.
+func (c *Client) Typing() []TypingUpdate {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]TypingUpdate, len(c.typing))
+	copy(out, c.typing)
+	return out
+}
+
+func (c *Client) SendPresence(ctx context.Context, available bool) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("not connected to WhatsApp")
+	}
+	c.mu.Lock()
+	c.presence = append(c.presence, available)
+	c.mu.Unlock()
+	return nil
+}
+
+// Presence returns the states passed to SendPresence so far, true for
+// available.
+func (c *Client) Presence() []bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]bool(nil), c.presence...)
+}
+
+func (c *Client) ResolveChatName(ctx context.Context, chatJID string, msg *events.Message) string {
+	if chatJID == "" && msg != nil {
+		chatJID = msg.Info.Chat.String()
+	}
+	c.mu.Lock()
+	name, ok := c.names[chatJID]
+	c.mu.Unlock()
+	if ok {
+		return name
+	}
+	if msg != nil && msg.Info.PushName != "" {
+		return msg.Info.PushName
+	}
+	return chatJID
+}
+
+// KnownChats lists the registered groups and the phone JIDs given a name
+// with SetChatName, as the contacts of the account.
+func (c *Client) KnownChats(ctx context.Context) ([]client.KnownChat, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.connected {
+		return nil, fmt.Errorf("not connected to WhatsApp")
+	}
+	var chats []client.KnownChat
+	for _, g := range c.groups {
+		chats = append(chats, client.KnownChat{JID: g.JID, Name: g.Subject})
+	}
+	for jid, name := range c.names {
+		if strings.HasSuffix(jid, "@"+types.DefaultUserServer) {
+			chats = append(chats, client.KnownChat{JID: jid, Name: name})
+		}
+	}
+	sort.Slice(chats, func(i, j int) bool { return chats[i].JID < chats[j].JID })
+	return chats, nil
+}
+
+// SetPingRTT sets the round trip Ping reports.
+func (c *Client) SetPingRTT(rtt time.Duration) {
+	c.mu.Lock()
+	c.pingRTT = rtt
+	c.mu.Unlock()
+}
+
+// Ping answers at once, reporting the round trip set with SetPingRTT.
+func (c *Client) Ping(ctx context.Context) (time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.connected {
+		return 0, fmt.Errorf("not connected to WhatsApp")
+	}
+	return c.pingRTT, nil
+}
+
+func (c *Client) PhoneJID(ctx context.Context, jid string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if pn, ok := c.lids[jid]; ok {
+		return pn
+	}
+	return jid
+}
+
+func (c *Client) SetActiveDeliveryReceipts(active bool) {
+	c.mu.Lock()
+	c.activeReceipt = active
+	c.mu.Unlock()
+}
+
+// ActiveDeliveryReceipts reports the last value passed to
+// SetActiveDeliveryReceipts.
+func (c *Client) ActiveDeliveryReceipts() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.activeReceipt
+}
+
+func (c *Client) MarkRead(ctx context.Context, chatJID, sender string, ids []string, ts time.Time) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("not connected to WhatsApp")
+	}
+	c.mu.Lock()
+	c.reads = append(c.reads, ReadReceipt{ChatJID: chatJID, Sender: sender, IDs: append([]string(nil), ids...), Timestamp: ts})
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Client) RevokeMessage(ctx context.Context, chatJID, messageID string) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("not connected to WhatsApp")
+	}
+	c.mu.Lock()
+	c.revokes = append(c.revokes, Revoke{ChatJID: chatJID, MessageID: messageID})
+	c.mu.Unlock()
+	return nil
+}
+
+// Revokes returns the messages passed to RevokeMessage so far.
+func (c *Client) Revokes() []Revoke {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]Revoke, len(c.revokes))
+	copy(out, c.revokes)
+	return out
+}
+
+// Reads returns the read receipts passed to MarkRead so far.
+func (c *Client) Reads() []ReadReceipt {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]ReadReceipt, len(c.reads))
+	copy(out, c.reads)
+	return out
+}
+
+func (c *Client) StartSync(ctx context.Context, eventHandler func(interface{})) error {
+	c.mu.Lock()
+	c.handlers = append(c.handlers, eventHandler)
+	c.mu.Unlock()
+	return c.Connect(ctx)
+}
+
+func (c *Client) DownloadMediaToFile(ctx context.Context, req client.MediaDownloadRequest, targetPath string) (int64, error) {
+	c.mu.Lock()
+	data, ok := c.media[req.DirectPath]
+	c.mu.Unlock()
+	if !ok {
+		return 0, fmt.Errorf("download failed with status code 404")
+	}
+	if err := os.WriteFile(targetPath, data, 0644); err != nil {
+		return 0, err
+	}
+	return int64(len(data)), nil
+}
+
+func (c *Client) UpdateGroup(ctx context.Context, groupJID string, upd client.GroupUpdate) (client.GroupSettings, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	g, ok := c.groups[groupJID]
+	if !ok {
+		return client.GroupSettings{}, fmt.Errorf("group %s not found", groupJID)
+	}
+	if upd.Subject != nil {
+		g.Subject = *upd.Subject
+	}
+	if upd.Description != nil {
+		g.Description = *upd.Description
+	}
+	if upd.Announce != nil {
+		g.Announce = *upd.Announce
+	}
+	if upd.Locked != nil {
+		g.Locked = *upd.Locked
+	}
+	c.groups[groupJID] = g
+	return g, nil
+}
+
+// CreateGroup registers a new group with the participants that have no
+// error set with SetAddError.
+func (c *Client) CreateGroup(ctx context.Context, subject string, participants []string) (client.GroupSettings, []client.GroupParticipantResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.connected {
+		return client.GroupSettings{}, nil, fmt.Errorf("not connected to WhatsApp")
+	}
+	c.nextID++
+	g := client.GroupSettings{JID: fmt.Sprintf("1203630000%08d@g.us", c.nextID), Subject: subject}
+	c.groups[g.JID] = g
+	results := make([]client.GroupParticipantResult, len(participants))
+	for i, p := range participants {
+		results[i] = client.GroupParticipantResult{JID: p, Error: c.addErrors[p]}
+		if results[i].Error == 0 {
+			c.participants[g.JID] = append(c.participants[g.JID], p)
+		}
+	}
+	return g, results, nil
+}
+
+func (c *Client) GetGroupJoinRequests(ctx context.Context, groupJID string) ([]client.GroupJoinRequest, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.groups[groupJID]; !ok {
+		return nil, fmt.Errorf("group %s not found", groupJID)
+	}
+	return append([]client.GroupJoinRequest(nil), c.joinRequests[groupJID]...), nil
+}
+
+// UpdateGroupJoinRequests resolves pending requests; participants without a
+// pending request get error code 404.
+func (c *Client) UpdateGroupJoinRequests(ctx context.Context, groupJID string, participants []string, approve bool) ([]client.GroupParticipantResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.groups[groupJID]; !ok {
+		return nil, fmt.Errorf("group %s not found", groupJID)
+	}
+	var results []client.GroupParticipantResult
+	for _, p := range participants {
+		result := client.GroupParticipantResult{JID: p, Error: 404}
+		pending := c.joinRequests[groupJID]
+		for i, req := range pending {
+			if req.JID == p {
+				c.joinRequests[groupJID] = append(pending[:i:i], pending[i+1:]...)
+				result.Error = 0
+				break
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// UpdateGroupParticipants changes the members of a group registered with
+// AddGroup. Adding fails with the code set by SetAddError, or 409 for a
+// member; the other changes fail with 404 for anyone who is not a member.
+func (c *Client) UpdateGroupParticipants(ctx context.Context, groupJID string, participants []string, action string) ([]client.GroupParticipantResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.groups[groupJID]; !ok {
+		return nil, fmt.Errorf("group %s not found", groupJID)
+	}
+	results := make([]client.GroupParticipantResult, len(participants))
+	for i, p := range participants {
+		results[i] = client.GroupParticipantResult{JID: p}
+		members := c.participants[groupJID]
+		idx := -1
+		for j, m := range members {
+			if m == p {
+				idx = j
+				break
+			}
+		}
+		switch {
+		case action == client.ParticipantAdd && c.addErrors[p] != 0:
+			results[i].Error = c.addErrors[p]
+		case action == client.ParticipantAdd && idx >= 0:
+			results[i].Error = 409
+		case action == client.ParticipantAdd:
+			c.participants[groupJID] = append(members, p)
+		case idx < 0:
+			results[i].Error = 404
+		case action == client.ParticipantRemove:
+			c.participants[groupJID] = append(members[:idx:idx], members[idx+1:]...)
+		}
+	}
+	return results, nil
+}
+
+func (c *Client) GroupParticipants(ctx context.Context, groupJID string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.groups[groupJID]; !ok {
+		return nil, fmt.Errorf("group %s not found", groupJID)
+	}
+	return append([]string(nil), c.participants[groupJID]...), nil
+}
+
+func (c *Client) Identity(ctx context.Context, contact string) (client.Identity, error) {
+	pn := c.PhoneJID(ctx, contact)
+	phone, server, _ := strings.Cut(pn, "@")
+	if server != types.DefaultUserServer {
+		return client.Identity{}, fmt.Errorf("%s is not a contact's phone JID", contact)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id := client.Identity{OwnPhone: OwnJID.User, OwnKey: OwnIdentityKey, Phone: phone, Keys: map[uint16][32]byte{}}
+	for device, key := range c.identities[pn] {
+		id.Keys[device] = key
+	}
+	return id, nil
+}
@@ -0,0 +1,82 @@
+package fakeclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestQRFlowIsDeterministic(t *testing.T) {
+	c := New()
+	qrChan, err := c.GetQRChannel(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "fake-qr-1", (<-qrChan).Code)
+	assert.Equal(t, "fake-qr-2", (<-qrChan).Code)
+	assert.False(t, c.IsAuthenticated())
+
+	c.Pair()
+	assert.Equal(t, "success", (<-qrChan).Event)
+	assert.True(t, c.IsAuthenticated())
+}
+
+func TestQRFlowTimesOutWithContext(t *testing.T) {
+	c := New()
+	ctx, cancel := context.WithCancel(context.Background())
+	qrChan, err := c.GetQRChannel(ctx)
+	require.NoError(t, err)
+	<-qrChan
+	<-qrChan
+	cancel()
+
+	assert.Equal(t, "timeout", (<-qrChan).Event)
+	assert.False(t, c.IsAuthenticated())
+}
+
+func TestSendMessageRecordsAndEchoes(t *testing.T) {
+	c := NewPaired()
+	c.SetEcho(true)
+
+	var received []*events.Message
+	require.NoError(t, c.StartSync(context.Background(), func(evt interface{}) {
+		if msg, ok := evt.(*events.Message); ok {
+			received = append(received, msg)
+		}
+	}))
+
+	id, err := c.SendMessage(context.Background(), "12345", "ping")
+	require.NoError(t, err)
+
+	sent := c.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, sent[0].ID, id)
+	assert.Equal(t, "12345@s.whatsapp.net", sent[0].Recipient)
+	require.Len(t, received, 1)
+	assert.True(t, received[0].Info.IsFromMe)
+	assert.Equal(t, sent[0].ID, received[0].Info.ID)
+	assert.Equal(t, "ping", received[0].Message.GetConversation())
+}
+
+func TestSendMessageRequiresConnection(t *testing.T) {
+	c := NewPaired()
+	_, err := c.SendMessage(context.Background(), "12345", "ping")
+	assert.Error(t, err)
+}
+
+func TestEmitTextReachesHandlers(t *testing.T) {
+	c := NewPaired()
+	var got *events.Message
+	require.NoError(t, c.StartSync(context.Background(), func(evt interface{}) {
+		if msg, ok := evt.(*events.Message); ok {
+			got = msg
+		}
+	}))
+
+	c.EmitText(OwnJID, OwnJID, "ID1", "note to self", time.Unix(1700000000, 0))
+	require.NotNil(t, got)
+	assert.Equal(t, "note to self", got.Message.GetConversation())
+}
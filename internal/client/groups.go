@@ -0,0 +1,263 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// GroupSettings is the editable state of a group.
+type GroupSettings struct {
+	JID         string
+	Subject     string
+	Description string
+	Announce    bool // only admins can send messages
+	Locked      bool // only admins can edit group info
+}
+
+// GroupUpdate lists group settings to change; nil fields are left untouched.
+type GroupUpdate struct {
+	Subject     *string
+	Description *string
+	Announce    *bool
+	Locked      *bool
+}
+
+// UpdateGroup applies the requested changes to a group and returns the
+// resulting settings as reported by WhatsApp.
+func (w *WAClient) UpdateGroup(ctx context.Context, groupJID string, upd GroupUpdate) (GroupSettings, error) {
+	if !w.client.IsConnected() {
+		return GroupSettings{}, fmt.Errorf("not connected to WhatsApp")
+	}
+	jid, err := parseGroupJID(groupJID)
+	if err != nil {
+		return GroupSettings{}, err
+	}
+
+	if upd.Subject != nil {
+		if err := w.client.SetGroupName(ctx, jid, *upd.Subject); err != nil {
+			return GroupSettings{}, fmt.Errorf("failed to set subject: %w", err)
+		}
+	}
+	if upd.Description != nil {
+		// The topic ID of the current description must be passed as the
+		// previous ID, otherwise WhatsApp rejects the change as a conflict.
+		info, err := w.client.GetGroupInfo(ctx, jid)
+		if err != nil {
+			return GroupSettings{}, fmt.Errorf("failed to get group info: %w", err)
+		}
+		if err := w.client.SetGroupTopic(ctx, jid, info.TopicID, "", *upd.Description); err != nil {
+			return GroupSettings{}, fmt.Errorf("failed to set description: %w", err)
+		}
+	}
+	if upd.Announce != nil {
+		if err := w.client.SetGroupAnnounce(ctx, jid, *upd.Announce); err != nil {
+			return GroupSettings{}, fmt.Errorf("failed to set announce mode: %w", err)
+		}
+	}
+	if upd.Locked != nil {
+		if err := w.client.SetGroupLocked(ctx, jid, *upd.Locked); err != nil {
+			return GroupSettings{}, fmt.Errorf("failed to set locked mode: %w", err)
+		}
+	}
+
+	info, err := w.client.GetGroupInfo(ctx, jid)
+	if err != nil {
+		return GroupSettings{}, fmt.Errorf("failed to get group info: %w", err)
+	}
+	return GroupSettings{
+		JID:         info.JID.String(),
+		Subject:     info.Name,
+		Description: info.Topic,
+		Announce:    info.IsAnnounce,
+		Locked:      info.IsLocked,
+	}, nil
+}
+
+// CreateGroup creates a group with the account as its admin and adds the
+// participants, returning the new group and the outcome for each
+// participant: WhatsApp creates the group even when some cannot be added.
+func (w *WAClient) CreateGroup(ctx context.Context, subject string, participants []string) (GroupSettings, []GroupParticipantResult, error) {
+	if !w.client.IsConnected() {
+		return GroupSettings{}, nil, fmt.Errorf("not connected to WhatsApp")
+	}
+	jids, err := parseParticipants(participants)
+	if err != nil {
+		return GroupSettings{}, nil, err
+	}
+	info, err := w.client.CreateGroup(ctx, whatsmeow.ReqCreateGroup{Name: subject, Participants: jids})
+	if err != nil {
+		return GroupSettings{}, nil, fmt.Errorf("failed to create group: %w", err)
+	}
+
+	own := w.client.Store.GetJID().ToNonAD()
+	ownLID := w.client.Store.GetLID().ToNonAD()
+	var results []GroupParticipantResult
+	for _, p := range info.Participants {
+		member := p.JID
+		if member.Server == types.HiddenUserServer && !p.PhoneNumber.IsEmpty() {
+			member = p.PhoneNumber
+		}
+		member = member.ToNonAD()
+		if member == own || member == ownLID {
+			continue
+		}
+		results = append(results, GroupParticipantResult{JID: member.String(), Error: p.Error})
+	}
+	return GroupSettings{
+		JID:         info.JID.String(),
+		Subject:     info.Name,
+		Description: info.Topic,
+		Announce:    info.IsAnnounce,
+		Locked:      info.IsLocked,
+	}, results, nil
+}
+
+func parseParticipants(participants []string) ([]types.JID, error) {
+	jids := make([]types.JID, len(participants))
+	for i, p := range participants {
+		var err error
+		if jids[i], err = parseJID(p); err != nil {
+			return nil, fmt.Errorf("invalid participant %s: %w", p, err)
+		}
+	}
+	return jids, nil
+}
+
+func parseGroupJID(groupJID string) (types.JID, error) {
+	jid, err := parseJID(groupJID)
+	if err != nil {
+		return types.JID{}, err
+	}
+	if jid.Server != types.GroupServer {
+		return types.JID{}, fmt.Errorf("%s is not a group JID", groupJID)
+	}
+	return jid, nil
+}
+
+// GroupJoinRequest is a pending request to join a group that requires admin approval.
+type GroupJoinRequest struct {
+	JID         string
+	RequestedAt time.Time
+}
+
+// GroupParticipantResult is the per-participant outcome of a group membership
+// change. Error is the WhatsApp error code (0 on success).
+type GroupParticipantResult struct {
+	JID   string
+	Error int
+}
+
+// Group membership changes accepted by UpdateGroupParticipants.
+const (
+	ParticipantAdd     = "add"
+	ParticipantRemove  = "remove"
+	ParticipantPromote = "promote"
+	ParticipantDemote  = "demote"
+)
+
+// UpdateGroupParticipants adds or removes members of a group, or makes them
+// admins or not, reporting the outcome for each participant.
+func (w *WAClient) UpdateGroupParticipants(ctx context.Context, groupJID string, participants []string, action string) ([]GroupParticipantResult, error) {
+	if !w.client.IsConnected() {
+		return nil, fmt.Errorf("not connected to WhatsApp")
+	}
+	jid, err := parseGroupJID(groupJID)
+	if err != nil {
+		return nil, err
+	}
+	jids, err := parseParticipants(participants)
+	if err != nil {
+		return nil, err
+	}
+	res, err := w.client.UpdateGroupParticipants(ctx, jid, jids, whatsmeow.ParticipantChange(action))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]GroupParticipantResult, len(res))
+	for i, p := range res {
+		member := p.JID
+		if member.Server == types.HiddenUserServer && !p.PhoneNumber.IsEmpty() {
+			member = p.PhoneNumber
+		}
+		out[i] = GroupParticipantResult{JID: member.ToNonAD().String(), Error: p.Error}
+	}
+	return out, nil
+}
+
+// GetGroupJoinRequests lists pending join requests for a group.
+func (w *WAClient) GetGroupJoinRequests(ctx context.Context, groupJID string) ([]GroupJoinRequest, error) {
+	if !w.client.IsConnected() {
+		return nil, fmt.Errorf("not connected to WhatsApp")
+	}
+	jid, err := parseGroupJID(groupJID)
+	if err != nil {
+		return nil, err
+	}
+	reqs, err := w.client.GetGroupRequestParticipants(ctx, jid)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]GroupJoinRequest, len(reqs))
+	for i, req := range reqs {
+		out[i] = GroupJoinRequest{JID: req.JID.String(), RequestedAt: req.RequestedAt}
+	}
+	return out, nil
+}
+
+// UpdateGroupJoinRequests approves or rejects pending join requests.
+func (w *WAClient) UpdateGroupJoinRequests(ctx context.Context, groupJID string, participants []string, approve bool) ([]GroupParticipantResult, error) {
+	if !w.client.IsConnected() {
+		return nil, fmt.Errorf("not connected to WhatsApp")
+	}
+	jid, err := parseGroupJID(groupJID)
+	if err != nil {
+		return nil, err
+	}
+	jids, err := parseParticipants(participants)
+	if err != nil {
+		return nil, err
+	}
+
+	action := whatsmeow.ParticipantChangeReject
+	if approve {
+		action = whatsmeow.ParticipantChangeApprove
+	}
+	res, err := w.client.UpdateGroupRequestParticipants(ctx, jid, jids, action)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]GroupParticipantResult, len(res))
+	for i, p := range res {
+		out[i] = GroupParticipantResult{JID: p.JID.String(), Error: p.Error}
+	}
+	return out, nil
+}
+
+// GroupParticipants lists the members of a group. Members that WhatsApp
+// only identifies by @lid JID are listed by their phone JID when it is known.
+func (w *WAClient) GroupParticipants(ctx context.Context, groupJID string) ([]string, error) {
+	if !w.client.IsConnected() {
+		return nil, fmt.Errorf("not connected to WhatsApp")
+	}
+	jid, err := parseGroupJID(groupJID)
+	if err != nil {
+		return nil, err
+	}
+	info, err := w.client.GetGroupInfo(ctx, jid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group info: %w", err)
+	}
+	out := make([]string, len(info.Participants))
+	for i, p := range info.Participants {
+		member := p.JID
+		if member.Server == types.HiddenUserServer && !p.PhoneNumber.IsEmpty() {
+			member = p.PhoneNumber
+		}
+		out[i] = member.ToNonAD().String()
+	}
+	return out, nil
+}
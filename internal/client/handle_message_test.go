@@ -88,3 +88,60 @@ func TestHandleMessageExtractsImageMediaMetadata(t *testing.T) {
 	assert.Equal(t, fileEncSha, media.FileEncSHA256)
 	assert.Equal(t, uint64(2048), media.FileLength)
 }
+
+func TestHandleMessageExtractsForwardingScore(t *testing.T) {
+	msg := &events.Message{
+		Info: types.MessageInfo{ID: "fwd-1"},
+		Message: &proto.Message{
+			ExtendedTextMessage: &proto.ExtendedTextMessage{
+				Text: goproto.String("forwarded text"),
+				ContextInfo: &proto.ContextInfo{
+					IsForwarded:     goproto.Bool(true),
+					ForwardingScore: goproto.Uint32(7),
+				},
+			},
+		},
+	}
+
+	details := HandleMessage(msg)
+
+	assert.Equal(t, "forwarded text", details.Content)
+	assert.Equal(t, uint32(7), details.ForwardingScore)
+	assert.Equal(t, uint32(0), ForwardingScore(&proto.Message{Conversation: goproto.String("plain")}))
+}
+
+func TestHandleMessagePrefersPhoneJIDForLIDChats(t *testing.T) {
+	lid := types.NewJID("98765432109876", types.HiddenUserServer)
+	pn := types.NewJID("4915112345678", types.DefaultUserServer)
+	msg := &events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{
+				Chat:           lid,
+				Sender:         lid,
+				SenderAlt:      pn,
+				AddressingMode: types.AddressingModeLID,
+			},
+			ID: "lid-1",
+		},
+		Message: &proto.Message{Conversation: goproto.String("hi")},
+	}
+
+	details := HandleMessage(msg)
+	assert.Equal(t, "4915112345678@s.whatsapp.net", details.ChatJID)
+	assert.Equal(t, "98765432109876@lid", details.ChatLID)
+	assert.Equal(t, "4915112345678", details.Sender)
+
+	// Own messages carry the other side's phone number as RecipientAlt
+	msg.Info.IsFromMe = true
+	msg.Info.Sender = types.NewJID("111", types.HiddenUserServer)
+	msg.Info.SenderAlt = types.EmptyJID
+	msg.Info.RecipientAlt = pn
+	details = HandleMessage(msg)
+	assert.Equal(t, "4915112345678@s.whatsapp.net", details.ChatJID)
+
+	// Without an alternative address the LID chat is kept as is
+	msg.Info.RecipientAlt = types.EmptyJID
+	details = HandleMessage(msg)
+	assert.Equal(t, "98765432109876@lid", details.ChatJID)
+	assert.Empty(t, details.ChatLID)
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"fmt"
+
+	"go.mau.fi/whatsmeow/store"
+	"google.golang.org/protobuf/proto"
+)
+
+// History sync modes a new device can ask the phone for when pairing.
+const (
+	// HistorySyncRecent pairs quickly with the recent messages of each chat.
+	HistorySyncRecent = "recent"
+	// HistorySyncFull asks for the complete archive, which can take a long
+	// time to arrive on large accounts.
+	HistorySyncFull = "full"
+)
+
+// ConfigureHistorySync sets how much history is requested when the device
+// is paired: mode is HistorySyncRecent or HistorySyncFull, and days, when
+// positive, limits it to the last days days. It must be called before
+// pairing; sessions that are already paired keep the history they got.
+func ConfigureHistorySync(mode string, days int) error {
+	if days < 0 {
+		return fmt.Errorf("invalid history sync days %d", days)
+	}
+	var limit *uint32
+	if days > 0 {
+		limit = proto.Uint32(uint32(days))
+	}
+
+	cfg := store.DeviceProps.HistorySyncConfig
+	switch mode {
+	case "", HistorySyncRecent:
+		store.DeviceProps.RequireFullSync = proto.Bool(false)
+		cfg.FullSyncDaysLimit = nil
+		cfg.RecentSyncDaysLimit = limit
+	case HistorySyncFull:
+		store.DeviceProps.RequireFullSync = proto.Bool(true)
+		cfg.FullSyncDaysLimit = limit
+		cfg.RecentSyncDaysLimit = nil
+	default:
+		return fmt.Errorf("unknown history sync mode %q (expected %q or %q)", mode, HistorySyncRecent, HistorySyncFull)
+	}
+	return nil
+}
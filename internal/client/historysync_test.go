@@ -0,0 +1,25 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mau.fi/whatsmeow/store"
+)
+
+func TestConfigureHistorySync(t *testing.T) {
+	t.Cleanup(func() { ConfigureHistorySync(HistorySyncRecent, 0) })
+
+	require.NoError(t, ConfigureHistorySync(HistorySyncFull, 365))
+	assert.True(t, store.DeviceProps.GetRequireFullSync())
+	assert.Equal(t, uint32(365), store.DeviceProps.HistorySyncConfig.GetFullSyncDaysLimit())
+
+	require.NoError(t, ConfigureHistorySync(HistorySyncRecent, 7))
+	assert.False(t, store.DeviceProps.GetRequireFullSync())
+	assert.Nil(t, store.DeviceProps.HistorySyncConfig.FullSyncDaysLimit)
+	assert.Equal(t, uint32(7), store.DeviceProps.HistorySyncConfig.GetRecentSyncDaysLimit())
+
+	assert.Error(t, ConfigureHistorySync("everything", 0))
+	assert.Error(t, ConfigureHistorySync(HistorySyncFull, -1))
+}
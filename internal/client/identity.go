@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"crypto/sha512"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.mau.fi/libsignal/fingerprint"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// securityCodeIterations is the number of hash rounds of each half of a
+// security code, as in Signal's safety numbers.
+const securityCodeIterations = 5200
+
+// Identity is the identity key material the session holds for a contact.
+type Identity struct {
+	// OwnPhone and OwnKey are the account's phone number and identity key.
+	OwnPhone string
+	OwnKey   [32]byte
+	// Phone is the contact's phone number, and Keys their identity keys by
+	// device ID, 0 being their phone. Keys is empty until the contact and
+	// the account exchanged a message.
+	Phone string
+	Keys  map[uint16][32]byte
+}
+
+// SecurityCode returns the 60-digit code both sides can compare to make sure
+// nobody is relaying their messages, in twelve groups of five digits. It is
+// computed like Signal's safety numbers from each side's phone number and
+// phone identity key, and is empty while the contact's key is unknown.
+func (id Identity) SecurityCode() string {
+	key, ok := id.Keys[0]
+	if !ok {
+		return ""
+	}
+	digits := fingerprint.NewDisplay(
+		fingerprintHash(id.OwnPhone, id.OwnKey),
+		fingerprintHash(id.Phone, key),
+	).DisplayText()
+	groups := make([]string, 0, len(digits)/5)
+	for i := 0; i+5 <= len(digits); i += 5 {
+		groups = append(groups, digits[i:i+5])
+	}
+	return strings.Join(groups, " ")
+}
+
+// fingerprintHash is one side's half of a security code.
+func fingerprintHash(phone string, key [32]byte) []byte {
+	// Keys are hashed in their serialized form, with the Curve25519 type byte
+	pub := append([]byte{0x05}, key[:]...)
+	hash := append(append([]byte{0, 0}, pub...), phone...)
+	for i := 0; i < securityCodeIterations; i++ {
+		sum := sha512.Sum512(append(hash, pub...))
+		hash = sum[:]
+	}
+	return hash
+}
+
+// Identity returns the identity keys the session holds for a contact, by
+// phone or LID JID.
+func (w *WAClient) Identity(ctx context.Context, contact string) (Identity, error) {
+	if w.client.Store.ID == nil {
+		return Identity{}, fmt.Errorf("not paired")
+	}
+	parsed, err := types.ParseJID(w.PhoneJID(ctx, contact))
+	if err != nil {
+		return Identity{}, fmt.Errorf("invalid JID %q: %w", contact, err)
+	}
+	if parsed.Server != types.DefaultUserServer {
+		return Identity{}, fmt.Errorf("%s is not a contact's phone JID", contact)
+	}
+	parsed = parsed.ToNonAD()
+	id := Identity{
+		OwnPhone: w.client.Store.ID.User,
+		OwnKey:   *w.client.Store.IdentityKey.Pub,
+		Phone:    parsed.User,
+		Keys:     map[uint16][32]byte{},
+	}
+
+	// Signal addresses are <user>:<device>; sessions moved to the contact's
+	// LID are stored under <lid>_1:<device>
+	patterns := []string{parsed.SignalAddressUser() + ":%"}
+	if lid, err := w.client.Store.LIDs.GetLIDForPN(ctx, parsed); err == nil && !lid.IsEmpty() {
+		patterns = append(patterns, lid.ToNonAD().SignalAddressUser()+":%")
+	}
+	for _, pattern := range patterns {
+		rows, err := w.db.QueryContext(ctx,
+			`SELECT their_id, identity FROM whatsmeow_identity_keys WHERE our_jid = ? AND their_id LIKE ?`,
+			w.client.Store.ID.String(), pattern,
+		)
+		if err != nil {
+			return Identity{}, fmt.Errorf("reading identity keys: %w", err)
+		}
+		for rows.Next() {
+			var address string
+			var key []byte
+			if err := rows.Scan(&address, &key); err != nil {
+				rows.Close()
+				return Identity{}, err
+			}
+			_, device, _ := strings.Cut(address, ":")
+			d, err := strconv.ParseUint(device, 10, 16)
+			if err != nil || len(key) != 32 {
+				continue
+			}
+			id.Keys[uint16(d)] = [32]byte(key)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return Identity{}, err
+		}
+	}
+	return id, nil
+}
@@ -0,0 +1,32 @@
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityCode(t *testing.T) {
+	alice := Identity{OwnPhone: "34600111222", OwnKey: [32]byte{1}, Phone: "34600333444", Keys: map[uint16][32]byte{}}
+	assert.Empty(t, alice.SecurityCode(), "unknown until the contact's phone key is")
+
+	alice.Keys[3] = [32]byte{3}
+	assert.Empty(t, alice.SecurityCode(), "linked devices do not count")
+
+	alice.Keys[0] = [32]byte{2}
+	code := alice.SecurityCode()
+	groups := strings.Split(code, " ")
+	assert.Len(t, groups, 12)
+	for _, g := range groups {
+		assert.Regexp(t, `^[0-9]{5}$`, g)
+	}
+
+	// Both sides compute the same code
+	bob := Identity{OwnPhone: "34600333444", OwnKey: [32]byte{2}, Phone: "34600111222", Keys: map[uint16][32]byte{0: {1}}}
+	assert.Equal(t, code, bob.SecurityCode())
+
+	// and any other key gives another
+	bob.Keys[0] = [32]byte{9}
+	assert.NotEqual(t, code, bob.SecurityCode())
+}
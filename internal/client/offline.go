@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// ErrOffline is returned by Offline for everything that needs WhatsApp.
+var ErrOffline = errors.New("not connected to WhatsApp: this instance is a read-only replica")
+
+// Offline is a Client without a WhatsApp session, for read-only replicas
+// that only serve a copy of the message store. It is never authenticated or
+// connected.
+type Offline struct{}
+
+var _ Client = Offline{}
+
+func (Offline) IsAuthenticated() bool { return false }
+func (Offline) IsConnected() bool     { return false }
+func (Offline) OwnJID() string        { return "" }
+
+func (Offline) GetQRChannel(ctx context.Context) (<-chan whatsmeow.QRChannelItem, error) {
+	return nil, ErrOffline
+}
+
+func (Offline) Authenticate(ctx context.Context) error { return ErrOffline }
+func (Offline) Connect(ctx context.Context) error      { return ErrOffline }
+func (Offline) Disconnect()                            {}
+
+func (Offline) SendMessage(ctx context.Context, recipient, message string) (string, error) {
+	return "", ErrOffline
+}
+
+func (Offline) SendMedia(ctx context.Context, recipient string, media OutgoingMedia) (string, error) {
+	return "", ErrOffline
+}
+
+func (Offline) SendTyping(ctx context.Context, recipient string, typing bool) error {
+	return ErrOffline
+}
+
+func (Offline) SendPresence(ctx context.Context, available bool) error {
+	return ErrOffline
+}
+
+// ResolveChatName falls back to the JID, like WAClient without a name.
+func (Offline) ResolveChatName(ctx context.Context, chatJID string, msg *events.Message) string {
+	return chatJID
+}
+
+// PhoneJID cannot map LIDs without the session and returns jid unchanged.
+func (Offline) PhoneJID(ctx context.Context, jid string) string { return jid }
+func (Offline) SetActiveDeliveryReceipts(active bool)           {}
+
+func (Offline) MarkRead(ctx context.Context, chatJID, sender string, ids []string, ts time.Time) error {
+	return ErrOffline
+}
+
+func (Offline) StartSync(ctx context.Context, eventHandler func(interface{})) error {
+	return ErrOffline
+}
+
+func (Offline) DownloadMediaToFile(ctx context.Context, req MediaDownloadRequest, targetPath string) (int64, error) {
+	return 0, ErrOffline
+}
+
+func (Offline) UpdateGroup(ctx context.Context, groupJID string, upd GroupUpdate) (GroupSettings, error) {
+	return GroupSettings{}, ErrOffline
+}
+
+func (Offline) CreateGroup(ctx context.Context, subject string, participants []string) (GroupSettings, []GroupParticipantResult, error) {
+	return GroupSettings{}, nil, ErrOffline
+}
+
+func (Offline) GetGroupJoinRequests(ctx context.Context, groupJID string) ([]GroupJoinRequest, error) {
+	return nil, ErrOffline
+}
+
+func (Offline) UpdateGroupJoinRequests(ctx context.Context, groupJID string, participants []string, approve bool) ([]GroupParticipantResult, error) {
+	return nil, ErrOffline
+}
+
+func (Offline) UpdateGroupParticipants(ctx context.Context, groupJID string, participants []string, action string) ([]GroupParticipantResult, error) {
+	return nil, ErrOffline
+}
+
+func (Offline) GroupParticipants(ctx context.Context, groupJID string) ([]string, error) {
+	return nil, ErrOffline
+}
+
+func (Offline) KnownChats(ctx context.Context) ([]KnownChat, error) {
+	return nil, ErrOffline
+}
+
+func (Offline) SendExtendedText(ctx context.Context, recipient, message string, ext TextContext) (string, error) {
+	return "", ErrOffline
+}
+
+func (Offline) RevokeMessage(ctx context.Context, chatJID, messageID string) error {
+	return ErrOffline
+}
+
+func (Offline) Identity(ctx context.Context, contact string) (Identity, error) {
+	return Identity{}, ErrOffline
+}
+
+func (Offline) SendPoll(ctx context.Context, recipient string, poll Poll) (string, error) {
+	return "", ErrOffline
+}
+
+func (Offline) DecryptPollVote(ctx context.Context, msg *events.Message) (PollVote, error) {
+	return PollVote{}, ErrOffline
+}
+
+func (Offline) Ping(ctx context.Context) (time.Duration, error) { return 0, ErrOffline }
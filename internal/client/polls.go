@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// MaxPollOptions is the most options WhatsApp lets a poll have.
+const MaxPollOptions = 12
+
+// Poll is a poll as created.
+type Poll struct {
+	Question string
+	Options  []string
+	// SelectableCount is how many options a voter may pick, 0 meaning any
+	// number.
+	SelectableCount int
+}
+
+// PollVote is a vote cast in a poll.
+type PollVote struct {
+	// PollID is the ID of the poll's message.
+	PollID string
+	// OptionHashes identify the options voted for; see PollOptionHash. A
+	// vote without options retracts the voter's earlier one.
+	OptionHashes []string
+}
+
+// PollOptionHash is how votes refer to the option of a poll: the hex SHA-256
+// of its name.
+func PollOptionHash(option string) string {
+	sum := sha256.Sum256([]byte(option))
+	return hex.EncodeToString(sum[:])
+}
+
+// SendPoll sends a poll and returns the ID of its message. Votes on it are
+// encrypted with a secret whatsmeow keeps in whatsapp.db.
+func (w *WAClient) SendPoll(ctx context.Context, recipient string, poll Poll) (string, error) {
+	if !w.client.IsConnected() {
+		return "", fmt.Errorf("not connected to WhatsApp")
+	}
+	recipientJID, err := parseJID(recipient)
+	if err != nil {
+		return "", err
+	}
+	resp, err := w.client.SendMessage(ctx, recipientJID, w.client.BuildPollCreation(poll.Question, poll.Options, poll.SelectableCount))
+	if err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// DecryptPollVote decrypts the vote of a poll update message.
+func (w *WAClient) DecryptPollVote(ctx context.Context, msg *events.Message) (PollVote, error) {
+	vote, err := w.client.DecryptPollVote(ctx, msg)
+	if err != nil {
+		return PollVote{}, err
+	}
+	return pollVote(msg.Message.GetPollUpdateMessage(), vote.GetSelectedOptions()), nil
+}
+
+func pollVote(update *waProto.PollUpdateMessage, selected [][]byte) PollVote {
+	v := PollVote{PollID: update.GetPollCreationMessageKey().GetID(), OptionHashes: []string{}}
+	for _, hash := range selected {
+		v.OptionHashes = append(v.OptionHashes, hex.EncodeToString(hash))
+	}
+	return v
+}
+
+// PollCreation returns the poll a message creates, whichever version of
+// the poll message it uses, or nil.
+func PollCreation(m *waProto.Message) *Poll {
+	pc := m.GetPollCreationMessage()
+	for _, v := range []*waProto.PollCreationMessage{m.GetPollCreationMessageV2(), m.GetPollCreationMessageV3(), m.GetPollCreationMessageV5()} {
+		if pc == nil {
+			pc = v
+		}
+	}
+	if pc == nil {
+		return nil
+	}
+	poll := &Poll{Question: pc.GetName(), SelectableCount: int(pc.GetSelectableOptionsCount())}
+	for _, o := range pc.GetOptions() {
+		poll.Options = append(poll.Options, o.GetOptionName())
+	}
+	return poll
+}
@@ -0,0 +1,339 @@
+package client
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/keychain"
+)
+
+// SessionFormatVersion is the version of session exports written by
+// ExportSession; ImportSession refuses others.
+const SessionFormatVersion = 1
+
+// SessionFenceFile is written to a store directory whose session was
+// exported. Connecting from it fails from then on: the session now belongs
+// to the host it was imported on, and two hosts using it would keep
+// replacing each other's connection and corrupt its encryption state.
+const SessionFenceFile = "session-exported.json"
+
+// Names of the files in a session export, a tar archive.
+const (
+	sessionManifestName = "session.json"
+	sessionDBName       = "whatsapp.db"
+)
+
+// SessionManifest describes a session export.
+type SessionManifest struct {
+	Version    int       `json:"version"`
+	ExportID   string    `json:"export_id"`
+	DeviceJID  string    `json:"device_jid"`
+	Host       string    `json:"host"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+// SessionFence records where a store's session went.
+type SessionFence struct {
+	SessionManifest
+	Output string `json:"output"`
+}
+
+// ErrSessionFenced is returned by Connect for a store whose session was
+// exported.
+var ErrSessionFenced = errors.New("session was exported")
+
+// ExportSession writes the linked device session of storeDir — whatsapp.db
+// with the device's keys, taken from the keychain for that credential
+// backend — to dst as a tar archive. The store must not be in use.
+func ExportSession(ctx context.Context, storeDir, credentialBackend string, dst io.Writer) (SessionManifest, error) {
+	kc, err := sessionKeychain(credentialBackend)
+	if err != nil {
+		return SessionManifest{}, err
+	}
+	return exportSession(ctx, storeDir, kc, dst)
+}
+
+// sessionKeychain opens the keychain holding the device keys for a
+// credential backend; the file backend has none.
+func sessionKeychain(credentialBackend string) (keychain.Keychain, error) {
+	switch credentialBackend {
+	case "", CredentialBackendFile:
+		return nil, nil
+	case CredentialBackendKeychain:
+		kc, err := keychain.Open(keychainService)
+		if err != nil {
+			return nil, fmt.Errorf("credential backend %q: %w", credentialBackend, err)
+		}
+		return kc, nil
+	default:
+		return nil, fmt.Errorf("unknown credential backend %q (expected %q or %q)", credentialBackend, CredentialBackendFile, CredentialBackendKeychain)
+	}
+}
+
+func exportSession(ctx context.Context, storeDir string, kc keychain.Keychain, dst io.Writer) (SessionManifest, error) {
+	if err := checkSessionFence(storeDir); err != nil {
+		return SessionManifest{}, err
+	}
+	deviceJID, err := sessionDevice(ctx, filepath.Join(storeDir, sessionDBName))
+	if err != nil {
+		return SessionManifest{}, err
+	}
+	if deviceJID == "" {
+		return SessionManifest{}, fmt.Errorf("no paired session in %s", storeDir)
+	}
+
+	// A consistent copy, even with a WAL, to put the keys back into
+	snapshot := filepath.Join(storeDir, fmt.Sprintf(".session-%d.db", time.Now().UnixNano()))
+	defer os.Remove(snapshot)
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s", filepath.Join(storeDir, sessionDBName)))
+	if err != nil {
+		return SessionManifest{}, err
+	}
+	_, err = db.ExecContext(ctx, `VACUUM INTO ?`, snapshot)
+	db.Close()
+	if err != nil {
+		return SessionManifest{}, fmt.Errorf("failed to copy whatsapp.db: %w", err)
+	}
+	if kc != nil {
+		if err := restoreKeys(ctx, snapshot, deviceJID, kc); err != nil {
+			return SessionManifest{}, err
+		}
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return SessionManifest{}, err
+	}
+	host, _ := os.Hostname()
+	m := SessionManifest{
+		Version:    SessionFormatVersion,
+		ExportID:   hex.EncodeToString(id),
+		DeviceJID:  deviceJID,
+		Host:       host,
+		ExportedAt: time.Now().UTC(),
+	}
+	manifest, err := json.Marshal(m)
+	if err != nil {
+		return m, err
+	}
+	data, err := os.ReadFile(snapshot)
+	if err != nil {
+		return m, err
+	}
+
+	tw := tar.NewWriter(dst)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{{sessionManifestName, manifest}, {sessionDBName, data}} {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0600, Size: int64(len(f.data)), ModTime: m.ExportedAt}); err != nil {
+			return m, err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return m, err
+		}
+	}
+	return m, tw.Close()
+}
+
+// restoreKeys writes the device keys kept in the keychain into a copy of
+// whatsapp.db, whose own keys are zeroed.
+func restoreKeys(ctx context.Context, dbPath, deviceJID string, kc keychain.Keychain) error {
+	blob, err := kc.Get(deviceJID)
+	if errors.Is(err, keychain.ErrNotFound) {
+		// Not moved to the keychain yet; whatsapp.db has the keys
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read device keys from keychain: %w", err)
+	}
+	var secrets deviceSecrets
+	if err := json.Unmarshal(blob, &secrets); err != nil || !secrets.valid() {
+		return fmt.Errorf("invalid device keys in keychain for %s", deviceJID)
+	}
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s", dbPath))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.ExecContext(ctx,
+		`UPDATE whatsmeow_device SET noise_key = ?, identity_key = ?, signed_pre_key = ?, adv_key = ? WHERE jid = ?`,
+		secrets.NoiseKey, secrets.IdentityKey, secrets.SignedPreKey, secrets.AdvSecretKey, deviceJID)
+	return err
+}
+
+// sessionDevice returns the JID of the device paired in a whatsapp.db, or
+// "" if there is none.
+func sessionDevice(ctx context.Context, dbPath string) (string, error) {
+	if _, err := os.Stat(dbPath); errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro", dbPath))
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	var tables int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'whatsmeow_device'`).Scan(&tables); err != nil || tables == 0 {
+		return "", err
+	}
+	var deviceJID string
+	err = db.QueryRowContext(ctx, `SELECT jid FROM whatsmeow_device LIMIT 1`).Scan(&deviceJID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return deviceJID, err
+}
+
+// ImportSession installs a session exported by ExportSession into storeDir.
+// A store that already has a paired session is only overwritten with force.
+// Importing lifts the store's fence, so a session can be moved back to the
+// host it was exported from.
+func ImportSession(ctx context.Context, storeDir string, src io.Reader, force bool) (SessionManifest, error) {
+	var m SessionManifest
+	if err := os.MkdirAll(storeDir, 0755); err != nil {
+		return m, err
+	}
+	dbPath := filepath.Join(storeDir, sessionDBName)
+	existing, err := sessionDevice(ctx, dbPath)
+	if err != nil {
+		return m, fmt.Errorf("failed to read %s: %w", dbPath, err)
+	}
+	if existing != "" && !force {
+		return m, fmt.Errorf("%s already has a paired session (%s); use --force to replace it", storeDir, existing)
+	}
+
+	tmp, err := os.CreateTemp(storeDir, ".session-*.db")
+	if err != nil {
+		return m, err
+	}
+	defer os.Remove(tmp.Name())
+	var haveManifest, haveDB bool
+	tr := tar.NewReader(src)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			tmp.Close()
+			return m, fmt.Errorf("invalid session export: %w", err)
+		}
+		switch hdr.Name {
+		case sessionManifestName:
+			if err := json.NewDecoder(tr).Decode(&m); err != nil {
+				tmp.Close()
+				return m, fmt.Errorf("invalid session manifest: %w", err)
+			}
+			haveManifest = true
+		case sessionDBName:
+			if _, err := io.Copy(tmp, tr); err != nil {
+				tmp.Close()
+				return m, err
+			}
+			haveDB = true
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return m, err
+	}
+	switch {
+	case !haveManifest || !haveDB:
+		return m, fmt.Errorf("invalid session export: missing %s or %s", sessionManifestName, sessionDBName)
+	case m.Version != SessionFormatVersion:
+		return m, fmt.Errorf("unsupported session export version %d, want %d", m.Version, SessionFormatVersion)
+	}
+	imported, err := sessionDevice(ctx, tmp.Name())
+	if err != nil || imported == "" {
+		return m, fmt.Errorf("invalid session export: no paired device in %s", sessionDBName)
+	}
+
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return m, err
+		}
+	}
+	if err := os.Rename(tmp.Name(), dbPath); err != nil {
+		return m, err
+	}
+	if err := os.Remove(filepath.Join(storeDir, SessionFenceFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return m, err
+	}
+	return m, nil
+}
+
+// FenceSession records that the session of storeDir was exported to output,
+// after which Connect refuses to use it, then removes the exported device
+// from the store: its row in whatsapp.db with the keys, sessions and
+// pre-keys that cascade from it, and its keys in the keychain of that
+// credential backend. The store can then no longer connect as the device
+// even if the fence file is deleted or a backup of it is missing the fence.
+func FenceSession(ctx context.Context, storeDir, credentialBackend string, m SessionManifest, output string) error {
+	kc, err := sessionKeychain(credentialBackend)
+	if err != nil {
+		return err
+	}
+	return fenceSession(ctx, storeDir, kc, m, output)
+}
+
+func fenceSession(ctx context.Context, storeDir string, kc keychain.Keychain, m SessionManifest, output string) error {
+	data, err := json.MarshalIndent(SessionFence{SessionManifest: m, Output: output}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(storeDir, SessionFenceFile), append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on", filepath.Join(storeDir, sessionDBName)))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(ctx, `DELETE FROM whatsmeow_device WHERE jid = ?`, m.DeviceJID); err != nil {
+		return fmt.Errorf("failed to remove device %s from whatsapp.db: %w", m.DeviceJID, err)
+	}
+	if kc != nil {
+		if err := kc.Delete(m.DeviceJID); err != nil {
+			return fmt.Errorf("failed to remove device keys from keychain: %w", err)
+		}
+	}
+	return nil
+}
+
+// ReadSessionFence returns the fence of storeDir; an error wrapping
+// os.ErrNotExist means the store's session was not exported.
+func ReadSessionFence(storeDir string) (SessionFence, error) {
+	var f SessionFence
+	data, err := os.ReadFile(filepath.Join(storeDir, SessionFenceFile))
+	if err != nil {
+		return f, err
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return f, fmt.Errorf("invalid %s: %w", SessionFenceFile, err)
+	}
+	return f, nil
+}
+
+// checkSessionFence returns an error wrapping ErrSessionFenced if the
+// session of storeDir was exported.
+func checkSessionFence(storeDir string) error {
+	f, err := ReadSessionFence(storeDir)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil
+	case err != nil:
+		return fmt.Errorf("%w from %s: %v", ErrSessionFenced, storeDir, err)
+	}
+	return fmt.Errorf("%w from %s on %s (export %s to %s): it belongs to the host it was imported on; import a session here to use this store again",
+		ErrSessionFenced, storeDir, f.ExportedAt.Format(time.RFC3339), f.ExportID, f.Output)
+}
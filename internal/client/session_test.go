@@ -0,0 +1,131 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/keychain"
+	"go.mau.fi/whatsmeow/proto/waAdv"
+	"go.mau.fi/whatsmeow/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// pairTestDevice saves a paired device into storeDir/whatsapp.db, with its
+// keys in kc if kc is set.
+func pairTestDevice(t *testing.T, storeDir string, kc keychain.Keychain) *store.Device {
+	t.Helper()
+	ctx := context.Background()
+	container, db := openTestContainer(t, filepath.Join(storeDir, "whatsapp.db"))
+	device := container.NewDevice()
+	if kc != nil {
+		var err error
+		device, err = (&keychainContainer{Container: container, db: db, kc: kc}).loadDevice(ctx)
+		require.NoError(t, err)
+	}
+	jid := types.NewADJID("4915112345678", 0, 1)
+	device.ID = &jid
+	device.Account = &waAdv.ADVSignedDeviceIdentity{Details: []byte{1}, AccountSignature: make([]byte, 64), AccountSignatureKey: make([]byte, 32), DeviceSignature: make([]byte, 64)}
+	require.NoError(t, device.Save(ctx))
+	return device
+}
+
+func TestSessionExportImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	from, to := t.TempDir(), t.TempDir()
+	kc := keychain.Memory{}
+	device := pairTestDevice(t, from, kc)
+
+	var buf bytes.Buffer
+	m, err := exportSession(ctx, from, kc, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, SessionFormatVersion, m.Version)
+	assert.Equal(t, device.ID.String(), m.DeviceJID)
+	assert.NotEmpty(t, m.ExportID)
+
+	imported, err := ImportSession(ctx, to, &buf, false)
+	require.NoError(t, err)
+	assert.Equal(t, m, imported)
+
+	// The keys kept in the keychain travel with the export, so the new host
+	// can use the file backend
+	container, _ := openTestContainer(t, filepath.Join(to, "whatsapp.db"))
+	restored, err := container.GetFirstDevice(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, restored.ID)
+	assert.Equal(t, device.ID.String(), restored.ID.String())
+	assert.Equal(t, device.NoiseKey.Priv, restored.NoiseKey.Priv)
+	assert.Equal(t, device.IdentityKey.Priv, restored.IdentityKey.Priv)
+}
+
+func TestSessionExportRequiresPairedDevice(t *testing.T) {
+	_, err := exportSession(context.Background(), t.TempDir(), nil, &bytes.Buffer{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no paired session")
+}
+
+func TestSessionFenceBlocksExportUntilImport(t *testing.T) {
+	ctx := context.Background()
+	from := t.TempDir()
+	kc := keychain.Memory{}
+	pairTestDevice(t, from, kc)
+
+	var buf bytes.Buffer
+	m, err := exportSession(ctx, from, kc, &buf)
+	require.NoError(t, err)
+	require.NoError(t, fenceSession(ctx, from, kc, m, "/backup/session.enc"))
+
+	// The device and its keys are gone, not only marked as exported
+	deviceJID, err := sessionDevice(ctx, filepath.Join(from, "whatsapp.db"))
+	require.NoError(t, err)
+	assert.Empty(t, deviceJID)
+	_, err = kc.Get(m.DeviceJID)
+	assert.True(t, errors.Is(err, keychain.ErrNotFound))
+
+	fence, err := ReadSessionFence(from)
+	require.NoError(t, err)
+	assert.Equal(t, m.ExportID, fence.ExportID)
+	assert.Equal(t, "/backup/session.enc", fence.Output)
+
+	err = checkSessionFence(from)
+	require.True(t, errors.Is(err, ErrSessionFenced))
+	assert.Contains(t, err.Error(), m.ExportID)
+	_, err = exportSession(ctx, from, nil, &bytes.Buffer{})
+	assert.True(t, errors.Is(err, ErrSessionFenced))
+
+	// Moving the session back lifts the fence
+	_, err = ImportSession(ctx, from, bytes.NewReader(buf.Bytes()), false)
+	require.NoError(t, err)
+	assert.NoError(t, checkSessionFence(from))
+	_, err = os.Stat(filepath.Join(from, SessionFenceFile))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestSessionImportKeepsPairedSessionWithoutForce(t *testing.T) {
+	ctx := context.Background()
+	from, to := t.TempDir(), t.TempDir()
+	pairTestDevice(t, from, nil)
+	pairTestDevice(t, to, nil)
+
+	var buf bytes.Buffer
+	_, err := exportSession(ctx, from, nil, &buf)
+	require.NoError(t, err)
+
+	_, err = ImportSession(ctx, to, bytes.NewReader(buf.Bytes()), false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--force")
+
+	_, err = ImportSession(ctx, to, bytes.NewReader(buf.Bytes()), true)
+	assert.NoError(t, err)
+}
+
+func TestSessionImportRejectsInvalidExport(t *testing.T) {
+	_, err := ImportSession(context.Background(), t.TempDir(), bytes.NewReader([]byte("not a tar")), false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid session export")
+}
@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// Limits for ad hoc admin queries.
+const (
+	AdminQueryDefaultRows = 100
+	AdminQueryMaxRows     = 1000
+	AdminQueryTimeout     = 5 * time.Second
+)
+
+// AdminQuery runs a validated read-only SQL query against the message
+// database, returning at most limit rows and giving up after AdminQueryTimeout.
+func (a *App) AdminQuery(ctx context.Context, query string, limit int) string {
+	if limit <= 0 {
+		limit = AdminQueryDefaultRows
+	}
+	if limit > AdminQueryMaxRows {
+		limit = AdminQueryMaxRows
+	}
+	ctx, cancel := context.WithTimeout(ctx, AdminQueryTimeout)
+	defer cancel()
+
+	result, err := a.store.ReadOnlyQuery(ctx, query, limit)
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(result)
+}
+
+// AdminSchema describes the tables and columns of the message database.
+func (a *App) AdminSchema() string {
+	tables, err := a.store.Schema()
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(tables)
+}
+
+// DatabaseSnapshot writes an online backup of the message database to a
+// temporary file in the store directory and returns its path. The caller
+// removes the file when done.
+func (a *App) DatabaseSnapshot(ctx context.Context) (string, error) {
+	f, err := os.CreateTemp(a.storeDir, "snapshot-*.db")
+	if err != nil {
+		return "", fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	path := f.Name()
+	f.Close()
+
+	if err := a.store.Snapshot(ctx, path); err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to snapshot database: %w", err)
+	}
+	return path, nil
+}
+
+// AnonymizedSnapshot writes a copy of the message database with phone
+// numbers, JIDs and names replaced by stable pseudonyms, and returns its
+// path. An empty salt uses a random one, so pseudonyms are only consistent
+// within this copy. The caller removes the file when done.
+func (a *App) AnonymizedSnapshot(ctx context.Context, salt string, dropContent bool) (string, error) {
+	path, err := a.DatabaseSnapshot(ctx)
+	if err != nil {
+		return "", err
+	}
+	key := []byte(salt)
+	if salt == "" {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			os.Remove(path)
+			return "", err
+		}
+	}
+	if _, err := store.AnonymizeDatabase(ctx, path, store.AnonymizeOptions{Salt: key, DropContent: dropContent}); err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to anonymize database: %w", err)
+	}
+	return path, nil
+}
+
+// Anonymize writes an anonymized copy of the message database to outputPath.
+func (a *App) Anonymize(ctx context.Context, outputPath, salt string, dropContent bool) string {
+	if outputPath == "" {
+		return output.Error(fmt.Errorf("--output is required"))
+	}
+	if _, err := os.Stat(outputPath); err == nil {
+		return output.Error(fmt.Errorf("%s already exists", outputPath))
+	}
+	path, err := a.AnonymizedSnapshot(ctx, salt, dropContent)
+	if err != nil {
+		return output.Error(err)
+	}
+	if err := moveFile(path, outputPath); err != nil {
+		os.Remove(path)
+		return output.Error(err)
+	}
+	return output.Success(map[string]interface{}{
+		"output":          outputPath,
+		"stable_salt":     salt != "",
+		"content_dropped": dropContent,
+	})
+}
+
+// moveFile renames src to dst, copying when they are on different devices.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
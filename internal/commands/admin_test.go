@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseSnapshot(t *testing.T) {
+	app, _ := newFakeApp(t)
+	require.NoError(t, app.store.StoreChat("123@s.whatsapp.net", "Alice", time.Now()))
+
+	path, err := app.DatabaseSnapshot(context.Background())
+	require.NoError(t, err)
+	defer os.Remove(path)
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+	var name string
+	require.NoError(t, db.QueryRow(`SELECT name FROM chats WHERE jid = ?`, "123@s.whatsapp.net").Scan(&name))
+	assert.Equal(t, "Alice", name)
+}
+
+func TestAnonymize(t *testing.T) {
+	app, _ := newFakeApp(t)
+	require.NoError(t, app.store.StoreChat("34600111222@s.whatsapp.net", "Alice", time.Now()))
+
+	out := filepath.Join(t.TempDir(), "anon.db")
+	result := app.Anonymize(context.Background(), out, "salt", false)
+	assert.Contains(t, result, `"success":true`)
+
+	db, err := sql.Open("sqlite3", out)
+	require.NoError(t, err)
+	defer db.Close()
+	var jid, name string
+	require.NoError(t, db.QueryRow(`SELECT jid, name FROM chats`).Scan(&jid, &name))
+	assert.NotEqual(t, "34600111222@s.whatsapp.net", jid)
+	assert.NotEqual(t, "Alice", name)
+
+	result = app.Anonymize(context.Background(), out, "salt", false)
+	assert.Contains(t, result, "already exists")
+}
@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/eventbus"
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// Account alert kinds.
+const (
+	AlertTemporaryBan   = "temporary_ban"
+	AlertLoggedOut      = "logged_out"
+	AlertClientOutdated = "client_outdated"
+	AlertStreamReplaced = "stream_replaced"
+	AlertConnectFailure = "connect_failure"
+	AlertStreamError    = "stream_error"
+	AlertRateLimited    = "rate_limited"
+)
+
+// rateLimitAlertTTL is how long a rate limit warning stays active. Unlike
+// the other alerts, it is not cleared by reconnecting.
+const rateLimitAlertTTL = time.Hour
+
+// accountAlertFor returns the alert a connection event signals, if any.
+func accountAlertFor(evt interface{}, now time.Time) (store.AccountAlert, bool) {
+	alert := store.AccountAlert{Severity: store.AlertCritical, CreatedAt: now.UTC()}
+	switch v := evt.(type) {
+	case *events.TemporaryBan:
+		alert.Kind, alert.Code, alert.Reason = AlertTemporaryBan, strconv.Itoa(int(v.Code)), v.String()
+		if v.Expire > 0 {
+			expires := alert.CreatedAt.Add(v.Expire)
+			alert.ExpiresAt = &expires
+		}
+	case *events.LoggedOut:
+		alert.Kind, alert.Reason = AlertLoggedOut, "logged out from the phone"
+		if v.OnConnect {
+			alert.Code, alert.Reason = v.Reason.NumberString(), "logged out: "+v.Reason.String()
+		}
+	case *events.ClientOutdated:
+		alert.Kind, alert.Code, alert.Reason = AlertClientOutdated, "405", "WhatsApp rejected this client as outdated; update whatsapp-cli"
+	case *events.StreamReplaced:
+		alert.Kind, alert.Reason = AlertStreamReplaced, "another client connected with this session"
+	case *events.ConnectFailure:
+		alert.Kind, alert.Code = AlertConnectFailure, v.Reason.NumberString()
+		alert.Reason = "connect failure: " + v.Reason.String()
+		if v.Message != "" {
+			alert.Reason += " (" + v.Message + ")"
+		}
+		if v.Reason >= 500 {
+			alert.Severity = store.AlertWarning
+		}
+	case *events.StreamError:
+		alert.Kind, alert.Severity, alert.Code = AlertStreamError, store.AlertWarning, v.Code
+		alert.Reason = "stream error " + v.Code
+	default:
+		return store.AccountAlert{}, false
+	}
+	return alert, true
+}
+
+// isRateLimited reports whether WhatsApp refused a send for going over its
+// rate limit, a common precursor of a ban.
+func isRateLimited(err error) bool {
+	return errors.Is(err, whatsmeow.ErrIQRateOverLimit) ||
+		(errors.Is(err, whatsmeow.ErrServerReturnedError) && strings.HasSuffix(err.Error(), " 429"))
+}
+
+// checkSendError raises a rate limit warning if err says sends are throttled.
+func (a *App) checkSendError(err error) {
+	if err == nil || !isRateLimited(err) {
+		return
+	}
+	now := time.Now().UTC()
+	expires := now.Add(rateLimitAlertTTL)
+	a.raiseAccountAlert(store.AccountAlert{
+		Kind:      AlertRateLimited,
+		Severity:  store.AlertWarning,
+		Code:      "429",
+		Reason:    "WhatsApp is rate limiting sends: " + err.Error(),
+		CreatedAt: now,
+		ExpiresAt: &expires,
+	})
+}
+
+// raiseAccountAlert persists an alert and pushes it to the event bus and
+// notifier with high priority. An alert of the same kind and code that is
+// still active is not raised again.
+func (a *App) raiseAccountAlert(alert store.AccountAlert) {
+	active, err := a.store.ActiveAccountAlerts(alert.CreatedAt)
+	if err == nil {
+		for _, other := range active {
+			if other.Kind == alert.Kind && other.Code == alert.Code {
+				return
+			}
+		}
+	}
+	if _, err := a.store.StoreAccountAlert(alert); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠ Failed to store account alert: %v\n", err)
+	}
+	fmt.Fprintf(os.Stderr, "\n🚨 Account %s: %s\n", alert.Severity, alert.Reason)
+	a.publishEvent(eventbus.Event{
+		Type:      eventbus.TypeAccountAlert,
+		Timestamp: alert.CreatedAt,
+		Priority:  eventbus.PriorityHigh,
+		Alert: &eventbus.AccountAlert{
+			Kind:      alert.Kind,
+			Severity:  alert.Severity,
+			Code:      alert.Code,
+			Reason:    alert.Reason,
+			ExpiresAt: alert.ExpiresAt,
+		},
+	})
+}
+
+// resolveAccountAlerts clears the active alerts once the account connects,
+// except rate limit warnings, which expire on their own.
+func (a *App) resolveAccountAlerts() {
+	n, err := a.store.ResolveAccountAlerts(time.Now(), AlertRateLimited)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠ Failed to resolve account alerts: %v\n", err)
+	} else if n > 0 {
+		fmt.Fprintf(os.Stderr, "✓ Cleared %d account alert(s)\n", n)
+	}
+}
+
+// AccountAlerts returns the active account alerts, critical ones first. A
+// store error is reported as no alerts.
+func (a *App) AccountAlerts() []store.AccountAlert {
+	alerts, err := a.store.ActiveAccountAlerts(time.Now())
+	if err != nil {
+		return nil
+	}
+	return alerts
+}
+
+// ListAccountAlerts returns the latest account alerts, newest first.
+func (a *App) ListAccountAlerts(limit int) string {
+	alerts, err := a.store.ListAccountAlerts(limit)
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(alerts)
+}
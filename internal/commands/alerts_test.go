@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/eventbus"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestAccountAlertsRaisedAndResolved(t *testing.T) {
+	app, fake := newFakeApp(t)
+	notified := &recordingPublisher{}
+	app.SetNotifier(eventbus.NewBus(notified, eventbus.FormatJSON, "wa"))
+	startSync(t, app, fake)
+
+	fake.Emit(&events.StreamError{Code: "503"})
+	fake.Emit(&events.TemporaryBan{Code: events.TempBanSentToTooManyPeople, Expire: 24 * time.Hour})
+	fake.Emit(&events.TemporaryBan{Code: events.TempBanSentToTooManyPeople, Expire: 23 * time.Hour})
+
+	require.Eventually(t, func() bool {
+		topics, _ := notified.snapshot()
+		return len(topics) == 2
+	}, time.Second, 5*time.Millisecond)
+	topics, evts := notified.snapshot()
+	assert.Equal(t, []string{"wa.alerts", "wa.alerts"}, topics)
+	assert.Equal(t, eventbus.PriorityHigh, evts[1].Priority)
+	require.NotNil(t, evts[1].Alert)
+	assert.Equal(t, AlertTemporaryBan, evts[1].Alert.Kind)
+	assert.Equal(t, store.AlertCritical, evts[1].Alert.Severity)
+	assert.Equal(t, "101", evts[1].Alert.Code)
+	require.NotNil(t, evts[1].Alert.ExpiresAt)
+
+	active := app.AccountAlerts()
+	require.Len(t, active, 2, "the repeated ban is not raised again")
+	assert.Equal(t, AlertTemporaryBan, active[0].Kind, "critical first")
+	assert.Equal(t, AlertStreamError, active[1].Kind)
+
+	// Connecting again clears them
+	fake.Disconnect()
+	require.NoError(t, fake.Connect(context.Background()))
+	require.Eventually(t, func() bool { return len(app.AccountAlerts()) == 0 }, time.Second, 5*time.Millisecond)
+
+	var resp struct {
+		Data []store.AccountAlert `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(app.ListAccountAlerts(10)), &resp))
+	require.Len(t, resp.Data, 2)
+	assert.NotNil(t, resp.Data[0].ResolvedAt)
+}
+
+func TestAccountAlertFor(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	alert, ok := accountAlertFor(&events.LoggedOut{OnConnect: true, Reason: events.ConnectFailureMainDeviceGone}, now)
+	require.True(t, ok)
+	assert.Equal(t, store.AccountAlert{Kind: AlertLoggedOut, Severity: store.AlertCritical, Code: "403", Reason: "logged out: 403: primary device was logged out", CreatedAt: now}, alert)
+
+	alert, ok = accountAlertFor(&events.ConnectFailure{Reason: events.ConnectFailureServiceUnavailable}, now)
+	require.True(t, ok)
+	assert.Equal(t, store.AlertWarning, alert.Severity, "server errors are transient")
+
+	_, ok = accountAlertFor(&events.Connected{}, now)
+	assert.False(t, ok)
+}
+
+func TestSendRateLimitRaisesWarning(t *testing.T) {
+	assert.True(t, isRateLimited(whatsmeow.ErrIQRateOverLimit))
+	assert.True(t, isRateLimited(fmt.Errorf("%w %d", whatsmeow.ErrServerReturnedError, 429)))
+	assert.False(t, isRateLimited(fmt.Errorf("%w %d", whatsmeow.ErrServerReturnedError, 479)))
+	assert.False(t, isRateLimited(errors.New("not connected to WhatsApp")))
+
+	app, _ := newFakeApp(t)
+	app.checkSendError(fmt.Errorf("%w %d", whatsmeow.ErrServerReturnedError, 429))
+	app.checkSendError(whatsmeow.ErrIQRateOverLimit)
+	active := app.AccountAlerts()
+	require.Len(t, active, 1)
+	assert.Equal(t, AlertRateLimited, active[0].Kind)
+	assert.Equal(t, store.AlertWarning, active[0].Severity)
+	require.NotNil(t, active[0].ExpiresAt)
+
+	// Reconnecting does not clear it; it expires
+	app.resolveAccountAlerts()
+	assert.Len(t, app.AccountAlerts(), 1)
+}
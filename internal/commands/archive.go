@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// ExportArchive writes the whole message database to outputPath as a
+// compressed protobuf archive for cold storage; ImportArchive loads it back.
+func (a *App) ExportArchive(ctx context.Context, outputPath string) string {
+	if outputPath == "" {
+		return output.Error(fmt.Errorf("--output is required"))
+	}
+	if _, err := os.Stat(outputPath); err == nil {
+		return output.Error(fmt.Errorf("%s already exists", outputPath))
+	}
+
+	// Write next to the target and rename, so a failed export never leaves a
+	// truncated archive behind
+	f, err := os.CreateTemp(filepath.Dir(outputPath), ".archive-*.tmp")
+	if err != nil {
+		return output.Error(fmt.Errorf("failed to create output file: %w", err))
+	}
+	defer os.Remove(f.Name())
+
+	tables, err := a.store.ExportArchive(ctx, f, archiveProgress("Archiving"))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return output.Error(err)
+	}
+	if err := os.Rename(f.Name(), outputPath); err != nil {
+		return output.Error(err)
+	}
+	info, err := os.Stat(outputPath)
+	if err != nil {
+		return output.Error(err)
+	}
+
+	var rows int64
+	for _, t := range tables {
+		rows += t.Rows
+	}
+	return output.Success(map[string]interface{}{
+		"output": outputPath,
+		"bytes":  info.Size(),
+		"tables": tables,
+		"rows":   rows,
+	})
+}
+
+// ImportArchive loads an archive written by ExportArchive into the store.
+// The import is all or nothing; rows already in the store are kept.
+func (a *App) ImportArchive(ctx context.Context, inputPath string) string {
+	if inputPath == "" {
+		return output.Error(fmt.Errorf("--input is required"))
+	}
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return output.Error(err)
+	}
+	defer f.Close()
+
+	tables, err := a.store.ImportArchive(ctx, f, archiveProgress("Importing"))
+	if err != nil {
+		return output.Error(err)
+	}
+	var rows, imported int64
+	for _, t := range tables {
+		rows += t.Rows
+		if t.Imported != nil {
+			imported += *t.Imported
+		}
+	}
+	return output.Success(map[string]interface{}{
+		"input":    inputPath,
+		"tables":   tables,
+		"rows":     rows,
+		"imported": imported,
+	})
+}
+
+func archiveProgress(verb string) store.ExportProgress {
+	return func(table string, done, total int64) {
+		fmt.Fprintf(os.Stderr, "\r📦 %s %s: %d/%d rows", verb, table, done, total)
+		if done == total {
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveExportImport(t *testing.T) {
+	src, _ := newFakeApp(t)
+	require.NoError(t, src.store.StoreChat("123@s.whatsapp.net", "Alice", time.Now()))
+	require.NoError(t, src.store.StoreMessage("m1", "123@s.whatsapp.net", "123", "hi", time.Now(), false, "", "", "", "", "", nil, nil, nil, 0))
+
+	out := filepath.Join(t.TempDir(), "store.archive")
+	var exported struct {
+		Success bool `json:"success"`
+		Data    struct {
+			Output string `json:"output"`
+			Bytes  int64  `json:"bytes"`
+			Rows   int64  `json:"rows"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(src.ExportArchive(context.Background(), out)), &exported))
+	require.True(t, exported.Success)
+	assert.Equal(t, int64(2), exported.Data.Rows)
+	assert.Positive(t, exported.Data.Bytes)
+	entries, err := os.ReadDir(filepath.Dir(out))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary file left behind")
+	assert.Contains(t, src.ExportArchive(context.Background(), out), "already exists")
+
+	dst, _ := newFakeApp(t)
+	var imported struct {
+		Success bool `json:"success"`
+		Data    struct {
+			Rows     int64 `json:"rows"`
+			Imported int64 `json:"imported"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(dst.ImportArchive(context.Background(), out)), &imported))
+	require.True(t, imported.Success)
+	assert.Equal(t, int64(2), imported.Data.Imported)
+	assert.Contains(t, dst.ListMessages(nil, nil, 10, 0, nil, nil, nil, nil, nil), `"content":"hi"`)
+
+	assert.Contains(t, dst.ImportArchive(context.Background(), ""), "--input is required")
+}
@@ -0,0 +1,194 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// awayWindow is a parsed store.AwayWindow.
+type awayWindow struct {
+	days  [7]bool
+	start time.Duration // offset from midnight
+	dur   time.Duration
+}
+
+func parseAwayWindow(w store.AwayWindow) (awayWindow, error) {
+	var pw awayWindow
+	if len(w.Days) == 0 {
+		for i := range pw.days {
+			pw.days[i] = true
+		}
+	}
+	for _, d := range w.Days {
+		wd, ok := weekdays[strings.ToLower(d)]
+		if !ok {
+			return awayWindow{}, fmt.Errorf("invalid day %q (use mon, tue, wed, thu, fri, sat, sun)", d)
+		}
+		pw.days[wd] = true
+	}
+
+	start, err := parseClock(w.Start, false)
+	if err != nil {
+		return awayWindow{}, fmt.Errorf("invalid start %q: %w", w.Start, err)
+	}
+	end, err := parseClock(w.End, true)
+	if err != nil {
+		return awayWindow{}, fmt.Errorf("invalid end %q: %w", w.End, err)
+	}
+	pw.start = start
+	pw.dur = end - start
+	if pw.dur <= 0 {
+		pw.dur += 24 * time.Hour
+	}
+	return pw, nil
+}
+
+// parseClock parses "HH:MM" into an offset from midnight. "24:00" is only
+// accepted as an end time.
+func parseClock(s string, allowEndOfDay bool) (time.Duration, error) {
+	h, m, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("expected HH:MM")
+	}
+	hours, err1 := strconv.Atoi(h)
+	minutes, err2 := strconv.Atoi(m)
+	if err1 != nil || err2 != nil || minutes < 0 || minutes > 59 || hours < 0 || hours > 24 {
+		return 0, fmt.Errorf("expected HH:MM")
+	}
+	if hours == 24 && (minutes != 0 || !allowEndOfDay) {
+		return 0, fmt.Errorf("expected HH:MM")
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
+
+// activeAwayWindow returns the start of the window occurrence containing t.
+func activeAwayWindow(windows []awayWindow, loc *time.Location, t time.Time) (time.Time, bool) {
+	local := t.In(loc)
+	for _, w := range windows {
+		// An occurrence that started yesterday may still be running
+		for back := 0; back <= 1; back++ {
+			day := local.AddDate(0, 0, -back)
+			if !w.days[day.Weekday()] {
+				continue
+			}
+			start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc).Add(w.start)
+			if !local.Before(start) && local.Before(start.Add(w.dur)) {
+				return start, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// validateAwayConfig checks an away configuration and returns its parsed
+// location and windows.
+func validateAwayConfig(cfg store.AwayConfig) (*time.Location, []awayWindow, error) {
+	if cfg.Enabled && strings.TrimSpace(cfg.Message) == "" {
+		return nil, nil, fmt.Errorf("message is required when the away message is enabled")
+	}
+	if cfg.Enabled && len(cfg.Windows) == 0 {
+		return nil, nil, fmt.Errorf("at least one window is required when the away message is enabled")
+	}
+	tz := cfg.Timezone
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid timezone %q", cfg.Timezone)
+	}
+	windows := make([]awayWindow, len(cfg.Windows))
+	for i, w := range cfg.Windows {
+		if windows[i], err = parseAwayWindow(w); err != nil {
+			return nil, nil, fmt.Errorf("window %d: %w", i+1, err)
+		}
+	}
+	return loc, windows, nil
+}
+
+// GetAwayConfig returns the away-message configuration.
+func (a *App) GetAwayConfig() string {
+	cfg, err := a.store.GetAwayConfig()
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(cfg)
+}
+
+// SetAwayConfig validates and stores the away-message configuration.
+func (a *App) SetAwayConfig(cfg store.AwayConfig) string {
+	if cfg.Timezone == "" {
+		cfg.Timezone = "UTC"
+	}
+	if _, _, err := validateAwayConfig(cfg); err != nil {
+		return output.Error(err)
+	}
+	if err := a.store.SaveAwayConfig(cfg); err != nil {
+		return output.Error(err)
+	}
+	return output.Success(cfg)
+}
+
+// SetAwayOptOut excludes a chat from (or re-includes it in) away messages.
+func (a *App) SetAwayOptOut(chatJID string, optOut bool) string {
+	if err := a.store.SetAwayOptOut(chatJID, optOut); err != nil {
+		return output.Error(err)
+	}
+	return output.Success(map[string]interface{}{
+		"chat_jid":  chatJID,
+		"opted_out": optOut,
+	})
+}
+
+// ListAwayOptOuts returns the chats excluded from away messages.
+func (a *App) ListAwayOptOuts() string {
+	jids, err := a.store.ListAwayOptOuts()
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(jids)
+}
+
+// maybeSendAway sends the away message for an incoming one-to-one message
+// received during an away window, at most once per contact and window.
+func (a *App) maybeSendAway(ctx context.Context, chatJID string, msgTime time.Time) {
+	if !strings.HasSuffix(chatJID, "@s.whatsapp.net") {
+		return
+	}
+	cfg, err := a.store.GetAwayConfig()
+	if err != nil || !cfg.Enabled {
+		return
+	}
+	loc, windows, err := validateAwayConfig(cfg)
+	if err != nil {
+		return
+	}
+	windowStart, ok := activeAwayWindow(windows, loc, msgTime)
+	if !ok {
+		return
+	}
+	if optedOut, err := a.store.IsAwayOptedOut(chatJID); err != nil || optedOut {
+		return
+	}
+
+	// Claim the reply before sending so concurrent messages cannot both send
+	claimed, err := a.store.RecordAwayReply(chatJID, windowStart, time.Now().UTC())
+	if err != nil || !claimed {
+		return
+	}
+	if err := a.sendAndStore(ctx, chatJID, cfg.Message); err != nil {
+		fmt.Fprintf(os.Stderr, "\n⚠ Failed to send away message to %s: %v\n", chatJID, err)
+	}
+}
@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client/fakeclient"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestActiveAwayWindow(t *testing.T) {
+	loc := time.UTC
+	evenings, err := parseAwayWindow(store.AwayWindow{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "18:00", End: "09:00"})
+	require.NoError(t, err)
+	weekend, err := parseAwayWindow(store.AwayWindow{Days: []string{"sat", "sun"}, Start: "00:00", End: "24:00"})
+	require.NoError(t, err)
+	windows := []awayWindow{evenings, weekend}
+
+	// 2024-06-03 is a Monday
+	tests := []struct {
+		name      string
+		at        time.Time
+		wantOK    bool
+		wantStart time.Time
+	}{
+		{"office hours", time.Date(2024, 6, 3, 12, 0, 0, 0, loc), false, time.Time{}},
+		{"monday evening", time.Date(2024, 6, 3, 20, 0, 0, 0, loc), true, time.Date(2024, 6, 3, 18, 0, 0, 0, loc)},
+		{"tuesday early morning", time.Date(2024, 6, 4, 8, 59, 0, 0, loc), true, time.Date(2024, 6, 3, 18, 0, 0, 0, loc)},
+		{"tuesday 9am", time.Date(2024, 6, 4, 9, 0, 0, 0, loc), false, time.Time{}},
+		{"saturday", time.Date(2024, 6, 8, 15, 0, 0, 0, loc), true, time.Date(2024, 6, 8, 0, 0, 0, 0, loc)},
+		{"saturday morning continues friday evening", time.Date(2024, 6, 8, 8, 0, 0, 0, loc), true, time.Date(2024, 6, 7, 18, 0, 0, 0, loc)},
+		{"monday morning not configured", time.Date(2024, 6, 3, 7, 0, 0, 0, loc), false, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, ok := activeAwayWindow(windows, loc, tt.at)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.True(t, tt.wantStart.Equal(start), "start %s", start)
+		})
+	}
+}
+
+func TestSetAwayConfigValidates(t *testing.T) {
+	app, _ := newFakeApp(t)
+
+	assert.Contains(t, app.SetAwayConfig(store.AwayConfig{Enabled: true, Windows: []store.AwayWindow{{Start: "18:00", End: "09:00"}}}), "message is required")
+	assert.Contains(t, app.SetAwayConfig(store.AwayConfig{Enabled: true, Message: "away"}), "at least one window")
+	assert.Contains(t, app.SetAwayConfig(store.AwayConfig{Message: "away", Timezone: "Mars/Base"}), "invalid timezone")
+	assert.Contains(t, app.SetAwayConfig(store.AwayConfig{Windows: []store.AwayWindow{{Start: "25:00", End: "09:00"}}}), "window 1: invalid start")
+	assert.Contains(t, app.SetAwayConfig(store.AwayConfig{Windows: []store.AwayWindow{{Days: []string{"someday"}, Start: "18:00", End: "09:00"}}}), "invalid day")
+
+	result := app.SetAwayConfig(store.AwayConfig{Enabled: true, Message: "away", Windows: []store.AwayWindow{{Start: "00:00", End: "24:00"}}})
+	assert.Contains(t, result, `"success":true`)
+	assert.Contains(t, app.GetAwayConfig(), `"timezone":"UTC"`)
+}
+
+func TestAwayMessageSentOncePerWindowAndRespectsOptOut(t *testing.T) {
+	app, fake := newFakeApp(t)
+	require.Contains(t, app.SetAwayConfig(store.AwayConfig{
+		Enabled: true,
+		Message: "We're closed, back tomorrow",
+		Windows: []store.AwayWindow{{Start: "00:00", End: "24:00"}},
+	}), `"success":true`)
+	require.Contains(t, app.SetAwayOptOut("222@s.whatsapp.net", true), `"opted_out":true`)
+	startSync(t, app, fake)
+
+	alice := types.NewJID("111", types.DefaultUserServer)
+	bob := types.NewJID("222", types.DefaultUserServer)
+	fake.EmitText(alice, alice, "A1", "hello?", time.Now())
+	require.Eventually(t, func() bool { return len(fake.Sent()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "We're closed, back tomorrow", fake.Sent()[0].Message)
+
+	fake.EmitText(alice, alice, "A2", "anyone?", time.Now())
+	fake.EmitText(bob, bob, "B1", "hi", time.Now())
+	fake.Emit(fakeclient.TextMessage(alice, fakeclient.OwnJID, "A3", "sure", time.Now(), true))
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, fake.Sent(), 1)
+	assert.Contains(t, app.ListAwayOptOuts(), "222@s.whatsapp.net")
+}
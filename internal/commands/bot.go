@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/bot"
+)
+
+// BotConfig configures the inbound command router.
+type BotConfig struct {
+	// Prefix marks a message as a command, e.g. "!".
+	Prefix string
+	// AllowedChats lists the chats commands are accepted from; "*" allows all.
+	AllowedChats []string
+	// Webhooks maps custom command names to the URL that answers them.
+	Webhooks map[string]string
+}
+
+// SetBot enables the command router with the built-in commands (ping, stats,
+// help) and the configured webhook commands. An empty AllowedChats disables it;
+// the prefix is still used for the handoff commands.
+func (a *App) SetBot(cfg BotConfig) {
+	if cfg.Prefix != "" {
+		a.commandPrefix = cfg.Prefix
+	}
+	if len(cfg.AllowedChats) == 0 {
+		a.bot = nil
+		return
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "!"
+	}
+
+	r := bot.NewRouter(cfg.Prefix, cfg.AllowedChats)
+	httpClient := &http.Client{Timeout: 15 * time.Second}
+	for name, url := range cfg.Webhooks {
+		r.Register(name, "custom command", bot.WebhookHandler{URL: url, Client: httpClient, Secret: a.webhookSecret})
+	}
+	r.Register("ping", "check that the bot is alive", bot.HandlerFunc(func(ctx context.Context, req bot.Request) (string, error) {
+		return "pong", nil
+	}))
+	r.Register("stats", "show archive statistics", bot.HandlerFunc(a.botStats))
+	r.Register("help", "list available commands", bot.HandlerFunc(func(ctx context.Context, req bot.Request) (string, error) {
+		var b strings.Builder
+		b.WriteString("Available commands:")
+		for _, c := range r.Commands() {
+			fmt.Fprintf(&b, "\n%s — %s", c[0], c[1])
+		}
+		return b.String(), nil
+	}))
+	a.bot = r
+}
+
+func (a *App) botStats(ctx context.Context, req bot.Request) (string, error) {
+	stats, err := a.store.Stats(req.ChatJID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("📊 %d messages in %d chats (%d in this chat). Up %s.",
+		stats.Messages, stats.Chats, stats.ChatMessages, time.Since(a.startedAt).Round(time.Second)), nil
+}
+
+// runBotCommand dispatches a command to r and sends the reply to its chat.
+func (a *App) runBotCommand(ctx context.Context, r *bot.Router, req bot.Request) {
+	reply, err := r.Dispatch(ctx, req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n⚠ Command %s failed: %v\n", req.Command, err)
+		reply = fmt.Sprintf("⚠ %s failed", req.Command)
+	}
+	if strings.TrimSpace(reply) == "" {
+		return
+	}
+	if err := a.sendAndStore(ctx, req.ChatJID, reply); err != nil {
+		fmt.Fprintf(os.Stderr, "\n⚠ Failed to send reply to %s: %v\n", req.ChatJID, err)
+	}
+}
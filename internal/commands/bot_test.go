@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestBotRepliesToCommands(t *testing.T) {
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("It's sunny"))
+	}))
+	defer hook.Close()
+
+	app, fake := newFakeApp(t)
+	app.SetBot(BotConfig{
+		AllowedChats: []string{testGroupJID},
+		Webhooks:     map[string]string{"weather": hook.URL},
+	})
+	startSync(t, app, fake)
+
+	group := types.NewJID("120363000000000001", types.GroupServer)
+	member := types.NewJID("111", types.DefaultUserServer)
+
+	fake.EmitText(group, member, "C1", "!ping", time.Now())
+	require.Eventually(t, func() bool { return len(fake.Sent()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "pong", fake.Sent()[0].Message)
+	assert.Equal(t, testGroupJID, fake.Sent()[0].Recipient)
+
+	fake.EmitText(group, member, "C2", "!weather berlin", time.Now())
+	require.Eventually(t, func() bool { return len(fake.Sent()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "It's sunny", fake.Sent()[1].Message)
+
+	fake.EmitText(group, member, "C3", "!stats", time.Now())
+	require.Eventually(t, func() bool { return len(fake.Sent()) == 3 }, time.Second, 5*time.Millisecond)
+	assert.Contains(t, fake.Sent()[2].Message, "in this chat")
+
+	// Other chats and plain messages are ignored
+	other := types.NewJID("120363000000000002", types.GroupServer)
+	fake.EmitText(other, member, "C4", "!ping", time.Now())
+	fake.EmitText(group, member, "C5", "ping", time.Now())
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, fake.Sent(), 3)
+}
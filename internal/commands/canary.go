@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/canary"
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+)
+
+// CanaryConfig enables the end-to-end self-test: every Interval a message is
+// sent to the account's own chat and the phone must acknowledge it with a
+// receipt within Timeout (canary.DefaultTimeout if 0).
+type CanaryConfig struct {
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+// SetCanary enables the self-test while syncing. A zero interval disables it.
+func (a *App) SetCanary(cfg CanaryConfig) error {
+	if cfg.Interval == 0 {
+		a.canary = nil
+		return nil
+	}
+	c, err := canary.New(cfg.Interval, cfg.Timeout)
+	if err != nil {
+		return err
+	}
+	a.canary = c
+	return nil
+}
+
+// CanaryStatus returns the outcome of the self-test, or nil if it is
+// disabled.
+func (a *App) CanaryStatus() *canary.Status {
+	if a.canary == nil {
+		return nil
+	}
+	status := a.canary.Status()
+	return &status
+}
+
+// runCanary checks the round trip every interval until ctx is cancelled.
+func (a *App) runCanary(ctx context.Context) {
+	c := a.canary
+	if c == nil {
+		return
+	}
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.checkCanary(ctx)
+		}
+	}
+}
+
+// checkCanary sends one canary message to the account's own chat and waits
+// for a receipt of it. The message bypasses send shaping and quiet hours,
+// and is not stored.
+func (a *App) checkCanary(ctx context.Context) {
+	c := a.canary
+	id, message, acked, end := c.Begin()
+	defer end()
+
+	start := time.Now()
+	err := a.sendCanary(ctx, id, message)
+	if err == nil {
+		timer := time.NewTimer(c.Timeout)
+		defer timer.Stop()
+		select {
+		case <-acked:
+		case <-timer.C:
+			err = fmt.Errorf("canary was not acknowledged within %s", c.Timeout)
+		case <-ctx.Done():
+			return
+		}
+	}
+	if c.Record(start, time.Now(), err) {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠ Canary failed: %v\n", err)
+		} else {
+			fmt.Fprintln(os.Stderr, "✓ Canary passed again")
+		}
+	}
+}
+
+func (a *App) sendCanary(ctx context.Context, id, message string) error {
+	if !a.client.IsConnected() {
+		return fmt.Errorf("not connected to WhatsApp")
+	}
+	own := a.client.OwnJID()
+	if own == "" {
+		return fmt.Errorf("not paired")
+	}
+	_, err := a.client.SendExtendedText(ctx, own, message, client.TextContext{ID: id})
+	return err
+}
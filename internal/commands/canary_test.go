@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/canary"
+	"github.com/vicentereig/whatsapp-cli/internal/client/fakeclient"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+func TestCanaryRoundTrip(t *testing.T) {
+	app, fake := newFakeApp(t)
+	require.NoError(t, app.SetCanary(CanaryConfig{Interval: time.Hour, Timeout: time.Second}))
+	fake.SetEcho(true)
+	fake.SetDeliveryReceipts(true)
+	startSync(t, app, fake)
+
+	app.checkCanary(context.Background())
+
+	status := app.CanaryStatus()
+	require.NotNil(t, status)
+	assert.True(t, status.OK, status.LastError)
+	assert.Equal(t, int64(1), status.Checks)
+	require.NotNil(t, status.LastSuccess)
+
+	sent := fake.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, fakeclient.OwnJID.String(), sent[0].Recipient)
+	assert.True(t, strings.HasPrefix(sent[0].Message, canary.Prefix))
+	assert.Equal(t, canary.Prefix+sent[0].ID, sent[0].Message, "the message carries its own ID")
+
+	// The canary does not end up in the message history
+	msgs, err := app.store.ListMessages(store.ListMessagesParams{Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, msgs)
+}
+
+func TestCanaryFailsWithoutReceipt(t *testing.T) {
+	app, fake := newFakeApp(t)
+	require.NoError(t, app.SetCanary(CanaryConfig{Interval: time.Hour, Timeout: 20 * time.Millisecond}))
+	// The phone never echoes the own chat back through sync; without a
+	// receipt the check fails
+	fake.SetEcho(true)
+	startSync(t, app, fake)
+
+	app.checkCanary(context.Background())
+
+	status := app.CanaryStatus()
+	require.NotNil(t, status)
+	assert.False(t, status.OK)
+	assert.Contains(t, status.LastError, "not acknowledged")
+	assert.Equal(t, int64(1), status.Failures)
+}
+
+func TestCanaryKeepsMessagesItDidNotSend(t *testing.T) {
+	app, fake := newFakeApp(t)
+	require.NoError(t, app.SetCanary(CanaryConfig{Interval: time.Hour, Timeout: time.Second}))
+	startSync(t, app, fake)
+
+	// A note to self that merely looks like a canary is kept
+	fake.Emit(fakeclient.TextMessage(fakeclient.OwnJID, fakeclient.OwnJID, "3EB0NOTACANARY", canary.Prefix+"3EB0NOTACANARY", time.Now(), true))
+
+	require.Eventually(t, func() bool {
+		msgs, err := app.store.ListMessages(store.ListMessagesParams{Limit: 10})
+		return err == nil && len(msgs) == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestSetCanary(t *testing.T) {
+	app, _ := newFakeApp(t)
+	assert.Nil(t, app.CanaryStatus())
+
+	assert.Error(t, app.SetCanary(CanaryConfig{Interval: 10 * time.Second}))
+	assert.Error(t, app.SetCanary(CanaryConfig{Interval: time.Minute, Timeout: 2 * time.Minute}))
+
+	require.NoError(t, app.SetCanary(CanaryConfig{Interval: time.Minute}))
+	require.NotNil(t, app.CanaryStatus())
+	require.NoError(t, app.SetCanary(CanaryConfig{}))
+	assert.Nil(t, app.CanaryStatus())
+}
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"crypto/ed25519"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// SetHashChain makes every message stored from now on, received, sent or
+// from history sync, extend a per-chat hash chain, so an exported
+// conversation can later be shown to match what was archived.
+func (a *App) SetHashChain(enabled bool) {
+	a.hashChain = enabled
+}
+
+// SetChainSigningKey makes chain exports carry an ed25519 signature of their
+// head, and chain exports checked later need a valid one. A nil key leaves
+// exports unsigned.
+func (a *App) SetChainSigningKey(key ed25519.PrivateKey) {
+	a.chainKey = key
+}
+
+// ExportChain returns the hash chain of a chat with the messages it covers,
+// signed when there is a signing key.
+func (a *App) ExportChain(chatJID string) string {
+	e, err := a.store.ExportChain(chatJID, time.Now())
+	if err != nil {
+		return output.Error(err)
+	}
+	if a.chainKey != nil {
+		store.SignChain(&e, a.chainKey)
+	}
+	return output.Success(e)
+}
+
+// VerifyChain checks the hash chain of a chat against the messages stored
+// now.
+func (a *App) VerifyChain(chatJID string) string {
+	v, err := a.store.VerifyChain(chatJID)
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(v)
+}
+
+// VerifyChainExport checks an exported chain: that it is intact, that the
+// store chained the same messages and, with a signing key, that it signed
+// the export.
+func (a *App) VerifyChainExport(e store.ChainExport) string {
+	var key ed25519.PublicKey
+	if a.chainKey != nil {
+		key = a.chainKey.Public().(ed25519.PublicKey)
+	}
+	v, err := a.store.VerifyChainExport(e, key)
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(v)
+}
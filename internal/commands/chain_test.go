@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestHashChainCoversStoredMessages(t *testing.T) {
+	app, fake := newFakeApp(t)
+	app.SetHashChain(true)
+	startSync(t, app, fake)
+
+	alice := types.NewJID("34600111222", types.DefaultUserServer)
+	ts := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	fake.EmitText(alice, alice, "M1", "hello", ts)
+	fake.EmitText(alice, alice, "M2", "are you there?", ts.Add(time.Minute))
+	require.Eventually(t, func() bool {
+		e, err := app.store.ExportChain(alice.String(), time.Now())
+		return err == nil && e.Length == 2
+	}, time.Second, 5*time.Millisecond)
+	assert.Contains(t, app.SendMessage(context.Background(), "34600111222", "yes", client.SendOptions{}), `"success":true`)
+
+	require.Eventually(t, func() bool {
+		e, err := app.store.ExportChain(alice.String(), time.Now())
+		return err == nil && e.Length == 3
+	}, time.Second, 5*time.Millisecond)
+	result := app.ExportChain("34600111222")
+	assert.Contains(t, result, `"content":"are you there?"`)
+	assert.Contains(t, app.VerifyChain("34600111222"), `"valid":true`)
+}
+
+func TestHashChainSignsExports(t *testing.T) {
+	app, fake := newFakeApp(t)
+	app.SetHashChain(true)
+	_, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	startSync(t, app, fake)
+
+	alice := types.NewJID("34600111222", types.DefaultUserServer)
+	fake.EmitText(alice, alice, "M1", "hello", time.Now())
+	require.Eventually(t, func() bool {
+		e, err := app.store.ExportChain(alice.String(), time.Now())
+		return err == nil && e.Length == 1
+	}, time.Second, 5*time.Millisecond)
+
+	var unsigned struct{ Data store.ChainExport }
+	require.NoError(t, json.Unmarshal([]byte(app.ExportChain("34600111222")), &unsigned))
+	assert.Empty(t, unsigned.Data.Signature)
+
+	app.SetChainSigningKey(key)
+	assert.Contains(t, app.VerifyChainExport(unsigned.Data), `"reason":"export is not signed"`)
+	var signed struct{ Data store.ChainExport }
+	require.NoError(t, json.Unmarshal([]byte(app.ExportChain("34600111222")), &signed))
+	assert.NotEmpty(t, signed.Data.Signature)
+	assert.Contains(t, app.VerifyChainExport(signed.Data), `"signed":true`)
+}
+
+func TestHashChainOffByDefault(t *testing.T) {
+	app, fake := newFakeApp(t)
+	startSync(t, app, fake)
+
+	alice := types.NewJID("34600111222", types.DefaultUserServer)
+	fake.EmitText(alice, alice, "M1", "hello", time.Now())
+	require.Eventually(t, func() bool {
+		exists, err := app.store.ChatExists(alice.String())
+		return err == nil && exists
+	}, time.Second, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.Contains(t, app.ExportChain("34600111222"), `"length":0`)
+}
@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/bot"
+)
+
+// chatOpsChanges are the admin commands that change the daemon's state.
+var chatOpsChanges = map[string]bool{"pause": true, "resume": true, "allow": true, "deny": true}
+
+// SetChatOps lets the admins, phone numbers or JIDs, control the daemon by
+// messaging the account from their own chat: status, pause and resume sync,
+// allow and deny bot chats, and stats. Replies are sent back over WhatsApp,
+// so it works where the HTTP port is not reachable. It uses the prefix of the
+// command bot, so call it after SetBot. Empty disables it.
+func (a *App) SetChatOps(admins []string) {
+	if len(admins) == 0 {
+		a.chatOps = nil
+		return
+	}
+	r := bot.NewRouter(a.commandPrefix, admins)
+	r.Register("status", "show connection, sync and queue state", bot.HandlerFunc(a.chatOpsStatus))
+	r.Register("pause", "pause sync: stop archiving incoming messages", bot.HandlerFunc(func(ctx context.Context, req bot.Request) (string, error) {
+		if a.syncPaused.Swap(true) {
+			return "⏸ Sync is already paused.", nil
+		}
+		return fmt.Sprintf("⏸ Sync paused. Incoming messages are not archived until %sresume.", a.commandPrefix), nil
+	}))
+	r.Register("resume", "resume sync", bot.HandlerFunc(func(ctx context.Context, req bot.Request) (string, error) {
+		if !a.syncPaused.Swap(false) {
+			return "▶ Sync is not paused.", nil
+		}
+		return "▶ Sync resumed.", nil
+	}))
+	r.Register("allow", "accept bot commands from a chat until restart", bot.HandlerFunc(a.chatOpsAllow))
+	r.Register("deny", "stop accepting bot commands from a chat", bot.HandlerFunc(a.chatOpsDeny))
+	r.Register("stats", "show archive statistics", bot.HandlerFunc(a.botStats))
+	r.Register("help", "list admin commands", bot.HandlerFunc(func(ctx context.Context, req bot.Request) (string, error) {
+		var b strings.Builder
+		b.WriteString("Admin commands:")
+		for _, c := range r.Commands() {
+			fmt.Fprintf(&b, "\n%s — %s", c[0], c[1])
+		}
+		return b.String(), nil
+	}))
+	a.chatOps = r
+}
+
+// runChatOps runs an admin command sent at sent. A command that changes
+// state is not applied when it waited in the offline queue: it is delivered
+// again on reconnect, possibly long after, and could undo a later change.
+// The admin is asked to send it again instead.
+func (a *App) runChatOps(ctx context.Context, req bot.Request, sent time.Time) {
+	if !chatOpsChanges[req.Command] || !a.conn.queued(sent) {
+		a.runBotCommand(ctx, a.chatOps, req)
+		return
+	}
+	reply := fmt.Sprintf("⏭ %s%s was sent at %s while the daemon was offline and was not applied. Send it again to apply it now.",
+		a.commandPrefix, req.Command, sent.Format(time.RFC3339))
+	if err := a.sendAndStore(ctx, req.ChatJID, reply); err != nil {
+		fmt.Fprintf(os.Stderr, "\n⚠ Failed to send reply to %s: %v\n", req.ChatJID, err)
+	}
+}
+
+func (a *App) chatOpsStatus(ctx context.Context, req bot.Request) (string, error) {
+	var b strings.Builder
+	switch {
+	case !a.client.IsConnected():
+		b.WriteString("🔴 Disconnected")
+	case a.syncPaused.Load():
+		b.WriteString("⏸ Connected, sync paused")
+	default:
+		b.WriteString("🟢 Connected, syncing")
+	}
+	fmt.Fprintf(&b, ". Up %s.", time.Since(a.startedAt).Round(time.Second))
+	if stats, err := a.store.Stats(""); err == nil {
+		fmt.Fprintf(&b, "\n📊 %d messages in %d chats.", stats.Messages, stats.Chats)
+	}
+	var pending int64
+	for _, n := range a.OutboxStats().Pending {
+		pending += n
+	}
+	fmt.Fprintf(&b, "\n📤 %d notifications queued.", pending)
+	if alerts := a.AccountAlerts(); len(alerts) > 0 {
+		fmt.Fprintf(&b, "\n⚠ %d account alerts, latest: %s.", len(alerts), alerts[0].Reason)
+	}
+	return b.String(), nil
+}
+
+// chatOpsChat reads the chat an allow or deny command names; a phone number
+// may be split by spaces.
+func (a *App) chatOpsChat(req bot.Request) (string, error) {
+	if a.bot == nil {
+		return "", errors.New("the command bot is disabled; set BOT_ALLOWED_CHATS to enable it")
+	}
+	chat := strings.Join(req.Args, "")
+	if chat == "" {
+		return "", fmt.Errorf("usage: %s%s <phone or JID>", a.commandPrefix, req.Command)
+	}
+	return chat, nil
+}
+
+func (a *App) chatOpsAllow(ctx context.Context, req bot.Request) (string, error) {
+	chat, err := a.chatOpsChat(req)
+	if err != nil {
+		return "⚠ " + err.Error(), nil
+	}
+	a.bot.Allow(chat)
+	return fmt.Sprintf("✅ %s may use bot commands until restart.", chat), nil
+}
+
+func (a *App) chatOpsDeny(ctx context.Context, req bot.Request) (string, error) {
+	chat, err := a.chatOpsChat(req)
+	if err != nil {
+		return "⚠ " + err.Error(), nil
+	}
+	if !a.bot.Disallow(chat) {
+		return fmt.Sprintf("%s was not allowed.", chat), nil
+	}
+	return fmt.Sprintf("🚫 %s may no longer use bot commands.", chat), nil
+}
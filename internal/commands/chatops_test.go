@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestChatOpsAdminCommands(t *testing.T) {
+	app, fake := newFakeApp(t)
+	app.SetBot(BotConfig{AllowedChats: []string{testGroupJID}})
+	app.SetChatOps([]string{"+49 151 12345678"})
+	startSync(t, app, fake)
+
+	admin := types.NewJID("4915112345678", types.DefaultUserServer)
+	alice := types.NewJID("111", types.DefaultUserServer)
+	reply := func(n int) string {
+		t.Helper()
+		require.Eventually(t, func() bool { return len(fake.Sent()) == n }, time.Second, 5*time.Millisecond)
+		assert.Equal(t, admin.String(), fake.Sent()[n-1].Recipient)
+		return fake.Sent()[n-1].Message
+	}
+
+	fake.EmitText(admin, admin, "A1", "!status", time.Now())
+	assert.Contains(t, reply(1), "Connected, syncing")
+
+	// Other contacts cannot run admin commands
+	fake.EmitText(alice, alice, "A2", "!status", time.Now())
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, fake.Sent(), 1)
+
+	fake.EmitText(admin, admin, "A3", "!pause sync", time.Now())
+	assert.Contains(t, reply(2), "Sync paused")
+	fake.EmitText(alice, alice, "M1", "not archived", time.Now())
+	fake.EmitText(admin, admin, "A4", "!status", time.Now())
+	assert.Contains(t, reply(3), "sync paused")
+	fake.EmitText(admin, admin, "A5", "!resume", time.Now())
+	assert.Contains(t, reply(4), "Sync resumed")
+	fake.EmitText(alice, alice, "M2", "archived", time.Now())
+	require.Eventually(t, func() bool {
+		_, err := app.store.MessageChat("M2", nil)
+		return err == nil
+	}, time.Second, 5*time.Millisecond)
+	_, err := app.store.MessageChat("M1", nil)
+	assert.Error(t, err, "messages received while paused are not archived")
+
+	// Allow lets a contact use the bot until deny
+	fake.EmitText(alice, alice, "B1", "!ping", time.Now())
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, fake.Sent(), 4)
+	fake.EmitText(admin, admin, "A6", "!allow +111", time.Now())
+	assert.Contains(t, reply(5), "may use bot commands")
+	fake.EmitText(alice, alice, "B2", "!ping", time.Now())
+	require.Eventually(t, func() bool { return len(fake.Sent()) == 6 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "pong", fake.Sent()[5].Message)
+	fake.EmitText(admin, admin, "A7", "!deny 111", time.Now())
+	assert.Contains(t, reply(7), "no longer")
+	fake.EmitText(alice, alice, "B3", "!ping", time.Now())
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, fake.Sent(), 7)
+}
+
+func TestChatOpsAllowNeedsBot(t *testing.T) {
+	app, fake := newFakeApp(t)
+	app.SetChatOps([]string{"4915112345678"})
+	startSync(t, app, fake)
+
+	admin := types.NewJID("4915112345678", types.DefaultUserServer)
+	fake.EmitText(admin, admin, "A1", "!allow 111", time.Now())
+	require.Eventually(t, func() bool { return len(fake.Sent()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Contains(t, fake.Sent()[0].Message, "BOT_ALLOWED_CHATS")
+}
+
+func TestChatOpsSkipsQueuedChanges(t *testing.T) {
+	app, fake := newFakeApp(t)
+	app.SetChatOps([]string{"4915112345678"})
+	startSync(t, app, fake)
+
+	// Delivered on reconnect from the offline queue
+	admin := types.NewJID("4915112345678", types.DefaultUserServer)
+	fake.EmitText(admin, admin, "A1", "!pause", time.Now().Add(-time.Hour))
+	require.Eventually(t, func() bool { return len(fake.Sent()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Contains(t, fake.Sent()[0].Message, "not applied")
+	assert.False(t, app.syncPaused.Load())
+
+	// Replies that only report are still sent
+	fake.EmitText(admin, admin, "A2", "!status", time.Now().Add(-time.Hour))
+	require.Eventually(t, func() bool { return len(fake.Sent()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Contains(t, fake.Sent()[1].Message, "Connected")
+}
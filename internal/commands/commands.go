@@ -2,52 +2,144 @@ package commands
 
 import (
 	"context"
+	"crypto/ed25519"
 	"database/sql"
 	"errors"
 	"fmt"
 	"mime"
+	"net/netip"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/vicentereig/whatsapp-cli/internal/avscan"
+	"github.com/vicentereig/whatsapp-cli/internal/bot"
+	"github.com/vicentereig/whatsapp-cli/internal/canary"
 	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/embeddings"
+	"github.com/vicentereig/whatsapp-cli/internal/eventbus"
+	"github.com/vicentereig/whatsapp-cli/internal/health"
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/logging"
+	"github.com/vicentereig/whatsapp-cli/internal/mediacrypt"
+	"github.com/vicentereig/whatsapp-cli/internal/mediaurl"
 	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/reqid"
+	"github.com/vicentereig/whatsapp-cli/internal/selfupdate"
+	"github.com/vicentereig/whatsapp-cli/internal/sticker"
 	"github.com/vicentereig/whatsapp-cli/internal/store"
+	"github.com/vicentereig/whatsapp-cli/internal/voice"
+	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
 )
 
 type App struct {
-	client          *client.WAClient
+	client          client.Client
 	store           *store.MessageStore
 	version         string
 	storeDir        string
+	writer          *store.BufferedWriter
 	mediaDownloader func(ctx context.Context, info store.MessageDownloadInfo, targetPath string) (int64, error)
 	mediaWorker     *mediaDownloadWorker
+	mediaLocks      keyedMutex
+	spam            SpamPolicy
+	greeter         *greeter
+	bot             *bot.Router
+	chatOps         *bot.Router
+	syncPaused      atomic.Bool
+	commandPrefix   string
+	startedAt       time.Time
+	syncing         atomic.Bool
+	conn            connectionTracker
+	quality         health.Quality
+	commit          string
+	updateMu        sync.Mutex
+	updater         *selfupdate.Updater
+	lastCheck       *updateCheck
+	events          *eventbus.Bus
+	notifier        *eventbus.Bus
+	onEvent         atomic.Pointer[func(eventbus.Event)]
+	outboxMaxAge    time.Duration
+	outboxReplayed  atomic.Int64
+	outboxExpired   atomic.Int64
+	receipts        ReceiptPolicy
+	// outboundAllowed are the private networks calendar feeds may still be
+	// fetched from
+	outboundAllowed []netip.Prefix
+	simulateTyping  bool
+	allowChat       func(chatJID string) bool
+	hashChain       bool
+	chainKey        ed25519.PrivateKey
+	reconcileChats  bool
+	sleep           func(ctx context.Context, d time.Duration) error
+	shaper          *sendShaper
+	dedup           *sendDeduper
+	embedder        *embeddings.Client
+	digests         *digester
+	followups       *followupTracker
+	messageWebhook  *messageWebhook
+	mediaURLs       *mediaurl.Signer
+	webhookSecret   []byte
+	recipes         atomic.Pointer[recipeBook]
+	homeAssistant   *homeAssistant
+	feeds           *feedPoller
+	canary          *canary.Canary
+	mediaKeys       mediacrypt.KeyWrapper
+	mediaScanner    avscan.Scanner
+	mediaTypes      MediaTypePolicy
+	ffmpeg          string
 }
 
-func NewApp(storeDir, version string) (*App, error) {
-	cli, err := client.NewWAClient(storeDir)
+func NewApp(storeDir, version, credentialBackend string) (*App, error) {
+	cli, err := client.NewWAClient(storeDir, credentialBackend)
 	if err != nil {
 		return nil, err
 	}
+	return NewAppWithClient(storeDir, version, cli)
+}
 
+// NewAppWithClient creates an App backed by the given WhatsApp client. It is
+// used with fakeclient to run the full application without a live account.
+func NewAppWithClient(storeDir, version string, cli client.Client) (*App, error) {
 	dbPath := filepath.Join(storeDir, "messages.db")
 	st, err := store.NewMessageStore(dbPath)
 	if err != nil {
 		return nil, err
 	}
+	return newApp(storeDir, version, cli, st), nil
+}
+
+// NewReplicaApp creates an App that serves an existing copy of messages.db
+// read-only, without a WhatsApp session. Everything that writes to the store
+// or needs WhatsApp fails.
+func NewReplicaApp(storeDir, version string) (*App, error) {
+	st, err := store.OpenReadOnly(filepath.Join(storeDir, "messages.db"))
+	if err != nil {
+		return nil, err
+	}
+	return newApp(storeDir, version, client.Offline{}, st), nil
+}
 
+func newApp(storeDir, version string, cli client.Client, st *store.MessageStore) *App {
 	app := &App{
-		client:   cli,
-		store:    st,
-		version:  resolveVersion(version, gitDescribe),
-		storeDir: storeDir,
+		client:        cli,
+		store:         st,
+		version:       resolveVersion(version, gitDescribe),
+		storeDir:      storeDir,
+		writer:        store.NewBufferedWriter(store.DefaultBufferLimit),
+		commandPrefix: "!",
+		startedAt:     time.Now(),
+		updater:       selfupdate.New(),
+		outboxMaxAge:  DefaultOutboxMaxAge,
+		sleep:         sleepContext,
 	}
 	app.mediaDownloader = app.downloadMediaWithClient
-	return app, nil
+	app.SetReceiptPolicy(DefaultReceiptPolicy)
+	return app
 }
 
 func (a *App) IsAuthenticated() bool {
@@ -58,19 +150,17 @@ func (a *App) IsConnected() bool {
 	return a.client.IsConnected()
 }
 
-// GetMediaFile returns the local file path and MIME type for a downloaded media message.
-func (a *App) GetMediaFile(messageID string, chatJID *string) (string, string, error) {
-	info, err := a.store.GetMessageForDownload(messageID, chatJID)
-	if err != nil {
-		return "", "", err
-	}
-	if info.LocalPath == nil || *info.LocalPath == "" {
-		return "", "", fmt.Errorf("media not yet downloaded")
-	}
-	if _, err := os.Stat(*info.LocalPath); err != nil {
-		return "", "", fmt.Errorf("media file not found on disk")
-	}
-	return *info.LocalPath, info.MimeType, nil
+// SyncAlive reports whether the sync loop is running and connected to
+// WhatsApp. It drives the systemd watchdog, so a stalled or disconnected
+// sync stops the pings and lets systemd restart the daemon.
+func (a *App) SyncAlive() bool {
+	return a.syncing.Load() && a.client.IsConnected()
+}
+
+// StoreHealth reports whether writes to the message store are succeeding and
+// how many are buffered in memory waiting for it to recover.
+func (a *App) StoreHealth() store.Health {
+	return a.writer.Health()
 }
 
 // RefreshChatNames iterates all chats in the DB and re-resolves names
@@ -103,6 +193,9 @@ func (a *App) Close() {
 	if a.client != nil {
 		a.client.Disconnect()
 	}
+	for _, b := range a.eventBuses() {
+		b.Close()
+	}
 	if a.store != nil {
 		a.store.Close()
 	}
@@ -155,15 +248,22 @@ func (a *App) Auth(ctx context.Context) string {
 	})
 }
 
-func (a *App) ListMessages(chatJID *string, query *string, limit, page int, includeJIDs, excludeJIDs []string, after *time.Time) string {
+func (a *App) ListMessages(chatJID *string, query *string, limit, page int, includeJIDs, excludeJIDs []string, after *time.Time, lang, tag *string) string {
+	if tag != nil {
+		t := strings.ToLower(*tag)
+		tag = &t
+	}
 	messages, err := a.store.ListMessages(store.ListMessagesParams{
-		ChatJID:     chatJID,
-		Query:       query,
-		Limit:       limit,
-		Page:        page,
-		IncludeJIDs: includeJIDs,
-		ExcludeJIDs: excludeJIDs,
-		After:       after,
+		ChatJID:      chatJID,
+		Query:        query,
+		Lang:         lang,
+		Tag:          tag,
+		HideSpamFrom: a.spam.QuarantineThreshold,
+		Limit:        limit,
+		Page:         page,
+		IncludeJIDs:  includeJIDs,
+		ExcludeJIDs:  excludeJIDs,
+		After:        after,
 	})
 	if err != nil {
 		return output.Error(err)
@@ -172,6 +272,19 @@ func (a *App) ListMessages(chatJID *string, query *string, limit, page int, incl
 	return output.Success(messages)
 }
 
+// MessageDuplicates lists other copies of a message (same text or same media
+// file) across all chats, oldest first.
+func (a *App) MessageDuplicates(messageID string, chatJID *string, limit int, includeJIDs, excludeJIDs []string) string {
+	dups, err := a.store.FindDuplicates(messageID, chatJID, includeJIDs, excludeJIDs, limit)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return output.Error(fmt.Errorf("message %s not found", messageID))
+		}
+		return output.Error(err)
+	}
+	return output.Success(dups)
+}
+
 func (a *App) SearchContacts(query string, includeJIDs, excludeJIDs []string) string {
 	contacts, err := a.store.SearchContacts(store.SearchContactsParams{
 		Query:       query,
@@ -185,6 +298,21 @@ func (a *App) SearchContacts(query string, includeJIDs, excludeJIDs []string) st
 	return output.Success(contacts)
 }
 
+// Autocomplete suggests up to limit contacts and group chats for a prefix of
+// their name or phone number, best matches first.
+func (a *App) Autocomplete(prefix string, limit int, includeJIDs, excludeJIDs []string) string {
+	suggestions, err := a.store.Autocomplete(store.AutocompleteParams{
+		Prefix:      strings.TrimSpace(prefix),
+		Limit:       limit,
+		IncludeJIDs: includeJIDs,
+		ExcludeJIDs: excludeJIDs,
+	})
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(suggestions)
+}
+
 func (a *App) ListChats(query *string, limit, page int, includeJIDs, excludeJIDs []string) string {
 	chats, err := a.store.ListChats(store.ListChatsParams{
 		Query:       query,
@@ -200,21 +328,234 @@ func (a *App) ListChats(query *string, limit, page int, includeJIDs, excludeJIDs
 	return output.Success(chats)
 }
 
-func (a *App) SendMessage(ctx context.Context, recipient, message string) string {
-	if err := a.client.Connect(ctx); err != nil {
+// SendMessage sends a text message, as a reply quoting the message
+// send.QuotedMessageID of the same chat if set. In groups it mentions the
+// members listed in send.Mentions. send.SimulateTyping, if non-nil,
+// overrides SetSimulateTyping for this message.
+func (a *App) SendMessage(ctx context.Context, recipient, message string, send client.SendOptions) string {
+	to, err := jid.ParseRecipient(recipient)
+	if err != nil {
+		return output.Error(err)
+	}
+	quotedMessageID := send.QuotedMessageID
+	opts := a.defaultSendOptions()
+	if send.SimulateTyping != nil {
+		opts.typing = *send.SimulateTyping
+	}
+	if quotedMessageID != "" {
+		if opts.quote, err = a.quoteFor(ctx, to, quotedMessageID); err != nil {
+			return output.Error(err)
+		}
+	}
+	if len(send.Mentions) > 0 {
+		if opts.mentions, err = a.mentionsFor(ctx, to, send.Mentions); err != nil {
+			return output.Error(err)
+		}
+		message = withMentions(message, opts.mentions)
+	}
+	key := newDedupKey(to, []byte(message), []byte(quotedMessageID), []byte(strings.Join(opts.mentions, ",")))
+	prev, dup, ok := a.dedup.check(key)
+	if !ok {
+		return output.Error(duplicateError(to, prev, time.Now()))
+	}
+
+	data, queued, err := a.queueDuringQuietHours(recipient, message, opts)
+	if !queued {
+		var id string
+		if err = a.client.Connect(ctx); err == nil {
+			id, err = a.send(ctx, recipient, message, opts)
+		}
+		data = map[string]interface{}{
+			"sent":       true,
+			"message_id": id,
+			"recipient":  recipient,
+			"message":    message,
+		}
+	}
+	if err != nil {
+		a.dedup.forget(key, prev)
 		return output.Error(err)
 	}
+	if quotedMessageID != "" {
+		data["quoted_message_id"] = quotedMessageID
+	}
+	if len(opts.mentions) > 0 {
+		data["mentions"] = opts.mentions
+	}
+	if dup {
+		data["duplicate"] = true
+		data["duplicate_of"] = prev.UTC()
+	}
+	return output.Success(data)
+}
+
+// SetFFmpeg sets the ffmpeg binary voice notes are transcoded with, a path
+// or a name looked up in PATH; empty means "ffmpeg".
+func (a *App) SetFFmpeg(path string) {
+	a.ffmpeg = path
+}
 
-	if err := a.client.SendMessage(ctx, recipient, message); err != nil {
+// SendMedia sends an attachment to recipient, paced like SendMessage. Quiet
+// hours do not hold it back. Stickers and voice notes are converted to
+// WhatsApp's formats first.
+func (a *App) SendMedia(ctx context.Context, recipient string, media client.OutgoingMedia) string {
+	to, err := jid.ParseRecipient(recipient)
+	if err != nil {
+		return output.Error(err)
+	}
+	if len(media.Data) == 0 {
+		return output.Error(fmt.Errorf("attachment is empty"))
+	}
+	if media.Type == "" {
+		media.Type = client.MediaTypeForMIME(media.MimeType)
+	}
+	if media.Type == "sticker" {
+		data, err := sticker.Convert(media.Data)
+		if err != nil {
+			return output.Error(err)
+		}
+		media = client.OutgoingMedia{Type: "sticker", Data: data, MimeType: sticker.MIMEType}
+	}
+	if media.Type == "voice" {
+		note, err := voice.Convert(ctx, a.ffmpeg, media.Data)
+		if err != nil {
+			return output.Error(err)
+		}
+		media = client.OutgoingMedia{Type: "voice", Data: note.Data, MimeType: voice.MIMEType, Seconds: note.Seconds, Waveform: note.Waveform}
+	}
+	key := newDedupKey(to, []byte(media.Caption), media.Data)
+	prev, dup, ok := a.dedup.check(key)
+	if !ok {
+		return output.Error(duplicateError(to, prev, time.Now()))
+	}
+	if err := a.client.Connect(ctx); err != nil {
+		a.dedup.forget(key, prev)
 		return output.Error(err)
 	}
+	id, err := a.sendMediaAndStore(ctx, to, media)
+	if err != nil {
+		a.dedup.forget(key, prev)
+		return output.Error(err)
+	}
+	data := map[string]interface{}{
+		"sent":       true,
+		"message_id": id,
+		"recipient":  to,
+		"media_type": media.Type,
+		"filename":   media.Filename,
+	}
+	if dup {
+		data["duplicate"] = true
+		data["duplicate_of"] = prev.UTC()
+	}
+	return output.Success(data)
+}
 
-	// Store the message
-	timestamp := time.Now()
-	chatJID := recipient
-	if !contains(recipient, "@") {
-		chatJID = recipient + "@s.whatsapp.net"
+// sendOptions shape how a message is sent.
+type sendOptions struct {
+	// typing shows the typing indicator before the message is sent.
+	typing bool
+	// quote makes the message a reply to another one.
+	quote *client.Quote
+	// mentions are the JIDs of the group members the message mentions.
+	mentions []string
+}
+
+func (a *App) defaultSendOptions() sendOptions {
+	return sendOptions{typing: a.simulateTyping}
+}
+
+// SetChatFilter keeps automated messages (greetings, away messages, bot
+// replies, recipes, feeds and reminders) away from the chats allow rejects,
+// e.g. those the API's phone filters exclude. nil allows every chat.
+func (a *App) SetChatFilter(allow func(chatJID string) bool) {
+	a.allowChat = allow
+}
+
+// chatAllowed reports whether automated messages may go to a chat.
+func (a *App) chatAllowed(chatJID string) bool {
+	return a.allowChat == nil || a.allowChat(chatJID)
+}
+
+// sendAndStore sends an automated text message on an established connection
+// and records it in the store. Chats the chat filter rejects are refused.
+func (a *App) sendAndStore(ctx context.Context, recipient, message string) error {
+	if !a.chatAllowed(recipient) {
+		return fmt.Errorf("chat %s is excluded by the phone filters", recipient)
 	}
+	_, err := a.send(ctx, recipient, message, a.defaultSendOptions())
+	return err
+}
+
+// send is sendAndStore with explicit options, returning the ID the message
+// was sent as.
+func (a *App) send(ctx context.Context, recipient, message string, opts sendOptions) (string, error) {
+	if err := a.waitForSlot(ctx); err != nil {
+		return "", err
+	}
+	if opts.typing {
+		if err := a.simulateTypingFor(ctx, recipient, message); err != nil {
+			return "", err
+		}
+	}
+	var id string
+	var err error
+	start := time.Now()
+	if opts.quote != nil || len(opts.mentions) > 0 {
+		id, err = a.client.SendExtendedText(ctx, recipient, message, client.TextContext{Quote: opts.quote, Mentions: opts.mentions})
+	} else {
+		id, err = a.client.SendMessage(ctx, recipient, message)
+	}
+	a.quality.Send(time.Now(), time.Since(start), err == nil)
+	if err != nil {
+		a.checkSendError(err)
+		return "", err
+	}
+	a.recordSent(ctx, recipient, id, message, nil)
+	return id, nil
+}
+
+// quoteFor looks up the message of recipient's chat a reply quotes.
+func (a *App) quoteFor(ctx context.Context, recipient, messageID string) (*client.Quote, error) {
+	chatJID := a.canonicalChatJID(ctx, jid.Normalize(recipient))
+	q, err := a.store.QuotedMessage(messageID, chatJID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("quoted message %s not found in chat %s", messageID, chatJID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sender := jid.Normalize(q.Sender)
+	if q.IsFromMe {
+		sender = a.client.OwnJID()
+	}
+	content := q.Content
+	if content == "" && q.MediaType != "" {
+		content = "[" + q.MediaType + "]"
+	}
+	return &client.Quote{MessageID: q.ID, Sender: sender, Content: content}, nil
+}
+
+// sendMediaAndStore sends an attachment on an established connection and
+// records it in the store, returning its message ID.
+func (a *App) sendMediaAndStore(ctx context.Context, recipient string, media client.OutgoingMedia) (string, error) {
+	if err := a.waitForSlot(ctx); err != nil {
+		return "", err
+	}
+	id, err := a.client.SendMedia(ctx, recipient, media)
+	if err != nil {
+		a.checkSendError(err)
+		return "", err
+	}
+	a.recordSent(ctx, recipient, id, media.Caption, &media)
+	return id, nil
+}
+
+// recordSent stores a message sent to recipient under the ID WhatsApp gave
+// it, with media if it was an attachment.
+func (a *App) recordSent(ctx context.Context, recipient, id, message string, media *client.OutgoingMedia) {
+	timestamp := time.Now()
+	chatJID := a.canonicalChatJID(ctx, jid.Normalize(recipient))
 
 	// Resolve a friendly chat name when available (falls back to JID/recipient)
 	chatName := a.client.ResolveChatName(ctx, chatJID, nil)
@@ -222,23 +563,32 @@ func (a *App) SendMessage(ctx context.Context, recipient, message string) string
 		chatName = recipient
 	}
 
-	// Store chat if needed
-	a.store.StoreChat(chatJID, chatName, timestamp)
-	a.store.StoreMessage(
-		fmt.Sprintf("%d", timestamp.Unix()),
-		chatJID,
-		"me",
-		message,
-		timestamp,
-		true,
-		"", "", "", "", "",
-		nil, nil, nil, 0,
-	)
-
-	return output.Success(map[string]interface{}{
-		"sent":      true,
-		"recipient": recipient,
-		"message":   message,
+	// Store chat and message; buffered if the store is currently failing
+	a.writer.Write(func() error {
+		if err := a.store.StoreChat(chatJID, chatName, timestamp); err != nil {
+			return err
+		}
+		var mediaType, filename, mimeType string
+		if media != nil {
+			mediaType, filename, mimeType = media.Type, media.Filename, media.MimeType
+		}
+		if err := a.store.StoreMessage(
+			id,
+			chatJID,
+			"me",
+			message,
+			timestamp,
+			true,
+			mediaType, filename, "", "", mimeType,
+			nil, nil, nil, 0,
+		); err != nil {
+			return err
+		}
+		if err := a.enrichMessage(enrichRequest{ID: id, ChatJID: chatJID, Sender: "me", Content: message, Timestamp: timestamp}); err != nil {
+			return err
+		}
+		a.publishMessage(chatJID, "me", timestamp, eventbus.Message{ID: id, Content: message, IsFromMe: true, MediaType: mediaType, Filename: filename, MimeType: mimeType})
+		return nil
 	})
 }
 
@@ -277,6 +627,9 @@ func (a *App) DownloadMedia(ctx context.Context, messageID string, chatJID *stri
 	if info.ChatName != nil && *info.ChatName != "" {
 		response["chat_name"] = *info.ChatName
 	}
+	if a.encryptsDownload(targetPath) {
+		response["encrypted"] = true
+	}
 	return output.Success(response)
 }
 
@@ -452,6 +805,13 @@ func (a *App) downloadMediaAndPersist(ctx context.Context, info store.MessageDow
 	if err != nil {
 		return "", 0, time.Time{}, err
 	}
+	a.scanDownload(ctx, info, finalPath)
+	if a.encryptsDownload(finalPath) {
+		if err := mediacrypt.EncryptFile(ctx, a.mediaKeys, finalPath); err != nil {
+			os.Remove(finalPath)
+			return "", 0, time.Time{}, fmt.Errorf("failed to encrypt media: %w", err)
+		}
+	}
 
 	now := time.Now().UTC()
 	if err := a.store.MarkMediaDownloaded(info.ID, info.ChatJID, finalPath, now); err != nil {
@@ -475,12 +835,7 @@ func (a *App) processMediaJob(ctx context.Context, job mediaJob) error {
 	if strings.TrimSpace(info.DirectPath) == "" || len(info.MediaKey) == 0 {
 		return nil
 	}
-	if info.LocalPath != nil {
-		if _, err := os.Stat(*info.LocalPath); err == nil {
-			return nil
-		}
-	}
-	_, _, _, err = a.downloadMediaAndPersist(ctx, info, "")
+	_, err = a.localMedia(ctx, info)
 	return err
 }
 
@@ -612,6 +967,14 @@ func (w *mediaDownloadWorker) Stop() {
 	w.wg.Wait()
 }
 
+// logPrefix tags log lines written on behalf of an API request with its ID.
+func logPrefix(ctx context.Context) string {
+	if id := reqid.FromContext(ctx); id != "" {
+		return "[request_id=" + id + "] "
+	}
+	return ""
+}
+
 func contains(s, substr string) bool {
 	for i := 0; i < len(s); i++ {
 		if i+len(substr) <= len(s) && s[i:i+len(substr)] == substr {
@@ -622,9 +985,15 @@ func contains(s, substr string) bool {
 }
 
 // Sync connects to WhatsApp and continuously syncs messages to the database.
-// If onMessage is non-nil, it is called for each message synced.
-func (a *App) Sync(ctx context.Context, onMessage func()) string {
+// If onEvent is non-nil, it receives every event published while syncing:
+// each message stored (synced, backfilled or sent), receipt, presence update
+// and account alert.
+func (a *App) Sync(ctx context.Context, onEvent func(eventbus.Event)) string {
 	messageCount := 0
+	if onEvent != nil {
+		a.onEvent.Store(&onEvent)
+		defer a.onEvent.Store(nil)
+	}
 
 	version := a.version
 	if strings.TrimSpace(version) == "" {
@@ -643,18 +1012,60 @@ func (a *App) Sync(ctx context.Context, onMessage func()) string {
 		}
 	}()
 
+	// Replay writes buffered while the store was failing
+	go a.writer.Run(ctx, 5*time.Second)
+
+	for _, b := range a.eventBuses() {
+		go b.Run(ctx)
+	}
+	// Replay notifications queued while a webhook or broker was down
+	go a.runOutbox(ctx, 30*time.Second)
+	// Deliver messages held back by quiet hours once they are over
+	go a.runSendQueue(ctx, time.Minute)
+	// Vectorize new messages for semantic search
+	go a.runEmbeddings(ctx, 30*time.Second)
+	// Summarize chats once their digest period is over
+	go a.runDigests(ctx, time.Minute)
+	// Notify chats left waiting for a reply
+	go a.runFollowups(ctx, time.Minute)
+	// Pick up edits of the recipes file
+	go a.runRecipeReload(ctx, 5*time.Second)
+	// Show chats in Home Assistant and take sends from it
+	go a.runHomeAssistant(ctx)
+	// Remind recipients of upcoming calendar events
+	go a.runReminders(ctx, time.Minute)
+	// Post new items of RSS and Atom feeds
+	go a.runFeeds(ctx)
+	// Check that a message to ourselves comes back through sync
+	go a.runCanary(ctx)
+	// Measure the round trip to WhatsApp's servers
+	go a.runPings(ctx, pingInterval)
+
 	// Create event handler
+	evLog := logging.WhatsMeow(logging.SubsystemWhatsApp, "Events")
 	eventHandler := func(evt interface{}) {
+		evLog.Debugf("Handling %T", evt)
 		switch v := evt.(type) {
 		case *events.Message:
 			// Extract message details
 			details := client.HandleMessage(v)
+			// Canary messages only check the round trip and are not stored
+			if details.IsFromMe && a.canary.Issued(details.ID) {
+				return
+			}
 			id := details.ID
 			chatJID := details.ChatJID
+			if details.ChatLID != "" {
+				a.linkLID(details.ChatLID, chatJID)
+			} else {
+				chatJID = a.canonicalChatJID(ctx, chatJID)
+			}
 			sender := details.Sender
 			content := details.Content
 			msgTime := details.Timestamp
 			isFromMe := details.IsFromMe
+			forwardingScore := details.ForwardingScore
+			senderDevice := int(details.SenderDevice)
 			mediaType := ""
 			filename := ""
 			url := ""
@@ -675,38 +1086,176 @@ func (a *App) Sync(ctx context.Context, onMessage func()) string {
 				fileLength = details.Media.FileLength
 			}
 
+			// Admin commands are answered even while sync is paused
+			adminCommand := false
+			if a.chatOps != nil && !isFromMe {
+				if req, ok := a.chatOps.Parse(chatJID, content); ok {
+					req.Sender = sender
+					req.MessageID = id
+					adminCommand = true
+					go a.runChatOps(ctx, req, msgTime)
+				}
+			}
+			if a.syncPaused.Load() {
+				return
+			}
+			// Votes update the results of a poll and are not messages
+			if v.Message.GetPollUpdateMessage() != nil {
+				a.recordPollVote(ctx, v, chatJID, sender)
+				return
+			}
+
 			chatName := a.client.ResolveChatName(ctx, chatJID, v)
 			if chatName == "" && chatJID != "" {
 				chatName = chatJID
 			}
 
-			// Store chat
-			a.store.StoreChat(chatJID, chatName, msgTime)
-
-			// Store message
-			a.store.StoreMessage(
-				id,
-				chatJID,
-				sender,
-				content,
-				msgTime,
-				isFromMe,
-				mediaType,
-				filename,
-				url,
-				directPath,
-				mimeType,
-				mediaKey, fileSHA256, fileEncSHA256, fileLength,
-			)
-
-			if directPath != "" && len(mediaKey) > 0 {
-				worker.Enqueue(mediaJob{messageID: id, chatJID: chatJID})
+			firstContact := a.isFirstContact(chatJID, isFromMe)
+
+			// Store chat and message; buffered if the store is currently failing
+			a.writer.Write(func() error {
+				if err := a.store.StoreChat(chatJID, chatName, msgTime); err != nil {
+					return err
+				}
+				if err := a.store.StoreMessage(
+					id,
+					chatJID,
+					sender,
+					content,
+					msgTime,
+					isFromMe,
+					mediaType,
+					filename,
+					url,
+					directPath,
+					mimeType,
+					mediaKey, fileSHA256, fileEncSHA256, fileLength,
+				); err != nil {
+					return err
+				}
+				if err := a.enrichMessage(enrichRequest{
+					ID:              id,
+					ChatJID:         chatJID,
+					Sender:          sender,
+					Content:         content,
+					Timestamp:       msgTime,
+					FileSHA256:      fileSHA256,
+					ForwardingScore: forwardingScore,
+					SenderDevice:    &senderDevice,
+					Live:            !isFromMe,
+				}); err != nil {
+					return err
+				}
+				if details.Poll != nil {
+					if err := a.storePoll(id, chatJID, *details.Poll, msgTime, nil); err != nil {
+						return err
+					}
+				}
+				a.publishMessage(chatJID, sender, msgTime, eventbus.Message{
+					ID:        id,
+					Content:   content,
+					IsFromMe:  isFromMe,
+					MediaType: mediaType,
+					Filename:  filename,
+					MimeType:  mimeType,
+				})
+				// The worker reads the stored row, so media is still fetched
+				// when this write is replayed after the store recovers, or
+				// when its key only came with a later copy of the message
+				if mediaType != "" {
+					worker.Enqueue(mediaJob{messageID: id, chatJID: chatJID})
+				}
+				return nil
+			})
+
+			if a.receipts.Read {
+				go a.markRead(ctx, v)
 			}
 
-			messageCount++
-			if onMessage != nil {
-				onMessage()
+			if isFromMe {
+				if mode, ok := a.parseHandoffCommand(content); ok {
+					a.switchChatMode(chatJID, mode)
+				}
+			}
+
+			// Automation stays silent while a human has taken over the chat
+			automated := !isFromMe && a.automationEnabled(chatJID)
+
+			if automated {
+				go a.maybeSendAway(ctx, chatJID, msgTime)
+			}
+
+			if a.bot != nil && automated && !adminCommand {
+				if req, ok := a.bot.Parse(chatJID, content); ok {
+					req.Sender = sender
+					req.MessageID = id
+					go a.runBotCommand(ctx, a.bot, req)
+				}
+			}
+
+			if !isFromMe && a.messageWebhook != nil {
+				go a.postMessageWebhook(ctx, messageWebhookData{
+					ID:        id,
+					ChatJID:   chatJID,
+					ChatName:  chatName,
+					Sender:    sender,
+					Name:      v.Info.PushName,
+					Content:   content,
+					MediaType: mediaType,
+					Filename:  filename,
+					MimeType:  mimeType,
+					Timestamp: msgTime,
+				})
+			}
+
+			if a.homeAssistant != nil {
+				name := v.Info.PushName
+				if name == "" || isFromMe {
+					name = sender
+				}
+				a.updateHomeAssistant(haChatState{
+					Text:      content,
+					Sender:    sender,
+					Name:      name,
+					ChatJID:   chatJID,
+					ChatName:  chatName,
+					MessageID: id,
+					IsFromMe:  isFromMe,
+					MediaType: mediaType,
+					Timestamp: msgTime,
+				})
+			}
+
+			if !isFromMe && a.recipes.Load() != nil {
+				name := v.Info.PushName
+				if name == "" {
+					name = sender
+				}
+				go a.runRecipes(ctx, recipeData{
+					MessageID: id,
+					ChatJID:   chatJID,
+					ChatName:  chatName,
+					Sender:    sender,
+					Name:      name,
+					Text:      content,
+				}, msgTime, automated)
 			}
+
+			if firstContact && automated {
+				phone := phoneNumber(chatJID)
+				name := v.Info.PushName
+				if name == "" {
+					name = phone
+				}
+				go a.greet(ctx, greetingData{
+					Name:    name,
+					Phone:   phone,
+					JID:     chatJID,
+					Message: content,
+				})
+			}
+
+			messageCount++
 			fmt.Fprintf(os.Stderr, "\r💬 Synced %d messages...", messageCount)
 
 		case *events.HistorySync:
@@ -715,17 +1264,24 @@ func (a *App) Sync(ctx context.Context, onMessage func()) string {
 				id := pn.GetID()
 				pushName := pn.GetPushname()
 				if id != "" && pushName != "" && pushName != "-" {
-					jid := id
-					if !strings.Contains(id, "@") {
-						jid = id + "@s.whatsapp.net"
-					}
-					a.store.UpdateChatName(jid, pushName)
+					a.store.UpdateChatName(jid.Normalize(id), pushName)
 				}
 			}
 
+			for _, m := range v.Data.GetPhoneNumberToLidMappings() {
+				if m.GetLidJID() != "" && m.GetPnJID() != "" {
+					a.linkLID(m.GetLidJID(), m.GetPnJID())
+				}
+			}
+
+			a.conn.history(v.Data.GetProgress(), time.Now())
 			fmt.Fprintf(os.Stderr, "\n📜 Processing history sync (%d conversations)...\n", len(v.Data.Conversations))
 			for _, conv := range v.Data.Conversations {
 				chatJID := conv.GetID()
+				if jid.IsLID(chatJID) && conv.GetPnJID() != "" {
+					a.linkLID(chatJID, conv.GetPnJID())
+				}
+				chatJID = a.canonicalChatJID(ctx, chatJID)
 				chatName := conv.GetName()
 				if chatName == "" {
 					chatName = a.client.ResolveChatName(ctx, chatJID, nil)
@@ -813,59 +1369,130 @@ func (a *App) Sync(ctx context.Context, onMessage func()) string {
 						fileEncSHA256 = doc.GetFileEncSHA256()
 						fileLength = doc.GetFileLength()
 					}
-
-					// Store chat
-					a.store.StoreChat(chatJID, chatName, msgTimestamp)
-
-					// Store message
-					a.store.StoreMessage(
-						msgID,
-						chatJID,
-						sender,
-						content,
-						msgTimestamp,
-						isFromMe,
-						mediaType,
-						filename,
-						url,
-						directPath,
-						mimeType,
-						mediaKey, fileSHA256, fileEncSHA256, fileLength,
-					)
-
-					if directPath != "" && len(mediaKey) > 0 {
-						worker.Enqueue(mediaJob{messageID: msgID, chatJID: chatJID})
+					poll := client.PollCreation(histMsg.Message)
+					if poll != nil {
+						content = poll.Question
 					}
 
+					// Store chat and message; buffered if the store is currently failing
+					a.writer.Write(func() error {
+						if err := a.store.StoreChat(chatJID, chatName, msgTimestamp); err != nil {
+							return err
+						}
+						if err := a.store.StoreMessage(
+							msgID,
+							chatJID,
+							sender,
+							content,
+							msgTimestamp,
+							isFromMe,
+							mediaType,
+							filename,
+							url,
+							directPath,
+							mimeType,
+							mediaKey, fileSHA256, fileEncSHA256, fileLength,
+						); err != nil {
+							return err
+						}
+						if err := a.enrichMessage(enrichRequest{
+							ID:         msgID,
+							ChatJID:    chatJID,
+							Sender:     sender,
+							Content:    content,
+							Timestamp:  msgTimestamp,
+							FileSHA256: fileSHA256,
+						}); err != nil {
+							return err
+						}
+						if poll != nil {
+							if err := a.storePoll(msgID, chatJID, *poll, msgTimestamp, histMsg.GetPollUpdates()); err != nil {
+								return err
+							}
+						}
+						a.publishMessage(chatJID, sender, msgTimestamp, eventbus.Message{
+							ID:        msgID,
+							Content:   content,
+							IsFromMe:  isFromMe,
+							MediaType: mediaType,
+							Filename:  filename,
+							MimeType:  mimeType,
+							History:   true,
+						})
+						if mediaType != "" {
+							worker.Enqueue(mediaJob{messageID: msgID, chatJID: chatJID})
+						}
+						return nil
+					})
+
 					messageCount++
-					if onMessage != nil {
-						onMessage()
-					}
 				}
 			}
 			fmt.Fprintf(os.Stderr, "\r💬 Synced %d messages...", messageCount)
 
+		case *events.IdentityChange:
+			a.recordIdentityChange(ctx, v)
+
+		case *events.GroupInfo:
+			a.handleGroupInfo(v)
+
+		case *events.Receipt:
+			if v.Type != types.ReceiptTypeRetry {
+				a.canary.Acknowledge(v.MessageIDs...)
+			}
+			a.publishReceipt(v)
+			a.recordReceipt(ctx, v)
+			a.recordReadSelf(ctx, v)
+
+		case *events.Presence:
+			a.publishPresence(v)
+
 		case *events.Connected:
+			a.conn.connect(time.Now())
+			a.quality.Connected(time.Now())
 			fmt.Fprintln(os.Stderr, "\n✓ Connected to WhatsApp")
 			fmt.Fprintln(os.Stderr, "🔄 Listening for messages... (Press Ctrl+C to stop)")
+			a.resolveAccountAlerts()
+
+		case *events.TemporaryBan, *events.LoggedOut, *events.ClientOutdated, *events.StreamReplaced, *events.ConnectFailure, *events.StreamError:
+			if alert, ok := accountAlertFor(v, time.Now()); ok {
+				a.raiseAccountAlert(alert)
+			}
 
 		case *events.OfflineSyncCompleted:
+			a.conn.offlineSyncCompleted()
 			// Contact store is now populated — refresh chat names
 			go a.RefreshChatNames(ctx)
+			if a.reconcileChats {
+				go a.reconcileOnSync(ctx)
+			}
+
+		case *events.KeepAliveTimeout:
+			a.quality.KeepaliveTimeout(time.Now())
+			fmt.Fprintf(os.Stderr, "\n⚠ WhatsApp did not answer %d keepalive(s) in a row\n", v.ErrorCount)
 
 		case *events.Disconnected:
+			a.conn.disconnect()
+			a.quality.Disconnected(time.Now())
 			fmt.Fprintln(os.Stderr, "\n⚠ Disconnected from WhatsApp")
 		}
 	}
 
 	// Start syncing
 	fmt.Fprintln(os.Stderr, "🚀 Starting WhatsApp sync...")
+	a.conn.start()
+	defer a.conn.stop()
 	if err := a.client.StartSync(ctx, eventHandler); err != nil {
 		return output.Error(err)
 	}
+	if a.client.IsConnected() {
+		a.conn.joined(time.Now())
+	}
+	a.syncing.Store(true)
 
 	// Wait for context cancellation (Ctrl+C)
 	<-ctx.Done()
+	a.syncing.Store(false)
 
 	fmt.Fprintf(os.Stderr, "\n\n✓ Sync completed. Total messages synced: %d\n", messageCount)
 
@@ -1,10 +1,19 @@
 package commands
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"image"
+	"image/png"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+	"golang.org/x/image/webp"
 )
 
 func TestResolveVersionReturnsExplicitValue(t *testing.T) {
@@ -39,3 +48,101 @@ func TestResolveVersionReturnsDevWhenDescribeFails(t *testing.T) {
 
 	require.Equal(t, "dev", version)
 }
+
+func TestSyncAliveTracksSyncLoop(t *testing.T) {
+	app, fake := newFakeApp(t)
+	require.False(t, app.SyncAlive(), "not alive before sync starts")
+
+	startSync(t, app, fake)
+	require.Eventually(t, app.SyncAlive, time.Second, 5*time.Millisecond)
+
+	fake.Disconnect()
+	require.False(t, app.SyncAlive(), "not alive while disconnected")
+}
+
+func TestSendMessageRejectsInvalidRecipient(t *testing.T) {
+	app, fake := newFakeApp(t)
+
+	result := app.SendMessage(context.Background(), "1234567890@broadcast", "hello", client.SendOptions{})
+	require.Contains(t, result, "broadcast lists are not supported")
+	result = app.SendMessage(context.Background(), "alice", "hello", client.SendOptions{})
+	require.Contains(t, result, "invalid recipient")
+	require.Empty(t, fake.Sent())
+
+	result = app.SendMessage(context.Background(), "120363123456789012@newsletter", "hello", client.SendOptions{})
+	require.Contains(t, result, `"success":true`)
+	require.Len(t, fake.Sent(), 1)
+}
+
+func TestSendMediaStoresAttachment(t *testing.T) {
+	app, fake := newFakeApp(t)
+
+	result := app.SendMedia(context.Background(), "1234567890", client.OutgoingMedia{})
+	require.Contains(t, result, "attachment is empty")
+
+	result = app.SendMedia(context.Background(), "1234567890", client.OutgoingMedia{
+		Data:     []byte("%PDF-1.4"),
+		MimeType: "application/pdf",
+		Filename: "invoice.pdf",
+		Caption:  "Your invoice",
+	})
+	require.Contains(t, result, `"media_type":"document"`)
+	require.Len(t, fake.Sent(), 1)
+	require.Contains(t, result, `"message_id":"`+fake.Sent()[0].ID+`"`)
+	require.NotNil(t, fake.Sent()[0].Media)
+	require.Equal(t, "1234567890@s.whatsapp.net", fake.Sent()[0].Recipient)
+	require.Equal(t, "document", fake.Sent()[0].Media.Type)
+
+	msgs, err := app.store.ListMessages(store.ListMessagesParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.Equal(t, "Your invoice", msgs[0].Content)
+	require.Equal(t, "document", msgs[0].MediaType)
+	require.Equal(t, fake.Sent()[0].ID, msgs[0].ID)
+}
+
+func TestSendMediaConvertsStickers(t *testing.T) {
+	app, fake := newFakeApp(t)
+
+	var src bytes.Buffer
+	require.NoError(t, png.Encode(&src, image.NewNRGBA(image.Rect(0, 0, 40, 20))))
+	result := app.SendMedia(context.Background(), "1234567890", client.OutgoingMedia{
+		Type: "sticker", Data: src.Bytes(), MimeType: "image/png", Caption: "ignored",
+	})
+	require.Contains(t, result, `"media_type":"sticker"`)
+	require.Len(t, fake.Sent(), 1)
+	media := fake.Sent()[0].Media
+	require.Equal(t, "image/webp", media.MimeType)
+	require.Empty(t, media.Caption)
+	cfg, err := webp.DecodeConfig(bytes.NewReader(media.Data))
+	require.NoError(t, err)
+	require.Equal(t, 512, cfg.Width)
+	require.Equal(t, 512, cfg.Height)
+
+	result = app.SendMedia(context.Background(), "1234567890", client.OutgoingMedia{Type: "sticker", Data: []byte("%PDF-1.4")})
+	require.Contains(t, result, "PNG, JPEG or WebP")
+}
+
+func TestSendVoiceNeedsFFmpeg(t *testing.T) {
+	app, fake := newFakeApp(t)
+	app.SetFFmpeg(filepath.Join(t.TempDir(), "ffmpeg"))
+
+	result := app.SendMedia(context.Background(), "1234567890", client.OutgoingMedia{Type: "voice", Data: []byte("OggS")})
+	require.Contains(t, result, "FFMPEG_PATH")
+	require.Empty(t, fake.Sent())
+}
+
+func TestNewReplicaAppServesStoreReadOnly(t *testing.T) {
+	primary, _ := newFakeApp(t)
+	require.NoError(t, primary.store.StoreChat("111@s.whatsapp.net", "Alice", time.Now()))
+	require.NoError(t, primary.store.StoreMessage("m1", "111@s.whatsapp.net", "111@s.whatsapp.net", "hello", time.Now(), false, "", "", "", "", "", nil, nil, nil, 0))
+
+	replica, err := NewReplicaApp(primary.storeDir, "test")
+	require.NoError(t, err)
+	defer replica.Close()
+
+	require.False(t, replica.IsAuthenticated())
+	require.Contains(t, replica.ListMessages(nil, nil, 10, 0, nil, nil, nil, nil, nil), `"content":"hello"`)
+	require.Contains(t, replica.SendMessage(context.Background(), "1234567890", "hi", client.SendOptions{}), "read-only replica")
+	require.Contains(t, replica.SetDraft("111@s.whatsapp.net", "later"), `"success":false`)
+}
@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/health"
+)
+
+const (
+	// catchUpTimeout bounds how long the sync loop counts as catching up
+	// after connecting, in case WhatsApp never reports the offline queue
+	// drained.
+	catchUpTimeout = 5 * time.Minute
+	// historyIdle is how long after its last chunk an unfinished history
+	// sync still counts as running.
+	historyIdle = time.Minute
+	// queuedSlack is how far a message may predate the connection and
+	// still count as live, allowing for clock skew with WhatsApp's servers.
+	queuedSlack = time.Minute
+	// pingInterval is how often the sync loop measures the round trip to
+	// WhatsApp's servers.
+	pingInterval = time.Minute
+)
+
+// connectionTracker follows the sync loop's connection to WhatsApp through
+// its events: connected, offline queue drained, history sync progress and
+// disconnected.
+type connectionTracker struct {
+	mu            sync.Mutex
+	running       bool
+	connected     bool
+	connectedAt   time.Time
+	offlineSynced bool
+	// historyAt is when the last chunk of an unfinished history sync
+	// arrived; zero when none is running.
+	historyAt time.Time
+}
+
+// start resets the tracker for a sync loop that is about to connect.
+func (t *connectionTracker) start() {
+	t.mu.Lock()
+	t.running, t.connected, t.offlineSynced = true, false, false
+	t.connectedAt, t.historyAt = time.Time{}, time.Time{}
+	t.mu.Unlock()
+}
+
+// stop records that the sync loop ended.
+func (t *connectionTracker) stop() {
+	t.mu.Lock()
+	t.running, t.connected = false, false
+	t.mu.Unlock()
+}
+
+func (t *connectionTracker) connect(now time.Time) {
+	t.mu.Lock()
+	t.connected, t.connectedAt, t.offlineSynced = true, now, false
+	t.mu.Unlock()
+}
+
+// joined records a connection made before the sync loop registered for
+// events, e.g. by QR pairing. Its offline queue went by unseen.
+func (t *connectionTracker) joined(now time.Time) {
+	t.mu.Lock()
+	if t.connectedAt.IsZero() {
+		t.connected, t.connectedAt, t.offlineSynced = true, now, true
+	}
+	t.mu.Unlock()
+}
+
+func (t *connectionTracker) disconnect() {
+	t.mu.Lock()
+	t.connected = false
+	t.mu.Unlock()
+}
+
+func (t *connectionTracker) offlineSyncCompleted() {
+	t.mu.Lock()
+	t.offlineSynced = true
+	t.mu.Unlock()
+}
+
+// history records a history sync chunk that brought the sync to progress
+// percent.
+func (t *connectionTracker) history(progress uint32, now time.Time) {
+	t.mu.Lock()
+	if progress >= 100 {
+		t.historyAt = time.Time{}
+	} else {
+		t.historyAt = now
+	}
+	t.mu.Unlock()
+}
+
+// queued reports whether a message sent at ts waited in the offline queue
+// for the current connection.
+func (t *connectionTracker) queued(ts time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return !t.connectedAt.IsZero() && ts.Before(t.connectedAt.Add(-queuedSlack))
+}
+
+func (t *connectionTracker) state(now time.Time) health.Connection {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case !t.running:
+		return health.ConnectionUnknown
+	case t.connectedAt.IsZero():
+		return health.ConnectionConnecting
+	case !t.connected:
+		return health.ConnectionOffline
+	case !t.offlineSynced && now.Sub(t.connectedAt) < catchUpTimeout,
+		!t.historyAt.IsZero() && now.Sub(t.historyAt) < historyIdle:
+		return health.ConnectionCatchingUp
+	}
+	return health.ConnectionOnline
+}
+
+// Connection reports the sync loop's connection to WhatsApp, including
+// whether it is still catching up on what it missed.
+func (a *App) Connection() health.Connection {
+	return a.conn.state(time.Now())
+}
+
+// ConnectionQuality reports the round trips of sends and pings, keepalive
+// timeouts and outages of the last health.QualityWindow.
+func (a *App) ConnectionQuality() health.QualityReport {
+	return a.quality.Report(time.Now())
+}
+
+// runPings measures the round trip to WhatsApp every interval until ctx is
+// cancelled.
+func (a *App) runPings(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.ping(ctx)
+		}
+	}
+}
+
+// ping measures one round trip. While disconnected there is nothing to
+// measure; the outage is accounted for already.
+func (a *App) ping(ctx context.Context) {
+	if !a.client.IsConnected() {
+		return
+	}
+	rtt, err := a.client.Ping(ctx)
+	if ctx.Err() != nil {
+		return
+	}
+	a.quality.Ping(time.Now(), rtt, err == nil)
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/health"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestConnectionTracker(t *testing.T) {
+	var c connectionTracker
+	now := time.Now()
+	assert.Equal(t, health.ConnectionUnknown, c.state(now))
+
+	c.start()
+	assert.Equal(t, health.ConnectionConnecting, c.state(now))
+	c.connect(now)
+	assert.Equal(t, health.ConnectionCatchingUp, c.state(now), "offline queue not drained yet")
+	assert.Equal(t, health.ConnectionOnline, c.state(now.Add(catchUpTimeout)), "WhatsApp never said it was drained")
+	c.offlineSyncCompleted()
+	assert.Equal(t, health.ConnectionOnline, c.state(now))
+
+	c.history(40, now)
+	assert.Equal(t, health.ConnectionCatchingUp, c.state(now.Add(time.Second)))
+	assert.Equal(t, health.ConnectionOnline, c.state(now.Add(historyIdle)), "history sync went quiet")
+	c.history(100, now)
+	assert.Equal(t, health.ConnectionOnline, c.state(now))
+
+	c.disconnect()
+	assert.Equal(t, health.ConnectionOffline, c.state(now))
+	c.connect(now)
+	assert.Equal(t, health.ConnectionCatchingUp, c.state(now))
+
+	c.stop()
+	assert.Equal(t, health.ConnectionUnknown, c.state(now))
+
+	// Paired and connected before the sync loop listened for events
+	c.start()
+	c.joined(now)
+	assert.Equal(t, health.ConnectionOnline, c.state(now))
+}
+
+func TestSyncReportsConnection(t *testing.T) {
+	app, fake := newFakeApp(t)
+	assert.Equal(t, health.ConnectionUnknown, app.Connection())
+	startSync(t, app, fake)
+	assert.Eventually(t, func() bool {
+		return app.Connection() == health.ConnectionOnline
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestSyncReportsConnectionQuality(t *testing.T) {
+	app, fake := newFakeApp(t)
+	startSync(t, app, fake)
+	fake.SetPingRTT(40 * time.Millisecond)
+
+	app.ping(context.Background())
+	assert.Contains(t, app.SendMessage(context.Background(), "34600111222", "hi", client.SendOptions{}), `"success":true`)
+	fake.Emit(&events.KeepAliveTimeout{ErrorCount: 1})
+	fake.Emit(&events.Disconnected{})
+
+	q := app.ConnectionQuality()
+	assert.Equal(t, 1, q.Ping.Samples)
+	assert.InDelta(t, 0.04, q.Ping.Last, 1e-9)
+	assert.Equal(t, 1, q.Send.Samples)
+	assert.Zero(t, q.Send.Failures)
+	assert.Equal(t, 1, q.KeepaliveTimeouts)
+	assert.Equal(t, 1, q.Disconnects)
+	assert.NotNil(t, q.OfflineSince)
+
+	fake.Emit(&events.Connected{})
+	q = app.ConnectionQuality()
+	assert.Nil(t, q.OfflineSince)
+	assert.Positive(t, q.OfflineSeconds)
+}
@@ -0,0 +1,215 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// Context window defaults: the budget used when none is given and the
+// largest one accepted.
+const (
+	DefaultContextTokens = 2000
+	MaxContextTokens     = 100000
+)
+
+// contextPage is the number of messages read per query while filling a
+// context window.
+const contextPage = 200
+
+// Roles of context window messages.
+const (
+	RoleMe   = "me"
+	RoleThem = "them"
+)
+
+// ContextMessage is one message of a context window.
+type ContextMessage struct {
+	ID        string    `json:"id"`
+	Role      string    `json:"role"`
+	Sender    string    `json:"sender"`
+	Name      string    `json:"name,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Content   string    `json:"content"`
+}
+
+// ChatContextWindow is the recent history of a chat cut to a token budget,
+// ready to be placed in an LLM prompt.
+type ChatContextWindow struct {
+	ChatJID  string `json:"chat_jid"`
+	ChatName string `json:"chat_name,omitempty"`
+	Budget   int    `json:"token_budget"`
+	// Tokens is the estimated size of Transcript.
+	Tokens int `json:"estimated_tokens"`
+	// Truncated reports that older messages, or the start of the oldest one
+	// included, did not fit.
+	Truncated bool             `json:"truncated"`
+	Messages  []ContextMessage `json:"messages"`
+	// Transcript has one "[time] role: content" line per message, oldest
+	// first; in groups other senders are named: "[time] them (Alice): …".
+	Transcript string `json:"transcript"`
+}
+
+// estimateTokens approximates the tokens of s for common LLM tokenizers,
+// which average about four characters per token on chat text.
+func estimateTokens(s string) int {
+	return (utf8.RuneCountInString(s) + 3) / 4
+}
+
+// ChatContext returns the most recent messages of a chat that fit in about
+// tokens tokens, oldest first, labelled by role for prompt assembly.
+// Quarantined messages are left out.
+func (a *App) ChatContext(chatJID string, tokens int, includeJIDs, excludeJIDs []string, after *time.Time) string {
+	if tokens <= 0 {
+		tokens = DefaultContextTokens
+	}
+	if tokens > MaxContextTokens {
+		return output.Error(fmt.Errorf("tokens must be at most %d", MaxContextTokens))
+	}
+	w := ChatContextWindow{ChatJID: chatJID, Budget: tokens, Messages: []ContextMessage{}}
+	group := jid.IsGroup(chatJID)
+
+	var lines []string // newest first
+	var names map[string]string
+	used := 0
+collect:
+	for page := 0; ; page++ {
+		messages, err := a.store.ListMessages(store.ListMessagesParams{
+			ChatJID:      &chatJID,
+			HideSpamFrom: a.spam.QuarantineThreshold,
+			Limit:        contextPage,
+			Page:         page,
+			IncludeJIDs:  includeJIDs,
+			ExcludeJIDs:  excludeJIDs,
+			After:        after,
+		})
+		if err != nil {
+			return output.Error(err)
+		}
+		if group {
+			if names, err = a.senderNames(messages, names); err != nil {
+				return output.Error(err)
+			}
+		}
+		for _, m := range messages {
+			if w.ChatName == "" && m.ChatName != m.ChatJID {
+				w.ChatName = m.ChatName
+			}
+			cm := contextMessage(m, names)
+			if cm.Content == "" {
+				continue
+			}
+			line := contextLine(cm, group)
+			cost := estimateTokens(line) + 1 // newline
+			if used+cost > tokens {
+				w.Truncated = true
+				if len(w.Messages) == 0 {
+					// Keep the end of the latest message rather than nothing
+					prefix := strings.TrimSuffix(line, cm.Content)
+					room := (tokens - estimateTokens(prefix) - 2) * 4
+					cm.Content = "…" + lastRunes(cm.Content, room)
+					line = prefix + cm.Content
+					w.Messages = append(w.Messages, cm)
+					lines = append(lines, line)
+					used += estimateTokens(line) + 1
+				}
+				break collect
+			}
+			w.Messages = append(w.Messages, cm)
+			lines = append(lines, line)
+			used += cost
+		}
+		if len(messages) < contextPage {
+			break
+		}
+	}
+
+	// Oldest first, as a conversation is read
+	for i, j := 0, len(w.Messages)-1; i < j; i, j = i+1, j-1 {
+		w.Messages[i], w.Messages[j] = w.Messages[j], w.Messages[i]
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	w.Transcript = strings.Join(lines, "\n")
+	w.Tokens = estimateTokens(w.Transcript)
+	return output.Success(w)
+}
+
+// senderNames adds the names of the senders of messages missing from names.
+func (a *App) senderNames(messages []store.Message, names map[string]string) (map[string]string, error) {
+	if names == nil {
+		names = make(map[string]string)
+	}
+	var missing []string
+	seen := make(map[string]bool)
+	for _, m := range messages {
+		sender := jid.Normalize(m.Sender)
+		if _, ok := names[sender]; ok || m.IsFromMe || seen[sender] {
+			continue
+		}
+		seen[sender] = true
+		missing = append(missing, sender)
+	}
+	found, err := a.store.ChatNames(missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, sender := range missing {
+		names[sender] = found[sender]
+	}
+	return names, nil
+}
+
+// contextMessage labels a stored message by role. Media without a caption is
+// shown as its type, e.g. "[image]".
+func contextMessage(m store.Message, names map[string]string) ContextMessage {
+	cm := ContextMessage{
+		ID:        m.ID,
+		Role:      RoleThem,
+		Sender:    m.Sender,
+		Timestamp: m.Timestamp,
+		Content:   strings.TrimSpace(m.Content),
+	}
+	if m.IsFromMe {
+		cm.Role = RoleMe
+	} else {
+		cm.Name = names[jid.Normalize(m.Sender)]
+	}
+	if m.MediaType != "" {
+		if cm.Content == "" {
+			cm.Content = "[" + m.MediaType + "]"
+		} else {
+			cm.Content = "[" + m.MediaType + "] " + cm.Content
+		}
+	}
+	return cm
+}
+
+// contextLine formats a message as a transcript line.
+func contextLine(m ContextMessage, group bool) string {
+	label := m.Role
+	if group && m.Role == RoleThem {
+		who := m.Name
+		if who == "" {
+			who = m.Sender
+		}
+		label += " (" + who + ")"
+	}
+	return fmt.Sprintf("[%s] %s: %s", m.Timestamp.UTC().Format("2006-01-02 15:04"), label, m.Content)
+}
+
+// lastRunes returns the last n runes of s.
+func lastRunes(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[len(r)-n:])
+}
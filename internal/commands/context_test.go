@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeContext(t *testing.T, result string) ChatContextWindow {
+	t.Helper()
+	var resp struct {
+		Success bool              `json:"success"`
+		Data    ChatContextWindow `json:"data"`
+		Error   string            `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result), &resp))
+	require.True(t, resp.Success, result)
+	return resp.Data
+}
+
+func TestChatContext(t *testing.T) {
+	app, _ := newFakeApp(t)
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	st := app.store
+	require.NoError(t, st.StoreChat(testGroupJID, "Climbing", base))
+	require.NoError(t, st.StoreChat("111@s.whatsapp.net", "Alice", base))
+	require.NoError(t, st.StoreMessage("m1", testGroupJID, "111@s.whatsapp.net", "anyone free saturday?", base, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, st.StoreMessage("m2", testGroupJID, "222@s.whatsapp.net", "", base.Add(time.Minute), false, "image", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, st.StoreMessage("m3", testGroupJID, "me", "I am, 10am?", base.Add(2*time.Minute), true, "", "", "", "", "", nil, nil, nil, 0))
+
+	w := decodeContext(t, app.ChatContext(testGroupJID, 0, nil, nil, nil))
+	assert.Equal(t, "Climbing", w.ChatName)
+	assert.Equal(t, DefaultContextTokens, w.Budget)
+	assert.False(t, w.Truncated)
+	require.Len(t, w.Messages, 3)
+	assert.Equal(t, []string{"m1", "m2", "m3"}, []string{w.Messages[0].ID, w.Messages[1].ID, w.Messages[2].ID})
+	assert.Equal(t, RoleThem, w.Messages[0].Role)
+	assert.Equal(t, "Alice", w.Messages[0].Name)
+	assert.Equal(t, RoleMe, w.Messages[2].Role)
+	assert.Equal(t, "[2026-03-01 09:00] them (Alice): anyone free saturday?\n"+
+		"[2026-03-01 09:01] them (222@s.whatsapp.net): [image]\n"+
+		"[2026-03-01 09:02] me: I am, 10am?", w.Transcript)
+	assert.Equal(t, estimateTokens(w.Transcript), w.Tokens)
+
+	// Only the newest messages that fit are kept
+	w = decodeContext(t, app.ChatContext(testGroupJID, 25, nil, nil, nil))
+	assert.True(t, w.Truncated)
+	require.Len(t, w.Messages, 2)
+	assert.Equal(t, "m2", w.Messages[0].ID)
+	assert.LessOrEqual(t, w.Tokens, 25)
+}
+
+func TestChatContextTruncatesLongMessage(t *testing.T) {
+	app, _ := newFakeApp(t)
+	chat := "333@s.whatsapp.net"
+	now := time.Now().UTC()
+	require.NoError(t, app.store.StoreChat(chat, "Bob", now))
+	require.NoError(t, app.store.StoreMessage("long", chat, chat, strings.Repeat("words ", 200)+"the end", now, false, "", "", "", "", "", nil, nil, nil, 0))
+
+	w := decodeContext(t, app.ChatContext(chat, 50, nil, nil, nil))
+	assert.True(t, w.Truncated)
+	require.Len(t, w.Messages, 1)
+	assert.True(t, strings.HasPrefix(w.Messages[0].Content, "…"))
+	assert.True(t, strings.HasSuffix(w.Transcript, "the end"))
+	assert.NotContains(t, w.Transcript, "(", "direct chats do not name the sender")
+	assert.LessOrEqual(t, w.Tokens, 50)
+
+	assert.Contains(t, app.ChatContext(chat, MaxContextTokens+1, nil, nil, nil), "tokens must be at most")
+}
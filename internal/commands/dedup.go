@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Ways of handling a send identical to a recent one.
+const (
+	// SendDedupReject refuses the duplicate.
+	SendDedupReject = "reject"
+	// SendDedupFlag sends it and marks the result "duplicate".
+	SendDedupFlag = "flag"
+)
+
+// sendDeduper remembers the sends of the last window to spot retries of
+// upstream systems that resend the same message.
+type sendDeduper struct {
+	mu     sync.Mutex
+	window time.Duration
+	flag   bool
+	sent   map[dedupKey]time.Time
+	now    func() time.Time
+}
+
+// dedupKey identifies a send by its recipient and content.
+type dedupKey = [sha256.Size]byte
+
+// SetSendDedup makes SendMessage and SendMedia treat a send with the same
+// recipient and content as one made within window as a duplicate: mode
+// SendDedupReject refuses it, SendDedupFlag sends it and marks the result.
+// A zero window disables it.
+func (a *App) SetSendDedup(window time.Duration, mode string) error {
+	if window < 0 {
+		return fmt.Errorf("invalid send dedup window %s", window)
+	}
+	if mode != SendDedupReject && mode != SendDedupFlag {
+		return fmt.Errorf("invalid send dedup mode %q: must be %q or %q", mode, SendDedupReject, SendDedupFlag)
+	}
+	if window == 0 {
+		a.dedup = nil
+		return nil
+	}
+	a.dedup = &sendDeduper{window: window, flag: mode == SendDedupFlag, sent: map[dedupKey]time.Time{}, now: time.Now}
+	return nil
+}
+
+func newDedupKey(recipient string, content ...[]byte) dedupKey {
+	h := sha256.New()
+	h.Write([]byte(recipient))
+	for _, c := range content {
+		h.Write([]byte{0})
+		h.Write(c)
+	}
+	var k dedupKey
+	h.Sum(k[:0])
+	return k
+}
+
+// check records a send under k. If the same send was made within the
+// window it returns when, and whether the send may go ahead.
+func (d *sendDeduper) check(k dedupKey) (prev time.Time, dup, allowed bool) {
+	if d == nil {
+		return time.Time{}, false, true
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.now()
+	for key, at := range d.sent {
+		if now.Sub(at) >= d.window {
+			delete(d.sent, key)
+		}
+	}
+	prev, dup = d.sent[k]
+	if dup && !d.flag {
+		return prev, true, false
+	}
+	d.sent[k] = now
+	return prev, dup, true
+}
+
+// forget undoes the send check recorded under k, so that a send which
+// failed can be retried; prev is the time check returned.
+func (d *sendDeduper) forget(k dedupKey, prev time.Time) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	if prev.IsZero() {
+		delete(d.sent, k)
+	} else {
+		d.sent[k] = prev
+	}
+	d.mu.Unlock()
+}
+
+// duplicateError is the error of a rejected duplicate send.
+func duplicateError(recipient string, prev, now time.Time) error {
+	return fmt.Errorf("duplicate send: the same message was sent to %s %s ago", recipient, now.Sub(prev).Round(time.Second))
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+)
+
+func TestSendDedupRejectsRetries(t *testing.T) {
+	app, fake := newFakeApp(t)
+	require.NoError(t, app.SetSendDedup(30*time.Second, SendDedupReject))
+	clock := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	app.dedup.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	assert.Contains(t, app.SendMessage(ctx, "1234567890", "invoice #1", client.SendOptions{}), `"sent":true`)
+	// Recipients are compared in their canonical form
+	result := app.SendMessage(ctx, "+1 234 567 890", "invoice #1", client.SendOptions{})
+	assert.Contains(t, result, "duplicate send")
+	assert.Contains(t, app.SendMessage(ctx, "1234567890", "invoice #2", client.SendOptions{}), `"sent":true`)
+	assert.Len(t, fake.Sent(), 2)
+
+	media := client.OutgoingMedia{Data: []byte("%PDF"), Filename: "a.pdf", MimeType: "application/pdf"}
+	assert.Contains(t, app.SendMedia(ctx, "1234567890", media), `"sent":true`)
+	assert.Contains(t, app.SendMedia(ctx, "1234567890", media), "duplicate send")
+	media.Caption = "updated"
+	assert.Contains(t, app.SendMedia(ctx, "1234567890", media), `"sent":true`)
+
+	clock = clock.Add(30 * time.Second)
+	assert.Contains(t, app.SendMessage(ctx, "1234567890", "invoice #1", client.SendOptions{}), `"sent":true`, "window passed")
+	assert.Len(t, fake.Sent(), 5)
+}
+
+func TestSendDedupFlagsRetries(t *testing.T) {
+	app, fake := newFakeApp(t)
+	require.NoError(t, app.SetSendDedup(time.Minute, SendDedupFlag))
+	ctx := context.Background()
+
+	assert.NotContains(t, app.SendMessage(ctx, "1234567890", "hi", client.SendOptions{}), "duplicate")
+	result := app.SendMessage(ctx, "1234567890", "hi", client.SendOptions{})
+	assert.Contains(t, result, `"sent":true`)
+	assert.Contains(t, result, `"duplicate":true`)
+	assert.Contains(t, result, `"duplicate_of"`)
+	assert.Len(t, fake.Sent(), 2)
+}
+
+func TestSendDedupForgetsFailedSends(t *testing.T) {
+	d := &sendDeduper{window: time.Minute, sent: map[dedupKey]time.Time{}, now: time.Now}
+	k := newDedupKey("1@s.whatsapp.net", []byte("hi"))
+	prev, dup, ok := d.check(k)
+	require.True(t, ok)
+	assert.False(t, dup)
+	d.forget(k, prev)
+	_, _, ok = d.check(k)
+	assert.True(t, ok, "a failed send can be retried")
+	_, _, ok = d.check(k)
+	assert.False(t, ok)
+
+	app, _ := newFakeApp(t)
+	assert.Error(t, app.SetSendDedup(time.Minute, "drop"))
+	assert.Error(t, app.SetSendDedup(-time.Second, SendDedupReject))
+	require.NoError(t, app.SetSendDedup(0, SendDedupReject))
+	assert.Nil(t, app.dedup)
+}
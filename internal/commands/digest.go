@@ -0,0 +1,388 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/vicentereig/whatsapp-cli/internal/eventbus"
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/langdetect"
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// Digest limits: entries per ranking, the shortest word counted as a topic
+// and the messages read per query.
+const (
+	digestTop          = 10
+	digestTopSenders   = 5
+	digestMinTopicLen  = 4
+	digestMessagesPage = 500
+)
+
+// DigestConfig schedules chat digests.
+type DigestConfig struct {
+	// Chats are the chats summarized.
+	Chats []string
+	// Periods lists the digests produced per chat: "daily", "weekly" or both.
+	Periods []string
+	// At is the "HH:MM" time in Timezone at which digests are produced.
+	// Weekly digests are produced on Mondays and cover the previous week.
+	At       string
+	Timezone string
+	// WebhookURL, if set, receives every digest as a JSON POST.
+	WebhookURL string
+}
+
+// Digest summarizes a chat over a period.
+type Digest struct {
+	ID        int64     `json:"id,omitempty"`
+	ChatJID   string    `json:"chat_jid"`
+	ChatName  string    `json:"chat_name,omitempty"`
+	Period    string    `json:"period"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	CreatedAt time.Time `json:"created_at"`
+	Messages  int       `json:"messages"`
+	FromMe    int       `json:"from_me"`
+	// Senders ranks who wrote most, other than the account itself.
+	Senders []DigestCount `json:"top_senders"`
+	// Topics ranks the words used in most messages, ignoring short and
+	// common words.
+	Topics []DigestCount `json:"top_topics"`
+	Links  []DigestCount `json:"top_links"`
+	// Unanswered lists the latest questions nobody else replied to within
+	// the period, oldest first.
+	Unanswered []ContextMessage `json:"unanswered_questions"`
+}
+
+// DigestCount is one entry of a digest ranking.
+type DigestCount struct {
+	Value string `json:"value"`
+	Name  string `json:"name,omitempty"`
+	Count int    `json:"count"`
+}
+
+type digester struct {
+	chats      []string
+	periods    []string
+	hour, min  int
+	loc        *time.Location
+	webhookURL string
+	httpClient *http.Client
+}
+
+// SetDigests schedules digests of the configured chats while syncing. A
+// config without chats disables them.
+func (a *App) SetDigests(cfg DigestConfig) error {
+	if len(cfg.Chats) == 0 {
+		a.digests = nil
+		return nil
+	}
+	d := &digester{
+		loc:        time.UTC,
+		webhookURL: cfg.WebhookURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, chat := range cfg.Chats {
+		d.chats = append(d.chats, jid.Normalize(chat))
+	}
+	for _, p := range cfg.Periods {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p != store.DigestDaily && p != store.DigestWeekly {
+			return fmt.Errorf("invalid digest period %q: must be %q or %q", p, store.DigestDaily, store.DigestWeekly)
+		}
+		d.periods = append(d.periods, p)
+	}
+	if len(d.periods) == 0 {
+		d.periods = []string{store.DigestDaily}
+	}
+	if cfg.At == "" {
+		cfg.At = "08:00"
+	}
+	at, err := parseClock(cfg.At, false)
+	if err != nil {
+		return fmt.Errorf("invalid digest time %q: %w", cfg.At, err)
+	}
+	d.hour, d.min = int(at/time.Hour), int(at%time.Hour/time.Minute)
+	if cfg.Timezone != "" {
+		if d.loc, err = time.LoadLocation(cfg.Timezone); err != nil {
+			return fmt.Errorf("invalid digest timezone %q", cfg.Timezone)
+		}
+	}
+	a.digests = d
+	return nil
+}
+
+// bounds returns the latest complete period at now.
+func (d *digester) bounds(period string, now time.Time) (start, end time.Time) {
+	local := now.In(d.loc)
+	y, m, day := local.Date()
+	end = time.Date(y, m, day, d.hour, d.min, 0, 0, d.loc)
+	if end.After(now) {
+		end = end.AddDate(0, 0, -1)
+	}
+	if period == store.DigestWeekly {
+		for end.Weekday() != time.Monday {
+			end = end.AddDate(0, 0, -1)
+		}
+		return end.AddDate(0, 0, -7), end
+	}
+	return end.AddDate(0, 0, -1), end
+}
+
+// runDigests produces due digests every interval until ctx is cancelled.
+func (a *App) runDigests(ctx context.Context, interval time.Duration) {
+	if a.digests == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		a.produceDigests(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// produceDigests generates, stores and delivers the digests of the latest
+// complete periods that do not exist yet. Periods missed while the daemon
+// was down are not caught up.
+func (a *App) produceDigests(ctx context.Context, now time.Time) {
+	d := a.digests
+	for _, period := range d.periods {
+		start, end := d.bounds(period, now)
+		for _, chat := range d.chats {
+			if done, err := a.store.HasDigest(chat, period, end); err != nil || done {
+				continue
+			}
+			digest, err := a.buildDigest(chat, period, start, end)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "⚠ Failed to build %s digest of %s: %v\n", period, chat, err)
+				continue
+			}
+			data, _ := json.Marshal(digest)
+			id, stored, err := a.store.StoreDigest(store.DigestRecord{
+				ChatJID:   chat,
+				Period:    period,
+				Start:     start,
+				End:       end,
+				CreatedAt: digest.CreatedAt,
+				Data:      data,
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "⚠ Failed to store %s digest of %s: %v\n", period, chat, err)
+				continue
+			}
+			if stored {
+				digest.ID = id
+				a.deliverDigest(ctx, digest)
+			}
+		}
+	}
+}
+
+// deliverDigest pushes a digest to the notifier and the digest webhook.
+func (a *App) deliverDigest(ctx context.Context, digest Digest) {
+	data, _ := json.Marshal(digest)
+	if a.notifier != nil {
+		a.notifier.Publish(eventbus.Event{
+			Type:      eventbus.TypeDigest,
+			ChatJID:   digest.ChatJID,
+			Timestamp: digest.CreatedAt,
+			Digest:    data,
+		})
+	}
+
+	d := a.digests
+	if d.webhookURL == "" {
+		return
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"event":  "digest",
+		"digest": json.RawMessage(data),
+	})
+	err := a.postWebhook(ctx, d.httpClient, d.webhookURL, body)
+	if err == nil {
+		return
+	}
+	if a.outboxMaxAge <= 0 || isPermanent(err) {
+		fmt.Fprintf(os.Stderr, "⚠ Digest webhook failed: %v\n", err)
+		return
+	}
+	if qerr := a.enqueueOutbox(SinkDigestWebhook, d.webhookURL, digest.ChatJID, body); qerr != nil {
+		fmt.Fprintf(os.Stderr, "⚠ Digest webhook failed: %v (not queued: %v)\n", err, qerr)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "⚠ Digest webhook failed, queued for retry: %v\n", err)
+}
+
+// buildDigest summarizes the messages of a chat in [start, end).
+func (a *App) buildDigest(chatJID, period string, start, end time.Time) (Digest, error) {
+	digest := Digest{
+		ChatJID:    chatJID,
+		Period:     period,
+		Start:      start.UTC(),
+		End:        end.UTC(),
+		CreatedAt:  time.Now().UTC(),
+		Senders:    []DigestCount{},
+		Topics:     []DigestCount{},
+		Links:      []DigestCount{},
+		Unanswered: []ContextMessage{},
+	}
+
+	// After is exclusive; step back so a message at start is included
+	after := start.Add(-time.Nanosecond)
+	var messages []store.Message // newest first
+	for page := 0; ; page++ {
+		batch, err := a.store.ListMessages(store.ListMessagesParams{
+			ChatJID:      &chatJID,
+			After:        &after,
+			Before:       &end,
+			HideSpamFrom: a.spam.QuarantineThreshold,
+			Limit:        digestMessagesPage,
+			Page:         page,
+		})
+		if err != nil {
+			return Digest{}, err
+		}
+		messages = append(messages, batch...)
+		if len(batch) < digestMessagesPage {
+			break
+		}
+	}
+	names, err := a.senderNames(messages, nil)
+	if err != nil {
+		return Digest{}, err
+	}
+
+	senders := make(map[string]int)
+	topics := make(map[string]int)
+	links := make(map[string]int)
+	var open []ContextMessage // questions not replied to yet, oldest first
+	for i := len(messages) - 1; i >= 0; i-- {
+		m := messages[i]
+		if digest.ChatName == "" && m.ChatName != m.ChatJID {
+			digest.ChatName = m.ChatName
+		}
+		digest.Messages++
+		sender := jid.Normalize(m.Sender)
+		if m.IsFromMe {
+			digest.FromMe++
+		} else {
+			senders[sender]++
+		}
+
+		// Anyone else writing answers the open questions
+		kept := open[:0]
+		for _, q := range open {
+			if (q.Sender == sender && !m.IsFromMe) || (m.Content == "" && m.MediaType == "") {
+				kept = append(kept, q)
+			}
+		}
+		open = kept
+
+		text := m.Content
+		for _, link := range linkRe.FindAllString(text, -1) {
+			links[strings.TrimRight(link, ".,;:!?)")]++
+		}
+		for word := range topicWords(linkRe.ReplaceAllString(text, " ")) {
+			topics[word]++
+		}
+		if !m.IsFromMe && strings.Contains(text, "?") {
+			open = append(open, ContextMessage{
+				ID:        m.ID,
+				Role:      RoleThem,
+				Sender:    sender,
+				Name:      names[sender],
+				Timestamp: m.Timestamp,
+				Content:   strings.TrimSpace(text),
+			})
+		}
+	}
+
+	digest.Senders = topCounts(senders, digestTopSenders, 1)
+	for i := range digest.Senders {
+		digest.Senders[i].Name = names[digest.Senders[i].Value]
+	}
+	digest.Topics = topCounts(topics, digestTop, 2)
+	digest.Links = topCounts(links, digestTop, 1)
+	if len(open) > digestTop {
+		open = open[len(open)-digestTop:]
+	}
+	digest.Unanswered = append(digest.Unanswered, open...)
+	return digest, nil
+}
+
+// linkRe matches http(s) links in message text.
+var linkRe = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// topicWords returns the distinct words of text that can be topics.
+func topicWords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	}) {
+		w = strings.Trim(w, "'")
+		if utf8.RuneCountInString(w) < digestMinTopicLen || langdetect.IsStopword(w) || strings.IndexFunc(w, unicode.IsLetter) < 0 {
+			continue
+		}
+		words[w] = true
+	}
+	return words
+}
+
+// topCounts returns up to n entries counted at least min times, most
+// frequent first.
+func topCounts(counts map[string]int, n, min int) []DigestCount {
+	out := []DigestCount{}
+	for v, c := range counts {
+		if c >= min {
+			out = append(out, DigestCount{Value: v, Count: c})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Value < out[j].Value
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// ListDigests returns the latest stored digests of a chat, newest first,
+// optionally only those of one period.
+func (a *App) ListDigests(chatJID, period string, limit int) string {
+	if period != "" && period != store.DigestDaily && period != store.DigestWeekly {
+		return output.Error(fmt.Errorf("invalid period %q: must be %q or %q", period, store.DigestDaily, store.DigestWeekly))
+	}
+	records, err := a.store.ListDigests(chatJID, period, limit)
+	if err != nil {
+		return output.Error(err)
+	}
+	digests := make([]Digest, 0, len(records))
+	for _, r := range records {
+		var d Digest
+		if err := json.Unmarshal(r.Data, &d); err != nil {
+			return output.Error(fmt.Errorf("digest %d: %w", r.ID, err))
+		}
+		d.ID = r.ID
+		digests = append(digests, d)
+	}
+	return output.Success(digests)
+}
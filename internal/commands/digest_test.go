@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+func TestDigestBounds(t *testing.T) {
+	app, _ := newFakeApp(t)
+	require.NoError(t, app.SetDigests(DigestConfig{Chats: []string{testGroupJID}, At: "08:00", Timezone: "Europe/Madrid"}))
+	d := app.digests
+	madrid := d.loc
+
+	// Wednesday 2026-03-04 07:30 Madrid: today's digest is not due yet
+	now := time.Date(2026, 3, 4, 7, 30, 0, 0, madrid)
+	start, end := d.bounds(store.DigestDaily, now)
+	assert.Equal(t, time.Date(2026, 3, 2, 8, 0, 0, 0, madrid), start)
+	assert.Equal(t, time.Date(2026, 3, 3, 8, 0, 0, 0, madrid), end)
+
+	start, end = d.bounds(store.DigestWeekly, now)
+	assert.Equal(t, time.Date(2026, 2, 23, 8, 0, 0, 0, madrid), start)
+	assert.Equal(t, time.Date(2026, 3, 2, 8, 0, 0, 0, madrid), end)
+
+	assert.Error(t, app.SetDigests(DigestConfig{Chats: []string{testGroupJID}, Periods: []string{"monthly"}}))
+	assert.Error(t, app.SetDigests(DigestConfig{Chats: []string{testGroupJID}, At: "8am"}))
+}
+
+func TestDigests(t *testing.T) {
+	var mu sync.Mutex
+	var hooks []map[string]any
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		hooks = append(hooks, body)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	app, _ := newFakeApp(t)
+	require.NoError(t, app.SetDigests(DigestConfig{Chats: []string{testGroupJID}, WebhookURL: hook.URL}))
+	st := app.store
+	base := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	alice, bob := "111@s.whatsapp.net", "222@s.whatsapp.net"
+	require.NoError(t, st.StoreChat(testGroupJID, "Climbing", base))
+	require.NoError(t, st.StoreChat(alice, "Alice", base))
+	msgs := []struct {
+		id, sender, text string
+		fromMe           bool
+	}{
+		{"m1", alice, "Who has the rope for saturday?", false},
+		{"m2", bob, "I have the rope, see https://example.com/gear.", false},
+		{"m3", alice, "Great! Weather for saturday looks good: https://example.com/gear", false},
+		{"m4", bob, "Can someone drive?", false},
+		{"m5", bob, "Anyone??", false},
+		{"m6", "me", "count me in", true},
+		{"m7", alice, "Which crag?", false},
+	}
+	for i, m := range msgs {
+		require.NoError(t, st.StoreMessage(m.id, testGroupJID, m.sender, m.text, base.Add(time.Duration(i)*time.Minute), m.fromMe, "", "", "", "", "", nil, nil, nil, 0))
+	}
+	// Outside the period
+	require.NoError(t, st.StoreMessage("old", testGroupJID, alice, "old question?", base.AddDate(0, 0, -2), false, "", "", "", "", "", nil, nil, nil, 0))
+
+	app.produceDigests(context.Background(), time.Date(2026, 3, 3, 8, 30, 0, 0, time.UTC))
+	app.produceDigests(context.Background(), time.Date(2026, 3, 3, 9, 30, 0, 0, time.UTC))
+
+	var resp struct {
+		Success bool     `json:"success"`
+		Data    []Digest `json:"data"`
+	}
+	result := app.ListDigests(testGroupJID, "", 10)
+	require.NoError(t, json.Unmarshal([]byte(result), &resp))
+	require.True(t, resp.Success, result)
+	require.Len(t, resp.Data, 1, "a period is summarized once")
+	d := resp.Data[0]
+	assert.NotZero(t, d.ID)
+	assert.Equal(t, "Climbing", d.ChatName)
+	assert.Equal(t, store.DigestDaily, d.Period)
+	assert.Equal(t, time.Date(2026, 3, 3, 8, 0, 0, 0, time.UTC), d.End)
+	assert.Equal(t, 7, d.Messages)
+	assert.Equal(t, 1, d.FromMe)
+	assert.Equal(t, []DigestCount{{Value: alice, Name: "Alice", Count: 3}, {Value: bob, Count: 3}}, d.Senders)
+	assert.Equal(t, []DigestCount{{Value: "https://example.com/gear", Count: 2}}, d.Links)
+	assert.Equal(t, []DigestCount{{Value: "rope", Count: 2}, {Value: "saturday", Count: 2}}, d.Topics)
+	require.Len(t, d.Unanswered, 1)
+	assert.Equal(t, "m7", d.Unanswered[0].ID, "questions followed by someone else's message are answered")
+	assert.Equal(t, "Alice", d.Unanswered[0].Name)
+
+	mu.Lock()
+	require.Len(t, hooks, 1)
+	assert.Equal(t, "digest", hooks[0]["event"])
+	assert.Equal(t, testGroupJID, hooks[0]["digest"].(map[string]any)["chat_jid"])
+	mu.Unlock()
+
+	assert.Contains(t, app.ListDigests(testGroupJID, "monthly", 10), "invalid period")
+}
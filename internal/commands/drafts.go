@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+)
+
+// maxDraftLength is the longest draft kept, WhatsApp's limit for a text
+// message.
+const maxDraftLength = 65536
+
+// GetDraft returns the draft kept for a chat.
+func (a *App) GetDraft(chatJID string) string {
+	d, err := a.store.GetDraft(chatJID)
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(d)
+}
+
+// SetDraft keeps a partially composed message for a chat so it can be picked
+// up later, e.g. by another step of an agent. An empty text discards the
+// draft.
+func (a *App) SetDraft(chatJID, text string) string {
+	if text == "" {
+		if err := a.store.DeleteDraft(chatJID); err != nil {
+			return output.Error(err)
+		}
+		return a.GetDraft(chatJID)
+	}
+	if n := utf8.RuneCountInString(text); n > maxDraftLength {
+		return output.Error(fmt.Errorf("draft is too long: %d characters, at most %d", n, maxDraftLength))
+	}
+	if err := a.store.SetDraft(chatJID, text, time.Now().UTC()); err != nil {
+		return output.Error(err)
+	}
+	return a.GetDraft(chatJID)
+}
@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrafts(t *testing.T) {
+	app, _ := newFakeApp(t)
+	chat := "120363012345678901@g.us"
+
+	assert.Contains(t, app.GetDraft(chat), `"text":""`)
+	assert.Contains(t, app.SetDraft(chat, "Step 1: confirm the date"), `"text":"Step 1: confirm the date"`)
+	assert.Contains(t, app.GetDraft(chat), `"text":"Step 1: confirm the date"`)
+
+	assert.Contains(t, app.SetDraft(chat, strings.Repeat("x", maxDraftLength+1)), "draft is too long")
+	assert.Contains(t, app.GetDraft(chat), "Step 1", "rejected draft leaves the old one")
+
+	assert.Contains(t, app.SetDraft(chat, ""), `"text":""`)
+	assert.Contains(t, app.GetDraft(chat), `"text":""`)
+}
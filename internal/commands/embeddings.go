@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/embeddings"
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+const (
+	// embedBatch is the number of messages sent per embeddings request.
+	embedBatch = 64
+	// maxEmbedRunes cuts the text embedded per message; embedding models
+	// accept a few thousand tokens at most.
+	maxEmbedRunes = 6000
+)
+
+// errSemanticSearchDisabled is returned by SemanticSearch without an embedder.
+var errSemanticSearchDisabled = errors.New("semantic search is not enabled (set EMBEDDINGS_URL and EMBEDDINGS_MODEL)")
+
+// SemanticResults is the response of SemanticSearch.
+type SemanticResults struct {
+	Model string `json:"model"`
+	// Indexed and Pending count the messages with text that have a vector and
+	// that are still waiting for one; pending messages cannot be found yet.
+	Indexed  int64                 `json:"indexed"`
+	Pending  int64                 `json:"pending"`
+	Messages []store.ScoredMessage `json:"messages"`
+}
+
+// SetEmbedder enables the embeddings index: while syncing, message text is
+// vectorized with e in the background, and SemanticSearch becomes available.
+func (a *App) SetEmbedder(e *embeddings.Client) {
+	a.embedder = e
+}
+
+// runEmbeddings indexes messages without a vector every interval, starting
+// with a backfill of the existing archive, newest messages first.
+func (a *App) runEmbeddings(ctx context.Context, interval time.Duration) {
+	if a.embedder == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := a.indexEmbeddings(ctx); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "⚠ Embedding messages failed after %d: %v\n", n, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// indexEmbeddings embeds messages until none are left without a vector and
+// returns how many it embedded. It stops at the first failing request; the
+// rest is picked up on the next run.
+func (a *App) indexEmbeddings(ctx context.Context) (int, error) {
+	model := a.embedder.Model()
+	total := 0
+	for ctx.Err() == nil {
+		messages, err := a.store.UnembeddedMessages(model, embedBatch)
+		if err != nil || len(messages) == 0 {
+			return total, err
+		}
+		texts := make([]string, len(messages))
+		for i, m := range messages {
+			texts[i] = embeddingText(m.Content)
+		}
+		vectors, err := a.embedder.Embed(ctx, texts)
+		if err != nil {
+			return total, err
+		}
+		batch := make([]store.Embedding, len(messages))
+		for i, m := range messages {
+			batch[i] = store.Embedding{MessageID: m.ID, ChatJID: m.ChatJID, Vector: vectors[i]}
+		}
+		if err := a.store.StoreEmbeddings(model, batch); err != nil {
+			return total, err
+		}
+		total += len(batch)
+	}
+	return total, ctx.Err()
+}
+
+// embeddingText prepares message content for embedding.
+func embeddingText(content string) string {
+	content = strings.TrimSpace(content)
+	if r := []rune(content); len(r) > maxEmbedRunes {
+		content = string(r[:maxEmbedRunes])
+	}
+	return content
+}
+
+// SemanticSearch returns the messages closest in meaning to query, best
+// first. Quarantined messages are left out.
+func (a *App) SemanticSearch(ctx context.Context, query string, chatJID *string, limit int, includeJIDs, excludeJIDs []string, after *time.Time) string {
+	if a.embedder == nil {
+		return output.Error(errSemanticSearchDisabled)
+	}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return output.Error(errors.New("query is required"))
+	}
+	vectors, err := a.embedder.Embed(ctx, []string{embeddingText(query)})
+	if err != nil {
+		return output.Error(fmt.Errorf("failed to embed query: %w", err))
+	}
+	model := a.embedder.Model()
+	messages, err := a.store.SemanticSearch(store.SemanticSearchParams{
+		Vector:       vectors[0],
+		Model:        model,
+		ChatJID:      chatJID,
+		After:        after,
+		Limit:        limit,
+		IncludeJIDs:  includeJIDs,
+		ExcludeJIDs:  excludeJIDs,
+		HideSpamFrom: a.spam.QuarantineThreshold,
+	})
+	if err != nil {
+		return output.Error(err)
+	}
+	indexed, pending, err := a.store.EmbeddingCounts(model)
+	if err != nil {
+		return output.Error(err)
+	}
+	if messages == nil {
+		messages = []store.ScoredMessage{}
+	}
+	return output.Success(SemanticResults{Model: model, Indexed: indexed, Pending: pending, Messages: messages})
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/embeddings"
+)
+
+// newTopicEmbedder serves embeddings with one dimension per topic keyword, a
+// stand-in for a real model.
+func newTopicEmbedder(t *testing.T, topics ...string) *embeddings.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		vectors := make([][]float32, len(req.Input))
+		for i, text := range req.Input {
+			v := make([]float32, len(topics)+1)
+			v[len(topics)] = 0.1
+			for j, topic := range topics {
+				if strings.Contains(strings.ToLower(text), topic) {
+					v[j] = 1
+				}
+			}
+			vectors[i] = v
+		}
+		json.NewEncoder(w).Encode(map[string]any{"embeddings": vectors})
+	}))
+	t.Cleanup(srv.Close)
+	e, err := embeddings.New(srv.URL, "topics", "")
+	require.NoError(t, err)
+	return e
+}
+
+func TestSemanticSearch(t *testing.T) {
+	app, _ := newFakeApp(t)
+	chat := "111@s.whatsapp.net"
+	now := time.Now().UTC()
+	require.NoError(t, app.store.StoreChat(chat, "Alice", now))
+	require.NoError(t, app.store.StoreMessage("m1", chat, chat, "the bike needs new brakes", now, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, app.store.StoreMessage("m2", chat, chat, "dinner at eight?", now.Add(-time.Minute), false, "", "", "", "", "", nil, nil, nil, 0))
+
+	assert.Contains(t, app.SemanticSearch(context.Background(), "bike", nil, 5, nil, nil, nil), "semantic search is not enabled")
+
+	app.SetEmbedder(newTopicEmbedder(t, "bike", "dinner"))
+	n, err := app.indexEmbeddings(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	var resp struct {
+		Success bool            `json:"success"`
+		Data    SemanticResults `json:"data"`
+	}
+	result := app.SemanticSearch(context.Background(), "when is dinner", nil, 1, nil, nil, nil)
+	require.NoError(t, json.Unmarshal([]byte(result), &resp))
+	require.True(t, resp.Success, result)
+	assert.Equal(t, "topics", resp.Data.Model)
+	assert.Equal(t, int64(2), resp.Data.Indexed)
+	assert.Equal(t, int64(0), resp.Data.Pending)
+	require.Len(t, resp.Data.Messages, 1)
+	assert.Equal(t, "m2", resp.Data.Messages[0].ID)
+
+	// New messages are picked up by the next run
+	require.NoError(t, app.store.StoreMessage("m3", chat, chat, "bike shop closes at six", now, false, "", "", "", "", "", nil, nil, nil, 0))
+	n, err = app.indexEmbeddings(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/langdetect"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// enrichRequest describes a freshly stored message to derive metadata from.
+type enrichRequest struct {
+	ID              string
+	ChatJID         string
+	Sender          string
+	Content         string
+	Timestamp       time.Time
+	FileSHA256      []byte
+	ForwardingScore uint32
+	// SenderDevice is nil when unknown, e.g. for history.
+	SenderDevice *int
+	// Live is set for messages received in real time from someone else; only
+	// those are scored for spam, since history has already been seen.
+	Live bool
+}
+
+// enrichMessage derives metadata from a message's content and stores it next
+// to the message, and with hash chaining on appends it to its chat's chain.
+// It runs inside the same buffered write as StoreMessage, so a replayed write
+// is enriched too.
+func (a *App) enrichMessage(req enrichRequest) error {
+	if a.hashChain {
+		if err := a.store.ChainMessage(req.ID, req.ChatJID, time.Now()); err != nil {
+			return err
+		}
+	}
+	e := store.Enrichment{
+		Lang:         langdetect.Detect(req.Content),
+		ContentHash:  store.ContentHash(req.Content, req.FileSHA256),
+		SenderDevice: req.SenderDevice,
+	}
+	if req.Live {
+		newSender, err := a.store.IsNewSender(req.ChatJID, req.Sender, req.Timestamp)
+		if err != nil {
+			return err
+		}
+		e.SpamScore, e.SpamFlags = scoreSpam(newSender, req.Content, req.ForwardingScore)
+		if e.IdentityChangedAt, err = a.recentIdentityChange(req.Sender, req.Timestamp); err != nil {
+			return err
+		}
+	}
+	if e.Lang == "" && e.ContentHash == "" && e.SpamScore == 0 && e.SenderDevice == nil && e.IdentityChangedAt == nil {
+		return nil
+	}
+	return a.store.EnrichMessage(req.ID, req.ChatJID, e)
+}
@@ -0,0 +1,24 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+func TestSendMessageStoresDetectedLanguage(t *testing.T) {
+	app, _ := newFakeApp(t)
+
+	result := app.SendMessage(context.Background(), "1234567890", "Ich bin heute nicht im Büro, aber morgen schon", client.SendOptions{})
+	require.Contains(t, result, `"success":true`)
+
+	lang := "de"
+	msgs, err := app.store.ListMessages(store.ListMessagesParams{Lang: &lang, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "de", msgs[0].Lang)
+}
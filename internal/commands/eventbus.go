@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/eventbus"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// receiptTypes maps the receipts worth replicating to their published name;
+// retries, history sync and other protocol receipts are skipped.
+var receiptTypes = map[types.ReceiptType]string{
+	types.ReceiptTypeDelivered:  "delivered",
+	types.ReceiptTypeRead:       "read",
+	types.ReceiptTypeReadSelf:   "read",
+	types.ReceiptTypePlayed:     "played",
+	types.ReceiptTypePlayedSelf: "played",
+}
+
+// SetEventBus replicates stored messages, receipts and presence updates to
+// bus while syncing. A nil bus disables replication.
+func (a *App) SetEventBus(bus *eventbus.Bus) {
+	a.events = bus
+	a.spoolBus(bus, SinkEventBus)
+}
+
+// SetNotifier pushes new-message events to bus (e.g. Redis pub/sub) as an
+// alternative to webhooks. A nil bus disables it.
+func (a *App) SetNotifier(bus *eventbus.Bus) {
+	a.notifier = bus
+	a.spoolBus(bus, SinkNotifier)
+}
+
+// eventBuses returns the configured buses.
+func (a *App) eventBuses() []*eventbus.Bus {
+	var buses []*eventbus.Bus
+	for _, b := range []*eventbus.Bus{a.events, a.notifier} {
+		if b != nil {
+			buses = append(buses, b)
+		}
+	}
+	return buses
+}
+
+// publishing reports whether published events have anywhere to go.
+func (a *App) publishing() bool {
+	return len(a.eventBuses()) > 0 || a.onEvent.Load() != nil
+}
+
+// publishEvent sends e to the configured buses and to the Sync caller.
+func (a *App) publishEvent(e eventbus.Event) {
+	for _, b := range a.eventBuses() {
+		b.Publish(e)
+	}
+	if onEvent := a.onEvent.Load(); onEvent != nil {
+		(*onEvent)(e)
+	}
+}
+
+// publishMessage replicates a message once it has been written to the store.
+func (a *App) publishMessage(chatJID, sender string, ts time.Time, msg eventbus.Message) {
+	if !a.publishing() {
+		return
+	}
+	a.publishEvent(eventbus.Event{
+		Type:      eventbus.TypeMessage,
+		ChatJID:   chatJID,
+		Sender:    sender,
+		Timestamp: ts,
+		Message:   &msg,
+	})
+}
+
+func (a *App) publishReceipt(evt *events.Receipt) {
+	if !a.publishing() {
+		return
+	}
+	receiptType, ok := receiptTypes[evt.Type]
+	if !ok {
+		return
+	}
+	ids := make([]string, len(evt.MessageIDs))
+	copy(ids, evt.MessageIDs)
+	a.publishEvent(eventbus.Event{
+		Type:      eventbus.TypeReceipt,
+		ChatJID:   evt.Chat.String(),
+		Sender:    evt.Sender.String(),
+		Timestamp: evt.Timestamp,
+		Receipt:   &eventbus.Receipt{MessageIDs: ids, Type: receiptType},
+	})
+}
+
+func (a *App) publishPresence(evt *events.Presence) {
+	if !a.publishing() {
+		return
+	}
+	presence := &eventbus.Presence{Available: !evt.Unavailable}
+	if !evt.LastSeen.IsZero() {
+		lastSeen := evt.LastSeen
+		presence.LastSeen = &lastSeen
+	}
+	a.publishEvent(eventbus.Event{
+		Type:      eventbus.TypePresence,
+		Sender:    evt.From.String(),
+		Timestamp: time.Now().UTC(),
+		Presence:  presence,
+	})
+}
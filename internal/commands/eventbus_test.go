@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/eventbus"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+type recordingPublisher struct {
+	mu     sync.Mutex
+	topics []string
+	events []eventbus.Event
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, topic, _ string, payload []byte) error {
+	var e eventbus.Event
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.topics = append(p.topics, topic)
+	p.events = append(p.events, e)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error { return nil }
+
+func (p *recordingPublisher) snapshot() ([]string, []eventbus.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.topics...), append([]eventbus.Event(nil), p.events...)
+}
+
+func TestSyncPublishesStoredEvents(t *testing.T) {
+	app, fake := newFakeApp(t)
+	pub := &recordingPublisher{}
+	app.SetEventBus(eventbus.NewBus(pub, eventbus.FormatJSON, "wa"))
+	startSync(t, app, fake)
+
+	alice := types.NewJID("111", types.DefaultUserServer)
+	ts := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	fake.EmitText(alice, alice, "M1", "hello", ts)
+	fake.Emit(&events.Receipt{
+		MessageSource: types.MessageSource{Chat: alice, Sender: alice},
+		MessageIDs:    []types.MessageID{"M0"},
+		Timestamp:     ts,
+		Type:          types.ReceiptTypeRead,
+	})
+	fake.Emit(&events.Receipt{MessageSource: types.MessageSource{Chat: alice}, Type: types.ReceiptTypeRetry})
+	fake.Emit(&events.Presence{From: alice, Unavailable: true, LastSeen: ts})
+
+	require.Eventually(t, func() bool {
+		topics, _ := pub.snapshot()
+		return len(topics) == 3
+	}, time.Second, 5*time.Millisecond)
+
+	topics, evts := pub.snapshot()
+	assert.Equal(t, []string{"wa.messages", "wa.receipts", "wa.presence"}, topics)
+	require.NotNil(t, evts[0].Message)
+	assert.Equal(t, "M1", evts[0].Message.ID)
+	assert.Equal(t, "hello", evts[0].Message.Content)
+	assert.Equal(t, alice.String(), evts[0].ChatJID)
+	assert.Equal(t, &eventbus.Receipt{MessageIDs: []string{"M0"}, Type: "read"}, evts[1].Receipt)
+	require.NotNil(t, evts[2].Presence)
+	assert.False(t, evts[2].Presence.Available)
+	assert.True(t, ts.Equal(*evts[2].Presence.LastSeen))
+}
+
+func TestSyncPublishesToNotifierAlongsideEventBus(t *testing.T) {
+	app, fake := newFakeApp(t)
+	replicated, notified := &recordingPublisher{}, &recordingPublisher{}
+	app.SetEventBus(eventbus.NewBus(replicated, eventbus.FormatJSON, ""))
+	app.SetNotifier(eventbus.NewBus(notified, eventbus.FormatJSON, ""))
+	startSync(t, app, fake)
+
+	alice := types.NewJID("111", types.DefaultUserServer)
+	fake.EmitText(alice, alice, "M1", "hello", time.Now())
+
+	for _, pub := range []*recordingPublisher{replicated, notified} {
+		require.Eventually(t, func() bool {
+			topics, _ := pub.snapshot()
+			return len(topics) == 1
+		}, time.Second, 5*time.Millisecond)
+	}
+}
+
+func TestSyncPassesEventsToCaller(t *testing.T) {
+	app, fake := newFakeApp(t)
+	var mu sync.Mutex
+	var evts []eventbus.Event
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		app.Sync(ctx, func(e eventbus.Event) {
+			mu.Lock()
+			evts = append(evts, e)
+			mu.Unlock()
+		})
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	require.Eventually(t, fake.IsConnected, time.Second, 5*time.Millisecond)
+
+	// Without any bus configured, the caller still gets every event
+	alice := types.NewJID("111", types.DefaultUserServer)
+	fake.EmitText(alice, alice, "M1", "hello", time.Now())
+	fake.Emit(&events.Receipt{
+		MessageSource: types.MessageSource{Chat: alice, Sender: alice},
+		MessageIDs:    []types.MessageID{"M0"},
+		Timestamp:     time.Now(),
+		Type:          types.ReceiptTypeDelivered,
+	})
+	fake.Emit(&events.Presence{From: alice})
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(evts) == 3
+	}, time.Second, 5*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, eventbus.TypeMessage, evts[0].Type)
+	assert.Equal(t, "M1", evts[0].Message.ID)
+	assert.Equal(t, eventbus.TypeReceipt, evts[1].Type)
+	assert.Equal(t, eventbus.TypePresence, evts[2].Type)
+}
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+)
+
+// ExportPostgres writes the message database to outputPath as a psql script
+// that creates the tables, loads every row and verifies the row counts
+// before committing. Nothing is written to Postgres; the script is run with
+// psql.
+func (a *App) ExportPostgres(ctx context.Context, outputPath string) string {
+	if outputPath == "" {
+		return output.Error(fmt.Errorf("--output is required"))
+	}
+
+	// Write next to the target and rename, so a failed export never leaves a
+	// truncated script behind
+	f, err := os.CreateTemp(filepath.Dir(outputPath), ".export-*.sql")
+	if err != nil {
+		return output.Error(fmt.Errorf("failed to create output file: %w", err))
+	}
+	defer os.Remove(f.Name())
+
+	tables, err := a.store.ExportPostgres(ctx, f, func(table string, done, total int64) {
+		fmt.Fprintf(os.Stderr, "\r📦 Copying %s: %d/%d rows", table, done, total)
+		if done == total {
+			fmt.Fprintln(os.Stderr)
+		}
+	})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return output.Error(err)
+	}
+	if err := os.Rename(f.Name(), outputPath); err != nil {
+		return output.Error(err)
+	}
+
+	var rows int64
+	for _, t := range tables {
+		rows += t.Rows
+	}
+	return output.Success(map[string]interface{}{
+		"output": outputPath,
+		"tables": tables,
+		"rows":   rows,
+	})
+}
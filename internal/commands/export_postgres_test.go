@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportPostgresWritesScript(t *testing.T) {
+	app, _ := newFakeApp(t)
+	require.NoError(t, app.store.StoreChat("123@s.whatsapp.net", "Alice", time.Now()))
+	require.NoError(t, app.store.StoreMessage("m1", "123@s.whatsapp.net", "123", "hi", time.Now(), false, "", "", "", "", "", nil, nil, nil, 0))
+
+	out := filepath.Join(t.TempDir(), "dump.sql")
+	var resp struct {
+		Success bool `json:"success"`
+		Data    struct {
+			Output string `json:"output"`
+			Rows   int64  `json:"rows"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(app.ExportPostgres(context.Background(), out)), &resp))
+	require.True(t, resp.Success)
+	assert.Equal(t, out, resp.Data.Output)
+	assert.Equal(t, int64(2), resp.Data.Rows)
+
+	dump, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(dump), "COMMIT;\n"))
+
+	entries, err := os.ReadDir(filepath.Dir(out))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary file left behind")
+}
+
+func TestExportPostgresRequiresOutput(t *testing.T) {
+	app, _ := newFakeApp(t)
+	result := app.ExportPostgres(context.Background(), "")
+	assert.Contains(t, result, "--output is required")
+	assert.Contains(t, result, "success\":false")
+}
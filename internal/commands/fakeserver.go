@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/client/fakeclient"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// FakeServerOptions controls the data a fake server starts with.
+type FakeServerOptions struct {
+	Chats    int
+	Messages int
+	Seed     int64
+	// Paired starts the fake account paired; otherwise it walks through
+	// the QR flow first.
+	Paired bool
+}
+
+// fakeSeedEpoch anchors seeded timestamps, so the same seed yields the same
+// data on every run and clients can snapshot responses.
+var fakeSeedEpoch = time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+
+var fakeNames = []string{"Alice", "Bob", "Carol", "Dave", "Erin", "Frank", "Grace", "Heidi", "Ivan", "Judy"}
+
+// NewFakeApp creates an App on a fakeclient in storeDir and seeds the store
+// with deterministic chats and messages, so the API can be developed against
+// without a WhatsApp account. The returned client completes a pending QR
+// pairing when its Pair is called.
+func NewFakeApp(storeDir, version string, opts FakeServerOptions) (*App, *fakeclient.Client, error) {
+	if opts.Chats <= 0 || opts.Messages < 0 {
+		return nil, nil, fmt.Errorf("chats must be positive and messages not negative")
+	}
+	fake := fakeclient.New()
+	if opts.Paired {
+		fake = fakeclient.NewPaired()
+	}
+	app, err := NewAppWithClient(storeDir, version, fake)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := app.seedFake(fake, opts); err != nil {
+		app.Close()
+		return nil, nil, err
+	}
+	return app, fake, nil
+}
+
+// fakeChat is a seeded chat and the contacts who write in it.
+type fakeChat struct {
+	jid     types.JID
+	name    string
+	members []types.JID
+}
+
+// fakeChats returns n deterministic chats, every fourth of them a group.
+func fakeChats(n int) []fakeChat {
+	contact := func(i int) types.JID {
+		return types.NewJID(fmt.Sprintf("1555%07d", i), types.DefaultUserServer)
+	}
+	chats := make([]fakeChat, n)
+	for i := range chats {
+		if i%4 == 3 {
+			chats[i] = fakeChat{
+				jid:     types.NewJID(fmt.Sprintf("120363%012d", i), types.GroupServer),
+				name:    fmt.Sprintf("Group %d", i),
+				members: []types.JID{contact(i - 1), contact(i - 2), contact(i - 3)},
+			}
+			continue
+		}
+		c := contact(i)
+		chats[i] = fakeChat{jid: c, name: fakeNames[i%len(fakeNames)], members: []types.JID{c}}
+		if i >= len(fakeNames) {
+			chats[i].name = fmt.Sprintf("%s %d", chats[i].name, i/len(fakeNames)+1)
+		}
+	}
+	return chats
+}
+
+func (a *App) seedFake(fake *fakeclient.Client, opts FakeServerOptions) error {
+	rng := rand.New(rand.NewSource(opts.Seed))
+	chats := fakeChats(opts.Chats)
+	last := make(map[string]time.Time, len(chats))
+
+	msgs := make([]store.Message, 0, opts.Messages)
+	for i := 0; i < opts.Messages; i++ {
+		chat := chats[rng.Intn(len(chats))]
+		fromMe := rng.Intn(3) == 0
+		sender := chat.members[rng.Intn(len(chat.members))].User
+		if fromMe {
+			sender = fakeclient.OwnJID.User
+		}
+		ts := fakeSeedEpoch.Add(time.Duration(i) * 7 * time.Minute)
+		msgs = append(msgs, store.Message{
+			ID:        fmt.Sprintf("FAKE%d-%06d", opts.Seed, i),
+			ChatJID:   chat.jid.String(),
+			Sender:    sender,
+			Content:   loadGenSentence(rng),
+			Timestamp: ts,
+			IsFromMe:  fromMe,
+		})
+		last[chat.jid.String()] = ts
+	}
+	for _, chat := range chats {
+		fake.SetChatName(chat.jid.String(), chat.name)
+		if chat.jid.Server == types.GroupServer {
+			members := make([]string, len(chat.members))
+			for i, m := range chat.members {
+				members[i] = m.String()
+			}
+			fake.AddGroupParticipants(chat.jid.String(), members...)
+		}
+		ts, ok := last[chat.jid.String()]
+		if !ok {
+			ts = fakeSeedEpoch
+		}
+		if err := a.store.StoreChat(chat.jid.String(), chat.name, ts); err != nil {
+			return err
+		}
+	}
+	for start := 0; start < len(msgs); start += loadGenBatchSize {
+		end := min(start+loadGenBatchSize, len(msgs))
+		if err := a.store.StoreMessages(msgs[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunFakeIncoming delivers a deterministic incoming message from one of the
+// seeded chats every interval until ctx is done, so clients can exercise
+// webhooks and event streams. Messages only reach the store once sync runs.
+func RunFakeIncoming(ctx context.Context, fake *fakeclient.Client, opts FakeServerOptions, every time.Duration) {
+	rng := rand.New(rand.NewSource(opts.Seed))
+	chats := fakeChats(opts.Chats)
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for n := 0; ; n++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		chat := chats[rng.Intn(len(chats))]
+		sender := chat.members[rng.Intn(len(chat.members))]
+		fake.EmitText(chat.jid, sender, fmt.Sprintf("FAKEIN%d-%06d", opts.Seed, n), loadGenSentence(rng), time.Now())
+	}
+}
@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+func TestNewFakeAppSeedsDeterministicData(t *testing.T) {
+	seeded := func() []store.Message {
+		app, _, err := NewFakeApp(t.TempDir(), "test", FakeServerOptions{Chats: 6, Messages: 40, Seed: 3, Paired: true})
+		require.NoError(t, err)
+		t.Cleanup(func() { app.Close() })
+		msgs, err := app.store.ListMessages(store.ListMessagesParams{Limit: 100})
+		require.NoError(t, err)
+		chats, err := app.store.ListChats(store.ListChatsParams{Limit: 10})
+		require.NoError(t, err)
+		assert.Len(t, chats, 6)
+		return msgs
+	}
+	first := seeded()
+	require.Len(t, first, 40)
+	assert.Equal(t, first, seeded())
+}
+
+func TestNewFakeAppPairsThroughQR(t *testing.T) {
+	app, fake, err := NewFakeApp(t.TempDir(), "test", FakeServerOptions{Chats: 1})
+	require.NoError(t, err)
+	t.Cleanup(func() { app.Close() })
+	assert.False(t, app.IsAuthenticated())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var codes []string
+	paired := make(chan struct{})
+	go func() {
+		app.AuthWithQRCallback(ctx, func(code string) {
+			codes = append(codes, code)
+			if len(codes) == 2 {
+				fake.Pair()
+			}
+		}, func() { close(paired) })
+	}()
+	select {
+	case <-paired:
+	case <-ctx.Done():
+		t.Fatal("pairing did not complete")
+	}
+	assert.Equal(t, []string{"fake-qr-1", "fake-qr-2"}, codes)
+	assert.True(t, app.IsAuthenticated())
+}
+
+func TestNewFakeAppRejectsNoChats(t *testing.T) {
+	_, _, err := NewFakeApp(t.TempDir(), "test", FakeServerOptions{})
+	assert.Error(t, err)
+}
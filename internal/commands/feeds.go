@@ -0,0 +1,243 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/feed"
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+)
+
+// Feed defaults and bounds.
+const (
+	DefaultFeedInterval = 15 * time.Minute
+	minFeedInterval     = time.Minute
+	// maxFeedPosts bounds the items posted per feed and chat in one poll, so
+	// a feed republishing its archive does not flood the chat.
+	maxFeedPosts      = 5
+	maxFeedBytes      = 10 << 20
+	maxFeedImageBytes = 5 << 20
+	// feedItemRetention is how long an item is remembered after it left
+	// its feed.
+	feedItemRetention = 30 * 24 * time.Hour
+)
+
+// FeedConfig posts the new items of RSS and Atom feeds to chats.
+type FeedConfig struct {
+	// Feeds maps each feed URL to the chats, groups or channels its items
+	// are posted to.
+	Feeds map[string][]string
+	// Interval is how often feeds are polled, DefaultFeedInterval by default.
+	Interval time.Duration
+	// Images sends an item's image with its title and link as caption.
+	Images bool
+}
+
+// feedPoller polls the configured feeds.
+type feedPoller struct {
+	urls       []string
+	chats      map[string][]string
+	interval   time.Duration
+	images     bool
+	httpClient *http.Client
+}
+
+// SetFeeds enables posting feed items while syncing. No feeds disables it.
+func (a *App) SetFeeds(cfg FeedConfig) error {
+	if len(cfg.Feeds) == 0 {
+		a.feeds = nil
+		return nil
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = DefaultFeedInterval
+	}
+	if cfg.Interval < minFeedInterval {
+		return fmt.Errorf("feed interval must be at least %s", minFeedInterval)
+	}
+	p := &feedPoller{
+		chats:      make(map[string][]string, len(cfg.Feeds)),
+		interval:   cfg.Interval,
+		images:     cfg.Images,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for feedURL, chats := range cfg.Feeds {
+		u, err := url.Parse(feedURL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("invalid feed URL %q: must be http(s)", feedURL)
+		}
+		if len(chats) == 0 {
+			return fmt.Errorf("feed %s has no chats", u.Redacted())
+		}
+		for _, c := range chats {
+			chat, err := jid.ParseRecipient(c)
+			if err != nil {
+				return err
+			}
+			p.chats[feedURL] = append(p.chats[feedURL], chat)
+		}
+		p.urls = append(p.urls, feedURL)
+	}
+	sort.Strings(p.urls)
+	a.feeds = p
+	return nil
+}
+
+// runFeeds polls the feeds every interval until ctx is cancelled.
+func (a *App) runFeeds(ctx context.Context) {
+	p := a.feeds
+	if p == nil {
+		return
+	}
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		a.pollFeeds(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollFeeds posts the items that appeared in each feed since the previous
+// poll, oldest first. The first poll of a feed for a chat only records its
+// current items, so adding a feed does not post its whole history. Each
+// item is posted once: a send that fails is reported and not retried.
+func (a *App) pollFeeds(ctx context.Context, now time.Time) {
+	p := a.feeds
+	for _, feedURL := range p.urls {
+		f, err := p.fetch(ctx, feedURL)
+		if err != nil {
+			if ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "⚠ Feed %s: %v\n", redactURL(feedURL), err)
+			}
+			continue
+		}
+		for _, chat := range p.chats[feedURL] {
+			polled, err := a.store.HasFeedItems(feedURL, chat)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "⚠ Feed %s: %v\n", redactURL(feedURL), err)
+				continue
+			}
+			var items []feed.Item
+			for _, item := range f.Items {
+				if item.ID == "" {
+					continue
+				}
+				seen, err := a.store.SeeFeedItem(feedURL, chat, item.ID, now.UTC())
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "⚠ Feed %s: %v\n", redactURL(feedURL), err)
+					continue
+				}
+				if seen && polled {
+					items = append(items, item)
+				}
+			}
+			if len(items) > maxFeedPosts {
+				items = items[:maxFeedPosts]
+			}
+			for i := len(items) - 1; i >= 0; i-- {
+				if err := a.postFeedItem(ctx, feedURL, chat, items[i]); err != nil {
+					fmt.Fprintf(os.Stderr, "⚠ Feed %s to %s failed: %v\n", redactURL(feedURL), chat, err)
+				}
+			}
+		}
+	}
+	if err := a.store.PruneFeedItems(now.Add(-feedItemRetention)); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠ Failed to prune feed items: %v\n", err)
+	}
+}
+
+// postFeedItem sends an item's title and link, with its image if enabled.
+// An image that cannot be fetched is left out.
+func (a *App) postFeedItem(ctx context.Context, feedURL, chat string, item feed.Item) error {
+	text := feedItemText(feedURL, item)
+	if a.feeds.images && item.Image != "" {
+		media, err := a.feeds.fetchImage(ctx, resolveFeedURL(feedURL, item.Image))
+		if err == nil {
+			media.Caption = text
+			_, err := a.sendMediaAndStore(ctx, chat, media)
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "⚠ Feed %s: image of %q: %v\n", redactURL(feedURL), item.Title, err)
+	}
+	return a.sendAndStore(ctx, chat, text)
+}
+
+// feedItemText is the bold title of an item followed by its link.
+func feedItemText(feedURL string, item feed.Item) string {
+	var lines []string
+	if item.Title != "" {
+		lines = append(lines, "*"+item.Title+"*")
+	}
+	if item.Link != "" {
+		lines = append(lines, resolveFeedURL(feedURL, item.Link))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// resolveFeedURL resolves a link of a feed, which may be relative to it.
+func resolveFeedURL(feedURL, link string) string {
+	base, err := url.Parse(feedURL)
+	if err != nil {
+		return link
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	return base.ResolveReference(ref).String()
+}
+
+func (p *feedPoller) fetch(ctx context.Context, feedURL string) (*feed.Feed, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8")
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching feed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching feed: %s", resp.Status)
+	}
+	return feed.Parse(io.LimitReader(resp.Body, maxFeedBytes))
+}
+
+func (p *feedPoller) fetchImage(ctx context.Context, imageURL string) (client.OutgoingMedia, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return client.OutgoingMedia{}, err
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return client.OutgoingMedia{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return client.OutgoingMedia{}, fmt.Errorf("%s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedImageBytes+1))
+	if err != nil {
+		return client.OutgoingMedia{}, err
+	}
+	if len(data) > maxFeedImageBytes {
+		return client.OutgoingMedia{}, fmt.Errorf("larger than %d MB", maxFeedImageBytes>>20)
+	}
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return client.OutgoingMedia{}, fmt.Errorf("not an image")
+	}
+	return client.OutgoingMedia{Type: "image", Data: data, MimeType: mimeType}, nil
+}
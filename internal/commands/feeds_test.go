@@ -0,0 +1,126 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pngHeader is enough of a PNG to be sniffed as one.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestPollFeeds(t *testing.T) {
+	var mu sync.Mutex
+	items := []string{"2", "1"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed.xml":
+			mu.Lock()
+			defer mu.Unlock()
+			var b strings.Builder
+			b.WriteString(`<rss version="2.0"><channel><title>Blog</title>`)
+			for _, id := range items {
+				fmt.Fprintf(&b, `<item><guid>post-%s</guid><title>Post %s</title><link>/posts/%s</link>`, id, id, id)
+				if id == "4" {
+					b.WriteString(`<enclosure url="/img/4.png" type="image/png"/>`)
+				}
+				if id == "5" {
+					b.WriteString(`<enclosure url="/img/missing.png" type="image/png"/>`)
+				}
+				b.WriteString(`</item>`)
+			}
+			b.WriteString(`</channel></rss>`)
+			w.Write([]byte(b.String()))
+		case "/img/4.png":
+			w.Write(pngHeader)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	app, fake := newFakeApp(t)
+	require.NoError(t, app.SetFeeds(FeedConfig{
+		Feeds:  map[string][]string{srv.URL + "/feed.xml": {"120363000000000000@newsletter"}},
+		Images: true,
+	}))
+	ctx := context.Background()
+	require.NoError(t, fake.Connect(ctx))
+	now := time.Now()
+
+	// The items already in the feed are not posted
+	app.pollFeeds(ctx, now)
+	assert.Empty(t, fake.Sent())
+
+	mu.Lock()
+	items = []string{"5", "4", "3", "2", "1"}
+	mu.Unlock()
+	app.pollFeeds(ctx, now.Add(time.Minute))
+	app.pollFeeds(ctx, now.Add(2*time.Minute))
+
+	sent := fake.Sent()
+	require.Len(t, sent, 3, "new items posted once")
+	for _, s := range sent {
+		assert.Equal(t, "120363000000000000@newsletter", s.Recipient)
+	}
+	assert.Equal(t, "*Post 3*\n"+srv.URL+"/posts/3", sent[0].Message)
+	assert.Nil(t, sent[0].Media)
+
+	require.NotNil(t, sent[1].Media, "image not sent")
+	assert.Equal(t, "image", sent[1].Media.Type)
+	assert.Equal(t, "image/png", sent[1].Media.MimeType)
+	assert.Equal(t, "*Post 4*\n"+srv.URL+"/posts/4", sent[1].Media.Caption)
+
+	// An image that cannot be fetched falls back to text
+	assert.Nil(t, sent[2].Media)
+	assert.Equal(t, "*Post 5*\n"+srv.URL+"/posts/5", sent[2].Message)
+}
+
+func TestPollFeedsLimitsPosts(t *testing.T) {
+	var mu sync.Mutex
+	entries := `<entry><id>old</id><title>Old</title></entry>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Write([]byte(`<feed xmlns="http://www.w3.org/2005/Atom">` + entries + `</feed>`))
+	}))
+	defer srv.Close()
+
+	app, fake := newFakeApp(t)
+	require.NoError(t, app.SetFeeds(FeedConfig{Feeds: map[string][]string{srv.URL: {"34600111222"}}}))
+	ctx := context.Background()
+	require.NoError(t, fake.Connect(ctx))
+
+	app.pollFeeds(ctx, time.Now())
+	mu.Lock()
+	for i := 1; i <= 10; i++ {
+		entries = fmt.Sprintf(`<entry><id>e%d</id><title>Entry %d</title></entry>`, i, i) + entries
+	}
+	mu.Unlock()
+	app.pollFeeds(ctx, time.Now())
+
+	// Only the newest are posted, oldest first
+	sent := fake.Sent()
+	require.Len(t, sent, maxFeedPosts)
+	assert.Equal(t, "34600111222@s.whatsapp.net", sent[0].Recipient)
+	assert.Equal(t, "*Entry 6*", sent[0].Message)
+	assert.Equal(t, "*Entry 10*", sent[4].Message)
+}
+
+func TestSetFeedsValidates(t *testing.T) {
+	app, _ := newFakeApp(t)
+	assert.Error(t, app.SetFeeds(FeedConfig{Feeds: map[string][]string{"ftp://example.com/feed": {"34600111222"}}}))
+	assert.Error(t, app.SetFeeds(FeedConfig{Feeds: map[string][]string{"https://example.com/feed": {"12"}}}))
+	assert.Error(t, app.SetFeeds(FeedConfig{Feeds: map[string][]string{"https://example.com/feed": nil}}))
+	assert.Error(t, app.SetFeeds(FeedConfig{Feeds: map[string][]string{"https://example.com/feed": {"34600111222"}}, Interval: time.Second}))
+	require.NoError(t, app.SetFeeds(FeedConfig{}))
+	assert.Nil(t, app.feeds)
+}
@@ -0,0 +1,189 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/eventbus"
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// DefaultFollowupAfter is how long an incoming message may wait for a reply
+// before its chat needs a follow-up.
+const DefaultFollowupAfter = 24 * time.Hour
+
+// Follow-up notification bounds: at most followupBatch chats are notified
+// per check, and only those waiting for less than followupNoticeWindow, so
+// enabling follow-ups on an old archive does not notify every chat ever left
+// unanswered.
+const (
+	followupBatch        = 100
+	followupNoticeWindow = 7 * 24 * time.Hour
+)
+
+// FollowupConfig selects the chats whose unanswered messages are tracked.
+type FollowupConfig struct {
+	// Chats are the chats tracked; "*" tracks every one-to-one chat.
+	Chats []string
+	// After is how long an incoming message may wait for a reply,
+	// DefaultFollowupAfter if zero.
+	After time.Duration
+	// WebhookURL, if set, receives every chat as it becomes overdue as a
+	// JSON POST.
+	WebhookURL string
+}
+
+type followupTracker struct {
+	chats      []string // nil for every one-to-one chat
+	after      time.Duration
+	webhookURL string
+	httpClient *http.Client
+}
+
+// SetFollowups tracks the incoming messages of the configured chats that are
+// not replied to in time. Overdue chats are listed by ListFollowups and, while
+// syncing, pushed to the notifier and the follow-up webhook once. A config
+// without chats disables tracking.
+func (a *App) SetFollowups(cfg FollowupConfig) error {
+	if len(cfg.Chats) == 0 {
+		a.followups = nil
+		return nil
+	}
+	if cfg.After < 0 {
+		return fmt.Errorf("follow-up window must be positive, got %s", cfg.After)
+	}
+	f := &followupTracker{
+		after:      cfg.After,
+		webhookURL: cfg.WebhookURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	if f.after == 0 {
+		f.after = DefaultFollowupAfter
+	}
+	for _, chat := range cfg.Chats {
+		if chat == "*" {
+			f.chats = nil
+			break
+		}
+		f.chats = append(f.chats, jid.Normalize(chat))
+	}
+	a.followups = f
+	return nil
+}
+
+// ListFollowups returns the tracked chats whose incoming messages have waited
+// longer than the follow-up window for a reply, longest waiting first.
+func (a *App) ListFollowups(limit int, includeJIDs, excludeJIDs []string) string {
+	if a.followups == nil {
+		return output.Error(fmt.Errorf("follow-ups are not enabled (set FOLLOWUP_CHATS)"))
+	}
+	followups, err := a.overdueFollowups(time.Now(), nil, limit, includeJIDs, excludeJIDs)
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(followups)
+}
+
+func (a *App) overdueFollowups(now time.Time, since *time.Time, limit int, includeJIDs, excludeJIDs []string) ([]store.Followup, error) {
+	f := a.followups
+	return a.store.Followups(store.FollowupParams{
+		Chats:        f.chats,
+		Before:       now.Add(-f.after),
+		After:        since,
+		HideSpamFrom: a.spam.QuarantineThreshold,
+		Limit:        limit,
+		IncludeJIDs:  includeJIDs,
+		ExcludeJIDs:  excludeJIDs,
+	})
+}
+
+// runFollowups notifies newly overdue chats every interval until ctx is
+// cancelled.
+func (a *App) runFollowups(ctx context.Context, interval time.Duration) {
+	if a.followups == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		a.notifyFollowups(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// notifyFollowups notifies each overdue chat once per unanswered message
+// that made it overdue; a chat replied to and left waiting again is notified
+// again.
+func (a *App) notifyFollowups(ctx context.Context, now time.Time) {
+	since := now.Add(-followupNoticeWindow)
+	if err := a.store.PruneFollowupNotices(since); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠ Failed to prune follow-up notices: %v\n", err)
+	}
+	followups, err := a.overdueFollowups(now, &since, followupBatch, nil, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠ Failed to check follow-ups: %v\n", err)
+		return
+	}
+	for _, f := range followups {
+		first, err := a.store.FollowupNotified(f.ChatJID, f.Message.ID, now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠ Failed to record follow-up of %s: %v\n", f.ChatJID, err)
+			continue
+		}
+		if first {
+			a.deliverFollowup(ctx, f)
+		}
+	}
+}
+
+// deliverFollowup pushes an overdue chat to the notifier and the follow-up
+// webhook.
+func (a *App) deliverFollowup(ctx context.Context, f store.Followup) {
+	name := f.ChatName
+	if name == "" {
+		name = f.ChatJID
+	}
+	fmt.Fprintf(os.Stderr, "⏰ %s is waiting for a reply since %s (%d message(s))\n", name, f.Since.Local().Format(time.DateTime), f.Unanswered)
+
+	data, _ := json.Marshal(f)
+	if a.notifier != nil {
+		a.notifier.Publish(eventbus.Event{
+			Type:      eventbus.TypeFollowup,
+			ChatJID:   f.ChatJID,
+			Timestamp: time.Now().UTC(),
+			Followup:  data,
+		})
+	}
+
+	t := a.followups
+	if t.webhookURL == "" {
+		return
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"event":    "followup",
+		"followup": json.RawMessage(data),
+	})
+	err := a.postWebhook(ctx, t.httpClient, t.webhookURL, body)
+	if err == nil {
+		return
+	}
+	if a.outboxMaxAge <= 0 || isPermanent(err) {
+		fmt.Fprintf(os.Stderr, "⚠ Follow-up webhook failed: %v\n", err)
+		return
+	}
+	if qerr := a.enqueueOutbox(SinkFollowupWebhook, t.webhookURL, f.ChatJID, body); qerr != nil {
+		fmt.Fprintf(os.Stderr, "⚠ Follow-up webhook failed: %v (not queued: %v)\n", err, qerr)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "⚠ Follow-up webhook failed, queued for retry: %v\n", err)
+}
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+func TestFollowups(t *testing.T) {
+	var mu sync.Mutex
+	var hooks []map[string]any
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		hooks = append(hooks, body)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	app, _ := newFakeApp(t)
+	assert.Contains(t, app.ListFollowups(10, nil, nil), "not enabled")
+	require.NoError(t, app.SetFollowups(FollowupConfig{Chats: []string{"*"}, After: time.Hour, WebhookURL: hook.URL}))
+
+	now := time.Now().UTC().Truncate(time.Second)
+	alice, bob, carol := "34600111222@s.whatsapp.net", "34600333444@s.whatsapp.net", "34600555666@s.whatsapp.net"
+	require.NoError(t, app.store.StoreChat(alice, "Alice", now))
+	require.NoError(t, app.store.StoreChat(bob, "Bob", now))
+	require.NoError(t, app.store.StoreChat(carol, "Carol", now))
+	require.NoError(t, app.store.StoreMessage("a1", alice, "34600111222", "Can you send the invoice?", now.Add(-2*time.Hour), false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, app.store.StoreMessage("b1", bob, "34600333444", "Thanks!", now.Add(-10*time.Minute), false, "", "", "", "", "", nil, nil, nil, 0))
+	// Listed, but waiting for longer than the notice window
+	require.NoError(t, app.store.StoreMessage("c1", carol, "34600555666", "Hello?", now.Add(-30*24*time.Hour), false, "", "", "", "", "", nil, nil, nil, 0))
+
+	var res struct {
+		Success bool             `json:"success"`
+		Data    []store.Followup `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(app.ListFollowups(10, nil, nil)), &res))
+	require.True(t, res.Success)
+	require.Len(t, res.Data, 2)
+	assert.Equal(t, carol, res.Data[0].ChatJID)
+	assert.Equal(t, alice, res.Data[1].ChatJID)
+	assert.Equal(t, "a1", res.Data[1].Message.ID)
+
+	app.notifyFollowups(context.Background(), now)
+	app.notifyFollowups(context.Background(), now.Add(time.Minute))
+	mu.Lock()
+	require.Len(t, hooks, 1, "notified once")
+	assert.Equal(t, "followup", hooks[0]["event"])
+	assert.Equal(t, alice, hooks[0]["followup"].(map[string]any)["chat_jid"])
+	mu.Unlock()
+
+	// A reply clears the chat; a new unanswered message is notified again
+	require.NoError(t, app.store.StoreMessage("a2", alice, "me", "Sent!", now.Add(time.Minute), true, "", "", "", "", "", nil, nil, nil, 0))
+	assert.NotContains(t, app.ListFollowups(10, nil, nil), alice)
+	require.NoError(t, app.store.StoreMessage("a3", alice, "34600111222", "Got it?", now.Add(2*time.Minute), false, "", "", "", "", "", nil, nil, nil, 0))
+	app.notifyFollowups(context.Background(), now.Add(2*time.Hour))
+	mu.Lock()
+	require.Len(t, hooks, 3)
+	mu.Unlock()
+}
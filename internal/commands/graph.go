@@ -0,0 +1,10 @@
+package commands
+
+import "github.com/vicentereig/whatsapp-cli/internal/store"
+
+// InteractionGraph returns who talks to whom in group chats. Quarantined
+// messages are left out.
+func (a *App) InteractionGraph(params store.GraphParams) (*store.Graph, error) {
+	params.HideSpamFrom = a.spam.QuarantineThreshold
+	return a.store.InteractionGraph(params)
+}
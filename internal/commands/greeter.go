@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/pkg/webhookverify"
+)
+
+// GreeterConfig configures the first-contact auto-responder.
+type GreeterConfig struct {
+	// Template is the reply sent to a JID the first time it messages the
+	// account. It is a text/template executed with greetingData.
+	Template string
+	// WebhookURL, if set, receives a JSON POST for every first contact.
+	WebhookURL string
+}
+
+// greetingData is the data available to greeting templates.
+type greetingData struct {
+	Name    string // push name or phone number
+	Phone   string
+	JID     string
+	Message string // the first message received
+}
+
+type greeter struct {
+	tmpl       *template.Template
+	webhookURL string
+	httpClient *http.Client
+}
+
+// SetGreeter enables the first-contact auto-responder. An empty template with
+// no webhook disables it.
+func (a *App) SetGreeter(cfg GreeterConfig) error {
+	if strings.TrimSpace(cfg.Template) == "" && cfg.WebhookURL == "" {
+		a.greeter = nil
+		return nil
+	}
+	g := &greeter{
+		webhookURL: cfg.WebhookURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	if strings.TrimSpace(cfg.Template) != "" {
+		tmpl, err := template.New("greeting").Option("missingkey=error").Parse(cfg.Template)
+		if err != nil {
+			return fmt.Errorf("invalid greeting template: %w", err)
+		}
+		g.tmpl = tmpl
+	}
+	a.greeter = g
+	return nil
+}
+
+// isFirstContact reports whether an incoming message opens a new one-to-one
+// chat. It must be called before the chat row is stored.
+func (a *App) isFirstContact(chatJID string, isFromMe bool) bool {
+	if a.greeter == nil || isFromMe || !strings.HasSuffix(chatJID, "@s.whatsapp.net") {
+		return false
+	}
+	exists, err := a.store.ChatExists(chatJID)
+	if err != nil {
+		// Without the store we cannot tell; staying quiet beats greeting twice
+		return false
+	}
+	return !exists
+}
+
+// greet sends the greeting and notifies the webhook for a first contact.
+func (a *App) greet(ctx context.Context, data greetingData) {
+	g := a.greeter
+	greeted := false
+	if g.tmpl != nil {
+		var buf bytes.Buffer
+		if err := g.tmpl.Execute(&buf, data); err != nil {
+			fmt.Fprintf(os.Stderr, "\n⚠ Failed to render greeting for %s: %v\n", data.JID, err)
+		} else if err := a.sendAndStore(ctx, data.JID, buf.String()); err != nil {
+			fmt.Fprintf(os.Stderr, "\n⚠ Failed to send greeting to %s: %v\n", data.JID, err)
+		} else {
+			greeted = true
+		}
+	}
+
+	if g.webhookURL == "" {
+		return
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"event":     "first_contact",
+		"jid":       data.JID,
+		"phone":     data.Phone,
+		"name":      data.Name,
+		"message":   data.Message,
+		"greeted":   greeted,
+		"timestamp": time.Now().UTC(),
+	})
+	err := a.postWebhook(ctx, g.httpClient, g.webhookURL, body)
+	if err == nil {
+		return
+	}
+	if a.outboxMaxAge <= 0 || isPermanent(err) {
+		fmt.Fprintf(os.Stderr, "\n⚠ Greeting webhook failed: %v\n", err)
+		return
+	}
+	if qerr := a.enqueueOutbox(SinkGreetingWebhook, g.webhookURL, data.JID, body); qerr != nil {
+		fmt.Fprintf(os.Stderr, "\n⚠ Greeting webhook failed: %v (not queued: %v)\n", err, qerr)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\n⚠ Greeting webhook failed, queued for retry: %v\n", err)
+}
+
+// SetWebhookSecret signs the requests of the message, greeting, digest and
+// bot command webhooks with secret, see package webhookverify. Empty sends them
+// unsigned. Call it before SetBot.
+func (a *App) SetWebhookSecret(secret string) {
+	a.webhookSecret = []byte(secret)
+}
+
+// postWebhook sends a JSON body to a webhook URL, signed if a webhook secret
+// is set. Rejections that retrying cannot fix are reported as permanent
+// errors.
+func (a *App) postWebhook(ctx context.Context, client *http.Client, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(a.webhookSecret) > 0 {
+		webhookverify.SignRequest(req, a.webhookSecret, webhookverify.DeliveryID(body), time.Now(), body)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return webhookStatusError(resp.StatusCode)
+	}
+	return nil
+}
+
+// webhookStatusError is returned when a webhook answers with a non-2xx status.
+type webhookStatusError int
+
+func (e webhookStatusError) Error() string {
+	return fmt.Sprintf("status %d", int(e))
+}
+
+// permanent reports whether the webhook rejected the request itself, so
+// sending it again cannot succeed.
+func (e webhookStatusError) permanent() bool {
+	return e >= 400 && e < 500 && e != http.StatusRequestTimeout && e != http.StatusTooManyRequests
+}
@@ -0,0 +1,140 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client/fakeclient"
+	"github.com/vicentereig/whatsapp-cli/pkg/webhookverify"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// startSync runs Sync in the background until the test ends and waits for
+// the fake client to be connected.
+func startSync(t *testing.T, app *App, fake *fakeclient.Client) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		app.Sync(ctx, nil)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	require.Eventually(t, fake.IsConnected, time.Second, 5*time.Millisecond)
+}
+
+func TestGreeterRepliesOnFirstContactOnly(t *testing.T) {
+	var mu sync.Mutex
+	var hooks []map[string]any
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		hooks = append(hooks, body)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	app, fake := newFakeApp(t)
+	require.NoError(t, app.SetGreeter(GreeterConfig{
+		Template:   "Hi {{.Name}}, thanks for reaching out!",
+		WebhookURL: hook.URL,
+	}))
+	startSync(t, app, fake)
+
+	alice := types.NewJID("111", types.DefaultUserServer)
+	msg := fakeclient.TextMessage(alice, alice, "M1", "hello", time.Now(), false)
+	msg.Info.PushName = "Alice"
+	fake.Emit(msg)
+
+	require.Eventually(t, func() bool { return len(fake.Sent()) == 1 }, time.Second, 5*time.Millisecond)
+	sent := fake.Sent()[0]
+	assert.Equal(t, alice.String(), sent.Recipient)
+	assert.Equal(t, "Hi Alice, thanks for reaching out!", sent.Message)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(hooks) == 1
+	}, time.Second, 5*time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, "first_contact", hooks[0]["event"])
+	assert.Equal(t, alice.String(), hooks[0]["jid"])
+	assert.Equal(t, true, hooks[0]["greeted"])
+	mu.Unlock()
+
+	// A second message from the same JID and a group message are not greeted
+	fake.EmitText(alice, alice, "M2", "are you there?", time.Now())
+	group := types.NewJID("120363000000000001", types.GroupServer)
+	fake.EmitText(group, types.NewJID("222", types.DefaultUserServer), "M3", "hi all", time.Now())
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, fake.Sent(), 1)
+}
+
+func TestSetGreeterRejectsInvalidTemplate(t *testing.T) {
+	app, _ := newFakeApp(t)
+	err := app.SetGreeter(GreeterConfig{Template: "Hi {{.Name"})
+	assert.ErrorContains(t, err, "invalid greeting template")
+	assert.NoError(t, app.SetGreeter(GreeterConfig{}))
+	assert.Nil(t, app.greeter)
+}
+
+func TestPostWebhookSignsWithSecret(t *testing.T) {
+	secret := "0123456789abcdef"
+	verifier := webhookverify.NewVerifier([]byte(secret))
+	var ids []string
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get(webhookverify.IDHeader))
+		verifier.Middleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(w, r)
+	}))
+	defer hook.Close()
+
+	app, _ := newFakeApp(t)
+	app.SetWebhookSecret(secret)
+	client := &http.Client{Timeout: time.Second}
+	body := []byte(`{"event":"first_contact"}`)
+	require.NoError(t, app.postWebhook(context.Background(), client, hook.URL, body))
+
+	// A replayed outbox item keeps its delivery ID, so a receiver that
+	// already accepted it rejects it for good rather than processing it twice
+	err := app.postWebhook(context.Background(), client, hook.URL, body)
+	require.Error(t, err)
+	assert.True(t, isPermanent(err))
+	assert.Equal(t, ids[0], ids[1])
+
+	app.SetWebhookSecret("")
+	err = app.postWebhook(context.Background(), client, hook.URL, []byte(`{}`))
+	assert.ErrorContains(t, err, "status 401")
+}
+
+func TestAutomatedRepliesSkipFilteredChats(t *testing.T) {
+	app, fake := newFakeApp(t)
+	require.NoError(t, app.SetGreeter(GreeterConfig{Template: "Hi {{.Name}}!"}))
+	alice := types.NewJID("111", types.DefaultUserServer)
+	bob := types.NewJID("222", types.DefaultUserServer)
+	app.SetBot(BotConfig{AllowedChats: []string{alice.String(), bob.String()}})
+	app.SetChatFilter(func(chatJID string) bool { return chatJID != alice.String() })
+	startSync(t, app, fake)
+
+	fake.EmitText(alice, alice, "M1", "!ping", time.Now())
+	fake.EmitText(bob, bob, "M2", "hello", time.Now())
+
+	require.Eventually(t, func() bool { return len(fake.Sent()) == 1 }, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	sent := fake.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, bob.String(), sent[0].Recipient)
+
+	assert.ErrorContains(t, app.sendAndStore(context.Background(), alice.String(), "hi"), "excluded")
+	assert.Len(t, fake.Sent(), 1)
+}
@@ -0,0 +1,385 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/reqid"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+	waBinary "go.mau.fi/whatsmeow/binary"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// UpdateGroup changes a group's subject, description and admin-only settings
+// on WhatsApp and records the resulting state in the groups table.
+func (a *App) UpdateGroup(ctx context.Context, groupJID string, subject, description *string, announce, locked *bool) string {
+	if subject == nil && description == nil && announce == nil && locked == nil {
+		return output.Error(fmt.Errorf("no group changes requested"))
+	}
+	if err := a.client.Connect(ctx); err != nil {
+		return output.Error(err)
+	}
+
+	settings, err := a.client.UpdateGroup(ctx, groupJID, client.GroupUpdate{
+		Subject:     subject,
+		Description: description,
+		Announce:    announce,
+		Locked:      locked,
+	})
+	if err != nil {
+		return output.Error(err)
+	}
+
+	now := time.Now().UTC()
+	if err := a.store.UpdateGroup(settings.JID, store.GroupUpdate{
+		Subject:     &settings.Subject,
+		Description: &settings.Description,
+		Announce:    &settings.Announce,
+		Locked:      &settings.Locked,
+		UpdatedAt:   now,
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "%s⚠ Failed to store group %s: %v\n", logPrefix(ctx), settings.JID, err)
+	}
+
+	return output.Success(store.Group{
+		JID:         settings.JID,
+		Subject:     settings.Subject,
+		Description: settings.Description,
+		Announce:    settings.Announce,
+		Locked:      settings.Locked,
+		UpdatedAt:   now,
+	})
+}
+
+// CreateGroup creates a group with the account as its admin, adds the
+// participants and records the new group in the groups and chats tables.
+// Participants that cannot be added, e.g. because their privacy settings
+// forbid it, are reported as failed; the group is created regardless.
+func (a *App) CreateGroup(ctx context.Context, subject string, participants []string) string {
+	subject = strings.TrimSpace(subject)
+	if subject == "" {
+		return output.Error(fmt.Errorf("group subject is required"))
+	}
+	members, err := groupMembers(participants)
+	if err != nil {
+		return output.Error(err)
+	}
+	if err := a.client.Connect(ctx); err != nil {
+		return output.Error(err)
+	}
+
+	settings, res, err := a.client.CreateGroup(ctx, subject, members)
+	if err != nil {
+		return output.Error(err)
+	}
+
+	now := time.Now().UTC()
+	if err := a.store.UpdateGroup(settings.JID, store.GroupUpdate{
+		Subject:     &settings.Subject,
+		Description: &settings.Description,
+		Announce:    &settings.Announce,
+		Locked:      &settings.Locked,
+		UpdatedAt:   now,
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "%s⚠ Failed to store group %s: %v\n", logPrefix(ctx), settings.JID, err)
+	}
+	if err := a.store.StoreChat(settings.JID, settings.Subject, now); err != nil {
+		fmt.Fprintf(os.Stderr, "%s⚠ Failed to store chat %s: %v\n", logPrefix(ctx), settings.JID, err)
+	}
+
+	results := make([]groupParticipantResult, len(res))
+	for i, p := range res {
+		results[i] = participantResult(client.ParticipantAdd, p)
+	}
+	return output.Success(map[string]interface{}{
+		"group": store.Group{
+			JID:         settings.JID,
+			Subject:     settings.Subject,
+			Description: settings.Description,
+			Announce:    settings.Announce,
+			Locked:      settings.Locked,
+			UpdatedAt:   now,
+		},
+		"participants": results,
+	})
+}
+
+// groupMembers normalizes the phone numbers or JIDs of group participants,
+// dropping duplicates. Only contacts can be members.
+func groupMembers(participants []string) ([]string, error) {
+	members := make([]string, 0, len(participants))
+	seen := map[string]bool{}
+	for _, p := range participants {
+		member, err := jid.ParseRecipient(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid participant %q: %w", p, err)
+		}
+		if server := jid.Server(member); server != jid.UserServer && server != jid.LIDServer {
+			return nil, fmt.Errorf("participant %s is not a contact", member)
+		}
+		if !seen[member] {
+			seen[member] = true
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+// participantChanges maps the membership changes to the status reported for
+// a participant they succeed for and the chat event recorded.
+var participantChanges = map[string]struct{ status, event string }{
+	client.ParticipantAdd:     {"added", store.ChatEventParticipantAdded},
+	client.ParticipantRemove:  {"removed", store.ChatEventParticipantRemoved},
+	client.ParticipantPromote: {"promoted", store.ChatEventParticipantPromoted},
+	client.ParticipantDemote:  {"demoted", store.ChatEventParticipantDemoted},
+}
+
+// UpdateGroupParticipants adds or removes members of a group, or promotes
+// them to admins or demotes them, and records each change that succeeds in
+// the chat_events table. Participants a change fails for are reported with
+// the WhatsApp error code and what it means.
+func (a *App) UpdateGroupParticipants(ctx context.Context, groupJID string, participants []string, action string) string {
+	change, ok := participantChanges[action]
+	if !ok {
+		return output.Error(fmt.Errorf("unknown participant action %q", action))
+	}
+	if len(participants) == 0 {
+		return output.Error(fmt.Errorf("no participants given"))
+	}
+	members, err := groupMembers(participants)
+	if err != nil {
+		return output.Error(err)
+	}
+	if err := a.client.Connect(ctx); err != nil {
+		return output.Error(err)
+	}
+	res, err := a.client.UpdateGroupParticipants(ctx, groupJID, members, action)
+	if err != nil {
+		return output.Error(err)
+	}
+
+	now := time.Now().UTC()
+	results := make([]groupParticipantResult, len(res))
+	for i, p := range res {
+		results[i] = participantResult(action, p)
+		if p.Error != 0 {
+			continue
+		}
+		evt := store.ChatEvent{ChatJID: groupJID, Type: change.event, Target: p.JID, Timestamp: now, RequestID: reqid.FromContext(ctx)}
+		a.writer.Write(func() error {
+			return a.store.StoreChatEvent(evt)
+		})
+	}
+	return output.Success(map[string]interface{}{
+		"group":   groupJID,
+		"action":  action,
+		"results": results,
+	})
+}
+
+func participantResult(action string, p client.GroupParticipantResult) groupParticipantResult {
+	if p.Error == 0 {
+		return groupParticipantResult{JID: p.JID, Status: participantChanges[action].status}
+	}
+	return groupParticipantResult{JID: p.JID, Status: "failed", Error: p.Error, Reason: participantErrorReason(action, p.Error)}
+}
+
+// participantErrorReason explains the error codes WhatsApp reports for
+// membership changes.
+func participantErrorReason(action string, code int) string {
+	switch {
+	case code == 403 && action == client.ParticipantAdd:
+		return "their privacy settings do not allow being added to groups"
+	case code == 403:
+		return "not allowed; the account must be a group admin"
+	case code == 404 && action == client.ParticipantAdd:
+		return "not on WhatsApp"
+	case code == 404:
+		return "not a member of the group"
+	case code == 408:
+		return "recently left the group and cannot be added back yet"
+	case code == 409:
+		return "already a member of the group"
+	}
+	return fmt.Sprintf("WhatsApp error %d", code)
+}
+
+// handleGroupInfo records subject, description and settings changes from
+// incoming group notifications, including those made from other devices.
+func (a *App) handleGroupInfo(evt *events.GroupInfo) {
+	a.recordJoinRequestChanges(evt)
+
+	upd := store.GroupUpdate{UpdatedAt: evt.Timestamp}
+	changed := false
+	if evt.Name != nil {
+		upd.Subject = &evt.Name.Name
+		changed = true
+	}
+	if evt.Topic != nil {
+		description := evt.Topic.Topic
+		if evt.Topic.TopicDeleted {
+			description = ""
+		}
+		upd.Description = &description
+		changed = true
+	}
+	if evt.Announce != nil {
+		upd.Announce = &evt.Announce.IsAnnounce
+		changed = true
+	}
+	if evt.Locked != nil {
+		upd.Locked = &evt.Locked.IsLocked
+		changed = true
+	}
+	if !changed {
+		return
+	}
+	if upd.UpdatedAt.IsZero() {
+		upd.UpdatedAt = time.Now().UTC()
+	}
+
+	jid := evt.JID.String()
+	a.writer.Write(func() error {
+		return a.store.UpdateGroup(jid, upd)
+	})
+}
+
+type groupJoinRequest struct {
+	JID         string    `json:"jid"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// groupParticipantResult is the outcome of a membership change for one
+// participant.
+type groupParticipantResult struct {
+	JID    string `json:"jid"`
+	Status string `json:"status"`
+	Error  int    `json:"error,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ListGroupJoinRequests returns the pending join requests of a group that
+// requires admin approval.
+func (a *App) ListGroupJoinRequests(ctx context.Context, groupJID string) string {
+	if err := a.client.Connect(ctx); err != nil {
+		return output.Error(err)
+	}
+	reqs, err := a.client.GetGroupJoinRequests(ctx, groupJID)
+	if err != nil {
+		return output.Error(err)
+	}
+
+	out := make([]groupJoinRequest, len(reqs))
+	for i, req := range reqs {
+		out[i] = groupJoinRequest{JID: req.JID, RequestedAt: req.RequestedAt}
+	}
+	return output.Success(map[string]interface{}{
+		"group":    groupJID,
+		"requests": out,
+	})
+}
+
+// UpdateGroupJoinRequests approves or rejects pending join requests and
+// records each successful decision in the chat_events table.
+func (a *App) UpdateGroupJoinRequests(ctx context.Context, groupJID string, participants []string, approve bool) string {
+	if len(participants) == 0 {
+		return output.Error(fmt.Errorf("no participants given"))
+	}
+	if err := a.client.Connect(ctx); err != nil {
+		return output.Error(err)
+	}
+	res, err := a.client.UpdateGroupJoinRequests(ctx, groupJID, participants, approve)
+	if err != nil {
+		return output.Error(err)
+	}
+
+	status, eventType := "rejected", store.ChatEventJoinRequestRejected
+	if approve {
+		status, eventType = "approved", store.ChatEventJoinRequestApproved
+	}
+	now := time.Now().UTC()
+	results := make([]groupParticipantResult, len(res))
+	for i, p := range res {
+		results[i] = groupParticipantResult{JID: p.JID, Status: status}
+		if p.Error != 0 {
+			results[i].Status = "failed"
+			results[i].Error = p.Error
+			continue
+		}
+		evt := store.ChatEvent{ChatJID: groupJID, Type: eventType, Target: p.JID, Timestamp: now, RequestID: reqid.FromContext(ctx)}
+		a.writer.Write(func() error {
+			return a.store.StoreChatEvent(evt)
+		})
+	}
+	return output.Success(map[string]interface{}{
+		"group":   groupJID,
+		"results": results,
+	})
+}
+
+// recordJoinRequestChanges stores membership request notifications, which
+// whatsmeow does not parse and passes through as unknown changes.
+func (a *App) recordJoinRequestChanges(evt *events.GroupInfo) {
+	var actor string
+	if evt.Sender != nil {
+		actor = evt.Sender.String()
+	}
+	ts := evt.Timestamp
+	if ts.IsZero() {
+		ts = time.Now().UTC()
+	}
+
+	for _, change := range evt.UnknownChanges {
+		if change == nil {
+			continue
+		}
+		for _, target := range changeTargets(change.GetChildren(), actor) {
+			var eventType string
+			switch change.Tag {
+			case "created_membership_requests":
+				eventType = store.ChatEventJoinRequest
+			case "revoked_membership_requests":
+				// The requester withdrawing shows up as a revoke by themselves;
+				// anyone else revoking is an admin rejection.
+				eventType = store.ChatEventJoinRequestRevoked
+				if target != actor {
+					eventType = store.ChatEventJoinRequestRejected
+				}
+			default:
+				continue
+			}
+			chatEvent := store.ChatEvent{
+				ChatJID:   evt.JID.String(),
+				Type:      eventType,
+				Actor:     actor,
+				Target:    target,
+				Timestamp: ts,
+			}
+			a.writer.Write(func() error {
+				return a.store.StoreChatEvent(chatEvent)
+			})
+		}
+	}
+}
+
+// changeTargets returns the JIDs listed in a group change node, falling back
+// to the sender when the change carries none.
+func changeTargets(children []waBinary.Node, sender string) []string {
+	var targets []string
+	for _, child := range children {
+		if jid, ok := child.Attrs["jid"].(types.JID); ok && !jid.IsEmpty() {
+			targets = append(targets, jid.String())
+		}
+	}
+	if len(targets) == 0 && sender != "" {
+		targets = append(targets, sender)
+	}
+	return targets
+}
@@ -0,0 +1,255 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/client/fakeclient"
+	"github.com/vicentereig/whatsapp-cli/internal/reqid"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+	waBinary "go.mau.fi/whatsmeow/binary"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+const testGroupJID = "120363000000000001@g.us"
+
+func newFakeApp(t *testing.T) (*App, *fakeclient.Client) {
+	t.Helper()
+	fake := fakeclient.NewPaired()
+	app, err := NewAppWithClient(t.TempDir(), "test", fake)
+	require.NoError(t, err)
+	t.Cleanup(app.Close)
+	return app, fake
+}
+
+func TestUpdateGroupAppliesAndPersists(t *testing.T) {
+	app, fake := newFakeApp(t)
+	fake.AddGroup(client.GroupSettings{JID: testGroupJID, Subject: "Old"})
+
+	subject := "Renamed"
+	announce := true
+	result := app.UpdateGroup(context.Background(), testGroupJID, &subject, nil, &announce, nil)
+
+	var resp struct {
+		Success bool           `json:"success"`
+		Data    map[string]any `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result), &resp))
+	require.True(t, resp.Success, result)
+	assert.Equal(t, "Renamed", resp.Data["subject"])
+	assert.Equal(t, true, resp.Data["announce"])
+
+	g, _ := fake.Group(testGroupJID)
+	assert.Equal(t, "Renamed", g.Subject)
+
+	stored, err := app.store.GetGroup(testGroupJID)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", stored.Subject)
+	assert.True(t, stored.Announce)
+}
+
+func TestUpdateGroupRequiresChanges(t *testing.T) {
+	app, _ := newFakeApp(t)
+	result := app.UpdateGroup(context.Background(), testGroupJID, nil, nil, nil, nil)
+	assert.Contains(t, result, "no group changes requested")
+}
+
+func TestHandleGroupInfoRecordsIncomingChanges(t *testing.T) {
+	app, _ := newFakeApp(t)
+	jid := types.NewJID("120363000000000001", types.GroupServer)
+
+	app.handleGroupInfo(&events.GroupInfo{
+		JID:       jid,
+		Timestamp: time.Now(),
+		Name:      &types.GroupName{Name: "From Phone"},
+		Topic:     &types.GroupTopic{Topic: "Rules: be nice"},
+		Locked:    &types.GroupLocked{IsLocked: true},
+	})
+
+	stored, err := app.store.GetGroup(jid.String())
+	require.NoError(t, err)
+	assert.Equal(t, "From Phone", stored.Subject)
+	assert.Equal(t, "Rules: be nice", stored.Description)
+	assert.True(t, stored.Locked)
+	assert.False(t, stored.Announce)
+
+	// Membership-only notifications do not touch the row
+	app.handleGroupInfo(&events.GroupInfo{JID: jid, Join: []types.JID{types.NewJID("123", types.DefaultUserServer)}})
+	again, err := app.store.GetGroup(jid.String())
+	require.NoError(t, err)
+	assert.Equal(t, stored, again)
+}
+
+func TestUpdateGroupJoinRequestsRecordsEvents(t *testing.T) {
+	app, fake := newFakeApp(t)
+	fake.AddGroup(client.GroupSettings{JID: testGroupJID})
+	fake.AddJoinRequest(testGroupJID, client.GroupJoinRequest{JID: "111@s.whatsapp.net", RequestedAt: time.Now()})
+	fake.AddJoinRequest(testGroupJID, client.GroupJoinRequest{JID: "222@s.whatsapp.net", RequestedAt: time.Now()})
+
+	result := app.ListGroupJoinRequests(context.Background(), testGroupJID)
+	assert.Contains(t, result, "111@s.whatsapp.net")
+	assert.Contains(t, result, "222@s.whatsapp.net")
+
+	ctx := reqid.NewContext(context.Background(), "req-42")
+	result = app.UpdateGroupJoinRequests(ctx, testGroupJID, []string{"111@s.whatsapp.net", "333@s.whatsapp.net"}, true)
+	var resp struct {
+		Success bool `json:"success"`
+		Data    struct {
+			Results []groupParticipantResult `json:"results"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result), &resp))
+	require.True(t, resp.Success, result)
+	require.Len(t, resp.Data.Results, 2)
+	assert.Equal(t, "approved", resp.Data.Results[0].Status)
+	assert.Equal(t, "failed", resp.Data.Results[1].Status)
+
+	chatEvents, err := app.store.ListChatEvents(testGroupJID, 0)
+	require.NoError(t, err)
+	require.Len(t, chatEvents, 1)
+	assert.Equal(t, "join_request_approved", chatEvents[0].Type)
+	assert.Equal(t, "111@s.whatsapp.net", chatEvents[0].Target)
+	assert.Equal(t, "req-42", chatEvents[0].RequestID)
+
+	result = app.ListGroupJoinRequests(context.Background(), testGroupJID)
+	assert.NotContains(t, result, "111@s.whatsapp.net")
+}
+
+func TestHandleGroupInfoRecordsJoinRequests(t *testing.T) {
+	app, _ := newFakeApp(t)
+	group := types.NewJID("120363000000000001", types.GroupServer)
+	requester := types.NewJID("111", types.DefaultUserServer)
+	admin := types.NewJID("999", types.DefaultUserServer)
+	ts := time.Now().UTC().Truncate(time.Second)
+
+	app.handleGroupInfo(&events.GroupInfo{
+		JID:       group,
+		Sender:    &requester,
+		Timestamp: ts,
+		UnknownChanges: []*waBinary.Node{{
+			Tag: "created_membership_requests",
+		}},
+	})
+	app.handleGroupInfo(&events.GroupInfo{
+		JID:       group,
+		Sender:    &admin,
+		Timestamp: ts.Add(time.Minute),
+		UnknownChanges: []*waBinary.Node{{
+			Tag:     "revoked_membership_requests",
+			Content: []waBinary.Node{{Tag: "participant", Attrs: waBinary.Attrs{"jid": requester}}},
+		}},
+	})
+
+	chatEvents, err := app.store.ListChatEvents(group.String(), 0)
+	require.NoError(t, err)
+	require.Len(t, chatEvents, 2)
+	assert.Equal(t, "join_request_rejected", chatEvents[0].Type)
+	assert.Equal(t, admin.String(), chatEvents[0].Actor)
+	assert.Equal(t, requester.String(), chatEvents[0].Target)
+	assert.Equal(t, "join_request", chatEvents[1].Type)
+	assert.Equal(t, requester.String(), chatEvents[1].Target)
+}
+
+func TestCreateGroupReportsParticipantsAndStoresGroup(t *testing.T) {
+	app, fake := newFakeApp(t)
+	fake.SetAddError("34600333444@s.whatsapp.net", 403)
+
+	result := app.CreateGroup(context.Background(), " Onboarding ", []string{"+34 600 111 222", "34600333444", "34600111222"})
+
+	var resp struct {
+		Success bool `json:"success"`
+		Data    struct {
+			Group        map[string]any `json:"group"`
+			Participants []struct {
+				JID    string `json:"jid"`
+				Status string `json:"status"`
+				Error  int    `json:"error"`
+			} `json:"participants"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result), &resp))
+	require.True(t, resp.Success, result)
+	groupJID, _ := resp.Data.Group["jid"].(string)
+	assert.True(t, strings.HasSuffix(groupJID, "@g.us"), groupJID)
+	assert.Equal(t, "Onboarding", resp.Data.Group["subject"])
+	require.Len(t, resp.Data.Participants, 2, "duplicates are added once")
+	assert.Equal(t, "34600111222@s.whatsapp.net", resp.Data.Participants[0].JID)
+	assert.Equal(t, "added", resp.Data.Participants[0].Status)
+	assert.Equal(t, "failed", resp.Data.Participants[1].Status)
+	assert.Equal(t, 403, resp.Data.Participants[1].Error)
+
+	members, err := fake.GroupParticipants(context.Background(), groupJID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"34600111222@s.whatsapp.net"}, members)
+
+	stored, err := app.store.GetGroup(groupJID)
+	require.NoError(t, err)
+	assert.Equal(t, "Onboarding", stored.Subject)
+	exists, err := app.store.ChatExists(groupJID)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestCreateGroupValidatesInput(t *testing.T) {
+	app, _ := newFakeApp(t)
+	assert.Contains(t, app.CreateGroup(context.Background(), " ", nil), "group subject is required")
+	assert.Contains(t, app.CreateGroup(context.Background(), "x", []string{"abc"}), "invalid participant")
+	assert.Contains(t, app.CreateGroup(context.Background(), "x", []string{testGroupJID}), "is not a contact")
+}
+
+func TestUpdateGroupParticipantsReportsFailures(t *testing.T) {
+	app, fake := newFakeApp(t)
+	fake.AddGroup(client.GroupSettings{JID: testGroupJID})
+	fake.AddGroupParticipants(testGroupJID, "34600111222@s.whatsapp.net")
+	fake.SetAddError("34600333444@s.whatsapp.net", 403)
+
+	result := app.UpdateGroupParticipants(context.Background(), testGroupJID, []string{"34600555666", "34600333444", "34600111222"}, client.ParticipantAdd)
+
+	var resp struct {
+		Success bool `json:"success"`
+		Data    struct {
+			Action  string                   `json:"action"`
+			Results []groupParticipantResult `json:"results"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result), &resp))
+	require.True(t, resp.Success, result)
+	assert.Equal(t, "add", resp.Data.Action)
+	assert.Equal(t, []groupParticipantResult{
+		{JID: "34600555666@s.whatsapp.net", Status: "added"},
+		{JID: "34600333444@s.whatsapp.net", Status: "failed", Error: 403, Reason: "their privacy settings do not allow being added to groups"},
+		{JID: "34600111222@s.whatsapp.net", Status: "failed", Error: 409, Reason: "already a member of the group"},
+	}, resp.Data.Results)
+
+	result = app.UpdateGroupParticipants(context.Background(), testGroupJID, []string{"34600111222", "34600999999"}, client.ParticipantRemove)
+	assert.Contains(t, result, `{"jid":"34600111222@s.whatsapp.net","status":"removed"}`)
+	assert.Contains(t, result, `"error":404,"reason":"not a member of the group"`)
+
+	members, err := fake.GroupParticipants(context.Background(), testGroupJID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"34600555666@s.whatsapp.net"}, members)
+
+	chatEvents, err := app.store.ListChatEvents(testGroupJID, 0)
+	require.NoError(t, err)
+	recorded := map[string]string{}
+	for _, e := range chatEvents {
+		recorded[e.Target] = e.Type
+	}
+	assert.Equal(t, map[string]string{
+		"34600555666@s.whatsapp.net": store.ChatEventParticipantAdded,
+		"34600111222@s.whatsapp.net": store.ChatEventParticipantRemoved,
+	}, recorded)
+}
+
+func TestUpdateGroupParticipantsValidatesInput(t *testing.T) {
+	app, _ := newFakeApp(t)
+	assert.Contains(t, app.UpdateGroupParticipants(context.Background(), testGroupJID, []string{"34600111222"}, "kick"), "unknown participant action")
+	assert.Contains(t, app.UpdateGroupParticipants(context.Background(), testGroupJID, nil, client.ParticipantAdd), "no participants given")
+}
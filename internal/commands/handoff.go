@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// GetChatMode returns whether a chat is handled by automation or a human.
+func (a *App) GetChatMode(chatJID string) string {
+	mode, err := a.store.GetChatMode(chatJID)
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(mode)
+}
+
+// SetChatMode switches a chat between bot and human mode.
+func (a *App) SetChatMode(chatJID, mode string) string {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode != store.ChatModeBot && mode != store.ChatModeHuman {
+		return output.Error(fmt.Errorf("invalid mode %q: must be %q or %q", mode, store.ChatModeBot, store.ChatModeHuman))
+	}
+	m := store.ChatMode{ChatJID: chatJID, Mode: mode, UpdatedAt: time.Now().UTC()}
+	if err := a.store.SetChatMode(m.ChatJID, m.Mode, m.UpdatedAt); err != nil {
+		return output.Error(err)
+	}
+	return output.Success(m)
+}
+
+// ListHumanChats returns the chats a human has taken over, among those the
+// JID filters let through.
+func (a *App) ListHumanChats(includeJIDs, excludeJIDs []string) string {
+	modes, err := a.store.ListChatModes(store.ChatModeHuman, includeJIDs, excludeJIDs)
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(modes)
+}
+
+// parseHandoffCommand recognises the "<prefix>human" and "<prefix>bot"
+// messages an operator sends to take over or hand back a chat.
+func (a *App) parseHandoffCommand(content string) (string, bool) {
+	text := strings.TrimSpace(content)
+	if !strings.HasPrefix(text, a.commandPrefix) {
+		return "", false
+	}
+	switch mode := strings.ToLower(strings.TrimPrefix(text, a.commandPrefix)); mode {
+	case store.ChatModeBot, store.ChatModeHuman:
+		return mode, true
+	}
+	return "", false
+}
+
+func (a *App) switchChatMode(chatJID, mode string) {
+	if err := a.store.SetChatMode(chatJID, mode, time.Now().UTC()); err != nil {
+		fmt.Fprintf(os.Stderr, "\n⚠ Failed to switch %s to %s mode: %v\n", chatJID, mode, err)
+	}
+}
+
+// automationEnabled reports whether automated replies may be sent to a chat:
+// the chat filter allows it and no human took it over. Lookup failures err
+// on the side of silence.
+func (a *App) automationEnabled(chatJID string) bool {
+	if !a.chatAllowed(chatJID) {
+		return false
+	}
+	mode, err := a.store.GetChatMode(chatJID)
+	return err == nil && mode.Mode != store.ChatModeHuman
+}
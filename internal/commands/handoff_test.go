@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client/fakeclient"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestHandoffSilencesAutomation(t *testing.T) {
+	app, fake := newFakeApp(t)
+	app.SetBot(BotConfig{AllowedChats: []string{"*"}})
+	startSync(t, app, fake)
+
+	contact := types.NewJID("4915112345678", types.DefaultUserServer)
+	fake.EmitText(contact, contact, "H1", "!ping", time.Now())
+	require.Eventually(t, func() bool { return len(fake.Sent()) == 1 }, time.Second, 5*time.Millisecond)
+
+	// The operator takes over from their phone
+	fake.Emit(fakeclient.TextMessage(contact, fakeclient.OwnJID, "H2", "!human", time.Now(), true))
+	mode, err := app.store.GetChatMode(contact.String())
+	require.NoError(t, err)
+	assert.Equal(t, store.ChatModeHuman, mode.Mode)
+
+	fake.EmitText(contact, contact, "H3", "!ping", time.Now())
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, fake.Sent(), 1)
+
+	var list struct {
+		Success bool             `json:"success"`
+		Data    []store.ChatMode `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(app.ListHumanChats(nil, nil)), &list))
+	require.Len(t, list.Data, 1)
+	assert.Equal(t, contact.String(), list.Data[0].ChatJID)
+
+	// Handing the chat back re-enables the bot
+	result := app.SetChatMode(contact.String(), "Bot")
+	assert.Contains(t, result, `"mode":"bot"`)
+	fake.EmitText(contact, contact, "H4", "!ping", time.Now())
+	require.Eventually(t, func() bool { return len(fake.Sent()) == 2 }, time.Second, 5*time.Millisecond)
+}
+
+func TestSetChatModeRejectsUnknownMode(t *testing.T) {
+	app, _ := newFakeApp(t)
+
+	result := app.SetChatMode("123@g.us", "robot")
+	assert.Contains(t, result, `"success":false`)
+	assert.Contains(t, result, "invalid mode")
+}
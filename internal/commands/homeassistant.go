@@ -0,0 +1,345 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/mqtt"
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+)
+
+// Home Assistant defaults: the MQTT discovery prefix HA listens on and the
+// topic everything else is published under.
+const (
+	DefaultHADiscoveryPrefix = "homeassistant"
+	DefaultHABaseTopic       = "whatsapp"
+)
+
+// haQueueSize bounds the chat updates waiting to be published; updates are
+// dropped rather than blocking sync while the broker is slow.
+const haQueueSize = 1000
+
+// haStateMaxLen is the longest state Home Assistant accepts; the full text
+// is kept in the sensor's attributes.
+const haStateMaxLen = 255
+
+// HomeAssistantConfig connects the daemon to Home Assistant through its MQTT
+// integration.
+type HomeAssistantConfig struct {
+	// MQTTURL is the broker HA uses, as mqtt[s]://[user:password@]host[:port].
+	MQTTURL string
+	// DiscoveryPrefix is HA's discovery prefix, "homeassistant" by default.
+	DiscoveryPrefix string
+	// BaseTopic prefixes the state, availability and command topics,
+	// "whatsapp" by default.
+	BaseTopic string
+	// Chats are the chats exposed to HA. Empty exposes every chat as soon as
+	// it receives a message.
+	Chats []string
+	// Allow, if set, filters the chats exposed and the recipients of sends,
+	// e.g. with the API's phone whitelist.
+	Allow func(chatJID string) bool
+	// Version is reported as the device's software version.
+	Version string
+}
+
+// homeAssistant publishes a sensor and a notify entity per chat.
+type homeAssistant struct {
+	client    *mqtt.Client
+	discovery string
+	base      string
+	nodeID    string
+	chats     map[string]bool
+	allow     func(chatJID string) bool
+	version   string
+	updates   chan haChatState
+
+	mu sync.Mutex
+	// exposed maps the object IDs of announced chats to their JID and name.
+	exposed map[string]haChat
+}
+
+type haChat struct {
+	JID  string
+	Name string
+}
+
+// haChatState is the state of a chat sensor: its latest message.
+type haChatState struct {
+	Text      string    `json:"text"`
+	Sender    string    `json:"sender"`
+	Name      string    `json:"name"`
+	ChatJID   string    `json:"chat_jid"`
+	ChatName  string    `json:"chat_name"`
+	MessageID string    `json:"message_id"`
+	IsFromMe  bool      `json:"is_from_me"`
+	MediaType string    `json:"media_type,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// haSendRequest is the payload of the "<base>/send" command topic.
+type haSendRequest struct {
+	To      string `json:"to"`
+	Message string `json:"message"`
+}
+
+var haObjectIDUnsafe = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// haObjectID turns a JID into an ID usable in topics and entity IDs.
+func haObjectID(chatJID string) string {
+	return strings.Trim(haObjectIDUnsafe.ReplaceAllString(chatJID, "_"), "_")
+}
+
+// SetHomeAssistant enables the Home Assistant integration while syncing. An
+// empty MQTTURL disables it.
+func (a *App) SetHomeAssistant(cfg HomeAssistantConfig) error {
+	if cfg.MQTTURL == "" {
+		a.homeAssistant = nil
+		return nil
+	}
+	if cfg.DiscoveryPrefix == "" {
+		cfg.DiscoveryPrefix = DefaultHADiscoveryPrefix
+	}
+	if cfg.BaseTopic == "" {
+		cfg.BaseTopic = DefaultHABaseTopic
+	}
+	for _, t := range []string{cfg.DiscoveryPrefix, cfg.BaseTopic} {
+		if strings.ContainsAny(t, "+#") || strings.HasSuffix(t, "/") {
+			return fmt.Errorf("invalid MQTT topic prefix %q", t)
+		}
+	}
+	nodeID := haObjectID(cfg.BaseTopic)
+	client, err := mqtt.New(cfg.MQTTURL, "whatsapp-cli-"+nodeID)
+	if err != nil {
+		return err
+	}
+	ha := &homeAssistant{
+		client:    client,
+		discovery: cfg.DiscoveryPrefix,
+		base:      cfg.BaseTopic,
+		nodeID:    nodeID,
+		allow:     cfg.Allow,
+		version:   cfg.Version,
+		updates:   make(chan haChatState, haQueueSize),
+		exposed:   make(map[string]haChat),
+	}
+	if len(cfg.Chats) > 0 {
+		ha.chats = make(map[string]bool, len(cfg.Chats))
+		for _, c := range cfg.Chats {
+			chat, err := jid.ParseRecipient(c)
+			if err != nil {
+				return err
+			}
+			ha.chats[chat] = true
+		}
+	}
+	client.SetWill(ha.availabilityTopic(), []byte("offline"), true)
+	a.homeAssistant = ha
+	return nil
+}
+
+func (ha *homeAssistant) availabilityTopic() string { return ha.base + "/status" }
+
+func (ha *homeAssistant) chatTopic(objectID, leaf string) string {
+	return ha.base + "/chat/" + objectID + "/" + leaf
+}
+
+// exposes reports whether a chat is shown in HA.
+func (ha *homeAssistant) exposes(chatJID string) bool {
+	if ha.chats != nil && !ha.chats[chatJID] {
+		return false
+	}
+	return ha.allow == nil || ha.allow(chatJID)
+}
+
+// runHomeAssistant connects to the broker and publishes chat updates until
+// ctx is cancelled.
+func (a *App) runHomeAssistant(ctx context.Context) {
+	ha := a.homeAssistant
+	if ha == nil {
+		return
+	}
+	ha.client.OnConnect(func() { a.announceHomeAssistant(ctx) })
+	ha.client.Subscribe(ha.base+"/send", func(_ string, payload []byte) {
+		var req haSendRequest
+		if err := json.Unmarshal(payload, &req); err != nil || req.To == "" || req.Message == "" {
+			fmt.Fprintf(os.Stderr, "\n⚠ Home Assistant: ignoring send without \"to\" and \"message\"\n")
+			return
+		}
+		go a.haSend(ctx, req.To, req.Message)
+	})
+	ha.client.Subscribe(ha.base+"/chat/+/send", func(topic string, payload []byte) {
+		objectID := strings.TrimSuffix(strings.TrimPrefix(topic, ha.base+"/chat/"), "/send")
+		ha.mu.Lock()
+		chat, ok := ha.exposed[objectID]
+		ha.mu.Unlock()
+		if !ok || len(payload) == 0 {
+			return
+		}
+		go a.haSend(ctx, chat.JID, string(payload))
+	})
+	running := make(chan struct{})
+	go func() {
+		ha.client.Run(ctx)
+		close(running)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			<-running
+			ha.client.Publish(context.Background(), ha.availabilityTopic(), []byte("offline"), true)
+			ha.client.Close()
+			return
+		case state := <-ha.updates:
+			if err := ha.publishState(ctx, state); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "\n⚠ Home Assistant: %v\n", err)
+			}
+		}
+	}
+}
+
+// announceHomeAssistant marks the device online and (re)publishes the
+// discovery configs, which the broker may have lost.
+func (a *App) announceHomeAssistant(ctx context.Context) {
+	ha := a.homeAssistant
+	ha.client.Publish(ctx, ha.availabilityTopic(), []byte("online"), true)
+
+	if ha.chats != nil {
+		jids := make([]string, 0, len(ha.chats))
+		for c := range ha.chats {
+			jids = append(jids, c)
+		}
+		names, _ := a.store.ChatNames(jids)
+		for _, c := range jids {
+			if ha.exposes(c) {
+				ha.remember(c, names[c])
+			}
+		}
+	}
+	ha.mu.Lock()
+	chats := make(map[string]haChat, len(ha.exposed))
+	for id, c := range ha.exposed {
+		chats[id] = c
+	}
+	ha.mu.Unlock()
+	for id, c := range chats {
+		if err := ha.publishDiscovery(ctx, id, c); err != nil {
+			fmt.Fprintf(os.Stderr, "\n⚠ Home Assistant: %v\n", err)
+			return
+		}
+	}
+}
+
+// remember records an exposed chat and reports whether it is new, or was
+// renamed, so its discovery config needs publishing.
+func (ha *homeAssistant) remember(chatJID, name string) (string, bool) {
+	if name == "" {
+		name = chatJID
+	}
+	id := haObjectID(chatJID)
+	ha.mu.Lock()
+	defer ha.mu.Unlock()
+	old, ok := ha.exposed[id]
+	ha.exposed[id] = haChat{JID: chatJID, Name: name}
+	return id, !ok || old.Name != name
+}
+
+// publishState announces the chat if needed, then publishes its latest
+// message as the sensor state.
+func (ha *homeAssistant) publishState(ctx context.Context, state haChatState) error {
+	id, changed := ha.remember(state.ChatJID, state.ChatName)
+	if changed {
+		if err := ha.publishDiscovery(ctx, id, haChat{JID: state.ChatJID, Name: state.ChatName}); err != nil {
+			return err
+		}
+	}
+	payload, _ := json.Marshal(state)
+	return ha.client.Publish(ctx, ha.chatTopic(id, "state"), payload, true)
+}
+
+// publishDiscovery publishes the HA discovery configs of a chat: a sensor
+// showing its latest message and a notify entity sending to it.
+func (ha *homeAssistant) publishDiscovery(ctx context.Context, objectID string, chat haChat) error {
+	if chat.Name == "" {
+		chat.Name = chat.JID
+	}
+	device := map[string]any{
+		"identifiers":  []string{"whatsapp_cli_" + ha.nodeID},
+		"name":         "WhatsApp",
+		"manufacturer": "whatsapp-cli",
+		"sw_version":   ha.version,
+	}
+	sensor := map[string]any{
+		"name":                  chat.Name,
+		"unique_id":             ha.nodeID + "_" + objectID + "_last_message",
+		"object_id":             ha.nodeID + "_" + objectID,
+		"state_topic":           ha.chatTopic(objectID, "state"),
+		"value_template":        fmt.Sprintf("{{ value_json.text[:%d] }}", haStateMaxLen),
+		"json_attributes_topic": ha.chatTopic(objectID, "state"),
+		"availability_topic":    ha.availabilityTopic(),
+		"icon":                  "mdi:whatsapp",
+		"device":                device,
+	}
+	notify := map[string]any{
+		"name":               chat.Name,
+		"unique_id":          ha.nodeID + "_" + objectID + "_send",
+		"object_id":          ha.nodeID + "_" + objectID,
+		"command_topic":      ha.chatTopic(objectID, "send"),
+		"availability_topic": ha.availabilityTopic(),
+		"icon":               "mdi:whatsapp",
+		"device":             device,
+	}
+	for component, config := range map[string]map[string]any{"sensor": sensor, "notify": notify} {
+		payload, _ := json.Marshal(config)
+		topic := ha.discovery + "/" + component + "/" + ha.nodeID + "/" + objectID + "/config"
+		if err := ha.client.Publish(ctx, topic, payload, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateHomeAssistant queues a live message for its chat's sensor.
+func (a *App) updateHomeAssistant(state haChatState) {
+	ha := a.homeAssistant
+	if ha == nil || !ha.exposes(state.ChatJID) {
+		return
+	}
+	select {
+	case ha.updates <- state:
+	default:
+	}
+}
+
+// haSend sends a message requested from HA and publishes the result to
+// "<base>/send/result".
+func (a *App) haSend(ctx context.Context, to, message string) {
+	ha := a.homeAssistant
+	recipient, err := jid.ParseRecipient(to)
+	var result string
+	switch {
+	case err != nil:
+		result = output.Error(err)
+	case !ha.exposes(recipient):
+		result = output.Error(fmt.Errorf("recipient %s is not exposed to Home Assistant", recipient))
+	default:
+		result = a.SendMessage(ctx, recipient, message, client.SendOptions{})
+	}
+	var res struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+	if json.Unmarshal([]byte(result), &res); !res.Success {
+		fmt.Fprintf(os.Stderr, "\n⚠ Home Assistant send to %s failed: %s\n", to, res.Error)
+	}
+	ha.client.Publish(ctx, ha.base+"/send/result", []byte(result), false)
+}
@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client/fakeclient"
+	"github.com/vicentereig/whatsapp-cli/internal/mqtt/mqtttest"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestHomeAssistantPublishesChatsAndSends(t *testing.T) {
+	broker := mqtttest.New(t)
+	app, fake := newFakeApp(t)
+	require.NoError(t, app.SetHomeAssistant(HomeAssistantConfig{MQTTURL: broker.URL(), Version: "1.2.3"}))
+	startSync(t, app, fake)
+
+	require.Eventually(t, func() bool {
+		m, ok := broker.Retained("whatsapp/status")
+		return ok && m.Payload == "online"
+	}, time.Second, 5*time.Millisecond)
+
+	alice := types.NewJID("34600111222", types.DefaultUserServer)
+	msg := fakeclient.TextMessage(alice, alice, "M1", "the door is open", time.Now(), false)
+	msg.Info.PushName = "Alice"
+	fake.Emit(msg)
+
+	var state map[string]any
+	require.Eventually(t, func() bool {
+		m, ok := broker.Retained("whatsapp/chat/34600111222_s_whatsapp_net/state")
+		return ok && json.Unmarshal([]byte(m.Payload), &state) == nil
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "the door is open", state["text"])
+	assert.Equal(t, "Alice", state["name"])
+	assert.Equal(t, "M1", state["message_id"])
+
+	sensor, ok := broker.Retained("homeassistant/sensor/whatsapp/34600111222_s_whatsapp_net/config")
+	require.True(t, ok)
+	var config map[string]any
+	require.NoError(t, json.Unmarshal([]byte(sensor.Payload), &config))
+	assert.Equal(t, "whatsapp/chat/34600111222_s_whatsapp_net/state", config["state_topic"])
+	assert.Equal(t, "whatsapp/status", config["availability_topic"])
+	assert.Equal(t, "1.2.3", config["device"].(map[string]any)["sw_version"])
+
+	notify, ok := broker.Retained("homeassistant/notify/whatsapp/34600111222_s_whatsapp_net/config")
+	require.True(t, ok)
+	require.NoError(t, json.Unmarshal([]byte(notify.Payload), &config))
+	assert.Equal(t, "whatsapp/chat/34600111222_s_whatsapp_net/send", config["command_topic"])
+
+	// The notify entity sends its message text to the chat
+	broker.Publish("whatsapp/chat/34600111222_s_whatsapp_net/send", "closing it", false)
+	require.Eventually(t, func() bool { return len(fake.Sent()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, alice.String(), fake.Sent()[0].Recipient)
+	assert.Equal(t, "closing it", fake.Sent()[0].Message)
+
+	// The generic send topic takes any recipient
+	broker.Publish("whatsapp/send", `{"to":"34600333444","message":"dinner is ready"}`, false)
+	require.Eventually(t, func() bool { return len(fake.Sent()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "34600333444@s.whatsapp.net", fake.Sent()[1].Recipient)
+}
+
+func TestHomeAssistantLimitsChats(t *testing.T) {
+	broker := mqtttest.New(t)
+	app, fake := newFakeApp(t)
+	require.NoError(t, app.SetHomeAssistant(HomeAssistantConfig{
+		MQTTURL: broker.URL(),
+		Chats:   []string{"34600111222"},
+		Allow:   func(chat string) bool { return chat != "34600999999@s.whatsapp.net" },
+	}))
+	startSync(t, app, fake)
+
+	// Configured chats are announced on connect
+	require.Eventually(t, func() bool {
+		_, ok := broker.Retained("homeassistant/sensor/whatsapp/34600111222_s_whatsapp_net/config")
+		return ok
+	}, time.Second, 5*time.Millisecond)
+	require.Eventually(t, func() bool { return broker.Subscribed("whatsapp/send") }, time.Second, 5*time.Millisecond)
+
+	bob := types.NewJID("34600333444", types.DefaultUserServer)
+	fake.EmitText(bob, bob, "M1", "hi", time.Now())
+	broker.Publish("whatsapp/send", `{"to":"34600333444","message":"hi"}`, false)
+
+	require.Eventually(t, func() bool {
+		for _, m := range broker.Messages() {
+			if m.Topic == "whatsapp/send/result" {
+				assert.Contains(t, m.Payload, "not exposed to Home Assistant")
+				return true
+			}
+		}
+		return false
+	}, time.Second, 5*time.Millisecond)
+	assert.Empty(t, fake.Sent())
+	_, ok := broker.Retained("whatsapp/chat/34600333444_s_whatsapp_net/state")
+	assert.False(t, ok, "chat outside the configured ones published")
+}
+
+func TestSetHomeAssistantValidates(t *testing.T) {
+	app, _ := newFakeApp(t)
+	assert.Error(t, app.SetHomeAssistant(HomeAssistantConfig{MQTTURL: "http://broker"}))
+	assert.Error(t, app.SetHomeAssistant(HomeAssistantConfig{MQTTURL: "mqtt://broker", BaseTopic: "whatsapp/#"}))
+	assert.Error(t, app.SetHomeAssistant(HomeAssistantConfig{MQTTURL: "mqtt://broker", Chats: []string{"12"}}))
+	require.NoError(t, app.SetHomeAssistant(HomeAssistantConfig{}))
+	assert.Nil(t, app.homeAssistant)
+}
@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// identityChangeWindow is how long after a contact's identity key changed
+// their messages are flagged with the change.
+const identityChangeWindow = 7 * 24 * time.Hour
+
+// contactIdentity is a contact's identity as served by the API.
+type contactIdentity struct {
+	JID string `json:"jid"`
+	// SecurityCode is empty until the contact and the account exchanged a
+	// message.
+	SecurityCode string              `json:"security_code"`
+	Devices      []deviceIdentityKey `json:"devices"`
+	Verification string              `json:"verification"`
+	VerifiedAt   *time.Time          `json:"verified_at,omitempty"`
+	ChangedAt    *time.Time          `json:"identity_changed_at,omitempty"`
+}
+
+type deviceIdentityKey struct {
+	Device      uint16 `json:"device"`
+	IdentityKey string `json:"identity_key"` // hex
+}
+
+// ContactIdentity returns the security code and identity keys of a contact,
+// and whether they were verified.
+func (a *App) ContactIdentity(ctx context.Context, contact string) string {
+	id, stored, err := a.contactIdentity(ctx, contact)
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(id.withStored(stored))
+}
+
+// VerifyContactIdentity marks the contact's current security code as
+// verified, after it was compared with the one on the contact's phone, or
+// marks the contact unverified again.
+func (a *App) VerifyContactIdentity(ctx context.Context, contact string, verified bool) string {
+	id, stored, err := a.contactIdentity(ctx, contact)
+	if err != nil {
+		return output.Error(err)
+	}
+	if !verified {
+		err = a.store.ClearIdentityVerified(stored.Sender)
+	} else if id.SecurityCode == "" {
+		return output.Error(fmt.Errorf("no identity key of %s is known yet; exchange a message first", id.JID))
+	} else {
+		err = a.store.SetIdentityVerified(stored.Sender, id.SecurityCode, time.Now().UTC())
+	}
+	if err != nil {
+		return output.Error(err)
+	}
+	if stored, err = a.store.GetContactIdentity(stored.Sender); err != nil {
+		return output.Error(err)
+	}
+	return output.Success(id.withStored(stored))
+}
+
+func (a *App) contactIdentity(ctx context.Context, contact string) (contactIdentity, store.ContactIdentity, error) {
+	pn := a.client.PhoneJID(ctx, jid.Normalize(contact))
+	if jid.IsGroup(pn) {
+		return contactIdentity{}, store.ContactIdentity{}, fmt.Errorf("%s is a group, not a contact", contact)
+	}
+	keys, err := a.client.Identity(ctx, pn)
+	if err != nil {
+		return contactIdentity{}, store.ContactIdentity{}, err
+	}
+	id := contactIdentity{JID: pn, SecurityCode: keys.SecurityCode(), Devices: []deviceIdentityKey{}}
+	for device, key := range keys.Keys {
+		id.Devices = append(id.Devices, deviceIdentityKey{Device: device, IdentityKey: hex.EncodeToString(key[:])})
+	}
+	slices.SortFunc(id.Devices, func(a, b deviceIdentityKey) int { return int(a.Device) - int(b.Device) })
+	stored, err := a.store.GetContactIdentity(keys.Phone)
+	return id, stored, err
+}
+
+// withStored adds the verification status. A verified code that no longer
+// matches means the key changed, even if WhatsApp did not report it.
+func (id contactIdentity) withStored(stored store.ContactIdentity) contactIdentity {
+	id.Verification = stored.Verification()
+	if id.Verification == store.VerificationVerified && stored.SecurityCode != id.SecurityCode {
+		id.Verification = store.VerificationChanged
+	}
+	id.VerifiedAt, id.ChangedAt = stored.VerifiedAt, stored.ChangedAt
+	return id
+}
+
+// recordIdentityChange notes that a contact's identity key changed, so their
+// next messages are flagged and a verified code counts as changed.
+func (a *App) recordIdentityChange(ctx context.Context, v *events.IdentityChange) {
+	pn := a.client.PhoneJID(ctx, v.JID.ToNonAD().String())
+	sender, _, _ := strings.Cut(pn, "@")
+	if err := a.store.RecordIdentityChange(sender, v.Timestamp); err != nil {
+		fmt.Fprintf(os.Stderr, "\n⚠ Failed to record identity change of %s: %v\n", pn, err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\n⚠ Security code with %s changed\n", pn)
+}
+
+// recentIdentityChange returns when sender's identity key changed if that
+// was within identityChangeWindow before a message sent at ts.
+func (a *App) recentIdentityChange(sender string, ts time.Time) (*time.Time, error) {
+	stored, err := a.store.GetContactIdentity(sender)
+	if err != nil || stored.ChangedAt == nil {
+		return nil, err
+	}
+	if age := ts.Sub(*stored.ChangedAt); age < 0 || age > identityChangeWindow {
+		return nil, nil
+	}
+	return stored.ChangedAt, nil
+}
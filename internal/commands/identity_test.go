@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client/fakeclient"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func decodeIdentity(t *testing.T, result string) contactIdentity {
+	t.Helper()
+	var resp struct {
+		Success bool            `json:"success"`
+		Data    contactIdentity `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result), &resp))
+	require.True(t, resp.Success, result)
+	return resp.Data
+}
+
+func TestContactIdentityVerification(t *testing.T) {
+	app, fake := newFakeApp(t)
+	ctx := context.Background()
+	alice := "34600111222@s.whatsapp.net"
+
+	assert.Contains(t, app.VerifyContactIdentity(ctx, alice, true), "exchange a message first")
+
+	fake.SetIdentityKey(alice, 0, [32]byte{2})
+	fake.SetIdentityKey(alice, 5, [32]byte{5})
+	id := decodeIdentity(t, app.ContactIdentity(ctx, "+34 600 111 222"))
+	assert.Equal(t, alice, id.JID)
+	assert.Len(t, id.SecurityCode, 71)
+	require.Len(t, id.Devices, 2)
+	assert.Equal(t, uint16(5), id.Devices[1].Device)
+	assert.Equal(t, store.VerificationUnverified, id.Verification)
+
+	id = decodeIdentity(t, app.VerifyContactIdentity(ctx, alice, true))
+	assert.Equal(t, store.VerificationVerified, id.Verification)
+	assert.NotNil(t, id.VerifiedAt)
+
+	// A new key changes the code, whether or not WhatsApp reports it
+	fake.SetIdentityKey(alice, 0, [32]byte{3})
+	assert.Equal(t, store.VerificationChanged, decodeIdentity(t, app.ContactIdentity(ctx, alice)).Verification)
+	id = decodeIdentity(t, app.VerifyContactIdentity(ctx, alice, false))
+	assert.Equal(t, store.VerificationUnverified, id.Verification)
+	assert.Nil(t, id.VerifiedAt)
+
+	assert.Contains(t, app.ContactIdentity(ctx, "120363012345678901@g.us"), "is a group")
+}
+
+func TestIdentityChangeFlagsMessages(t *testing.T) {
+	app, fake := newFakeApp(t)
+	startSync(t, app, fake)
+	alice := types.NewJID("34600111222", types.DefaultUserServer)
+
+	require.NoError(t, app.store.SetIdentityVerified("34600111222", "12345", time.Now().Add(-time.Hour)))
+	fake.Emit(&events.IdentityChange{JID: alice, Timestamp: time.Now()})
+	fake.Emit(fakeclient.TextMessage(alice, alice, "M1", "new phone, who dis", time.Now().Add(time.Second), false))
+	linked := alice
+	linked.Device = 7
+	fake.Emit(fakeclient.TextMessage(alice, linked, "M2", "from the laptop", time.Now().Add(2*time.Second), false))
+
+	var messages []store.Message
+	require.Eventually(t, func() bool {
+		messages, _ = app.store.ListMessages(store.ListMessagesParams{Limit: 10})
+		return len(messages) == 2
+	}, time.Second, 5*time.Millisecond)
+	laptop, phone := messages[0], messages[1]
+	require.NotNil(t, phone.SenderDevice)
+	assert.Equal(t, 0, *phone.SenderDevice)
+	require.NotNil(t, laptop.SenderDevice)
+	assert.Equal(t, 7, *laptop.SenderDevice)
+	assert.NotNil(t, phone.IdentityChangedAt)
+	assert.Equal(t, store.VerificationChanged, phone.Verification)
+}
@@ -0,0 +1,213 @@
+package commands
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// DefaultInspectTop is how many of the largest chats InspectStore lists.
+const DefaultInspectTop = 10
+
+// mediaStatus compares the downloads recorded in the store with the files
+// on disk. Missing are downloads whose file is gone; Unreferenced are files
+// under <store>/media no message points at.
+type mediaStatus struct {
+	Dir          string
+	Files        int
+	Bytes        int64
+	Downloaded   int
+	Missing      []store.MediaRef
+	Unreferenced []string
+}
+
+// InspectStore summarizes the store for debugging: database size and
+// schema, rows per table, the largest chats and the state of downloaded
+// media.
+func (a *App) InspectStore(top int) string {
+	if top <= 0 {
+		top = DefaultInspectTop
+	}
+	dbPath := filepath.Join(a.storeDir, "messages.db")
+	info, err := os.Stat(dbPath)
+	if err != nil {
+		return output.Error(err)
+	}
+	schema, err := a.store.SchemaStatus()
+	if err != nil {
+		return output.Error(err)
+	}
+	tables, err := a.store.TableCounts()
+	if err != nil {
+		return output.Error(err)
+	}
+	chats, err := a.store.LargestChats(top)
+	if err != nil {
+		return output.Error(err)
+	}
+	media, err := a.mediaStatus()
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(map[string]interface{}{
+		"path":          dbPath,
+		"bytes":         info.Size(),
+		"schema":        schema,
+		"tables":        tables,
+		"largest_chats": chats,
+		"media": map[string]interface{}{
+			"dir":                media.Dir,
+			"files":              media.Files,
+			"bytes":              media.Bytes,
+			"downloaded":         media.Downloaded,
+			"missing_files":      len(media.Missing),
+			"unreferenced_files": len(media.Unreferenced),
+			"missing_examples":   mediaExamples(media.Missing),
+			"unreferenced":       firstN(media.Unreferenced, 5),
+		},
+	})
+}
+
+// StoreDoctor checks the store for inconsistencies and, with repair, fixes
+// those it can: it creates missing chats, drops empty ones, corrects chat
+// ordering, deletes orphaned embeddings, forgets downloads whose file is
+// gone and removes media files no message refers to.
+func (a *App) StoreDoctor(repair bool) string {
+	issues, err := a.store.Diagnose(repair)
+	if err != nil {
+		return output.Error(err)
+	}
+	media, err := a.mediaStatus()
+	if err != nil {
+		return output.Error(err)
+	}
+
+	if n := len(media.Missing); n > 0 {
+		issue := store.StoreIssue{
+			Check:       "missing_media_files",
+			Description: "downloaded media whose file is gone; repair forgets the download so it can be fetched again",
+			Count:       int64(n),
+			Examples:    mediaExamples(media.Missing),
+			Repairable:  true,
+		}
+		if repair {
+			if err := a.store.ClearMediaPaths(media.Missing); err != nil {
+				return output.Error(err)
+			}
+			issue.Repaired = true
+		}
+		issues = append(issues, issue)
+	}
+	if n := len(media.Unreferenced); n > 0 {
+		issue := store.StoreIssue{
+			Check:       "unreferenced_media_files",
+			Description: "files under the media directory no message refers to; repair deletes them",
+			Count:       int64(n),
+			Examples:    firstN(media.Unreferenced, 5),
+			Repairable:  true,
+		}
+		if repair {
+			for _, path := range media.Unreferenced {
+				if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+					return output.Error(err)
+				}
+				pruneEmptyDirs(filepath.Dir(path), media.Dir)
+			}
+			issue.Repaired = true
+		}
+		issues = append(issues, issue)
+	}
+
+	healthy := true
+	for _, issue := range issues {
+		if !issue.Repaired {
+			healthy = false
+		}
+	}
+	return output.Success(map[string]interface{}{
+		"healthy":  healthy,
+		"repaired": repair,
+		"issues":   issues,
+	})
+}
+
+func (a *App) mediaStatus() (*mediaStatus, error) {
+	dir, err := filepath.Abs(filepath.Join(a.storeDir, "media"))
+	if err != nil {
+		return nil, err
+	}
+	refs, err := a.store.DownloadedMedia()
+	if err != nil {
+		return nil, err
+	}
+	status := &mediaStatus{Dir: dir, Downloaded: len(refs)}
+	referenced := make(map[string]bool, len(refs))
+	for _, r := range refs {
+		path := filepath.Clean(r.Path)
+		referenced[path] = true
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			status.Missing = append(status.Missing, r)
+		}
+	}
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		status.Files++
+		status.Bytes += info.Size()
+		if !referenced[path] {
+			status.Unreferenced = append(status.Unreferenced, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(status.Unreferenced)
+	return status, nil
+}
+
+// pruneEmptyDirs removes dir and its parents up to, but not including, root
+// while they are empty.
+func pruneEmptyDirs(dir, root string) {
+	for dir != root && len(dir) > len(root) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func mediaExamples(refs []store.MediaRef) []string {
+	var out []string
+	for _, r := range refs {
+		if len(out) == 5 {
+			break
+		}
+		out = append(out, fmt.Sprintf("%s/%s: %s", r.ChatJID, r.MessageID, r.Path))
+	}
+	return out
+}
+
+func firstN(s []string, n int) []string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectStoreAndDoctorMedia(t *testing.T) {
+	app, _ := newFakeApp(t)
+	now := time.Now()
+	require.NoError(t, app.store.StoreChat("123@s.whatsapp.net", "Alice", now))
+	require.NoError(t, app.store.StoreMessage("m1", "123@s.whatsapp.net", "123", "", now, false, "image", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, app.store.StoreMessage("m2", "123@s.whatsapp.net", "123", "", now, false, "image", "", "", "", "", nil, nil, nil, 0))
+
+	mediaDir := filepath.Join(app.storeDir, "media", "123_s.whatsapp.net")
+	kept := filepath.Join(mediaDir, "m1", "photo.jpg")
+	stray := filepath.Join(mediaDir, "old", "stray.jpg")
+	for _, path := range []string{kept, stray} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("jpeg"), 0o644))
+	}
+	require.NoError(t, app.store.MarkMediaDownloaded("m1", "123@s.whatsapp.net", kept, now))
+	require.NoError(t, app.store.MarkMediaDownloaded("m2", "123@s.whatsapp.net", filepath.Join(mediaDir, "m2", "gone.jpg"), now))
+
+	var inspected struct {
+		Success bool `json:"success"`
+		Data    struct {
+			Schema struct {
+				UpToDate bool `json:"up_to_date"`
+			} `json:"schema"`
+			LargestChats []struct {
+				JID      string `json:"jid"`
+				Messages int64  `json:"messages"`
+			} `json:"largest_chats"`
+			Media struct {
+				Files        int `json:"files"`
+				Downloaded   int `json:"downloaded"`
+				Missing      int `json:"missing_files"`
+				Unreferenced int `json:"unreferenced_files"`
+			} `json:"media"`
+		} `json:"data"`
+	}
+	result := app.InspectStore(0)
+	require.NoError(t, json.Unmarshal([]byte(result), &inspected))
+	require.True(t, inspected.Success, result)
+	assert.True(t, inspected.Data.Schema.UpToDate)
+	require.Len(t, inspected.Data.LargestChats, 1)
+	assert.Equal(t, int64(2), inspected.Data.LargestChats[0].Messages)
+	assert.Equal(t, 2, inspected.Data.Media.Files)
+	assert.Equal(t, 2, inspected.Data.Media.Downloaded)
+	assert.Equal(t, 1, inspected.Data.Media.Missing)
+	assert.Equal(t, 1, inspected.Data.Media.Unreferenced)
+
+	type doctorResult struct {
+		Success bool `json:"success"`
+		Data    struct {
+			Healthy bool `json:"healthy"`
+			Issues  []struct {
+				Check    string `json:"check"`
+				Repaired bool   `json:"repaired"`
+			} `json:"issues"`
+		} `json:"data"`
+	}
+	var checked doctorResult
+	require.NoError(t, json.Unmarshal([]byte(app.StoreDoctor(false)), &checked))
+	require.True(t, checked.Success)
+	assert.False(t, checked.Data.Healthy)
+	require.Len(t, checked.Data.Issues, 2)
+	assert.Equal(t, "missing_media_files", checked.Data.Issues[0].Check)
+	assert.Equal(t, "unreferenced_media_files", checked.Data.Issues[1].Check)
+	assert.FileExists(t, stray)
+
+	var repaired doctorResult
+	require.NoError(t, json.Unmarshal([]byte(app.StoreDoctor(true)), &repaired))
+	require.True(t, repaired.Success)
+	assert.True(t, repaired.Data.Healthy)
+	assert.NoFileExists(t, stray)
+	assert.NoDirExists(t, filepath.Dir(stray))
+	assert.FileExists(t, kept)
+
+	var after doctorResult
+	require.NoError(t, json.Unmarshal([]byte(app.StoreDoctor(false)), &after))
+	assert.True(t, after.Data.Healthy)
+	assert.Empty(t, after.Data.Issues)
+}
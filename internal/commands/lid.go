@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+)
+
+// phoneNumber returns the phone number of a phone-number JID, or "" for
+// @lid and other JIDs that do not carry one.
+func phoneNumber(chatJID string) string {
+	if jid.Server(chatJID) != jid.UserServer {
+		return ""
+	}
+	return jid.User(chatJID)
+}
+
+// ResolvePhoneJID returns the phone-number JID of a @lid JID when the store
+// or the session knows it, and jid unchanged otherwise.
+func (a *App) ResolvePhoneJID(chatJID string) string {
+	if !jid.IsLID(chatJID) {
+		return chatJID
+	}
+	if resolved, err := a.store.ResolveChatJID(chatJID); err == nil && !jid.IsLID(resolved) {
+		return resolved
+	}
+	return a.client.PhoneJID(context.Background(), jid.Normalize(chatJID))
+}
+
+// canonicalChatJID maps a @lid chat to its phone-number JID when known and
+// links the two in the store, so the conversation is kept under one chat.
+func (a *App) canonicalChatJID(ctx context.Context, chatJID string) string {
+	chatJID = jid.Normalize(chatJID)
+	if !jid.IsLID(chatJID) {
+		return chatJID
+	}
+	if resolved, err := a.store.ResolveChatJID(chatJID); err == nil && resolved != chatJID {
+		return resolved
+	}
+	pn := jid.Normalize(a.client.PhoneJID(ctx, chatJID))
+	if pn == chatJID {
+		return chatJID
+	}
+	a.linkLID(chatJID, pn)
+	return pn
+}
+
+// linkLID records that lid and pn identify the same account.
+func (a *App) linkLID(lid, pn string) {
+	if err := a.store.LinkChatJID(lid, pn, time.Now().UTC()); err != nil {
+		fmt.Fprintf(os.Stderr, "\n⚠ Failed to link %s to %s: %v\n", lid, pn, err)
+	}
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client/fakeclient"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestSyncStoresLIDChatsUnderPhoneJID(t *testing.T) {
+	app, fake := newFakeApp(t)
+	startSync(t, app, fake)
+
+	lid := types.NewJID("98765432109876", types.HiddenUserServer)
+	pn := types.NewJID("4915112345678", types.DefaultUserServer)
+	now := time.Now()
+
+	// Unknown mapping: the chat is kept under its LID
+	fake.EmitText(lid, lid, "m1", "first", now)
+	// The phone number arrives alongside a later message
+	msg := fakeclient.TextMessage(lid, lid, "m2", "second", now.Add(time.Second), false)
+	msg.Info.SenderAlt = pn
+	fake.Emit(msg)
+
+	require.Eventually(t, func() bool {
+		st, err := app.store.Stats(pn.String())
+		return err == nil && st.ChatMessages == 2
+	}, time.Second, 5*time.Millisecond)
+
+	chats, err := app.store.ListChats(store.ListChatsParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, chats, 1, "the LID chat is merged into the phone chat")
+	assert.Equal(t, pn.String(), chats[0].JID)
+	assert.Equal(t, pn.String(), app.ResolvePhoneJID(lid.String()))
+}
+
+func TestResolvePhoneJIDUsesSessionMapping(t *testing.T) {
+	app, fake := newFakeApp(t)
+	fake.MapLID("111@lid", "222@s.whatsapp.net")
+
+	assert.Equal(t, "222@s.whatsapp.net", app.ResolvePhoneJID("111@lid"))
+	assert.Equal(t, "333@lid", app.ResolvePhoneJID("333@lid"))
+	assert.Equal(t, "444@s.whatsapp.net", app.ResolvePhoneJID("444@s.whatsapp.net"))
+}
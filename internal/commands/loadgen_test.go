@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+func TestLoadGenWritesDeterministicMessages(t *testing.T) {
+	st, err := store.NewMessageStore(filepath.Join(t.TempDir(), "messages.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { st.Close() })
+	app := &App{store: st}
+
+	var resp struct {
+		Success bool           `json:"success"`
+		Data    map[string]any `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(app.LoadGen(LoadGenOptions{Chats: 5, Messages: 2500, Seed: 7})), &resp))
+	require.True(t, resp.Success)
+	assert.Equal(t, float64(2500), resp.Data["messages"])
+	assert.Contains(t, resp.Data["query_timings_ms"], "search_ms")
+
+	// Same seed again must not duplicate rows
+	app.LoadGen(LoadGenOptions{Chats: 5, Messages: 2500, Seed: 7})
+
+	total := 0
+	for page := 0; ; page++ {
+		msgs, err := st.ListMessages(store.ListMessagesParams{Limit: 1000, Page: page})
+		require.NoError(t, err)
+		if len(msgs) == 0 {
+			break
+		}
+		total += len(msgs)
+	}
+	assert.Equal(t, 2500, total)
+
+	chats, err := st.ListChats(store.ListChatsParams{Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, chats, 5)
+}
+
+func TestLoadGenIsReproducible(t *testing.T) {
+	var runs [2][]store.Message
+	for i := range runs {
+		st, err := store.NewMessageStore(filepath.Join(t.TempDir(), "messages.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { st.Close() })
+		app := &App{store: st}
+		require.Contains(t, app.LoadGen(LoadGenOptions{Chats: 3, Messages: 200, Seed: 42}), `"success":true`)
+		runs[i], err = st.ListMessages(store.ListMessagesParams{Limit: 200})
+		require.NoError(t, err)
+	}
+	require.Len(t, runs[0], 200)
+	for i := range runs[0] {
+		assert.Equal(t, runs[0][i].ID, runs[1][i].ID)
+		assert.Equal(t, runs[0][i].Content, runs[1][i].Content)
+		assert.True(t, runs[0][i].Timestamp.Equal(runs[1][i].Timestamp))
+	}
+}
+
+func TestLoadGenRejectsEmptyRun(t *testing.T) {
+	app := &App{}
+	assert.Contains(t, app.LoadGen(LoadGenOptions{}), `"success":false`)
+}
@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/vicentereig/whatsapp-cli/internal/mediacrypt"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// notFoundError is an error the API reports as 404: it matches fs.ErrNotExist.
+type notFoundError struct{ msg string }
+
+func (e notFoundError) Error() string        { return e.msg }
+func (e notFoundError) Is(target error) bool { return target == fs.ErrNotExist }
+
+// OpenMediaFile opens the media of a message, decrypting it if it is
+// encrypted. Media that was not downloaded yet, or whose file is gone, is
+// downloaded to the store's media directory first. It returns the file's
+// name and the MIME type its content was verified to be along with it.
+// Errors matching fs.ErrNotExist mean the message or its media does not
+// exist, fs.ErrPermission that the media is quarantined by the malware scan
+// or of a type the MediaTypePolicy refuses.
+func (a *App) OpenMediaFile(ctx context.Context, messageID string, chatJID *string) (io.ReadSeekCloser, string, string, error) {
+	info, err := a.store.GetMessageForDownload(messageID, chatJID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", "", notFoundError{fmt.Sprintf("message %s not found", messageID)}
+		}
+		return nil, "", "", err
+	}
+	path, err := a.localMedia(ctx, info)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return a.openMedia(ctx, info, path)
+}
+
+// openMedia opens the downloaded media of a message at path, as
+// OpenMediaFile does.
+func (a *App) openMedia(ctx context.Context, info store.MessageDownloadInfo, path string) (io.ReadSeekCloser, string, string, error) {
+	encrypted, err := mediacrypt.IsEncrypted(path)
+	if err != nil {
+		return nil, "", "", notFoundError{"media file not found on disk"}
+	}
+	var f io.ReadSeekCloser
+	if !encrypted {
+		if f, err = os.Open(path); err != nil {
+			return nil, "", "", notFoundError{"media file not found on disk"}
+		}
+	} else {
+		if a.mediaKeys == nil {
+			return nil, "", "", fmt.Errorf("media file is encrypted but no media encryption key is configured")
+		}
+		if f, err = mediacrypt.Open(ctx, a.mediaKeys, path); err != nil {
+			return nil, "", "", fmt.Errorf("decrypting media file: %w", err)
+		}
+	}
+	if err := a.checkMediaScan(ctx, info, f); err != nil {
+		f.Close()
+		return nil, "", "", err
+	}
+	mimeType, name, err := a.verifyMediaType(info, f, filepath.Base(path))
+	if err != nil {
+		f.Close()
+		return nil, "", "", err
+	}
+	return f, name, mimeType, nil
+}
+
+// localMedia returns the path of a message's downloaded media, downloading
+// it first if it is not on disk.
+func (a *App) localMedia(ctx context.Context, info store.MessageDownloadInfo) (string, error) {
+	if path, ok := downloadedPath(info); ok {
+		return path, nil
+	}
+	if strings.TrimSpace(info.DirectPath) == "" || len(info.MediaKey) == 0 {
+		return "", notFoundError{fmt.Sprintf("message %s has no downloadable media", info.ID)}
+	}
+
+	unlock := a.mediaLocks.lock(info.ChatJID + "/" + info.ID)
+	defer unlock()
+	// Another request, or the media worker, may have downloaded it while
+	// we waited
+	if fresh, err := a.store.GetMessageForDownload(info.ID, &info.ChatJID); err == nil {
+		if path, ok := downloadedPath(fresh); ok {
+			return path, nil
+		}
+	}
+	path, _, _, err := a.downloadMediaAndPersist(ctx, info, "")
+	if err != nil {
+		return "", fmt.Errorf("downloading media: %w", err)
+	}
+	return path, nil
+}
+
+// downloadedPath returns the recorded download of a message's media if the
+// file still exists.
+func downloadedPath(info store.MessageDownloadInfo) (string, bool) {
+	if info.LocalPath == nil || *info.LocalPath == "" {
+		return "", false
+	}
+	if _, err := os.Stat(*info.LocalPath); err != nil {
+		return "", false
+	}
+	return *info.LocalPath, true
+}
+
+// keyedMutex serializes downloads of the same media, so concurrent requests
+// do not write the same file. Its zero value is ready to use.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*refMutex
+}
+
+type refMutex struct {
+	sync.Mutex
+	refs int
+}
+
+// lock locks key and returns the function that unlocks it.
+func (k *keyedMutex) lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*refMutex)
+	}
+	m := k.locks[key]
+	if m == nil {
+		m = &refMutex{}
+		k.locks[key] = m
+	}
+	m.refs++
+	k.mu.Unlock()
+
+	m.Lock()
+	return func() {
+		m.Unlock()
+		k.mu.Lock()
+		if m.refs--; m.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
@@ -4,13 +4,19 @@ import (
 	"context"
 	"encoding/json"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client/fakeclient"
 	"github.com/vicentereig/whatsapp-cli/internal/output"
 	"github.com/vicentereig/whatsapp-cli/internal/store"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
 )
 
 type fakeDownloadStats struct {
@@ -151,3 +157,40 @@ func TestDownloadMediaErrorsWhenMetadataMissing(t *testing.T) {
 	require.NotNil(t, res.Error)
 	assert.Contains(t, *res.Error, "no downloadable media")
 }
+
+func TestSyncDownloadsMediaOfReplayedWrites(t *testing.T) {
+	storeDir := t.TempDir()
+	fake := fakeclient.NewPaired()
+	app, err := NewAppWithClient(storeDir, "test", fake)
+	require.NoError(t, err)
+	t.Cleanup(app.Close)
+	fake.SetMedia("/v/t62/photo", []byte("photo"))
+	startSync(t, app, fake)
+
+	// Hold message writes behind a write that stays busy until released
+	var busy atomic.Bool
+	busy.Store(true)
+	app.writer.Write(func() error {
+		if busy.Load() {
+			return sqlite3.Error{Code: sqlite3.ErrBusy}
+		}
+		return nil
+	})
+
+	alice := types.NewJID("111", types.DefaultUserServer)
+	msg := fakeclient.TextMessage(alice, alice, "IMG1", "", time.Now(), false)
+	msg.Message = &waProto.Message{ImageMessage: &waProto.ImageMessage{
+		DirectPath: proto.String("/v/t62/photo"),
+		MediaKey:   []byte("media-key"),
+		Mimetype:   proto.String("image/jpeg"),
+	}}
+	fake.Emit(msg)
+	require.False(t, app.writer.Health().Healthy)
+
+	busy.Store(false)
+	require.Equal(t, 2, app.writer.Flush())
+	require.Eventually(t, func() bool {
+		info, err := app.store.GetMessageForDownload("IMG1", nil)
+		return err == nil && info.LocalPath != nil
+	}, 2*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vicentereig/whatsapp-cli/internal/mediacrypt"
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+)
+
+// SetMediaEncryption encrypts the media downloaded to the store's media
+// directory, each file with its own key wrapped by keys. nil stores new
+// downloads unencrypted; encrypted files then cannot be read.
+func (a *App) SetMediaEncryption(keys mediacrypt.KeyWrapper) {
+	a.mediaKeys = keys
+}
+
+// encryptsDownload reports whether a download to path is encrypted: it is
+// when encryption is enabled and path is in the store's media directory,
+// not a location chosen for the download.
+func (a *App) encryptsDownload(path string) bool {
+	if a.mediaKeys == nil {
+		return false
+	}
+	dir, err := filepath.Abs(filepath.Join(a.storeDir, "media"))
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(filepath.Clean(path), dir+string(os.PathSeparator))
+}
+
+// EncryptMedia encrypts the downloaded media in the store's media directory
+// that is not encrypted yet, e.g. after enabling encryption.
+func (a *App) EncryptMedia(ctx context.Context) string {
+	if a.mediaKeys == nil {
+		return output.Error(fmt.Errorf("media encryption is not configured (set MEDIA_ENCRYPTION_KEY or MEDIA_ENCRYPTION_VAULT_KEY)"))
+	}
+	refs, err := a.store.DownloadedMedia()
+	if err != nil {
+		return output.Error(err)
+	}
+	var encrypted, already, skipped int
+	for _, r := range refs {
+		if err := ctx.Err(); err != nil {
+			return output.Error(err)
+		}
+		if !a.encryptsDownload(r.Path) {
+			skipped++
+			continue
+		}
+		done, err := mediacrypt.IsEncrypted(r.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			skipped++
+			continue
+		}
+		if err != nil {
+			return output.Error(err)
+		}
+		if done {
+			already++
+			continue
+		}
+		if err := mediacrypt.EncryptFile(ctx, a.mediaKeys, r.Path); err != nil {
+			return output.Error(fmt.Errorf("encrypting %s: %w", r.Path, err))
+		}
+		encrypted++
+	}
+	return output.Success(map[string]interface{}{
+		"encrypted":         encrypted,
+		"already_encrypted": already,
+		"skipped":           skipped,
+		"key_id":            a.mediaKeys.KeyID(),
+	})
+}
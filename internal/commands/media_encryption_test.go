@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/mediacrypt"
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+const testPhoto = "\xff\xd8\xff\xe0 a private photo"
+
+// newMediaApp returns an App whose downloads write testPhoto, with an image
+// message "msg1" waiting to be downloaded.
+func newMediaApp(t *testing.T) *App {
+	t.Helper()
+	dir := t.TempDir()
+	st, err := store.NewMessageStore(filepath.Join(dir, "messages.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { st.Close() })
+	require.NoError(t, st.StoreChat("1234@s.whatsapp.net", "John Doe", time.Now()))
+	require.NoError(t, st.StoreMessage("msg1", "1234@s.whatsapp.net", "1234", "", time.Now(), false,
+		"image", "photo.jpg", "https://example.com", "/media/direct/path", "image/jpeg",
+		[]byte{1}, []byte{2}, []byte{3}, uint64(len(testPhoto))))
+
+	return &App{
+		store:    st,
+		version:  "test",
+		storeDir: dir,
+		mediaDownloader: func(ctx context.Context, info store.MessageDownloadInfo, targetPath string) (int64, error) {
+			return int64(len(testPhoto)), os.WriteFile(targetPath, []byte(testPhoto), 0o644)
+		},
+	}
+}
+
+func readMedia(t *testing.T, app *App) string {
+	t.Helper()
+	f, name, mimeType, err := app.OpenMediaFile(context.Background(), "msg1", nil)
+	require.NoError(t, err)
+	defer f.Close()
+	assert.Equal(t, "photo.jpg", name)
+	assert.Equal(t, "image/jpeg", mimeType)
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestMediaEncryptedAtRest(t *testing.T) {
+	app := newMediaApp(t)
+	keys, err := mediacrypt.ParseLocalKey(strings.Repeat("0f", 32))
+	require.NoError(t, err)
+	app.SetMediaEncryption(keys)
+
+	var res output.Result
+	require.NoError(t, json.Unmarshal([]byte(app.DownloadMedia(context.Background(), "msg1", nil, "")), &res))
+	require.True(t, res.Success)
+	data := res.Data.(map[string]interface{})
+	assert.Equal(t, true, data["encrypted"])
+
+	onDisk, err := os.ReadFile(data["path"].(string))
+	require.NoError(t, err)
+	assert.NotContains(t, string(onDisk), "private photo")
+	assert.Equal(t, testPhoto, readMedia(t, app))
+
+	// Without the key the file cannot be read
+	app.SetMediaEncryption(nil)
+	_, _, _, err = app.OpenMediaFile(context.Background(), "msg1", nil)
+	assert.ErrorContains(t, err, "no media encryption key")
+}
+
+func TestMediaDownloadedElsewhereIsNotEncrypted(t *testing.T) {
+	app := newMediaApp(t)
+	keys, err := mediacrypt.ParseLocalKey(strings.Repeat("0f", 32))
+	require.NoError(t, err)
+	app.SetMediaEncryption(keys)
+
+	outputPath := filepath.Join(t.TempDir(), "photo.jpg")
+	app.DownloadMedia(context.Background(), "msg1", nil, outputPath)
+	onDisk, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+	assert.Equal(t, testPhoto, string(onDisk))
+	assert.Equal(t, testPhoto, readMedia(t, app))
+}
+
+func TestEncryptMedia(t *testing.T) {
+	app := newMediaApp(t)
+	app.DownloadMedia(context.Background(), "msg1", nil, "")
+	assert.Contains(t, app.EncryptMedia(context.Background()), "not configured")
+
+	keys, err := mediacrypt.ParseLocalKey(strings.Repeat("0f", 32))
+	require.NoError(t, err)
+	app.SetMediaEncryption(keys)
+	assert.Contains(t, app.EncryptMedia(context.Background()), `"encrypted":1`)
+	assert.Contains(t, app.EncryptMedia(context.Background()), `"already_encrypted":1`)
+
+	info, err := app.store.GetMessageForDownload("msg1", nil)
+	require.NoError(t, err)
+	encrypted, err := mediacrypt.IsEncrypted(*info.LocalPath)
+	require.NoError(t, err)
+	assert.True(t, encrypted)
+	assert.Equal(t, testPhoto, readMedia(t, app))
+}
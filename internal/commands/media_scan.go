@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/avscan"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// SetMediaScanner scans downloaded media for malware with s before it is
+// served. Infected media is quarantined: it stays on disk but OpenMediaFile
+// refuses it. nil disables scanning.
+func (a *App) SetMediaScanner(s avscan.Scanner) {
+	a.mediaScanner = s
+}
+
+// quarantinedError is an error the API reports as 403: it matches
+// fs.ErrPermission.
+type quarantinedError struct{ msg string }
+
+func (e quarantinedError) Error() string        { return e.msg }
+func (e quarantinedError) Is(target error) bool { return target == fs.ErrPermission }
+
+// scanDownload scans media just downloaded to path, before it is encrypted.
+// A failed scan is only reported: the media is scanned again before it is
+// served.
+func (a *App) scanDownload(ctx context.Context, info store.MessageDownloadInfo, path string) {
+	if a.mediaScanner == nil {
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	if _, _, err := a.scanMedia(ctx, info, f); err != nil {
+		fmt.Fprintf(os.Stderr, "\n⚠ Failed to scan media of message %s: %v\n", info.ID, err)
+	}
+}
+
+// scanMedia scans the media of a message read from r and records the result.
+func (a *App) scanMedia(ctx context.Context, info store.MessageDownloadInfo, r io.Reader) (result, threat string, err error) {
+	verdict, err := a.mediaScanner.Scan(ctx, r)
+	switch {
+	case err != nil:
+		result = store.MediaScanFailed
+	case verdict.Infected:
+		result, threat = store.MediaScanInfected, verdict.Threat
+	default:
+		result = store.MediaScanClean
+	}
+	if markErr := a.store.MarkMediaScanned(info.ID, info.ChatJID, result, threat, time.Now().UTC()); err == nil {
+		err = markErr
+	}
+	return result, threat, err
+}
+
+// checkMediaScan refuses quarantined media. When scanning is enabled, media
+// not found clean yet is scanned from f first, which is left at its start.
+func (a *App) checkMediaScan(ctx context.Context, info store.MessageDownloadInfo, f io.ReadSeeker) error {
+	result, threat := info.MediaScan, info.MediaThreat
+	if a.mediaScanner != nil && !scanned(result) {
+		// Downloading it may just have scanned it
+		if fresh, err := a.store.GetMessageForDownload(info.ID, &info.ChatJID); err == nil {
+			result, threat = fresh.MediaScan, fresh.MediaThreat
+		}
+		if !scanned(result) {
+			var err error
+			if result, threat, err = a.scanMedia(ctx, info, f); err != nil {
+				return fmt.Errorf("scanning media: %w", err)
+			}
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+		}
+	}
+	if result == store.MediaScanInfected {
+		return quarantinedError{fmt.Sprintf("media of message %s is quarantined: %s found", info.ID, threat)}
+	}
+	return nil
+}
+
+// scanned reports whether a scan result is final.
+func scanned(result string) bool {
+	return result == store.MediaScanClean || result == store.MediaScanInfected
+}
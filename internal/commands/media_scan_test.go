@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/avscan"
+	"github.com/vicentereig/whatsapp-cli/internal/mediacrypt"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// fakeScanner finds threat in content containing it, or fails with err.
+type fakeScanner struct {
+	threat string
+	err    error
+	scans  int
+}
+
+func (s *fakeScanner) Scan(ctx context.Context, r io.Reader) (avscan.Verdict, error) {
+	s.scans++
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return avscan.Verdict{}, err
+	}
+	if s.err != nil {
+		return avscan.Verdict{}, s.err
+	}
+	if s.threat != "" && strings.Contains(string(data), "private") {
+		return avscan.Verdict{Infected: true, Threat: s.threat}, nil
+	}
+	return avscan.Verdict{}, nil
+}
+
+func TestOpenMediaFileScansDownloads(t *testing.T) {
+	app := newMediaApp(t)
+	scanner := &fakeScanner{}
+	app.SetMediaScanner(scanner)
+
+	assert.Equal(t, testPhoto, readMedia(t, app))
+	assert.Equal(t, testPhoto, readMedia(t, app))
+	assert.Equal(t, 1, scanner.scans, "scanned once, when downloaded")
+	info, err := app.store.GetMessageForDownload("msg1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, store.MediaScanClean, info.MediaScan)
+}
+
+func TestOpenMediaFileQuarantinesInfectedMedia(t *testing.T) {
+	app := newMediaApp(t)
+	keys, err := mediacrypt.ParseLocalKey(strings.Repeat("0f", 32))
+	require.NoError(t, err)
+	app.SetMediaEncryption(keys)
+	app.SetMediaScanner(&fakeScanner{threat: "Eicar-Test-Signature"})
+
+	_, _, _, err = app.OpenMediaFile(context.Background(), "msg1", nil)
+	assert.ErrorIs(t, err, fs.ErrPermission)
+	assert.EqualError(t, err, "media of message msg1 is quarantined: Eicar-Test-Signature found")
+
+	// It stays quarantined with scanning turned off
+	app.SetMediaScanner(nil)
+	_, _, _, err = app.OpenMediaFile(context.Background(), "msg1", nil)
+	assert.ErrorIs(t, err, fs.ErrPermission)
+	messages, err := app.store.ListMessages(store.ListMessagesParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, store.MediaScanInfected, messages[0].MediaScan)
+	assert.Equal(t, "Eicar-Test-Signature", messages[0].MediaThreat)
+}
+
+func TestOpenMediaFileRescansAfterFailedScan(t *testing.T) {
+	app := newMediaApp(t)
+	scanner := &fakeScanner{err: errors.New("clamd is down")}
+	app.SetMediaScanner(scanner)
+
+	_, _, _, err := app.OpenMediaFile(context.Background(), "msg1", nil)
+	assert.ErrorContains(t, err, "scanning media: clamd is down")
+	assert.Equal(t, 2, scanner.scans, "scanned when downloaded and before serving")
+
+	scanner.err = nil
+	assert.Equal(t, testPhoto, readMedia(t, app))
+	assert.Equal(t, 3, scanner.scans)
+}
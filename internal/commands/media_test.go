@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+func TestOpenMediaFileDownloadsOnDemand(t *testing.T) {
+	app := newMediaApp(t)
+	var downloads atomic.Int32
+	download := app.mediaDownloader
+	app.mediaDownloader = func(ctx context.Context, info store.MessageDownloadInfo, targetPath string) (int64, error) {
+		downloads.Add(1)
+		return download(ctx, info, targetPath)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f, _, _, err := app.OpenMediaFile(context.Background(), "msg1", nil)
+			if assert.NoError(t, err) {
+				f.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), downloads.Load(), "concurrent requests download once")
+
+	info, err := app.store.GetMessageForDownload("msg1", nil)
+	require.NoError(t, err)
+	require.NotNil(t, info.LocalPath)
+	assert.Equal(t, testPhoto, readMedia(t, app))
+
+	// A file removed from disk is downloaded again
+	require.NoError(t, os.Remove(*info.LocalPath))
+	assert.Equal(t, testPhoto, readMedia(t, app))
+	assert.Equal(t, int32(2), downloads.Load())
+}
+
+func TestOpenMediaFileNotFound(t *testing.T) {
+	app := newMediaApp(t)
+	_, _, _, err := app.OpenMediaFile(context.Background(), "missing", nil)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.EqualError(t, err, "message missing not found")
+
+	require.NoError(t, app.store.StoreMessage("text1", "1234@s.whatsapp.net", "1234", "hi", time.Now(), false,
+		"", "", "", "", "", nil, nil, nil, 0))
+	_, _, _, err = app.OpenMediaFile(context.Background(), "text1", nil)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.ErrorContains(t, err, "no downloadable media")
+}
+
+func TestOpenMediaFileDownloadFailure(t *testing.T) {
+	app := newMediaApp(t)
+	app.mediaDownloader = func(ctx context.Context, info store.MessageDownloadInfo, targetPath string) (int64, error) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	_, _, _, err := app.OpenMediaFile(context.Background(), "msg1", nil)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.NotErrorIs(t, err, fs.ErrNotExist)
+}
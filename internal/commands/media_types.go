@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// MediaTypePolicy decides what media OpenMediaFile serves, by the type its
+// content actually is rather than the one the sender declared.
+type MediaTypePolicy struct {
+	// Blocked are MIME types, or "type/*" for all of a kind, that are
+	// never served.
+	Blocked []string
+	// RejectMismatch refuses media whose content is not of its declared
+	// type, instead of serving it as the type it is.
+	RejectMismatch bool
+}
+
+// SetMediaTypePolicy sets what media OpenMediaFile refuses to serve.
+func (a *App) SetMediaTypePolicy(p MediaTypePolicy) {
+	a.mediaTypes = p
+}
+
+// sniffLen is how much of a file http.DetectContentType looks at.
+const sniffLen = 512
+
+// verifyMediaType determines the type of the media read from f, which is
+// left at its start, checks it against the policy and returns it with name
+// given the matching extension.
+func (a *App) verifyMediaType(info store.MessageDownloadInfo, f io.ReadSeeker, name string) (string, string, error) {
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", "", err
+	}
+
+	declared, sniffed := baseMediaType(info.MimeType), baseMediaType(http.DetectContentType(buf[:n]))
+	actual := declared
+	// Content sniffed as plain text or arbitrary bytes says nothing about
+	// its type
+	if sniffed != "application/octet-stream" && sniffed != "text/plain" && !compatibleMediaTypes(declared, sniffed) {
+		if declared != "" && a.mediaTypes.RejectMismatch {
+			return "", "", quarantinedError{fmt.Sprintf("media of message %s is declared as %s but is %s", info.ID, declared, sniffed)}
+		}
+		actual = sniffed
+	}
+	if actual == "" {
+		actual = "application/octet-stream"
+	}
+	if blockedMediaType(actual, a.mediaTypes.Blocked) {
+		return "", "", quarantinedError{fmt.Sprintf("media of message %s is %s, which is not served", info.ID, actual)}
+	}
+	name = withMediaExtension(name, actual)
+	if actual == declared {
+		// Keep parameters such as the codecs of voice notes
+		return info.MimeType, name, nil
+	}
+	return actual, name, nil
+}
+
+// baseMediaType is a MIME type without its parameters, in lower case.
+func baseMediaType(mimeType string) string {
+	if t, _, err := mime.ParseMediaType(mimeType); err == nil {
+		return t
+	}
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// compatibleMediaTypes reports whether content sniffed as sniffed can be of
+// the declared type. Sniffing only tells containers apart: audio and video
+// share theirs, and office documents and e-books are zip files.
+func compatibleMediaTypes(declared, sniffed string) bool {
+	if declared == sniffed {
+		return true
+	}
+	switch {
+	case sniffed == "application/ogg" || strings.HasPrefix(sniffed, "audio/") || strings.HasPrefix(sniffed, "video/"):
+		return declared == "application/ogg" || strings.HasPrefix(declared, "audio/") || strings.HasPrefix(declared, "video/")
+	case sniffed == "application/zip":
+		return strings.HasPrefix(declared, "application/")
+	}
+	return false
+}
+
+// blockedMediaType reports whether mimeType is listed in blocked.
+func blockedMediaType(mimeType string, blocked []string) bool {
+	kind, _, _ := strings.Cut(mimeType, "/")
+	for _, b := range blocked {
+		if b == mimeType || b == kind+"/*" {
+			return true
+		}
+	}
+	return false
+}
+
+// withMediaExtension gives name the extension of mimeType when it has none
+// or one that stands for another kind of content.
+func withMediaExtension(name, mimeType string) string {
+	ext := filepath.Ext(name)
+	if ext != "" {
+		byExt := baseMediaType(mime.TypeByExtension(ext))
+		if byExt == "" || compatibleMediaTypes(mimeType, byExt) {
+			return name
+		}
+	}
+	want := extensionForMime(mimeType)
+	if want == "" {
+		return name
+	}
+	return strings.TrimSuffix(name, ext) + want
+}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+const testPNG = "\x89PNG\r\n\x1a\n a private picture"
+
+func TestOpenMediaFileVerifiesType(t *testing.T) {
+	app := newMediaApp(t)
+	ctx := context.Background()
+	content := testPNG
+	app.mediaDownloader = func(ctx context.Context, info store.MessageDownloadInfo, targetPath string) (int64, error) {
+		return int64(len(content)), os.WriteFile(targetPath, []byte(content), 0o644)
+	}
+
+	// Declared as a JPEG photo.jpg, but a PNG
+	f, name, mimeType, err := app.OpenMediaFile(ctx, "msg1", nil)
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	f.Close()
+	require.NoError(t, err)
+	assert.Equal(t, testPNG, string(data), "sniffing leaves the file at its start")
+	assert.Equal(t, "image/png", mimeType)
+	assert.Equal(t, "photo.png", name)
+
+	app.SetMediaTypePolicy(MediaTypePolicy{RejectMismatch: true})
+	_, _, _, err = app.OpenMediaFile(ctx, "msg1", nil)
+	assert.ErrorIs(t, err, fs.ErrPermission)
+	assert.EqualError(t, err, "media of message msg1 is declared as image/jpeg but is image/png")
+}
+
+func TestOpenMediaFileBlocksTypes(t *testing.T) {
+	app := newMediaApp(t)
+	ctx := context.Background()
+	content := "<!DOCTYPE html><script>alert(1)</script>"
+	app.mediaDownloader = func(ctx context.Context, info store.MessageDownloadInfo, targetPath string) (int64, error) {
+		return int64(len(content)), os.WriteFile(targetPath, []byte(content), 0o644)
+	}
+	app.SetMediaTypePolicy(MediaTypePolicy{Blocked: []string{"text/html"}})
+	_, _, _, err := app.OpenMediaFile(ctx, "msg1", nil)
+	assert.ErrorIs(t, err, fs.ErrPermission)
+	assert.EqualError(t, err, "media of message msg1 is text/html, which is not served")
+
+	info, err := app.store.GetMessageForDownload("msg1", nil)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(*info.LocalPath))
+	content = testPhoto
+	app.SetMediaTypePolicy(MediaTypePolicy{Blocked: []string{"image/*"}})
+	_, _, _, err = app.OpenMediaFile(ctx, "msg1", nil)
+	assert.EqualError(t, err, "media of message msg1 is image/jpeg, which is not served")
+}
+
+func TestCompatibleMediaTypes(t *testing.T) {
+	assert.True(t, compatibleMediaTypes("audio/ogg", "application/ogg"))
+	assert.True(t, compatibleMediaTypes("audio/mp4", "video/mp4"))
+	assert.True(t, compatibleMediaTypes("application/vnd.openxmlformats-officedocument.wordprocessingml.document", "application/zip"))
+	assert.False(t, compatibleMediaTypes("image/jpeg", "text/html"))
+	assert.False(t, compatibleMediaTypes("application/pdf", "image/png"))
+}
+
+func TestWithMediaExtension(t *testing.T) {
+	assert.Equal(t, "photo.jpg", withMediaExtension("photo.jpg", "image/jpeg"))
+	assert.Equal(t, "photo.png", withMediaExtension("photo.jpg", "image/png"))
+	assert.Equal(t, "invoice.pdf", withMediaExtension("invoice", "application/pdf"))
+	assert.Equal(t, "notes.unknownext", withMediaExtension("notes.unknownext", "application/pdf"))
+}
@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// mediaZipManifest is the manifest.json of a chat's media bundle.
+type mediaZipManifest struct {
+	ChatJID   string         `json:"chat_jid"`
+	ChatName  string         `json:"chat_name,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	After     *time.Time     `json:"after,omitempty"`
+	Before    *time.Time     `json:"before,omitempty"`
+	MediaType string         `json:"media_type,omitempty"`
+	Files     []mediaZipFile `json:"files"`
+	// Skipped are the media left out, e.g. because they are quarantined or
+	// their file is gone.
+	Skipped []mediaZipSkip `json:"skipped"`
+}
+
+type mediaZipFile struct {
+	Name      string    `json:"name"`
+	MessageID string    `json:"message_id"`
+	Sender    string    `json:"sender"`
+	IsFromMe  bool      `json:"is_from_me"`
+	Timestamp time.Time `json:"timestamp"`
+	MediaType string    `json:"media_type"`
+	MimeType  string    `json:"mime_type"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	Caption   string    `json:"caption,omitempty"`
+}
+
+type mediaZipSkip struct {
+	MessageID string `json:"message_id"`
+	Reason    string `json:"reason"`
+}
+
+// WriteChatMediaZip writes the media of a chat that was downloaded, oldest
+// first, as a ZIP archive to w, with a manifest.json describing each file.
+// Media is not downloaded for it, and media OpenMediaFile refuses is listed
+// as skipped. An error matching fs.ErrNotExist, returned before anything is
+// written, means no media matches p.
+func (a *App) WriteChatMediaZip(ctx context.Context, p store.ChatMediaParams, w io.Writer) error {
+	p.HideSpamFrom = a.spam.QuarantineThreshold
+	infos, err := a.store.DownloadedChatMedia(p)
+	if err != nil {
+		return err
+	}
+	if len(infos) == 0 {
+		return notFoundError{fmt.Sprintf("chat %s has no downloaded media", p.ChatJID)}
+	}
+
+	manifest := mediaZipManifest{
+		ChatJID:   infos[0].ChatJID,
+		CreatedAt: time.Now().UTC(),
+		After:     p.After,
+		Before:    p.Before,
+		MediaType: p.MediaType,
+		Files:     []mediaZipFile{},
+		Skipped:   []mediaZipSkip{},
+	}
+	if infos[0].ChatName != nil {
+		manifest.ChatName = *infos[0].ChatName
+	}
+	zw := zip.NewWriter(w)
+	names := map[string]bool{"manifest.json": true}
+	for _, info := range infos {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		localPath, ok := downloadedPath(info)
+		if !ok {
+			manifest.Skipped = append(manifest.Skipped, mediaZipSkip{info.ID, "media file not found on disk"})
+			continue
+		}
+		f, name, mimeType, err := a.openMedia(ctx, info, localPath)
+		if err != nil {
+			manifest.Skipped = append(manifest.Skipped, mediaZipSkip{info.ID, err.Error()})
+			continue
+		}
+		file, err := addZipMedia(zw, names, info, f, name, mimeType)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	mw, err := zw.Create("manifest.json")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(mw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// addZipMedia adds the media of a message, read from r, to zw. Its name
+// starts with the message's time, so the archive lists in chat order.
+func addZipMedia(zw *zip.Writer, names map[string]bool, info store.MessageDownloadInfo, r io.Reader, name, mimeType string) (mediaZipFile, error) {
+	stem := info.MessageTime.UTC().Format("20060102-150405") + "_" + name
+	entry := stem
+	for i := 2; names[entry]; i++ {
+		ext := path.Ext(stem)
+		entry = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(stem, ext), i, ext)
+	}
+	names[entry] = true
+
+	// Photos, videos and audio are compressed already
+	method := zip.Deflate
+	if t := baseMediaType(mimeType); strings.HasPrefix(t, "image/") || strings.HasPrefix(t, "video/") || strings.HasPrefix(t, "audio/") {
+		method = zip.Store
+	}
+	zf, err := zw.CreateHeader(&zip.FileHeader{Name: entry, Method: method, Modified: info.MessageTime})
+	if err != nil {
+		return mediaZipFile{}, err
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(zf, h), r)
+	if err != nil {
+		return mediaZipFile{}, fmt.Errorf("adding media of message %s: %w", info.ID, err)
+	}
+	return mediaZipFile{
+		Name:      entry,
+		MessageID: info.ID,
+		Sender:    info.Sender,
+		IsFromMe:  info.IsFromMe,
+		Timestamp: info.MessageTime.UTC(),
+		MediaType: info.MediaType,
+		MimeType:  mimeType,
+		Size:      n,
+		SHA256:    hex.EncodeToString(h.Sum(nil)),
+		Caption:   info.Content,
+	}, nil
+}
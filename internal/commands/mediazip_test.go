@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+func TestWriteChatMediaZip(t *testing.T) {
+	app := newMediaApp(t)
+	app.SetMediaTypePolicy(MediaTypePolicy{Blocked: []string{"text/html"}})
+	chat := "1234@s.whatsapp.net"
+	// msg1 is downloaded on demand; the others are on disk already, or
+	// were and are gone
+	f, _, _, err := app.OpenMediaFile(context.Background(), "msg1", nil)
+	require.NoError(t, err)
+	f.Close()
+	later := time.Now().Add(time.Minute)
+	for i, m := range []struct{ id, filename, mimeType, content string }{
+		{"msg2", "notes.txt", "text/plain", "meeting notes"},
+		{"msg3", "page.html", "text/html", "<html><body>hi</body></html>"},
+		{"msg4", "gone.txt", "text/plain", ""},
+	} {
+		at := later.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, app.store.StoreMessage(m.id, chat, "", "caption "+m.id, at, true,
+			"document", m.filename, "", "/p", m.mimeType, []byte{1}, nil, nil, 0))
+		path := filepath.Join(t.TempDir(), m.filename)
+		if m.content != "" {
+			require.NoError(t, os.WriteFile(path, []byte(m.content), 0o644))
+		}
+		require.NoError(t, app.store.MarkMediaDownloaded(m.id, chat, path, at))
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, app.WriteChatMediaZip(context.Background(), store.ChatMediaParams{ChatJID: chat}, &buf))
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, zr.File, 3)
+	assert.Equal(t, zip.Store, zr.File[0].Method)
+	assert.Equal(t, zip.Deflate, zr.File[1].Method)
+	assert.Equal(t, "manifest.json", zr.File[2].Name)
+	read := func(f *zip.File) []byte {
+		r, err := f.Open()
+		require.NoError(t, err)
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return data
+	}
+	assert.Equal(t, testPhoto, string(read(zr.File[0])))
+	assert.Equal(t, "meeting notes", string(read(zr.File[1])))
+
+	var manifest mediaZipManifest
+	require.NoError(t, json.Unmarshal(read(zr.File[2]), &manifest))
+	assert.Equal(t, chat, manifest.ChatJID)
+	assert.Equal(t, "John Doe", manifest.ChatName)
+	require.Len(t, manifest.Files, 2)
+	assert.Equal(t, zr.File[0].Name, manifest.Files[0].Name)
+	assert.Regexp(t, `^\d{8}-\d{6}_photo\.jpg$`, manifest.Files[0].Name)
+	assert.Equal(t, "msg1", manifest.Files[0].MessageID)
+	assert.Equal(t, "image/jpeg", manifest.Files[0].MimeType)
+	assert.Equal(t, int64(len(testPhoto)), manifest.Files[0].Size)
+	assert.Len(t, manifest.Files[0].SHA256, 64)
+	assert.Equal(t, "msg2", manifest.Files[1].MessageID)
+	assert.Equal(t, "caption msg2", manifest.Files[1].Caption)
+	assert.True(t, manifest.Files[1].IsFromMe)
+	require.Len(t, manifest.Skipped, 2)
+	assert.Equal(t, "msg3", manifest.Skipped[0].MessageID)
+	assert.Contains(t, manifest.Skipped[0].Reason, "not served")
+	assert.Equal(t, mediaZipSkip{"msg4", "media file not found on disk"}, manifest.Skipped[1])
+
+	// Filters that match nothing write nothing
+	buf.Reset()
+	err = app.WriteChatMediaZip(context.Background(), store.ChatMediaParams{ChatJID: chat, MediaType: "video"}, &buf)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.Zero(t, buf.Len())
+}
@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+)
+
+// mentionsFor resolves the users a message to group mentions to their JIDs,
+// checking that each is a member so that WhatsApp notifies them.
+func (a *App) mentionsFor(ctx context.Context, group string, mentions []string) ([]string, error) {
+	if !jid.IsGroup(group) {
+		return nil, fmt.Errorf("mentions are only supported in group chats")
+	}
+	if err := a.client.Connect(ctx); err != nil {
+		return nil, err
+	}
+	members, err := a.client.GroupParticipants(ctx, group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members of %s: %w", group, err)
+	}
+	isMember := make(map[string]bool, len(members))
+	// Groups may list their members by @lid JID
+	for _, m := range members {
+		isMember[jid.Normalize(a.client.PhoneJID(ctx, jid.Normalize(m)))] = true
+	}
+
+	var resolved, strangers []string
+	seen := map[string]bool{}
+	for _, m := range mentions {
+		user := jid.Normalize(a.client.PhoneJID(ctx, jid.Normalize(m)))
+		if seen[user] {
+			continue
+		}
+		seen[user] = true
+		if !isMember[user] {
+			strangers = append(strangers, m)
+			continue
+		}
+		resolved = append(resolved, user)
+	}
+	if len(strangers) > 0 {
+		return nil, fmt.Errorf("cannot mention %s: not a member of %s", strings.Join(strangers, ", "), group)
+	}
+	return resolved, nil
+}
+
+// withMentions makes sure message names every mentioned user as "@" and
+// their number, which is where WhatsApp shows the mention; the users it does
+// not name yet are put in front of it.
+func withMentions(message string, mentions []string) string {
+	var missing []string
+	for _, m := range mentions {
+		tag := "@" + jid.User(m)
+		if !containsTag(message, tag) {
+			missing = append(missing, tag)
+		}
+	}
+	if len(missing) == 0 {
+		return message
+	}
+	return strings.Join(missing, " ") + " " + message
+}
+
+// containsTag reports whether tag occurs in s other than as the start of a
+// longer number.
+func containsTag(s, tag string) bool {
+	for i := strings.Index(s, tag); i >= 0; {
+		end := i + len(tag)
+		if end == len(s) || s[end] < '0' || s[end] > '9' {
+			return true
+		}
+		next := strings.Index(s[end:], tag)
+		if next < 0 {
+			return false
+		}
+		i = end + next
+	}
+	return false
+}
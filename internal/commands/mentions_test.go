@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+)
+
+func TestWithMentions(t *testing.T) {
+	mentions := []string{"111@s.whatsapp.net", "222@s.whatsapp.net"}
+	assert.Equal(t, "hi @111 and @222", withMentions("hi @111 and @222", mentions))
+	assert.Equal(t, "@222 hi @111", withMentions("hi @111", mentions))
+	assert.Equal(t, "@111 @222 call @1112", withMentions("call @1112", mentions), "a longer number is not a mention")
+}
+
+func TestSendMessageMentionsGroupMembers(t *testing.T) {
+	app, fake := newFakeApp(t)
+	ctx := context.Background()
+	group := "120363000000000001@g.us"
+	fake.AddGroup(client.GroupSettings{JID: group, Subject: "Team"})
+	fake.AddGroupParticipants(group, "111@s.whatsapp.net", "222@s.whatsapp.net")
+
+	result := app.SendMessage(ctx, group, "standup in 5", client.SendOptions{Mentions: []string{"+111", "222@s.whatsapp.net"}})
+	assert.Contains(t, result, `"mentions":["111@s.whatsapp.net","222@s.whatsapp.net"]`)
+	sent := fake.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "@111 @222 standup in 5", sent[0].Message)
+	assert.Equal(t, []string{"111@s.whatsapp.net", "222@s.whatsapp.net"}, sent[0].Mentions)
+
+	result = app.SendMessage(ctx, group, "hey @333", client.SendOptions{Mentions: []string{"333"}})
+	assert.Contains(t, result, "cannot mention 333: not a member of "+group)
+	result = app.SendMessage(ctx, "1234567890", "hey", client.SendOptions{Mentions: []string{"222"}})
+	assert.Contains(t, result, "only supported in group chats")
+	assert.Len(t, fake.Sent(), 1)
+}
+
+func TestSendMessageMentionsLIDMembers(t *testing.T) {
+	app, fake := newFakeApp(t)
+	group := "120363000000000001@g.us"
+	fake.AddGroup(client.GroupSettings{JID: group, Subject: "Team"})
+	fake.AddGroupParticipants(group, "98765432109876@lid")
+	fake.MapLID("98765432109876@lid", "111@s.whatsapp.net")
+
+	result := app.SendMessage(context.Background(), group, "hi", client.SendOptions{Mentions: []string{"+111"}})
+	assert.Contains(t, result, `"mentions":["111@s.whatsapp.net"]`)
+	require.Len(t, fake.Sent(), 1)
+	assert.Equal(t, "@111 hi", fake.Sent()[0].Message)
+}
@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/reqid"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// MergeChats folds the history of a contact's old chat into their new one,
+// for contacts who changed phone numbers. The old JID stays resolvable as an
+// alias of the new one.
+func (a *App) MergeChats(ctx context.Context, from, into string) string {
+	for _, chatJID := range []*string{&from, &into} {
+		*chatJID = jid.Normalize(*chatJID)
+		if jid.User(*chatJID) == "" || jid.Server(*chatJID) == "" {
+			return output.Error(fmt.Errorf("invalid chat JID %q", *chatJID))
+		}
+		if jid.IsGroup(*chatJID) {
+			return output.Error(fmt.Errorf("%s is a group; only individual chats can be merged", *chatJID))
+		}
+	}
+
+	result, err := a.store.MergeChats(from, into, reqid.FromContext(ctx), time.Now().UTC())
+	if err != nil {
+		return output.Error(err)
+	}
+	aliases, err := a.store.ListChatAliases(result.Into)
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(struct {
+		store.MergeResult
+		Aliases []store.ChatAlias `json:"aliases"`
+	}{result, aliases})
+}
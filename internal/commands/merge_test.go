@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeChats(t *testing.T) {
+	app, _ := newFakeApp(t)
+	now := time.Now()
+	require.NoError(t, app.store.StoreChat("111@s.whatsapp.net", "Alice", now))
+	require.NoError(t, app.store.StoreMessage("m1", "111@s.whatsapp.net", "111@s.whatsapp.net", "hi", now, false, "", "", "", "", "", nil, nil, nil, 0))
+
+	result := app.MergeChats(context.Background(), "111", "222@s.whatsapp.net")
+	assert.Contains(t, result, `"success":true`)
+	assert.Contains(t, result, `"messages":1`)
+	assert.Contains(t, result, `"alias_jid":"111@s.whatsapp.net"`)
+
+	chat := "111@s.whatsapp.net"
+	msgs := app.ListMessages(&chat, nil, 10, 0, nil, nil, nil, nil, nil)
+	assert.Contains(t, msgs, `"chat_jid":"222@s.whatsapp.net"`)
+
+	assert.Contains(t, app.MergeChats(context.Background(), "123-456@g.us", "222"), "only individual chats")
+	assert.Contains(t, app.MergeChats(context.Background(), "", "222"), "invalid chat JID")
+}
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/mediaurl"
+)
+
+// messageWebhookAttempts is how often a message is posted before it is
+// queued in the outbox, or dropped without one.
+const messageWebhookAttempts = 3
+
+type messageWebhook struct {
+	url        string
+	httpClient *http.Client
+	retryDelay time.Duration // multiplied by the attempt number
+}
+
+// messageWebhookData is the payload posted for every incoming message.
+type messageWebhookData struct {
+	Event     string    `json:"event"`
+	ID        string    `json:"id"`
+	ChatJID   string    `json:"chat_jid"`
+	ChatName  string    `json:"chat_name,omitempty"`
+	Sender    string    `json:"sender"`
+	Name      string    `json:"name,omitempty"` // push name
+	Content   string    `json:"content"`
+	MediaType string    `json:"media_type,omitempty"`
+	Filename  string    `json:"filename,omitempty"`
+	MimeType  string    `json:"mime_type,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	// MediaURL downloads the media without an API key until
+	// MediaURLExpiresAt; set with SetMediaURLs.
+	MediaURL          string     `json:"media_url,omitempty"`
+	MediaURLExpiresAt *time.Time `json:"media_url_expires_at,omitempty"`
+}
+
+// SetMessageWebhook posts every message received while syncing to url as
+// JSON, signed if a webhook secret is set. Failed posts are retried a few
+// times, then queued in the outbox. Empty disables it.
+func (a *App) SetMessageWebhook(url string) {
+	if url == "" {
+		a.messageWebhook = nil
+		return
+	}
+	a.messageWebhook = &messageWebhook{
+		url:        url,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		retryDelay: time.Second,
+	}
+}
+
+// SetMediaURLs adds URLs signed by s to webhook payloads of messages with
+// media, so consumers can download it without the API key. Nil disables them.
+func (a *App) SetMediaURLs(s *mediaurl.Signer) {
+	a.mediaURLs = s
+}
+
+// postMessageWebhook delivers an incoming message to the message webhook.
+func (a *App) postMessageWebhook(ctx context.Context, data messageWebhookData) {
+	h := a.messageWebhook
+	data.Event = "message"
+	data.Timestamp = data.Timestamp.UTC()
+	if data.MediaType != "" && a.mediaURLs != nil {
+		u, expires := a.mediaURLs.URL(data.ID, data.ChatJID, time.Now())
+		data.MediaURL, data.MediaURLExpiresAt = u, &expires
+	}
+	body, _ := json.Marshal(data)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = a.postWebhook(ctx, h.httpClient, h.url, body); err == nil {
+			return
+		}
+		if attempt == messageWebhookAttempts || isPermanent(err) || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Duration(attempt) * h.retryDelay):
+		}
+	}
+	if a.outboxMaxAge <= 0 || isPermanent(err) {
+		fmt.Fprintf(os.Stderr, "\n⚠ Message webhook failed for %s: %v\n", data.ID, err)
+		return
+	}
+	if qerr := a.enqueueOutbox(SinkMessageWebhook, h.url, data.ChatJID, body); qerr != nil {
+		fmt.Fprintf(os.Stderr, "\n⚠ Message webhook failed for %s: %v (not queued: %v)\n", data.ID, err, qerr)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\n⚠ Message webhook failed for %s, queued for retry: %v\n", data.ID, err)
+}
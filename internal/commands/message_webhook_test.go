@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client/fakeclient"
+	"github.com/vicentereig/whatsapp-cli/internal/mediaurl"
+	"github.com/vicentereig/whatsapp-cli/pkg/webhookverify"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestMessageWebhookPostsIncomingMessages(t *testing.T) {
+	secret := "0123456789abcdef"
+	verifier := webhookverify.NewVerifier([]byte(secret))
+	var mu sync.Mutex
+	var hooks []map[string]any
+	failures := 2
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		// The receiver is unavailable for the first attempts
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, err := verifier.VerifyRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		var data map[string]any
+		json.Unmarshal(body, &data)
+		hooks = append(hooks, data)
+	}))
+	defer hook.Close()
+
+	app, fake := newFakeApp(t)
+	app.SetWebhookSecret(secret)
+	app.SetMessageWebhook(hook.URL)
+	app.messageWebhook.retryDelay = time.Millisecond
+	startSync(t, app, fake)
+
+	alice := types.NewJID("111", types.DefaultUserServer)
+	msg := fakeclient.TextMessage(alice, alice, "M1", "hello", time.Now(), false)
+	msg.Info.PushName = "Alice"
+	fake.Emit(msg)
+	// Messages of the account are not posted
+	fake.Emit(fakeclient.TextMessage(alice, types.NewJID("999", types.DefaultUserServer), "M2", "hi Alice", time.Now(), true))
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(hooks) == 1
+	}, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, hooks, 1)
+	assert.Equal(t, "message", hooks[0]["event"])
+	assert.Equal(t, "M1", hooks[0]["id"])
+	assert.Equal(t, alice.String(), hooks[0]["chat_jid"])
+	assert.Equal(t, "Alice", hooks[0]["name"])
+	assert.Equal(t, "hello", hooks[0]["content"])
+}
+
+func TestMessageWebhookQueuesUndelivered(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	status := http.StatusBadGateway
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		w.WriteHeader(status)
+	}))
+	defer hook.Close()
+
+	app, fake := newFakeApp(t)
+	app.SetOutboxMaxAge(time.Hour)
+	app.SetMessageWebhook(hook.URL)
+	app.messageWebhook.retryDelay = time.Millisecond
+	startSync(t, app, fake)
+
+	alice := types.NewJID("111", types.DefaultUserServer)
+	fake.EmitText(alice, alice, "M1", "hello", time.Now())
+	require.Eventually(t, func() bool {
+		return app.OutboxStats().Pending[SinkMessageWebhook] == 1
+	}, time.Second, 5*time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, messageWebhookAttempts, requests)
+
+	// A rejected message is neither retried nor queued
+	status, requests = http.StatusBadRequest, 0
+	mu.Unlock()
+	fake.EmitText(alice, alice, "M2", "hello again", time.Now())
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests == 1
+	}, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, 1, requests)
+	mu.Unlock()
+	assert.Equal(t, int64(1), app.OutboxStats().Pending[SinkMessageWebhook])
+}
+
+func TestMessageWebhookSignsMediaURLs(t *testing.T) {
+	bodies := make(chan map[string]any, 2)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data map[string]any
+		json.NewDecoder(r.Body).Decode(&data)
+		bodies <- data
+	}))
+	defer hook.Close()
+
+	app, _ := newFakeApp(t)
+	app.SetMessageWebhook(hook.URL)
+	signer := mediaurl.New("key", "https://wa.example.com", time.Minute)
+	app.SetMediaURLs(signer)
+
+	app.postMessageWebhook(context.Background(), messageWebhookData{
+		ID: "M1", ChatJID: "111@s.whatsapp.net", MediaType: "image", Timestamp: time.Now(),
+	})
+	data := <-bodies
+	u, err := url.Parse(data["media_url"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, "wa.example.com", u.Host)
+	assert.Equal(t, mediaurl.PathPrefix+"M1", u.Path)
+	chat, ok := signer.Verify("M1", u.Query(), time.Now())
+	assert.True(t, ok)
+	assert.Equal(t, "111@s.whatsapp.net", chat)
+	assert.NotEmpty(t, data["media_url_expires_at"])
+
+	// Text messages have no media to link
+	app.postMessageWebhook(context.Background(), messageWebhookData{
+		ID: "M2", ChatJID: "111@s.whatsapp.net", Timestamp: time.Now(),
+	})
+	data = <-bodies
+	assert.NotContains(t, data, "media_url")
+}
@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+const (
+	// maxMetaKeyLength bounds a metadata key.
+	maxMetaKeyLength = 64
+	// maxMetaValueLength bounds a metadata value, in bytes. Metadata is for
+	// references such as CRM IDs, not documents.
+	maxMetaValueLength = 4096
+)
+
+// validMetaKey reports whether key is 1 to 64 letters, digits, '_', '.' or '-'.
+func validMetaKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("metadata key cannot be empty")
+	}
+	if len(key) > maxMetaKeyLength {
+		return fmt.Errorf("metadata key %q is longer than %d characters", key, maxMetaKeyLength)
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.' || r == '-') {
+			return fmt.Errorf("metadata key %q may only contain letters, digits, '_', '.' and '-'", key)
+		}
+	}
+	return nil
+}
+
+// metaTarget resolves the record metadata is attached to. target is a chat or
+// contact JID, or a message ID whose chat is found as for tags.
+func (a *App) metaTarget(kind, target string, chatJID *string) (store.MetaTarget, error) {
+	switch kind {
+	case store.MetaChat, store.MetaContact:
+		j := jid.Normalize(target)
+		if j == "" {
+			return store.MetaTarget{}, fmt.Errorf("%s JID required", kind)
+		}
+		if !strings.Contains(j, "@") {
+			j += "@s.whatsapp.net"
+		}
+		return store.MetaTarget{Kind: kind, JID: j}, nil
+	case store.MetaMessage:
+		chat, err := a.messageChat(target, chatJID)
+		if err != nil {
+			return store.MetaTarget{}, err
+		}
+		return store.MetaTarget{Kind: kind, JID: chat, MessageID: target}, nil
+	}
+	return store.MetaTarget{}, fmt.Errorf("unknown metadata kind %q (want chat, contact or message)", kind)
+}
+
+// SetMeta sets a metadata key of a chat, contact or message, so integrators
+// can keep their own state, e.g. a ticket number, next to the archive. The
+// result lists all of the record's metadata.
+func (a *App) SetMeta(kind, target string, chatJID *string, key, value string) string {
+	if err := validMetaKey(key); err != nil {
+		return output.Error(err)
+	}
+	if len(value) > maxMetaValueLength {
+		return output.Error(fmt.Errorf("metadata value is longer than %d bytes", maxMetaValueLength))
+	}
+	t, err := a.metaTarget(kind, target, chatJID)
+	if err != nil {
+		return output.Error(err)
+	}
+	if err := a.store.SetMeta(t, key, value, time.Now().UTC()); err != nil {
+		return output.Error(err)
+	}
+	return a.metaResult(t)
+}
+
+// DeleteMeta removes a metadata key of a chat, contact or message. The result
+// lists the record's remaining metadata.
+func (a *App) DeleteMeta(kind, target string, chatJID *string, key string) string {
+	t, err := a.metaTarget(kind, target, chatJID)
+	if err != nil {
+		return output.Error(err)
+	}
+	deleted, err := a.store.DeleteMeta(t, key)
+	if err != nil {
+		return output.Error(err)
+	}
+	if !deleted {
+		return output.Error(fmt.Errorf("%s %s has no metadata key %q", kind, target, key))
+	}
+	return a.metaResult(t)
+}
+
+// GetMeta lists the metadata of a chat, contact or message.
+func (a *App) GetMeta(kind, target string, chatJID *string) string {
+	t, err := a.metaTarget(kind, target, chatJID)
+	if err != nil {
+		return output.Error(err)
+	}
+	return a.metaResult(t)
+}
+
+// FindMeta lists metadata entries, most recently updated first. kind, key
+// and value narrow the entries when set, e.g. to find the chat of a CRM ID.
+func (a *App) FindMeta(kind, key string, value *string, limit, page int, includeJIDs, excludeJIDs []string) string {
+	switch kind {
+	case "", store.MetaChat, store.MetaContact, store.MetaMessage:
+	default:
+		return output.Error(fmt.Errorf("unknown metadata kind %q (want chat, contact or message)", kind))
+	}
+	entries, err := a.store.FindMeta(store.MetaFilter{
+		Kind:        kind,
+		Key:         key,
+		Value:       value,
+		IncludeJIDs: includeJIDs,
+		ExcludeJIDs: excludeJIDs,
+		Limit:       limit,
+		Page:        page,
+	})
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(entries)
+}
+
+func (a *App) metaResult(t store.MetaTarget) string {
+	meta, err := a.store.Meta(t)
+	if err != nil {
+		return output.Error(err)
+	}
+	res := map[string]interface{}{
+		"kind": t.Kind,
+		"jid":  t.JID,
+		"meta": meta,
+	}
+	if t.MessageID != "" {
+		res["id"] = t.MessageID
+	}
+	return output.Success(res)
+}
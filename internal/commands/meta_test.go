@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+func TestMeta(t *testing.T) {
+	app, _ := newFakeApp(t)
+	now := time.Now().UTC()
+	chat := "34600111222@s.whatsapp.net"
+	require.NoError(t, app.store.StoreChat(chat, "Alice", now))
+	require.NoError(t, app.store.StoreMessage("m1", chat, "34600111222", "my order is late", now, false, "", "", "", "", "", nil, nil, nil, 0))
+
+	var res struct {
+		Success bool `json:"success"`
+		Data    struct {
+			Kind string            `json:"kind"`
+			JID  string            `json:"jid"`
+			ID   string            `json:"id"`
+			Meta map[string]string `json:"meta"`
+		} `json:"data"`
+		Error string `json:"error"`
+	}
+	decode := func(out string) {
+		t.Helper()
+		res.Data.Meta = nil
+		require.NoError(t, json.Unmarshal([]byte(out), &res))
+		require.True(t, res.Success, res.Error)
+	}
+	decode(app.SetMeta(store.MetaChat, "+34 600 111 222", nil, "crm_id", "C-42"))
+	assert.Equal(t, chat, res.Data.JID)
+	assert.Equal(t, map[string]string{"crm_id": "C-42"}, res.Data.Meta)
+
+	decode(app.SetMeta(store.MetaMessage, "m1", nil, "ticket", "T-7"))
+	assert.Equal(t, "m1", res.Data.ID)
+	assert.Equal(t, chat, res.Data.JID)
+	assert.Equal(t, map[string]string{"ticket": "T-7"}, res.Data.Meta)
+
+	assert.Contains(t, app.GetMeta(store.MetaContact, chat, nil), `"meta":{}`)
+	assert.Contains(t, app.GetMeta(store.MetaChat, chat, nil), `"crm_id":"C-42"`)
+
+	value := "C-42"
+	found := app.FindMeta("", "crm_id", &value, 10, 0, nil, nil)
+	assert.Contains(t, found, `"jid":"`+chat+`"`)
+	assert.Contains(t, app.FindMeta("", "crm_id", nil, 10, 0, nil, []string{"@s.whatsapp.net"}), `"data":[]`)
+
+	decode(app.DeleteMeta(store.MetaChat, chat, nil, "crm_id"))
+	assert.Empty(t, res.Data.Meta)
+	assert.Contains(t, app.DeleteMeta(store.MetaChat, chat, nil, "crm_id"), "no metadata key")
+
+	assert.Contains(t, app.SetMeta(store.MetaChat, chat, nil, "crm id", "x"), "may only contain")
+	assert.Contains(t, app.SetMeta(store.MetaChat, chat, nil, "", "x"), "cannot be empty")
+	assert.Contains(t, app.SetMeta(store.MetaChat, chat, nil, "k", strings.Repeat("x", 5000)), "longer than 4096 bytes")
+	assert.Contains(t, app.SetMeta(store.MetaMessage, "missing", nil, "k", "v"), "message missing not found")
+	assert.Contains(t, app.SetMeta("group", chat, nil, "k", "v"), "unknown metadata kind")
+	assert.Contains(t, app.FindMeta("group", "", nil, 10, 0, nil, nil), "unknown metadata kind")
+}
@@ -0,0 +1,198 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/eventbus"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// Outbox sinks: the backends whose undelivered notifications are queued.
+const (
+	SinkEventBus        = "event_bus"
+	SinkNotifier        = "notifier"
+	SinkGreetingWebhook = "greeting_webhook"
+	SinkDigestWebhook   = "digest_webhook"
+	SinkRecipeWebhook   = "recipe_webhook"
+	SinkFollowupWebhook = "followup_webhook"
+	SinkMessageWebhook  = "message_webhook"
+)
+
+// DefaultOutboxMaxAge is how long an undelivered notification is kept for
+// replay before it is dropped.
+const DefaultOutboxMaxAge = 24 * time.Hour
+
+// outboxBatch is the number of queued notifications replayed per sink and
+// round.
+const outboxBatch = 100
+
+// SetOutboxMaxAge sets how long notifications a webhook or broker did not
+// accept are kept in the store for replay. Zero disables the outbox, so
+// failed notifications are only logged.
+func (a *App) SetOutboxMaxAge(maxAge time.Duration) {
+	a.outboxMaxAge = maxAge
+	a.spoolBus(a.events, SinkEventBus)
+	a.spoolBus(a.notifier, SinkNotifier)
+}
+
+// spoolBus queues the events bus fails to publish under sink.
+func (a *App) spoolBus(bus *eventbus.Bus, sink string) {
+	if bus == nil {
+		return
+	}
+	if a.outboxMaxAge <= 0 {
+		bus.SetSpool(nil)
+		return
+	}
+	bus.SetSpool(func(topic, key string, payload []byte) error {
+		return a.enqueueOutbox(sink, topic, key, payload)
+	})
+}
+
+func (a *App) enqueueOutbox(sink, target, key string, payload []byte) error {
+	return a.store.EnqueueOutbox(store.OutboxItem{
+		Sink:      sink,
+		Target:    target,
+		Key:       key,
+		Payload:   payload,
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+// OutboxStats returns the queue depth per sink and the replay counters.
+func (a *App) OutboxStats() store.OutboxStats {
+	stats := store.OutboxStats{
+		Replayed: a.outboxReplayed.Load(),
+		Expired:  a.outboxExpired.Load(),
+	}
+	depth, err := a.store.OutboxDepth()
+	if err != nil {
+		depth = map[string]int64{}
+	}
+	stats.Pending = depth
+	return stats
+}
+
+// runOutbox replays queued notifications every interval until ctx is
+// cancelled.
+func (a *App) runOutbox(ctx context.Context, interval time.Duration) {
+	if a.outboxMaxAge <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.replayOutbox(ctx)
+		}
+	}
+}
+
+// replayOutbox drops expired notifications and delivers the queued ones of
+// every configured sink in order, stopping at a sink's first failure.
+func (a *App) replayOutbox(ctx context.Context) {
+	if n, err := a.store.ExpireOutbox(time.Now().UTC().Add(-a.outboxMaxAge)); err == nil && n > 0 {
+		a.outboxExpired.Add(n)
+		fmt.Fprintf(os.Stderr, "⚠ Dropped %d queued notifications older than %s\n", n, a.outboxMaxAge)
+	}
+
+	for sink, deliver := range a.outboxSinks() {
+		drained := a.replaySink(ctx, sink, deliver)
+		if bus := a.sinkBus(sink); bus != nil && drained {
+			bus.Resume()
+		}
+	}
+}
+
+// replaySink delivers the queued notifications of one sink and reports
+// whether its queue was emptied.
+func (a *App) replaySink(ctx context.Context, sink string, deliver func(ctx context.Context, item store.OutboxItem) error) bool {
+	for {
+		items, err := a.store.PendingOutbox(sink, outboxBatch)
+		if err != nil {
+			return false
+		}
+		for _, item := range items {
+			err := deliver(ctx, item)
+			if err != nil && !isPermanent(err) {
+				a.store.RecordOutboxFailure(item.ID, err.Error())
+				return false
+			}
+			if err := a.store.DeleteOutbox(item.ID); err != nil {
+				return false
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "⚠ Dropped queued %s notification %d: %v\n", sink, item.ID, err)
+				continue
+			}
+			a.outboxReplayed.Add(1)
+		}
+		if len(items) < outboxBatch || ctx.Err() != nil {
+			return ctx.Err() == nil
+		}
+	}
+}
+
+// outboxSinks maps each configured sink to the function delivering its
+// queued notifications. Notifications of sinks no longer configured stay
+// queued until they expire.
+func (a *App) outboxSinks() map[string]func(ctx context.Context, item store.OutboxItem) error {
+	sinks := map[string]func(ctx context.Context, item store.OutboxItem) error{}
+	for _, sink := range []string{SinkEventBus, SinkNotifier} {
+		if bus := a.sinkBus(sink); bus != nil {
+			sinks[sink] = func(ctx context.Context, item store.OutboxItem) error {
+				return bus.Deliver(ctx, item.Target, item.Key, item.Payload)
+			}
+		}
+	}
+	if g := a.greeter; g != nil && g.webhookURL != "" {
+		sinks[SinkGreetingWebhook] = func(ctx context.Context, item store.OutboxItem) error {
+			return a.postWebhook(ctx, g.httpClient, item.Target, item.Payload)
+		}
+	}
+	if d := a.digests; d != nil && d.webhookURL != "" {
+		sinks[SinkDigestWebhook] = func(ctx context.Context, item store.OutboxItem) error {
+			return a.postWebhook(ctx, d.httpClient, item.Target, item.Payload)
+		}
+	}
+	if f := a.followups; f != nil && f.webhookURL != "" {
+		sinks[SinkFollowupWebhook] = func(ctx context.Context, item store.OutboxItem) error {
+			return a.postWebhook(ctx, f.httpClient, item.Target, item.Payload)
+		}
+	}
+	if h := a.messageWebhook; h != nil {
+		sinks[SinkMessageWebhook] = func(ctx context.Context, item store.OutboxItem) error {
+			return a.postWebhook(ctx, h.httpClient, item.Target, item.Payload)
+		}
+	}
+	if book := a.recipes.Load(); book != nil {
+		sinks[SinkRecipeWebhook] = func(ctx context.Context, item store.OutboxItem) error {
+			return a.postWebhook(ctx, book.httpClient, item.Target, item.Payload)
+		}
+	}
+	return sinks
+}
+
+// isPermanent reports whether err says a notification was rejected rather
+// than not delivered, so it must not be retried.
+func isPermanent(err error) bool {
+	var p interface{ permanent() bool }
+	return errors.As(err, &p) && p.permanent()
+}
+
+func (a *App) sinkBus(sink string) *eventbus.Bus {
+	switch sink {
+	case SinkEventBus:
+		return a.events
+	case SinkNotifier:
+		return a.notifier
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/eventbus"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// switchablePublisher fails every publish while down is set.
+type switchablePublisher struct {
+	recordingPublisher
+	down atomic.Bool
+}
+
+func (p *switchablePublisher) Publish(ctx context.Context, topic, key string, payload []byte) error {
+	if p.down.Load() {
+		return errors.New("connection refused")
+	}
+	return p.recordingPublisher.Publish(ctx, topic, key, payload)
+}
+
+func TestOutboxReplaysEventsAfterBrokerRecovers(t *testing.T) {
+	app, fake := newFakeApp(t)
+	pub := &switchablePublisher{}
+	pub.down.Store(true)
+	bus := eventbus.NewBus(pub, eventbus.FormatJSON, "wa")
+	app.SetEventBus(bus)
+	startSync(t, app, fake)
+
+	alice := types.NewJID("111", types.DefaultUserServer)
+	fake.EmitText(alice, alice, "M1", "hello", time.Now())
+	require.Eventually(t, func() bool {
+		return app.OutboxStats().Pending[SinkEventBus] == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, bus.Spooling())
+
+	// Still down: the event stays queued
+	app.replayOutbox(context.Background())
+	assert.Equal(t, int64(1), app.OutboxStats().Pending[SinkEventBus])
+
+	pub.down.Store(false)
+	app.replayOutbox(context.Background())
+	stats := app.OutboxStats()
+	assert.Empty(t, stats.Pending)
+	assert.Equal(t, int64(1), stats.Replayed)
+	assert.False(t, bus.Spooling())
+
+	topics, evts := pub.snapshot()
+	require.Len(t, evts, 1)
+	assert.Equal(t, []string{"wa.messages"}, topics)
+	assert.Equal(t, "M1", evts[0].Message.ID)
+}
+
+func TestOutboxQueuesFailedGreetingWebhook(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	var mu sync.Mutex
+	var delivered []map[string]any
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if code := int(status.Load()); code != http.StatusOK {
+			w.WriteHeader(code)
+			return
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		delivered = append(delivered, body)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	app, _ := newFakeApp(t)
+	require.NoError(t, app.SetGreeter(GreeterConfig{WebhookURL: hook.URL}))
+	ctx := context.Background()
+
+	app.greet(ctx, greetingData{JID: "111@s.whatsapp.net", Phone: "111"})
+	assert.Equal(t, map[string]int64{SinkGreetingWebhook: 1}, app.OutboxStats().Pending)
+
+	status.Store(http.StatusOK)
+	app.replayOutbox(ctx)
+	assert.Empty(t, app.OutboxStats().Pending)
+	mu.Lock()
+	require.Len(t, delivered, 1)
+	assert.Equal(t, "111@s.whatsapp.net", delivered[0]["jid"])
+	mu.Unlock()
+
+	// A rejected request is not worth retrying
+	status.Store(http.StatusBadRequest)
+	app.greet(ctx, greetingData{JID: "222@s.whatsapp.net", Phone: "222"})
+	assert.Empty(t, app.OutboxStats().Pending)
+}
+
+func TestOutboxExpiresOldNotifications(t *testing.T) {
+	app, _ := newFakeApp(t)
+	app.SetOutboxMaxAge(time.Hour)
+	require.NoError(t, app.store.EnqueueOutbox(store.OutboxItem{Sink: SinkNotifier, Target: "whatsapp:messages", Payload: []byte("{}"), CreatedAt: time.Now().Add(-2 * time.Hour)}))
+	require.NoError(t, app.store.EnqueueOutbox(store.OutboxItem{Sink: SinkNotifier, Target: "whatsapp:messages", Payload: []byte("{}"), CreatedAt: time.Now()}))
+
+	app.replayOutbox(context.Background())
+	stats := app.OutboxStats()
+	assert.Equal(t, int64(1), stats.Expired)
+	assert.Equal(t, map[string]int64{SinkNotifier: 1}, stats.Pending, "kept for a notifier that is configured again")
+}
+
+func TestOutboxDisabled(t *testing.T) {
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer hook.Close()
+
+	app, _ := newFakeApp(t)
+	app.SetOutboxMaxAge(0)
+	require.NoError(t, app.SetGreeter(GreeterConfig{WebhookURL: hook.URL}))
+
+	app.greet(context.Background(), greetingData{JID: "111@s.whatsapp.net"})
+	assert.Empty(t, app.OutboxStats().Pending)
+}
@@ -0,0 +1,182 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// SendPoll sends a poll to recipient, paced like SendMessage. selectable is
+// how many options a voter may pick, 0 meaning any number. Quiet hours do
+// not hold it back.
+func (a *App) SendPoll(ctx context.Context, recipient, question string, options []string, selectable int) string {
+	to, err := jid.ParseRecipient(recipient)
+	if err != nil {
+		return output.Error(err)
+	}
+	poll, err := newPoll(question, options, selectable)
+	if err != nil {
+		return output.Error(err)
+	}
+	key := newDedupKey(to, []byte(poll.Question), []byte(strings.Join(poll.Options, "\n")))
+	prev, dup, ok := a.dedup.check(key)
+	if !ok {
+		return output.Error(duplicateError(to, prev, time.Now()))
+	}
+	if err := a.client.Connect(ctx); err != nil {
+		a.dedup.forget(key, prev)
+		return output.Error(err)
+	}
+	id, err := a.sendPollAndStore(ctx, to, poll)
+	if err != nil {
+		a.dedup.forget(key, prev)
+		return output.Error(err)
+	}
+	data := map[string]interface{}{
+		"sent":             true,
+		"message_id":       id,
+		"recipient":        to,
+		"question":         poll.Question,
+		"options":          poll.Options,
+		"selectable_count": poll.SelectableCount,
+	}
+	if dup {
+		data["duplicate"] = true
+		data["duplicate_of"] = prev.UTC()
+	}
+	return output.Success(data)
+}
+
+// newPoll checks a poll before it is sent.
+func newPoll(question string, options []string, selectable int) (client.Poll, error) {
+	poll := client.Poll{Question: strings.TrimSpace(question), SelectableCount: selectable}
+	if poll.Question == "" {
+		return poll, fmt.Errorf("poll question is required")
+	}
+	seen := map[string]bool{}
+	for _, o := range options {
+		o = strings.TrimSpace(o)
+		if o == "" {
+			return poll, fmt.Errorf("poll options cannot be empty")
+		}
+		if seen[o] {
+			return poll, fmt.Errorf("poll option %q is listed twice", o)
+		}
+		seen[o] = true
+		poll.Options = append(poll.Options, o)
+	}
+	if len(poll.Options) < 2 || len(poll.Options) > client.MaxPollOptions {
+		return poll, fmt.Errorf("a poll needs between 2 and %d options, got %d", client.MaxPollOptions, len(poll.Options))
+	}
+	if selectable < 0 || selectable > len(poll.Options) {
+		return poll, fmt.Errorf("selectable count must be between 0 (any number) and %d, got %d", len(poll.Options), selectable)
+	}
+	return poll, nil
+}
+
+// sendPollAndStore sends a poll on an established connection and records
+// it in the store, so votes on it can be counted.
+func (a *App) sendPollAndStore(ctx context.Context, recipient string, poll client.Poll) (string, error) {
+	if err := a.waitForSlot(ctx); err != nil {
+		return "", err
+	}
+	id, err := a.client.SendPoll(ctx, recipient, poll)
+	if err != nil {
+		a.checkSendError(err)
+		return "", err
+	}
+	a.recordSent(ctx, recipient, id, poll.Question, nil)
+	chatJID := a.canonicalChatJID(ctx, jid.Normalize(recipient))
+	a.writer.Write(func() error {
+		return a.storePoll(id, chatJID, poll, time.Now(), nil)
+	})
+	return id, nil
+}
+
+// PollResults counts the votes of the poll sent as message id.
+func (a *App) PollResults(id string, chatJID *string) string {
+	results, err := a.store.GetPollResults(id, chatJID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return output.Error(fmt.Errorf("poll %s not found", id))
+	}
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(results)
+}
+
+// storePoll records a poll with the votes history sync delivered it with.
+func (a *App) storePoll(id, chatJID string, poll client.Poll, createdAt time.Time, votes []*waProto.PollUpdate) error {
+	if err := a.store.StorePoll(store.Poll{
+		MessageID:       id,
+		ChatJID:         chatJID,
+		Question:        poll.Question,
+		Options:         poll.Options,
+		SelectableCount: poll.SelectableCount,
+		CreatedAt:       createdAt,
+	}); err != nil {
+		return err
+	}
+	for _, v := range votes {
+		voter := v.GetPollUpdateMessageKey().GetParticipant()
+		if voter == "" {
+			voter = v.GetPollUpdateMessageKey().GetRemoteJID()
+		}
+		if v.GetPollUpdateMessageKey().GetFromMe() {
+			voter = a.client.OwnJID()
+		}
+		if parsed, err := types.ParseJID(voter); err == nil {
+			voter = parsed.User
+		}
+		if err := a.store.StorePollVote(store.PollVote{
+			PollID:       id,
+			ChatJID:      chatJID,
+			Voter:        voter,
+			OptionHashes: hexHashes(v.GetVote().GetSelectedOptions()),
+			VotedAt:      time.UnixMilli(v.GetSenderTimestampMS()),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordPollVote decrypts a vote received by the sync loop and stores it as
+// the voter's current one.
+func (a *App) recordPollVote(ctx context.Context, msg *events.Message, chatJID, voter string) {
+	vote, err := a.client.DecryptPollVote(ctx, msg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n⚠ Failed to read poll vote %s: %v\n", msg.Info.ID, err)
+		return
+	}
+	a.writer.Write(func() error {
+		return a.store.StorePollVote(store.PollVote{
+			PollID:       vote.PollID,
+			ChatJID:      chatJID,
+			Voter:        voter,
+			OptionHashes: vote.OptionHashes,
+			VotedAt:      msg.Info.Timestamp,
+		})
+	})
+}
+
+func hexHashes(hashes [][]byte) []string {
+	out := make([]string, 0, len(hashes))
+	for _, h := range hashes {
+		out = append(out, hex.EncodeToString(h))
+	}
+	return out
+}
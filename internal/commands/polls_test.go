@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/client/fakeclient"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func pollResults(t *testing.T, app *App, id string) store.PollResults {
+	t.Helper()
+	var resp struct {
+		Success bool              `json:"success"`
+		Data    store.PollResults `json:"data"`
+	}
+	result := app.PollResults(id, nil)
+	require.NoError(t, json.Unmarshal([]byte(result), &resp))
+	require.True(t, resp.Success, result)
+	return resp.Data
+}
+
+func TestSendPollCountsVotes(t *testing.T) {
+	app, fake := newFakeApp(t)
+	startSync(t, app, fake)
+	group := types.NewJID("120363000000000001", types.GroupServer)
+
+	result := app.SendPoll(context.Background(), group.String(), " Lunch? ", []string{"Pizza", " Sushi"}, 1)
+	assert.Contains(t, result, `"options":["Pizza","Sushi"]`)
+	sent := fake.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, &client.Poll{Question: "Lunch?", Options: []string{"Pizza", "Sushi"}, SelectableCount: 1}, sent[0].Poll)
+
+	alice := types.NewJID("111", types.DefaultUserServer)
+	bob := types.NewJID("222", types.DefaultUserServer)
+	now := time.Now()
+	fake.Emit(fakeclient.PollVoteMessage(group, alice, "V1", sent[0].ID, []string{"Pizza"}, now))
+	fake.Emit(fakeclient.PollVoteMessage(group, bob, "V2", sent[0].ID, []string{"Pizza"}, now))
+	fake.Emit(fakeclient.PollVoteMessage(group, bob, "V3", sent[0].ID, []string{"Sushi"}, now.Add(time.Second)))
+
+	r := pollResults(t, app, sent[0].ID)
+	assert.Equal(t, "Lunch?", r.Question)
+	assert.Equal(t, 1, r.SelectableCount)
+	assert.Equal(t, 2, r.Voters)
+	assert.Equal(t, []store.PollOptionResults{
+		{Name: "Pizza", Votes: 1, Voters: []string{"111"}},
+		{Name: "Sushi", Votes: 1, Voters: []string{"222"}},
+	}, r.Options)
+
+	// Votes are not stored as messages
+	messages, err := app.store.ListMessages(store.ListMessagesParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "Lunch?", messages[0].Content)
+}
+
+func TestIncomingPollIsStored(t *testing.T) {
+	app, fake := newFakeApp(t)
+	startSync(t, app, fake)
+	alice := types.NewJID("111", types.DefaultUserServer)
+
+	fake.Emit(fakeclient.PollMessage(alice, alice, "P1", client.Poll{Question: "Coffee?", Options: []string{"Yes", "No"}}, time.Now(), false))
+	fake.Emit(fakeclient.PollVoteMessage(alice, fakeclient.OwnJID, "V1", "P1", []string{"Yes"}, time.Now()))
+
+	r := pollResults(t, app, "P1")
+	assert.Equal(t, "Coffee?", r.Question)
+	assert.Equal(t, []string{fakeclient.OwnJID.User}, r.Options[0].Voters)
+	assert.Contains(t, app.PollResults("P2", nil), "poll P2 not found")
+}
+
+func TestSendPollValidates(t *testing.T) {
+	app, fake := newFakeApp(t)
+	ctx := context.Background()
+	for _, tc := range []struct {
+		question   string
+		options    []string
+		selectable int
+		err        string
+	}{
+		{"", []string{"a", "b"}, 0, "poll question is required"},
+		{"Q", []string{"a"}, 0, "between 2 and 12 options, got 1"},
+		{"Q", []string{"a", " a "}, 0, `poll option \"a\" is listed twice`},
+		{"Q", []string{"a", ""}, 0, "poll options cannot be empty"},
+		{"Q", []string{"a", "b"}, 3, "selectable count must be between 0 (any number) and 2"},
+	} {
+		assert.Contains(t, app.SendPoll(ctx, "1234567890", tc.question, tc.options, tc.selectable), tc.err)
+	}
+	assert.Empty(t, fake.Sent())
+}
@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+)
+
+// SendTyping shows the "typing…" indicator in a chat, or clears it. WhatsApp
+// clears it by itself after about 25 seconds, and when a message is sent.
+func (a *App) SendTyping(ctx context.Context, chatJID string, typing bool) string {
+	to, err := jid.ParseRecipient(chatJID)
+	if err != nil {
+		return output.Error(err)
+	}
+	if err := a.client.Connect(ctx); err != nil {
+		return output.Error(err)
+	}
+	if err := a.client.SendTyping(ctx, to, typing); err != nil {
+		return output.Error(err)
+	}
+	state := "paused"
+	if typing {
+		state = "composing"
+	}
+	return output.Success(map[string]interface{}{"chat_jid": to, "state": state})
+}
+
+// SendPresence shows the account as online ("available") to its contacts,
+// or as offline. While it is available, the phone gets no notifications.
+func (a *App) SendPresence(ctx context.Context, available bool) string {
+	if err := a.client.Connect(ctx); err != nil {
+		return output.Error(err)
+	}
+	if err := a.client.SendPresence(ctx, available); err != nil {
+		return output.Error(err)
+	}
+	state := "unavailable"
+	if available {
+		state = "available"
+	}
+	return output.Success(map[string]interface{}{"state": state})
+}
@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendTypingAndPresence(t *testing.T) {
+	app, fake := newFakeApp(t)
+
+	result := app.SendTyping(context.Background(), "+34 600 111 222", true)
+	assert.Contains(t, result, `"state":"composing"`)
+	result = app.SendTyping(context.Background(), testGroupJID, false)
+	assert.Contains(t, result, `"state":"paused"`)
+	typing := fake.Typing()
+	require.Len(t, typing, 2)
+	assert.Equal(t, "34600111222@s.whatsapp.net", typing[0].Recipient)
+	assert.True(t, typing[0].Typing)
+	assert.Equal(t, testGroupJID, typing[1].Recipient)
+	assert.False(t, typing[1].Typing)
+
+	assert.Contains(t, app.SendTyping(context.Background(), "abc", true), `"success":false`)
+
+	assert.Contains(t, app.SendPresence(context.Background(), true), `"state":"available"`)
+	assert.Contains(t, app.SendPresence(context.Background(), false), `"state":"unavailable"`)
+	assert.Equal(t, []bool{true, false}, fake.Presence())
+}
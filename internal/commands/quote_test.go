@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+)
+
+func TestSendMessageQuotesMessage(t *testing.T) {
+	app, fake := newFakeApp(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	chat := "1234567890@s.whatsapp.net"
+	require.NoError(t, app.store.StoreChat(chat, "Alice", now))
+	require.NoError(t, app.store.StoreMessage("q1", chat, "1234567890", "are you coming?", now, false, "", "", "", "", "", nil, nil, nil, 0))
+
+	result := app.SendMessage(ctx, "1234567890", "yes", client.SendOptions{QuotedMessageID: "q1"})
+	assert.Contains(t, result, `"quoted_message_id":"q1"`)
+	sent := fake.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, &client.Quote{MessageID: "q1", Sender: chat, Content: "are you coming?"}, sent[0].Quote)
+
+	result = app.SendMessage(ctx, "1234567890", "yes", client.SendOptions{QuotedMessageID: "missing"})
+	assert.Contains(t, result, "quoted message missing not found")
+	assert.Len(t, fake.Sent(), 1)
+}
+
+func TestSendMessageQuotesOwnSentMessage(t *testing.T) {
+	app, fake := newFakeApp(t)
+	ctx := context.Background()
+
+	app.SendMessage(ctx, "1234567890", "are you coming?", client.SendOptions{})
+	sent := fake.Sent()
+	require.Len(t, sent, 1)
+	id := sent[0].ID
+
+	result := app.SendMessage(ctx, "1234567890", "I mean tonight", client.SendOptions{QuotedMessageID: id})
+	assert.Contains(t, result, `"quoted_message_id":"`+id+`"`)
+	sent = fake.Sent()
+	require.Len(t, sent, 2)
+	assert.Equal(t, &client.Quote{MessageID: id, Sender: app.client.OwnJID(), Content: "are you coming?"}, sent[1].Quote)
+}
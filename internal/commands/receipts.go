@@ -0,0 +1,182 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// maxReadBatch bounds the unread messages MarkChatRead sends receipts for;
+// reading the newest ones marks the chat read on the phone.
+const maxReadBatch = 100
+
+// ReceiptPolicy selects the receipts sent for incoming messages.
+type ReceiptPolicy struct {
+	// Delivery shows senders their messages as delivered once the daemon
+	// has them. Without it receipts are sent as "inactive", which WhatsApp
+	// does not render, so delivery is only shown once the phone has them.
+	Delivery bool
+	// Read marks incoming messages as read as soon as they are archived.
+	// Read state syncs to the phone, so chats no longer show as unread there.
+	Read bool
+}
+
+// DefaultReceiptPolicy keeps the inactive receipts earlier versions sent, so
+// a sender only sees a message delivered once the phone has it, and leaves
+// marking messages read to the user.
+var DefaultReceiptPolicy = ReceiptPolicy{}
+
+// SetReceiptPolicy sets the receipts sent for incoming messages.
+func (a *App) SetReceiptPolicy(p ReceiptPolicy) {
+	a.receipts = p
+	a.client.SetActiveDeliveryReceipts(p.Delivery)
+}
+
+// ReceiptPolicy returns the receipts sent for incoming messages.
+func (a *App) ReceiptPolicy() ReceiptPolicy {
+	return a.receipts
+}
+
+// markRead sends a read receipt for an incoming live message when the policy
+// asks for it.
+func (a *App) markRead(ctx context.Context, msg *events.Message) {
+	if !a.receipts.Read || msg.Info.IsFromMe || msg.Info.Chat.Server == types.BroadcastServer {
+		return
+	}
+	if err := a.client.MarkRead(ctx, msg.Info.Chat.String(), msg.Info.Sender.String(), []string{msg.Info.ID}, msg.Info.Timestamp); err != nil {
+		fmt.Fprintf(os.Stderr, "\n⚠ Failed to mark %s as read: %v\n", msg.Info.ID, err)
+		return
+	}
+	chatJID, ids := msg.Info.Chat.String(), []string{msg.Info.ID}
+	a.writer.Write(func() error {
+		return a.store.MarkMessagesRead(chatJID, ids, time.Now())
+	})
+}
+
+// storedReceiptTypes maps the receipts recipients send for messages of the
+// account to the type they are stored as.
+var storedReceiptTypes = map[types.ReceiptType]string{
+	types.ReceiptTypeDelivered: store.ReceiptDelivered,
+	types.ReceiptTypeRead:      store.ReceiptRead,
+	types.ReceiptTypePlayed:    store.ReceiptPlayed,
+}
+
+// recordReceipt stores a receipt for messages of the account, so their
+// status shows whether they were delivered and read. Chats and recipients
+// addressed by LID are recorded under their phone JID, like messages.
+func (a *App) recordReceipt(ctx context.Context, evt *events.Receipt) {
+	receiptType, ok := storedReceiptTypes[evt.Type]
+	if !ok || evt.IsFromMe {
+		return
+	}
+	chatJID := a.canonicalChatJID(ctx, evt.Chat.String())
+	recipient := jid.Normalize(a.client.PhoneJID(ctx, evt.Sender.ToNonAD().String()))
+	ids, at := append([]string(nil), evt.MessageIDs...), evt.Timestamp
+	a.writer.Write(func() error {
+		return a.store.RecordReceipt(chatJID, recipient, ids, receiptType, at)
+	})
+}
+
+// MessageStatus reports whether a message of the account was delivered,
+// read or played, and by whom.
+func (a *App) MessageStatus(messageID string, chatJID *string) string {
+	chat, err := a.messageChat(messageID, chatJID)
+	if err != nil {
+		return output.Error(err)
+	}
+	st, err := a.store.MessageStatus(messageID, chat)
+	if err != nil {
+		return output.Error(err)
+	}
+	if st.Status == "" {
+		return output.Error(fmt.Errorf("message %s was not sent by this account", messageID))
+	}
+	return output.Success(st)
+}
+
+// recordReadSelf keeps the read state of a chat in step with the messages
+// the account read on its other devices.
+func (a *App) recordReadSelf(ctx context.Context, evt *events.Receipt) {
+	if evt.Type != types.ReceiptTypeReadSelf {
+		return
+	}
+	chatJID, ids := a.canonicalChatJID(ctx, evt.Chat.String()), append([]string(nil), evt.MessageIDs...)
+	a.writer.Write(func() error {
+		return a.store.MarkMessagesRead(chatJID, ids, time.Now())
+	})
+}
+
+// MarkChatRead sends read receipts for the unread messages of a chat, the
+// newest maxReadBatch of them, or for the messages ids, and records the
+// chat as read up to the newest of them. WhatsApp syncs the receipts to the
+// phone, which clears the chat's unread badge.
+func (a *App) MarkChatRead(ctx context.Context, chatJID string, ids []string) string {
+	chatJID = jid.Normalize(chatJID)
+	var refs []store.ReadRef
+	var err error
+	if len(ids) > 0 {
+		refs, err = a.store.IncomingMessages(chatJID, ids)
+		if err == nil && len(refs) == 0 {
+			err = fmt.Errorf("no incoming messages of chat %s among %s", chatJID, strings.Join(ids, ", "))
+		}
+	} else {
+		refs, err = a.store.UnreadMessages(chatJID, maxReadBatch)
+	}
+	if err != nil {
+		return output.Error(err)
+	}
+
+	if len(refs) > 0 {
+		if err := a.client.Connect(ctx); err != nil {
+			return output.Error(err)
+		}
+		// Group receipts name the sender of the messages they acknowledge
+		bySender := map[string][]string{}
+		var senders []string
+		for _, r := range refs {
+			sender := ""
+			if strings.HasSuffix(chatJID, "@"+types.GroupServer) {
+				sender = jid.Normalize(r.Sender)
+			}
+			if _, ok := bySender[sender]; !ok {
+				senders = append(senders, sender)
+			}
+			bySender[sender] = append(bySender[sender], r.ID)
+		}
+		now := time.Now()
+		for _, sender := range senders {
+			if err := a.client.MarkRead(ctx, chatJID, sender, bySender[sender], now); err != nil {
+				return output.Error(err)
+			}
+		}
+		if err := a.store.MarkChatRead(chatJID, refs[0].Timestamp, now); err != nil {
+			return output.Error(err)
+		}
+	}
+	state, err := a.store.ChatReadState(chatJID)
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(struct {
+		store.ChatRead
+		Marked int `json:"marked"`
+	}{state, len(refs)})
+}
+
+// ChatReadState reports how far a chat was read and how many of its
+// messages are unread.
+func (a *App) ChatReadState(chatJID string) string {
+	state, err := a.store.ChatReadState(jid.Normalize(chatJID))
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(state)
+}
@@ -0,0 +1,182 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/client/fakeclient"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestReceiptPolicyDefaults(t *testing.T) {
+	app, fake := newFakeApp(t)
+	assert.Equal(t, DefaultReceiptPolicy, app.ReceiptPolicy())
+	assert.False(t, fake.ActiveDeliveryReceipts())
+
+	app.SetReceiptPolicy(ReceiptPolicy{Delivery: true})
+	assert.True(t, fake.ActiveDeliveryReceipts())
+}
+
+func TestSyncMarksIncomingMessagesRead(t *testing.T) {
+	app, fake := newFakeApp(t)
+	app.SetReceiptPolicy(ReceiptPolicy{Delivery: true, Read: true})
+	startSync(t, app, fake)
+
+	alice := types.NewJID("111", types.DefaultUserServer)
+	ts := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	fake.EmitText(alice, alice, "M1", "hello", ts)
+	fake.Emit(fakeclient.TextMessage(alice, fakeclient.OwnJID, "M2", "hi", ts, true))
+
+	require.Eventually(t, func() bool { return len(fake.Reads()) == 1 }, time.Second, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	reads := fake.Reads()
+	require.Len(t, reads, 1, "own messages are not marked read")
+	assert.Equal(t, fakeclient.ReadReceipt{ChatJID: alice.String(), Sender: alice.String(), IDs: []string{"M1"}, Timestamp: ts}, reads[0])
+}
+
+func TestSyncLeavesMessagesUnreadByDefault(t *testing.T) {
+	app, fake := newFakeApp(t)
+	startSync(t, app, fake)
+
+	alice := types.NewJID("111", types.DefaultUserServer)
+	fake.EmitText(alice, alice, "M1", "hello", time.Now())
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, fake.Reads())
+}
+
+func TestMarkChatRead(t *testing.T) {
+	app, fake := newFakeApp(t)
+	ts := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, app.store.StoreChat(testGroupJID, "Team", ts))
+	require.NoError(t, app.store.StoreMessage("G1", testGroupJID, "111", "hi", ts, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, app.store.StoreMessage("G2", testGroupJID, "222", "hey", ts.Add(time.Minute), false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, app.store.StoreMessage("G3", testGroupJID, "111", "there?", ts.Add(2*time.Minute), false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, app.store.StoreMessage("G4", testGroupJID, "", "mine", ts.Add(3*time.Minute), true, "", "", "", "", "", nil, nil, nil, 0))
+
+	assert.Contains(t, app.ChatReadState(testGroupJID), `"unread":3`)
+
+	result := app.MarkChatRead(context.Background(), testGroupJID, []string{"G1"})
+	assert.Contains(t, result, `"marked":1`)
+	assert.Contains(t, result, `"unread":2`)
+
+	result = app.MarkChatRead(context.Background(), testGroupJID, nil)
+	assert.Contains(t, result, `"marked":2`)
+	assert.Contains(t, result, `"unread":0`)
+	assert.Contains(t, result, `"read_until":"2026-03-01T12:02:00Z"`)
+
+	reads := fake.Reads()
+	require.Len(t, reads, 3)
+	assert.Equal(t, []string{"G1"}, reads[0].IDs)
+	assert.Equal(t, "111@s.whatsapp.net", reads[0].Sender)
+	assert.Equal(t, []string{"G3"}, reads[1].IDs, "one receipt per sender, newest first")
+	assert.Equal(t, "111@s.whatsapp.net", reads[1].Sender)
+	assert.Equal(t, []string{"G2"}, reads[2].IDs)
+	assert.Equal(t, "222@s.whatsapp.net", reads[2].Sender)
+
+	// Nothing left to read
+	assert.Contains(t, app.MarkChatRead(context.Background(), testGroupJID, nil), `"marked":0`)
+	assert.Len(t, fake.Reads(), 3)
+
+	assert.Contains(t, app.MarkChatRead(context.Background(), testGroupJID, []string{"G4"}), `"success":false`)
+}
+
+func TestSyncRecordsMessagesReadElsewhere(t *testing.T) {
+	app, fake := newFakeApp(t)
+	startSync(t, app, fake)
+
+	alice := types.NewJID("111", types.DefaultUserServer)
+	ts := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	fake.EmitText(alice, alice, "M1", "hello", ts)
+	fake.EmitText(alice, alice, "M2", "anyone?", ts.Add(time.Minute))
+	require.Eventually(t, func() bool {
+		state, err := app.store.ChatReadState(alice.String())
+		return err == nil && state.Unread == 2
+	}, time.Second, 5*time.Millisecond)
+
+	fake.Emit(&events.Receipt{
+		MessageSource: types.MessageSource{Chat: alice, Sender: fakeclient.OwnJID, IsFromMe: true},
+		MessageIDs:    []string{"M1"},
+		Type:          types.ReceiptTypeReadSelf,
+		Timestamp:     time.Now(),
+	})
+	require.Eventually(t, func() bool {
+		state, err := app.store.ChatReadState(alice.String())
+		return err == nil && state.Unread == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestSyncRecordsReceiptsOfSentMessages(t *testing.T) {
+	app, fake := newFakeApp(t)
+	startSync(t, app, fake)
+
+	alice := types.NewJID("34600111222", types.DefaultUserServer)
+	ts := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	fake.Emit(fakeclient.TextMessage(alice, fakeclient.OwnJID, "OUT1", "hi", ts, true))
+	fake.EmitText(alice, alice, "IN1", "hello", ts.Add(time.Minute))
+	require.Eventually(t, func() bool {
+		return strings.Contains(app.MessageStatus("IN1", nil), "not sent by this account")
+	}, time.Second, 5*time.Millisecond)
+	assert.Contains(t, app.MessageStatus("OUT1", nil), `"status":"sent"`)
+
+	device := alice
+	device.Device = 3
+	fake.Emit(&events.Receipt{
+		MessageSource: types.MessageSource{Chat: alice, Sender: device},
+		MessageIDs:    []string{"OUT1"},
+		Type:          types.ReceiptTypeDelivered,
+		Timestamp:     ts.Add(2 * time.Minute),
+	})
+	fake.Emit(&events.Receipt{
+		MessageSource: types.MessageSource{Chat: alice, Sender: alice},
+		MessageIDs:    []string{"OUT1"},
+		Type:          types.ReceiptTypeRead,
+		Timestamp:     ts.Add(3 * time.Minute),
+	})
+	require.Eventually(t, func() bool {
+		return strings.Contains(app.MessageStatus("OUT1", nil), `"status":"read"`)
+	}, time.Second, 5*time.Millisecond)
+	result := app.MessageStatus("OUT1", nil)
+	assert.Contains(t, result, `"delivered_at":"2026-03-01T12:02:00Z"`)
+	assert.Contains(t, result, `"recipient":"34600111222@s.whatsapp.net","type":"delivered"`)
+
+	assert.Contains(t, app.MessageStatus("missing", nil), "not found")
+}
+
+func TestSentMessageStatusFollowsReceipts(t *testing.T) {
+	app, fake := newFakeApp(t)
+	fake.SetDeliveryReceipts(true)
+	startSync(t, app, fake)
+
+	var sent struct {
+		Data struct {
+			MessageID string `json:"message_id"`
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(app.SendMessage(context.Background(), "34600111222", "hi", client.SendOptions{})), &sent))
+	require.NotEmpty(t, sent.Data.MessageID)
+	require.Eventually(t, func() bool {
+		return strings.Contains(app.MessageStatus(sent.Data.MessageID, nil), `"status":"delivered"`)
+	}, time.Second, 5*time.Millisecond)
+
+	// Receipts naming the chat by LID join the message stored under the
+	// phone JID
+	lid := types.NewJID("98765432109876", types.HiddenUserServer)
+	fake.MapLID(lid.String(), "34600111222@s.whatsapp.net")
+	fake.Emit(&events.Receipt{
+		MessageSource: types.MessageSource{Chat: lid, Sender: lid},
+		MessageIDs:    []string{sent.Data.MessageID},
+		Type:          types.ReceiptTypeRead,
+		Timestamp:     time.Now(),
+	})
+	require.Eventually(t, func() bool {
+		return strings.Contains(app.MessageStatus(sent.Data.MessageID, nil), `"status":"read"`)
+	}, time.Second, 5*time.Millisecond)
+	assert.Contains(t, app.MessageStatus(sent.Data.MessageID, nil), `"recipient":"34600111222@s.whatsapp.net","type":"read"`)
+}
@@ -0,0 +1,408 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// RecipesFile is the JSON file of automation recipes, e.g.
+//
+//	{"recipes": [{
+//	  "name": "invoices",
+//	  "when": {"chats": ["34600111222"], "keywords": ["invoice"]},
+//	  "do": [{"tag": "invoice"}, {"forward": "34600333444"}, {"reply": "Thanks {{.Name}}, got it!"}]
+//	}]}
+type RecipesFile struct {
+	Recipes []Recipe `json:"recipes"`
+}
+
+// Recipe runs its actions, in order, for every incoming message matching
+// all of its conditions.
+type Recipe struct {
+	Name string         `json:"name"`
+	When RecipeWhen     `json:"when"`
+	Do   []RecipeAction `json:"do"`
+}
+
+// RecipeWhen are the conditions of a recipe; empty ones match every message.
+type RecipeWhen struct {
+	// Chats are JIDs or phone numbers.
+	Chats []string `json:"chats,omitempty"`
+	// Keywords match messages containing any of them, ignoring case.
+	Keywords []string `json:"keywords,omitempty"`
+	// Time lists the windows the message must arrive in, in Timezone
+	// (default UTC).
+	Time     []store.AwayWindow `json:"time,omitempty"`
+	Timezone string             `json:"timezone,omitempty"`
+}
+
+// RecipeAction is one action; exactly one of Reply, Forward, Webhook and
+// Tag is set. Reply and Text are text/templates executed with recipeData.
+type RecipeAction struct {
+	// Reply answers in the chat of the message.
+	Reply string `json:"reply,omitempty"`
+	// Forward sends the message text to a JID or phone number, formatted
+	// with Text if set.
+	Forward string `json:"forward,omitempty"`
+	Text    string `json:"text,omitempty"`
+	// Webhook receives the message as a JSON POST.
+	Webhook string `json:"webhook,omitempty"`
+	// Tag labels the message, see GET /tags.
+	Tag string `json:"tag,omitempty"`
+}
+
+// defaultForwardText is how forwarded messages are formatted without Text.
+const defaultForwardText = "{{.ChatName}}: {{.Text}}"
+
+// recipeData is the data available to reply and forward templates.
+type recipeData struct {
+	Recipe    string
+	MessageID string
+	ChatJID   string
+	ChatName  string
+	Sender    string // phone number of the sender
+	Name      string // push name, or the sender if it has none
+	Text      string
+}
+
+type recipe struct {
+	name     string
+	chats    map[string]bool
+	keywords []string
+	loc      *time.Location
+	windows  []awayWindow
+	actions  []recipeAction
+}
+
+type recipeAction struct {
+	kind   string // reply, forward, webhook or tag
+	tmpl   *template.Template
+	target string
+}
+
+// recipeBook is the loaded recipes file; it is replaced as a whole when the
+// file changes.
+type recipeBook struct {
+	path       string
+	modTime    time.Time
+	size       int64
+	recipes    []recipe
+	httpClient *http.Client
+}
+
+// parseRecipes validates a recipes file; recipes without a name are named
+// after their position.
+func parseRecipes(data []byte) ([]recipe, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var file RecipesFile
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid recipes file: %w", err)
+	}
+	recipes := make([]recipe, 0, len(file.Recipes))
+	names := make(map[string]bool)
+	for i, r := range file.Recipes {
+		name := strings.TrimSpace(r.Name)
+		if name == "" {
+			name = fmt.Sprintf("recipe %d", i+1)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("%s: duplicate name", name)
+		}
+		names[name] = true
+		parsed, err := parseRecipe(name, r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		recipes = append(recipes, parsed)
+	}
+	return recipes, nil
+}
+
+func parseRecipe(name string, r Recipe) (recipe, error) {
+	p := recipe{name: name}
+	if len(r.When.Chats) > 0 {
+		p.chats = make(map[string]bool, len(r.When.Chats))
+		for _, c := range r.When.Chats {
+			chat, err := jid.ParseRecipient(c)
+			if err != nil {
+				return recipe{}, err
+			}
+			p.chats[chat] = true
+		}
+	}
+	for _, k := range r.When.Keywords {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+			p.keywords = append(p.keywords, k)
+		}
+	}
+	loc, windows, err := validateAwayConfig(store.AwayConfig{Timezone: r.When.Timezone, Windows: r.When.Time})
+	if err != nil {
+		return recipe{}, err
+	}
+	p.loc, p.windows = loc, windows
+
+	if len(r.Do) == 0 {
+		return recipe{}, fmt.Errorf("at least one action is required in \"do\"")
+	}
+	for i, a := range r.Do {
+		action, err := parseRecipeAction(a)
+		if err != nil {
+			return recipe{}, fmt.Errorf("action %d: %w", i+1, err)
+		}
+		p.actions = append(p.actions, action)
+	}
+	return p, nil
+}
+
+func parseRecipeAction(a RecipeAction) (recipeAction, error) {
+	set := 0
+	for _, v := range []string{a.Reply, a.Forward, a.Webhook, a.Tag} {
+		if strings.TrimSpace(v) != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return recipeAction{}, fmt.Errorf("set exactly one of reply, forward, webhook and tag")
+	}
+	if a.Text != "" && a.Forward == "" {
+		return recipeAction{}, fmt.Errorf("text is only used with forward")
+	}
+
+	switch {
+	case a.Reply != "":
+		tmpl, err := parseRecipeTemplate(a.Reply)
+		if err != nil {
+			return recipeAction{}, err
+		}
+		return recipeAction{kind: "reply", tmpl: tmpl}, nil
+	case a.Forward != "":
+		target, err := jid.ParseRecipient(a.Forward)
+		if err != nil {
+			return recipeAction{}, err
+		}
+		text := a.Text
+		if text == "" {
+			text = defaultForwardText
+		}
+		tmpl, err := parseRecipeTemplate(text)
+		if err != nil {
+			return recipeAction{}, err
+		}
+		return recipeAction{kind: "forward", tmpl: tmpl, target: target}, nil
+	case a.Webhook != "":
+		u, err := url.Parse(a.Webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return recipeAction{}, fmt.Errorf("invalid webhook URL %q", a.Webhook)
+		}
+		return recipeAction{kind: "webhook", target: a.Webhook}, nil
+	default:
+		return recipeAction{kind: "tag", target: strings.ToLower(strings.TrimSpace(a.Tag))}, nil
+	}
+}
+
+func parseRecipeTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("recipe").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// matches reports whether an incoming message meets every condition.
+func (r *recipe) matches(chatJID, content string, at time.Time) bool {
+	if r.chats != nil && !r.chats[chatJID] {
+		return false
+	}
+	if len(r.keywords) > 0 {
+		lower := strings.ToLower(content)
+		found := false
+		for _, k := range r.keywords {
+			if strings.Contains(lower, k) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(r.windows) > 0 {
+		if _, ok := activeAwayWindow(r.windows, r.loc, at); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// SetRecipes loads the recipes in path; Sync reloads the file whenever it
+// changes. An empty path disables recipes.
+func (a *App) SetRecipes(path string) error {
+	if path == "" {
+		a.recipes.Store(nil)
+		return nil
+	}
+	book, err := loadRecipeBook(path)
+	if err != nil {
+		return err
+	}
+	a.recipes.Store(book)
+	return nil
+}
+
+func loadRecipeBook(path string) (*recipeBook, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	recipes, err := parseRecipes(data)
+	if err != nil {
+		return nil, err
+	}
+	return &recipeBook{
+		path:       path,
+		modTime:    info.ModTime(),
+		size:       info.Size(),
+		recipes:    recipes,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// runRecipeReload reloads the recipes file when its size or modification
+// time changes. A file that fails to parse is reported and the recipes
+// loaded before stay in effect.
+func (a *App) runRecipeReload(ctx context.Context, interval time.Duration) {
+	if a.recipes.Load() == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var failed time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current := a.recipes.Load()
+		if current == nil {
+			// Recipes were disabled
+			return
+		}
+		info, err := os.Stat(current.path)
+		if err != nil || (info.ModTime().Equal(current.modTime) && info.Size() == current.size) || info.ModTime().Equal(failed) {
+			continue
+		}
+		book, err := loadRecipeBook(current.path)
+		if err != nil {
+			failed = info.ModTime()
+			fmt.Fprintf(os.Stderr, "\n⚠ Recipes not reloaded, keeping the previous ones: %v\n", err)
+			continue
+		}
+		a.recipes.Store(book)
+		fmt.Fprintf(os.Stderr, "\nℹ️  Reloaded %d recipes from %s\n", len(book.recipes), book.path)
+	}
+}
+
+// runRecipes runs the recipes matching an incoming message. Replies are
+// left out while a human has taken over the chat.
+func (a *App) runRecipes(ctx context.Context, data recipeData, at time.Time, automated bool) {
+	book := a.recipes.Load()
+	if book == nil {
+		return
+	}
+	for i := range book.recipes {
+		r := &book.recipes[i]
+		if !r.matches(data.ChatJID, data.Text, at) {
+			continue
+		}
+		data.Recipe = r.name
+		for _, action := range r.actions {
+			if action.kind == "reply" && !automated {
+				continue
+			}
+			if err := a.runRecipeAction(ctx, book, action, data, at); err != nil {
+				fmt.Fprintf(os.Stderr, "\n⚠ Recipe %s: %s failed: %v\n", r.name, action.kind, err)
+			}
+		}
+	}
+}
+
+func (a *App) runRecipeAction(ctx context.Context, book *recipeBook, action recipeAction, data recipeData, at time.Time) error {
+	switch action.kind {
+	case "reply", "forward":
+		var buf bytes.Buffer
+		if err := action.tmpl.Execute(&buf, data); err != nil {
+			return err
+		}
+		recipient := data.ChatJID
+		if action.kind == "forward" {
+			recipient = action.target
+		}
+		return a.sendAndStore(ctx, recipient, buf.String())
+	case "tag":
+		return a.store.TagMessage(data.MessageID, data.ChatJID, action.target, time.Now().UTC())
+	default:
+		body, _ := json.Marshal(map[string]interface{}{
+			"event":      "recipe",
+			"recipe":     data.Recipe,
+			"message_id": data.MessageID,
+			"chat_jid":   data.ChatJID,
+			"chat_name":  data.ChatName,
+			"sender":     data.Sender,
+			"name":       data.Name,
+			"text":       data.Text,
+			"timestamp":  at.UTC(),
+		})
+		err := a.postWebhook(ctx, book.httpClient, action.target, body)
+		if err == nil || a.outboxMaxAge <= 0 || isPermanent(err) {
+			return err
+		}
+		if qerr := a.enqueueOutbox(SinkRecipeWebhook, action.target, data.ChatJID, body); qerr != nil {
+			return fmt.Errorf("%w (not queued: %v)", err, qerr)
+		}
+		return fmt.Errorf("%w, queued for retry", err)
+	}
+}
+
+// ListTags lists the tags given to messages, by recipes or through the
+// API, most used first.
+func (a *App) ListTags(includeJIDs, excludeJIDs []string) string {
+	tags, err := a.store.Tags(includeJIDs, excludeJIDs)
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(tags)
+}
+
+// ListTaggedMessages returns the messages carrying tag, newest first.
+func (a *App) ListTaggedMessages(tag string, limit, page int, includeJIDs, excludeJIDs []string, after *time.Time) string {
+	messages, err := a.store.TaggedMessages(strings.ToLower(tag), store.ListMessagesParams{
+		HideSpamFrom: a.spam.QuarantineThreshold,
+		Limit:        limit,
+		Page:         page,
+		IncludeJIDs:  includeJIDs,
+		ExcludeJIDs:  excludeJIDs,
+		After:        after,
+	})
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(messages)
+}
@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client/fakeclient"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func writeRecipes(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestParseRecipesRejectsInvalidFiles(t *testing.T) {
+	for name, file := range map[string]string{
+		"unknown field":     `{"recipes":[{"name":"a","when":{"chat":["34600111222"]},"do":[{"tag":"x"}]}]}`,
+		"no actions":        `{"recipes":[{"name":"a","when":{}}]}`,
+		"two in one action": `{"recipes":[{"name":"a","do":[{"tag":"x","reply":"hi"}]}]}`,
+		"text without fwd":  `{"recipes":[{"name":"a","do":[{"reply":"hi","text":"x"}]}]}`,
+		"bad chat":          `{"recipes":[{"name":"a","when":{"chats":["12"]},"do":[{"tag":"x"}]}]}`,
+		"bad webhook":       `{"recipes":[{"name":"a","do":[{"webhook":"ftp://example.com"}]}]}`,
+		"bad template":      `{"recipes":[{"name":"a","do":[{"reply":"Hi {{.Name"}]}]}`,
+		"bad timezone":      `{"recipes":[{"name":"a","when":{"timezone":"Mars/Base"},"do":[{"tag":"x"}]}]}`,
+		"bad window":        `{"recipes":[{"name":"a","when":{"time":[{"days":["mon"],"start":"25:00","end":"26:00"}]},"do":[{"tag":"x"}]}]}`,
+		"duplicate names":   `{"recipes":[{"name":"a","do":[{"tag":"x"}]},{"name":"a","do":[{"tag":"y"}]}]}`,
+	} {
+		_, err := parseRecipes([]byte(file))
+		assert.Error(t, err, name)
+	}
+
+	recipes, err := parseRecipes([]byte(`{"recipes":[{"do":[{"forward":"34600333444"}]}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "recipe 1", recipes[0].name)
+	assert.Equal(t, "34600333444@s.whatsapp.net", recipes[0].actions[0].target)
+}
+
+func TestRecipeMatches(t *testing.T) {
+	recipes, err := parseRecipes([]byte(`{"recipes":[{
+		"name": "office",
+		"when": {
+			"chats": ["34600111222"],
+			"keywords": ["Invoice", "receipt"],
+			"time": [{"days": ["mon","tue","wed","thu","fri"], "start": "09:00", "end": "17:00"}],
+			"timezone": "Europe/Madrid"
+		},
+		"do": [{"tag": "invoice"}]
+	}]}`))
+	require.NoError(t, err)
+	r := &recipes[0]
+
+	chat := "34600111222@s.whatsapp.net"
+	// Monday 2024-01-08 10:00 in Madrid
+	open := time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC)
+	assert.True(t, r.matches(chat, "here is the INVOICE", open))
+	assert.True(t, r.matches(chat, "receipt attached", open))
+	assert.False(t, r.matches(chat, "hello", open), "no keyword")
+	assert.False(t, r.matches("34600999999@s.whatsapp.net", "invoice", open), "other chat")
+	assert.False(t, r.matches(chat, "invoice", open.Add(10*time.Hour)), "outside the window")
+	assert.False(t, r.matches(chat, "invoice", open.AddDate(0, 0, 5)), "on a Saturday")
+}
+
+func TestRecipesRunOnIncomingMessages(t *testing.T) {
+	var mu sync.Mutex
+	var hooks []map[string]any
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		hooks = append(hooks, body)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	path := filepath.Join(t.TempDir(), "recipes.json")
+	writeRecipes(t, path, `{"recipes":[{
+		"name": "invoices",
+		"when": {"keywords": ["invoice"]},
+		"do": [
+			{"tag": "Invoice"},
+			{"forward": "34600333444", "text": "{{.Name}} sent an invoice: {{.Text}}"},
+			{"webhook": "`+hook.URL+`"},
+			{"reply": "Thanks {{.Name}}, we got it."}
+		]
+	}]}`)
+
+	app, fake := newFakeApp(t)
+	require.NoError(t, app.SetRecipes(path))
+	startSync(t, app, fake)
+
+	alice := types.NewJID("34600111222", types.DefaultUserServer)
+	msg := fakeclient.TextMessage(alice, alice, "M1", "invoice for March", time.Now(), false)
+	msg.Info.PushName = "Alice"
+	fake.Emit(msg)
+
+	require.Eventually(t, func() bool { return len(fake.Sent()) == 2 }, time.Second, 5*time.Millisecond)
+	sent := fake.Sent()
+	assert.Equal(t, "34600333444@s.whatsapp.net", sent[0].Recipient)
+	assert.Equal(t, "Alice sent an invoice: invoice for March", sent[0].Message)
+	assert.Equal(t, alice.String(), sent[1].Recipient)
+	assert.Equal(t, "Thanks Alice, we got it.", sent[1].Message)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(hooks) == 1
+	}, time.Second, 5*time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, "recipe", hooks[0]["event"])
+	assert.Equal(t, "invoices", hooks[0]["recipe"])
+	assert.Equal(t, "M1", hooks[0]["message_id"])
+	mu.Unlock()
+
+	tagged, err := app.store.TaggedMessages("invoice", store.ListMessagesParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, tagged, 1)
+	assert.Equal(t, "M1", tagged[0].ID)
+
+	// Own messages and messages without the keyword run no recipe
+	fake.Emit(fakeclient.TextMessage(alice, alice, "M2", "invoice sent", time.Now(), true))
+	fake.EmitText(alice, alice, "M3", "hello", time.Now())
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, fake.Sent(), 2)
+}
+
+func TestRecipesSkipRepliesDuringHandoff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recipes.json")
+	writeRecipes(t, path, `{"recipes":[{"name":"ack","do":[{"reply":"ok"},{"tag":"seen"}]}]}`)
+
+	app, fake := newFakeApp(t)
+	require.NoError(t, app.SetRecipes(path))
+	alice := types.NewJID("34600111222", types.DefaultUserServer)
+	require.NoError(t, app.store.SetChatMode(alice.String(), store.ChatModeHuman, time.Now()))
+	startSync(t, app, fake)
+
+	fake.EmitText(alice, alice, "M1", "hi", time.Now())
+	require.Eventually(t, func() bool {
+		tags, err := app.store.Tags(nil, nil)
+		return err == nil && len(tags) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Empty(t, fake.Sent())
+}
+
+func TestRecipesReloadOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recipes.json")
+	writeRecipes(t, path, `{"recipes":[{"name":"one","do":[{"tag":"a"}]}]}`)
+
+	app, _ := newFakeApp(t)
+	require.NoError(t, app.SetRecipes(path))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go app.runRecipeReload(ctx, 5*time.Millisecond)
+
+	names := func() []string {
+		var names []string
+		for _, r := range app.recipes.Load().recipes {
+			names = append(names, r.name)
+		}
+		return names
+	}
+
+	writeRecipes(t, path, `{"recipes":[{"name":"one","do":[{"tag":"a"}]},{"name":"two","do":[{"tag":"b"}]}]}`)
+	require.Eventually(t, func() bool { return len(names()) == 2 }, time.Second, 5*time.Millisecond)
+
+	// A broken edit keeps the recipes loaded before
+	writeRecipes(t, path, `{"recipes":[{"name":"three"`)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, []string{"one", "two"}, names())
+
+	assert.ErrorContains(t, app.SetRecipes(filepath.Join(t.TempDir(), "missing.json")), "missing.json")
+	require.NoError(t, app.SetRecipes(""))
+	assert.Nil(t, app.recipes.Load())
+}
@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// SetReconcileChats makes sync reconcile the store with the chats the phone
+// knows of once it has caught up after connecting, so chats silent since
+// before the account was paired are listed too.
+func (a *App) SetReconcileChats(enabled bool) {
+	a.reconcileChats = enabled
+}
+
+// ReconcileChats creates the chats the phone knows of that the store does
+// not, and updates the names of the others.
+func (a *App) ReconcileChats(ctx context.Context) string {
+	if err := a.client.Connect(ctx); err != nil {
+		return output.Error(err)
+	}
+	result, err := a.reconcile(ctx)
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(result)
+}
+
+func (a *App) reconcile(ctx context.Context) (store.ChatReconciliation, error) {
+	known, err := a.client.KnownChats(ctx)
+	if err != nil {
+		return store.ChatReconciliation{}, err
+	}
+	names := make(map[string]string, len(known))
+	for _, c := range known {
+		names[c.JID] = c.Name
+	}
+	return a.store.ReconcileChats(names, time.Now().UTC())
+}
+
+// reconcileOnSync runs the reconciliation sync does after connecting.
+func (a *App) reconcileOnSync(ctx context.Context) {
+	result, err := a.reconcile(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠ Failed to reconcile chats: %v\n", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "📇 Reconciled %d chats from the phone: %d new, %d renamed\n", result.Known, result.Created, result.Renamed)
+}
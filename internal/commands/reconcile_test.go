@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+func TestReconcileChats(t *testing.T) {
+	app, fake := newFakeApp(t)
+	fake.AddGroup(client.GroupSettings{JID: testGroupJID, Subject: "Team"})
+	fake.SetChatName("34600111222@s.whatsapp.net", "Alice")
+
+	result := app.ReconcileChats(context.Background())
+	assert.Contains(t, result, `"known":2,"created":2,"renamed":0`)
+
+	chats, err := app.store.ListChats(store.ListChatsParams{Limit: 10})
+	require.NoError(t, err)
+	names := map[string]string{}
+	for _, c := range chats {
+		names[c.JID] = c.Name
+	}
+	assert.Equal(t, map[string]string{"34600111222@s.whatsapp.net": "Alice", testGroupJID: "Team"}, names)
+}
+
+func TestSyncReconcilesChatsWhenEnabled(t *testing.T) {
+	app, fake := newFakeApp(t)
+	fake.AddGroup(client.GroupSettings{JID: testGroupJID, Subject: "Team"})
+	app.SetReconcileChats(true)
+	startSync(t, app, fake)
+
+	assert.Eventually(t, func() bool {
+		exists, err := app.store.ChatExists(testGroupJID)
+		return err == nil && exists
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,318 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/ical"
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+	"github.com/vicentereig/whatsapp-cli/internal/netguard"
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// Reminder defaults and bounds. Leads are in minutes.
+const (
+	DefaultReminderLead      = 60
+	maxReminderLead          = 7 * 24 * 60
+	DefaultReminderTemplate  = "Reminder: {{.Summary}} on {{.Date}} at {{.Time}}"
+	reminderCalendarRefresh  = 5 * time.Minute
+	maxReminderCalendarBytes = 10 << 20
+)
+
+// reminderData is what reminder templates are executed with. Times are in
+// the reminder's timezone.
+type reminderData struct {
+	Reminder    string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	// Date and Time are the start formatted as "Monday 2 January" and
+	// "15:04".
+	Date string
+	Time string
+}
+
+// calendar is a fetched calendar, kept between checks.
+type calendar struct {
+	events    []ical.Event
+	fetchedAt time.Time
+}
+
+// reminderRunner fetches the calendars of the reminders.
+type reminderRunner struct {
+	httpClient *http.Client
+	calendars  map[string]*calendar
+}
+
+// ListReminders returns every reminder.
+func (a *App) ListReminders() string {
+	reminders, err := a.store.Reminders()
+	if err != nil {
+		return output.Error(err)
+	}
+	for i := range reminders {
+		reminders[i].CalendarURL = redactURL(reminders[i].CalendarURL)
+	}
+	return output.Success(reminders)
+}
+
+// GetReminder returns one reminder.
+func (a *App) GetReminder(id int64) string {
+	r, err := a.store.GetReminder(id)
+	if err == sql.ErrNoRows {
+		return output.Error(fmt.Errorf("reminder %d not found", id))
+	}
+	if err != nil {
+		return output.Error(err)
+	}
+	r.CalendarURL = redactURL(r.CalendarURL)
+	return output.Success(r)
+}
+
+// CreateReminder validates and saves a reminder. Its recipients are sent
+// the template LeadMinutes before each event of the calendar, while
+// syncing.
+func (a *App) CreateReminder(r store.Reminder) string {
+	if err := normalizeReminder(&r); err != nil {
+		return output.Error(err)
+	}
+	r.CreatedAt = time.Now().UTC()
+	id, err := a.store.CreateReminder(r)
+	if err != nil {
+		return output.Error(err)
+	}
+	return a.GetReminder(id)
+}
+
+// DeleteReminder removes a reminder.
+func (a *App) DeleteReminder(id int64) string {
+	err := a.store.DeleteReminder(id)
+	if err == sql.ErrNoRows {
+		return output.Error(fmt.Errorf("reminder %d not found", id))
+	}
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(map[string]any{"deleted": true, "id": id})
+}
+
+// normalizeReminder validates a reminder and fills in its defaults.
+func normalizeReminder(r *store.Reminder) error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	u, err := url.Parse(strings.TrimSpace(r.CalendarURL))
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("calendar_url must be an http(s) or webcal URL")
+	}
+	switch u.Scheme {
+	case "webcal":
+		u.Scheme = "https"
+	case "http", "https":
+	default:
+		return fmt.Errorf("calendar_url must be an http(s) or webcal URL")
+	}
+	r.CalendarURL = u.String()
+	if len(r.Recipients) == 0 {
+		return fmt.Errorf("at least one recipient is required")
+	}
+	for i, to := range r.Recipients {
+		if r.Recipients[i], err = jid.ParseRecipient(to); err != nil {
+			return err
+		}
+	}
+	if r.LeadMinutes == 0 {
+		r.LeadMinutes = DefaultReminderLead
+	}
+	if r.LeadMinutes < 0 || r.LeadMinutes > maxReminderLead {
+		return fmt.Errorf("lead_minutes must be between 1 and %d", maxReminderLead)
+	}
+	if r.Timezone == "" {
+		r.Timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q", r.Timezone)
+	}
+	if r.Template == "" {
+		r.Template = DefaultReminderTemplate
+	}
+	tmpl, err := parseRecipeTemplate(r.Template)
+	if err != nil {
+		return err
+	}
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, loc)
+	if err := tmpl.Execute(io.Discard, newReminderData(*r, ical.Event{Start: start, End: start})); err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	return nil
+}
+
+func newReminderData(r store.Reminder, e ical.Event) reminderData {
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	start := e.Start.In(loc)
+	return reminderData{
+		Reminder:    r.Name,
+		Summary:     e.Summary,
+		Description: e.Description,
+		Location:    e.Location,
+		Start:       start,
+		End:         e.End.In(loc),
+		AllDay:      e.AllDay,
+		Date:        start.Format("Monday 2 January"),
+		Time:        start.Format("15:04"),
+	}
+}
+
+// redactURL hides the password of a URL with credentials.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return u.Redacted()
+}
+
+// SetOutboundAllowedNetworks lets calendar feeds be fetched from the given
+// private networks; any other loopback, private or link-local address is
+// refused, since calendar URLs come from API clients.
+func (a *App) SetOutboundAllowedNetworks(nets []netip.Prefix) {
+	a.outboundAllowed = nets
+}
+
+func (a *App) newReminderRunner() *reminderRunner {
+	return &reminderRunner{
+		httpClient: netguard.Client(30*time.Second, a.outboundAllowed),
+		calendars:  make(map[string]*calendar),
+	}
+}
+
+// runReminders sends due reminders every interval until ctx is cancelled.
+func (a *App) runReminders(ctx context.Context, interval time.Duration) {
+	rr := a.newReminderRunner()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		a.sendReminders(ctx, rr, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendReminders sends the reminders of the events starting within the lead
+// of each reminder. Each occurrence of an event is reminded once: a send
+// that fails is reported and not retried. Events that started while the
+// daemon was down are not caught up.
+func (a *App) sendReminders(ctx context.Context, rr *reminderRunner, now time.Time) {
+	reminders, err := a.store.Reminders()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠ Failed to load reminders: %v\n", err)
+		return
+	}
+	used := make(map[string]bool)
+	for _, r := range reminders {
+		key := r.CalendarURL + " " + r.Timezone
+		used[key] = true
+		events, err := rr.events(ctx, key, r, now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠ Reminder %q: %v\n", r.Name, err)
+		}
+		if len(events) == 0 {
+			continue
+		}
+		tmpl, err := parseRecipeTemplate(r.Template)
+		if err != nil {
+			continue
+		}
+		for _, e := range ical.Between(events, now, now.Add(time.Duration(r.LeadMinutes)*time.Minute)) {
+			uid := e.UID
+			if uid == "" {
+				uid = e.Summary
+			}
+			claimed, err := a.store.ClaimReminderSend(r.ID, uid, e.Start, now.UTC())
+			if err != nil || !claimed {
+				continue
+			}
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, newReminderData(r, e)); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠ Reminder %q: %v\n", r.Name, err)
+				continue
+			}
+			for _, to := range r.Recipients {
+				if err := a.sendAndStore(ctx, to, buf.String()); err != nil {
+					fmt.Fprintf(os.Stderr, "⚠ Reminder %q to %s failed: %v\n", r.Name, to, err)
+				}
+			}
+		}
+	}
+	for key := range rr.calendars {
+		if !used[key] {
+			delete(rr.calendars, key)
+		}
+	}
+	if err := a.store.PruneReminderSends(now.Add(-24 * time.Hour)); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠ Failed to prune reminder sends: %v\n", err)
+	}
+}
+
+// events returns the events of a reminder's calendar, fetching it again
+// once it is older than reminderCalendarRefresh. If fetching fails, the
+// events fetched before are returned with the error.
+func (rr *reminderRunner) events(ctx context.Context, key string, r store.Reminder, now time.Time) ([]ical.Event, error) {
+	cal := rr.calendars[key]
+	if cal != nil && now.Sub(cal.fetchedAt) < reminderCalendarRefresh {
+		return cal.events, nil
+	}
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	events, err := rr.fetch(ctx, r.CalendarURL, loc)
+	if err != nil {
+		if cal == nil {
+			return nil, err
+		}
+		// Try again on the next check rather than every minute
+		cal.fetchedAt = now
+		return cal.events, err
+	}
+	rr.calendars[key] = &calendar{events: events, fetchedAt: now}
+	return events, nil
+}
+
+func (rr *reminderRunner) fetch(ctx context.Context, calendarURL string, loc *time.Location) ([]ical.Event, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, calendarURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/calendar")
+	resp, err := rr.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching calendar: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching calendar: %s", resp.Status)
+	}
+	return ical.Parse(io.LimitReader(resp.Body, maxReminderCalendarBytes), loc)
+}
@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+const reminderCalendar = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:cut-1\r\n" +
+	"SUMMARY:Haircut\r\n" +
+	"LOCATION:Main street 1\r\n" +
+	"DTSTART:20260310T090000Z\r\n" +
+	"DTEND:20260310T094500Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:cut-2\r\n" +
+	"SUMMARY:Colour\r\n" +
+	"DTSTART:20260310T150000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestReminders(t *testing.T) {
+	var fetches atomic.Int32
+	cal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		user, pass, _ := r.BasicAuth()
+		if user != "salon" || pass != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar")
+		w.Write([]byte(reminderCalendar))
+	}))
+	defer cal.Close()
+
+	app, fake := newFakeApp(t)
+	calURL := "http://salon:s3cret@" + cal.Listener.Addr().String() + "/cal.ics"
+	var created struct {
+		Success bool           `json:"success"`
+		Data    store.Reminder `json:"data"`
+		Error   string         `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(app.CreateReminder(store.Reminder{
+		Name:        "Salon",
+		CalendarURL: calURL,
+		Recipients:  []string{"34600111222"},
+		Template:    "{{.Summary}} today at {{.Time}}, {{.Location}}",
+		Timezone:    "Europe/Madrid",
+	})), &created))
+	require.True(t, created.Success, created.Error)
+	assert.Equal(t, 60, created.Data.LeadMinutes)
+	assert.Equal(t, []string{"34600111222@s.whatsapp.net"}, created.Data.Recipients)
+	assert.NotContains(t, created.Data.CalendarURL, "s3cret")
+
+	ctx := context.Background()
+	require.NoError(t, fake.Connect(ctx))
+
+	// Calendars on private addresses are only fetched when allowed
+	app.sendReminders(ctx, app.newReminderRunner(), time.Date(2026, 3, 10, 8, 15, 0, 0, time.UTC))
+	assert.Empty(t, fake.Sent())
+	assert.Zero(t, fetches.Load())
+	app.SetOutboundAllowedNetworks([]netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")})
+	rr := app.newReminderRunner()
+
+	// Too early for either event
+	app.sendReminders(ctx, rr, time.Date(2026, 3, 10, 7, 30, 0, 0, time.UTC))
+	assert.Empty(t, fake.Sent())
+
+	// Within the hour before the first one, which is only reminded once
+	app.sendReminders(ctx, rr, time.Date(2026, 3, 10, 8, 15, 0, 0, time.UTC))
+	app.sendReminders(ctx, rr, time.Date(2026, 3, 10, 8, 16, 0, 0, time.UTC))
+	require.Len(t, fake.Sent(), 1)
+	assert.Equal(t, "34600111222@s.whatsapp.net", fake.Sent()[0].Recipient)
+	assert.Equal(t, "Haircut today at 10:00, Main street 1", fake.Sent()[0].Message)
+	assert.EqualValues(t, 2, fetches.Load(), "calendar fetched again before the refresh interval")
+
+	// Events that already started are not reminded
+	app.sendReminders(ctx, rr, time.Date(2026, 3, 10, 15, 5, 0, 0, time.UTC))
+	assert.Len(t, fake.Sent(), 1)
+	assert.EqualValues(t, 3, fetches.Load())
+
+	assert.Contains(t, app.DeleteReminder(created.Data.ID), `"deleted":true`)
+	assert.Contains(t, app.DeleteReminder(created.Data.ID), "not found")
+	assert.Contains(t, app.GetReminder(created.Data.ID), "not found")
+}
+
+func TestCreateReminderValidates(t *testing.T) {
+	app, _ := newFakeApp(t)
+	valid := store.Reminder{Name: "r", CalendarURL: "webcal://cal.example.com/a.ics", Recipients: []string{"34600111222"}}
+	for name, mutate := range map[string]func(r *store.Reminder){
+		"name":       func(r *store.Reminder) { r.Name = " " },
+		"url":        func(r *store.Reminder) { r.CalendarURL = "ftp://cal.example.com/a.ics" },
+		"recipients": func(r *store.Reminder) { r.Recipients = nil },
+		"recipient":  func(r *store.Reminder) { r.Recipients = []string{"12"} },
+		"lead":       func(r *store.Reminder) { r.LeadMinutes = 20000 },
+		"timezone":   func(r *store.Reminder) { r.Timezone = "Mars/Olympus" },
+		"template":   func(r *store.Reminder) { r.Template = "{{.Nope}}" },
+	} {
+		r := valid
+		mutate(&r)
+		assert.Contains(t, app.CreateReminder(r), `"success":false`, name)
+	}
+
+	result := app.CreateReminder(valid)
+	assert.Contains(t, result, `"calendar_url":"https://cal.example.com/a.ics"`)
+	assert.Contains(t, result, `"template":"Reminder: {{.Summary}} on {{.Date}} at {{.Time}}"`)
+	assert.Contains(t, app.ListReminders(), `"name":"r"`)
+}
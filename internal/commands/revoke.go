@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+)
+
+// RevokeResult reports a message deleted for everyone.
+type RevokeResult struct {
+	ID        string    `json:"id"`
+	ChatJID   string    `json:"chat_jid"`
+	Revoked   bool      `json:"revoked"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// RevokeMessage deletes a message the account sent for everyone in the chat
+// and marks it deleted in the archive. Messages of other chat members and
+// chats outside the JID filters cannot be revoked.
+func (a *App) RevokeMessage(ctx context.Context, messageID string, chatJID *string, includeJIDs, excludeJIDs []string) string {
+	target, err := a.store.RevokeTarget(messageID, chatJID, includeJIDs, excludeJIDs)
+	if errors.Is(err, sql.ErrNoRows) {
+		return output.Error(fmt.Errorf("message %s not found", messageID))
+	}
+	if err != nil {
+		return output.Error(err)
+	}
+	if !target.IsFromMe {
+		return output.Error(fmt.Errorf("message %s was not sent by this account", messageID))
+	}
+	if target.DeletedAt != nil {
+		return output.Error(fmt.Errorf("message %s was already revoked at %s", messageID, target.DeletedAt.UTC().Format(time.RFC3339)))
+	}
+
+	if err := a.client.RevokeMessage(ctx, target.ChatJID, target.ID); err != nil {
+		return output.Error(fmt.Errorf("failed to revoke message %s: %w", messageID, err))
+	}
+	result := RevokeResult{ID: target.ID, ChatJID: target.ChatJID, Revoked: true, DeletedAt: time.Now().UTC()}
+	if err := a.store.MarkMessageDeleted(target.ID, target.ChatJID, result.DeletedAt); err != nil {
+		// The message is gone from WhatsApp either way
+		return output.Error(fmt.Errorf("message %s was revoked but not marked deleted: %w", messageID, err))
+	}
+	return output.Success(result)
+}
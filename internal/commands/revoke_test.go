@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/client/fakeclient"
+)
+
+func TestRevokeMessage(t *testing.T) {
+	app, fake := newFakeApp(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	chat := "34600111222@s.whatsapp.net"
+	require.NoError(t, app.store.StoreChat(chat, "Alice", now))
+	require.NoError(t, app.store.StoreMessage("mine", chat, "me", "wrong chat, sorry", now, true, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, app.store.StoreMessage("theirs", chat, "34600111222", "hi", now, false, "", "", "", "", "", nil, nil, nil, 0))
+
+	var res struct {
+		Success bool         `json:"success"`
+		Data    RevokeResult `json:"data"`
+		Error   string       `json:"error"`
+	}
+	// Nothing is marked deleted while WhatsApp cannot be reached
+	require.NoError(t, json.Unmarshal([]byte(app.RevokeMessage(ctx, "mine", nil, nil, nil)), &res))
+	assert.False(t, res.Success)
+	assert.Contains(t, res.Error, "failed to revoke")
+
+	require.NoError(t, fake.Connect(ctx))
+	require.NoError(t, json.Unmarshal([]byte(app.RevokeMessage(ctx, "mine", nil, nil, nil)), &res))
+	require.True(t, res.Success, res.Error)
+	assert.Equal(t, chat, res.Data.ChatJID)
+	assert.True(t, res.Data.Revoked)
+	assert.Equal(t, []fakeclient.Revoke{{ChatJID: chat, MessageID: "mine"}}, fake.Revokes())
+	assert.Contains(t, app.ListMessages(nil, nil, 10, 0, nil, nil, nil, nil, nil), `"deleted_at"`)
+
+	res.Error = ""
+	require.NoError(t, json.Unmarshal([]byte(app.RevokeMessage(ctx, "mine", nil, nil, nil)), &res))
+	assert.Contains(t, res.Error, "already revoked")
+	require.NoError(t, json.Unmarshal([]byte(app.RevokeMessage(ctx, "theirs", nil, nil, nil)), &res))
+	assert.Contains(t, res.Error, "not sent by this account")
+	require.NoError(t, json.Unmarshal([]byte(app.RevokeMessage(ctx, "mine", nil, nil, []string{"@s.whatsapp.net"})), &res))
+	assert.Contains(t, res.Error, "not found")
+	assert.Len(t, fake.Revokes(), 1)
+}
+
+func TestRevokeSentMessage(t *testing.T) {
+	app, fake := newFakeApp(t)
+	ctx := context.Background()
+	require.NoError(t, fake.Connect(ctx))
+
+	var sent struct {
+		Success bool
+		Data    struct {
+			MessageID string `json:"message_id"`
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(app.SendMessage(ctx, "34600111222", "wrong chat, sorry", client.SendOptions{})), &sent))
+	require.True(t, sent.Success)
+	require.Len(t, fake.Sent(), 1)
+	assert.Equal(t, fake.Sent()[0].ID, sent.Data.MessageID, "stored under the ID WhatsApp has")
+
+	assert.Contains(t, app.RevokeMessage(ctx, sent.Data.MessageID, nil, nil, nil), `"revoked":true`)
+	assert.Equal(t, []fakeclient.Revoke{{ChatJID: "34600111222@s.whatsapp.net", MessageID: sent.Data.MessageID}}, fake.Revokes())
+}
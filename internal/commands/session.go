@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/mediacrypt"
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+)
+
+// ExportSession writes the linked device session of storeDir to outputPath,
+// encrypted with key, so another host can take it over with ImportSession
+// without pairing again. The store is fenced afterwards: the device is
+// removed from it, and connecting from it fails until a session is imported
+// into it. It runs without an App, since
+// whatsapp.db must not be open.
+func ExportSession(ctx context.Context, storeDir, credentialBackend, outputPath string, key mediacrypt.KeyWrapper) string {
+	if outputPath == "" {
+		return output.Error(fmt.Errorf("--output is required"))
+	}
+	if key == nil {
+		return output.Error(fmt.Errorf("SESSION_KEY is required to encrypt the session"))
+	}
+	if _, err := os.Stat(outputPath); err == nil {
+		return output.Error(fmt.Errorf("%s already exists", outputPath))
+	}
+
+	// Write next to the target and rename, so a failed export never leaves a
+	// truncated session behind
+	f, err := os.CreateTemp(filepath.Dir(outputPath), ".session-*.tmp")
+	if err != nil {
+		return output.Error(fmt.Errorf("failed to create output file: %w", err))
+	}
+	defer os.Remove(f.Name())
+
+	pr, pw := io.Pipe()
+	var manifest client.SessionManifest
+	exported := make(chan error, 1)
+	go func() {
+		var err error
+		manifest, err = client.ExportSession(ctx, storeDir, credentialBackend, pw)
+		pw.CloseWithError(err)
+		exported <- err
+	}()
+	err = mediacrypt.Encrypt(ctx, key, f, pr)
+	pr.CloseWithError(err)
+	if exportErr := <-exported; err == nil {
+		err = exportErr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return output.Error(err)
+	}
+	if err := os.Chmod(f.Name(), 0600); err != nil {
+		return output.Error(err)
+	}
+	if err := os.Rename(f.Name(), outputPath); err != nil {
+		return output.Error(err)
+	}
+	if err := client.FenceSession(ctx, storeDir, credentialBackend, manifest, outputPath); err != nil {
+		return output.Error(fmt.Errorf("exported to %s but failed to fence %s, do not start it again: %w", outputPath, storeDir, err))
+	}
+	return output.Success(map[string]interface{}{
+		"output":  outputPath,
+		"session": manifest,
+		"fenced":  filepath.Join(storeDir, client.SessionFenceFile),
+	})
+}
+
+// ImportSession installs a session written by ExportSession into storeDir.
+// The host it was exported from must no longer use it; its store is fenced
+// by the export.
+func ImportSession(ctx context.Context, storeDir, inputPath string, key mediacrypt.KeyWrapper, force bool) string {
+	if inputPath == "" {
+		return output.Error(fmt.Errorf("--input is required"))
+	}
+	if key == nil {
+		return output.Error(fmt.Errorf("SESSION_KEY is required to decrypt the session"))
+	}
+	f, err := mediacrypt.Open(ctx, key, inputPath)
+	if err != nil {
+		return output.Error(err)
+	}
+	defer f.Close()
+
+	manifest, err := client.ImportSession(ctx, storeDir, f, force)
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(map[string]interface{}{
+		"input":   inputPath,
+		"session": manifest,
+	})
+}
@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+	"github.com/vicentereig/whatsapp-cli/internal/mediacrypt"
+	"go.mau.fi/whatsmeow/proto/waAdv"
+	"go.mau.fi/whatsmeow/store/sqlstore"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func pairSessionStore(t *testing.T, storeDir string) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on", filepath.Join(storeDir, "whatsapp.db")))
+	require.NoError(t, err)
+	defer db.Close()
+	container := sqlstore.NewWithDB(db, "sqlite3", nil)
+	require.NoError(t, container.Upgrade(context.Background()))
+	device := container.NewDevice()
+	jid := types.NewADJID("4915112345678", 0, 1)
+	device.ID = &jid
+	device.Account = &waAdv.ADVSignedDeviceIdentity{Details: []byte{1}, AccountSignature: make([]byte, 64), AccountSignatureKey: make([]byte, 32), DeviceSignature: make([]byte, 64)}
+	require.NoError(t, device.Save(context.Background()))
+}
+
+func TestSessionExportImportEncrypted(t *testing.T) {
+	ctx := context.Background()
+	from, to := t.TempDir(), t.TempDir()
+	pairSessionStore(t, from)
+	key, err := mediacrypt.ParseLocalKey(strings.Repeat("0f", 32))
+	require.NoError(t, err)
+	out := filepath.Join(t.TempDir(), "session.enc")
+
+	var exported struct {
+		Success bool
+		Error   string
+		Data    struct {
+			Session client.SessionManifest `json:"session"`
+			Fenced  string                 `json:"fenced"`
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(ExportSession(ctx, from, client.CredentialBackendFile, out, key)), &exported))
+	require.True(t, exported.Success, exported.Error)
+	assert.Equal(t, "4915112345678:1@s.whatsapp.net", exported.Data.Session.DeviceJID)
+	assert.FileExists(t, exported.Data.Fenced)
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro", filepath.Join(from, "whatsapp.db")))
+	require.NoError(t, err)
+	var devices int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM whatsmeow_device`).Scan(&devices))
+	db.Close()
+	assert.Zero(t, devices, "the exported device is removed from the old store")
+
+	encrypted, err := mediacrypt.IsEncrypted(out)
+	require.NoError(t, err)
+	assert.True(t, encrypted)
+	info, err := os.Stat(out)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Exporting again from the fenced store, or over the export, fails
+	result := ExportSession(ctx, from, client.CredentialBackendFile, filepath.Join(t.TempDir(), "again.enc"), key)
+	assert.Contains(t, result, "session was exported")
+	assert.Contains(t, ExportSession(ctx, from, client.CredentialBackendFile, out, key), "already exists")
+
+	other, err := mediacrypt.ParseLocalKey(strings.Repeat("f0", 32))
+	require.NoError(t, err)
+	assert.Contains(t, ImportSession(ctx, to, out, other, false), `"success":false`)
+	assert.NoFileExists(t, filepath.Join(to, "whatsapp.db"))
+
+	var imported struct {
+		Success bool
+		Error   string
+		Data    struct {
+			Session client.SessionManifest `json:"session"`
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(ImportSession(ctx, to, out, key, false)), &imported))
+	require.True(t, imported.Success, imported.Error)
+	assert.Equal(t, exported.Data.Session.ExportID, imported.Data.Session.ExportID)
+	assert.FileExists(t, filepath.Join(to, "whatsapp.db"))
+}
+
+func TestSessionExportRequiresKey(t *testing.T) {
+	result := ExportSession(context.Background(), t.TempDir(), client.CredentialBackendFile, filepath.Join(t.TempDir(), "session.enc"), nil)
+	assert.Contains(t, result, "SESSION_KEY is required")
+}
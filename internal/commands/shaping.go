@@ -0,0 +1,218 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// SendShaping paces outgoing messages so bulk sends look less automated.
+type SendShaping struct {
+	// MinDelay and MaxDelay bound the random gap kept between consecutive
+	// sends; zero MaxDelay sends back to back.
+	MinDelay time.Duration
+	MaxDelay time.Duration
+	// PerMinute caps the sends in any rolling minute; zero is unlimited.
+	PerMinute int
+	// QuietHours is an "HH:MM-HH:MM" window in Timezone during which
+	// messages sent through SendMessage are queued instead of delivered.
+	// Replies sent by the bot, greeting and away message are not held back.
+	QuietHours string
+	Timezone   string
+}
+
+// sendQueueBatch is the number of queued sends delivered per round.
+const sendQueueBatch = 50
+
+// sendShaper spaces sends according to a SendShaping.
+type sendShaper struct {
+	mu     sync.Mutex
+	cfg    SendShaping
+	quiet  *awayWindow
+	loc    *time.Location
+	last   time.Time
+	recent []time.Time // send times within the last minute
+	now    func() time.Time
+	jitter func(n int64) int64
+}
+
+// SetSendShaping configures delays, the per-minute cap and quiet hours for
+// outgoing messages.
+func (a *App) SetSendShaping(cfg SendShaping) error {
+	if cfg.MinDelay < 0 || (cfg.MaxDelay != 0 && cfg.MaxDelay < cfg.MinDelay) {
+		return fmt.Errorf("invalid send delay %s-%s", cfg.MinDelay, cfg.MaxDelay)
+	}
+	if cfg.MaxDelay == 0 {
+		cfg.MaxDelay = cfg.MinDelay
+	}
+	if cfg.PerMinute < 0 {
+		return fmt.Errorf("invalid sends per minute %d", cfg.PerMinute)
+	}
+	s := &sendShaper{cfg: cfg, loc: time.UTC, now: time.Now, jitter: rand.Int64N}
+	if cfg.QuietHours != "" {
+		start, end, ok := strings.Cut(cfg.QuietHours, "-")
+		if !ok {
+			return fmt.Errorf("invalid quiet hours %q (use HH:MM-HH:MM)", cfg.QuietHours)
+		}
+		w, err := parseAwayWindow(store.AwayWindow{Start: strings.TrimSpace(start), End: strings.TrimSpace(end)})
+		if err != nil {
+			return fmt.Errorf("invalid quiet hours %q: %w", cfg.QuietHours, err)
+		}
+		s.quiet = &w
+		if cfg.Timezone != "" {
+			if s.loc, err = time.LoadLocation(cfg.Timezone); err != nil {
+				return fmt.Errorf("invalid quiet hours timezone %q", cfg.Timezone)
+			}
+		}
+	}
+	a.shaper = s
+	return nil
+}
+
+// quietUntil returns the end of the current quiet hours, if it is quiet time.
+func (s *sendShaper) quietUntil() (time.Time, bool) {
+	if s == nil || s.quiet == nil {
+		return time.Time{}, false
+	}
+	start, ok := activeAwayWindow([]awayWindow{*s.quiet}, s.loc, s.now())
+	if !ok {
+		return time.Time{}, false
+	}
+	return start.Add(s.quiet.dur), true
+}
+
+// reserve books the next send slot and returns how long to wait for it.
+func (s *sendShaper) reserve() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	at := now
+	if !s.last.IsZero() && s.cfg.MaxDelay > 0 {
+		gap := s.cfg.MinDelay
+		if spread := s.cfg.MaxDelay - s.cfg.MinDelay; spread > 0 {
+			gap += time.Duration(s.jitter(int64(spread) + 1))
+		}
+		if next := s.last.Add(gap); next.After(at) {
+			at = next
+		}
+	}
+	if s.cfg.PerMinute > 0 {
+		s.prune(at)
+		if len(s.recent) >= s.cfg.PerMinute {
+			if next := s.recent[len(s.recent)-s.cfg.PerMinute].Add(time.Minute); next.After(at) {
+				at = next
+			}
+			s.prune(at)
+		}
+		s.recent = append(s.recent, at)
+	}
+	s.last = at
+	return at.Sub(now)
+}
+
+// prune forgets sends more than a minute before t.
+func (s *sendShaper) prune(t time.Time) {
+	i := 0
+	for i < len(s.recent) && !s.recent[i].After(t.Add(-time.Minute)) {
+		i++
+	}
+	s.recent = s.recent[i:]
+}
+
+// waitForSlot blocks until the shaping allows the next send.
+func (a *App) waitForSlot(ctx context.Context) error {
+	if a.shaper == nil {
+		return nil
+	}
+	if d := a.shaper.reserve(); d > 0 {
+		return a.sleep(ctx, d)
+	}
+	return nil
+}
+
+// queueDuringQuietHours stores a message sent during quiet hours for later
+// delivery. It returns the result data and false when it is not quiet time.
+func (a *App) queueDuringQuietHours(recipient, message string, opts sendOptions) (map[string]interface{}, bool, error) {
+	until, quiet := a.shaper.quietUntil()
+	if !quiet {
+		return nil, false, nil
+	}
+	q := store.QueuedSend{
+		Recipient:      recipient,
+		Message:        message,
+		SimulateTyping: opts.typing,
+		QueuedAt:       time.Now().UTC(),
+		Mentions:       opts.mentions,
+	}
+	if opts.quote != nil {
+		q.QuotedMessageID = opts.quote.MessageID
+	}
+	id, err := a.store.QueueSend(q)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to queue message: %w", err)
+	}
+	return map[string]interface{}{
+		"sent":          false,
+		"queued":        true,
+		"queue_id":      id,
+		"recipient":     recipient,
+		"message":       message,
+		"deliver_after": until.UTC(),
+	}, true, nil
+}
+
+// runSendQueue delivers messages queued during quiet hours once they are
+// over, checking every interval until ctx is cancelled.
+func (a *App) runSendQueue(ctx context.Context, interval time.Duration) {
+	if a.shaper == nil || a.shaper.quiet == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		a.flushSendQueue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// flushSendQueue sends queued messages in order outside quiet hours,
+// stopping at the first failure so it is retried on the next round.
+func (a *App) flushSendQueue(ctx context.Context) {
+	for {
+		queued, err := a.store.ListQueuedSends(sendQueueBatch)
+		if err != nil || len(queued) == 0 {
+			return
+		}
+		for _, q := range queued {
+			if _, quiet := a.shaper.quietUntil(); quiet || !a.client.IsConnected() {
+				return
+			}
+			opts := sendOptions{typing: q.SimulateTyping, mentions: q.Mentions}
+			if q.QuotedMessageID != "" {
+				quote, err := a.quoteFor(ctx, q.Recipient, q.QuotedMessageID)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "\n⚠ Sending queued message %d without its quote: %v\n", q.ID, err)
+				}
+				opts.quote = quote
+			}
+			if _, err := a.send(ctx, q.Recipient, q.Message, opts); err != nil {
+				fmt.Fprintf(os.Stderr, "\n⚠ Failed to send queued message %d to %s: %v\n", q.ID, q.Recipient, err)
+				return
+			}
+			if err := a.store.DeleteQueuedSend(q.ID); err != nil {
+				return
+			}
+		}
+	}
+}
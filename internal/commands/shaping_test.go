@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+)
+
+// fixedShaper points app's shaper at a controllable clock that advances by
+// every delay the app sleeps.
+func fixedShaper(t *testing.T, app *App, cfg SendShaping, now time.Time) *time.Time {
+	t.Helper()
+	require.NoError(t, app.SetSendShaping(cfg))
+	clock := now
+	app.shaper.now = func() time.Time { return clock }
+	app.shaper.jitter = func(n int64) int64 { return n - 1 }
+	app.sleep = func(_ context.Context, d time.Duration) error {
+		clock = clock.Add(d)
+		return nil
+	}
+	return &clock
+}
+
+func TestSendShaperSpacesSends(t *testing.T) {
+	app, _ := newFakeApp(t)
+	fixedShaper(t, app, SendShaping{MinDelay: 2 * time.Second, MaxDelay: 4 * time.Second}, time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC))
+
+	assert.Zero(t, app.shaper.reserve(), "first send goes right away")
+	assert.Equal(t, 4*time.Second, app.shaper.reserve())
+	assert.Equal(t, 8*time.Second, app.shaper.reserve(), "slots queue up behind each other")
+}
+
+func TestSendShaperCapsPerMinute(t *testing.T) {
+	app, fake := newFakeApp(t)
+	start := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	clock := fixedShaper(t, app, SendShaping{PerMinute: 2}, start)
+
+	for i := 0; i < 3; i++ {
+		app.SendMessage(context.Background(), "1234567890", "hi", client.SendOptions{})
+	}
+	assert.Len(t, fake.Sent(), 3)
+	assert.Equal(t, start.Add(time.Minute), *clock, "third send waits for the first to leave the window")
+}
+
+func TestQuietHoursQueueSends(t *testing.T) {
+	app, fake := newFakeApp(t)
+	night := time.Date(2026, 3, 2, 23, 30, 0, 0, time.UTC)
+	clock := fixedShaper(t, app, SendShaping{QuietHours: "22:00-07:00", Timezone: "UTC"}, night)
+	ctx := context.Background()
+
+	result := app.SendMessage(ctx, "1234567890", "good morning", client.SendOptions{})
+	assert.Contains(t, result, `"queued":true`)
+	assert.Contains(t, result, `"deliver_after":"2026-03-03T07:00:00Z"`)
+	assert.Empty(t, fake.Sent())
+
+	// Automated replies are not held back
+	require.NoError(t, fake.Connect(ctx))
+	require.NoError(t, app.sendAndStore(ctx, "1234567890", "away reply"))
+	require.Len(t, fake.Sent(), 1)
+
+	app.flushSendQueue(ctx)
+	assert.Len(t, fake.Sent(), 1, "still quiet")
+
+	*clock = time.Date(2026, 3, 3, 7, 0, 0, 0, time.UTC)
+	app.flushSendQueue(ctx)
+	sent := fake.Sent()
+	require.Len(t, sent, 2)
+	assert.Equal(t, "good morning", sent[1].Message)
+	n, err := app.store.CountQueuedSends()
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestSetSendShapingValidates(t *testing.T) {
+	app, _ := newFakeApp(t)
+	assert.Error(t, app.SetSendShaping(SendShaping{MinDelay: 5 * time.Second, MaxDelay: time.Second}))
+	assert.Error(t, app.SetSendShaping(SendShaping{PerMinute: -1}))
+	assert.Error(t, app.SetSendShaping(SendShaping{QuietHours: "22:00"}))
+	assert.Error(t, app.SetSendShaping(SendShaping{QuietHours: "25:00-07:00"}))
+	assert.Error(t, app.SetSendShaping(SendShaping{QuietHours: "22:00-07:00", Timezone: "Nowhere/Land"}))
+	assert.NoError(t, app.SetSendShaping(SendShaping{MinDelay: time.Second}))
+	assert.Equal(t, time.Second, app.shaper.cfg.MaxDelay, "a single delay is fixed")
+}
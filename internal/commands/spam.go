@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+)
+
+// Spam flags and the score each adds. Scores add up to at most 100.
+const (
+	spamFlagNewSender          = "new_sender"
+	spamFlagLink               = "link"
+	spamFlagForwarded          = "forwarded"
+	spamFlagForwardedManyTimes = "forwarded_many_times"
+
+	spamWeightNewSender          = 30
+	spamWeightLink               = 30
+	spamWeightForwarded          = 10
+	spamWeightForwardedManyTimes = 40
+
+	// forwardedManyTimes is the forwarding score from which WhatsApp shows
+	// the "Forwarded many times" label.
+	forwardedManyTimes = 5
+)
+
+var linkPattern = regexp.MustCompile(`(?i)\b(https?://|www\.)\S+|\b(wa\.me|chat\.whatsapp\.com|bit\.ly|t\.me)/\S+`)
+
+// SpamPolicy controls how scored messages are handled.
+type SpamPolicy struct {
+	// QuarantineThreshold hides messages scoring at or above it from default
+	// listings and lists them in the quarantine instead. 0 disables quarantine.
+	QuarantineThreshold int
+}
+
+// SetSpamPolicy replaces the spam policy. It should be called before serving.
+func (a *App) SetSpamPolicy(p SpamPolicy) {
+	a.spam = p
+}
+
+// scoreSpam scores a message from 0 to 100 using cheap heuristics and returns
+// the flags that contributed.
+func scoreSpam(newSender bool, content string, forwardingScore uint32) (int, []string) {
+	score := 0
+	var flags []string
+	if newSender {
+		score += spamWeightNewSender
+		flags = append(flags, spamFlagNewSender)
+	}
+	if linkPattern.MatchString(content) {
+		score += spamWeightLink
+		flags = append(flags, spamFlagLink)
+	}
+	switch {
+	case forwardingScore >= forwardedManyTimes:
+		score += spamWeightForwardedManyTimes
+		flags = append(flags, spamFlagForwardedManyTimes)
+	case forwardingScore > 0:
+		score += spamWeightForwarded
+		flags = append(flags, spamFlagForwarded)
+	}
+	return score, flags
+}
+
+// ListQuarantine returns messages held back by the spam policy for review.
+func (a *App) ListQuarantine(limit, page int, includeJIDs, excludeJIDs []string) string {
+	if a.spam.QuarantineThreshold <= 0 {
+		return output.Error(fmt.Errorf("spam quarantine is disabled"))
+	}
+	messages, err := a.store.ListQuarantine(a.spam.QuarantineThreshold, limit, page, includeJIDs, excludeJIDs)
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(messages)
+}
+
+// ReleaseQuarantined returns a quarantined message to the default listings.
+func (a *App) ReleaseQuarantined(messageID string, chatJID *string) string {
+	if err := a.store.ReleaseQuarantined(messageID, chatJID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return output.Error(fmt.Errorf("message %s not found", messageID))
+		}
+		return output.Error(err)
+	}
+	return output.Success(map[string]interface{}{
+		"released": true,
+		"id":       messageID,
+	})
+}
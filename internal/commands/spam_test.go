@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScoreSpam(t *testing.T) {
+	tests := []struct {
+		name       string
+		newSender  bool
+		content    string
+		forwarding uint32
+		wantScore  int
+		wantFlags  []string
+	}{
+		{"known sender plain text", false, "see you later", 0, 0, nil},
+		{"new sender", true, "hi", 0, 30, []string{"new_sender"}},
+		{"link", false, "look www.example.com", 0, 30, []string{"link"}},
+		{"invite link", false, "join chat.whatsapp.com/AbCdEf", 0, 30, []string{"link"}},
+		{"forwarded once", false, "hi", 1, 10, []string{"forwarded"}},
+		{"all flags", true, "WIN https://spam.example/prize", 9, 100, []string{"new_sender", "link", "forwarded_many_times"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, flags := scoreSpam(tt.newSender, tt.content, tt.forwarding)
+			assert.Equal(t, tt.wantScore, score)
+			assert.Equal(t, tt.wantFlags, flags)
+		})
+	}
+}
+
+func TestQuarantineHidesAndReleasesSpam(t *testing.T) {
+	app, _ := newFakeApp(t)
+	app.SetSpamPolicy(SpamPolicy{QuarantineThreshold: 70})
+
+	chat := "120363000000000001@g.us"
+	base := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, app.store.StoreChat(chat, "Group", base))
+
+	store := func(id, sender, content string, ts time.Time, forwarding uint32) {
+		require.NoError(t, app.store.StoreMessage(id, chat, sender, content, ts, false, "", "", "", "", "", nil, nil, nil, 0))
+		require.NoError(t, app.enrichMessage(enrichRequest{
+			ID: id, ChatJID: chat, Sender: sender, Content: content, Timestamp: ts,
+			ForwardingScore: forwarding, Live: true,
+		}))
+	}
+	store("regular1", "111", "morning all", base, 0)
+	store("regular2", "111", "check https://example.com", base.Add(time.Minute), 0)
+	store("spam", "999", "Free prize at https://spam.example", base.Add(2*time.Minute), 6)
+
+	var list struct {
+		Data []struct {
+			ID        string   `json:"id"`
+			SpamScore int      `json:"spam_score"`
+			SpamFlags []string `json:"spam_flags"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(app.ListMessages(&chat, nil, 10, 0, nil, nil, nil, nil, nil)), &list))
+	require.Len(t, list.Data, 2)
+	assert.Equal(t, "regular2", list.Data[0].ID)
+	assert.Equal(t, 30, list.Data[0].SpamScore)
+
+	require.NoError(t, json.Unmarshal([]byte(app.ListQuarantine(10, 0, nil, nil)), &list))
+	require.Len(t, list.Data, 1)
+	assert.Equal(t, "spam", list.Data[0].ID)
+	assert.Equal(t, 100, list.Data[0].SpamScore)
+	assert.Equal(t, []string{"new_sender", "link", "forwarded_many_times"}, list.Data[0].SpamFlags)
+
+	assert.Contains(t, app.ReleaseQuarantined("spam", &chat), `"released":true`)
+	assert.Contains(t, app.ReleaseQuarantined("missing", nil), "not found")
+
+	require.NoError(t, json.Unmarshal([]byte(app.ListQuarantine(10, 0, nil, nil)), &list))
+	assert.Empty(t, list.Data)
+	require.NoError(t, json.Unmarshal([]byte(app.ListMessages(&chat, nil, 10, 0, nil, nil, nil, nil, nil)), &list))
+	assert.Len(t, list.Data, 3)
+}
+
+func TestListQuarantineDisabled(t *testing.T) {
+	app, _ := newFakeApp(t)
+	assert.Contains(t, app.ListQuarantine(10, 0, nil, nil), "spam quarantine is disabled")
+}
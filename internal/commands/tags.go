@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+)
+
+// maxTagLength bounds a user-defined tag.
+const maxTagLength = 64
+
+// normalizeTag lower-cases a tag, as recipes do, and rejects empty, overlong
+// and whitespace-containing ones.
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	switch {
+	case tag == "":
+		return "", fmt.Errorf("tag cannot be empty")
+	case len(tag) > maxTagLength:
+		return "", fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+	case strings.IndexFunc(tag, unicode.IsSpace) >= 0:
+		return "", fmt.Errorf("tag %q contains whitespace", tag)
+	}
+	return tag, nil
+}
+
+// TagMessage adds tags to a message. Tags are lower-cased; tags the message
+// already carries are kept. The result lists all of the message's tags.
+func (a *App) TagMessage(messageID string, chatJID *string, tags []string) string {
+	if len(tags) == 0 {
+		return output.Error(fmt.Errorf("at least one tag is required"))
+	}
+	normalized := make([]string, len(tags))
+	for i, tag := range tags {
+		t, err := normalizeTag(tag)
+		if err != nil {
+			return output.Error(err)
+		}
+		normalized[i] = t
+	}
+
+	chat, err := a.messageChat(messageID, chatJID)
+	if err != nil {
+		return output.Error(err)
+	}
+	now := time.Now().UTC()
+	for _, tag := range normalized {
+		if err := a.store.TagMessage(messageID, chat, tag, now); err != nil {
+			return output.Error(err)
+		}
+	}
+	return a.messageTagsResult(messageID, chat)
+}
+
+// UntagMessage removes a tag from a message. The result lists the tags the
+// message still carries.
+func (a *App) UntagMessage(messageID string, chatJID *string, tag string) string {
+	tag, err := normalizeTag(tag)
+	if err != nil {
+		return output.Error(err)
+	}
+	chat, err := a.messageChat(messageID, chatJID)
+	if err != nil {
+		return output.Error(err)
+	}
+	removed, err := a.store.UntagMessage(messageID, chat, tag)
+	if err != nil {
+		return output.Error(err)
+	}
+	if !removed {
+		return output.Error(fmt.Errorf("message %s is not tagged %q", messageID, tag))
+	}
+	return a.messageTagsResult(messageID, chat)
+}
+
+// GetMessageTags lists the tags of a message.
+func (a *App) GetMessageTags(messageID string, chatJID *string) string {
+	chat, err := a.messageChat(messageID, chatJID)
+	if err != nil {
+		return output.Error(err)
+	}
+	return a.messageTagsResult(messageID, chat)
+}
+
+// MessageChat returns the chat of a message given by ID; chatJID picks one
+// when the ID is not unique.
+func (a *App) MessageChat(messageID string, chatJID *string) (string, error) {
+	return a.messageChat(messageID, chatJID)
+}
+
+// messageChat resolves the chat of a message given by ID.
+func (a *App) messageChat(messageID string, chatJID *string) (string, error) {
+	chat, err := a.store.MessageChat(messageID, chatJID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("message %s not found", messageID)
+	}
+	return chat, err
+}
+
+func (a *App) messageTagsResult(messageID, chatJID string) string {
+	tags, err := a.store.MessageTags(messageID, chatJID)
+	if err != nil {
+		return output.Error(err)
+	}
+	return output.Success(map[string]interface{}{
+		"id":       messageID,
+		"chat_jid": chatJID,
+		"tags":     tags,
+	})
+}
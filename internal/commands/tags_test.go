@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagMessage(t *testing.T) {
+	app, _ := newFakeApp(t)
+	now := time.Now().UTC()
+	chat, group := "34600111222@s.whatsapp.net", "120363000000000001@g.us"
+	require.NoError(t, app.store.StoreChat(chat, "Alice", now))
+	require.NoError(t, app.store.StoreChat(group, "Team", now))
+	require.NoError(t, app.store.StoreMessage("m1", chat, "34600111222", "invoice attached", now, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, app.store.StoreMessage("m2", chat, "34600111222", "thanks", now, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, app.store.StoreMessage("dup", chat, "34600111222", "a", now, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, app.store.StoreMessage("dup", group, "34600111222", "b", now, false, "", "", "", "", "", nil, nil, nil, 0))
+
+	var res struct {
+		Success bool `json:"success"`
+		Data    struct {
+			ChatJID string   `json:"chat_jid"`
+			Tags    []string `json:"tags"`
+		} `json:"data"`
+		Error string `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(app.TagMessage("m1", nil, []string{" Needs-Reply ", "invoice"})), &res))
+	require.True(t, res.Success, res.Error)
+	assert.Equal(t, chat, res.Data.ChatJID)
+	assert.Equal(t, []string{"invoice", "needs-reply"}, res.Data.Tags)
+
+	upper, lower := "NEEDS-REPLY", "needs-reply"
+	list := app.ListMessages(nil, nil, 10, 0, nil, nil, nil, nil, &upper)
+	assert.Contains(t, list, `"id":"m1"`)
+	assert.NotContains(t, list, `"id":"m2"`)
+
+	require.NoError(t, json.Unmarshal([]byte(app.UntagMessage("m1", nil, "needs-reply")), &res))
+	require.True(t, res.Success, res.Error)
+	assert.Equal(t, []string{"invoice"}, res.Data.Tags)
+	assert.NotContains(t, app.ListMessages(nil, nil, 10, 0, nil, nil, nil, nil, &lower), `"id":"m1"`)
+	assert.Contains(t, app.UntagMessage("m1", nil, "needs-reply"), "not tagged")
+
+	assert.Contains(t, app.TagMessage("missing", nil, []string{"x"}), "message missing not found")
+	assert.Contains(t, app.TagMessage("dup", nil, []string{"x"}), "specify chat JID")
+	assert.Contains(t, app.TagMessage("m1", nil, []string{"two words"}), "whitespace")
+	assert.Contains(t, app.TagMessage("m1", nil, nil), "at least one tag")
+
+	require.NoError(t, json.Unmarshal([]byte(app.TagMessage("dup", &group, []string{"x"})), &res))
+	require.True(t, res.Success, res.Error)
+	assert.Equal(t, group, res.Data.ChatJID)
+	assert.Contains(t, app.GetMessageTags("dup", &chat), `"tags":[]`)
+}
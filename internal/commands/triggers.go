@@ -0,0 +1,25 @@
+package commands
+
+import (
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/store"
+)
+
+// NewMessages serves polling triggers of automation platforms such as
+// Zapier and Make. Quarantined messages are left out.
+func (a *App) NewMessages(params store.NewMessagesParams) ([]store.TriggerMessage, error) {
+	params.HideSpamFrom = a.spam.QuarantineThreshold
+	return a.store.NewMessages(params)
+}
+
+// MessagesSince serves the replay of the event stream after a reconnect.
+// Quarantined messages are left out.
+func (a *App) MessagesSince(since time.Time, limit int, includeJIDs, excludeJIDs []string) ([]store.TriggerMessage, error) {
+	return a.store.MessagesSince(since, limit, includeJIDs, excludeJIDs, a.spam.QuarantineThreshold)
+}
+
+// NewChats serves the new-chat polling trigger.
+func (a *App) NewChats(sinceID int64, limit int, includeJIDs, excludeJIDs []string) ([]store.TriggerChat, error) {
+	return a.store.NewChats(sinceID, limit, includeJIDs, excludeJIDs)
+}
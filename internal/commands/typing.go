@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+	"unicode/utf8"
+)
+
+// Typing simulation: the indicator is shown for typingBase plus typingPerRune
+// for every character of the message, at most typingMax, which approximates
+// a fast typist without holding up long messages.
+const (
+	typingBase    = 500 * time.Millisecond
+	typingPerRune = 50 * time.Millisecond
+	typingMax     = 8 * time.Second
+)
+
+// SetSimulateTyping makes every send, including bot, greeting and away
+// replies, show the "typing…" indicator for a time proportional to the
+// message length first.
+func (a *App) SetSimulateTyping(enabled bool) {
+	a.simulateTyping = enabled
+}
+
+// typingDelay returns how long typing message is simulated for.
+func typingDelay(message string) time.Duration {
+	d := typingBase + time.Duration(utf8.RuneCountInString(message))*typingPerRune
+	if d > typingMax {
+		return typingMax
+	}
+	return d
+}
+
+// simulateTypingFor shows the typing indicator to recipient for the time it
+// would take to type message. Failing to send the indicator does not stop
+// the message; only a cancelled ctx does.
+func (a *App) simulateTypingFor(ctx context.Context, recipient, message string) error {
+	if err := a.client.SendTyping(ctx, recipient, true); err != nil {
+		fmt.Fprintf(os.Stderr, "\n⚠ Failed to send typing indicator to %s: %v\n", recipient, err)
+		return nil
+	}
+	if err := a.sleep(ctx, typingDelay(message)); err != nil {
+		a.client.SendTyping(context.WithoutCancel(ctx), recipient, false)
+		return err
+	}
+	return nil
+}
+
+// sleepContext waits for d or until ctx is cancelled.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
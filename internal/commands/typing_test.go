@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/client"
+)
+
+func TestTypingDelayGrowsWithLength(t *testing.T) {
+	assert.Equal(t, 500*time.Millisecond, typingDelay(""))
+	assert.Equal(t, 750*time.Millisecond, typingDelay("héllo"))
+	assert.Equal(t, typingMax, typingDelay(strings.Repeat("x", 1000)))
+}
+
+func TestSendMessageSimulatesTyping(t *testing.T) {
+	app, fake := newFakeApp(t)
+	var slept []time.Duration
+	app.sleep = func(_ context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+
+	result := app.SendMessage(context.Background(), "1234567890", "hello", client.SendOptions{})
+	assert.Contains(t, result, `"success":true`)
+	assert.Empty(t, fake.Typing(), "off by default")
+
+	app.SetSimulateTyping(true)
+	app.SendMessage(context.Background(), "1234567890", "hello", client.SendOptions{})
+	typing := fake.Typing()
+	require.Len(t, typing, 1)
+	assert.Equal(t, "1234567890", typing[0].Recipient)
+	assert.True(t, typing[0].Typing)
+	assert.Equal(t, []time.Duration{typingDelay("hello")}, slept)
+	assert.Len(t, fake.Sent(), 2)
+
+	off := false
+	app.SendMessage(context.Background(), "1234567890", "hello", client.SendOptions{SimulateTyping: &off})
+	assert.Len(t, fake.Typing(), 1, "per-message override")
+}
+
+func TestSendMessageTypingCancelled(t *testing.T) {
+	app, fake := newFakeApp(t)
+	app.SetSimulateTyping(true)
+	app.sleep = func(ctx context.Context, _ time.Duration) error { return context.Canceled }
+
+	result := app.SendMessage(context.Background(), "1234567890", "hello", client.SendOptions{})
+	assert.Contains(t, result, `"success":false`)
+	assert.Empty(t, fake.Sent())
+	typing := fake.Typing()
+	require.Len(t, typing, 2)
+	assert.False(t, typing[1].Typing, "indicator cleared")
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"context"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/selfupdate"
+)
+
+// UpdateCheckInterval is how long a release check is reused, keeping
+// /version well under GitHub's unauthenticated rate limit.
+const UpdateCheckInterval = time.Hour
+
+type versionInfo struct {
+	Version         string     `json:"version"`
+	Commit          string     `json:"commit"`
+	Latest          string     `json:"latest,omitempty"`
+	UpdateAvailable bool       `json:"update_available"`
+	ReleaseURL      string     `json:"release_url,omitempty"`
+	CheckedAt       *time.Time `json:"checked_at,omitempty"`
+	UpdateError     string     `json:"update_error,omitempty"`
+}
+
+// updateCheck caches the most recent release check.
+type updateCheck struct {
+	status    selfupdate.Status
+	err       error
+	checkedAt time.Time
+}
+
+// SetBuildInfo records the commit the binary was built from.
+func (a *App) SetBuildInfo(commit string) {
+	a.commit = commit
+}
+
+// SetUpdateCheck enables or disables looking up the latest release on GitHub.
+func (a *App) SetUpdateCheck(enabled bool) {
+	a.updateMu.Lock()
+	defer a.updateMu.Unlock()
+	if enabled {
+		a.updater = selfupdate.New()
+	} else {
+		a.updater = nil
+	}
+	a.lastCheck = nil
+}
+
+// VersionInfo reports the running version and commit, and whether a newer
+// release is available. A failed release check is reported alongside the
+// version rather than failing the call.
+func (a *App) VersionInfo(ctx context.Context) string {
+	info := versionInfo{Version: a.version, Commit: a.commit}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+
+	a.updateMu.Lock()
+	defer a.updateMu.Unlock()
+	if a.updater == nil {
+		return output.Success(info)
+	}
+	if a.lastCheck == nil || time.Since(a.lastCheck.checkedAt) > UpdateCheckInterval {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		status, _, err := a.updater.Check(ctx, a.version)
+		cancel()
+		a.lastCheck = &updateCheck{status: status, err: err, checkedAt: time.Now().UTC()}
+	}
+
+	checkedAt := a.lastCheck.checkedAt
+	info.CheckedAt = &checkedAt
+	if a.lastCheck.err != nil {
+		info.UpdateError = a.lastCheck.err.Error()
+		return output.Success(info)
+	}
+	info.Latest = a.lastCheck.status.Latest
+	info.UpdateAvailable = a.lastCheck.status.UpdateAvailable
+	info.ReleaseURL = a.lastCheck.status.ReleaseURL
+	return output.Success(info)
+}
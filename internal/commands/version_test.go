@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/selfupdate"
+)
+
+func TestVersionInfoReportsUpdateAndCachesCheck(t *testing.T) {
+	var calls atomic.Int32
+	gh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"tag_name":"v9.0.0","html_url":"https://example.com/v9.0.0"}`))
+	}))
+	defer gh.Close()
+
+	app, _ := newFakeApp(t)
+	app.version = "v1.3.1"
+	app.SetBuildInfo("abc123")
+	app.updater = &selfupdate.Updater{Repo: "acme/cli", APIURL: gh.URL, Client: gh.Client()}
+
+	var resp struct {
+		Data versionInfo `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(app.VersionInfo(context.Background())), &resp))
+	assert.Equal(t, "v1.3.1", resp.Data.Version)
+	assert.Equal(t, "abc123", resp.Data.Commit)
+	assert.Equal(t, "v9.0.0", resp.Data.Latest)
+	assert.True(t, resp.Data.UpdateAvailable)
+	assert.NotNil(t, resp.Data.CheckedAt)
+
+	app.VersionInfo(context.Background())
+	assert.Equal(t, int32(1), calls.Load(), "release check should be cached")
+}
+
+func TestVersionInfoReportsCheckFailure(t *testing.T) {
+	gh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusForbidden)
+	}))
+	defer gh.Close()
+
+	app, _ := newFakeApp(t)
+	app.updater = &selfupdate.Updater{Repo: "acme/cli", APIURL: gh.URL, Client: gh.Client()}
+
+	var resp struct {
+		Success bool        `json:"success"`
+		Data    versionInfo `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(app.VersionInfo(context.Background())), &resp))
+	assert.True(t, resp.Success)
+	assert.Equal(t, "unknown", resp.Data.Commit)
+	assert.False(t, resp.Data.UpdateAvailable)
+	assert.Contains(t, resp.Data.UpdateError, "403")
+}
+
+func TestVersionInfoWithoutUpdateCheck(t *testing.T) {
+	app, _ := newFakeApp(t)
+	app.SetUpdateCheck(false)
+
+	var resp struct {
+		Data map[string]any `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(app.VersionInfo(context.Background())), &resp))
+	assert.NotContains(t, resp.Data, "checked_at")
+	assert.Equal(t, false, resp.Data["update_available"])
+}
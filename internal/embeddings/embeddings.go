@@ -0,0 +1,111 @@
+// Package embeddings turns text into vectors through an HTTP embedding API.
+// It speaks the OpenAI embeddings protocol, which hosted providers and local
+// model servers (Ollama, llama.cpp, LocalAI, vLLM) all implement, and also
+// accepts the response of Ollama's native /api/embed endpoint.
+package embeddings
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// requestTimeout bounds one embedding request; local models on a CPU can take
+// a while for a full batch.
+const requestTimeout = 2 * time.Minute
+
+// Client embeds text with one model.
+type Client struct {
+	url    string
+	model  string
+	apiKey string
+	http   *http.Client
+}
+
+// New returns a client posting to endpoint, e.g.
+// "https://api.openai.com/v1/embeddings" or "http://localhost:11434/api/embed".
+// apiKey is sent as a bearer token when set.
+func New(endpoint, model, apiKey string) (*Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid embeddings URL: %s", endpoint)
+	}
+	if strings.TrimSpace(model) == "" {
+		return nil, fmt.Errorf("an embeddings model is required")
+	}
+	return &Client{
+		url:    endpoint,
+		model:  model,
+		apiKey: apiKey,
+		http:   &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+// Model returns the model name vectors are produced with. Vectors of
+// different models are not comparable.
+func (c *Client) Model() string {
+	return c.model
+}
+
+// Embed returns one vector per text, in order.
+func (c *Client) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	body, _ := json.Marshal(map[string]any{"model": c.model, "input": texts})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("embeddings API returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var out struct {
+		// OpenAI protocol
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		// Ollama /api/embed
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("invalid embeddings response: %w", err)
+	}
+	vectors := out.Embeddings
+	if len(out.Data) > 0 {
+		vectors = make([][]float32, len(out.Data))
+		for _, d := range out.Data {
+			if d.Index < 0 || d.Index >= len(vectors) {
+				return nil, fmt.Errorf("invalid embeddings response: index %d out of range", d.Index)
+			}
+			vectors[d.Index] = d.Embedding
+		}
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("embeddings API returned %d vectors for %d inputs", len(vectors), len(texts))
+	}
+	for i, v := range vectors {
+		if len(v) == 0 {
+			return nil, fmt.Errorf("embeddings API returned an empty vector for input %d", i)
+		}
+	}
+	return vectors, nil
+}
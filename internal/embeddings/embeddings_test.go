@@ -0,0 +1,77 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	_, err := New("localhost:11434", "nomic-embed-text", "")
+	assert.Error(t, err)
+	_, err = New("http://localhost:11434/api/embed", " ", "")
+	assert.Error(t, err)
+	c, err := New("http://localhost:11434/api/embed", "nomic-embed-text", "")
+	require.NoError(t, err)
+	assert.Equal(t, "nomic-embed-text", c.Model())
+}
+
+func TestEmbed_OpenAI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "text-embedding-3-small", req.Model)
+		assert.Equal(t, []string{"a", "b"}, req.Input)
+		// Out of order on purpose: results are placed by index
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "text-embedding-3-small", "sk-test")
+	require.NoError(t, err)
+	vectors, err := c.Embed(context.Background(), []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 0}, {0, 1}}, vectors)
+}
+
+func TestEmbed_Ollama(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+		w.Write([]byte(`{"model":"nomic-embed-text","embeddings":[[0.5,0.5,0]]}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL+"/api/embed", "nomic-embed-text", "")
+	require.NoError(t, err)
+	vectors, err := c.Embed(context.Background(), []string{"hola"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{0.5, 0.5, 0}}, vectors)
+}
+
+func TestEmbed_Errors(t *testing.T) {
+	body := `{"error":"model not found"}`
+	status := http.StatusNotFound
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	c, err := New(srv.URL, "missing", "")
+	require.NoError(t, err)
+
+	_, err = c.Embed(context.Background(), []string{"a"})
+	assert.ErrorContains(t, err, "model not found")
+
+	status, body = http.StatusOK, `{"data":[]}`
+	_, err = c.Embed(context.Background(), []string{"a"})
+	assert.ErrorContains(t, err, "0 vectors for 1 inputs")
+}
@@ -0,0 +1,249 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Brokers supported by New.
+const (
+	BrokerNATS  = "nats"
+	BrokerKafka = "kafka"
+	BrokerMQTT  = "mqtt"
+)
+
+// DefaultQueueSize bounds the events waiting to be published. When the
+// broker falls behind, newer events are dropped instead of blocking sync.
+const DefaultQueueSize = 10000
+
+// publishAttempts is how often an event is tried before it is dropped.
+const publishAttempts = 3
+
+// Publisher delivers an encoded event to a broker topic.
+type Publisher interface {
+	Publish(ctx context.Context, topic, key string, payload []byte) error
+	Close() error
+}
+
+// Config selects the broker, its address, payload format and topic prefix.
+type Config struct {
+	Broker      string
+	URL         string
+	Format      string
+	TopicPrefix string
+}
+
+// Stats counts events by outcome.
+type Stats struct {
+	Published int64 `json:"published"`
+	Failed    int64 `json:"failed"`
+	Dropped   int64 `json:"dropped"`
+	Spooled   int64 `json:"spooled,omitempty"`
+}
+
+// Bus queues events and publishes them in the background.
+type Bus struct {
+	pub    Publisher
+	format string
+	prefix string
+	queue  chan Event
+	// only, if set, limits the event types published.
+	only map[string]bool
+	// topic, if set, overrides the per-type topic naming.
+	topic func(Event) string
+	// spool, if set, keeps events the broker did not accept.
+	spool func(topic, key string, payload []byte) error
+	// spooling is set while the broker is failing; events then go straight
+	// to the spool, behind the ones already there.
+	spooling atomic.Bool
+
+	published atomic.Int64
+	failed    atomic.Int64
+	dropped   atomic.Int64
+	spooled   atomic.Int64
+}
+
+// New creates a Bus for the configured broker.
+func New(cfg Config) (*Bus, error) {
+	var pub Publisher
+	var err error
+	switch cfg.Broker {
+	case BrokerNATS:
+		pub, err = NewNATS(cfg.URL)
+	case BrokerKafka:
+		pub, err = NewKafkaREST(cfg.URL)
+	case BrokerMQTT:
+		pub, err = NewMQTT(cfg.URL)
+	default:
+		return nil, fmt.Errorf("unknown event broker %q", cfg.Broker)
+	}
+	if err != nil {
+		return nil, err
+	}
+	b := NewBus(pub, cfg.Format, cfg.TopicPrefix)
+	if cfg.Broker == BrokerMQTT {
+		b.topic = b.mqttTopic
+	}
+	return b, nil
+}
+
+// NewBus creates a Bus publishing through pub. Topics are named
+// "<prefix>.messages", "<prefix>.receipts" and "<prefix>.presence".
+func NewBus(pub Publisher, format, prefix string) *Bus {
+	if format == "" {
+		format = FormatJSON
+	}
+	if prefix == "" {
+		prefix = "whatsapp"
+	}
+	return &Bus{pub: pub, format: format, prefix: prefix, queue: make(chan Event, DefaultQueueSize)}
+}
+
+// Topic returns the topic events of the given type are published to.
+func (b *Bus) Topic(eventType string) string {
+	switch eventType {
+	case TypeMessage:
+		return b.prefix + ".messages"
+	case TypeReceipt:
+		return b.prefix + ".receipts"
+	case TypeAccountAlert:
+		return b.prefix + ".alerts"
+	default:
+		return b.prefix + "." + eventType
+	}
+}
+
+// Publish queues e without blocking. It returns false if the queue is full
+// and the event was dropped.
+func (b *Bus) Publish(e Event) bool {
+	if b.only != nil && !b.only[e.Type] {
+		return true
+	}
+	select {
+	case b.queue <- e:
+		return true
+	default:
+		if n := b.dropped.Add(1); n == 1 || n%1000 == 0 {
+			fmt.Fprintf(os.Stderr, "⚠ Event bus queue full, %d events dropped\n", n)
+		}
+		return false
+	}
+}
+
+// Run publishes queued events until ctx is cancelled.
+func (b *Bus) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-b.queue:
+			b.send(ctx, e)
+		}
+	}
+}
+
+func (b *Bus) send(ctx context.Context, e Event) {
+	payload, err := Encode(e, b.format)
+	if err != nil {
+		b.failed.Add(1)
+		fmt.Fprintf(os.Stderr, "⚠ Failed to encode %s event: %v\n", e.Type, err)
+		return
+	}
+	topic := b.Topic(e.Type)
+	if b.topic != nil {
+		topic = b.topic(e)
+	}
+	if b.spool != nil && b.spooling.Load() {
+		b.save(e, topic, payload, nil)
+		return
+	}
+	for attempt := 1; ; attempt++ {
+		pubCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		err = b.pub.Publish(pubCtx, topic, e.Key(), payload)
+		cancel()
+		if err == nil {
+			b.published.Add(1)
+			return
+		}
+		if attempt == publishAttempts || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Duration(attempt) * 500 * time.Millisecond):
+		}
+	}
+	if b.spool != nil {
+		b.save(e, topic, payload, err)
+		return
+	}
+	b.failed.Add(1)
+	fmt.Fprintf(os.Stderr, "⚠ Failed to publish %s event to %s: %v\n", e.Type, topic, err)
+}
+
+// SetSpool hands events that could not be published to save, which is
+// expected to keep them for a later Deliver. Until Resume is called, newer
+// events are saved without trying the broker, so they stay in order.
+func (b *Bus) SetSpool(save func(topic, key string, payload []byte) error) {
+	b.spool = save
+}
+
+// save spools an event after publishing failed with cause (nil when the bus
+// was already spooling).
+func (b *Bus) save(e Event, topic string, payload []byte, cause error) {
+	if err := b.spool(topic, e.Key(), payload); err != nil {
+		b.failed.Add(1)
+		fmt.Fprintf(os.Stderr, "⚠ Failed to queue %s event for %s: %v\n", e.Type, topic, err)
+		return
+	}
+	b.spooled.Add(1)
+	if cause != nil && !b.spooling.Swap(true) {
+		fmt.Fprintf(os.Stderr, "⚠ Failed to publish to %s, queueing events until it recovers: %v\n", topic, cause)
+	}
+}
+
+// Deliver publishes an already encoded event, such as one replayed from the
+// spool.
+func (b *Bus) Deliver(ctx context.Context, topic, key string, payload []byte) error {
+	pubCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := b.pub.Publish(pubCtx, topic, key, payload); err != nil {
+		return err
+	}
+	b.published.Add(1)
+	return nil
+}
+
+// Spooling reports whether new events are being spooled instead of published.
+func (b *Bus) Spooling() bool {
+	return b.spooling.Load()
+}
+
+// Resume publishes new events to the broker again, once the spooled ones
+// have been delivered.
+func (b *Bus) Resume() {
+	b.spooling.Store(false)
+}
+
+// Stats returns the number of published, failed and dropped events.
+func (b *Bus) Stats() Stats {
+	return Stats{
+		Published: b.published.Load(),
+		Failed:    b.failed.Load(),
+		Dropped:   b.dropped.Load(),
+		Spooled:   b.spooled.Load(),
+	}
+}
+
+// Publisher returns the broker the bus publishes through.
+func (b *Bus) Publisher() Publisher {
+	return b.pub
+}
+
+// Close releases the broker connection.
+func (b *Bus) Close() error {
+	return b.pub.Close()
+}
@@ -0,0 +1,133 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type published struct {
+	topic, key string
+	payload    []byte
+}
+
+type fakePublisher struct {
+	mu       sync.Mutex
+	failures int
+	got      []published
+}
+
+func (p *fakePublisher) Publish(_ context.Context, topic, key string, payload []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("broker unavailable")
+	}
+	p.got = append(p.got, published{topic, key, payload})
+	return nil
+}
+
+func (p *fakePublisher) Close() error { return nil }
+
+func (p *fakePublisher) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.got)
+}
+
+func runBus(t *testing.T, b *Bus) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestBusPublishesToTypedTopics(t *testing.T) {
+	pub := &fakePublisher{}
+	b := NewBus(pub, FormatJSON, "wa")
+	runBus(t, b)
+
+	b.Publish(Event{Type: TypeMessage, ChatJID: "c1", Timestamp: testTime, Message: &Message{ID: "M1"}})
+	b.Publish(Event{Type: TypeReceipt, ChatJID: "c1", Timestamp: testTime, Receipt: &Receipt{MessageIDs: []string{"M1"}, Type: "read"}})
+	b.Publish(Event{Type: TypePresence, Sender: "u1", Timestamp: testTime, Presence: &Presence{Available: true}})
+
+	require.Eventually(t, func() bool { return pub.count() == 3 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "wa.messages", pub.got[0].topic)
+	assert.Equal(t, "c1", pub.got[0].key)
+	assert.Equal(t, "wa.receipts", pub.got[1].topic)
+	assert.Equal(t, "wa.presence", pub.got[2].topic)
+	assert.Equal(t, "u1", pub.got[2].key, "presence keyed by sender")
+	assert.Equal(t, Stats{Published: 3}, b.Stats())
+}
+
+func TestBusRetriesFailedPublish(t *testing.T) {
+	pub := &fakePublisher{failures: 1}
+	b := NewBus(pub, FormatProtobuf, "")
+	runBus(t, b)
+
+	b.Publish(Event{Type: TypeMessage, ChatJID: "c1", Message: &Message{ID: "M1"}})
+	require.Eventually(t, func() bool { return pub.count() == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "whatsapp.messages", pub.got[0].topic)
+	assert.Equal(t, Stats{Published: 1}, b.Stats())
+}
+
+func TestBusDropsWhenQueueFull(t *testing.T) {
+	b := NewBus(&fakePublisher{}, FormatJSON, "")
+	for i := 0; i < DefaultQueueSize; i++ {
+		require.True(t, b.Publish(Event{Type: TypeMessage}))
+	}
+	assert.False(t, b.Publish(Event{Type: TypeMessage}))
+	assert.Equal(t, int64(1), b.Stats().Dropped)
+}
+
+func TestNewUnknownBroker(t *testing.T) {
+	_, err := New(Config{Broker: "rabbitmq", URL: "amqp://localhost"})
+	assert.Error(t, err)
+}
+
+func TestBusSpoolsWhileBrokerFails(t *testing.T) {
+	pub := &fakePublisher{failures: 100}
+	b := NewBus(pub, FormatJSON, "")
+	var mu sync.Mutex
+	var spooled []published
+	b.SetSpool(func(topic, key string, payload []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		spooled = append(spooled, published{topic, key, payload})
+		return nil
+	})
+	runBus(t, b)
+
+	b.Publish(Event{Type: TypeMessage, ChatJID: "c1", Message: &Message{ID: "M1"}})
+	b.Publish(Event{Type: TypeMessage, ChatJID: "c1", Message: &Message{ID: "M2"}})
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(spooled) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, b.Spooling())
+	assert.Equal(t, Stats{Spooled: 2}, b.Stats())
+	pub.mu.Lock()
+	assert.Equal(t, 100-publishAttempts, pub.failures, "second event skips the broker")
+	pub.failures = 0
+	pub.mu.Unlock()
+
+	require.NoError(t, b.Deliver(context.Background(), spooled[0].topic, spooled[0].key, spooled[0].payload))
+	b.Resume()
+	assert.False(t, b.Spooling())
+	assert.Equal(t, "whatsapp.messages", pub.got[0].topic)
+	assert.Equal(t, int64(1), b.Stats().Published)
+}
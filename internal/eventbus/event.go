@@ -0,0 +1,202 @@
+// Package eventbus replicates stored messages, receipts and presence updates
+// to an external broker (NATS or Kafka) so downstream pipelines can consume
+// them without polling the REST API. Redis pub/sub is supported as a
+// lightweight push channel for new messages.
+package eventbus
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Event types, also used as the topic suffix.
+const (
+	TypeMessage  = "message"
+	TypeReceipt  = "receipt"
+	TypePresence = "presence"
+	// TypeDigest is a scheduled chat digest. Digests are only pushed to the
+	// notifier, which always uses JSON.
+	TypeDigest = "digest"
+	// TypeAccountAlert warns that the account is banned, logged out or at
+	// risk of a ban.
+	TypeAccountAlert = "account_alert"
+	// TypeFollowup is a chat whose incoming messages were not replied to
+	// in time. Like digests, follow-ups are only pushed to the notifier.
+	TypeFollowup = "followup"
+)
+
+// PriorityHigh marks events that need an operator's attention right away.
+const PriorityHigh = "high"
+
+// Payload formats.
+const (
+	FormatJSON     = "json"
+	FormatProtobuf = "protobuf"
+)
+
+// Event is a single replicated record. Exactly one of Message, Receipt,
+// Presence, Digest, Alert or Followup is set, matching Type.
+type Event struct {
+	Type      string    `json:"type"`
+	ChatJID   string    `json:"chat_jid,omitempty"`
+	Sender    string    `json:"sender,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Message   *Message  `json:"message,omitempty"`
+	Receipt   *Receipt  `json:"receipt,omitempty"`
+	Presence  *Presence `json:"presence,omitempty"`
+	// Digest is the JSON digest as served by the API; it has no protobuf
+	// encoding.
+	Digest json.RawMessage `json:"digest,omitempty"`
+	Alert  *AccountAlert   `json:"alert,omitempty"`
+	// Followup is the JSON follow-up as served by the API; like Digest it
+	// has no protobuf encoding.
+	Followup json.RawMessage `json:"followup,omitempty"`
+	// Priority is PriorityHigh for events to act on at once, else empty.
+	Priority string `json:"priority,omitempty"`
+}
+
+// Message is a message written to the store.
+type Message struct {
+	ID        string `json:"id"`
+	Content   string `json:"content"`
+	IsFromMe  bool   `json:"is_from_me"`
+	MediaType string `json:"media_type,omitempty"`
+	Filename  string `json:"filename,omitempty"`
+	MimeType  string `json:"mime_type,omitempty"`
+	// History is set for messages backfilled by a history sync rather than
+	// received live.
+	History bool `json:"history,omitempty"`
+}
+
+// Receipt reports that messages were delivered, read or played.
+type Receipt struct {
+	MessageIDs []string `json:"message_ids"`
+	Type       string   `json:"type"`
+}
+
+// Presence reports a contact going online or offline.
+type Presence struct {
+	Available bool       `json:"available"`
+	LastSeen  *time.Time `json:"last_seen,omitempty"`
+}
+
+// AccountAlert reports a sign that the account is restricted or at risk.
+type AccountAlert struct {
+	Kind      string     `json:"kind"`
+	Severity  string     `json:"severity"`
+	Code      string     `json:"code,omitempty"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Key is the partitioning key of the event; events of one chat keep their
+// order on Kafka.
+func (e Event) Key() string {
+	if e.ChatJID != "" {
+		return e.ChatJID
+	}
+	return e.Sender
+}
+
+// Encode serialises e in the given format. The protobuf layout is described
+// by event.proto in this directory.
+func Encode(e Event, format string) ([]byte, error) {
+	switch format {
+	case FormatJSON, "":
+		return json.Marshal(e)
+	case FormatProtobuf:
+		return encodeProto(e), nil
+	default:
+		return nil, fmt.Errorf("unknown event format %q", format)
+	}
+}
+
+func encodeProto(e Event) []byte {
+	var b []byte
+	b = appendString(b, 1, e.Type)
+	b = appendString(b, 2, e.ChatJID)
+	b = appendString(b, 3, e.Sender)
+	b = appendInt64(b, 4, unixMilli(e.Timestamp))
+	if m := e.Message; m != nil {
+		var mb []byte
+		mb = appendString(mb, 1, m.ID)
+		mb = appendString(mb, 2, m.Content)
+		mb = appendBool(mb, 3, m.IsFromMe)
+		mb = appendString(mb, 4, m.MediaType)
+		mb = appendString(mb, 5, m.Filename)
+		mb = appendString(mb, 6, m.MimeType)
+		mb = appendBool(mb, 7, m.History)
+		b = appendMessage(b, 5, mb)
+	}
+	if r := e.Receipt; r != nil {
+		var rb []byte
+		for _, id := range r.MessageIDs {
+			rb = protowire.AppendTag(rb, 1, protowire.BytesType)
+			rb = protowire.AppendString(rb, id)
+		}
+		rb = appendString(rb, 2, r.Type)
+		b = appendMessage(b, 6, rb)
+	}
+	if p := e.Presence; p != nil {
+		var pb []byte
+		pb = appendBool(pb, 1, p.Available)
+		if p.LastSeen != nil {
+			pb = appendInt64(pb, 2, unixMilli(*p.LastSeen))
+		}
+		b = appendMessage(b, 7, pb)
+	}
+	if a := e.Alert; a != nil {
+		var ab []byte
+		ab = appendString(ab, 1, a.Kind)
+		ab = appendString(ab, 2, a.Severity)
+		ab = appendString(ab, 3, a.Code)
+		ab = appendString(ab, 4, a.Reason)
+		if a.ExpiresAt != nil {
+			ab = appendInt64(ab, 5, unixMilli(*a.ExpiresAt))
+		}
+		b = appendMessage(b, 8, ab)
+	}
+	b = appendString(b, 9, e.Priority)
+	return b
+}
+
+// The helpers below skip zero values, as proto3 does for scalar fields.
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendInt64(b []byte, num protowire.Number, v int64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(v))
+}
+
+func appendBool(b []byte, num protowire.Number, v bool) []byte {
+	if !v {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, 1)
+}
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
@@ -0,0 +1,105 @@
+package eventbus
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var testTime = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestEncodeJSON(t *testing.T) {
+	b, err := Encode(Event{
+		Type:      TypeMessage,
+		ChatJID:   "123@s.whatsapp.net",
+		Sender:    "123@s.whatsapp.net",
+		Timestamp: testTime,
+		Message:   &Message{ID: "M1", Content: "hi"},
+	}, FormatJSON)
+	require.NoError(t, err)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, "message", got["type"])
+	assert.Equal(t, "2026-03-01T12:00:00Z", got["timestamp"])
+	assert.Equal(t, map[string]any{"id": "M1", "content": "hi", "is_from_me": false}, got["message"])
+	assert.NotContains(t, got, "receipt")
+}
+
+// fields decodes one level of a protobuf message into field number -> raw values.
+func fields(t *testing.T, b []byte) map[protowire.Number][]any {
+	t.Helper()
+	out := map[protowire.Number][]any{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			require.GreaterOrEqual(t, n, 0)
+			out[num] = append(out[num], v)
+			b = b[n:]
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			require.GreaterOrEqual(t, n, 0)
+			out[num] = append(out[num], v)
+			b = b[n:]
+		default:
+			t.Fatalf("unexpected wire type %v", typ)
+		}
+	}
+	return out
+}
+
+func TestEncodeProtobuf(t *testing.T) {
+	b, err := Encode(Event{
+		Type:      TypeReceipt,
+		ChatJID:   "123@s.whatsapp.net",
+		Timestamp: testTime,
+		Receipt:   &Receipt{MessageIDs: []string{"M1", "M2"}, Type: "read"},
+	}, FormatProtobuf)
+	require.NoError(t, err)
+
+	top := fields(t, b)
+	assert.Equal(t, []any{[]byte("receipt")}, top[1])
+	assert.Equal(t, []any{[]byte("123@s.whatsapp.net")}, top[2])
+	assert.NotContains(t, top, protowire.Number(3), "empty sender omitted")
+	assert.Equal(t, []any{uint64(testTime.UnixMilli())}, top[4])
+	require.Len(t, top[6], 1)
+
+	receipt := fields(t, top[6][0].([]byte))
+	assert.Equal(t, []any{[]byte("M1"), []byte("M2")}, receipt[1])
+	assert.Equal(t, []any{[]byte("read")}, receipt[2])
+}
+
+func TestEncodeProtobufAccountAlert(t *testing.T) {
+	expires := testTime.Add(24 * time.Hour)
+	b, err := Encode(Event{
+		Type:      TypeAccountAlert,
+		Timestamp: testTime,
+		Alert:     &AccountAlert{Kind: "temporary_ban", Severity: "critical", Code: "101", Reason: "sent to too many people", ExpiresAt: &expires},
+		Priority:  PriorityHigh,
+	}, FormatProtobuf)
+	require.NoError(t, err)
+
+	top := fields(t, b)
+	assert.Equal(t, []any{[]byte("account_alert")}, top[1])
+	assert.Equal(t, []any{[]byte("high")}, top[9])
+	require.Len(t, top[8], 1)
+
+	alert := fields(t, top[8][0].([]byte))
+	assert.Equal(t, []any{[]byte("temporary_ban")}, alert[1])
+	assert.Equal(t, []any{[]byte("critical")}, alert[2])
+	assert.Equal(t, []any{[]byte("101")}, alert[3])
+	assert.Equal(t, []any{uint64(expires.UnixMilli())}, alert[5])
+}
+
+func TestEncodeUnknownFormat(t *testing.T) {
+	_, err := Encode(Event{Type: TypeMessage}, "avro")
+	assert.Error(t, err)
+}
@@ -0,0 +1,58 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKafkaRESTPublish(t *testing.T) {
+	var gotPath, gotType string
+	var gotBody struct {
+		Records []kafkaRecord `json:"records"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotType = r.Header.Get("Content-Type")
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Write([]byte(`{"offsets":[{"partition":0,"offset":7}]}`))
+	}))
+	defer srv.Close()
+
+	k, err := NewKafkaREST(srv.URL + "/")
+	require.NoError(t, err)
+	require.NoError(t, k.Publish(context.Background(), "whatsapp.messages", "123@s.whatsapp.net", []byte{0x0a, 0x01}))
+
+	assert.Equal(t, "/topics/whatsapp.messages", gotPath)
+	assert.Equal(t, "application/vnd.kafka.binary.v2+json", gotType)
+	require.Len(t, gotBody.Records, 1)
+	assert.Equal(t, []byte("123@s.whatsapp.net"), gotBody.Records[0].Key)
+	assert.Equal(t, []byte{0x0a, 0x01}, gotBody.Records[0].Value)
+}
+
+func TestKafkaRESTPublishErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/topics/missing" {
+			http.Error(w, `{"error_code":40401,"message":"Topic not found"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"offsets":[{"error_code":1,"error":"leader not available"}]}`))
+	}))
+	defer srv.Close()
+
+	k, err := NewKafkaREST(srv.URL)
+	require.NoError(t, err)
+
+	err = k.Publish(context.Background(), "missing", "", []byte("x"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Topic not found")
+
+	err = k.Publish(context.Background(), "whatsapp.messages", "", []byte("x"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "leader not available")
+}
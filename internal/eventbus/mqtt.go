@@ -0,0 +1,55 @@
+package eventbus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"github.com/vicentereig/whatsapp-cli/internal/mqtt"
+)
+
+// MQTT publishes to an MQTT broker with QoS 0. Events are published to
+// "<prefix>/<type>/<chat JID>", e.g. "whatsapp/messages/1234567890@s.whatsapp.net",
+// so consumers can subscribe to one chat or, with wildcards, to all of them.
+type MQTT struct {
+	client *mqtt.Client
+}
+
+// NewMQTT parses an mqtt:// or mqtts:// URL of the form
+// mqtt://[user:password@]host[:port].
+func NewMQTT(rawURL string) (*MQTT, error) {
+	var id [4]byte
+	rand.Read(id[:])
+	client, err := mqtt.New(rawURL, "whatsapp-cli-"+hex.EncodeToString(id[:]))
+	if err != nil {
+		return nil, err
+	}
+	return &MQTT{client: client}, nil
+}
+
+// Client returns the connection events are published on, so commands can be
+// received on it too.
+func (m *MQTT) Client() *mqtt.Client {
+	return m.client
+}
+
+// Publish sends payload to topic. The key is not used by MQTT.
+func (m *MQTT) Publish(ctx context.Context, topic, key string, payload []byte) error {
+	return m.client.Publish(ctx, topic, payload, false)
+}
+
+// Close disconnects from the broker.
+func (m *MQTT) Close() error {
+	return m.client.Close()
+}
+
+// mqttTopic names the topic of an event on MQTT, where levels are separated
+// by slashes.
+func (b *Bus) mqttTopic(e Event) string {
+	name := strings.TrimPrefix(b.Topic(e.Type), b.prefix+".")
+	if e.ChatJID == "" {
+		return b.prefix + "/" + name
+	}
+	return b.prefix + "/" + name + "/" + e.ChatJID
+}
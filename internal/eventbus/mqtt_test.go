@@ -0,0 +1,34 @@
+package eventbus
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/mqtt/mqtttest"
+)
+
+func TestMQTTPublishesPerChatTopics(t *testing.T) {
+	broker := mqtttest.New(t)
+	b, err := New(Config{Broker: BrokerMQTT, URL: broker.URL(), Format: FormatJSON, TopicPrefix: "wa"})
+	require.NoError(t, err)
+	defer b.Close()
+	runBus(t, b)
+
+	b.Publish(Event{Type: TypeMessage, ChatJID: "c1@s.whatsapp.net", Timestamp: testTime, Message: &Message{ID: "M1", Content: "hi"}})
+	b.Publish(Event{Type: TypeReceipt, ChatJID: "c1@s.whatsapp.net", Receipt: &Receipt{MessageIDs: []string{"M1"}, Type: "read"}})
+	b.Publish(Event{Type: TypePresence, Sender: "c2@s.whatsapp.net", Presence: &Presence{Available: true}})
+
+	require.Eventually(t, func() bool { return len(broker.Messages()) == 3 }, time.Second, 5*time.Millisecond)
+	msgs := broker.Messages()
+	assert.Equal(t, "wa/messages/c1@s.whatsapp.net", msgs[0].Topic)
+	assert.Equal(t, "wa/receipts/c1@s.whatsapp.net", msgs[1].Topic)
+	assert.Equal(t, "wa/presence", msgs[2].Topic)
+	assert.False(t, msgs[0].Retain)
+
+	var e Event
+	require.NoError(t, json.Unmarshal([]byte(msgs[0].Payload), &e))
+	assert.Equal(t, "M1", e.Message.ID)
+}
@@ -0,0 +1,190 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NATS publishes to a NATS server using the core text protocol. It connects
+// lazily and reconnects on the next publish after a failure.
+type NATS struct {
+	url         *url.URL
+	dialTimeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	w    *bufio.Writer
+}
+
+// NewNATS parses a nats:// or tls:// URL, optionally carrying user:password
+// or a token as the user part.
+func NewNATS(rawURL string) (*NATS, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NATS URL: %w", err)
+	}
+	if u.Scheme != "nats" && u.Scheme != "tls" {
+		return nil, fmt.Errorf("invalid NATS URL %q: scheme must be nats:// or tls://", rawURL)
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	return &NATS{url: u, dialTimeout: 5 * time.Second}, nil
+}
+
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+}
+
+type natsConnect struct {
+	Verbose   bool   `json:"verbose"`
+	Pedantic  bool   `json:"pedantic"`
+	Name      string `json:"name"`
+	Lang      string `json:"lang"`
+	Version   string `json:"version"`
+	User      string `json:"user,omitempty"`
+	Pass      string `json:"pass,omitempty"`
+	AuthToken string `json:"auth_token,omitempty"`
+}
+
+// Publish sends payload on subject. The key is not used by NATS.
+func (n *NATS) Publish(ctx context.Context, subject, key string, payload []byte) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn == nil {
+		if err := n.connect(ctx); err != nil {
+			return err
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		n.conn.SetWriteDeadline(deadline)
+	} else {
+		n.conn.SetWriteDeadline(time.Now().Add(n.dialTimeout))
+	}
+	fmt.Fprintf(n.w, "PUB %s %d\r\n", subject, len(payload))
+	n.w.Write(payload)
+	n.w.WriteString("\r\n")
+	if err := n.w.Flush(); err != nil {
+		n.closeLocked()
+		return fmt.Errorf("nats publish: %w", err)
+	}
+	return nil
+}
+
+// connect dials the server and completes the INFO/CONNECT/PING handshake.
+func (n *NATS) connect(ctx context.Context) error {
+	d := net.Dialer{Timeout: n.dialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", n.url.Host)
+	if err != nil {
+		return fmt.Errorf("nats connect: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(n.dialTimeout))
+	r := bufio.NewReader(conn)
+
+	line, err := r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("nats connect: %w", err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("nats connect: unexpected greeting %q", strings.TrimSpace(line))
+	}
+	var info natsInfo
+	json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info)
+
+	if n.url.Scheme == "tls" || info.TLSRequired {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: n.url.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return fmt.Errorf("nats tls: %w", err)
+		}
+		conn = tlsConn
+		r = bufio.NewReader(conn)
+	}
+
+	opts := natsConnect{Name: "whatsapp-cli", Lang: "go", Version: "1"}
+	if u := n.url.User; u != nil {
+		if pass, ok := u.Password(); ok {
+			opts.User, opts.Pass = u.Username(), pass
+		} else {
+			opts.AuthToken = u.Username()
+		}
+	}
+	connectJSON, _ := json.Marshal(opts)
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "CONNECT %s\r\nPING\r\n", connectJSON)
+	if err := w.Flush(); err != nil {
+		conn.Close()
+		return fmt.Errorf("nats connect: %w", err)
+	}
+	line, err = r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("nats connect: %w", err)
+	}
+	if line = strings.TrimSpace(line); line != "PONG" {
+		conn.Close()
+		return fmt.Errorf("nats connect: %s", line)
+	}
+	conn.SetDeadline(time.Time{})
+
+	n.conn, n.w = conn, w
+	go n.readLoop(conn, r)
+	return nil
+}
+
+// readLoop answers server PINGs so the connection is not dropped as stale,
+// and closes the connection on protocol errors.
+func (n *NATS) readLoop(conn net.Conn, r *bufio.Reader) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "PING"):
+			n.mu.Lock()
+			if n.conn == conn {
+				n.w.WriteString("PONG\r\n")
+				n.w.Flush()
+			}
+			n.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			n.mu.Lock()
+			if n.conn == conn {
+				n.closeLocked()
+			}
+			n.mu.Unlock()
+			return
+		}
+	}
+	n.mu.Lock()
+	if n.conn == conn {
+		n.closeLocked()
+	}
+	n.mu.Unlock()
+}
+
+func (n *NATS) closeLocked() {
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn, n.w = nil, nil
+	}
+}
+
+// Close closes the connection, if any.
+func (n *NATS) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.closeLocked()
+	return nil
+}
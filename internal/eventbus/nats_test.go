@@ -0,0 +1,106 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNATS accepts connections, completes the handshake and reports each
+// CONNECT and PUB it receives.
+func fakeNATS(t *testing.T) (addr string, connects, pubs <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	connectCh := make(chan string, 4)
+	pubCh := make(chan string, 16)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				fmt.Fprint(conn, "INFO {\"server_id\":\"test\"}\r\n")
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch {
+					case strings.HasPrefix(line, "CONNECT "):
+						connectCh <- strings.TrimSpace(strings.TrimPrefix(line, "CONNECT "))
+					case strings.HasPrefix(line, "PING"):
+						fmt.Fprint(conn, "PONG\r\n")
+					case strings.HasPrefix(line, "PUB "):
+						var subject string
+						var size int
+						fmt.Sscanf(line, "PUB %s %d", &subject, &size)
+						payload := make([]byte, size+2)
+						if _, err := io.ReadFull(r, payload); err != nil {
+							return
+						}
+						pubCh <- subject + " " + string(payload[:size])
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), connectCh, pubCh
+}
+
+func TestNATSPublish(t *testing.T) {
+	addr, connects, pubs := fakeNATS(t)
+	n, err := NewNATS("nats://alice:s3cret@" + addr)
+	require.NoError(t, err)
+	defer n.Close()
+
+	require.NoError(t, n.Publish(context.Background(), "whatsapp.messages", "", []byte(`{"a":1}`)))
+	assert.Contains(t, <-connects, `"user":"alice","pass":"s3cret"`)
+	assert.Equal(t, `whatsapp.messages {"a":1}`, <-pubs)
+
+	require.NoError(t, n.Publish(context.Background(), "whatsapp.receipts", "", []byte("x")))
+	assert.Equal(t, "whatsapp.receipts x", <-pubs)
+	assert.Len(t, connects, 0, "connection should be reused")
+}
+
+func TestNATSReconnects(t *testing.T) {
+	addr, connects, pubs := fakeNATS(t)
+	n, err := NewNATS("nats://token@" + addr)
+	require.NoError(t, err)
+	defer n.Close()
+
+	require.NoError(t, n.Publish(context.Background(), "a", "", []byte("1")))
+	assert.Contains(t, <-connects, `"auth_token":"token"`)
+	<-pubs
+
+	// Simulate a dropped connection
+	n.mu.Lock()
+	n.closeLocked()
+	n.mu.Unlock()
+
+	require.NoError(t, n.Publish(context.Background(), "a", "", []byte("2")))
+	select {
+	case <-connects:
+	case <-time.After(time.Second):
+		t.Fatal("expected a new CONNECT")
+	}
+	assert.Equal(t, "a 2", <-pubs)
+}
+
+func TestNewNATSInvalidURL(t *testing.T) {
+	_, err := NewNATS("http://localhost:4222")
+	assert.Error(t, err)
+}
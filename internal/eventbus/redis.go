@@ -0,0 +1,176 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRedisChannel is the channel new messages are published to.
+const DefaultRedisChannel = "whatsapp:messages"
+
+// Redis publishes with PUBLISH over the RESP protocol. It connects lazily and
+// reconnects on the next publish after a failure.
+type Redis struct {
+	url     *url.URL
+	timeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// NewRedis parses a redis:// or rediss:// URL of the form
+// redis://[user:password@]host[:port][/db].
+func NewRedis(rawURL string) (*Redis, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("invalid Redis URL %q: scheme must be redis:// or rediss://", rawURL)
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if _, err := strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid Redis URL %q: database must be a number", rawURL)
+		}
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	return &Redis{url: u, timeout: 5 * time.Second}, nil
+}
+
+// NewRedisNotifier returns a Bus that publishes new-message, digest and
+// account alert events as JSON to channel, or to "<channel>:<chat JID>" when
+// perChat is set. Receipts and presence updates are not published.
+func NewRedisNotifier(rawURL, channel string, perChat bool) (*Bus, error) {
+	pub, err := NewRedis(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if channel == "" {
+		channel = DefaultRedisChannel
+	}
+	b := NewBus(pub, FormatJSON, "")
+	b.only = map[string]bool{TypeMessage: true, TypeDigest: true, TypeAccountAlert: true}
+	b.topic = func(e Event) string {
+		if perChat && e.ChatJID != "" {
+			return channel + ":" + e.ChatJID
+		}
+		return channel
+	}
+	return b, nil
+}
+
+// Publish sends payload to channel. The key is not used by Redis.
+func (r *Redis) Publish(ctx context.Context, channel, key string, payload []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn == nil {
+		if err := r.connect(ctx); err != nil {
+			return err
+		}
+	}
+	if _, err := r.do(ctx, "PUBLISH", channel, string(payload)); err != nil {
+		r.closeLocked()
+		return fmt.Errorf("redis publish: %w", err)
+	}
+	return nil
+}
+
+func (r *Redis) connect(ctx context.Context) error {
+	d := net.Dialer{Timeout: r.timeout}
+	conn, err := d.DialContext(ctx, "tcp", r.url.Host)
+	if err != nil {
+		return fmt.Errorf("redis connect: %w", err)
+	}
+	if r.url.Scheme == "rediss" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: r.url.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return fmt.Errorf("redis tls: %w", err)
+		}
+		conn = tlsConn
+	}
+	r.conn, r.r = conn, bufio.NewReader(conn)
+
+	if u := r.url.User; u != nil {
+		args := []string{"AUTH"}
+		if pass, ok := u.Password(); ok {
+			if u.Username() != "" {
+				args = append(args, u.Username())
+			}
+			args = append(args, pass)
+		} else {
+			args = append(args, u.Username())
+		}
+		if _, err := r.do(ctx, args...); err != nil {
+			r.closeLocked()
+			return fmt.Errorf("redis auth: %w", err)
+		}
+	}
+	if db := strings.Trim(r.url.Path, "/"); db != "" && db != "0" {
+		if _, err := r.do(ctx, "SELECT", db); err != nil {
+			r.closeLocked()
+			return fmt.Errorf("redis select: %w", err)
+		}
+	}
+	return nil
+}
+
+// do sends a command and reads a single simple, integer or error reply.
+func (r *Redis) do(ctx context.Context, args ...string) (string, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(r.timeout)
+	}
+	r.conn.SetDeadline(deadline)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := r.conn.Write([]byte(b.String())); err != nil {
+		return "", err
+	}
+	line, err := r.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return "", fmt.Errorf("empty reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", fmt.Errorf("%s", line[1:])
+	default:
+		return "", fmt.Errorf("unexpected reply %q", line)
+	}
+}
+
+func (r *Redis) closeLocked() {
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn, r.r = nil, nil
+	}
+}
+
+// Close closes the connection, if any.
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closeLocked()
+	return nil
+}
@@ -0,0 +1,137 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis records each command it receives, replying -ERR to PUBLISH on
+// channels starting with "fail".
+func fakeRedis(t *testing.T) (addr string, cmds <-chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	ch := make(chan []string, 16)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					var n int
+					if _, err := fmt.Fscanf(r, "*%d\r\n", &n); err != nil {
+						return
+					}
+					args := make([]string, n)
+					for i := range args {
+						var size int
+						if _, err := fmt.Fscanf(r, "$%d\r\n", &size); err != nil {
+							return
+						}
+						buf := make([]byte, size+2)
+						if _, err := io.ReadFull(r, buf); err != nil {
+							return
+						}
+						args[i] = string(buf[:size])
+					}
+					ch <- args
+					if args[0] == "PUBLISH" && strings.HasPrefix(args[1], "fail") {
+						fmt.Fprint(conn, "-ERR nope\r\n")
+					} else if args[0] == "PUBLISH" {
+						fmt.Fprint(conn, ":1\r\n")
+					} else {
+						fmt.Fprint(conn, "+OK\r\n")
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), ch
+}
+
+func TestRedisPublish(t *testing.T) {
+	addr, cmds := fakeRedis(t)
+	r, err := NewRedis("redis://:s3cret@" + addr + "/2")
+	require.NoError(t, err)
+	defer r.Close()
+
+	require.NoError(t, r.Publish(context.Background(), "whatsapp:messages", "", []byte(`{"a":1}`)))
+	assert.Equal(t, []string{"AUTH", "s3cret"}, <-cmds)
+	assert.Equal(t, []string{"SELECT", "2"}, <-cmds)
+	assert.Equal(t, []string{"PUBLISH", "whatsapp:messages", `{"a":1}`}, <-cmds)
+
+	err = r.Publish(context.Background(), "fail", "", []byte("x"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nope")
+	<-cmds
+
+	// The failed publish dropped the connection, so the next one reconnects
+	require.NoError(t, r.Publish(context.Background(), "whatsapp:messages", "", []byte("y")))
+	assert.Equal(t, []string{"AUTH", "s3cret"}, <-cmds)
+}
+
+func TestRedisNotifierPerChat(t *testing.T) {
+	addr, cmds := fakeRedis(t)
+	b, err := NewRedisNotifier("redis://"+addr, "", true)
+	require.NoError(t, err)
+	defer b.Close()
+	runBus(t, b)
+
+	b.Publish(Event{Type: TypeReceipt, ChatJID: "c1", Receipt: &Receipt{Type: "read"}})
+	b.Publish(Event{Type: TypeMessage, ChatJID: "c1", Timestamp: testTime, Message: &Message{ID: "M1", Content: "hi"}})
+
+	select {
+	case cmd := <-cmds:
+		require.Len(t, cmd, 3)
+		assert.Equal(t, "whatsapp:messages:c1", cmd[1])
+		var e Event
+		require.NoError(t, json.Unmarshal([]byte(cmd[2]), &e))
+		assert.Equal(t, "M1", e.Message.ID)
+	case <-time.After(time.Second):
+		t.Fatal("no PUBLISH received")
+	}
+	assert.Len(t, cmds, 0, "receipts are not published")
+}
+
+func TestRedisNotifierPublishesDigests(t *testing.T) {
+	addr, cmds := fakeRedis(t)
+	b, err := NewRedisNotifier("redis://"+addr, "", false)
+	require.NoError(t, err)
+	defer b.Close()
+	runBus(t, b)
+
+	b.Publish(Event{Type: TypeDigest, ChatJID: "c1", Timestamp: testTime, Digest: json.RawMessage(`{"period":"daily","messages":3}`)})
+
+	select {
+	case cmd := <-cmds:
+		require.Len(t, cmd, 3)
+		assert.Equal(t, "whatsapp:messages", cmd[1])
+		assert.Contains(t, cmd[2], `"type":"digest"`)
+		assert.Contains(t, cmd[2], `"digest":{"period":"daily","messages":3}`)
+	case <-time.After(time.Second):
+		t.Fatal("no PUBLISH received")
+	}
+}
+
+func TestNewRedisInvalidURL(t *testing.T) {
+	for _, u := range []string{"http://localhost", "redis://localhost/x"} {
+		_, err := NewRedis(u)
+		assert.Error(t, err, u)
+	}
+}
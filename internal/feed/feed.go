@@ -0,0 +1,238 @@
+// Package feed reads RSS 2.0, RSS 1.0 (RDF) and Atom feeds.
+package feed
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Feed is a parsed feed. Items are in document order, which for almost
+// every feed is newest first.
+type Feed struct {
+	Title string
+	Items []Item
+}
+
+// Item is an entry of a feed.
+type Item struct {
+	// ID identifies the item across fetches: its GUID or Atom ID, else its
+	// link, else its title.
+	ID    string
+	Title string
+	Link  string
+	// Image is the URL of the item's image, if it has one: an image
+	// enclosure, a Media RSS thumbnail or content, or the first image of
+	// its HTML.
+	Image string
+}
+
+type mediaRef struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Medium string `xml:"medium,attr"`
+}
+
+type rssItem struct {
+	Title       string     `xml:"title"`
+	Link        string     `xml:"link"`
+	GUID        string     `xml:"guid"`
+	About       string     `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Description string     `xml:"description"`
+	Content     string     `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Enclosures  []mediaRef `xml:"enclosure"`
+	Thumbnails  []mediaRef `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	Media       []mediaRef `xml:"http://search.yahoo.com/mrss/ content"`
+	Groups      []struct {
+		Thumbnails []mediaRef `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+		Media      []mediaRef `xml:"http://search.yahoo.com/mrss/ content"`
+	} `xml:"http://search.yahoo.com/mrss/ group"`
+}
+
+type rssDoc struct {
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	// RSS 1.0 items are siblings of the channel
+	Items []rssItem `xml:"item"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type atomDoc struct {
+	Title   string `xml:"title"`
+	Entries []struct {
+		ID         string     `xml:"id"`
+		Title      string     `xml:"title"`
+		Links      []atomLink `xml:"link"`
+		Summary    string     `xml:"summary"`
+		Content    string     `xml:"content"`
+		Thumbnails []mediaRef `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+		Media      []mediaRef `xml:"http://search.yahoo.com/mrss/ content"`
+		Groups     []struct {
+			Thumbnails []mediaRef `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+		} `xml:"http://search.yahoo.com/mrss/ group"`
+	} `xml:"entry"`
+}
+
+// Parse reads a feed, detecting its format from the root element.
+func Parse(r io.Reader) (*Feed, error) {
+	d := xml.NewDecoder(bufio.NewReader(r))
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	d.CharsetReader = charsetReader
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("not a feed: no root element")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid feed: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(start.Name.Local) {
+		case "rss", "rdf":
+			var doc rssDoc
+			if err := d.DecodeElement(&doc, &start); err != nil {
+				return nil, fmt.Errorf("invalid RSS feed: %w", err)
+			}
+			return doc.feed(), nil
+		case "feed":
+			var doc atomDoc
+			if err := d.DecodeElement(&doc, &start); err != nil {
+				return nil, fmt.Errorf("invalid Atom feed: %w", err)
+			}
+			return doc.feed(), nil
+		default:
+			return nil, fmt.Errorf("not a feed: root element <%s>", start.Name.Local)
+		}
+	}
+}
+
+func (doc rssDoc) feed() *Feed {
+	f := &Feed{Title: text(doc.Channel.Title)}
+	for _, it := range append(doc.Channel.Items, doc.Items...) {
+		item := Item{
+			ID:    firstNonEmpty(it.GUID, it.About, it.Link, it.Title),
+			Title: text(it.Title),
+			Link:  strings.TrimSpace(it.Link),
+		}
+		refs := append(append([]mediaRef{}, it.Enclosures...), it.Thumbnails...)
+		refs = append(refs, it.Media...)
+		for _, g := range it.Groups {
+			refs = append(append(refs, g.Thumbnails...), g.Media...)
+		}
+		item.Image = firstImage(refs, it.Content, it.Description)
+		f.Items = append(f.Items, item)
+	}
+	return f
+}
+
+func (doc atomDoc) feed() *Feed {
+	f := &Feed{Title: text(doc.Title)}
+	for _, e := range doc.Entries {
+		item := Item{Title: text(e.Title)}
+		var refs []mediaRef
+		for _, l := range e.Links {
+			switch l.Rel {
+			case "", "alternate":
+				if item.Link == "" {
+					item.Link = strings.TrimSpace(l.Href)
+				}
+			case "enclosure":
+				refs = append(refs, mediaRef{URL: l.Href, Type: l.Type})
+			}
+		}
+		refs = append(append(refs, e.Thumbnails...), e.Media...)
+		for _, g := range e.Groups {
+			refs = append(refs, g.Thumbnails...)
+		}
+		item.ID = firstNonEmpty(e.ID, item.Link, e.Title)
+		item.Image = firstImage(refs, e.Content, e.Summary)
+		f.Items = append(f.Items, item)
+	}
+	return f
+}
+
+var (
+	imgSrc  = regexp.MustCompile(`(?i)<img[^>]+src=["']([^"']+)["']`)
+	htmlTag = regexp.MustCompile(`<[^>]*>`)
+)
+
+// firstImage returns the first media reference that is an image, else the
+// first image of the HTML bodies.
+func firstImage(refs []mediaRef, bodies ...string) string {
+	for _, r := range refs {
+		if r.URL != "" && (strings.HasPrefix(r.Type, "image/") || r.Medium == "image" || (r.Type == "" && r.Medium == "")) {
+			return strings.TrimSpace(r.URL)
+		}
+	}
+	for _, b := range bodies {
+		if m := imgSrc.FindStringSubmatch(b); m != nil {
+			return html.UnescapeString(m[1])
+		}
+	}
+	return ""
+}
+
+// text cleans up a title: feeds often escape HTML twice, and some include
+// tags.
+func text(s string) string {
+	s = html.UnescapeString(s)
+	s = htmlTag.ReplaceAllString(s, "")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// charsetReader decodes the single-byte charsets feeds still use besides
+// UTF-8.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "latin-1", "windows-1252", "cp1252", "us-ascii":
+		return &latin1Reader{r: bufio.NewReader(input)}, nil
+	}
+	return nil, fmt.Errorf("unsupported charset %q", charset)
+}
+
+// latin1Reader converts ISO-8859-1 to UTF-8.
+type latin1Reader struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	for len(l.buf) < len(p) {
+		b, err := l.r.ReadByte()
+		if err != nil {
+			if len(l.buf) == 0 {
+				return 0, err
+			}
+			break
+		}
+		l.buf = utf8.AppendRune(l.buf, rune(b))
+	}
+	n := copy(p, l.buf)
+	l.buf = l.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,77 @@
+package feed
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRSS(t *testing.T) {
+	f, err := Parse(strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:media="http://search.yahoo.com/mrss/" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+<channel>
+  <title>Bakery news</title>
+  <item>
+    <title>New &amp;amp; improved croissants</title>
+    <link>https://bakery.example.com/croissants</link>
+    <guid isPermaLink="false">post-2</guid>
+    <media:thumbnail url="https://bakery.example.com/croissant.jpg"/>
+  </item>
+  <item>
+    <title>Opening hours</title>
+    <link>https://bakery.example.com/hours</link>
+    <enclosure url="https://bakery.example.com/hours.mp3" type="audio/mpeg" length="1"/>
+    <content:encoded><![CDATA[<p>Hi</p><img src="https://bakery.example.com/shop.png">]]></content:encoded>
+  </item>
+  <item>
+    <title>No image</title>
+    <link>https://bakery.example.com/plain</link>
+  </item>
+</channel>
+</rss>`))
+	require.NoError(t, err)
+	assert.Equal(t, "Bakery news", f.Title)
+	assert.Equal(t, []Item{
+		{ID: "post-2", Title: "New & improved croissants", Link: "https://bakery.example.com/croissants", Image: "https://bakery.example.com/croissant.jpg"},
+		{ID: "https://bakery.example.com/hours", Title: "Opening hours", Link: "https://bakery.example.com/hours", Image: "https://bakery.example.com/shop.png"},
+		{ID: "https://bakery.example.com/plain", Title: "No image", Link: "https://bakery.example.com/plain"},
+	}, f.Items)
+}
+
+func TestParseAtom(t *testing.T) {
+	f, err := Parse(strings.NewReader(`<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title type="text">Release notes</title>
+  <entry>
+    <id>tag:example.com,2026:1</id>
+    <title type="html">v1.2 &lt;em&gt;released&lt;/em&gt;</title>
+    <link rel="alternate" href="https://example.com/v1.2"/>
+    <link rel="enclosure" type="image/png" href="https://example.com/v1.2.png"/>
+  </entry>
+</feed>`))
+	require.NoError(t, err)
+	assert.Equal(t, "Release notes", f.Title)
+	require.Len(t, f.Items, 1)
+	assert.Equal(t, Item{ID: "tag:example.com,2026:1", Title: "v1.2 released", Link: "https://example.com/v1.2", Image: "https://example.com/v1.2.png"}, f.Items[0])
+}
+
+func TestParseRDFAndLatin1(t *testing.T) {
+	f, err := Parse(strings.NewReader("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n" +
+		`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/">` +
+		`<channel><title>Caf` + "\xe9" + `</title></channel>` +
+		`<item rdf:about="https://example.com/1"><title>One</title><link>https://example.com/1</link></item>` +
+		`</rdf:RDF>`))
+	require.NoError(t, err)
+	assert.Equal(t, "Café", f.Title)
+	require.Len(t, f.Items, 1)
+	assert.Equal(t, "https://example.com/1", f.Items[0].ID)
+}
+
+func TestParseRejectsOtherDocuments(t *testing.T) {
+	_, err := Parse(strings.NewReader(`<html><body>hi</body></html>`))
+	assert.ErrorContains(t, err, "not a feed")
+	_, err = Parse(strings.NewReader(``))
+	assert.Error(t, err)
+}
@@ -0,0 +1,235 @@
+// Package health models the lifecycle of the daemon as one state machine,
+// so readiness probes, the status endpoint, metrics and logs all report the
+// same state.
+package health
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// State is where the daemon is in its lifecycle.
+type State string
+
+// States, in the order a healthy daemon passes through them.
+const (
+	// Starting: the session is paired but the sync loop is not running or
+	// not connected yet.
+	Starting State = "starting"
+	// Pairing: the account is not linked; a QR code is waiting to be scanned.
+	Pairing State = "pairing"
+	// Backfilling: connected and catching up on the messages received while
+	// the daemon was away, or on the history sent after pairing.
+	Backfilling State = "backfilling"
+	// Live: connected and caught up.
+	Live State = "live"
+	// Degraded: paired and syncing, but disconnected from WhatsApp or
+	// failing its canary, so messages may not be delivered.
+	Degraded State = "degraded"
+	// LoggedOut: WhatsApp logged the session out, banned the account or
+	// refused the client; it cannot send until an operator acts.
+	LoggedOut State = "logged_out"
+)
+
+// States lists every state.
+var States = []State{Starting, Pairing, Backfilling, Live, Degraded, LoggedOut}
+
+// DefaultReady are the states in which the daemon reports ready. Catching
+// up after a reconnect counts as ready, as it did before the state machine,
+// so a brief outage does not take the daemon out of rotation.
+var DefaultReady = []State{Backfilling, Live}
+
+// ParseStates parses a comma-separated list of state names.
+func ParseStates(s string) ([]State, error) {
+	var states []State
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		state, ok := parseState(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown state %q (must be one of %s)", name, joinStates(States))
+		}
+		states = append(states, state)
+	}
+	if len(states) == 0 {
+		return nil, fmt.Errorf("at least one state is required")
+	}
+	return states, nil
+}
+
+func parseState(name string) (State, bool) {
+	for _, s := range States {
+		if string(s) == name {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+func joinStates(states []State) string {
+	names := make([]string, len(states))
+	for i, s := range states {
+		names[i] = string(s)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Connection is what the sync loop knows about its connection to WhatsApp.
+type Connection string
+
+const (
+	// ConnectionUnknown means no sync loop reports on the connection; it is
+	// not held against the daemon.
+	ConnectionUnknown Connection = ""
+	// ConnectionConnecting: the sync loop has not connected yet.
+	ConnectionConnecting Connection = "connecting"
+	// ConnectionCatchingUp: connected, receiving what was missed.
+	ConnectionCatchingUp Connection = "catching_up"
+	ConnectionOnline     Connection = "online"
+	ConnectionOffline    Connection = "offline"
+)
+
+// Signals are the facts the state is derived from.
+type Signals struct {
+	Paired bool
+	// Pairing is set while a QR code flow is running.
+	Pairing bool
+	// Syncing is set while the sync loop runs.
+	Syncing    bool
+	Connection Connection
+	// AccountAlert is the kind of the active critical account alert, e.g.
+	// "logged_out" or "temporary_ban", and AlertReason what WhatsApp said.
+	AccountAlert string
+	AlertReason  string
+	// CanaryError is why the last canary check failed; empty when it passed
+	// or no canary runs.
+	CanaryError string
+}
+
+// Evaluate derives the state of the daemon from sig, with the reason it is
+// not live.
+func Evaluate(sig Signals) (State, string) {
+	switch {
+	case sig.AccountAlert != "":
+		return LoggedOut, "account " + strings.ReplaceAll(sig.AccountAlert, "_", " ")
+	case !sig.Paired && sig.Pairing:
+		return Pairing, "not authenticated"
+	case !sig.Paired:
+		return Starting, "not authenticated"
+	case !sig.Syncing:
+		return Starting, "not syncing"
+	case sig.Connection == ConnectionConnecting:
+		return Starting, "connecting"
+	case sig.Connection == ConnectionCatchingUp:
+		return Backfilling, "catching up"
+	case sig.Connection == ConnectionOffline:
+		return Degraded, "disconnected"
+	case sig.CanaryError != "":
+		return Degraded, "canary failed"
+	}
+	return Live, ""
+}
+
+// Status is the current state of the daemon.
+type Status struct {
+	State State `json:"state"`
+	// Reason says why the daemon is not live; empty when it is.
+	Reason string    `json:"reason,omitempty"`
+	Since  time.Time `json:"since"`
+	Ready  bool      `json:"ready"`
+}
+
+// Machine holds the signals of the daemon and the state derived from them,
+// and reports every change of state.
+type Machine struct {
+	ready map[State]bool
+
+	mu          sync.Mutex
+	signals     Signals
+	status      Status
+	transitions map[State]int64
+	onChange    func(from, to Status)
+}
+
+// NewMachine returns a machine in the starting state that reports ready in
+// the given states.
+func NewMachine(ready []State) *Machine {
+	m := &Machine{
+		ready:       map[State]bool{},
+		transitions: map[State]int64{},
+	}
+	for _, s := range ready {
+		m.ready[s] = true
+	}
+	m.status = Status{State: Starting, Reason: "not authenticated", Since: time.Now().UTC(), Ready: m.ready[Starting]}
+	return m
+}
+
+// OnChange calls f, synchronously, whenever the state changes.
+func (m *Machine) OnChange(f func(from, to Status)) {
+	m.mu.Lock()
+	m.onChange = f
+	m.mu.Unlock()
+}
+
+// Update applies f to the signals and re-derives the state.
+func (m *Machine) Update(f func(sig *Signals)) Status {
+	m.mu.Lock()
+	f(&m.signals)
+	state, reason := Evaluate(m.signals)
+	from := m.status
+	if state == from.State {
+		m.status.Reason = reason
+		status := m.status
+		m.mu.Unlock()
+		return status
+	}
+	m.status = Status{State: state, Reason: reason, Since: time.Now().UTC(), Ready: m.ready[state]}
+	m.transitions[state]++
+	to, onChange := m.status, m.onChange
+	m.mu.Unlock()
+	if onChange != nil {
+		onChange(from, to)
+	}
+	return to
+}
+
+// Status returns the current state.
+func (m *Machine) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status
+}
+
+// Signals returns the signals the current state was derived from.
+func (m *Machine) Signals() Signals {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.signals
+}
+
+// ReadyStates returns the states in which the machine reports ready.
+func (m *Machine) ReadyStates() []State {
+	var states []State
+	for _, s := range States {
+		if m.ready[s] {
+			states = append(states, s)
+		}
+	}
+	return states
+}
+
+// Transitions returns how often each state was entered since startup.
+func (m *Machine) Transitions() map[State]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[State]int64, len(m.transitions))
+	for s, n := range m.transitions {
+		out[s] = n
+	}
+	return out
+}
@@ -0,0 +1,81 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluate(t *testing.T) {
+	live := Signals{Paired: true, Syncing: true, Connection: ConnectionOnline}
+	for name, tc := range map[string]struct {
+		sig    Signals
+		state  State
+		reason string
+	}{
+		"fresh":         {Signals{}, Starting, "not authenticated"},
+		"qr code shown": {Signals{Pairing: true}, Pairing, "not authenticated"},
+		"paired":        {Signals{Paired: true}, Starting, "not syncing"},
+		"connecting":    {Signals{Paired: true, Syncing: true, Connection: ConnectionConnecting}, Starting, "connecting"},
+		"catching up":   {Signals{Paired: true, Syncing: true, Connection: ConnectionCatchingUp}, Backfilling, "catching up"},
+		"live":          {live, Live, ""},
+		"unknown conn":  {Signals{Paired: true, Syncing: true}, Live, ""},
+		"disconnected":  {Signals{Paired: true, Syncing: true, Connection: ConnectionOffline}, Degraded, "disconnected"},
+		"canary":        {Signals{Paired: true, Syncing: true, Connection: ConnectionOnline, CanaryError: "timeout"}, Degraded, "canary failed"},
+		"banned":        {Signals{Paired: true, Syncing: true, AccountAlert: "temporary_ban"}, LoggedOut, "account temporary ban"},
+		"logged out":    {Signals{AccountAlert: "logged_out"}, LoggedOut, "account logged out"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			state, reason := Evaluate(tc.sig)
+			assert.Equal(t, tc.state, state)
+			assert.Equal(t, tc.reason, reason)
+		})
+	}
+}
+
+func TestParseStates(t *testing.T) {
+	states, err := ParseStates("live, degraded,")
+	require.NoError(t, err)
+	assert.Equal(t, []State{Live, Degraded}, states)
+
+	_, err = ParseStates("live,ready")
+	assert.ErrorContains(t, err, `unknown state "ready"`)
+	_, err = ParseStates(" , ")
+	assert.Error(t, err)
+}
+
+func TestMachine(t *testing.T) {
+	m := NewMachine([]State{Live, Degraded})
+	assert.Equal(t, Starting, m.Status().State)
+	assert.False(t, m.Status().Ready)
+
+	var changes [][2]State
+	m.OnChange(func(from, to Status) { changes = append(changes, [2]State{from.State, to.State}) })
+
+	m.Update(func(sig *Signals) { sig.Pairing = true })
+	m.Update(func(sig *Signals) { sig.Paired, sig.Pairing = true, false })
+	m.Update(func(sig *Signals) { sig.Syncing, sig.Connection = true, ConnectionCatchingUp })
+	status := m.Update(func(sig *Signals) { sig.Connection = ConnectionOnline })
+	assert.Equal(t, Live, status.State)
+	assert.True(t, status.Ready)
+	since := status.Since
+
+	// Changing only the reason is not a transition
+	m.Update(func(sig *Signals) { sig.Connection = ConnectionOffline })
+	status = m.Update(func(sig *Signals) { sig.CanaryError = "timeout" })
+	assert.Equal(t, Status{State: Degraded, Reason: "disconnected", Since: status.Since, Ready: true}, status)
+	assert.False(t, status.Since.Before(since))
+	status = m.Update(func(sig *Signals) { sig.Connection = ConnectionOnline })
+	assert.Equal(t, "canary failed", status.Reason)
+
+	assert.Equal(t, [][2]State{
+		{Starting, Pairing},
+		{Pairing, Starting},
+		{Starting, Backfilling},
+		{Backfilling, Live},
+		{Live, Degraded},
+	}, changes)
+	assert.Equal(t, map[State]int64{Pairing: 1, Starting: 1, Backfilling: 1, Live: 1, Degraded: 1}, m.Transitions())
+	assert.Equal(t, []State{Live, Degraded}, m.ReadyStates())
+}
@@ -0,0 +1,193 @@
+package health
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// QualityWindow is how far back connection quality is reported.
+const QualityWindow = 24 * time.Hour
+
+// maxSamples bounds the round trips kept per kind; beyond it the oldest
+// are dropped even if they are inside the window.
+const maxSamples = 10000
+
+// Latency summarizes the round trips of one kind, in seconds. Percentiles
+// cover the successful ones only.
+type Latency struct {
+	Samples  int     `json:"samples"`
+	Failures int     `json:"failures"`
+	Last     float64 `json:"last_seconds"`
+	Mean     float64 `json:"mean_seconds"`
+	P50      float64 `json:"p50_seconds"`
+	P95      float64 `json:"p95_seconds"`
+	Max      float64 `json:"max_seconds"`
+}
+
+// QualityReport is how the connection to WhatsApp fared over the last
+// QualityWindow. Slow or failing pings with fast sends point at the local
+// network; fast pings with slow or failing sends point at WhatsApp.
+type QualityReport struct {
+	WindowSeconds float64 `json:"window_seconds"`
+	// Send covers text messages, from handing them to WhatsApp until it
+	// acknowledged them.
+	Send Latency `json:"send"`
+	// Ping covers the keepalive queries sent to WhatsApp's servers.
+	Ping Latency `json:"ping"`
+	// KeepaliveTimeouts counts WhatsApp's own keepalives that went
+	// unanswered.
+	KeepaliveTimeouts int `json:"keepalive_timeouts"`
+	Disconnects       int `json:"disconnects"`
+	// OfflineSeconds is how long the connection was down, including an
+	// outage still going on.
+	OfflineSeconds float64    `json:"offline_seconds"`
+	OfflineSince   *time.Time `json:"offline_since,omitempty"`
+}
+
+type sample struct {
+	at  time.Time
+	rtt time.Duration
+	ok  bool
+}
+
+type outage struct {
+	from, to time.Time
+}
+
+// Quality records round trips, keepalive timeouts and outages of the
+// connection to WhatsApp and reports on the last QualityWindow of them.
+type Quality struct {
+	mu                sync.Mutex
+	send, ping        []sample
+	keepaliveTimeouts []time.Time
+	outages           []outage
+	offlineSince      time.Time
+}
+
+// Send records a message send that took rtt, or failed.
+func (q *Quality) Send(at time.Time, rtt time.Duration, ok bool) {
+	q.mu.Lock()
+	q.send = appendSample(q.send, sample{at, rtt, ok})
+	q.mu.Unlock()
+}
+
+// Ping records a ping that took rtt, or went unanswered.
+func (q *Quality) Ping(at time.Time, rtt time.Duration, ok bool) {
+	q.mu.Lock()
+	q.ping = appendSample(q.ping, sample{at, rtt, ok})
+	q.mu.Unlock()
+}
+
+// KeepaliveTimeout records an unanswered keepalive of WhatsApp's.
+func (q *Quality) KeepaliveTimeout(at time.Time) {
+	q.mu.Lock()
+	q.keepaliveTimeouts = append(q.keepaliveTimeouts, at)
+	q.mu.Unlock()
+}
+
+// Disconnected records that the connection went down; it counts as offline
+// until Connected.
+func (q *Quality) Disconnected(at time.Time) {
+	q.mu.Lock()
+	if q.offlineSince.IsZero() {
+		q.offlineSince = at
+	}
+	q.mu.Unlock()
+}
+
+// Connected ends the outage that began with Disconnected, if any.
+func (q *Quality) Connected(at time.Time) {
+	q.mu.Lock()
+	if !q.offlineSince.IsZero() {
+		q.outages = append(q.outages, outage{q.offlineSince, at})
+		q.offlineSince = time.Time{}
+	}
+	q.mu.Unlock()
+}
+
+// Report summarizes the QualityWindow before now, forgetting what happened
+// before it.
+func (q *Quality) Report(now time.Time) QualityReport {
+	since := now.Add(-QualityWindow)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.send, q.ping = pruneSamples(q.send, since), pruneSamples(q.ping, since)
+	for len(q.keepaliveTimeouts) > 0 && q.keepaliveTimeouts[0].Before(since) {
+		q.keepaliveTimeouts = q.keepaliveTimeouts[1:]
+	}
+	for len(q.outages) > 0 && q.outages[0].to.Before(since) {
+		q.outages = q.outages[1:]
+	}
+
+	r := QualityReport{
+		WindowSeconds:     QualityWindow.Seconds(),
+		Send:              summarize(q.send),
+		Ping:              summarize(q.ping),
+		KeepaliveTimeouts: len(q.keepaliveTimeouts),
+		Disconnects:       len(q.outages),
+	}
+	outages := q.outages
+	if !q.offlineSince.IsZero() {
+		outages = append(outages[:len(outages):len(outages)], outage{q.offlineSince, now})
+		offlineSince := q.offlineSince
+		r.OfflineSince = &offlineSince
+		r.Disconnects++
+	}
+	for _, o := range outages {
+		from := o.from
+		if from.Before(since) {
+			from = since
+		}
+		r.OfflineSeconds += o.to.Sub(from).Seconds()
+	}
+	return r
+}
+
+func appendSample(samples []sample, s sample) []sample {
+	if len(samples) >= maxSamples {
+		samples = samples[1:]
+	}
+	return append(samples, s)
+}
+
+func pruneSamples(samples []sample, since time.Time) []sample {
+	i := 0
+	for i < len(samples) && samples[i].at.Before(since) {
+		i++
+	}
+	return samples[i:]
+}
+
+func summarize(samples []sample) Latency {
+	l := Latency{Samples: len(samples)}
+	var rtts []time.Duration
+	var total time.Duration
+	for _, s := range samples {
+		if !s.ok {
+			l.Failures++
+			continue
+		}
+		rtts = append(rtts, s.rtt)
+		total += s.rtt
+		l.Last = s.rtt.Seconds()
+	}
+	if len(rtts) == 0 {
+		return l
+	}
+	sort.Slice(rtts, func(i, j int) bool { return rtts[i] < rtts[j] })
+	l.Mean = (total / time.Duration(len(rtts))).Seconds()
+	l.P50 = percentile(rtts, 50).Seconds()
+	l.P95 = percentile(rtts, 95).Seconds()
+	l.Max = rtts[len(rtts)-1].Seconds()
+	return l
+}
+
+// percentile returns the nearest-rank percentile p of sorted.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
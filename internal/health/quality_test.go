@@ -0,0 +1,62 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQualityLatency(t *testing.T) {
+	var q Quality
+	now := time.Now()
+	for i := 1; i <= 20; i++ {
+		q.Ping(now.Add(-time.Duration(21-i)*time.Minute), time.Duration(i)*10*time.Millisecond, true)
+	}
+	q.Ping(now, 0, false)
+	q.Send(now.Add(-QualityWindow-time.Minute), time.Minute, true)
+	q.Send(now, 300*time.Millisecond, true)
+
+	r := q.Report(now)
+	assert.Equal(t, QualityWindow.Seconds(), r.WindowSeconds)
+	assert.Equal(t, 21, r.Ping.Samples)
+	assert.Equal(t, 1, r.Ping.Failures)
+	assert.InDelta(t, 0.2, r.Ping.Last, 1e-9, "failures have no round trip")
+	assert.InDelta(t, 0.105, r.Ping.Mean, 1e-9)
+	assert.InDelta(t, 0.1, r.Ping.P50, 1e-9)
+	assert.InDelta(t, 0.19, r.Ping.P95, 1e-9)
+	assert.InDelta(t, 0.2, r.Ping.Max, 1e-9)
+
+	assert.Equal(t, 1, r.Send.Samples, "sends older than the window are forgotten")
+	assert.InDelta(t, 0.3, r.Send.Max, 1e-9)
+}
+
+func TestQualityOutages(t *testing.T) {
+	var q Quality
+	now := time.Now()
+	q.Connected(now.Add(-30 * time.Hour))
+
+	// Half of this outage is inside the window
+	q.Disconnected(now.Add(-QualityWindow - time.Hour))
+	q.Connected(now.Add(-QualityWindow + time.Hour))
+	q.Disconnected(now.Add(-2 * time.Hour))
+	q.Disconnected(now.Add(-time.Hour))
+	q.Connected(now.Add(-90 * time.Minute).Add(time.Hour))
+	q.KeepaliveTimeout(now.Add(-QualityWindow - time.Second))
+	q.KeepaliveTimeout(now.Add(-time.Minute))
+
+	r := q.Report(now)
+	assert.Equal(t, 2, r.Disconnects)
+	assert.InDelta(t, (time.Hour + 90*time.Minute).Seconds(), r.OfflineSeconds, 1e-6)
+	assert.Nil(t, r.OfflineSince)
+	assert.Equal(t, 1, r.KeepaliveTimeouts)
+
+	// An outage still going on counts up to now
+	q.Disconnected(now.Add(-10 * time.Minute))
+	r = q.Report(now)
+	assert.Equal(t, 3, r.Disconnects)
+	assert.InDelta(t, (time.Hour + 100*time.Minute).Seconds(), r.OfflineSeconds, 1e-6)
+	require.NotNil(t, r.OfflineSince)
+	assert.Equal(t, now.Add(-10*time.Minute), *r.OfflineSince)
+}
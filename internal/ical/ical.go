@@ -0,0 +1,386 @@
+// Package ical reads the events of an iCalendar (RFC 5545) feed, as served
+// by calendar apps and CalDAV servers as ICS exports.
+//
+// Recurring events are expanded for FREQ, INTERVAL, COUNT and UNTIL, and for
+// BYDAY in weekly rules; EXDATE and RECURRENCE-ID overrides are honoured.
+// Rules using other parts only yield their first occurrence.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxOccurrences bounds the expansion of a recurring event.
+const maxOccurrences = 100000
+
+// Event is a VEVENT, or one occurrence of it as returned by Between.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Status      string
+	Start       time.Time
+	End         time.Time
+	// AllDay is set for events with a DATE rather than a DATE-TIME start.
+	AllDay bool
+	// RecurrenceID is set for an event overriding one occurrence of a
+	// recurring event with the same UID.
+	RecurrenceID time.Time
+
+	rule    *rule
+	exdates map[int64]bool
+}
+
+// Cancelled reports whether the event was cancelled.
+func (e Event) Cancelled() bool {
+	return strings.EqualFold(e.Status, "CANCELLED")
+}
+
+type rule struct {
+	freq     string
+	interval int
+	count    int
+	until    time.Time
+	byDay    []time.Weekday
+}
+
+// Parse reads the events of a calendar. Floating times and dates, and times
+// in unknown time zones, are read in loc.
+func Parse(r io.Reader, loc *time.Location) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	var cur *Event
+	var duration time.Duration
+	depth := 0
+	for _, line := range lines {
+		name, params, value := parseLine(line)
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			cur = &Event{}
+			duration, depth = 0, 0
+			continue
+		case cur == nil:
+			continue
+		case name == "BEGIN":
+			// Nested components, e.g. VALARM, are skipped
+			depth++
+			continue
+		case name == "END" && depth > 0:
+			depth--
+			continue
+		case depth > 0:
+			continue
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if cur.Start.IsZero() {
+				return nil, fmt.Errorf("event %q has no DTSTART", cur.UID)
+			}
+			if cur.End.IsZero() {
+				cur.End = cur.Start.Add(duration)
+			}
+			if cur.AllDay && cur.End.Equal(cur.Start) {
+				cur.End = cur.Start.AddDate(0, 0, 1)
+			}
+			events = append(events, *cur)
+			cur = nil
+			continue
+		}
+		switch name {
+		case "UID":
+			cur.UID = value
+		case "SUMMARY":
+			cur.Summary = unescape(value)
+		case "DESCRIPTION":
+			cur.Description = unescape(value)
+		case "LOCATION":
+			cur.Location = unescape(value)
+		case "STATUS":
+			cur.Status = strings.ToUpper(value)
+		case "DTSTART":
+			cur.Start, cur.AllDay, err = parseTime(value, params, loc)
+		case "DTEND":
+			cur.End, _, err = parseTime(value, params, loc)
+		case "DURATION":
+			duration, err = parseDuration(value)
+		case "RECURRENCE-ID":
+			cur.RecurrenceID, _, err = parseTime(value, params, loc)
+		case "RRULE":
+			cur.rule = parseRule(value, loc)
+		case "EXDATE":
+			for _, v := range strings.Split(value, ",") {
+				var t time.Time
+				if t, _, err = parseTime(v, params, loc); err != nil {
+					break
+				}
+				if cur.exdates == nil {
+					cur.exdates = make(map[int64]bool)
+				}
+				cur.exdates[t.Unix()] = true
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", name, value, err)
+		}
+	}
+	return events, nil
+}
+
+// Between returns the occurrences of events starting in [from, to), sorted
+// by start. Cancelled occurrences are left out.
+func Between(events []Event, from, to time.Time) []Event {
+	overridden := make(map[string]bool)
+	for _, e := range events {
+		if !e.RecurrenceID.IsZero() {
+			overridden[e.UID+"@"+strconv.FormatInt(e.RecurrenceID.Unix(), 10)] = true
+		}
+	}
+	var out []Event
+	for _, e := range events {
+		if !e.RecurrenceID.IsZero() || e.rule == nil {
+			if !e.Cancelled() && !e.Start.Before(from) && e.Start.Before(to) {
+				out = append(out, e)
+			}
+			continue
+		}
+		duration := e.End.Sub(e.Start)
+		e.expand(to, func(start time.Time) {
+			if start.Before(from) || e.exdates[start.Unix()] || e.Cancelled() ||
+				overridden[e.UID+"@"+strconv.FormatInt(start.Unix(), 10)] {
+				return
+			}
+			occ := e
+			occ.Start, occ.End = start, start.Add(duration)
+			occ.rule, occ.exdates = nil, nil
+			out = append(out, occ)
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out
+}
+
+// expand calls f with the start of every occurrence before to.
+func (e Event) expand(to time.Time, f func(time.Time)) {
+	r := e.rule
+	emitted := 0
+	emit := func(t time.Time) bool {
+		if !t.Before(to) || (!r.until.IsZero() && t.After(r.until)) || (r.count > 0 && emitted >= r.count) {
+			return false
+		}
+		emitted++
+		f(t)
+		return true
+	}
+	for i := 0; i < maxOccurrences; i++ {
+		n := i * r.interval
+		switch r.freq {
+		case "DAILY":
+			if !emit(e.Start.AddDate(0, 0, n)) {
+				return
+			}
+		case "WEEKLY":
+			week := e.Start.AddDate(0, 0, 7*n)
+			if len(r.byDay) == 0 {
+				if !emit(week) {
+					return
+				}
+				continue
+			}
+			// Occurrences fall on the listed days of the week starting on
+			// Monday, never before DTSTART
+			monday := week.AddDate(0, 0, -((int(week.Weekday()) + 6) % 7))
+			for _, d := range r.byDay {
+				t := monday.AddDate(0, 0, (int(d)+6)%7)
+				if t.Before(e.Start) {
+					continue
+				}
+				if !emit(t) {
+					return
+				}
+			}
+		case "MONTHLY", "YEARLY":
+			months := n
+			if r.freq == "YEARLY" {
+				months = 12 * n
+			}
+			t := e.Start.AddDate(0, months, 0)
+			if t.Day() != e.Start.Day() {
+				// e.g. the 31st in a shorter month, which has no occurrence
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+		default:
+			emit(e.Start)
+			return
+		}
+	}
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseRule reads an RRULE. Rules it cannot expand get no frequency, so
+// only their first occurrence is kept.
+func parseRule(value string, loc *time.Location) *rule {
+	r := &rule{interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		k, v, _ := strings.Cut(part, "=")
+		switch strings.ToUpper(k) {
+		case "FREQ":
+			r.freq = strings.ToUpper(v)
+		case "INTERVAL":
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				r.interval = n
+			}
+		case "COUNT":
+			r.count, _ = strconv.Atoi(v)
+		case "UNTIL":
+			r.until, _, _ = parseTime(v, nil, loc)
+		case "WKST":
+		case "BYDAY":
+			for _, d := range strings.Split(v, ",") {
+				wd, ok := weekdays[strings.ToUpper(d)]
+				if !ok {
+					return &rule{interval: 1}
+				}
+				r.byDay = append(r.byDay, wd)
+			}
+		default:
+			return &rule{interval: 1}
+		}
+	}
+	if len(r.byDay) > 0 && r.freq != "WEEKLY" {
+		return &rule{interval: 1}
+	}
+	sort.Slice(r.byDay, func(i, j int) bool { return (r.byDay[i]+6)%7 < (r.byDay[j]+6)%7 })
+	return r
+}
+
+// parseTime reads a DATE or DATE-TIME value and reports whether it is a
+// date.
+func parseTime(value string, params map[string]string, loc *time.Location) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	if tzid := params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// parseDuration reads a DURATION value, e.g. "PT1H30M" or "P1D".
+func parseDuration(value string) (time.Duration, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	sign := time.Duration(1)
+	if strings.HasPrefix(s, "-") {
+		sign = -1
+	}
+	s = strings.TrimLeft(s, "+-")
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("not a duration")
+	}
+	var d time.Duration
+	inTime := false
+	num := ""
+	for _, c := range s[1:] {
+		if c >= '0' && c <= '9' {
+			num += string(c)
+			continue
+		}
+		if c == 'T' {
+			inTime = true
+			continue
+		}
+		n, err := strconv.Atoi(num)
+		if err != nil {
+			return 0, fmt.Errorf("not a duration")
+		}
+		num = ""
+		unit := map[rune]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour}
+		if inTime {
+			unit = map[rune]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+		}
+		u, ok := unit[c]
+		if !ok {
+			return 0, fmt.Errorf("not a duration")
+		}
+		d += time.Duration(n) * u
+	}
+	if num != "" {
+		return 0, fmt.Errorf("not a duration")
+	}
+	return sign * d, nil
+}
+
+// unfold reads the content lines of a calendar, joining folded lines.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, sc.Err()
+}
+
+// parseLine splits a content line into its upper-cased name, parameters and
+// value.
+func parseLine(line string) (string, map[string]string, string) {
+	inQuotes := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			inQuotes = !inQuotes
+		} else if c == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return strings.ToUpper(line), nil, ""
+	}
+	parts := strings.Split(line[:colon], ";")
+	var params map[string]string
+	for _, p := range parts[1:] {
+		k, v, _ := strings.Cut(p, "=")
+		if params == nil {
+			params = make(map[string]string)
+		}
+		params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:]
+}
+
+var textUnescaper = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+func unescape(s string) string {
+	return textUnescaper.Replace(s)
+}
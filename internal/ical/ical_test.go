@@ -0,0 +1,138 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:Europe/Madrid\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:cut-1\r\n" +
+	"SUMMARY:Haircut\\, Ana\r\n" +
+	"DESCRIPTION:Bring the voucher\\nThanks\r\n" +
+	"LOCATION:Main \r\n" +
+	" street 1\r\n" +
+	"DTSTART;TZID=Europe/Madrid:20260310T100000\r\n" +
+	"DURATION:PT45M\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"TRIGGER:-PT15M\r\n" +
+	"DESCRIPTION:alarm\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup\r\n" +
+	"SUMMARY:Standup\r\n" +
+	"DTSTART:20260302T080000Z\r\n" +
+	"DTEND:20260302T081500Z\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=5\r\n" +
+	"EXDATE:20260304T080000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup\r\n" +
+	"SUMMARY:Standup (moved)\r\n" +
+	"RECURRENCE-ID:20260309T080000Z\r\n" +
+	"DTSTART:20260309T090000Z\r\n" +
+	"DTEND:20260309T091500Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:holiday\r\n" +
+	"SUMMARY:Closed\r\n" +
+	"DTSTART;VALUE=DATE:20260319\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:cancelled\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"DTSTART:20260305T080000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParse(t *testing.T) {
+	events, err := Parse(strings.NewReader(testCalendar), time.UTC)
+	require.NoError(t, err)
+	require.Len(t, events, 5)
+
+	cut := events[0]
+	madrid, _ := time.LoadLocation("Europe/Madrid")
+	assert.Equal(t, "Haircut, Ana", cut.Summary)
+	assert.Equal(t, "Bring the voucher\nThanks", cut.Description)
+	assert.Equal(t, "Main street 1", cut.Location)
+	assert.True(t, cut.Start.Equal(time.Date(2026, 3, 10, 10, 0, 0, 0, madrid)))
+	assert.Equal(t, 45*time.Minute, cut.End.Sub(cut.Start))
+
+	holiday := events[3]
+	assert.True(t, holiday.AllDay)
+	assert.Equal(t, 24*time.Hour, holiday.End.Sub(holiday.Start))
+	assert.True(t, events[4].Cancelled())
+}
+
+func TestBetweenExpandsRecurringEvents(t *testing.T) {
+	events, err := Parse(strings.NewReader(testCalendar), time.UTC)
+	require.NoError(t, err)
+
+	got := Between(events, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC))
+	var starts []string
+	for _, e := range got {
+		starts = append(starts, e.Start.UTC().Format("01-02 15:04")+" "+e.Summary)
+	}
+	assert.Equal(t, []string{
+		"03-02 08:00 Standup",
+		// 03-04 is excluded and 03-09 moved
+		"03-09 09:00 Standup (moved)",
+		"03-10 09:00 Haircut, Ana",
+		"03-11 08:00 Standup",
+		// COUNT=5 ends the series; the cancelled event is left out
+		"03-16 08:00 Standup",
+		"03-19 00:00 Closed",
+	}, starts)
+	assert.Equal(t, 15*time.Minute, got[0].End.Sub(got[0].Start))
+
+	got = Between(events, time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC))
+	require.Len(t, got, 1)
+	assert.Equal(t, "standup", got[0].UID)
+}
+
+func TestRules(t *testing.T) {
+	start := time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC)
+	var got []string
+	e := Event{Start: start, rule: parseRule("FREQ=MONTHLY;UNTIL=20260601T000000Z", time.UTC)}
+	e.expand(start.AddDate(1, 0, 0), func(t time.Time) { got = append(got, t.Format("01-02")) })
+	// Months without a 31st have no occurrence
+	assert.Equal(t, []string{"01-31", "03-31", "05-31"}, got)
+
+	got = nil
+	e.rule = parseRule("FREQ=DAILY;INTERVAL=2;COUNT=3", time.UTC)
+	e.expand(start.AddDate(1, 0, 0), func(t time.Time) { got = append(got, t.Format("01-02")) })
+	assert.Equal(t, []string{"01-31", "02-02", "02-04"}, got)
+
+	// Unsupported rules keep the first occurrence only
+	got = nil
+	e.rule = parseRule("FREQ=MONTHLY;BYDAY=1MO", time.UTC)
+	e.expand(start.AddDate(1, 0, 0), func(t time.Time) { got = append(got, t.Format("01-02")) })
+	assert.Equal(t, []string{"01-31"}, got)
+}
+
+func TestParseDuration(t *testing.T) {
+	for in, want := range map[string]time.Duration{
+		"PT1H30M": 90 * time.Minute,
+		"P1D":     24 * time.Hour,
+		"P1W":     7 * 24 * time.Hour,
+		"-PT15M":  -15 * time.Minute,
+		"P1DT2H":  26 * time.Hour,
+	} {
+		got, err := parseDuration(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	for _, in := range []string{"1H", "P", "PT1X", "P1"} {
+		_, err := parseDuration(in)
+		assert.Error(t, err, in)
+	}
+}
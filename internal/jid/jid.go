@@ -0,0 +1,96 @@
+// Package jid normalizes WhatsApp JIDs so one contact or group is always
+// written the same way, whichever device, client or user typed it.
+package jid
+
+import "strings"
+
+// Servers of the JIDs the archive deals with.
+const (
+	UserServer   = "s.whatsapp.net"
+	LegacyServer = "c.us"
+	GroupServer  = "g.us"
+	LIDServer    = "lid"
+)
+
+// userServers address individual accounts; their JIDs may carry an agent
+// and device part ("user.agent:device@server") that identifies one of the
+// account's devices rather than the account.
+var userServers = map[string]bool{
+	UserServer:   true,
+	LIDServer:    true,
+	"hosted":     true,
+	"hosted.lid": true,
+}
+
+// Normalize returns the canonical form of a chat or user JID: lower case,
+// without agent or device part, with the legacy c.us server replaced by
+// s.whatsapp.net. Input without a server is taken as a phone number, so
+// "+49 151 1234-5678" becomes "4915112345678@s.whatsapp.net". Anything that
+// is neither is returned trimmed but otherwise unchanged.
+func Normalize(s string) string {
+	s = strings.TrimSpace(s)
+	at := strings.LastIndexByte(s, '@')
+	if at < 0 {
+		if phone := digits(s); phone != "" {
+			return phone + "@" + UserServer
+		}
+		return s
+	}
+
+	user, server := strings.ToLower(s[:at]), strings.ToLower(s[at+1:])
+	if server == LegacyServer {
+		server = UserServer
+	}
+	if userServers[server] {
+		if i := strings.IndexAny(user, ".:"); i >= 0 {
+			user = user[:i]
+		}
+	}
+	return user + "@" + server
+}
+
+// User returns the user part of the normalized JID, which for phone-number
+// JIDs is the phone number.
+func User(s string) string {
+	n := Normalize(s)
+	if at := strings.LastIndexByte(n, '@'); at >= 0 {
+		return n[:at]
+	}
+	return n
+}
+
+// Server returns the server part of the normalized JID, or "" if it has none.
+func Server(s string) string {
+	n := Normalize(s)
+	if at := strings.LastIndexByte(n, '@'); at >= 0 {
+		return n[at+1:]
+	}
+	return ""
+}
+
+// IsGroup reports whether s is a group JID.
+func IsGroup(s string) bool {
+	return Server(s) == GroupServer
+}
+
+// IsLID reports whether s is a linked identifier (@lid) JID.
+func IsLID(s string) bool {
+	return Server(s) == LIDServer
+}
+
+// digits returns the digits of a phone number written with the usual
+// separators ("+", spaces, dashes, dots, parentheses), or "" if s contains
+// anything else.
+func digits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' || r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return ""
+		}
+	}
+	return b.String()
+}
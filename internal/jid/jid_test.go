@@ -0,0 +1,73 @@
+package jid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	for in, want := range map[string]string{
+		"4915112345678@s.whatsapp.net":     "4915112345678@s.whatsapp.net",
+		" 4915112345678@S.WhatsApp.Net ":   "4915112345678@s.whatsapp.net",
+		"4915112345678:12@s.whatsapp.net":  "4915112345678@s.whatsapp.net",
+		"4915112345678.0:3@s.whatsapp.net": "4915112345678@s.whatsapp.net",
+		"4915112345678@c.us":               "4915112345678@s.whatsapp.net",
+		"98765432109876:7@lid":             "98765432109876@lid",
+		"120363012345678901@g.us":          "120363012345678901@g.us",
+		"120363012345678901@G.US":          "120363012345678901@g.us",
+		"status@broadcast":                 "status@broadcast",
+		"4915112345678":                    "4915112345678@s.whatsapp.net",
+		"+49 (151) 1234-5678":              "4915112345678@s.whatsapp.net",
+		"me":                               "me",
+		"":                                 "",
+	} {
+		assert.Equal(t, want, Normalize(in), in)
+	}
+}
+
+func TestUserServer(t *testing.T) {
+	assert.Equal(t, "4915112345678", User("4915112345678:12@s.whatsapp.net"))
+	assert.Equal(t, "me", User("me"))
+	assert.Equal(t, "s.whatsapp.net", Server("4915112345678"))
+	assert.Equal(t, "", Server("me"))
+	assert.True(t, IsGroup("1-2@g.us"))
+	assert.True(t, IsLID("1:2@lid"))
+	assert.False(t, IsLID("1@s.whatsapp.net"))
+}
+
+func TestParseRecipient(t *testing.T) {
+	for in, want := range map[string]string{
+		"4915112345678":                   "4915112345678@s.whatsapp.net",
+		"+49 (151) 1234-5678":             "4915112345678@s.whatsapp.net",
+		"4915112345678:12@s.whatsapp.net": "4915112345678@s.whatsapp.net",
+		"4915112345678@c.us":              "4915112345678@s.whatsapp.net",
+		"120363012345678901@g.us":         "120363012345678901@g.us",
+		"4915112345678-1445678901@g.us":   "4915112345678-1445678901@g.us",
+		"98765432109876:7@lid":            "98765432109876@lid",
+		"120363123456789012@newsletter":   "120363123456789012@newsletter",
+		"status@broadcast":                "status@broadcast",
+	} {
+		got, err := ParseRecipient(in)
+		if assert.NoError(t, err, in) {
+			assert.Equal(t, want, got, in)
+		}
+	}
+
+	for in, msg := range map[string]string{
+		"":                          "required",
+		"alice":                     "expected a phone number",
+		"12345":                     "7-15 digits",
+		"1234567890123456":          "7-15 digits",
+		"alice@s.whatsapp.net":      "phone JID",
+		"family@g.us":               "not a group JID",
+		"abc@lid":                   "not a linked identifier",
+		"my-channel@newsletter":     "not a channel JID",
+		"1234567890@broadcast":      "broadcast lists are not supported",
+		"4915112345678@example.com": `unsupported server "example.com"`,
+		"13135550000@bot":           `unsupported server "bot"`,
+	} {
+		_, err := ParseRecipient(in)
+		assert.ErrorContains(t, err, msg, in)
+	}
+}
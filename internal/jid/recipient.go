@@ -0,0 +1,91 @@
+package jid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Servers that can only be sent to, not archived as regular chats.
+const (
+	NewsletterServer = "newsletter"
+	BroadcastServer  = "broadcast"
+)
+
+// StatusBroadcast is the JID status updates are posted to.
+const StatusBroadcast = "status@" + BroadcastServer
+
+// Phone numbers in E.164 have at most 15 digits; anything under 7 cannot be
+// a full international number.
+const (
+	minPhoneDigits = 7
+	maxPhoneDigits = 15
+)
+
+// recipientHint lists the accepted recipient forms for error messages.
+const recipientHint = "a phone number, <phone>@s.whatsapp.net, <id>@g.us, <id>@lid, <id>@newsletter or status@broadcast"
+
+// ParseRecipient validates s as the recipient of a message and returns its
+// normalized JID. Bare phone numbers address individual chats; groups,
+// linked identifiers (@lid), channels (@newsletter) and status updates
+// (status@broadcast) must be given as full JIDs. Broadcast lists and other
+// servers are refused, since WhatsApp does not accept messages to them from
+// a linked device.
+func ParseRecipient(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", fmt.Errorf("recipient is required")
+	}
+	if !strings.Contains(s, "@") {
+		phone := digits(s)
+		if phone == "" {
+			return "", fmt.Errorf("invalid recipient %q: expected %s", s, recipientHint)
+		}
+		if len(phone) < minPhoneDigits || len(phone) > maxPhoneDigits {
+			return "", fmt.Errorf("invalid recipient %q: a phone number has %d-%d digits including the country code", s, minPhoneDigits, maxPhoneDigits)
+		}
+		return phone + "@" + UserServer, nil
+	}
+
+	n := Normalize(s)
+	user, server := User(n), Server(n)
+	switch server {
+	case UserServer:
+		if !allDigits(user) || len(user) < minPhoneDigits || len(user) > maxPhoneDigits {
+			return "", fmt.Errorf("invalid recipient %q: the user part of a phone JID must be a %d-%d digit phone number", s, minPhoneDigits, maxPhoneDigits)
+		}
+	case GroupServer:
+		// Old groups are "<creator phone>-<timestamp>@g.us"
+		creator, created, old := strings.Cut(user, "-")
+		if !allDigits(creator) || (old && !allDigits(created)) {
+			return "", fmt.Errorf("invalid recipient %q: not a group JID", s)
+		}
+	case LIDServer:
+		if !allDigits(user) {
+			return "", fmt.Errorf("invalid recipient %q: not a linked identifier", s)
+		}
+	case NewsletterServer:
+		if !allDigits(user) {
+			return "", fmt.Errorf("invalid recipient %q: not a channel JID", s)
+		}
+	case BroadcastServer:
+		if n != StatusBroadcast {
+			return "", fmt.Errorf("invalid recipient %q: broadcast lists are not supported, only %s", s, StatusBroadcast)
+		}
+	default:
+		return "", fmt.Errorf("invalid recipient %q: unsupported server %q, expected %s", s, server, recipientHint)
+	}
+	return n, nil
+}
+
+// allDigits reports whether s is a non-empty string of ASCII digits.
+func allDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
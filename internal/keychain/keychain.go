@@ -0,0 +1,40 @@
+// Package keychain stores small secrets in the operating system's credential
+// store: the login keychain on macOS and the Credential Manager on Windows.
+package keychain
+
+import "errors"
+
+// ErrNotFound is returned by Get when no secret is stored for an account.
+var ErrNotFound = errors.New("secret not found in keychain")
+
+// ErrUnsupported is returned by Open on platforms without a supported
+// credential store.
+var ErrUnsupported = errors.New("keychain is not supported on this platform")
+
+// Keychain reads and writes secrets for accounts under one service name.
+type Keychain interface {
+	Get(account string) ([]byte, error)
+	Set(account string, secret []byte) error
+	Delete(account string) error
+}
+
+// Memory is an in-process Keychain, used in tests.
+type Memory map[string][]byte
+
+func (m Memory) Get(account string) ([]byte, error) {
+	v, ok := m[account]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), v...), nil
+}
+
+func (m Memory) Set(account string, secret []byte) error {
+	m[account] = append([]byte(nil), secret...)
+	return nil
+}
+
+func (m Memory) Delete(account string) error {
+	delete(m, account)
+	return nil
+}
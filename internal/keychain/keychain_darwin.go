@@ -0,0 +1,55 @@
+//go:build darwin
+
+package keychain
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// macKeychain uses the security(1) tool to manage generic passwords in the
+// user's login keychain.
+type macKeychain struct {
+	service string
+}
+
+// Open returns the login keychain, storing items under service.
+func Open(service string) (Keychain, error) {
+	if _, err := exec.LookPath("security"); err != nil {
+		return nil, ErrUnsupported
+	}
+	return macKeychain{service: service}, nil
+}
+
+func (k macKeychain) Get(account string) ([]byte, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", k.service, "-a", account, "-w").Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 44 {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("keychain read failed: %w", err)
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(out)))
+}
+
+func (k macKeychain) Set(account string, secret []byte) error {
+	// Pass the command on stdin (-i) so the secret never shows up in the
+	// process list; -U updates the item if it already exists.
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %q -a %q -w %q\n",
+		k.service, account, base64.StdEncoding.EncodeToString(secret)))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("keychain write failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (k macKeychain) Delete(account string) error {
+	err := exec.Command("security", "delete-generic-password", "-s", k.service, "-a", account).Run()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 44 {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,9 @@
+//go:build !darwin && !windows
+
+package keychain
+
+// Open reports ErrUnsupported: only macOS and Windows credential stores are
+// implemented.
+func Open(service string) (Keychain, error) {
+	return nil, ErrUnsupported
+}
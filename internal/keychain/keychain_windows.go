@@ -0,0 +1,113 @@
+//go:build windows
+
+package keychain
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32       = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW  = advapi32.NewProc("CredReadW")
+	procCredWriteW = advapi32.NewProc("CredWriteW")
+	procCredDelete = advapi32.NewProc("CredDeleteW")
+	procCredFree   = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	errorNotFound           = syscall.Errno(1168)
+)
+
+// credential mirrors the Win32 CREDENTIALW structure.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// winCredentials stores generic credentials in the Windows Credential
+// Manager, one per account, named "<service>:<account>".
+type winCredentials struct {
+	service string
+}
+
+// Open returns the Windows Credential Manager, storing items under service.
+func Open(service string) (Keychain, error) {
+	if err := procCredReadW.Find(); err != nil {
+		return nil, ErrUnsupported
+	}
+	return winCredentials{service: service}, nil
+}
+
+func (k winCredentials) target(account string) (*uint16, error) {
+	return syscall.UTF16PtrFromString(k.service + ":" + account)
+}
+
+func (k winCredentials) Get(account string) ([]byte, error) {
+	target, err := k.target(account)
+	if err != nil {
+		return nil, err
+	}
+	var cred *credential
+	r, _, callErr := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if r == 0 {
+		if errors.Is(callErr, errorNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, callErr
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	blob := unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)
+	return append([]byte(nil), blob...), nil
+}
+
+func (k winCredentials) Set(account string, secret []byte) error {
+	target, err := k.target(account)
+	if err != nil {
+		return err
+	}
+	user, err := syscall.UTF16PtrFromString(account)
+	if err != nil {
+		return err
+	}
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(secret)),
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	if len(secret) > 0 {
+		cred.CredentialBlob = &secret[0]
+	}
+	r, _, callErr := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if r == 0 {
+		return callErr
+	}
+	return nil
+}
+
+func (k winCredentials) Delete(account string) error {
+	target, err := k.target(account)
+	if err != nil {
+		return err
+	}
+	r, _, callErr := procCredDelete.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0)
+	if r == 0 && !errors.Is(callErr, errorNotFound) {
+		return callErr
+	}
+	return nil
+}
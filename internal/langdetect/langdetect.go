@@ -0,0 +1,135 @@
+// Package langdetect guesses the language of short chat messages. It uses the
+// Unicode script for languages with their own alphabet and stopword frequency
+// for Latin-script languages, which is cheap and good enough for the
+// one-line-to-one-paragraph texts typical of chats.
+package langdetect
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minWords is the number of words a Latin-script text needs before a guess is
+// made; shorter texts ("ok", "haha", names) are too ambiguous.
+const minWords = 3
+
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "you", "to", "of", "it", "that", "this", "for", "with", "have", "was", "what", "not", "be", "on", "my", "we", "i'm", "just", "will", "can", "your", "at", "do"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ich", "du", "wir", "ein", "eine", "mit", "auf", "zu", "es", "sie", "den", "dem", "auch", "noch", "wie", "was", "aber", "schon", "bin", "hast", "habe", "heute", "morgen", "ja", "nein"},
+	"es": {"el", "la", "los", "las", "que", "de", "y", "es", "en", "un", "una", "por", "para", "con", "no", "lo", "pero", "como", "muy", "estoy", "está", "hola", "gracias", "qué", "yo", "tu", "mañana"},
+	"fr": {"le", "la", "les", "et", "est", "je", "tu", "nous", "vous", "un", "une", "des", "pas", "que", "qui", "dans", "pour", "avec", "sur", "ce", "c'est", "mais", "bien", "merci", "oui", "demain", "très"},
+	"it": {"il", "lo", "la", "gli", "le", "che", "di", "e", "è", "non", "un", "una", "per", "con", "sono", "ma", "come", "anche", "ciao", "grazie", "domani", "molto", "perché", "questo"},
+	"pt": {"o", "a", "os", "as", "que", "de", "e", "é", "não", "um", "uma", "para", "com", "em", "do", "da", "mas", "como", "eu", "você", "obrigado", "obrigada", "amanhã", "muito", "está"},
+	"nl": {"de", "het", "een", "en", "is", "niet", "ik", "je", "jij", "we", "wij", "met", "op", "van", "dat", "die", "voor", "maar", "ook", "nog", "wat", "morgen", "bedankt", "zijn", "heb"},
+}
+
+var stopwordIndex = buildIndex()
+
+func buildIndex() map[string][]string {
+	idx := make(map[string][]string)
+	for lang, words := range stopwords {
+		for _, w := range words {
+			idx[w] = append(idx[w], lang)
+		}
+	}
+	return idx
+}
+
+// Detect returns the ISO 639-1 code of the dominant language of text, or ""
+// when it cannot tell.
+func Detect(text string) string {
+	if lang := detectScript(text); lang != "" {
+		return lang
+	}
+	return detectStopwords(text)
+}
+
+// detectScript recognises languages written in a script of their own. Han
+// without kana is reported as Chinese.
+func detectScript(text string) string {
+	counts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			counts["ja"]++
+		case unicode.Is(unicode.Hangul, r):
+			counts["ko"]++
+		case unicode.Is(unicode.Han, r):
+			counts["zh"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			counts["ru"]++
+		case unicode.Is(unicode.Greek, r):
+			counts["el"]++
+		case unicode.Is(unicode.Hebrew, r):
+			counts["he"]++
+		case unicode.Is(unicode.Arabic, r):
+			counts["ar"]++
+		case unicode.Is(unicode.Devanagari, r):
+			counts["hi"]++
+		case unicode.Is(unicode.Thai, r):
+			counts["th"]++
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+	// Japanese mixes kanji with kana; any kana decides it.
+	if counts["ja"] > 0 {
+		counts["ja"] += counts["zh"]
+		counts["zh"] = 0
+	}
+
+	best, bestCount := "", 0
+	for lang, n := range counts {
+		if n > bestCount || (n == bestCount && lang < best) {
+			best, bestCount = lang, n
+		}
+	}
+	if bestCount*2 < letters {
+		return ""
+	}
+	return best
+}
+
+func detectStopwords(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	if len(words) < minWords {
+		return ""
+	}
+
+	scores := make(map[string]int)
+	for _, w := range words {
+		for _, lang := range stopwordIndex[w] {
+			scores[lang]++
+		}
+	}
+
+	best, bestScore, second := "", 0, 0
+	for lang, n := range scores {
+		if n > bestScore || (n == bestScore && lang < best) {
+			second = bestScore
+			best, bestScore = lang, n
+		} else if n > second {
+			second = n
+		}
+	}
+	// Require at least two hits and a strict lead over the runner-up so that
+	// a single shared word ("de", "la") does not decide the language.
+	if bestScore < 2 || bestScore == second {
+		return ""
+	}
+	return best
+}
+
+// IsStopword reports whether word, in lower case, is a common function word
+// of one of the detected languages.
+func IsStopword(word string) bool {
+	return len(stopwordIndex[word]) > 0
+}
@@ -0,0 +1,36 @@
+package langdetect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Are you coming to the party tonight? I think it will be fun", "en"},
+		{"Ich bin heute nicht da, aber morgen schon", "de"},
+		{"Hola, ¿qué tal? Estoy en la oficina pero salgo pronto", "es"},
+		{"Je suis pas sûr que ce soit une bonne idée", "fr"},
+		{"Ciao, non posso venire domani perché sono al lavoro", "it"},
+		{"Eu não sei se você está em casa amanhã", "pt"},
+		{"Ik heb het niet gezien, maar morgen kijk ik", "nl"},
+		{"Привет, как дела?", "ru"},
+		{"Καλημέρα σε όλους", "el"},
+		{"明日は雨が降るでしょう", "ja"},
+		{"我们明天见", "zh"},
+		{"안녕하세요 반갑습니다", "ko"},
+		{"ok", ""},
+		{"haha 😂😂", ""},
+		{"https://example.com", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.want, Detect(tt.text))
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// Package logging provides the structured logger used for the whatsmeow
+// client and its session store. Output goes to stderr so it never mixes with
+// the JSON the CLI prints on stdout.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// Subsystems whose level can be set on its own.
+const (
+	// SubsystemWhatsApp is the whatsmeow client: connection, protocol
+	// events, encryption and media.
+	SubsystemWhatsApp = "whatsapp"
+	// SubsystemStore is whatsmeow's session database.
+	SubsystemStore = "store"
+	// SubsystemHealth is the daemon's lifecycle: pairing, syncing, and
+	// losing the connection or the session.
+	SubsystemHealth = "health"
+)
+
+// Output formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Config selects how much is logged and how.
+type Config struct {
+	// Level is the minimum level logged: debug, info, warn or error.
+	Level string
+	// Levels overrides Level per subsystem, e.g. {"whatsapp": "debug"}.
+	Levels map[string]string
+	// Format is "text" (key=value) or "json" (one object per line).
+	Format string
+}
+
+// DefaultConfig only logs errors, so one-shot CLI commands stay quiet.
+var DefaultConfig = Config{Level: "error", Format: FormatText}
+
+var (
+	mu     sync.RWMutex
+	cfg              = DefaultConfig
+	output io.Writer = os.Stderr
+)
+
+// ParseLevel parses a level name as accepted in Config.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q (must be debug, info, warn or error)", s)
+}
+
+// Configure validates c and applies it to loggers created afterwards.
+func Configure(c Config) error {
+	if _, err := ParseLevel(c.Level); err != nil {
+		return err
+	}
+	for subsystem, level := range c.Levels {
+		if _, err := ParseLevel(level); err != nil {
+			return fmt.Errorf("%s: %w", subsystem, err)
+		}
+	}
+	switch c.Format {
+	case "":
+		c.Format = FormatText
+	case FormatText, FormatJSON:
+	default:
+		return fmt.Errorf("unknown log format %q (must be text or json)", c.Format)
+	}
+	mu.Lock()
+	cfg = c
+	mu.Unlock()
+	return nil
+}
+
+// SetOutput redirects loggers created afterwards to w.
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	output = w
+	mu.Unlock()
+}
+
+// Logger returns a logger tagged with subsystem, logging at the level
+// configured for it.
+func Logger(subsystem string) *slog.Logger {
+	mu.RLock()
+	c, w := cfg, output
+	mu.RUnlock()
+
+	level := c.Level
+	if l, ok := c.Levels[subsystem]; ok && l != "" {
+		level = l
+	}
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		lvl = slog.LevelError
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	var h slog.Handler
+	if c.Format == FormatJSON {
+		h = slog.NewJSONHandler(w, opts)
+	} else {
+		h = slog.NewTextHandler(w, opts)
+	}
+	return slog.New(h).With("subsystem", subsystem)
+}
+
+// WhatsMeow returns a whatsmeow logger for module, logging at the level
+// configured for subsystem.
+func WhatsMeow(subsystem, module string) waLog.Logger {
+	return &waLogger{l: Logger(subsystem), module: module}
+}
+
+// waLogger adapts a slog.Logger to whatsmeow's logger interface, tagging
+// every record with the module path ("Client/Socket").
+type waLogger struct {
+	l      *slog.Logger
+	module string
+}
+
+func (w *waLogger) log(level slog.Level, format string, args []interface{}) {
+	ctx := context.Background()
+	if !w.l.Enabled(ctx, level) {
+		return
+	}
+	w.l.Log(ctx, level, fmt.Sprintf(format, args...), "module", w.module)
+}
+
+func (w *waLogger) Debugf(msg string, args ...interface{}) { w.log(slog.LevelDebug, msg, args) }
+func (w *waLogger) Infof(msg string, args ...interface{})  { w.log(slog.LevelInfo, msg, args) }
+func (w *waLogger) Warnf(msg string, args ...interface{})  { w.log(slog.LevelWarn, msg, args) }
+func (w *waLogger) Errorf(msg string, args ...interface{}) { w.log(slog.LevelError, msg, args) }
+
+func (w *waLogger) Sub(module string) waLog.Logger {
+	return &waLogger{l: w.l, module: w.module + "/" + module}
+}
@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useConfig applies c with output captured in the returned buffer, restoring
+// the defaults when the test ends.
+func useConfig(t *testing.T, c Config) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, Configure(c))
+	SetOutput(&buf)
+	t.Cleanup(func() {
+		Configure(DefaultConfig)
+		SetOutput(os.Stderr)
+	})
+	return &buf
+}
+
+func TestWhatsMeow_SubsystemLevels(t *testing.T) {
+	buf := useConfig(t, Config{
+		Level:  "info",
+		Levels: map[string]string{SubsystemStore: "error"},
+	})
+
+	wa := WhatsMeow(SubsystemWhatsApp, "Client")
+	wa.Debugf("hidden")
+	wa.Infof("connected to %s", "server")
+	db := WhatsMeow(SubsystemStore, "Database")
+	db.Warnf("hidden too")
+	db.Errorf("upgrade failed")
+
+	out := buf.String()
+	assert.NotContains(t, out, "hidden")
+	assert.Contains(t, out, `msg="connected to server"`)
+	assert.Contains(t, out, "subsystem=whatsapp module=Client")
+	assert.Contains(t, out, `msg="upgrade failed"`)
+	assert.Contains(t, out, "subsystem=store module=Database")
+}
+
+func TestWhatsMeow_JSON(t *testing.T) {
+	buf := useConfig(t, Config{Level: "debug", Format: FormatJSON})
+
+	WhatsMeow(SubsystemWhatsApp, "Client").Sub("Recv").Debugf("<iq id=%q/>", "1")
+
+	var rec map[string]interface{}
+	require.NoError(t, json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &rec))
+	assert.Equal(t, "DEBUG", rec["level"])
+	assert.Equal(t, `<iq id="1"/>`, rec["msg"])
+	assert.Equal(t, "whatsapp", rec["subsystem"])
+	assert.Equal(t, "Client/Recv", rec["module"])
+	assert.Contains(t, rec, "time")
+}
+
+func TestConfigure_Invalid(t *testing.T) {
+	assert.Error(t, Configure(Config{Level: "verbose"}))
+	assert.ErrorContains(t, Configure(Config{Level: "info", Levels: map[string]string{SubsystemStore: "loud"}}), "store")
+	assert.Error(t, Configure(Config{Level: "info", Format: "xml"}))
+}
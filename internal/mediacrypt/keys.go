@@ -0,0 +1,191 @@
+package mediacrypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// LocalKey wraps file keys with AES-256-GCM under a master key held in
+// memory.
+type LocalKey struct {
+	key []byte
+	id  string
+}
+
+// NewLocalKey returns a wrapper for a 32-byte master key.
+func NewLocalKey(master []byte) (*LocalKey, error) {
+	if len(master) != keySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", keySize, len(master))
+	}
+	sum := sha256.Sum256(master)
+	return &LocalKey{key: master, id: "local:" + hex.EncodeToString(sum[:4])}, nil
+}
+
+// ParseLocalKey decodes a master key given as 64 hex digits or as base64.
+func ParseLocalKey(s string) (*LocalKey, error) {
+	s = strings.TrimSpace(s)
+	key, err := hex.DecodeString(s)
+	if err != nil || len(key) != keySize {
+		if key, err = base64.StdEncoding.DecodeString(s); err != nil {
+			return nil, fmt.Errorf("master key must be 64 hex digits or base64")
+		}
+	}
+	return NewLocalKey(key)
+}
+
+// KeyID is "local:" and the start of the master key's SHA-256, so a file can
+// be matched to its key without revealing it.
+func (k *LocalKey) KeyID() string { return k.id }
+
+func (k *LocalKey) Wrap(_ context.Context, key []byte) ([]byte, error) {
+	aead, err := newAEAD(k.key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, key, []byte(k.id)), nil
+}
+
+func (k *LocalKey) Unwrap(_ context.Context, wrapped []byte) ([]byte, error) {
+	aead, err := newAEAD(k.key)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, fmt.Errorf("wrapped key is too short")
+	}
+	key, err := aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], []byte(k.id))
+	if err != nil {
+		return nil, fmt.Errorf("wrong master key")
+	}
+	return key, nil
+}
+
+// VaultTransit wraps file keys with a key of HashiCorp Vault's (or
+// OpenBao's) transit secrets engine, so the master key never leaves the key
+// management service. Every wrap and unwrap is a request to Vault.
+type VaultTransit struct {
+	addr  string // e.g. https://vault.example.com:8200
+	mount string // transit engine mount, "transit" by default
+	key   string
+	token string
+	http  *http.Client
+}
+
+// NewVaultTransit returns a wrapper using the transit key named key, mounted
+// at mount ("transit" if empty), of the Vault server at addr.
+func NewVaultTransit(addr, mount, key, token string) (*VaultTransit, error) {
+	u, err := url.Parse(addr)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid Vault address %q", addr)
+	}
+	if key == "" || token == "" {
+		return nil, fmt.Errorf("a transit key name and a Vault token are required")
+	}
+	if mount == "" {
+		mount = "transit"
+	}
+	return &VaultTransit{
+		addr:  strings.TrimRight(addr, "/"),
+		mount: strings.Trim(mount, "/"),
+		key:   key,
+		token: token,
+		http:  &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// KeyID is "vault:" and the transit key name. Vault tracks the key version
+// inside the wrapped key, so rotating it does not break existing files.
+func (v *VaultTransit) KeyID() string { return "vault:" + v.mount + "/" + v.key }
+
+func (v *VaultTransit) Wrap(ctx context.Context, key []byte) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	if err := v.call(ctx, "encrypt", map[string]string{"plaintext": base64.StdEncoding.EncodeToString(key)}, &resp); err != nil {
+		return nil, err
+	}
+	return []byte(resp.Data.Ciphertext), nil
+}
+
+func (v *VaultTransit) Unwrap(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := v.call(ctx, "decrypt", map[string]string{"ciphertext": string(wrapped)}, &resp); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+}
+
+func (v *VaultTransit) call(ctx context.Context, op string, body map[string]string, out any) error {
+	payload, _ := json.Marshal(body)
+	endpoint := fmt.Sprintf("%s/v1/%s/%s/%s", v.addr, v.mount, op, url.PathEscape(v.key))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := v.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault: %w", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(data, &e) == nil && len(e.Errors) > 0 {
+			return fmt.Errorf("vault: %s: %s", resp.Status, strings.Join(e.Errors, "; "))
+		}
+		return fmt.Errorf("vault: %s", resp.Status)
+	}
+	return json.Unmarshal(data, out)
+}
+
+// FromEnv returns the wrapper configured by the environment, read through
+// getenv, or nil if media encryption is not enabled:
+//
+//	MEDIA_ENCRYPTION_KEY         master key, 64 hex digits or base64
+//	MEDIA_ENCRYPTION_VAULT_KEY   transit key name, with VAULT_ADDR and VAULT_TOKEN
+//	MEDIA_ENCRYPTION_VAULT_MOUNT transit engine mount, "transit" by default
+func FromEnv(getenv func(string) string) (KeyWrapper, error) {
+	local, vaultKey := getenv("MEDIA_ENCRYPTION_KEY"), getenv("MEDIA_ENCRYPTION_VAULT_KEY")
+	switch {
+	case local != "" && vaultKey != "":
+		return nil, fmt.Errorf("set either MEDIA_ENCRYPTION_KEY or MEDIA_ENCRYPTION_VAULT_KEY, not both")
+	case local != "":
+		k, err := ParseLocalKey(local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MEDIA_ENCRYPTION_KEY: %w", err)
+		}
+		return k, nil
+	case vaultKey != "":
+		v, err := NewVaultTransit(getenv("VAULT_ADDR"), getenv("MEDIA_ENCRYPTION_VAULT_MOUNT"), vaultKey, getenv("VAULT_TOKEN"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid MEDIA_ENCRYPTION_VAULT_KEY: %w", err)
+		}
+		return v, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,329 @@
+// Package mediacrypt encrypts media files at rest. Each file is encrypted
+// with its own random key, which is stored in the file's header wrapped by a
+// master key. The master key stays outside the media directory: in the
+// configuration or in a key management service.
+//
+// A file is a header followed by the content in chunks of ChunkSize bytes,
+// each sealed with AES-256-GCM, so it can be read from any offset without
+// decrypting what comes before:
+//
+//	"WAMEDIA\x01" | key ID length (2 bytes) | key ID | wrapped key length (2 bytes) | wrapped key | chunks…
+//
+// Each chunk's nonce is its index and whether it is the last one, and the
+// header is authenticated with every chunk, so chunks cannot be reordered,
+// dropped or moved to another file.
+package mediacrypt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ChunkSize is the plaintext size of all chunks but the last.
+const ChunkSize = 64 << 10
+
+const (
+	magic   = "WAMEDIA\x01"
+	keySize = 32
+)
+
+// ErrNotEncrypted is returned when opening a file that does not start with
+// the header of an encrypted file.
+var ErrNotEncrypted = errors.New("file is not encrypted")
+
+// KeyWrapper wraps the keys of files with a master key.
+type KeyWrapper interface {
+	// KeyID names the master key. It is stored in each file, so a file
+	// wrapped by another key is reported as such.
+	KeyID() string
+	Wrap(ctx context.Context, key []byte) ([]byte, error)
+	Unwrap(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// Encrypt writes src to dst encrypted with a new key wrapped by w.
+func Encrypt(ctx context.Context, w KeyWrapper, dst io.Writer, src io.Reader) error {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	wrapped, err := w.Wrap(ctx, key)
+	if err != nil {
+		return fmt.Errorf("wrapping file key: %w", err)
+	}
+	header, err := encodeHeader(w.KeyID(), wrapped)
+	if err != nil {
+		return err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	if _, err := dst.Write(header); err != nil {
+		return err
+	}
+
+	br := bufio.NewReaderSize(src, ChunkSize)
+	buf := make([]byte, ChunkSize, ChunkSize+aead.Overhead())
+	for index := uint64(0); ; index++ {
+		n, err := io.ReadFull(br, buf)
+		last := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !last {
+			return err
+		}
+		if !last {
+			if _, err := br.Peek(1); err == io.EOF {
+				last = true
+			} else if err != nil {
+				return err
+			}
+		}
+		sealed := aead.Seal(buf[:0], chunkNonce(index, last), buf[:n], header)
+		if _, err := dst.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+		buf = buf[:ChunkSize]
+	}
+}
+
+// EncryptFile encrypts the file at path in place. The encrypted copy is
+// written next to it and renamed over it.
+func EncryptFile(ctx context.Context, w KeyWrapper, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".wa-encrypt-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := Encrypt(ctx, w, tmp, src); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// IsEncrypted reports whether the file at path starts with the header of an
+// encrypted file.
+func IsEncrypted(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	b := make([]byte, len(magic))
+	if _, err := io.ReadFull(f, b); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return false, nil
+		}
+		return false, err
+	}
+	return string(b) == magic, nil
+}
+
+// Reader decrypts an encrypted file. It implements io.ReadSeeker over the
+// plaintext, so it can be served with http.ServeContent.
+type Reader struct {
+	r      io.ReaderAt
+	aead   cipher.AEAD
+	header []byte
+	size   int64 // plaintext
+	chunks int64
+	offset int64
+
+	chunk      []byte // decrypted chunk at chunkIndex
+	chunkIndex int64
+}
+
+// NewReader reads the encrypted file r of size bytes, unwrapping its key
+// with w. It returns ErrNotEncrypted if r is not an encrypted file.
+func NewReader(ctx context.Context, w KeyWrapper, r io.ReaderAt, size int64) (*Reader, error) {
+	header, keyID, wrapped, err := readHeader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	if keyID != w.KeyID() {
+		return nil, fmt.Errorf("file was encrypted with master key %q, not %q", keyID, w.KeyID())
+	}
+	key, err := w.Unwrap(ctx, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("unwrapping file key: %w", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sealedChunk := int64(ChunkSize + aead.Overhead())
+	body := size - int64(len(header))
+	full, rem := body/sealedChunk, body%sealedChunk
+	rd := &Reader{r: r, aead: aead, header: header, chunkIndex: -1}
+	switch {
+	case rem == 0 && full > 0:
+		rd.chunks, rd.size = full, full*ChunkSize
+	case rem >= int64(aead.Overhead()):
+		rd.chunks, rd.size = full+1, full*ChunkSize+rem-int64(aead.Overhead())
+	default:
+		return nil, fmt.Errorf("encrypted file is truncated")
+	}
+	return rd, nil
+}
+
+// Open opens the encrypted file at path. It returns ErrNotEncrypted if the
+// file is not encrypted.
+func Open(ctx context.Context, w KeyWrapper, path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r, err := NewReader(ctx, w, f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &File{Reader: r, f: f}, nil
+}
+
+// File is an encrypted file opened for reading.
+type File struct {
+	*Reader
+	f *os.File
+}
+
+func (f *File) Close() error { return f.f.Close() }
+
+// Size is the size of the plaintext.
+func (r *Reader) Size() int64 { return r.size }
+
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	index := r.offset / ChunkSize
+	if index != r.chunkIndex {
+		if err := r.load(index); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.chunk[r.offset-index*ChunkSize:])
+	r.offset += int64(n)
+	return n, nil
+}
+
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative position")
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+// load decrypts the chunk at index.
+func (r *Reader) load(index int64) error {
+	sealedChunk := int64(ChunkSize + r.aead.Overhead())
+	sealed := make([]byte, sealedChunk)
+	n, err := r.r.ReadAt(sealed, int64(len(r.header))+index*sealedChunk)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	last := index == r.chunks-1
+	chunk, err := r.aead.Open(sealed[:0], chunkNonce(uint64(index), last), sealed[:n], r.header)
+	if err != nil {
+		return fmt.Errorf("encrypted file is corrupted")
+	}
+	r.chunk, r.chunkIndex = chunk, index
+	return nil
+}
+
+func encodeHeader(keyID string, wrapped []byte) ([]byte, error) {
+	if len(keyID) > 0xffff || len(wrapped) > 0xffff {
+		return nil, fmt.Errorf("key ID or wrapped key too long")
+	}
+	var b bytes.Buffer
+	b.WriteString(magic)
+	binary.Write(&b, binary.BigEndian, uint16(len(keyID)))
+	b.WriteString(keyID)
+	binary.Write(&b, binary.BigEndian, uint16(len(wrapped)))
+	b.Write(wrapped)
+	return b.Bytes(), nil
+}
+
+func readHeader(r io.ReaderAt, size int64) (header []byte, keyID string, wrapped []byte, err error) {
+	sr := io.NewSectionReader(r, 0, size)
+	prefix := make([]byte, len(magic)+2)
+	if _, err := io.ReadFull(sr, prefix); err != nil || string(prefix[:len(magic)]) != magic {
+		return nil, "", nil, ErrNotEncrypted
+	}
+	id := make([]byte, binary.BigEndian.Uint16(prefix[len(magic):]))
+	if _, err := io.ReadFull(sr, id); err != nil {
+		return nil, "", nil, fmt.Errorf("encrypted file is truncated")
+	}
+	var n uint16
+	if err := binary.Read(sr, binary.BigEndian, &n); err != nil {
+		return nil, "", nil, fmt.Errorf("encrypted file is truncated")
+	}
+	wrapped = make([]byte, n)
+	if _, err := io.ReadFull(sr, wrapped); err != nil {
+		return nil, "", nil, fmt.Errorf("encrypted file is truncated")
+	}
+	header, err = encodeHeader(string(id), wrapped)
+	return header, string(id), wrapped, err
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce is the big-endian chunk index followed by 1 for the last chunk.
+func chunkNonce(index uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce, index)
+	if last {
+		nonce[8] = 1
+	}
+	return nonce
+}
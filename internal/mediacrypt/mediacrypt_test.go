@@ -0,0 +1,212 @@
+package mediacrypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(t *testing.T) *LocalKey {
+	t.Helper()
+	master := make([]byte, 32)
+	rand.Read(master)
+	k, err := NewLocalKey(master)
+	require.NoError(t, err)
+	return k
+}
+
+func encrypt(t *testing.T, k KeyWrapper, plain []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, Encrypt(context.Background(), k, &buf, bytes.NewReader(plain)))
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	k := testKey(t)
+	for _, size := range []int{0, 1, ChunkSize - 1, ChunkSize, ChunkSize + 1, 3*ChunkSize - 5} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+		sealed := encrypt(t, k, plain)
+		if size >= 64 {
+			assert.False(t, bytes.Contains(sealed, plain[:64]))
+		}
+
+		r, err := NewReader(context.Background(), k, bytes.NewReader(sealed), int64(len(sealed)))
+		require.NoError(t, err, "size %d", size)
+		assert.Equal(t, int64(size), r.Size())
+		got, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, plain, got, "size %d", size)
+	}
+}
+
+func TestReaderSeeks(t *testing.T) {
+	k := testKey(t)
+	plain := make([]byte, 2*ChunkSize+100)
+	rand.Read(plain)
+	sealed := encrypt(t, k, plain)
+	r, err := NewReader(context.Background(), k, bytes.NewReader(sealed), int64(len(sealed)))
+	require.NoError(t, err)
+
+	end, err := r.Seek(0, io.SeekEnd)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(plain)), end)
+
+	// A range across a chunk boundary
+	_, err = r.Seek(ChunkSize-10, io.SeekStart)
+	require.NoError(t, err)
+	got := make([]byte, 30)
+	_, err = io.ReadFull(r, got)
+	require.NoError(t, err)
+	assert.Equal(t, plain[ChunkSize-10:ChunkSize+20], got)
+
+	_, err = r.Seek(-50, io.SeekEnd)
+	require.NoError(t, err)
+	rest, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, plain[len(plain)-50:], rest)
+}
+
+func TestReaderDetectsTampering(t *testing.T) {
+	k := testKey(t)
+	plain := make([]byte, 2*ChunkSize+100)
+	sealed := encrypt(t, k, plain)
+
+	flipped := bytes.Clone(sealed)
+	flipped[len(flipped)-200] ^= 1
+	r, err := NewReader(context.Background(), k, bytes.NewReader(flipped), int64(len(flipped)))
+	require.NoError(t, err)
+	_, err = io.ReadAll(r)
+	assert.ErrorContains(t, err, "corrupted")
+
+	// Dropping the last chunk turns the previous one into the last
+	truncated := sealed[:len(sealed)-(100+16)]
+	r, err = NewReader(context.Background(), k, bytes.NewReader(truncated), int64(len(truncated)))
+	require.NoError(t, err)
+	_, err = io.ReadAll(r)
+	assert.ErrorContains(t, err, "corrupted")
+
+	_, err = NewReader(context.Background(), testKey(t), bytes.NewReader(sealed), int64(len(sealed)))
+	assert.ErrorContains(t, err, "master key")
+
+	_, err = NewReader(context.Background(), k, strings.NewReader("GIF89a"), 6)
+	assert.ErrorIs(t, err, ErrNotEncrypted)
+}
+
+func TestEncryptFile(t *testing.T) {
+	k := testKey(t)
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	require.NoError(t, os.WriteFile(path, []byte("\xff\xd8\xff\xe0 photo"), 0o600))
+
+	encrypted, err := IsEncrypted(path)
+	require.NoError(t, err)
+	assert.False(t, encrypted)
+
+	require.NoError(t, EncryptFile(context.Background(), k, path))
+	encrypted, err = IsEncrypted(path)
+	require.NoError(t, err)
+	assert.True(t, encrypted)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	f, err := Open(context.Background(), k, path)
+	require.NoError(t, err)
+	defer f.Close()
+	got, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "\xff\xd8\xff\xe0 photo", string(got))
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	assert.Len(t, entries, 1)
+}
+
+func TestParseLocalKey(t *testing.T) {
+	hexKey := strings.Repeat("ab", 32)
+	k, err := ParseLocalKey(hexKey)
+	require.NoError(t, err)
+	b64, err := ParseLocalKey(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xab}, 32)))
+	require.NoError(t, err)
+	assert.Equal(t, k.KeyID(), b64.KeyID())
+	assert.True(t, strings.HasPrefix(k.KeyID(), "local:"))
+
+	_, err = ParseLocalKey("abcd")
+	assert.Error(t, err)
+}
+
+func TestVaultTransit(t *testing.T) {
+	// A stand-in for the transit engine that "encrypts" by prefixing
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/media":
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{"ciphertext": "vault:v1:" + body["plaintext"]}})
+		case "/v1/transit/decrypt/media":
+			json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{"plaintext": strings.TrimPrefix(body["ciphertext"], "vault:v1:")}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+
+	v, err := NewVaultTransit(vault.URL, "", "media", "s.token")
+	require.NoError(t, err)
+	assert.Equal(t, "vault:transit/media", v.KeyID())
+	sealed := encrypt(t, v, []byte("voice note"))
+	r, err := NewReader(context.Background(), v, bytes.NewReader(sealed), int64(len(sealed)))
+	require.NoError(t, err)
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "voice note", string(got))
+
+	denied, err := NewVaultTransit(vault.URL, "", "media", "wrong")
+	require.NoError(t, err)
+	_, err = denied.Wrap(context.Background(), make([]byte, 32))
+	assert.ErrorContains(t, err, "permission denied")
+}
+
+func TestFromEnv(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+
+	keys, err := FromEnv(env(nil))
+	require.NoError(t, err)
+	assert.Nil(t, keys)
+
+	keys, err = FromEnv(env(map[string]string{"MEDIA_ENCRYPTION_KEY": strings.Repeat("0f", 32)}))
+	require.NoError(t, err)
+	assert.IsType(t, &LocalKey{}, keys)
+
+	keys, err = FromEnv(env(map[string]string{"MEDIA_ENCRYPTION_VAULT_KEY": "media", "VAULT_ADDR": "https://vault.example.com:8200", "VAULT_TOKEN": "s.token"}))
+	require.NoError(t, err)
+	assert.Equal(t, "vault:transit/media", keys.KeyID())
+
+	for _, vars := range []map[string]string{
+		{"MEDIA_ENCRYPTION_KEY": "short"},
+		{"MEDIA_ENCRYPTION_VAULT_KEY": "media"},
+		{"MEDIA_ENCRYPTION_KEY": strings.Repeat("0f", 32), "MEDIA_ENCRYPTION_VAULT_KEY": "media"},
+	} {
+		_, err := FromEnv(env(vars))
+		assert.Error(t, err, vars)
+	}
+}
@@ -0,0 +1,77 @@
+// Package mediaurl signs short-lived URLs of the media of a message, so
+// webhook consumers can fetch it without holding an API key.
+//
+// A URL names the message, its chat and an expiry time, and carries an
+// HMAC-SHA256 of the three:
+//
+//	https://wa.example.com/api/v1/media/<message ID>?chat_jid=<chat>&expires=<unix>&sig=<signature>
+//
+// The signing key is derived from the API key, so changing API_KEY revokes
+// every URL handed out.
+package mediaurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTTL is how long a signed URL stays valid.
+const DefaultTTL = 15 * time.Minute
+
+// PathPrefix is the path of media downloads, followed by the message ID.
+const PathPrefix = "/api/v1/media/"
+
+const keyInfo = "whatsapp-cli media url v1"
+
+// Signer signs media URLs under a base URL and verifies them.
+type Signer struct {
+	key     []byte
+	baseURL string
+	ttl     time.Duration
+}
+
+// New returns a Signer for the API key apiKey. URLs start with baseURL, the
+// address the API is reachable at, and are valid for ttl, DefaultTTL if zero.
+func New(apiKey, baseURL string, ttl time.Duration) *Signer {
+	mac := hmac.New(sha256.New, []byte(apiKey))
+	mac.Write([]byte(keyInfo))
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Signer{key: mac.Sum(nil), baseURL: strings.TrimRight(baseURL, "/"), ttl: ttl}
+}
+
+// URL returns the signed URL of the media of a message and when it expires.
+func (s *Signer) URL(messageID, chatJID string, now time.Time) (string, time.Time) {
+	expires := now.Add(s.ttl).Truncate(time.Second)
+	q := url.Values{}
+	q.Set("chat_jid", chatJID)
+	q.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	q.Set("sig", s.sign(messageID, chatJID, expires.Unix()))
+	return s.baseURL + PathPrefix + url.PathEscape(messageID) + "?" + q.Encode(), expires.UTC()
+}
+
+// Verify reports whether query signs the media of messageID and has not
+// expired at now. It returns the chat the URL is limited to.
+func (s *Signer) Verify(messageID string, query url.Values, now time.Time) (string, bool) {
+	chatJID, sig := query.Get("chat_jid"), query.Get("sig")
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || chatJID == "" || sig == "" || now.Unix() >= expires {
+		return "", false
+	}
+	if !hmac.Equal([]byte(sig), []byte(s.sign(messageID, chatJID, expires))) {
+		return "", false
+	}
+	return chatJID, true
+}
+
+func (s *Signer) sign(messageID, chatJID string, expires int64) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(messageID + "\n" + chatJID + "\n" + strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
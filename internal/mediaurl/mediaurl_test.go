@@ -0,0 +1,47 @@
+package mediaurl
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	s := New("test-key", "https://wa.example.com/", 10*time.Minute)
+	now := time.Unix(1_750_000_000, 0)
+	raw, expires := s.URL("3EB0 A/1", "120363012345678901@g.us", now)
+	assert.Equal(t, now.Add(10*time.Minute).UTC(), expires)
+
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "wa.example.com", u.Host)
+	require.True(t, strings.HasPrefix(u.Path, PathPrefix))
+	id := strings.TrimPrefix(u.Path, PathPrefix)
+	assert.Equal(t, "3EB0 A/1", id)
+
+	chat, ok := s.Verify(id, u.Query(), now.Add(time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, "120363012345678901@g.us", chat)
+
+	// Expired, for another message or chat, or signed with another API key
+	_, ok = s.Verify(id, u.Query(), expires)
+	assert.False(t, ok)
+	_, ok = s.Verify("other", u.Query(), now)
+	assert.False(t, ok)
+	q := u.Query()
+	q.Set("chat_jid", "34600111222@s.whatsapp.net")
+	_, ok = s.Verify(id, q, now)
+	assert.False(t, ok)
+	q = u.Query()
+	q.Set("expires", "9999999999")
+	_, ok = s.Verify(id, q, now)
+	assert.False(t, ok)
+	_, ok = New("other-key", "https://wa.example.com", 0).Verify(id, u.Query(), now)
+	assert.False(t, ok)
+	_, ok = s.Verify(id, url.Values{}, now)
+	assert.False(t, ok)
+}
@@ -0,0 +1,498 @@
+// Package mqtt is a minimal MQTT 3.1.1 client: QoS 0 publishes (optionally
+// retained), subscriptions with a last will, and keep-alive pings. It
+// reconnects on the next publish after a failure, or in the background
+// while Run is active.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Packet types, shifted into the high nibble of the fixed header.
+const (
+	packetConnect     = 1
+	packetConnAck     = 2
+	packetPublish     = 3
+	packetPubAck      = 4
+	packetSubscribe   = 8
+	packetSubAck      = 9
+	packetPingReq     = 12
+	packetPingResp    = 13
+	packetDisconnect  = 14
+	maxRemainingBytes = 268435455
+)
+
+// DefaultKeepAlive is the keep-alive interval announced to the broker.
+const DefaultKeepAlive = 60 * time.Second
+
+// ErrNotConnected is returned by Publish after Close.
+var ErrNotConnected = errors.New("mqtt: client closed")
+
+// Handler receives the messages of a subscription.
+type Handler func(topic string, payload []byte)
+
+// Client is an MQTT connection to one broker.
+type Client struct {
+	url       *url.URL
+	clientID  string
+	timeout   time.Duration
+	keepAlive time.Duration
+
+	willTopic   string
+	willPayload []byte
+	willRetain  bool
+	onConnect   func()
+
+	mu       sync.Mutex
+	conn     net.Conn
+	w        *bufio.Writer
+	subs     map[string]Handler
+	nextID   uint16
+	closed   bool
+	lastRead atomic.Int64 // unix nanoseconds
+	lost     chan struct{}
+}
+
+// New parses an mqtt:// or mqtts:// URL of the form
+// mqtt://[user:password@]host[:port].
+func New(rawURL, clientID string) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MQTT URL: %w", err)
+	}
+	if u.Scheme != "mqtt" && u.Scheme != "mqtts" {
+		return nil, fmt.Errorf("invalid MQTT URL %q: scheme must be mqtt:// or mqtts://", rawURL)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid MQTT URL %q: host is required", rawURL)
+	}
+	if u.Port() == "" {
+		port := "1883"
+		if u.Scheme == "mqtts" {
+			port = "8883"
+		}
+		u.Host = net.JoinHostPort(u.Hostname(), port)
+	}
+	return &Client{
+		url:       u,
+		clientID:  clientID,
+		timeout:   5 * time.Second,
+		keepAlive: DefaultKeepAlive,
+		subs:      make(map[string]Handler),
+	}, nil
+}
+
+// SetWill sets the message the broker publishes when the connection is
+// lost without a disconnect. It applies from the next connection.
+func (c *Client) SetWill(topic string, payload []byte, retain bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.willTopic, c.willPayload, c.willRetain = topic, payload, retain
+}
+
+// OnConnect sets a function run after every successful connection, e.g. to
+// publish retained state the broker may have lost.
+func (c *Client) OnConnect(f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onConnect = f
+}
+
+// Subscribe registers handler for topics matching filter, which may use the
+// + and # wildcards. Subscriptions are renewed on every connection; handlers
+// run on the connection's read loop, so they must not block.
+func (c *Client) Subscribe(filter string, handler Handler) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subs[filter] = handler
+	if c.conn == nil {
+		return nil
+	}
+	if err := c.writeSubscribe([]string{filter}); err != nil {
+		c.closeLocked()
+		return fmt.Errorf("mqtt subscribe: %w", err)
+	}
+	return nil
+}
+
+// Publish sends payload to topic with QoS 0.
+func (c *Client) Publish(ctx context.Context, topic string, payload []byte, retain bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		if err := c.connectLocked(ctx); err != nil {
+			return err
+		}
+	}
+	header := byte(packetPublish << 4)
+	if retain {
+		header |= 0x01
+	}
+	body := appendString(nil, topic)
+	body = append(body, payload...)
+	c.setWriteDeadline(ctx)
+	if err := writePacket(c.w, header, body); err != nil {
+		c.closeLocked()
+		return fmt.Errorf("mqtt publish: %w", err)
+	}
+	return nil
+}
+
+// Run keeps the client connected until ctx is cancelled, reconnecting with
+// backoff and pinging the broker. It leaves the connection open, so a last
+// message can still be published before Close.
+func (c *Client) Run(ctx context.Context) {
+	backoff := time.Second
+	ping := time.NewTicker(c.keepAlive / 2)
+	defer ping.Stop()
+	for {
+		c.mu.Lock()
+		lost := c.lost
+		if c.conn == nil {
+			if err := c.connectLocked(ctx); err != nil {
+				c.mu.Unlock()
+				if ctx.Err() != nil {
+					return
+				}
+				fmt.Fprintf(os.Stderr, "\n⚠ MQTT: %v, retrying in %s\n", err, backoff)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				backoff = min(2*backoff, time.Minute)
+				continue
+			}
+			lost = c.lost
+			backoff = time.Second
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-lost:
+		case <-ping.C:
+			c.ping()
+		}
+	}
+}
+
+// ping sends a PINGREQ, and drops the connection when the broker has been
+// silent for more than one and a half keep-alive intervals.
+func (c *Client) ping() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return
+	}
+	if time.Since(time.Unix(0, c.lastRead.Load())) > c.keepAlive*3/2 {
+		c.closeLocked()
+		return
+	}
+	c.setWriteDeadline(context.Background())
+	if err := writePacket(c.w, packetPingReq<<4, nil); err != nil {
+		c.closeLocked()
+	}
+}
+
+// connectLocked dials the broker, completes the CONNECT/CONNACK handshake
+// and renews the subscriptions.
+func (c *Client) connectLocked(ctx context.Context) error {
+	if c.closed {
+		return ErrNotConnected
+	}
+	d := net.Dialer{Timeout: c.timeout}
+	conn, err := d.DialContext(ctx, "tcp", c.url.Host)
+	if err != nil {
+		return fmt.Errorf("mqtt connect: %w", err)
+	}
+	if c.url.Scheme == "mqtts" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: c.url.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return fmt.Errorf("mqtt tls: %w", err)
+		}
+		conn = tlsConn
+	}
+	conn.SetDeadline(time.Now().Add(c.timeout))
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	if err := writePacket(w, packetConnect<<4, c.connectBody()); err != nil {
+		conn.Close()
+		return fmt.Errorf("mqtt connect: %w", err)
+	}
+	header, body, err := readPacket(r)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("mqtt connect: %w", err)
+	}
+	if header>>4 != packetConnAck || len(body) != 2 {
+		conn.Close()
+		return fmt.Errorf("mqtt connect: unexpected packet type %d", header>>4)
+	}
+	if rc := body[1]; rc != 0 {
+		conn.Close()
+		return fmt.Errorf("mqtt connect: %s", connAckReason(rc))
+	}
+	conn.SetDeadline(time.Time{})
+
+	c.conn, c.w = conn, w
+	c.lost = make(chan struct{})
+	c.lastRead.Store(time.Now().UnixNano())
+	if len(c.subs) > 0 {
+		filters := make([]string, 0, len(c.subs))
+		for f := range c.subs {
+			filters = append(filters, f)
+		}
+		if err := c.writeSubscribe(filters); err != nil {
+			c.closeLocked()
+			return fmt.Errorf("mqtt subscribe: %w", err)
+		}
+	}
+	go c.readLoop(conn, r)
+	if c.onConnect != nil {
+		go c.onConnect()
+	}
+	return nil
+}
+
+func (c *Client) connectBody() []byte {
+	var flags byte = 0x02 // clean session
+	body := appendString(nil, "MQTT")
+	body = append(body, 4) // protocol level 3.1.1
+	if c.willTopic != "" {
+		flags |= 0x04
+		if c.willRetain {
+			flags |= 0x20
+		}
+	}
+	user := c.url.User
+	if user != nil {
+		flags |= 0x80
+		if _, ok := user.Password(); ok {
+			flags |= 0x40
+		}
+	}
+	body = append(body, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(c.keepAlive/time.Second))
+	body = appendString(body, c.clientID)
+	if c.willTopic != "" {
+		body = appendString(body, c.willTopic)
+		body = binary.BigEndian.AppendUint16(body, uint16(len(c.willPayload)))
+		body = append(body, c.willPayload...)
+	}
+	if user != nil {
+		body = appendString(body, user.Username())
+		if pass, ok := user.Password(); ok {
+			body = appendString(body, pass)
+		}
+	}
+	return body
+}
+
+func (c *Client) writeSubscribe(filters []string) error {
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID = 1
+	}
+	body := binary.BigEndian.AppendUint16(nil, c.nextID)
+	for _, f := range filters {
+		body = appendString(body, f)
+		body = append(body, 0) // QoS 0
+	}
+	c.setWriteDeadline(context.Background())
+	return writePacket(c.w, packetSubscribe<<4|0x02, body)
+}
+
+// readLoop dispatches incoming publishes and closes the connection when it
+// fails.
+func (c *Client) readLoop(conn net.Conn, r *bufio.Reader) {
+	for {
+		header, body, err := readPacket(r)
+		if err != nil {
+			break
+		}
+		c.lastRead.Store(time.Now().UnixNano())
+		if header>>4 != packetPublish {
+			continue
+		}
+		topic, rest, ok := readString(body)
+		if !ok {
+			break
+		}
+		if qos := header >> 1 & 0x03; qos > 0 {
+			if len(rest) < 2 {
+				break
+			}
+			id := rest[:2]
+			rest = rest[2:]
+			if qos == 1 {
+				c.mu.Lock()
+				if c.conn == conn {
+					writePacket(c.w, packetPubAck<<4, id)
+				}
+				c.mu.Unlock()
+			}
+		}
+		c.dispatch(topic, rest)
+	}
+	c.mu.Lock()
+	if c.conn == conn {
+		c.closeLocked()
+	}
+	c.mu.Unlock()
+}
+
+func (c *Client) dispatch(topic string, payload []byte) {
+	c.mu.Lock()
+	var handlers []Handler
+	for filter, h := range c.subs {
+		if Match(filter, topic) {
+			handlers = append(handlers, h)
+		}
+	}
+	c.mu.Unlock()
+	for _, h := range handlers {
+		h(topic, payload)
+	}
+}
+
+func (c *Client) setWriteDeadline(ctx context.Context) {
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetWriteDeadline(deadline)
+	} else {
+		c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	}
+}
+
+func (c *Client) closeLocked() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn, c.w = nil, nil
+		close(c.lost)
+	}
+}
+
+// Close disconnects from the broker; the last will is not published.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		c.setWriteDeadline(context.Background())
+		writePacket(c.w, packetDisconnect<<4, nil)
+	}
+	c.closeLocked()
+	c.closed = true
+	return nil
+}
+
+// Match reports whether topic matches filter, which may use the + (one
+// level) and # (all remaining levels) wildcards.
+func Match(filter, topic string) bool {
+	fl := strings.Split(filter, "/")
+	tl := strings.Split(topic, "/")
+	for i, f := range fl {
+		if f == "#" {
+			return true
+		}
+		if i >= len(tl) || (f != "+" && f != tl[i]) {
+			return false
+		}
+	}
+	return len(fl) == len(tl)
+}
+
+func connAckReason(rc byte) string {
+	switch rc {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad user name or password"
+	case 5:
+		return "not authorized"
+	}
+	return fmt.Sprintf("refused with code %d", rc)
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func readString(b []byte) (string, []byte, bool) {
+	if len(b) < 2 {
+		return "", nil, false
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, false
+	}
+	return string(b[2 : 2+n]), b[2+n:], true
+}
+
+func writePacket(w *bufio.Writer, header byte, body []byte) error {
+	if len(body) > maxRemainingBytes {
+		return fmt.Errorf("packet of %d bytes is too large", len(body))
+	}
+	w.WriteByte(header)
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		w.WriteByte(b)
+		if n == 0 {
+			break
+		}
+	}
+	w.Write(body)
+	return w.Flush()
+}
+
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, mult := 0, 1
+	for i := 0; ; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n += int(b&0x7f) * mult
+		if b&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return 0, nil, fmt.Errorf("malformed remaining length")
+		}
+		mult *= 128
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
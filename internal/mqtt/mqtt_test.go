@@ -0,0 +1,117 @@
+package mqtt_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vicentereig/whatsapp-cli/internal/mqtt"
+	"github.com/vicentereig/whatsapp-cli/internal/mqtt/mqtttest"
+)
+
+func TestPublishAndSubscribe(t *testing.T) {
+	broker := mqtttest.New(t)
+	broker.Username, broker.Password = "alice", "s3cret"
+
+	c, err := mqtt.New("mqtt://alice:s3cret@"+broker.Addr, "test-client")
+	require.NoError(t, err)
+	defer c.Close()
+	c.SetWill("whatsapp/status", []byte("offline"), true)
+
+	got := make(chan string, 4)
+	require.NoError(t, c.Subscribe("whatsapp/chat/+/send", func(topic string, payload []byte) {
+		got <- topic + " " + string(payload)
+	}))
+
+	require.NoError(t, c.Publish(context.Background(), "whatsapp/status", []byte("online"), true))
+	require.Eventually(t, func() bool { return broker.Subscribed("whatsapp/chat/1/send") }, time.Second, 5*time.Millisecond)
+
+	connects := broker.Connects()
+	require.Len(t, connects, 1)
+	assert.Equal(t, "test-client", connects[0].ClientID)
+	require.NotNil(t, connects[0].Will)
+	assert.Equal(t, "offline", connects[0].Will.Payload)
+
+	m, ok := broker.Retained("whatsapp/status")
+	require.True(t, ok)
+	assert.Equal(t, "online", m.Payload)
+
+	broker.Publish("whatsapp/chat/1/send", "hello", false)
+	broker.Publish("whatsapp/other", "ignored", false)
+	select {
+	case msg := <-got:
+		assert.Equal(t, "whatsapp/chat/1/send hello", msg)
+	case <-time.After(time.Second):
+		t.Fatal("subscribed message not delivered")
+	}
+	assert.Len(t, got, 0)
+}
+
+func TestRejectedCredentials(t *testing.T) {
+	broker := mqtttest.New(t)
+	broker.Username, broker.Password = "alice", "s3cret"
+
+	c, err := mqtt.New("mqtt://alice:wrong@"+broker.Addr, "test-client")
+	require.NoError(t, err)
+	err = c.Publish(context.Background(), "t", []byte("x"), false)
+	assert.ErrorContains(t, err, "bad user name or password")
+}
+
+func TestRunReconnectsAndResubscribes(t *testing.T) {
+	broker := mqtttest.New(t)
+	c, err := mqtt.New(broker.URL(), "test-client")
+	require.NoError(t, err)
+	c.SetWill("whatsapp/status", []byte("offline"), true)
+	connected := make(chan struct{}, 4)
+	c.OnConnect(func() {
+		c.Publish(context.Background(), "whatsapp/status", []byte("online"), true)
+		connected <- struct{}{}
+	})
+	require.NoError(t, c.Subscribe("whatsapp/send", func(string, []byte) {}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+	<-connected
+	require.Eventually(t, func() bool { return len(broker.Messages()) == 1 }, time.Second, 5*time.Millisecond)
+
+	// A lost connection publishes the will, then Run reconnects, renews the
+	// subscription and restores the state
+	broker.DropClients()
+	<-connected
+	require.Eventually(t, func() bool { return broker.Subscribed("whatsapp/send") }, 3*time.Second, 5*time.Millisecond)
+	require.Eventually(t, func() bool {
+		m, _ := broker.Retained("whatsapp/status")
+		return m.Payload == "online"
+	}, time.Second, 5*time.Millisecond)
+	var payloads []string
+	for _, m := range broker.Messages() {
+		payloads = append(payloads, m.Payload)
+	}
+	assert.Equal(t, []string{"online", "offline", "online"}, payloads)
+
+	cancel()
+	<-done
+	require.NoError(t, c.Close())
+	assert.Len(t, broker.Connects(), 2)
+}
+
+func TestNewRejectsInvalidURLs(t *testing.T) {
+	for _, u := range []string{"http://broker", "mqtt://", "://"} {
+		_, err := mqtt.New(u, "c")
+		assert.Error(t, err, u)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	assert.True(t, mqtt.Match("a/+/c", "a/b/c"))
+	assert.True(t, mqtt.Match("a/#", "a/b/c"))
+	assert.True(t, mqtt.Match("a/b", "a/b"))
+	assert.False(t, mqtt.Match("a/+", "a/b/c"))
+	assert.False(t, mqtt.Match("a/b/c", "a/b"))
+}
@@ -0,0 +1,380 @@
+// Package mqtttest is an in-memory MQTT 3.1.1 broker for tests. It supports
+// what package mqtt uses: QoS 0 publishes, retained messages, wildcard
+// subscriptions, last wills and pings.
+package mqtttest
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Message is a publish the broker received.
+type Message struct {
+	ClientID string
+	Topic    string
+	Payload  string
+	Retain   bool
+}
+
+// Connect is a CONNECT the broker accepted.
+type Connect struct {
+	ClientID string
+	Username string
+	Password string
+	Will     *Message
+}
+
+// Broker accepts any client, or only those with Username and Password when
+// set.
+type Broker struct {
+	Addr     string
+	Username string
+	Password string
+
+	ln       net.Listener
+	mu       sync.Mutex
+	clients  map[*client]bool
+	retained map[string]Message
+	messages []Message
+	connects []Connect
+}
+
+type client struct {
+	id   string
+	conn net.Conn
+	mu   sync.Mutex
+	subs []string
+	will *Message
+}
+
+// New starts a broker on a random local port, stopped when the test ends.
+func New(t *testing.T) *Broker {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &Broker{
+		Addr:     ln.Addr().String(),
+		ln:       ln,
+		clients:  make(map[*client]bool),
+		retained: make(map[string]Message),
+	}
+	go b.accept()
+	t.Cleanup(b.Close)
+	return b
+}
+
+// URL is the mqtt:// URL of the broker.
+func (b *Broker) URL() string {
+	return "mqtt://" + b.Addr
+}
+
+// Messages returns the publishes received so far, in order.
+func (b *Broker) Messages() []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Message(nil), b.messages...)
+}
+
+// Retained returns the retained message of topic.
+func (b *Broker) Retained(topic string) (Message, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	m, ok := b.retained[topic]
+	return m, ok
+}
+
+// Connects returns the accepted connections, in order.
+func (b *Broker) Connects() []Connect {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Connect(nil), b.connects...)
+}
+
+// Subscribed reports whether a client subscribed to a filter matching topic.
+func (b *Broker) Subscribed(topic string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.clients {
+		if c.subscribed(topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// Publish delivers a message to the subscribed clients, as if another client
+// had published it.
+func (b *Broker) Publish(topic, payload string, retain bool) {
+	b.route(Message{ClientID: "mqtttest", Topic: topic, Payload: payload, Retain: retain})
+}
+
+// DropClients closes every connection without a DISCONNECT, so the wills of
+// the clients are published.
+func (b *Broker) DropClients() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.clients {
+		c.conn.Close()
+	}
+}
+
+// Close stops the broker.
+func (b *Broker) Close() {
+	b.ln.Close()
+	b.DropClients()
+}
+
+func (b *Broker) accept() {
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		go b.serve(conn)
+	}
+}
+
+func (b *Broker) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	header, body, err := readPacket(r)
+	if err != nil || header>>4 != 1 {
+		return
+	}
+	c, connect, ok := parseConnect(body)
+	if !ok {
+		return
+	}
+	c.conn = conn
+	if b.Username != "" && (connect.Username != b.Username || connect.Password != b.Password) {
+		writePacket(conn, 0x20, []byte{0, 4})
+		return
+	}
+	b.mu.Lock()
+	b.connects = append(b.connects, connect)
+	b.clients[c] = true
+	b.mu.Unlock()
+	c.write(0x20, []byte{0, 0})
+
+	clean := false
+	for {
+		header, body, err := readPacket(r)
+		if err != nil {
+			break
+		}
+		switch header >> 4 {
+		case 3:
+			topic, rest, ok := readString(body)
+			if !ok {
+				break
+			}
+			if header>>1&0x03 > 0 && len(rest) >= 2 {
+				rest = rest[2:]
+			}
+			b.route(Message{ClientID: c.id, Topic: topic, Payload: string(rest), Retain: header&0x01 != 0})
+		case 8:
+			if len(body) < 2 {
+				break
+			}
+			id, rest := body[:2], body[2:]
+			var filters []string
+			for len(rest) > 0 {
+				f, tail, ok := readString(rest)
+				if !ok || len(tail) < 1 {
+					break
+				}
+				filters = append(filters, f)
+				rest = tail[1:]
+			}
+			c.mu.Lock()
+			c.subs = append(c.subs, filters...)
+			c.mu.Unlock()
+			c.write(0x90, append(id, make([]byte, len(filters))...))
+			b.sendRetained(c, filters)
+		case 12:
+			c.write(0xd0, nil)
+		case 14:
+			clean = true
+		}
+		if clean {
+			break
+		}
+	}
+
+	b.mu.Lock()
+	delete(b.clients, c)
+	b.mu.Unlock()
+	if !clean && c.will != nil {
+		b.route(*c.will)
+	}
+}
+
+func (b *Broker) route(m Message) {
+	b.mu.Lock()
+	b.messages = append(b.messages, m)
+	if m.Retain {
+		if m.Payload == "" {
+			delete(b.retained, m.Topic)
+		} else {
+			b.retained[m.Topic] = m
+		}
+	}
+	var targets []*client
+	for c := range b.clients {
+		if c.subscribed(m.Topic) {
+			targets = append(targets, c)
+		}
+	}
+	b.mu.Unlock()
+	for _, c := range targets {
+		c.write(0x30, append(appendString(nil, m.Topic), m.Payload...))
+	}
+}
+
+func (b *Broker) sendRetained(c *client, filters []string) {
+	b.mu.Lock()
+	var msgs []Message
+	for _, m := range b.retained {
+		for _, f := range filters {
+			if match(f, m.Topic) {
+				msgs = append(msgs, m)
+				break
+			}
+		}
+	}
+	b.mu.Unlock()
+	for _, m := range msgs {
+		c.write(0x31, append(appendString(nil, m.Topic), m.Payload...))
+	}
+}
+
+func (c *client) subscribed(topic string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, f := range c.subs {
+		if match(f, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *client) write(header byte, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	writePacket(c.conn, header, body)
+}
+
+func parseConnect(body []byte) (*client, Connect, bool) {
+	proto, rest, ok := readString(body)
+	if !ok || proto != "MQTT" || len(rest) < 4 {
+		return nil, Connect{}, false
+	}
+	flags := rest[1]
+	rest = rest[4:]
+	var connect Connect
+	if connect.ClientID, rest, ok = readString(rest); !ok {
+		return nil, Connect{}, false
+	}
+	c := &client{id: connect.ClientID}
+	if flags&0x04 != 0 {
+		var topic, payload string
+		if topic, rest, ok = readString(rest); !ok {
+			return nil, Connect{}, false
+		}
+		if payload, rest, ok = readString(rest); !ok {
+			return nil, Connect{}, false
+		}
+		c.will = &Message{ClientID: c.id, Topic: topic, Payload: payload, Retain: flags&0x20 != 0}
+		connect.Will = c.will
+	}
+	if flags&0x80 != 0 {
+		if connect.Username, rest, ok = readString(rest); !ok {
+			return nil, Connect{}, false
+		}
+	}
+	if flags&0x40 != 0 {
+		if connect.Password, _, ok = readString(rest); !ok {
+			return nil, Connect{}, false
+		}
+	}
+	return c, connect, true
+}
+
+func match(filter, topic string) bool {
+	fl := strings.Split(filter, "/")
+	tl := strings.Split(topic, "/")
+	for i, f := range fl {
+		if f == "#" {
+			return true
+		}
+		if i >= len(tl) || (f != "+" && f != tl[i]) {
+			return false
+		}
+	}
+	return len(fl) == len(tl)
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func readString(b []byte) (string, []byte, bool) {
+	if len(b) < 2 {
+		return "", nil, false
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, false
+	}
+	return string(b[2 : 2+n]), b[2+n:], true
+}
+
+func writePacket(w io.Writer, header byte, body []byte) {
+	buf := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if n == 0 {
+			break
+		}
+	}
+	w.Write(append(buf, body...))
+}
+
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, mult := 0, 1
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n += int(b&0x7f) * mult
+		if b&0x80 == 0 {
+			break
+		}
+		mult *= 128
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
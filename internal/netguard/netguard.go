@@ -0,0 +1,99 @@
+// Package netguard keeps requests to URLs supplied by API clients, such as
+// calendar feeds and media links, from reaching the host's own network.
+//
+// Addresses are checked when a connection is dialed, after DNS resolution,
+// so a name that resolves to a private address and redirects to one are
+// refused as well. Proxies from the environment are not used, since the
+// dialed address would then be the proxy's.
+package netguard
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// reservedPrefixes reach internal services without being loopback, private
+// or link-local by net/netip's definition.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"),  // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),  // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),    // reserved, and broadcast
+	netip.MustParsePrefix("64:ff9b:1::/48"), // local-use NAT64
+	netip.MustParsePrefix("fec0::/10"),      // site-local
+}
+
+// Blocked reports whether requests must not reach ip: loopback, private,
+// link-local, multicast and reserved addresses are blocked unless they are
+// in allowed.
+func Blocked(ip netip.Addr, allowed []netip.Prefix) bool {
+	ip = ip.Unmap()
+	for _, p := range allowed {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return true
+	}
+	for _, p := range reservedPrefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Client returns an HTTP client that refuses to connect to blocked
+// addresses outside of allowed.
+func Client(timeout time.Duration, allowed []netip.Prefix) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if Blocked(ap.Addr(), allowed) {
+				return fmt.Errorf("connecting to %s is not allowed", ap.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// ParseNetworks parses a comma-separated list of CIDR prefixes or single
+// addresses, e.g. "10.0.0.0/8,192.168.1.5".
+func ParseNetworks(s string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if !strings.Contains(f, "/") {
+			ip, err := netip.ParseAddr(f)
+			if err != nil {
+				return nil, fmt.Errorf("invalid network %q", f)
+			}
+			out = append(out, netip.PrefixFrom(ip.Unmap(), ip.Unmap().BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q", f)
+		}
+		out = append(out, p.Masked())
+	}
+	return out, nil
+}
@@ -0,0 +1,64 @@
+package netguard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlocked(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1":       true,
+		"::1":             true,
+		"10.1.2.3":        true,
+		"172.20.0.1":      true,
+		"192.168.1.10":    true,
+		"169.254.169.254": true,
+		"100.100.0.1":     true,
+		"0.0.0.0":         true,
+		"::":              true,
+		"fd00::1":         true,
+		"fe80::1":         true,
+		"::ffff:10.0.0.1": true,
+		"224.0.0.1":       true,
+		"8.8.8.8":         false,
+		"2606:4700::1111": false,
+	} {
+		assert.Equal(t, want, Blocked(netip.MustParseAddr(addr), nil), addr)
+	}
+
+	allowed, err := ParseNetworks("10.0.0.0/8, 192.168.1.10")
+	require.NoError(t, err)
+	assert.False(t, Blocked(netip.MustParseAddr("10.1.2.3"), allowed))
+	assert.False(t, Blocked(netip.MustParseAddr("192.168.1.10"), allowed))
+	assert.True(t, Blocked(netip.MustParseAddr("192.168.1.11"), allowed))
+
+	_, err = ParseNetworks("10.0.0.0/33")
+	assert.Error(t, err)
+}
+
+func TestClientRefusesBlockedAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	_, err := Client(time.Second, nil).Get(srv.URL)
+	assert.ErrorContains(t, err, "not allowed")
+
+	// Names are checked after resolution
+	_, err = Client(time.Second, nil).Get("http://localhost:1/")
+	assert.ErrorContains(t, err, "not allowed")
+
+	loopback, err := ParseNetworks("127.0.0.0/8")
+	require.NoError(t, err)
+	resp, err := Client(time.Second, loopback).Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,50 @@
+// Package reqid carries request IDs through contexts so work triggered by an
+// API request can be correlated across logs, responses and webhooks.
+package reqid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Header is the HTTP header used to accept and propagate request IDs.
+const Header = "X-Request-ID"
+
+// maxLen bounds client-supplied IDs so they stay safe to log and echo.
+const maxLen = 128
+
+type contextKey struct{}
+
+// New returns a random 128-bit request ID in hex.
+func New() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// Valid reports whether a client-supplied ID may be reused: 1-128 characters
+// of letters, digits, '-', '_', '.' or ':'.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+			r == '-' || r == '_' || r == '.' || r == ':') {
+			return false
+		}
+	}
+	return true
+}
+
+// NewContext returns a copy of ctx carrying id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx, or "".
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -0,0 +1,30 @@
+package reqid
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	a, b := New(), New()
+	assert.Len(t, a, 32)
+	assert.NotEqual(t, a, b)
+	assert.True(t, Valid(a))
+}
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid("req-123_abc.def:1"))
+	assert.False(t, Valid(""))
+	assert.False(t, Valid("has space"))
+	assert.False(t, Valid("line\nbreak"))
+	assert.False(t, Valid(strings.Repeat("a", 129)))
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, FromContext(ctx))
+	assert.Equal(t, "abc", FromContext(NewContext(ctx, "abc")))
+}
@@ -0,0 +1,362 @@
+// Package selfupdate checks GitHub releases for newer versions of the CLI and
+// replaces the running binary with a verified release artifact.
+package selfupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRepo is the GitHub repository releases are published to.
+const DefaultRepo = "vicentereig/whatsapp-cli"
+
+// SignatureAsset is the release asset holding the base64 ed25519 signature
+// of checksums.txt.
+const SignatureAsset = "checksums.txt.ed25519"
+
+// maxDownload caps release downloads so a bad response cannot fill the disk.
+const maxDownload = 200 << 20
+
+// Release is a published GitHub release.
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	URL    string  `json:"html_url"`
+	Assets []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+func (r *Release) asset(name string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Status describes how the running version compares to the latest release.
+type Status struct {
+	Current         string `json:"current"`
+	Latest          string `json:"latest"`
+	UpdateAvailable bool   `json:"update_available"`
+	ReleaseURL      string `json:"release_url,omitempty"`
+}
+
+// Updater fetches releases of Repo for the OS/Arch platform. PublicKey is
+// the release signing key; Apply refuses to install anything without it.
+type Updater struct {
+	Repo      string
+	APIURL    string
+	Client    *http.Client
+	OS        string
+	Arch      string
+	PublicKey ed25519.PublicKey
+}
+
+// New returns an Updater for the official releases of the current platform.
+func New() *Updater {
+	return &Updater{
+		Repo:   DefaultRepo,
+		APIURL: "https://api.github.com",
+		Client: &http.Client{Timeout: 2 * time.Minute},
+		OS:     runtime.GOOS,
+		Arch:   runtime.GOARCH,
+	}
+}
+
+// ParsePublicKey decodes a base64 raw ed25519 public key. An empty key
+// decodes to nil, which leaves self-update disabled.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid release signing key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// Latest returns the most recent published release.
+func (u *Updater) Latest(ctx context.Context) (*Release, error) {
+	body, err := u.get(ctx, fmt.Sprintf("%s/repos/%s/releases/latest", strings.TrimRight(u.APIURL, "/"), u.Repo), "application/vnd.github+json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch latest release: %w", err)
+	}
+	var rel Release
+	if err := json.Unmarshal(body, &rel); err != nil {
+		return nil, fmt.Errorf("failed to decode latest release: %w", err)
+	}
+	if rel.Tag == "" {
+		return nil, fmt.Errorf("latest release has no tag")
+	}
+	return &rel, nil
+}
+
+// Check compares current against the latest release.
+func (u *Updater) Check(ctx context.Context, current string) (Status, *Release, error) {
+	rel, err := u.Latest(ctx)
+	if err != nil {
+		return Status{}, nil, err
+	}
+	return Status{
+		Current:         current,
+		Latest:          rel.Tag,
+		UpdateAvailable: Newer(rel.Tag, current),
+		ReleaseURL:      rel.URL,
+	}, rel, nil
+}
+
+// ArchiveName is the release archive holding the binary for a platform.
+func ArchiveName(goos, goarch string) string {
+	if goos == "windows" {
+		return fmt.Sprintf("whatsapp-cli-%s-%s.zip", goos, goarch)
+	}
+	return fmt.Sprintf("whatsapp-cli-%s-%s.tar.gz", goos, goarch)
+}
+
+func binaryName(goos, goarch string) string {
+	if goos == "windows" {
+		return fmt.Sprintf("whatsapp-cli-%s-%s.exe", goos, goarch)
+	}
+	return fmt.Sprintf("whatsapp-cli-%s-%s", goos, goarch)
+}
+
+// Apply downloads the release archive for the updater's platform, checks it
+// against the release's checksums.txt, whose signature must verify against
+// PublicKey, and atomically replaces exePath with the binary inside it.
+func (u *Updater) Apply(ctx context.Context, rel *Release, exePath string) error {
+	if len(u.PublicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("this build has no release signing key, so it cannot verify updates; download the release manually")
+	}
+	archiveName := ArchiveName(u.OS, u.Arch)
+	archive, ok := rel.asset(archiveName)
+	if !ok {
+		return fmt.Errorf("release %s has no build for %s/%s", rel.Tag, u.OS, u.Arch)
+	}
+	sums, ok := rel.asset("checksums.txt")
+	if !ok {
+		return fmt.Errorf("release %s has no checksums.txt", rel.Tag)
+	}
+	sig, ok := rel.asset(SignatureAsset)
+	if !ok {
+		return fmt.Errorf("release %s has no %s", rel.Tag, SignatureAsset)
+	}
+
+	sumsBody, err := u.get(ctx, sums.URL, "")
+	if err != nil {
+		return fmt.Errorf("failed to download checksums: %w", err)
+	}
+	sigBody, err := u.get(ctx, sig.URL, "")
+	if err != nil {
+		return fmt.Errorf("failed to download checksums signature: %w", err)
+	}
+	if !verifySignature(u.PublicKey, sumsBody, sigBody) {
+		return fmt.Errorf("checksums.txt of release %s is not signed by the release key", rel.Tag)
+	}
+	want, err := checksumFor(sumsBody, archiveName)
+	if err != nil {
+		return err
+	}
+	data, err := u.get(ctx, archive.URL, "")
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", archiveName, err)
+	}
+	got := sha256.Sum256(data)
+	if hex.EncodeToString(got[:]) != want {
+		return fmt.Errorf("checksum mismatch for %s", archiveName)
+	}
+
+	bin, err := extract(data, u.OS, binaryName(u.OS, u.Arch))
+	if err != nil {
+		return err
+	}
+	return replaceBinary(exePath, bin)
+}
+
+func (u *Updater) get(ctx context.Context, url, accept string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := u.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDownload+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxDownload {
+		return nil, fmt.Errorf("GET %s: response exceeds %d bytes", url, maxDownload)
+	}
+	return body, nil
+}
+
+func verifySignature(key ed25519.PublicKey, msg, sig []byte) bool {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil || len(raw) != ed25519.SignatureSize {
+		return false
+	}
+	return ed25519.Verify(key, msg, raw)
+}
+
+// checksumFor finds name in a sha256sum-style listing.
+func checksumFor(sums []byte, name string) (string, error) {
+	sc := bufio.NewScanner(bytes.NewReader(sums))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("checksums.txt has no entry for %s", name)
+}
+
+func extract(data []byte, goos, name string) ([]byte, error) {
+	if goos == "windows" {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open archive: %w", err)
+		}
+		for _, f := range zr.File {
+			if filepath.Base(f.Name) != name {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return io.ReadAll(io.LimitReader(rc, maxDownload))
+		}
+		return nil, fmt.Errorf("archive does not contain %s", name)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("archive does not contain %s", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeReg && filepath.Base(hdr.Name) == name {
+			return io.ReadAll(io.LimitReader(tr, maxDownload))
+		}
+	}
+}
+
+// replaceBinary writes bin next to exePath and renames it into place, so the
+// old binary stays intact if anything fails. Windows cannot overwrite a
+// running executable, so the old one is moved aside first.
+func replaceBinary(exePath string, bin []byte) error {
+	info, err := os.Stat(exePath)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(exePath)
+	tmp, err := os.CreateTemp(dir, ".whatsapp-cli-update-*")
+	if err != nil {
+		return fmt.Errorf("cannot write to %s: %w", dir, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(bin); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm() | 0o111); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "windows" {
+		old := exePath + ".old"
+		os.Remove(old)
+		if err := os.Rename(exePath, old); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp.Name(), exePath); err != nil {
+			os.Rename(old, exePath)
+			return err
+		}
+		return nil
+	}
+	return os.Rename(tmp.Name(), exePath)
+}
+
+// Newer reports whether latest is a higher semantic version than current.
+// Non-release versions such as "dev" or git describe output never compare
+// as older, so development builds are not offered updates.
+func Newer(latest, current string) bool {
+	l, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+func parseVersion(v string) ([3]int, bool) {
+	var out [3]int
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return out, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return out, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
@@ -0,0 +1,200 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tarball(t *testing.T, name string, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+	_, err := tw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+// releaseServer serves a fake GitHub API with one linux/amd64 release whose
+// checksums.txt is signed by the returned updater's key.
+func releaseServer(t *testing.T, archive []byte, checksum string) *Updater {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sums := fmt.Sprintf("%s  whatsapp-cli-darwin-arm64.tar.gz\n%s  %s\n", "00", checksum, ArchiveName("linux", "amd64"))
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	name := ArchiveName("linux", "amd64")
+	mux.HandleFunc("GET /repos/acme/cli/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Release{
+			Tag: "v2.0.0",
+			URL: "https://github.com/acme/cli/releases/tag/v2.0.0",
+			Assets: []Asset{
+				{Name: name, URL: srv.URL + "/download/" + name},
+				{Name: "checksums.txt", URL: srv.URL + "/download/checksums.txt"},
+				{Name: SignatureAsset, URL: srv.URL + "/download/" + SignatureAsset},
+			},
+		})
+	})
+	mux.HandleFunc("GET /download/"+name, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	})
+	mux.HandleFunc("GET /download/checksums.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, sums)
+	})
+	mux.HandleFunc("GET /download/"+SignatureAsset, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(sums))))
+	})
+
+	return &Updater{Repo: "acme/cli", APIURL: srv.URL, Client: srv.Client(), OS: "linux", Arch: "amd64", PublicKey: pub}
+}
+
+func sha(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestCheck(t *testing.T) {
+	u := releaseServer(t, nil, "")
+
+	status, rel, err := u.Check(context.Background(), "1.3.1")
+	require.NoError(t, err)
+	assert.Equal(t, "v2.0.0", rel.Tag)
+	assert.Equal(t, Status{
+		Current:         "1.3.1",
+		Latest:          "v2.0.0",
+		UpdateAvailable: true,
+		ReleaseURL:      "https://github.com/acme/cli/releases/tag/v2.0.0",
+	}, status)
+}
+
+func TestApplyReplacesBinary(t *testing.T) {
+	archive := tarball(t, "whatsapp-cli-linux-amd64", []byte("new binary"))
+	u := releaseServer(t, archive, sha(archive))
+	rel, err := u.Latest(context.Background())
+	require.NoError(t, err)
+
+	exe := filepath.Join(t.TempDir(), "whatsapp-cli")
+	require.NoError(t, os.WriteFile(exe, []byte("old binary"), 0755))
+
+	require.NoError(t, u.Apply(context.Background(), rel, exe))
+	got, err := os.ReadFile(exe)
+	require.NoError(t, err)
+	assert.Equal(t, "new binary", string(got))
+	info, err := os.Stat(exe)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	entries, err := os.ReadDir(filepath.Dir(exe))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary file left behind")
+}
+
+func TestApplyRejectsChecksumMismatch(t *testing.T) {
+	archive := tarball(t, "whatsapp-cli-linux-amd64", []byte("tampered"))
+	u := releaseServer(t, archive, sha([]byte("something else")))
+	rel, err := u.Latest(context.Background())
+	require.NoError(t, err)
+
+	exe := filepath.Join(t.TempDir(), "whatsapp-cli")
+	require.NoError(t, os.WriteFile(exe, []byte("old binary"), 0755))
+
+	err = u.Apply(context.Background(), rel, exe)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum mismatch")
+	got, _ := os.ReadFile(exe)
+	assert.Equal(t, "old binary", string(got))
+}
+
+func TestApplyRejectsUntrustedSignature(t *testing.T) {
+	archive := tarball(t, "whatsapp-cli-linux-amd64", []byte("new binary"))
+	u := releaseServer(t, archive, sha(archive))
+	rel, err := u.Latest(context.Background())
+	require.NoError(t, err)
+
+	exe := filepath.Join(t.TempDir(), "whatsapp-cli")
+	require.NoError(t, os.WriteFile(exe, []byte("old binary"), 0755))
+
+	other, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	u.PublicKey = other
+	err = u.Apply(context.Background(), rel, exe)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not signed by the release key")
+
+	u.PublicKey = nil
+	err = u.Apply(context.Background(), rel, exe)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no release signing key")
+
+	got, _ := os.ReadFile(exe)
+	assert.Equal(t, "old binary", string(got))
+}
+
+func TestApplyMissingPlatform(t *testing.T) {
+	u := releaseServer(t, nil, "")
+	rel, err := u.Latest(context.Background())
+	require.NoError(t, err)
+
+	u.OS, u.Arch = "plan9", "386"
+	err = u.Apply(context.Background(), rel, filepath.Join(t.TempDir(), "whatsapp-cli"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no build for plan9/386")
+}
+
+func TestParsePublicKey(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	key, err := ParsePublicKey(" " + base64.StdEncoding.EncodeToString(pub) + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, pub, key)
+
+	key, err = ParsePublicKey("")
+	require.NoError(t, err)
+	assert.Nil(t, key)
+
+	_, err = ParsePublicKey("c2hvcnQ=")
+	assert.Error(t, err)
+}
+
+func TestNewer(t *testing.T) {
+	for _, tc := range []struct {
+		latest, current string
+		want            bool
+	}{
+		{"v1.4.0", "1.3.1", true},
+		{"v1.3.10", "v1.3.9", true},
+		{"v2.0.0", "v1.9.9", true},
+		{"v1.3.1", "1.3.1", false},
+		{"v1.3.0", "v1.3.1", false},
+		{"v1.4.0", "dev", false},
+		{"v1.4.0", "v1.3.1-4-gabcdef0", false},
+		{"nightly", "v1.3.1", false},
+	} {
+		assert.Equal(t, tc.want, Newer(tc.latest, tc.current), "%s vs %s", tc.latest, tc.current)
+	}
+}
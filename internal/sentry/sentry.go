@@ -0,0 +1,264 @@
+// Package sentry reports errors and panics to a Sentry-compatible server
+// (Sentry, GlitchTip, Bugsink) through its envelope endpoint. Event text is
+// scrubbed of phone numbers and quoted strings before it leaves the process,
+// so message content and contacts are never reported.
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sendTimeout bounds how long delivering one event may take.
+const sendTimeout = 10 * time.Second
+
+// Client sends events to the project a DSN points at. A nil *Client is valid
+// and discards everything, so callers need not check whether reporting is
+// configured.
+type Client struct {
+	endpoint    string
+	key         string
+	dsn         string
+	release     string
+	environment string
+	serverName  string
+	http        *http.Client
+	wg          sync.WaitGroup
+}
+
+// New parses dsn ("https://<key>@<host>/<project id>") and returns a client
+// tagging events with release and environment.
+func New(dsn, release, environment string) (*Client, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User == nil {
+		return nil, fmt.Errorf("invalid DSN (expected https://<key>@<host>/<project>)")
+	}
+	key := u.User.Username()
+	path := strings.TrimSuffix(u.Path, "/")
+	i := strings.LastIndex(path, "/")
+	project := path[i+1:]
+	if key == "" || project == "" {
+		return nil, fmt.Errorf("invalid DSN (expected https://<key>@<host>/<project>)")
+	}
+	host, _ := os.Hostname()
+	return &Client{
+		endpoint:    fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, path[:i], project),
+		key:         key,
+		dsn:         dsn,
+		release:     release,
+		environment: environment,
+		serverName:  host,
+		http:        &http.Client{Timeout: sendTimeout},
+	}, nil
+}
+
+// Event is the subset of the Sentry event payload this client sends.
+type Event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       string            `json:"level"`
+	Release     string            `json:"release,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Exception   struct {
+		Values []Exception `json:"values"`
+	} `json:"exception"`
+}
+
+// Exception describes the error or panic value of an Event.
+type Exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *Stacktrace `json:"stacktrace,omitempty"`
+}
+
+// Stacktrace lists frames oldest call first, as Sentry expects.
+type Stacktrace struct {
+	Frames []Frame `json:"frames"`
+}
+
+// Frame is one call in a Stacktrace.
+type Frame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+// CaptureError reports err in the background.
+func (c *Client) CaptureError(err error, tags map[string]string) {
+	if c == nil || err == nil {
+		return
+	}
+	c.send(c.event("error", fmt.Sprintf("%T", err), err.Error(), stacktrace(2), tags))
+}
+
+// CapturePanic reports a value recovered from a panic in the background. It
+// must be called from the deferred function that recovered it, so the stack
+// still shows where the panic happened.
+func (c *Client) CapturePanic(recovered any, tags map[string]string) {
+	if c == nil {
+		return
+	}
+	c.send(c.event("fatal", "panic", fmt.Sprint(recovered), stacktrace(2), tags))
+}
+
+// Flush waits up to timeout for queued events to be delivered and reports
+// whether they all were.
+func (c *Client) Flush(timeout time.Duration) bool {
+	if c == nil {
+		return true
+	}
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (c *Client) event(level, typ, value string, st *Stacktrace, tags map[string]string) *Event {
+	ev := &Event{
+		EventID:     newEventID(),
+		Timestamp:   time.Now().UTC(),
+		Platform:    "go",
+		Level:       level,
+		Release:     c.release,
+		Environment: c.environment,
+		ServerName:  c.serverName,
+	}
+	if len(tags) > 0 {
+		ev.Tags = make(map[string]string, len(tags))
+		for k, v := range tags {
+			ev.Tags[k] = Scrub(v)
+		}
+	}
+	ev.Exception.Values = []Exception{{Type: typ, Value: Scrub(value), Stacktrace: st}}
+	return ev
+}
+
+func (c *Client) send(ev *Event) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		defer cancel()
+		if err := c.Send(ctx, ev); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠ Failed to report error to Sentry: %v\n", err)
+		}
+	}()
+}
+
+// Send delivers ev as a single-item envelope.
+func (c *Client) Send(ctx context.Context, ev *Event) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	header, _ := json.Marshal(map[string]any{"event_id": ev.EventID, "dsn": c.dsn, "sent_at": time.Now().UTC()})
+	item, _ := json.Marshal(map[string]any{"type": "event", "length": len(payload)})
+
+	var body bytes.Buffer
+	for _, line := range [][]byte{header, item, payload} {
+		body.Write(line)
+		body.WriteByte('\n')
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=whatsapp-cli/%s, sentry_key=%s", c.release, c.key))
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("server returned %s", resp.Status)
+	}
+	return nil
+}
+
+// newEventID returns a random 128-bit event ID in the hex form Sentry uses.
+func newEventID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// stacktrace returns the caller's stack, skipping skip frames above it and
+// the runtime's panic machinery.
+func stacktrace(skip int) *Stacktrace {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var out []Frame
+	for {
+		f, more := frames.Next()
+		if f.Function == "runtime.gopanic" {
+			// Everything so far is the deferred function recovering the panic
+			out = out[:0]
+		} else if !strings.HasPrefix(f.Function, "runtime.") {
+			out = append(out, Frame{
+				Function: f.Function,
+				Module:   packageName(f.Function),
+				AbsPath:  f.File,
+				Lineno:   f.Line,
+				InApp:    strings.HasPrefix(f.Function, "github.com/vicentereig/whatsapp-cli/") || strings.HasPrefix(f.Function, "main."),
+			})
+		}
+		if !more {
+			break
+		}
+	}
+	// Sentry lists the outermost call first
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return &Stacktrace{Frames: out}
+}
+
+// packageName returns the import path of a fully qualified function name.
+func packageName(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
+
+var (
+	// quotedRe matches quoted strings, which is how errors embed message
+	// text, names and other user input.
+	quotedRe = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|'[^']*'`)
+	// phoneRe matches phone numbers, including the user part of JIDs.
+	phoneRe = regexp.MustCompile(`\+?(?:\(\d+\) ?)?\d[\d ().-]{5,}\d`)
+)
+
+// Scrub removes quoted strings and phone numbers from s.
+func Scrub(s string) string {
+	s = quotedRe.ReplaceAllString(s, `"[redacted]"`)
+	return phoneRe.ReplaceAllString(s, "[phone]")
+}
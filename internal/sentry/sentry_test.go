@@ -0,0 +1,124 @@
+package sentry
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// envelopeServer records the events posted to it.
+func envelopeServer(t *testing.T) (*httptest.Server, chan *http.Request, chan Event) {
+	t.Helper()
+	reqs := make(chan *http.Request, 10)
+	events := make(chan Event, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sc := bufio.NewScanner(r.Body)
+		sc.Buffer(nil, 1<<20)
+		var lines [][]byte
+		for sc.Scan() {
+			lines = append(lines, append([]byte(nil), sc.Bytes()...))
+		}
+		var ev Event
+		if len(lines) == 3 && json.Unmarshal(lines[2], &ev) == nil {
+			events <- ev
+		}
+		reqs <- r
+	}))
+	t.Cleanup(srv.Close)
+	return srv, reqs, events
+}
+
+func newTestClient(t *testing.T, srv *httptest.Server) *Client {
+	t.Helper()
+	c, err := New("http://public@"+srv.Listener.Addr().String()+"/7", "1.2.3", "test")
+	require.NoError(t, err)
+	return c
+}
+
+func TestNew(t *testing.T) {
+	c, err := New("https://abc@o1.ingest.sentry.io/42", "1.0.0", "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://o1.ingest.sentry.io/api/42/envelope/", c.endpoint)
+	assert.Equal(t, "abc", c.key)
+
+	c, err = New("https://abc@glitchtip.example.com/sub/3", "1.0.0", "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://glitchtip.example.com/sub/api/3/envelope/", c.endpoint)
+
+	for _, dsn := range []string{"", "https://o1.ingest.sentry.io/42", "https://abc@o1.ingest.sentry.io/", "ftp://abc@host/1"} {
+		_, err := New(dsn, "", "")
+		assert.Error(t, err, dsn)
+	}
+}
+
+func TestScrub(t *testing.T) {
+	assert.Equal(t, `send to [phone]@s.whatsapp.net failed: message "[redacted]" too long`,
+		Scrub(`send to 34600111222@s.whatsapp.net failed: message "see you at 5pm" too long`))
+	assert.Equal(t, "call [phone] or [phone]", Scrub("call +34 600 111 222 or (555) 123-4567"))
+	assert.Equal(t, "status 500 after 3 retries", Scrub("status 500 after 3 retries"))
+}
+
+func TestCaptureError(t *testing.T) {
+	srv, reqs, events := envelopeServer(t)
+	c := newTestClient(t, srv)
+
+	c.CaptureError(errors.New(`no chat for "Alice" (34600111222)`), map[string]string{"path": "/chats/34600111222@s.whatsapp.net"})
+	require.True(t, c.Flush(5*time.Second))
+
+	r := <-reqs
+	assert.Equal(t, "/api/7/envelope/", r.URL.Path)
+	assert.Contains(t, r.Header.Get("X-Sentry-Auth"), "sentry_key=public")
+	ev := <-events
+	assert.Equal(t, "error", ev.Level)
+	assert.Equal(t, "1.2.3", ev.Release)
+	assert.Equal(t, "test", ev.Environment)
+	require.Len(t, ev.Exception.Values, 1)
+	assert.Equal(t, `no chat for "[redacted]" ([phone])`, ev.Exception.Values[0].Value)
+	assert.Equal(t, "/chats/[phone]@s.whatsapp.net", ev.Tags["path"])
+}
+
+func panicky() {
+	var m map[string]int
+	m["boom"] = 1
+}
+
+func TestCapturePanic(t *testing.T) {
+	srv, _, events := envelopeServer(t)
+	c := newTestClient(t, srv)
+
+	func() {
+		defer func() {
+			if v := recover(); v != nil {
+				c.CapturePanic(v, nil)
+			}
+		}()
+		panicky()
+	}()
+	require.True(t, c.Flush(5*time.Second))
+
+	ev := <-events
+	assert.Equal(t, "fatal", ev.Level)
+	ex := ev.Exception.Values[0]
+	assert.Equal(t, "panic", ex.Type)
+	assert.Contains(t, ex.Value, "nil map")
+	require.NotNil(t, ex.Stacktrace)
+	frames := ex.Stacktrace.Frames
+	require.NotEmpty(t, frames)
+	// The innermost frame is where the panic happened, not the recovery
+	assert.Equal(t, "github.com/vicentereig/whatsapp-cli/internal/sentry.panicky", frames[len(frames)-1].Function)
+	assert.True(t, frames[len(frames)-1].InApp)
+}
+
+func TestNilClient(t *testing.T) {
+	var c *Client
+	c.CaptureError(errors.New("ignored"), nil)
+	c.CapturePanic("ignored", nil)
+	assert.True(t, c.Flush(time.Second))
+}
@@ -0,0 +1,113 @@
+// Package sticker converts images to the format WhatsApp requires of
+// stickers: a 512x512 WebP, the image scaled to fit and centered on a
+// transparent background.
+package sticker
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/vicentereig/whatsapp-cli/internal/webp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Size is the width and height of a sticker.
+const Size = 512
+
+// MIMEType is the MIME type of a converted sticker.
+const MIMEType = "image/webp"
+
+// MaxBytes is the largest sticker WhatsApp accepts.
+const MaxBytes = 100 << 10
+
+// reductions are tried in turn until the sticker fits in MaxBytes: photos
+// do not fit losslessly, so colors lose precision first and then the image
+// shrinks on its canvas.
+var reductions = []struct {
+	scale int
+	bits  uint
+}{
+	{Size, 0},
+	{Size, 2},
+	{Size, 3},
+	{Size * 3 / 4, 3},
+	{Size / 2, 3},
+	{Size / 2, 4},
+}
+
+// maxSourcePixels bounds the images converted, so a small file cannot
+// claim dimensions that take gigabytes to decode.
+const maxSourcePixels = 50_000_000
+
+// Convert turns a PNG, JPEG or WebP image into a sticker. A WebP that
+// already is 512x512 and within MaxBytes is returned as is. Images that do
+// not fit in MaxBytes lose color precision and, if that is not enough, are
+// shrunk; those that still do not fit are refused.
+func Convert(data []byte) ([]byte, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("sticker must be a PNG, JPEG or WebP image")
+	}
+	if format == "webp" && cfg.Width == Size && cfg.Height == Size && len(data) <= MaxBytes {
+		return data, nil
+	}
+	if cfg.Width*cfg.Height > maxSourcePixels {
+		return nil, fmt.Errorf("image is too large to convert (%dx%d)", cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s image: %v", format, err)
+	}
+
+	for _, r := range reductions {
+		out, err := encode(img, r.scale, r.bits)
+		if err != nil {
+			return nil, err
+		}
+		if len(out) <= MaxBytes {
+			return out, nil
+		}
+	}
+	return nil, fmt.Errorf("image is too detailed for a sticker: it does not fit in %d KB", MaxBytes>>10)
+}
+
+// encode scales img to fit a scale x scale square centered on the sticker's
+// transparent canvas, rounds its colors to drop bits of precision, and
+// encodes it.
+func encode(img image.Image, scale int, bits uint) ([]byte, error) {
+	b := img.Bounds()
+	w, h := scale, scale
+	if b.Dx() > b.Dy() {
+		h = max(1, b.Dy()*scale/b.Dx())
+	} else {
+		w = max(1, b.Dx()*scale/b.Dy())
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, Size, Size))
+	target := image.Rect((Size-w)/2, (Size-h)/2, (Size-w)/2+w, (Size-h)/2+h)
+	draw.CatmullRom.Scale(dst, target, img, b, draw.Src, nil)
+	if bits > 0 {
+		posterize(dst, bits)
+	}
+
+	var buf bytes.Buffer
+	if err := webp.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// posterize rounds the color channels of img to multiples of 1<<bits,
+// which the lossless encoder codes in far fewer bytes.
+func posterize(img *image.NRGBA, bits uint) {
+	half := 1 << bits >> 1
+	for i := 0; i < len(img.Pix); i += 4 {
+		for c := i; c < i+3; c++ {
+			v := min(int(img.Pix[c])+half, 0xff)
+			img.Pix[c] = uint8(v >> bits << bits)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package sticker
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/webp"
+)
+
+func TestConvertFitsImageOnTransparentSquare(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 200, 100))
+	for i := range src.Pix {
+		src.Pix[i] = 0xff
+	}
+	var in bytes.Buffer
+	require.NoError(t, png.Encode(&in, src))
+
+	out, err := Convert(in.Bytes())
+	require.NoError(t, err)
+	img, err := webp.Decode(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, Size, Size), img.Bounds())
+	// 512x256, centered vertically
+	assert.Equal(t, color.NRGBA{}, color.NRGBAModel.Convert(img.At(256, 10)))
+	assert.Equal(t, color.NRGBA{255, 255, 255, 255}, color.NRGBAModel.Convert(img.At(256, 256)))
+
+	// Already a sticker
+	again, err := Convert(out)
+	require.NoError(t, err)
+	assert.Equal(t, out, again)
+}
+
+func TestConvertJPEG(t *testing.T) {
+	var in bytes.Buffer
+	require.NoError(t, jpeg.Encode(&in, image.NewGray(image.Rect(0, 0, 64, 640)), nil))
+	out, err := Convert(in.Bytes())
+	require.NoError(t, err)
+	cfg, err := webp.DecodeConfig(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, Size, cfg.Width)
+	assert.Equal(t, Size, cfg.Height)
+}
+
+func TestConvertRejectsOtherFiles(t *testing.T) {
+	_, err := Convert([]byte("%PDF-1.4"))
+	assert.ErrorContains(t, err, "PNG, JPEG or WebP")
+}
+
+func TestConvertFitsPhotosInSizeLimit(t *testing.T) {
+	// A photo does not fit losslessly
+	photo, err := os.ReadFile("testdata/photo.jpeg")
+	require.NoError(t, err)
+	out, err := Convert(photo)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(out), MaxBytes)
+	img, err := webp.Decode(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, Size, Size), img.Bounds())
+
+	// Noise does not fit however it is reduced
+	noise := image.NewNRGBA(image.Rect(0, 0, Size, Size))
+	rand.New(rand.NewSource(1)).Read(noise.Pix)
+	var in bytes.Buffer
+	require.NoError(t, png.Encode(&in, noise))
+	_, err = Convert(in.Bytes())
+	assert.ErrorContains(t, err, "100 KB")
+}
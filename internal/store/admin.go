@@ -0,0 +1,200 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// QueryResult is the outcome of a read-only ad hoc query.
+type QueryResult struct {
+	Columns   []string        `json:"columns"`
+	Rows      [][]interface{} `json:"rows"`
+	Truncated bool            `json:"truncated"`
+}
+
+// TableSchema describes a table of the message database.
+type TableSchema struct {
+	Name    string         `json:"name"`
+	Columns []ColumnSchema `json:"columns"`
+	Indexes []string       `json:"indexes"`
+}
+
+// ColumnSchema describes one column of a table.
+type ColumnSchema struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	NotNull    bool   `json:"not_null"`
+	PrimaryKey bool   `json:"primary_key"`
+}
+
+// writeOpcodes are SQLite VDBE instructions that modify the database. A
+// statement whose EXPLAIN output contains none of them cannot write.
+var writeOpcodes = map[string]bool{
+	"OpenWrite": true, "Insert": true, "Delete": true, "IdxInsert": true,
+	"IdxDelete": true, "Clear": true, "Destroy": true, "CreateBtree": true,
+	"ParseSchema": true, "DropTable": true, "DropIndex": true, "DropTrigger": true,
+	"Vacuum": true, "JournalMode": true,
+}
+
+// ValidateReadOnlyQuery checks that query is a single SELECT (or WITH …
+// SELECT) statement whose compiled program performs no writes.
+func (s *MessageStore) ValidateReadOnlyQuery(ctx context.Context, query string) error {
+	q := strings.TrimSpace(query)
+	q = strings.TrimSpace(strings.TrimSuffix(q, ";"))
+	if q == "" {
+		return fmt.Errorf("query is empty")
+	}
+	if strings.Contains(q, ";") {
+		return fmt.Errorf("only a single statement is allowed")
+	}
+	first := strings.ToUpper(strings.Fields(q)[0])
+	if first != "SELECT" && first != "WITH" {
+		return fmt.Errorf("only SELECT statements are allowed")
+	}
+
+	rows, err := s.db.QueryContext(ctx, "EXPLAIN "+q)
+	if err != nil {
+		return fmt.Errorf("invalid query: %w", err)
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		vals := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		// EXPLAIN columns: addr, opcode, p1, p2, p3, p4, p5, comment
+		if op, ok := vals[1].(string); ok && writeOpcodes[op] {
+			return fmt.Errorf("query is not read-only (%s)", op)
+		}
+		if op, ok := vals[1].([]byte); ok && writeOpcodes[string(op)] {
+			return fmt.Errorf("query is not read-only (%s)", op)
+		}
+	}
+	return rows.Err()
+}
+
+// ReadOnlyQuery validates and runs query on a connection switched to
+// query_only mode, returning at most maxRows rows. ctx bounds the run time.
+func (s *MessageStore) ReadOnlyQuery(ctx context.Context, query string, maxRows int) (QueryResult, error) {
+	if err := s.ValidateReadOnlyQuery(ctx, query); err != nil {
+		return QueryResult{}, err
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return QueryResult{}, err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "PRAGMA query_only = ON"); err != nil {
+		return QueryResult{}, err
+	}
+	// Restore the pooled connection for regular writers
+	defer conn.ExecContext(context.Background(), "PRAGMA query_only = OFF")
+
+	rows, err := conn.QueryContext(ctx, strings.TrimSuffix(strings.TrimSpace(query), ";"))
+	if err != nil {
+		return QueryResult{}, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return QueryResult{}, err
+	}
+	result := QueryResult{Columns: cols, Rows: [][]interface{}{}}
+	for rows.Next() {
+		if len(result.Rows) >= maxRows {
+			result.Truncated = true
+			break
+		}
+		vals := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return QueryResult{}, err
+		}
+		for i, v := range vals {
+			if b, ok := v.([]byte); ok {
+				vals[i] = string(b)
+			}
+		}
+		result.Rows = append(result.Rows, vals)
+	}
+	return result, rows.Err()
+}
+
+// Schema describes the tables, columns and indexes of the message database.
+func (s *MessageStore) Schema() ([]TableSchema, error) {
+	names, err := s.queryStrings(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make([]TableSchema, 0, len(names))
+	for _, name := range names {
+		t := TableSchema{Name: name, Columns: []ColumnSchema{}}
+		rows, err := s.db.Query(fmt.Sprintf("PRAGMA table_info(%q)", name))
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var cid, notNull, pk int
+			var col ColumnSchema
+			var dflt sql.NullString
+			if err := rows.Scan(&cid, &col.Name, &col.Type, &notNull, &dflt, &pk); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			col.NotNull = notNull != 0
+			col.PrimaryKey = pk != 0
+			t.Columns = append(t.Columns, col)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		t.Indexes, err = s.queryStrings(`SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND name NOT LIKE 'sqlite_%' ORDER BY name`, name)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+func (s *MessageStore) queryStrings(query string, args ...interface{}) ([]string, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []string{}
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+// Snapshot writes a consistent copy of the live database to path using
+// VACUUM INTO. path must not exist or be an empty file.
+func (s *MessageStore) Snapshot(ctx context.Context, path string) error {
+	_, err := s.db.ExecContext(ctx, `VACUUM INTO ?`, path)
+	return err
+}
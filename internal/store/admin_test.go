@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnlyQuery(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+	chatJID := "123@s.whatsapp.net"
+	require.NoError(t, store.StoreChat(chatJID, "Alice", time.Now()))
+	for _, id := range []string{"m1", "m2", "m3"} {
+		require.NoError(t, store.StoreMessage(id, chatJID, "123", "hello "+id, time.Now(), false, "", "", "", "", "", nil, nil, nil, 0))
+	}
+
+	result, err := store.ReadOnlyQuery(ctx, "SELECT id, content FROM messages ORDER BY id;", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id", "content"}, result.Columns)
+	require.Len(t, result.Rows, 2)
+	assert.Equal(t, []interface{}{"m1", "hello m1"}, result.Rows[0])
+	assert.True(t, result.Truncated)
+
+	result, err = store.ReadOnlyQuery(ctx, "WITH c AS (SELECT COUNT(*) AS n FROM messages) SELECT n FROM c", 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, result.Rows[0][0])
+	assert.False(t, result.Truncated)
+}
+
+func TestReadOnlyQueryRejectsWrites(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	for query, msg := range map[string]string{
+		"":                              "empty",
+		"DELETE FROM messages":          "only SELECT",
+		"SELECT 1; DROP TABLE messages": "single statement",
+		"PRAGMA journal_mode = DELETE":  "only SELECT",
+		"WITH x AS (SELECT 1) DELETE FROM messages": "not read-only",
+		"SELECT * FROM no_such_table":               "invalid query",
+	} {
+		_, err := store.ReadOnlyQuery(ctx, query, 10)
+		require.Error(t, err, query)
+		assert.Contains(t, err.Error(), msg, query)
+	}
+
+	// Writers keep working on the pooled connections afterwards
+	_, err := store.ReadOnlyQuery(ctx, "SELECT 1", 1)
+	require.NoError(t, err)
+	require.NoError(t, store.StoreChat("1@s.whatsapp.net", "Bob", time.Now()))
+}
+
+func TestSchema(t *testing.T) {
+	store := setupTestDB(t)
+
+	tables, err := store.Schema()
+	require.NoError(t, err)
+
+	var messages *TableSchema
+	for i := range tables {
+		if tables[i].Name == "messages" {
+			messages = &tables[i]
+		}
+	}
+	require.NotNil(t, messages)
+	assert.Contains(t, messages.Indexes, "idx_messages_lang")
+	var names []string
+	for _, c := range messages.Columns {
+		names = append(names, c.Name)
+		if c.Name == "id" {
+			assert.True(t, c.PrimaryKey)
+		}
+	}
+	assert.Contains(t, names, "content_hash")
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Account alert severities. A critical alert means the account cannot send
+// (banned, logged out); a warning that it is at risk.
+const (
+	AlertCritical = "critical"
+	AlertWarning  = "warning"
+)
+
+// AccountAlert is a sign from WhatsApp that the account is restricted or at
+// risk of being banned. It is active until resolved, which happens once the
+// account connects again, or until it expires.
+type AccountAlert struct {
+	ID        int64      `json:"id"`
+	Kind      string     `json:"kind"`
+	Severity  string     `json:"severity"`
+	Code      string     `json:"code,omitempty"`
+	Reason    string     `json:"reason"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// ResolvedAt is nil while the alert is active.
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// StoreAccountAlert appends an alert and returns its ID.
+func (s *MessageStore) StoreAccountAlert(alert AccountAlert) (int64, error) {
+	res, err := s.db.Exec(
+		`INSERT INTO account_alerts (kind, severity, code, reason, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		alert.Kind, alert.Severity, alert.Code, alert.Reason, alert.CreatedAt.UTC(), utcOrNil(alert.ExpiresAt),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// ResolveAccountAlerts resolves the active alerts at the given time, except
+// those of the kinds in keep, and returns how many it resolved.
+func (s *MessageStore) ResolveAccountAlerts(at time.Time, keep ...string) (int64, error) {
+	query := `UPDATE account_alerts SET resolved_at = ? WHERE resolved_at IS NULL`
+	args := []any{at.UTC()}
+	if len(keep) > 0 {
+		query += ` AND kind NOT IN (?` + strings.Repeat(", ?", len(keep)-1) + `)`
+		for _, k := range keep {
+			args = append(args, k)
+		}
+	}
+	res, err := s.db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ActiveAccountAlerts returns the alerts that are neither resolved nor
+// expired at now, critical ones first, newest first.
+func (s *MessageStore) ActiveAccountAlerts(now time.Time) ([]AccountAlert, error) {
+	return s.queryAccountAlerts(
+		`WHERE resolved_at IS NULL AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY severity = ? DESC, created_at DESC, id DESC`,
+		now.UTC(), AlertCritical,
+	)
+}
+
+// ListAccountAlerts returns the most recent alerts, newest first.
+func (s *MessageStore) ListAccountAlerts(limit int) ([]AccountAlert, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	return s.queryAccountAlerts(`ORDER BY created_at DESC, id DESC LIMIT ?`, limit)
+}
+
+func (s *MessageStore) queryAccountAlerts(clause string, args ...any) ([]AccountAlert, error) {
+	rows, err := s.db.Query(
+		`SELECT id, kind, severity, code, reason, created_at, expires_at, resolved_at FROM account_alerts `+clause,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []AccountAlert{}
+	for rows.Next() {
+		var a AccountAlert
+		var expires, resolved sql.NullTime
+		if err := rows.Scan(&a.ID, &a.Kind, &a.Severity, &a.Code, &a.Reason, &a.CreatedAt, &expires, &resolved); err != nil {
+			return nil, err
+		}
+		if expires.Valid {
+			a.ExpiresAt = &expires.Time
+		}
+		if resolved.Valid {
+			a.ResolvedAt = &resolved.Time
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
+
+func utcOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
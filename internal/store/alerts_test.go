@@ -0,0 +1,49 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountAlerts(t *testing.T) {
+	s := setupTestDB(t)
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	expires := now.Add(time.Hour)
+
+	_, err := s.StoreAccountAlert(AccountAlert{Kind: "stream_error", Severity: AlertWarning, Code: "503", Reason: "stream error 503", CreatedAt: now.Add(-time.Minute)})
+	require.NoError(t, err)
+	id, err := s.StoreAccountAlert(AccountAlert{Kind: "temporary_ban", Severity: AlertCritical, Code: "101", Reason: "banned", CreatedAt: now.Add(-2 * time.Minute), ExpiresAt: &expires})
+	require.NoError(t, err)
+
+	active, err := s.ActiveAccountAlerts(now)
+	require.NoError(t, err)
+	require.Len(t, active, 2)
+	assert.Equal(t, id, active[0].ID, "critical alerts first")
+	assert.True(t, expires.Equal(*active[0].ExpiresAt))
+	assert.Nil(t, active[0].ResolvedAt)
+
+	active, err = s.ActiveAccountAlerts(expires)
+	require.NoError(t, err)
+	require.Len(t, active, 1, "the ban expired")
+	assert.Equal(t, "stream_error", active[0].Kind)
+
+	n, err := s.ResolveAccountAlerts(now, "stream_error")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n, "kept alerts stay active")
+	n, err = s.ResolveAccountAlerts(now)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+	active, err = s.ActiveAccountAlerts(now)
+	require.NoError(t, err)
+	assert.Empty(t, active)
+
+	all, err := s.ListAccountAlerts(10)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, "stream_error", all[0].Kind, "newest first")
+	require.NotNil(t, all[1].ResolvedAt)
+	assert.True(t, now.Equal(*all[1].ResolvedAt))
+}
@@ -0,0 +1,340 @@
+package store
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// AnonymizeOptions controls how a database copy is pseudonymized.
+type AnonymizeOptions struct {
+	// Salt keys the pseudonyms. The same salt maps a phone number or name to
+	// the same pseudonym in every export, so datasets can be joined.
+	Salt []byte
+	// DropContent blanks message text instead of only rewriting the phone
+	// numbers and names found in it.
+	DropContent bool
+}
+
+// AnonymizeSummary counts the rows rewritten per table.
+type AnonymizeSummary struct {
+	Tables map[string]int64 `json:"tables"`
+}
+
+// phonePattern matches phone numbers written in message text, with optional
+// "+" and spaces or dashes between digit groups.
+var phonePattern = regexp.MustCompile(`\+?\d[\d \-]{5,}\d`)
+
+// anonymizer maps identifiers to stable pseudonyms.
+type anonymizer struct {
+	salt  []byte
+	names map[string]string
+}
+
+// digits replaces a string of digits with pseudo-random digits of the same
+// length derived from it.
+func (a *anonymizer) digits(s string) string {
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte("digits:" + s))
+	sum := mac.Sum(nil)
+	out := make([]byte, len(s))
+	for i := range out {
+		out[i] = '0' + byte(binary.BigEndian.Uint16(sum[(i*2)%len(sum):])%10)
+	}
+	// Keep the leading digit non-zero so numbers still look like numbers
+	if len(out) > 0 && out[0] == '0' {
+		out[0] = '1'
+	}
+	return string(out)
+}
+
+// jid pseudonymizes the user part of a JID ("user[:device]@server"), keeping
+// the device and server. Values that are not numeric users, such as "me" or
+// "status@broadcast", are returned unchanged.
+func (a *anonymizer) jid(s string) string {
+	if s == "" || s == "me" {
+		return s
+	}
+	user, server, hasServer := strings.Cut(s, "@")
+	user, device, hasDevice := strings.Cut(user, ":")
+	if !isDigits(user) {
+		return s
+	}
+	out := a.digits(user)
+	if hasDevice {
+		out += ":" + device
+	}
+	if hasServer {
+		out += "@" + server
+	}
+	return out
+}
+
+// name returns a pseudonym like "Contact 3f9a1c" for a display name.
+func (a *anonymizer) name(kind, s string) string {
+	if s == "" {
+		return s
+	}
+	if isDigits(s) || strings.Contains(s, "@") {
+		return a.jid(s)
+	}
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte("name:" + s))
+	p := kind + " " + hex.EncodeToString(mac.Sum(nil))[:6]
+	a.names[s] = p
+	return p
+}
+
+// text rewrites phone numbers and known names inside free text.
+func (a *anonymizer) text(s string, names *strings.Replacer) string {
+	if s == "" {
+		return s
+	}
+	s = phonePattern.ReplaceAllStringFunc(s, func(m string) string {
+		var d strings.Builder
+		for _, r := range m {
+			if r >= '0' && r <= '9' {
+				d.WriteRune(r)
+			}
+		}
+		return a.digits(d.String())
+	})
+	if names != nil {
+		s = names.Replace(s)
+	}
+	return s
+}
+
+func (a *anonymizer) nameReplacer() *strings.Replacer {
+	var olds []string
+	for old := range a.names {
+		// Very short names would match inside ordinary words
+		if len(old) >= 3 {
+			olds = append(olds, old)
+		}
+	}
+	if len(olds) == 0 {
+		return nil
+	}
+	// Replacer tries patterns in argument order; prefer the longest match
+	sort.Slice(olds, func(i, j int) bool { return len(olds[i]) > len(olds[j]) })
+	pairs := make([]string, 0, 2*len(olds))
+	for _, old := range olds {
+		pairs = append(pairs, old, a.names[old])
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// AnonymizeDatabase rewrites the message database at path in place,
+// replacing phone numbers, JIDs and names with stable pseudonyms while
+// keeping the table structure, message IDs and timestamps. Media download
+// secrets, local file paths, queued notifications and queued sends are
+// removed. It must only be run on a copy, such as one written by Snapshot.
+func AnonymizeDatabase(ctx context.Context, path string, opts AnonymizeOptions) (AnonymizeSummary, error) {
+	if len(opts.Salt) == 0 {
+		return AnonymizeSummary{}, fmt.Errorf("a salt is required")
+	}
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s", path))
+	if err != nil {
+		return AnonymizeSummary{}, err
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return AnonymizeSummary{}, err
+	}
+	defer tx.Rollback()
+
+	an := &anonymizer{salt: opts.Salt, names: map[string]string{}}
+	summary := AnonymizeSummary{Tables: map[string]int64{}}
+
+	// Names first, so they can also be replaced inside message text
+	n, err := rewriteRows(ctx, tx, "chats", []string{"jid", "name"}, func(v []sql.NullString) {
+		kind := "Contact"
+		if strings.HasSuffix(v[0].String, "@g.us") {
+			kind = "Group"
+		}
+		v[0].String = an.jid(v[0].String)
+		v[1].String = an.name(kind, v[1].String)
+	})
+	if err != nil {
+		return summary, err
+	}
+	summary.Tables["chats"] = n
+
+	n, err = rewriteRows(ctx, tx, "groups", []string{"jid", "subject", "description"}, func(v []sql.NullString) {
+		v[0].String = an.jid(v[0].String)
+		v[1].String = an.name("Group", v[1].String)
+		if v[2].String != "" {
+			v[2].String = "[redacted]"
+		}
+	})
+	if err != nil {
+		return summary, err
+	}
+	summary.Tables["groups"] = n
+
+	names := an.nameReplacer()
+	n, err = rewriteRows(ctx, tx, "messages", []string{"chat_jid", "sender", "content", "filename"}, func(v []sql.NullString) {
+		v[0].String = an.jid(v[0].String)
+		v[1].String = an.jid(v[1].String)
+		if opts.DropContent && v[2].String != "" {
+			v[2].String = "[redacted]"
+		} else {
+			v[2].String = an.text(v[2].String, names)
+		}
+		v[3].String = an.text(v[3].String, names)
+	})
+	if err != nil {
+		return summary, err
+	}
+	summary.Tables["messages"] = n
+	if _, err := tx.ExecContext(ctx, `UPDATE messages SET url = NULL, direct_path = NULL, media_key = NULL,
+		file_enc_sha256 = NULL, local_path = NULL, downloaded_at = NULL`); err != nil {
+		return summary, err
+	}
+
+	n, err = rewriteRows(ctx, tx, "chat_events", []string{"chat_jid", "actor", "target"}, func(v []sql.NullString) {
+		for i := range v {
+			v[i].String = an.jid(v[i].String)
+		}
+	})
+	if err != nil {
+		return summary, err
+	}
+	summary.Tables["chat_events"] = n
+
+	for _, table := range []string{"away_optouts", "away_replies", "chat_modes", "chat_reads", "message_tags", "followup_notices"} {
+		n, err := rewriteRows(ctx, tx, table, []string{"chat_jid"}, func(v []sql.NullString) {
+			v[0].String = an.jid(v[0].String)
+		})
+		if err != nil {
+			return summary, err
+		}
+		summary.Tables[table] = n
+	}
+
+	n, err = rewriteRows(ctx, tx, "chat_aliases", []string{"alias_jid", "jid"}, func(v []sql.NullString) {
+		for i := range v {
+			v[i].String = an.jid(v[i].String)
+		}
+	})
+	if err != nil {
+		return summary, err
+	}
+	summary.Tables["chat_aliases"] = n
+
+	// Queued notifications, sends, drafts, digests and polls carry message
+	// text verbatim, and embeddings can be inverted to recover much of it.
+	// Reminders hold calendar URLs, which often embed credentials, and
+	// contact identities phone numbers with their security codes. Metadata
+	// is whatever integrators keep about people, such as CRM IDs. Receipts
+	// name their recipients by phone number. Hash chains
+	// would no longer verify against the pseudonymized messages.
+	for _, table := range []string{"outbox", "send_queue", "drafts", "digests", "message_embeddings", "reminders", "reminder_sends", "feed_items", "contact_identities", "polls", "poll_votes", "metadata", "message_chain", "message_receipts"} {
+		res, err := tx.ExecContext(ctx, "DELETE FROM "+table)
+		if err != nil {
+			return summary, err
+		}
+		summary.Tables[table], _ = res.RowsAffected()
+	}
+
+	if err := tx.Commit(); err != nil {
+		return summary, err
+	}
+	// Rewritten values must not survive in free pages
+	if _, err := db.ExecContext(ctx, "VACUUM"); err != nil {
+		return summary, err
+	}
+	return summary, nil
+}
+
+// rewriteRows passes the given columns of every row of table to fn and
+// writes back the values it changes, matching rows by rowid.
+func rewriteRows(ctx context.Context, tx *sql.Tx, table string, cols []string, fn func([]sql.NullString)) (int64, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT rowid, %s FROM %s", strings.Join(cols, ", "), table))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", table, err)
+	}
+	type update struct {
+		rowid int64
+		vals  []sql.NullString
+	}
+	var updates []update
+	for rows.Next() {
+		var rowid int64
+		vals := make([]sql.NullString, len(cols))
+		ptrs := []interface{}{&rowid}
+		for i := range vals {
+			ptrs = append(ptrs, &vals[i])
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		orig := make([]sql.NullString, len(vals))
+		copy(orig, vals)
+		fn(vals)
+		if changed(orig, vals) {
+			updates = append(updates, update{rowid, vals})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	sets := make([]string, len(cols))
+	for i, c := range cols {
+		sets[i] = c + " = ?"
+	}
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("UPDATE %s SET %s WHERE rowid = ?", table, strings.Join(sets, ", ")))
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	for _, u := range updates {
+		args := make([]interface{}, 0, len(cols)+1)
+		for _, v := range u.vals {
+			if v.Valid {
+				args = append(args, v.String)
+			} else {
+				args = append(args, nil)
+			}
+		}
+		args = append(args, u.rowid)
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return 0, fmt.Errorf("failed to rewrite %s: %w", table, err)
+		}
+	}
+	return int64(len(updates)), nil
+}
+
+func changed(a, b []sql.NullString) bool {
+	for i := range a {
+		if a[i].String != b[i].String {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnonymizeDatabase(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+	alice := "4915112345678@s.whatsapp.net"
+	group := "120363000000000001@g.us"
+	ts := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, store.StoreChat(alice, "Alice Example", ts))
+	require.NoError(t, store.StoreChat(group, "Family", ts))
+	require.NoError(t, store.StoreMessage("m1", alice, alice, "Hi, call Alice Example at +49 151 12345678", ts, false,
+		"image", "", "https://mmg.example", "/v/t62", "image/jpeg", []byte{1}, []byte{2}, []byte{3}, 10))
+	require.NoError(t, store.StoreMessage("m2", group, "me", "see you", ts, true, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, store.StoreChatEvent(ChatEvent{ChatJID: group, Type: ChatEventJoinRequest, Target: alice, Timestamp: ts}))
+
+	path := filepath.Join(t.TempDir(), "copy.db")
+	require.NoError(t, store.Snapshot(ctx, path))
+	summary, err := AnonymizeDatabase(ctx, path, AnonymizeOptions{Salt: []byte("s1")})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), summary.Tables["chats"])
+	assert.Equal(t, int64(2), summary.Tables["messages"])
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var chatJID, name string
+	require.NoError(t, db.QueryRow(`SELECT jid, name FROM chats WHERE jid LIKE '%@s.whatsapp.net'`).Scan(&chatJID, &name))
+	assert.NotEqual(t, alice, chatJID)
+	assert.Regexp(t, `^[1-9]\d{12}@s\.whatsapp\.net$`, chatJID, "same length and server")
+	assert.Regexp(t, `^Contact [0-9a-f]{6}$`, name)
+
+	var groupName string
+	require.NoError(t, db.QueryRow(`SELECT name FROM chats WHERE jid LIKE '%@g.us'`).Scan(&groupName))
+	assert.Regexp(t, `^Group [0-9a-f]{6}$`, groupName)
+
+	var sender, content string
+	var url, mediaKey sql.NullString
+	var msgTime time.Time
+	require.NoError(t, db.QueryRow(`SELECT sender, content, url, media_key, timestamp FROM messages WHERE id = 'm1'`).
+		Scan(&sender, &content, &url, &mediaKey, &msgTime))
+	assert.Equal(t, chatJID, sender, "pseudonyms are stable across tables")
+	assert.NotContains(t, content, "Alice")
+	assert.NotContains(t, content, "12345678")
+	assert.Contains(t, content, name, "names in text use the chat's pseudonym")
+	assert.Contains(t, content, strings.TrimSuffix(chatJID, "@s.whatsapp.net"), "numbers in text match the JID pseudonym")
+	assert.False(t, url.Valid)
+	assert.False(t, mediaKey.Valid)
+	assert.True(t, ts.Equal(msgTime), "timestamps are kept")
+
+	var own string
+	require.NoError(t, db.QueryRow(`SELECT sender FROM messages WHERE id = 'm2'`).Scan(&own))
+	assert.Equal(t, "me", own)
+
+	var target string
+	require.NoError(t, db.QueryRow(`SELECT target FROM chat_events`).Scan(&target))
+	assert.Equal(t, chatJID, target)
+
+	// The live store is untouched
+	var live string
+	require.NoError(t, store.db.QueryRow(`SELECT sender FROM messages WHERE id = 'm1'`).Scan(&live))
+	assert.Equal(t, alice, live)
+}
+
+func TestAnonymizeDatabaseDropContentAndSalt(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+	alice := "4915112345678@s.whatsapp.net"
+	require.NoError(t, store.StoreChat(alice, "Alice", time.Now()))
+	require.NoError(t, store.StoreMessage("m1", alice, alice, "secret", time.Now(), false, "", "", "", "", "", nil, nil, nil, 0))
+
+	anonymized := func(salt string) (jid, content string) {
+		path := filepath.Join(t.TempDir(), "copy.db")
+		require.NoError(t, store.Snapshot(ctx, path))
+		_, err := AnonymizeDatabase(ctx, path, AnonymizeOptions{Salt: []byte(salt), DropContent: true})
+		require.NoError(t, err)
+		db, err := sql.Open("sqlite3", path)
+		require.NoError(t, err)
+		defer db.Close()
+		require.NoError(t, db.QueryRow(`SELECT chat_jid, content FROM messages`).Scan(&jid, &content))
+		return jid, content
+	}
+
+	jid1, content := anonymized("a")
+	assert.Equal(t, "[redacted]", content)
+	jid2, _ := anonymized("a")
+	jid3, _ := anonymized("b")
+	assert.Equal(t, jid1, jid2, "same salt, same pseudonym")
+	assert.NotEqual(t, jid1, jid3)
+
+	_, err := AnonymizeDatabase(ctx, filepath.Join(t.TempDir(), "x.db"), AnonymizeOptions{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,581 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ArchiveVersion is the version of the archive layout in archive.proto.
+const ArchiveVersion = 1
+
+// maxArchiveRecord bounds one record read from an archive, so a corrupt
+// length prefix fails instead of allocating gigabytes.
+const maxArchiveRecord = 256 << 20
+
+// ArchiveTableSummary reports the rows of one table in an archive, and on
+// import how many of them were new to the store.
+type ArchiveTableSummary struct {
+	Table    string `json:"table"`
+	Rows     int64  `json:"rows"`
+	Imported *int64 `json:"imported,omitempty"`
+	Skipped  bool   `json:"skipped,omitempty"`
+}
+
+// ExportArchive writes every table of the message database to out as a
+// compressed protobuf archive (see archive.proto). Like ExportPostgres, all
+// tables are read in one transaction, so the archive is a consistent
+// snapshot.
+func (s *MessageStore) ExportArchive(ctx context.Context, out io.Writer, progress ExportProgress) ([]ArchiveTableSummary, error) {
+	tables, err := s.Schema()
+	if err != nil {
+		return nil, err
+	}
+	// chats first: messages reference it
+	sort.SliceStable(tables, func(i, j int) bool {
+		return tables[i].Name == "chats" && tables[j].Name != "chats"
+	})
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	zw, err := zstd.NewWriter(out)
+	if err != nil {
+		return nil, err
+	}
+	defer zw.Close()
+	w := bufio.NewWriterSize(zw, 1<<16)
+	var header []byte
+	header = protowire.AppendTag(header, 1, protowire.VarintType)
+	header = protowire.AppendVarint(header, ArchiveVersion)
+	header = appendArchiveVarint(header, 2, uint64(time.Now().UnixMilli()))
+	if err := writeArchiveRecord(w, 1, header); err != nil {
+		return nil, err
+	}
+
+	summary := make([]ArchiveTableSummary, 0, len(tables))
+	for _, t := range tables {
+		var total int64
+		if err := tx.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %q", t.Name)).Scan(&total); err != nil {
+			return nil, err
+		}
+		n, err := archiveTable(ctx, tx, w, t, total, progress)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", t.Name, err)
+		}
+		summary = append(summary, ArchiveTableSummary{Table: t.Name, Rows: n})
+	}
+
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+func archiveTable(ctx context.Context, tx *sql.Tx, w *bufio.Writer, t TableSchema, total int64, progress ExportProgress) (int64, error) {
+	cols := make([]string, len(t.Columns))
+	var table []byte
+	table = protowire.AppendTag(table, 1, protowire.BytesType)
+	table = protowire.AppendString(table, t.Name)
+	for i, c := range t.Columns {
+		cols[i] = fmt.Sprintf("%q", c.Name)
+		table = protowire.AppendTag(table, 2, protowire.BytesType)
+		table = protowire.AppendString(table, c.Name)
+	}
+	table = appendArchiveVarint(table, 3, uint64(total))
+	if err := writeArchiveRecord(w, 2, table); err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %q", strings.Join(cols, ", "), t.Name))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	vals := make([]interface{}, len(t.Columns))
+	ptrs := make([]interface{}, len(t.Columns))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	var n int64
+	var row []byte
+	for rows.Next() {
+		if n == total {
+			// Rows can't appear inside the transaction; guard the count
+			// readers rely on anyway.
+			return n, fmt.Errorf("more rows than the %d counted", total)
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return n, err
+		}
+		row = row[:0]
+		for _, v := range vals {
+			value, err := encodeArchiveValue(v)
+			if err != nil {
+				return n, err
+			}
+			row = protowire.AppendTag(row, 1, protowire.BytesType)
+			row = protowire.AppendBytes(row, value)
+		}
+		if err := writeArchiveRecord(w, 3, row); err != nil {
+			return n, err
+		}
+		n++
+		if progress != nil && n%progressEvery == 0 {
+			progress(t.Name, n, total)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+	if n != total {
+		return n, fmt.Errorf("copied %d of %d rows", n, total)
+	}
+	if progress != nil {
+		progress(t.Name, n, total)
+	}
+	return n, nil
+}
+
+// writeArchiveRecord writes a Record holding msg in field num, prefixed by
+// its length.
+func writeArchiveRecord(w *bufio.Writer, num protowire.Number, msg []byte) error {
+	var b []byte
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	b = protowire.AppendBytes(b, msg)
+	if _, err := w.Write(protowire.AppendVarint(nil, uint64(len(b)))); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+func appendArchiveVarint(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// encodeArchiveValue encodes a value scanned from SQLite as a Value message.
+// Oneof fields are written even when zero, so a set 0 differs from NULL.
+func encodeArchiveValue(v interface{}) ([]byte, error) {
+	var b []byte
+	switch v := v.(type) {
+	case nil:
+	case int64:
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeZigZag(v))
+	case float64:
+		b = protowire.AppendTag(b, 2, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(v))
+	case string:
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendString(b, v)
+	case []byte:
+		b = protowire.AppendTag(b, 4, protowire.BytesType)
+		b = protowire.AppendBytes(b, v)
+	case time.Time:
+		_, offset := v.Zone()
+		var ts []byte
+		ts = appendArchiveVarint(ts, 1, uint64(v.Unix()))
+		ts = appendArchiveVarint(ts, 2, uint64(int64(v.Nanosecond())))
+		ts = appendArchiveVarint(ts, 3, uint64(int64(offset)))
+		b = protowire.AppendTag(b, 5, protowire.BytesType)
+		b = protowire.AppendBytes(b, ts)
+	case bool:
+		b = protowire.AppendTag(b, 6, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(v))
+	default:
+		return nil, fmt.Errorf("unsupported column value %T", v)
+	}
+	return b, nil
+}
+
+// ImportArchive loads an archive written by ExportArchive into the store in
+// one transaction. Rows whose key is already present are kept as they are,
+// so importing the same archive twice adds nothing. Tables with an
+// auto-increment ID (event logs, queues) keep their IDs in an empty table
+// and are appended with new ones otherwise. Tables this version doesn't
+// know are skipped.
+func (s *MessageStore) ImportArchive(ctx context.Context, in io.Reader, progress ExportProgress) ([]ArchiveTableSummary, error) {
+	zr, err := archiveReader(in)
+	if err != nil {
+		return nil, fmt.Errorf("not a whatsapp-cli archive: %w", err)
+	}
+	defer zr.Close()
+	r := bufio.NewReaderSize(zr, 1<<16)
+
+	num, msg, err := readArchiveRecord(r)
+	if err != nil || num != 1 {
+		return nil, fmt.Errorf("not a whatsapp-cli archive: missing header")
+	}
+	if v, err := archiveVersion(msg); err != nil {
+		return nil, err
+	} else if v != ArchiveVersion {
+		return nil, fmt.Errorf("unsupported archive version %d (this version reads %d)", v, ArchiveVersion)
+	}
+
+	schema, err := s.Schema()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]TableSchema, len(schema))
+	for _, t := range schema {
+		known[t.Name] = t
+	}
+
+	// Foreign keys are checked per connection and can't change inside a
+	// transaction; the archive may hold rows the source never enforced them on.
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return nil, err
+	}
+	defer conn.ExecContext(context.Background(), "PRAGMA foreign_keys = ON")
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var summary []ArchiveTableSummary
+	for {
+		num, msg, err := readArchiveRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if num != 2 {
+			return nil, fmt.Errorf("corrupt archive: expected a table, got record %d", num)
+		}
+		name, columns, total, err := decodeArchiveTable(msg)
+		if err != nil {
+			return nil, err
+		}
+		t, ok := known[name]
+		if !ok {
+			if err := skipArchiveRows(r, total); err != nil {
+				return nil, fmt.Errorf("failed to import %s: %w", name, err)
+			}
+			summary = append(summary, ArchiveTableSummary{Table: name, Rows: total, Skipped: true})
+			continue
+		}
+		imported, err := importArchiveTable(ctx, tx, r, t, columns, total, progress)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import %s: %w", name, err)
+		}
+		summary = append(summary, ArchiveTableSummary{Table: name, Rows: total, Imported: &imported})
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+func importArchiveTable(ctx context.Context, tx *sql.Tx, r *bufio.Reader, t TableSchema, columns []string, total int64, progress ExportProgress) (int64, error) {
+	have := make(map[string]bool, len(t.Columns))
+	for _, c := range t.Columns {
+		have[c.Name] = true
+	}
+	for _, c := range columns {
+		if !have[c] {
+			return 0, fmt.Errorf("column %s is unknown to this version; upgrade whatsapp-cli to import this archive", c)
+		}
+	}
+
+	// Keep auto-increment IDs only when they can't collide with existing rows
+	skip := -1
+	if id, ok := identityColumn(t); ok {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %q)", t.Name)).Scan(&exists); err != nil {
+			return 0, err
+		}
+		if exists {
+			for i, c := range columns {
+				if c == id {
+					skip = i
+				}
+			}
+		}
+	}
+	var quoted, marks []string
+	for i, c := range columns {
+		if i == skip {
+			continue
+		}
+		quoted = append(quoted, fmt.Sprintf("%q", c))
+		marks = append(marks, "?")
+	}
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT OR IGNORE INTO %q (%s) VALUES (%s)", t.Name, strings.Join(quoted, ", "), strings.Join(marks, ", ")))
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	args := make([]interface{}, 0, len(columns))
+	var imported int64
+	for n := int64(1); n <= total; n++ {
+		num, msg, err := readArchiveRecord(r)
+		if err == io.EOF {
+			return imported, fmt.Errorf("archive is truncated: %d of %d rows", n-1, total)
+		}
+		if err != nil {
+			return imported, err
+		}
+		if num != 3 {
+			return imported, fmt.Errorf("corrupt archive: expected a row, got record %d", num)
+		}
+		values, err := decodeArchiveRow(msg)
+		if err != nil {
+			return imported, err
+		}
+		if len(values) != len(columns) {
+			return imported, fmt.Errorf("corrupt archive: row has %d values for %d columns", len(values), len(columns))
+		}
+		args = args[:0]
+		for i, v := range values {
+			if i != skip {
+				args = append(args, v)
+			}
+		}
+		res, err := stmt.ExecContext(ctx, args...)
+		if err != nil {
+			return imported, err
+		}
+		affected, _ := res.RowsAffected()
+		imported += affected
+		if progress != nil && n%progressEvery == 0 {
+			progress(t.Name, n, total)
+		}
+	}
+	if progress != nil {
+		progress(t.Name, total, total)
+	}
+	return imported, nil
+}
+
+func skipArchiveRows(r *bufio.Reader, total int64) error {
+	for n := int64(0); n < total; n++ {
+		num, _, err := readArchiveRecord(r)
+		if err == io.EOF {
+			return fmt.Errorf("archive is truncated: %d of %d rows", n, total)
+		}
+		if err != nil {
+			return err
+		}
+		if num != 3 {
+			return fmt.Errorf("corrupt archive: expected a row, got record %d", num)
+		}
+	}
+	return nil
+}
+
+// archiveReader decompresses an archive. Archives are zstd streams; the
+// first builds wrote gzip, which is still read.
+func archiveReader(in io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(in)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return gzip.NewReader(br)
+	}
+	zr, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return zr.IOReadCloser(), nil
+}
+
+// readArchiveRecord reads one length-prefixed Record and returns the number
+// and content of its field. It returns io.EOF at the end of the archive.
+func readArchiveRecord(r *bufio.Reader) (protowire.Number, []byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err == io.EOF {
+		return 0, nil, io.EOF
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("corrupt archive: %w", err)
+	}
+	if size > maxArchiveRecord {
+		return 0, nil, fmt.Errorf("corrupt archive: record of %d bytes", size)
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, nil, fmt.Errorf("archive is truncated: %w", err)
+	}
+	num, typ, n := protowire.ConsumeTag(buf)
+	if n < 0 || typ != protowire.BytesType {
+		return 0, nil, errCorruptArchive
+	}
+	msg, m := protowire.ConsumeBytes(buf[n:])
+	if m < 0 {
+		return 0, nil, errCorruptArchive
+	}
+	return num, msg, nil
+}
+
+var errCorruptArchive = errors.New("corrupt archive: invalid record")
+
+// archiveFields calls fn with each field of msg.
+func archiveFields(msg []byte, fn func(num protowire.Number, typ protowire.Type, v []byte) int) error {
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			return errCorruptArchive
+		}
+		msg = msg[n:]
+		m := fn(num, typ, msg)
+		if m < 0 {
+			return errCorruptArchive
+		}
+		msg = msg[m:]
+	}
+	return nil
+}
+
+func archiveVersion(msg []byte) (uint64, error) {
+	var version uint64
+	err := archiveFields(msg, func(num protowire.Number, typ protowire.Type, b []byte) int {
+		if num == 1 && typ == protowire.VarintType {
+			v, n := protowire.ConsumeVarint(b)
+			version = v
+			return n
+		}
+		return protowire.ConsumeFieldValue(num, typ, b)
+	})
+	return version, err
+}
+
+func decodeArchiveTable(msg []byte) (name string, columns []string, rows int64, err error) {
+	err = archiveFields(msg, func(num protowire.Number, typ protowire.Type, b []byte) int {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			name = v
+			return n
+		case num == 2 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			columns = append(columns, v)
+			return n
+		case num == 3 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			rows = int64(v)
+			return n
+		}
+		return protowire.ConsumeFieldValue(num, typ, b)
+	})
+	if err == nil && name == "" {
+		err = errCorruptArchive
+	}
+	return name, columns, rows, err
+}
+
+func decodeArchiveRow(msg []byte) ([]interface{}, error) {
+	var values []interface{}
+	err := archiveFields(msg, func(num protowire.Number, typ protowire.Type, b []byte) int {
+		if num != 1 || typ != protowire.BytesType {
+			return protowire.ConsumeFieldValue(num, typ, b)
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return n
+		}
+		value, err := decodeArchiveValue(v)
+		if err != nil {
+			return -1
+		}
+		values = append(values, value)
+		return n
+	})
+	return values, err
+}
+
+func decodeArchiveValue(msg []byte) (interface{}, error) {
+	var value interface{}
+	err := archiveFields(msg, func(num protowire.Number, typ protowire.Type, b []byte) int {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			value = protowire.DecodeZigZag(v)
+			return n
+		case num == 2 && typ == protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			value = math.Float64frombits(v)
+			return n
+		case num == 3 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			value = v
+			return n
+		case num == 4 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			value = append([]byte{}, v...)
+			return n
+		case num == 5 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n
+			}
+			t, err := decodeArchiveTime(v)
+			if err != nil {
+				return -1
+			}
+			value = t
+			return n
+		case num == 6 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			value = protowire.DecodeBool(v)
+			return n
+		}
+		return protowire.ConsumeFieldValue(num, typ, b)
+	})
+	return value, err
+}
+
+func decodeArchiveTime(msg []byte) (time.Time, error) {
+	var sec, nsec, offset int64
+	err := archiveFields(msg, func(num protowire.Number, typ protowire.Type, b []byte) int {
+		if typ != protowire.VarintType {
+			return protowire.ConsumeFieldValue(num, typ, b)
+		}
+		v, n := protowire.ConsumeVarint(b)
+		switch num {
+		case 1:
+			sec = int64(v)
+		case 2:
+			nsec = int64(int32(v))
+		case 3:
+			offset = int64(int32(v))
+		}
+		return n
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	t := time.Unix(sec, nsec)
+	if offset == 0 {
+		return t.UTC(), nil
+	}
+	return t.In(time.FixedZone("", int(offset))), nil
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	src := setupTestDB(t)
+	chatJID := "123@s.whatsapp.net"
+	ts := time.Date(2026, 3, 1, 12, 0, 0, 123456789, time.FixedZone("", 2*3600))
+	require.NoError(t, src.StoreChat(chatJID, "Alice", ts))
+	require.NoError(t, src.StoreMessage("m1", chatJID, "123", "line one\nline two", ts, false,
+		"image", "", "https://mmg.example", "/v/t62", "image/jpeg", []byte{0xde, 0xad}, nil, nil, 2048))
+	require.NoError(t, src.StoreMessage("m2", chatJID, "me", "", ts.Add(-time.Hour).UTC(), true, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, src.StoreChatEvent(ChatEvent{ChatJID: chatJID, Type: ChatEventJoinRequest, Target: "456", Timestamp: ts}))
+	require.NoError(t, src.StoreEmbeddings("m", []Embedding{{MessageID: "m1", ChatJID: chatJID, Vector: []float32{0.5, -1.25}}}))
+	// An orphaned message, as older versions could leave behind
+	_, err := src.db.Exec(`PRAGMA foreign_keys = OFF; INSERT INTO messages (id, chat_jid, sender, content, timestamp, is_from_me) VALUES ('m3', 'gone@s.whatsapp.net', 'x', 'orphan', ?, 0); PRAGMA foreign_keys = ON`, ts)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	var progressed []string
+	summary, err := src.ExportArchive(context.Background(), &buf, func(table string, done, total int64) {
+		progressed = append(progressed, table)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "chats", summary[0].Table)
+	assert.Contains(t, progressed, "messages")
+	archive := buf.Bytes()
+	assert.Equal(t, []byte{0x28, 0xb5, 0x2f, 0xfd}, archive[:4], "zstd frame")
+
+	dst := setupTestDB(t)
+	imported, err := dst.ImportArchive(context.Background(), bytes.NewReader(archive), nil)
+	require.NoError(t, err)
+	counts := map[string]int64{}
+	for _, s := range imported {
+		require.NotNil(t, s.Imported, s.Table)
+		assert.Equal(t, s.Rows, *s.Imported, s.Table)
+		counts[s.Table] = s.Rows
+	}
+	assert.Equal(t, int64(3), counts["messages"])
+	assert.Equal(t, int64(1), counts["chat_events"])
+
+	for _, table := range []string{"chats", "messages", "chat_events", "message_embeddings"} {
+		assert.Equal(t, dumpTable(t, src, table), dumpTable(t, dst, table), table)
+	}
+
+	// Importing again adds nothing but appends to the event log
+	again, err := dst.ImportArchive(context.Background(), bytes.NewReader(archive), nil)
+	require.NoError(t, err)
+	for _, s := range again {
+		want := int64(0)
+		if s.Table == "chat_events" {
+			want = 1
+		}
+		assert.Equal(t, want, *s.Imported, s.Table)
+	}
+	events, err := dst.ListChatEvents(chatJID, 10)
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+}
+
+func TestImportArchiveRejectsCorruptInput(t *testing.T) {
+	src := setupTestDB(t)
+	require.NoError(t, src.StoreChat("123@s.whatsapp.net", "Alice", time.Now()))
+	var buf bytes.Buffer
+	_, err := src.ExportArchive(context.Background(), &buf, nil)
+	require.NoError(t, err)
+
+	dst := setupTestDB(t)
+	_, err = dst.ImportArchive(context.Background(), bytes.NewReader([]byte("not an archive")), nil)
+	assert.ErrorContains(t, err, "not a whatsapp-cli archive")
+	_, err = dst.ImportArchive(context.Background(), bytes.NewReader(buf.Bytes()[:buf.Len()/2]), nil)
+	assert.Error(t, err)
+
+	chats, err := dst.ListChats(ListChatsParams{Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, chats, "a failed import leaves the store unchanged")
+}
+
+func TestImportArchiveReadsGzip(t *testing.T) {
+	src := setupTestDB(t)
+	require.NoError(t, src.StoreChat("123@s.whatsapp.net", "Alice", time.Now()))
+	var buf bytes.Buffer
+	_, err := src.ExportArchive(context.Background(), &buf, nil)
+	require.NoError(t, err)
+
+	// Recompress as the first builds wrote archives
+	zr, err := archiveReader(&buf)
+	require.NoError(t, err)
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, err = io.Copy(zw, zr)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	dst := setupTestDB(t)
+	_, err = dst.ImportArchive(context.Background(), &gz, nil)
+	require.NoError(t, err)
+	assert.Equal(t, dumpTable(t, src, "chats"), dumpTable(t, dst, "chats"))
+}
+
+func dumpTable(t *testing.T, s *MessageStore, table string) [][]interface{} {
+	t.Helper()
+	// Migrated columns are added in map order, so select them by name
+	var cols string
+	require.NoError(t, s.db.QueryRow(fmt.Sprintf("SELECT group_concat(quote(name), ', ') FROM (SELECT name FROM pragma_table_info(%q) ORDER BY name)", table)).Scan(&cols))
+	rows, err := s.db.Query(fmt.Sprintf("SELECT %s FROM %q ORDER BY 1, 2", cols, table))
+	require.NoError(t, err)
+	defer rows.Close()
+	var out [][]interface{}
+	for rows.Next() {
+		vals := make([]interface{}, strings.Count(cols, ",")+1)
+		ptrs := make([]interface{}, len(vals))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		require.NoError(t, rows.Scan(ptrs...))
+		out = append(out, vals)
+	}
+	require.NoError(t, rows.Err())
+	return out
+}
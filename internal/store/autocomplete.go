@@ -0,0 +1,77 @@
+package store
+
+import (
+	"strings"
+)
+
+// Suggestion is an autocomplete match: a contact or a group chat.
+type Suggestion struct {
+	JID   string `json:"jid"`
+	Name  string `json:"name"`
+	Type  string `json:"type"`            // "contact" or "group"
+	Phone string `json:"phone,omitempty"` // only for phone-number JIDs
+}
+
+// AutocompleteParams selects the chats suggested for a prefix.
+type AutocompleteParams struct {
+	Prefix      string
+	Limit       int
+	IncludeJIDs []string
+	ExcludeJIDs []string
+}
+
+// Autocomplete suggests the chats whose name starts with params.Prefix,
+// ignoring case, then those with a later word of the name starting with it,
+// then those whose phone number starts with it. Within each rank, recently
+// active chats come first. Name and phone prefixes are looked up in indexes;
+// only word matches scan the chats.
+func (s *MessageStore) Autocomplete(params AutocompleteParams) ([]Suggestion, error) {
+	prefix := escapeLike(params.Prefix)
+	query := `SELECT jid, COALESCE(name, '') FROM (
+		SELECT jid, name, last_message_time, 0 AS rank FROM chats WHERE name LIKE ? ESCAPE '\'
+		UNION ALL
+		SELECT jid, name, last_message_time, 1 FROM chats WHERE name LIKE ? ESCAPE '\'`
+	args := []interface{}{prefix + "%", "% " + prefix + "%"}
+	if phone := strings.TrimPrefix(params.Prefix, "+"); isDigits(phone) {
+		// A range over the primary key; incrementing the last digit, '9'
+		// included, gives the first JID past the prefix
+		query += `
+		UNION ALL
+		SELECT jid, name, last_message_time, 2 FROM chats WHERE jid >= ? AND jid < ?`
+		args = append(args, phone, phone[:len(phone)-1]+string(phone[len(phone)-1]+1))
+	}
+	query += `
+	) WHERE 1=1`
+	query, args = appendJIDFilter(query, args, "jid", params.IncludeJIDs, params.ExcludeJIDs)
+	query += " GROUP BY jid ORDER BY MIN(rank), MAX(last_message_time) DESC LIMIT ?"
+	args = append(args, params.Limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	suggestions := []Suggestion{}
+	for rows.Next() {
+		var sg Suggestion
+		if err := rows.Scan(&sg.JID, &sg.Name); err != nil {
+			return nil, err
+		}
+		sg.Type = "contact"
+		if user, server, ok := strings.Cut(sg.JID, "@"); ok {
+			switch server {
+			case "g.us":
+				sg.Type = "group"
+			case "s.whatsapp.net":
+				sg.Phone = user
+			}
+		}
+		suggestions = append(suggestions, sg)
+	}
+	return suggestions, rows.Err()
+}
+
+// escapeLike escapes the LIKE wildcards in s for use with ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutocomplete(t *testing.T) {
+	s := setupTestDB(t)
+	now := time.Now().UTC()
+	for _, c := range []struct {
+		jid, name string
+		age       time.Duration
+	}{
+		{"34600111222@s.whatsapp.net", "John Smith", 3 * time.Hour},
+		{"34600333444@s.whatsapp.net", "Joanna", time.Hour},
+		{"34699000000@s.whatsapp.net", "Mary Jones", 0},
+		{"120363000000000001@g.us", "Jogging club", 2 * time.Hour},
+		{"44700111222@s.whatsapp.net", "Bob", 0},
+		{"44700999888@s.whatsapp.net", "100% Bob_", 0},
+	} {
+		require.NoError(t, s.StoreChat(c.jid, c.name, now.Add(-c.age)))
+	}
+	complete := func(prefix string, limit int) []string {
+		t.Helper()
+		suggestions, err := s.Autocomplete(AutocompleteParams{Prefix: prefix, Limit: limit})
+		require.NoError(t, err)
+		names := make([]string, len(suggestions))
+		for i, sg := range suggestions {
+			names[i] = sg.Name
+		}
+		return names
+	}
+
+	// Name prefixes first, most recent first, then later words
+	assert.Equal(t, []string{"Joanna", "Jogging club", "John Smith", "Mary Jones"}, complete("jo", 10))
+	assert.Equal(t, []string{"Joanna", "Jogging club"}, complete("JO", 2))
+	assert.Equal(t, []string{"John Smith"}, complete("smi", 10))
+
+	// Phone prefixes, with or without +, after name matches
+	assert.Equal(t, []string{"Mary Jones", "Joanna", "John Smith"}, complete("+346", 10))
+	assert.Equal(t, []string{"Mary Jones"}, complete("34699", 10))
+
+	// LIKE wildcards match literally
+	assert.Equal(t, []string{"100% Bob_"}, complete("100%", 10))
+	assert.Empty(t, complete("b_b", 10))
+
+	suggestions, err := s.Autocomplete(AutocompleteParams{Prefix: "jo", Limit: 10, ExcludeJIDs: []string{"@g.us"}})
+	require.NoError(t, err)
+	require.Len(t, suggestions, 3)
+	assert.Equal(t, Suggestion{JID: "34600333444@s.whatsapp.net", Name: "Joanna", Type: "contact", Phone: "34600333444"}, suggestions[0])
+
+	suggestions, err = s.Autocomplete(AutocompleteParams{Prefix: "jog", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []Suggestion{{JID: "120363000000000001@g.us", Name: "Jogging club", Type: "group"}}, suggestions)
+}
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// AwayWindow is a recurring period during which the away message is active.
+// Start and End are "HH:MM" in the configured timezone; an End at or before
+// Start runs past midnight and "24:00" means end of day. Empty Days means
+// every day.
+type AwayWindow struct {
+	Days  []string `json:"days,omitempty"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+// AwayConfig is the away-message configuration.
+type AwayConfig struct {
+	Enabled  bool         `json:"enabled"`
+	Message  string       `json:"message"`
+	Timezone string       `json:"timezone"`
+	Windows  []AwayWindow `json:"windows"`
+}
+
+// GetAwayConfig returns the stored away configuration, or a disabled zero
+// configuration if none was saved.
+func (s *MessageStore) GetAwayConfig() (AwayConfig, error) {
+	var cfg AwayConfig
+	var windows string
+	err := s.db.QueryRow(`SELECT enabled, message, timezone, windows FROM away_config WHERE id = 1`).
+		Scan(&cfg.Enabled, &cfg.Message, &cfg.Timezone, &windows)
+	if err == sql.ErrNoRows {
+		return AwayConfig{Timezone: "UTC", Windows: []AwayWindow{}}, nil
+	}
+	if err != nil {
+		return AwayConfig{}, err
+	}
+	if err := json.Unmarshal([]byte(windows), &cfg.Windows); err != nil {
+		return AwayConfig{}, err
+	}
+	return cfg, nil
+}
+
+// SaveAwayConfig replaces the away configuration.
+func (s *MessageStore) SaveAwayConfig(cfg AwayConfig) error {
+	if cfg.Windows == nil {
+		cfg.Windows = []AwayWindow{}
+	}
+	windows, err := json.Marshal(cfg.Windows)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO away_config (id, enabled, message, timezone, windows) VALUES (1, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			enabled = excluded.enabled,
+			message = excluded.message,
+			timezone = excluded.timezone,
+			windows = excluded.windows`,
+		cfg.Enabled, cfg.Message, cfg.Timezone, string(windows),
+	)
+	return err
+}
+
+// SetAwayOptOut adds or removes a chat from the away-message opt-out list.
+func (s *MessageStore) SetAwayOptOut(chatJID string, optOut bool) error {
+	if !optOut {
+		_, err := s.db.Exec(`DELETE FROM away_optouts WHERE chat_jid = ?`, chatJID)
+		return err
+	}
+	_, err := s.db.Exec(
+		`INSERT INTO away_optouts (chat_jid, created_at) VALUES (?, ?) ON CONFLICT(chat_jid) DO NOTHING`,
+		chatJID, time.Now().UTC(),
+	)
+	return err
+}
+
+// IsAwayOptedOut reports whether a chat opted out of away messages.
+func (s *MessageStore) IsAwayOptedOut(chatJID string) (bool, error) {
+	var exists int
+	err := s.db.QueryRow(`SELECT 1 FROM away_optouts WHERE chat_jid = ?`, chatJID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ListAwayOptOuts returns the JIDs of chats that opted out of away messages.
+func (s *MessageStore) ListAwayOptOuts() ([]string, error) {
+	rows, err := s.db.Query(`SELECT chat_jid FROM away_optouts ORDER BY chat_jid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jids := []string{}
+	for rows.Next() {
+		var jid string
+		if err := rows.Scan(&jid); err != nil {
+			return nil, err
+		}
+		jids = append(jids, jid)
+	}
+	return jids, rows.Err()
+}
+
+// RecordAwayReply claims the away reply for a chat in the window occurrence
+// starting at windowStart. It returns false if a reply was already recorded,
+// so each contact gets at most one reply per window.
+func (s *MessageStore) RecordAwayReply(chatJID string, windowStart, sentAt time.Time) (bool, error) {
+	res, err := s.db.Exec(
+		`INSERT INTO away_replies (chat_jid, window_start, sent_at) VALUES (?, ?, ?)
+		ON CONFLICT(chat_jid, window_start) DO NOTHING`,
+		chatJID, windowStart.Unix(), sentAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
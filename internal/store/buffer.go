@@ -0,0 +1,144 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// DefaultBufferLimit is the number of pending writes kept in memory while the
+// database is failing. Older writes are dropped once the limit is reached.
+const DefaultBufferLimit = 10000
+
+// Health describes the write health of the message store.
+type Health struct {
+	Healthy   bool   `json:"healthy"`
+	Buffered  int    `json:"buffered"`
+	Dropped   int64  `json:"dropped"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// BufferedWriter applies writes to the store and keeps them in memory when the
+// database temporarily rejects them (busy, locked, disk full, I/O error),
+// replaying them in order once the database accepts writes again. Writes that
+// fail for any other reason (constraint violations, bad SQL) would fail the
+// same way on every retry, so they are logged and dropped instead.
+type BufferedWriter struct {
+	mu      sync.Mutex
+	pending []func() error
+	limit   int
+	dropped int64
+	lastErr string
+}
+
+// NewBufferedWriter creates a BufferedWriter holding at most limit pending writes.
+func NewBufferedWriter(limit int) *BufferedWriter {
+	if limit <= 0 {
+		limit = DefaultBufferLimit
+	}
+	return &BufferedWriter{limit: limit}
+}
+
+// Write runs fn immediately unless earlier writes are still pending, in which
+// case fn is queued behind them to preserve ordering. It reports whether fn
+// was applied right away.
+func (b *BufferedWriter) Write(fn func() error) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.pending) == 0 {
+		err := fn()
+		if err == nil {
+			return true
+		}
+		b.lastErr = err.Error()
+		if !isTemporary(err) {
+			b.drop(err)
+			return false
+		}
+	}
+
+	if len(b.pending) >= b.limit {
+		b.pending = b.pending[1:]
+		b.dropped++
+	}
+	b.pending = append(b.pending, fn)
+	return false
+}
+
+// Flush replays pending writes in order, stopping at the first temporary
+// failure. Writes that fail permanently are dropped so they do not hold up
+// the ones behind them. It returns the number of writes applied.
+func (b *BufferedWriter) Flush() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	applied := 0
+	for len(b.pending) > 0 {
+		if err := b.pending[0](); err != nil {
+			b.lastErr = err.Error()
+			if isTemporary(err) {
+				break
+			}
+			b.drop(err)
+		} else {
+			applied++
+		}
+		b.pending[0] = nil
+		b.pending = b.pending[1:]
+	}
+	if len(b.pending) == 0 {
+		b.lastErr = ""
+	}
+	return applied
+}
+
+// drop records a write discarded after a permanent failure.
+func (b *BufferedWriter) drop(err error) {
+	b.dropped++
+	fmt.Fprintf(os.Stderr, "⚠ Dropped store write: %v\n", err)
+}
+
+// isTemporary reports whether a failed write may succeed if retried later.
+func isTemporary(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	switch sqliteErr.Code {
+	case sqlite3.ErrBusy, sqlite3.ErrLocked, sqlite3.ErrFull, sqlite3.ErrIoErr:
+		return true
+	}
+	return false
+}
+
+// Run flushes pending writes every interval until ctx is cancelled.
+func (b *BufferedWriter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.Flush()
+		}
+	}
+}
+
+// Health returns a snapshot of the writer state.
+func (b *BufferedWriter) Health() Health {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return Health{
+		Healthy:   len(b.pending) == 0,
+		Buffered:  len(b.pending),
+		Dropped:   b.dropped,
+		LastError: b.lastErr,
+	}
+}
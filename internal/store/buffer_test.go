@@ -0,0 +1,125 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBufferedWriterAppliesWritesWhenHealthy(t *testing.T) {
+	w := NewBufferedWriter(10)
+	calls := 0
+
+	applied := w.Write(func() error { calls++; return nil })
+
+	assert.True(t, applied)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, Health{Healthy: true}, w.Health())
+}
+
+func TestBufferedWriterBuffersAndReplaysInOrder(t *testing.T) {
+	w := NewBufferedWriter(10)
+	storeErr := sqlite3.Error{Code: sqlite3.ErrIoErr}
+	failing := true
+	var order []int
+
+	write := func(n int) func() error {
+		return func() error {
+			if failing {
+				return storeErr
+			}
+			order = append(order, n)
+			return nil
+		}
+	}
+
+	assert.False(t, w.Write(write(1)))
+	assert.False(t, w.Write(write(2)))
+
+	health := w.Health()
+	assert.False(t, health.Healthy)
+	assert.Equal(t, 2, health.Buffered)
+	assert.Equal(t, "disk I/O error", health.LastError)
+
+	// Still failing: nothing is applied
+	assert.Equal(t, 0, w.Flush())
+
+	failing = false
+	assert.Equal(t, 2, w.Flush())
+	assert.Equal(t, []int{1, 2}, order)
+	assert.Equal(t, Health{Healthy: true}, w.Health())
+}
+
+func TestBufferedWriterQueuesBehindPendingWrites(t *testing.T) {
+	w := NewBufferedWriter(10)
+	w.Write(func() error { return sqlite3.Error{Code: sqlite3.ErrLocked} })
+
+	called := false
+	applied := w.Write(func() error { called = true; return nil })
+
+	assert.False(t, applied)
+	assert.False(t, called, "write must wait behind pending writes")
+	assert.Equal(t, 2, w.Health().Buffered)
+}
+
+func TestBufferedWriterDropsOldestWhenFull(t *testing.T) {
+	w := NewBufferedWriter(2)
+	var order []int
+	failing := true
+	write := func(n int) func() error {
+		return func() error {
+			if failing {
+				return sqlite3.Error{Code: sqlite3.ErrFull}
+			}
+			order = append(order, n)
+			return nil
+		}
+	}
+
+	w.Write(write(1))
+	w.Write(write(2))
+	w.Write(write(3))
+
+	health := w.Health()
+	assert.Equal(t, 2, health.Buffered)
+	assert.Equal(t, int64(1), health.Dropped)
+
+	failing = false
+	w.Flush()
+	assert.Equal(t, []int{2, 3}, order)
+}
+
+func TestBufferedWriterDropsPermanentFailures(t *testing.T) {
+	w := NewBufferedWriter(10)
+	busy := true
+	var order []int
+	write := func(n int) func() error {
+		return func() error {
+			if busy {
+				return sqlite3.Error{Code: sqlite3.ErrBusy}
+			}
+			order = append(order, n)
+			return nil
+		}
+	}
+	broken := func() error { return sqlite3.Error{Code: sqlite3.ErrConstraint} }
+
+	// Failing right away is not retried
+	assert.False(t, w.Write(broken))
+	assert.Equal(t, Health{Healthy: true, Dropped: 1, LastError: "constraint failed"}, w.Health())
+
+	// Failing on replay does not hold up the writes behind it
+	w.Write(write(1))
+	w.Write(broken)
+	w.Write(write(2))
+	w.Write(func() error { return errors.New("no such column: foo") })
+	w.Write(write(3))
+	assert.Equal(t, 5, w.Health().Buffered)
+
+	busy = false
+	assert.Equal(t, 3, w.Flush())
+	assert.Equal(t, []int{1, 2, 3}, order)
+	assert.Equal(t, Health{Healthy: true, Dropped: 3}, w.Health())
+}
@@ -0,0 +1,335 @@
+package store
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+)
+
+// ChainAlgorithm names how chain hashes are computed: hash n is
+// SHA-256(hash n-1 || JSON of ChainRecord n), hex-encoded, with 32 zero
+// bytes before the first record. Anyone can recompute a chain from an export.
+const ChainAlgorithm = "sha256-chain-v1"
+
+// ChainRecord is the part of a message a chain hash covers. Its JSON
+// encoding, with the fields in this order, is what is hashed. It leaves out
+// what storing the same message again may legitimately change: the sender
+// once its LID resolves to a phone number, the timestamp history sync
+// reports, and the media hash filled in after a download.
+type ChainRecord struct {
+	MessageID string `json:"message_id"`
+	IsFromMe  bool   `json:"is_from_me"`
+	Content   string `json:"content"`
+	MediaType string `json:"media_type"`
+}
+
+// ChainLink is a message at its place in a chat's chain.
+type ChainLink struct {
+	Seq int64 `json:"seq"`
+	ChainRecord
+	Hash      string    `json:"hash"`
+	ChainedAt time.Time `json:"chained_at"`
+}
+
+// ChainExport is a chat's chain with the messages it covers, oldest link
+// first. Head is the hash of the last link: publishing or notarizing it
+// commits to the whole conversation up to that point. Signature, when the
+// archive has a signing key, is its base64 ed25519 signature of the head;
+// see SignChain.
+type ChainExport struct {
+	ChatJID    string      `json:"chat_jid"`
+	Algorithm  string      `json:"algorithm"`
+	Length     int         `json:"length"`
+	Head       string      `json:"head"`
+	ExportedAt time.Time   `json:"exported_at"`
+	PublicKey  string      `json:"public_key,omitempty"`
+	Signature  string      `json:"signature,omitempty"`
+	Links      []ChainLink `json:"links"`
+}
+
+// ChainVerification is the outcome of checking a chain. BrokenAt is the
+// first link that does not hold, and Reason says why. Signed reports an
+// export whose signature was checked against the signing key.
+type ChainVerification struct {
+	ChatJID  string `json:"chat_jid"`
+	Length   int    `json:"length"`
+	Head     string `json:"head"`
+	Valid    bool   `json:"valid"`
+	Signed   bool   `json:"signed,omitempty"`
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// ParseChainSigningKey decodes a base64 ed25519 seed. An empty key decodes
+// to nil, which leaves exports unsigned.
+func ParseChainSigningKey(s string) (ed25519.PrivateKey, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	seed, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("chain signing key must be a base64 32-byte ed25519 seed")
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// chainStatement is what a chain signature covers: the algorithm, chat,
+// length, head and export time, one per line.
+func chainStatement(e ChainExport) []byte {
+	return []byte(strings.Join([]string{
+		e.Algorithm, e.ChatJID, strconv.Itoa(e.Length), e.Head, e.ExportedAt.UTC().Format(time.RFC3339Nano),
+	}, "\n"))
+}
+
+// SignChain signs the head of an export with key, and records the public
+// key it verifies with.
+func SignChain(e *ChainExport, key ed25519.PrivateKey) {
+	e.PublicKey = base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	e.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, chainStatement(*e)))
+}
+
+// verifyChainSignature checks the signature of an export against key, not
+// against the public key the export names, which anyone could replace.
+func verifyChainSignature(e ChainExport, key ed25519.PublicKey) string {
+	if e.Signature == "" {
+		return "export is not signed"
+	}
+	sig, err := base64.StdEncoding.DecodeString(e.Signature)
+	if err != nil || !ed25519.Verify(key, chainStatement(e), sig) {
+		return "signature does not match the signing key"
+	}
+	return ""
+}
+
+// ChainHash returns the hash of r following the link hashed prev, or
+// following nothing when prev is empty.
+func ChainHash(prev string, r ChainRecord) (string, error) {
+	prevSum := make([]byte, sha256.Size)
+	if prev != "" {
+		b, err := hex.DecodeString(prev)
+		if err != nil || len(b) != sha256.Size {
+			return "", fmt.Errorf("invalid chain hash %q", prev)
+		}
+		prevSum = b
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write(prevSum)
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// chainRecord reads the hashed fields of a stored message; sql.ErrNoRows
+// means it is gone.
+func chainRecord(q interface {
+	QueryRow(string, ...interface{}) *sql.Row
+}, chatJID, messageID string) (ChainRecord, error) {
+	r := ChainRecord{MessageID: messageID}
+	var content, mediaType sql.NullString
+	err := q.QueryRow(
+		`SELECT content, is_from_me, media_type FROM messages WHERE id = ? AND chat_jid = ?`,
+		messageID, chatJID,
+	).Scan(&content, &r.IsFromMe, &mediaType)
+	r.Content, r.MediaType = content.String, mediaType.String
+	return r, err
+}
+
+// ChainMessage appends a stored message to its chat's chain. Messages are
+// chained once, in the order they are stored; chaining one again changes
+// nothing.
+func (s *MessageStore) ChainMessage(messageID, chatJID string, at time.Time) error {
+	chatJID = s.resolve(chatJID)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRow(`SELECT 1 FROM message_chain WHERE chat_jid = ? AND message_id = ?`, chatJID, messageID).Scan(&exists)
+	if err == nil {
+		return nil
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+	var seq int64
+	var prev string
+	err = tx.QueryRow(`SELECT seq, hash FROM message_chain WHERE chat_jid = ? ORDER BY seq DESC LIMIT 1`, chatJID).Scan(&seq, &prev)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	r, err := chainRecord(tx, chatJID, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to chain message %s: %w", messageID, err)
+	}
+	hash, err := ChainHash(prev, r)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO message_chain (chat_jid, seq, message_id, hash, chained_at) VALUES (?, ?, ?, ?, ?)`,
+		chatJID, seq+1, messageID, hash, at.UTC(),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// chainLinks lists the links of the chain recorded under chatJID, oldest
+// first, without their records.
+func (s *MessageStore) chainLinks(chatJID string) ([]ChainLink, error) {
+	rows, err := s.db.Query(`SELECT seq, message_id, hash, chained_at FROM message_chain WHERE chat_jid = ? ORDER BY seq`, chatJID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var links []ChainLink
+	for rows.Next() {
+		var l ChainLink
+		if err := rows.Scan(&l.Seq, &l.MessageID, &l.Hash, &l.ChainedAt); err != nil {
+			return nil, err
+		}
+		l.ChainedAt = l.ChainedAt.UTC()
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// chainWithRecords lists the chain recorded under chatJID with the messages
+// its links cover as they are stored now, and the links whose message is
+// gone. A chain stays under the JID it was built for: after a merge, its
+// messages are read from the chat they moved to.
+func (s *MessageStore) chainWithRecords(chatJID string) ([]ChainLink, map[int64]bool, error) {
+	links, err := s.chainLinks(chatJID)
+	if err != nil {
+		return nil, nil, err
+	}
+	messagesJID := s.resolve(chatJID)
+	deleted := map[int64]bool{}
+	for i := range links {
+		r, err := chainRecord(s.db, messagesJID, links[i].MessageID)
+		if err == sql.ErrNoRows {
+			deleted[links[i].Seq] = true
+		} else if err != nil {
+			return nil, nil, err
+		}
+		links[i].ChainRecord = r
+	}
+	return links, deleted, nil
+}
+
+// ExportChain returns the chain of a chat with the messages it covers. A
+// message deleted since it was chained is exported by ID only, and the
+// export fails verification from its link on.
+func (s *MessageStore) ExportChain(chatJID string, at time.Time) (ChainExport, error) {
+	chatJID = jid.Normalize(chatJID)
+	e := ChainExport{ChatJID: chatJID, Algorithm: ChainAlgorithm, ExportedAt: at.UTC(), Links: []ChainLink{}}
+	links, _, err := s.chainWithRecords(chatJID)
+	if err != nil {
+		return e, err
+	}
+	if len(links) > 0 {
+		e.Links, e.Length, e.Head = links, len(links), links[len(links)-1].Hash
+	}
+	return e, nil
+}
+
+// VerifyChain recomputes the chain of a chat from the messages stored now,
+// which detects messages altered or deleted after they were chained, and
+// links edited or removed.
+func (s *MessageStore) VerifyChain(chatJID string) (ChainVerification, error) {
+	chatJID = jid.Normalize(chatJID)
+	links, deleted, err := s.chainWithRecords(chatJID)
+	if err != nil {
+		return ChainVerification{ChatJID: chatJID}, err
+	}
+	v := verifyLinks(chatJID, links)
+	if !v.Valid && deleted[v.BrokenAt] {
+		v.Reason = "message " + links[v.BrokenAt-1].MessageID + " was deleted"
+	}
+	return v, nil
+}
+
+// VerifyChainExport checks that an exported chain is intact and that the
+// store chained the same messages: each link must hash to its recorded hash,
+// and that hash must match the stored link with the same sequence number.
+// An export ending before the stored chain does is valid; it predates the
+// messages chained since. With a key, the export must also carry a valid
+// signature made with it.
+func (s *MessageStore) VerifyChainExport(e ChainExport, key ed25519.PublicKey) (ChainVerification, error) {
+	chatJID := jid.Normalize(e.ChatJID)
+	if e.Algorithm != ChainAlgorithm {
+		return ChainVerification{ChatJID: chatJID}, fmt.Errorf("unsupported chain algorithm %q, want %s", e.Algorithm, ChainAlgorithm)
+	}
+	v := verifyLinks(chatJID, e.Links)
+	if !v.Valid {
+		return v, nil
+	}
+	if e.Head != v.Head {
+		return brokenChain(v, int64(len(e.Links)), "head does not match the last link"), nil
+	}
+	if e.Length != len(e.Links) {
+		return brokenChain(v, int64(len(e.Links)), "length does not match the links"), nil
+	}
+	if key != nil {
+		e.ChatJID = chatJID
+		if reason := verifyChainSignature(e, key); reason != "" {
+			v.Valid, v.Reason = false, reason
+			return v, nil
+		}
+		v.Signed = true
+	}
+	stored, err := s.chainLinks(chatJID)
+	if err != nil {
+		return v, err
+	}
+	for i, l := range e.Links {
+		if i >= len(stored) {
+			return brokenChain(v, l.Seq, "link is not in the stored chain"), nil
+		}
+		if stored[i].Hash != l.Hash {
+			return brokenChain(v, l.Seq, "link differs from the stored chain"), nil
+		}
+	}
+	return v, nil
+}
+
+// verifyLinks recomputes the hashes of links, which must be numbered from 1.
+func verifyLinks(chatJID string, links []ChainLink) ChainVerification {
+	v := ChainVerification{ChatJID: chatJID, Length: len(links), Valid: true}
+	prev := ""
+	for i, l := range links {
+		if l.Seq != int64(i+1) {
+			return brokenChain(v, int64(i+1), fmt.Sprintf("link %d is missing", i+1))
+		}
+		hash, err := ChainHash(prev, l.ChainRecord)
+		if err != nil {
+			return brokenChain(v, l.Seq, err.Error())
+		}
+		if !strings.EqualFold(hash, l.Hash) {
+			return brokenChain(v, l.Seq, "message "+l.MessageID+" does not match its hash")
+		}
+		prev = l.Hash
+	}
+	v.Head = prev
+	return v
+}
+
+func brokenChain(v ChainVerification, seq int64, reason string) ChainVerification {
+	v.Valid, v.BrokenAt, v.Reason = false, seq, reason
+	return v
+}
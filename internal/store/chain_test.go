@@ -0,0 +1,148 @@
+package store
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageChain(t *testing.T) {
+	s := setupTestDB(t)
+	chat := "1234@s.whatsapp.net"
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, s.StoreChat(chat, "John", base))
+	for i, id := range []string{"m1", "m2", "m3"} {
+		require.NoError(t, s.StoreMessage(id, chat, "1234", "text "+id, base.Add(time.Duration(i)*time.Minute), false,
+			"", "", "", "", "", nil, nil, nil, 0))
+		require.NoError(t, s.ChainMessage(id, chat, base))
+	}
+	require.NoError(t, s.ChainMessage("m2", chat, base), "chaining again is a no-op")
+	assert.Error(t, s.ChainMessage("missing", chat, base))
+
+	e, err := s.ExportChain(chat, base)
+	require.NoError(t, err)
+	assert.Equal(t, ChainAlgorithm, e.Algorithm)
+	require.Equal(t, 3, e.Length)
+	assert.Equal(t, "text m2", e.Links[1].Content)
+	assert.Equal(t, e.Links[2].Hash, e.Head)
+	first, err := ChainHash("", e.Links[0].ChainRecord)
+	require.NoError(t, err)
+	assert.Equal(t, first, e.Links[0].Hash)
+
+	v, err := s.VerifyChain(chat)
+	require.NoError(t, err)
+	assert.True(t, v.Valid)
+	assert.Equal(t, e.Head, v.Head)
+
+	v, err = s.VerifyChainExport(e, nil)
+	require.NoError(t, err)
+	assert.True(t, v.Valid, v.Reason)
+
+	// An export altered after the fact
+	forged := e
+	forged.Links = append([]ChainLink(nil), e.Links...)
+	forged.Links[1].Content = "text m2, edited"
+	v, err = s.VerifyChainExport(forged, nil)
+	require.NoError(t, err)
+	assert.False(t, v.Valid)
+	assert.Equal(t, int64(2), v.BrokenAt)
+
+	// An export whose hashes were recomputed to match differs from the store
+	prev := e.Links[0].Hash
+	for i := 1; i < len(forged.Links); i++ {
+		forged.Links[i].Hash, err = ChainHash(prev, forged.Links[i].ChainRecord)
+		require.NoError(t, err)
+		prev = forged.Links[i].Hash
+	}
+	forged.Head = prev
+	v, err = s.VerifyChainExport(forged, nil)
+	require.NoError(t, err)
+	assert.False(t, v.Valid)
+	assert.Equal(t, int64(2), v.BrokenAt)
+	assert.Contains(t, v.Reason, "stored chain")
+
+	_, err = s.VerifyChainExport(ChainExport{ChatJID: chat, Algorithm: "md5"}, nil)
+	assert.Error(t, err)
+
+	// Storing a message again may change what the chain leaves out
+	require.NoError(t, s.StoreMessage("m1", chat, "1234@s.whatsapp.net", "text m1", base.Add(time.Hour), false,
+		"", "", "", "", "", nil, []byte{0xab}, nil, 0))
+	v, err = s.VerifyChain(chat)
+	require.NoError(t, err)
+	assert.True(t, v.Valid, v.Reason)
+	v, err = s.VerifyChainExport(e, nil)
+	require.NoError(t, err)
+	assert.True(t, v.Valid, v.Reason)
+
+	// Messages altered or deleted in the store
+	_, err = s.db.Exec(`UPDATE messages SET content = 'rewritten' WHERE id = 'm3'`)
+	require.NoError(t, err)
+	v, err = s.VerifyChain(chat)
+	require.NoError(t, err)
+	assert.False(t, v.Valid)
+	assert.Equal(t, int64(3), v.BrokenAt)
+	assert.Contains(t, v.Reason, "m3")
+
+	_, err = s.db.Exec(`DELETE FROM messages WHERE id = 'm2'`)
+	require.NoError(t, err)
+	v, err = s.VerifyChain(chat)
+	require.NoError(t, err)
+	assert.False(t, v.Valid)
+	assert.Equal(t, int64(2), v.BrokenAt)
+	assert.Equal(t, "message m2 was deleted", v.Reason)
+}
+
+func TestSignedChainExport(t *testing.T) {
+	s := setupTestDB(t)
+	chat := "1234@s.whatsapp.net"
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, s.StoreChat(chat, "John", at))
+	require.NoError(t, s.StoreMessage("m1", chat, "1234", "hello", at, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, s.ChainMessage("m1", chat, at))
+
+	_, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	pub := key.Public().(ed25519.PublicKey)
+	e, err := s.ExportChain(chat, at)
+	require.NoError(t, err)
+
+	v, err := s.VerifyChainExport(e, pub)
+	require.NoError(t, err)
+	assert.False(t, v.Valid)
+	assert.Equal(t, "export is not signed", v.Reason)
+
+	SignChain(&e, key)
+	v, err = s.VerifyChainExport(e, pub)
+	require.NoError(t, err)
+	assert.True(t, v.Valid, v.Reason)
+	assert.True(t, v.Signed)
+
+	// Re-signed by another key, with that key's public half
+	_, other, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	resigned := e
+	SignChain(&resigned, other)
+	v, err = s.VerifyChainExport(resigned, pub)
+	require.NoError(t, err)
+	assert.False(t, v.Valid)
+	assert.Equal(t, "signature does not match the signing key", v.Reason)
+
+	// The signature covers when the export was made
+	backdated := e
+	backdated.ExportedAt = at.Add(-time.Hour)
+	v, err = s.VerifyChainExport(backdated, pub)
+	require.NoError(t, err)
+	assert.False(t, v.Valid)
+
+	key, err = ParseChainSigningKey(" " + "BwcHBwcHBwcHBwcHBwcHBwcHBwcHBwcHBwcHBwcHBwc=" + "\n")
+	require.NoError(t, err)
+	assert.Len(t, key, ed25519.PrivateKeySize)
+	key, err = ParseChainSigningKey("")
+	require.NoError(t, err)
+	assert.Nil(t, key)
+	_, err = ParseChainSigningKey("c2hvcnQ=")
+	assert.Error(t, err)
+}
@@ -0,0 +1,47 @@
+package store
+
+import "time"
+
+// ChatMediaParams selects the downloaded media of a chat.
+type ChatMediaParams struct {
+	ChatJID string
+	After   *time.Time
+	Before  *time.Time
+	// MediaType restricts the media to one type, e.g. "image".
+	MediaType string
+	// HideSpamFrom excludes media of messages with a spam score at or above
+	// this value, as in ListMessagesParams.
+	HideSpamFrom int
+}
+
+// DownloadedChatMedia lists the messages of a chat whose media was
+// downloaded, oldest first.
+func (s *MessageStore) DownloadedChatMedia(p ChatMediaParams) ([]MessageDownloadInfo, error) {
+	query := downloadInfoQuery + `
+		WHERE m.chat_jid = ? AND COALESCE(m.local_path, '') != ''`
+	args := []interface{}{s.resolve(p.ChatJID)}
+	if p.After != nil {
+		query += " AND m.timestamp > ?"
+		args = append(args, *p.After)
+	}
+	if p.Before != nil {
+		query += " AND m.timestamp < ?"
+		args = append(args, *p.Before)
+	}
+	if p.MediaType != "" {
+		query += " AND m.media_type = ?"
+		args = append(args, p.MediaType)
+	}
+	if p.HideSpamFrom > 0 {
+		query += " AND (COALESCE(m.spam_score, 0) < ? OR m.spam_released = 1)"
+		args = append(args, p.HideSpamFrom)
+	}
+	query += " ORDER BY m.timestamp, m.id"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanDownloadInfo(rows)
+}
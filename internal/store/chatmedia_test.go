@@ -0,0 +1,49 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadedChatMedia(t *testing.T) {
+	s := setupTestDB(t)
+	chat := "1234@s.whatsapp.net"
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, s.StoreChat(chat, "Album", base))
+	for i, m := range []struct{ id, mediaType string }{
+		{"a", "image"}, {"b", "video"}, {"c", "image"}, {"d", "image"}, {"e", ""},
+	} {
+		at := base.Add(time.Duration(i) * time.Hour)
+		require.NoError(t, s.StoreMessage(m.id, chat, "1234", "", at, false, m.mediaType, m.id+".jpg", "", "/p", "image/jpeg", []byte{1}, nil, nil, 10))
+		// d is not downloaded
+		if m.id != "d" {
+			require.NoError(t, s.MarkMediaDownloaded(m.id, chat, "/media/"+m.id, at))
+		}
+	}
+	require.NoError(t, s.StoreChat("999@s.whatsapp.net", "Other", base))
+	require.NoError(t, s.StoreMessage("x", "999@s.whatsapp.net", "999", "", base, false, "image", "x.jpg", "", "/p", "image/jpeg", []byte{1}, nil, nil, 10))
+	require.NoError(t, s.MarkMediaDownloaded("x", "999@s.whatsapp.net", "/media/x", base))
+
+	ids := func(infos []MessageDownloadInfo) []string {
+		out := []string{}
+		for _, info := range infos {
+			out = append(out, info.ID)
+		}
+		return out
+	}
+
+	all, err := s.DownloadedChatMedia(ChatMediaParams{ChatJID: chat})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "e"}, ids(all))
+	require.NotNil(t, all[0].ChatName)
+	assert.Equal(t, "Album", *all[0].ChatName)
+	assert.Equal(t, "/media/a", *all[0].LocalPath)
+
+	after, before := base, base.Add(3*time.Hour)
+	images, err := s.DownloadedChatMedia(ChatMediaParams{ChatJID: chat, After: &after, Before: &before, MediaType: "image"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, ids(images))
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Chat modes. In bot mode automation (greetings, away messages, bot commands)
+// may reply; in human mode an operator has taken over and automation stays
+// silent.
+const (
+	ChatModeBot   = "bot"
+	ChatModeHuman = "human"
+)
+
+// ChatMode is the handoff mode of a chat.
+type ChatMode struct {
+	ChatJID   string    `json:"chat_jid"`
+	Mode      string    `json:"mode"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// GetChatMode returns the mode of a chat; chats never switched are in bot mode.
+func (s *MessageStore) GetChatMode(chatJID string) (ChatMode, error) {
+	m := ChatMode{ChatJID: s.resolve(chatJID)}
+	err := s.db.QueryRow(`SELECT mode, updated_at FROM chat_modes WHERE chat_jid = ?`, m.ChatJID).
+		Scan(&m.Mode, &m.UpdatedAt)
+	if err == sql.ErrNoRows {
+		m.Mode = ChatModeBot
+		return m, nil
+	}
+	return m, err
+}
+
+// SetChatMode records the mode of a chat.
+func (s *MessageStore) SetChatMode(chatJID, mode string, updatedAt time.Time) error {
+	_, err := s.db.Exec(
+		`INSERT INTO chat_modes (chat_jid, mode, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(chat_jid) DO UPDATE SET mode = excluded.mode, updated_at = excluded.updated_at`,
+		s.resolve(chatJID), mode, updatedAt,
+	)
+	return err
+}
+
+// ListChatModes returns the chats currently in the given mode, most recently
+// switched first, among those the JID filters let through.
+func (s *MessageStore) ListChatModes(mode string, includeJIDs, excludeJIDs []string) ([]ChatMode, error) {
+	query, args := appendJIDFilter(`SELECT chat_jid, mode, updated_at FROM chat_modes WHERE mode = ?`, []interface{}{mode}, "chat_jid", includeJIDs, excludeJIDs)
+	rows, err := s.db.Query(query+" ORDER BY updated_at DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	modes := []ChatMode{}
+	for rows.Next() {
+		var m ChatMode
+		if err := rows.Scan(&m.ChatJID, &m.Mode, &m.UpdatedAt); err != nil {
+			return nil, err
+		}
+		modes = append(modes, m)
+	}
+	return modes, rows.Err()
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatModes(t *testing.T) {
+	store := setupTestDB(t)
+	jid := "4915112345678@s.whatsapp.net"
+	base := time.Now().UTC().Truncate(time.Second)
+
+	mode, err := store.GetChatMode(jid)
+	require.NoError(t, err)
+	assert.Equal(t, ChatModeBot, mode.Mode, "chats default to bot mode")
+
+	require.NoError(t, store.SetChatMode(jid, ChatModeHuman, base))
+	require.NoError(t, store.SetChatMode("123@g.us", ChatModeHuman, base.Add(time.Minute)))
+	require.NoError(t, store.SetChatMode("456@g.us", ChatModeBot, base))
+
+	mode, err = store.GetChatMode(jid)
+	require.NoError(t, err)
+	assert.Equal(t, ChatModeHuman, mode.Mode)
+	assert.True(t, mode.UpdatedAt.Equal(base))
+
+	human, err := store.ListChatModes(ChatModeHuman, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, human, 2)
+	assert.Equal(t, "123@g.us", human[0].ChatJID)
+	assert.Equal(t, jid, human[1].ChatJID)
+
+	human, err = store.ListChatModes(ChatModeHuman, nil, []string{"@g.us"})
+	require.NoError(t, err)
+	require.Len(t, human, 1)
+	assert.Equal(t, jid, human[0].ChatJID)
+}
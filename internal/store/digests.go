@@ -0,0 +1,83 @@
+package store
+
+import "time"
+
+// Digest periods.
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// DigestRecord is a stored chat digest. Data is the digest itself as JSON;
+// its layout belongs to the code generating it.
+type DigestRecord struct {
+	ID        int64
+	ChatJID   string
+	Period    string
+	Start     time.Time
+	End       time.Time
+	CreatedAt time.Time
+	Data      []byte
+}
+
+// HasDigest reports whether the digest of a chat for the period ending at end
+// was already generated.
+func (s *MessageStore) HasDigest(chatJID, period string, end time.Time) (bool, error) {
+	var n int
+	err := s.db.QueryRow(
+		`SELECT COUNT(*) FROM digests WHERE chat_jid = ? AND period = ? AND period_end = ?`,
+		s.resolve(chatJID), period, end.UTC(),
+	).Scan(&n)
+	return n > 0, err
+}
+
+// StoreDigest saves a digest and returns its ID. A digest already stored for
+// the same chat and period is kept and its ID returned with stored false.
+func (s *MessageStore) StoreDigest(d DigestRecord) (id int64, stored bool, err error) {
+	res, err := s.db.Exec(
+		`INSERT OR IGNORE INTO digests (chat_jid, period, period_start, period_end, created_at, data) VALUES (?, ?, ?, ?, ?, ?)`,
+		s.resolve(d.ChatJID), d.Period, d.Start.UTC(), d.End.UTC(), d.CreatedAt.UTC(), string(d.Data),
+	)
+	if err != nil {
+		return 0, false, err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		id, err = res.LastInsertId()
+		return id, true, err
+	}
+	err = s.db.QueryRow(
+		`SELECT id FROM digests WHERE chat_jid = ? AND period = ? AND period_end = ?`,
+		s.resolve(d.ChatJID), d.Period, d.End.UTC(),
+	).Scan(&id)
+	return id, false, err
+}
+
+// ListDigests returns the latest digests of a chat, newest first, optionally
+// only those of one period.
+func (s *MessageStore) ListDigests(chatJID, period string, limit int) ([]DigestRecord, error) {
+	q := `SELECT id, chat_jid, period, period_start, period_end, created_at, data FROM digests WHERE chat_jid = ?`
+	args := []interface{}{s.resolve(chatJID)}
+	if period != "" {
+		q += " AND period = ?"
+		args = append(args, period)
+	}
+	q += " ORDER BY period_end DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var digests []DigestRecord
+	for rows.Next() {
+		var d DigestRecord
+		var data string
+		if err := rows.Scan(&d.ID, &d.ChatJID, &d.Period, &d.Start, &d.End, &d.CreatedAt, &data); err != nil {
+			return nil, err
+		}
+		d.Data = []byte(data)
+		digests = append(digests, d)
+	}
+	return digests, rows.Err()
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigests(t *testing.T) {
+	store := setupTestDB(t)
+	chat := "120363000000000001@g.us"
+	end := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+
+	done, err := store.HasDigest(chat, DigestDaily, end)
+	require.NoError(t, err)
+	assert.False(t, done)
+
+	id, stored, err := store.StoreDigest(DigestRecord{ChatJID: chat, Period: DigestDaily, Start: end.AddDate(0, 0, -1), End: end, CreatedAt: end, Data: []byte(`{"messages":3}`)})
+	require.NoError(t, err)
+	assert.True(t, stored)
+	again, stored, err := store.StoreDigest(DigestRecord{ChatJID: chat, Period: DigestDaily, Start: end.AddDate(0, 0, -1), End: end, CreatedAt: end, Data: []byte(`{"messages":4}`)})
+	require.NoError(t, err)
+	assert.False(t, stored, "a period is summarized once")
+	assert.Equal(t, id, again)
+
+	_, _, err = store.StoreDigest(DigestRecord{ChatJID: chat, Period: DigestWeekly, Start: end.AddDate(0, 0, -7), End: end, CreatedAt: end, Data: []byte(`{}`)})
+	require.NoError(t, err)
+	_, _, err = store.StoreDigest(DigestRecord{ChatJID: chat, Period: DigestDaily, Start: end, End: end.AddDate(0, 0, 1), CreatedAt: end, Data: []byte(`{"messages":7}`)})
+	require.NoError(t, err)
+
+	done, err = store.HasDigest(chat, DigestDaily, end)
+	require.NoError(t, err)
+	assert.True(t, done)
+
+	digests, err := store.ListDigests(chat, DigestDaily, 10)
+	require.NoError(t, err)
+	require.Len(t, digests, 2)
+	assert.Equal(t, `{"messages":7}`, string(digests[0].Data))
+	assert.Equal(t, `{"messages":3}`, string(digests[1].Data))
+	assert.True(t, digests[1].End.Equal(end))
+
+	digests, err = store.ListDigests(chat, "", 10)
+	require.NoError(t, err)
+	assert.Len(t, digests, 3)
+	digests, err = store.ListDigests("111@s.whatsapp.net", "", 10)
+	require.NoError(t, err)
+	assert.Empty(t, digests)
+}
@@ -0,0 +1,40 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Draft is a partially composed message kept for a chat.
+type Draft struct {
+	ChatJID   string    `json:"chat_jid"`
+	Text      string    `json:"text"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// GetDraft returns the draft of a chat; chats without one get an empty text.
+func (s *MessageStore) GetDraft(chatJID string) (Draft, error) {
+	d := Draft{ChatJID: s.resolve(chatJID)}
+	err := s.db.QueryRow(`SELECT text, updated_at FROM drafts WHERE chat_jid = ?`, d.ChatJID).
+		Scan(&d.Text, &d.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return d, nil
+	}
+	return d, err
+}
+
+// SetDraft replaces the draft of a chat.
+func (s *MessageStore) SetDraft(chatJID, text string, updatedAt time.Time) error {
+	_, err := s.db.Exec(
+		`INSERT INTO drafts (chat_jid, text, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(chat_jid) DO UPDATE SET text = excluded.text, updated_at = excluded.updated_at`,
+		s.resolve(chatJID), text, updatedAt,
+	)
+	return err
+}
+
+// DeleteDraft discards the draft of a chat.
+func (s *MessageStore) DeleteDraft(chatJID string) error {
+	_, err := s.db.Exec(`DELETE FROM drafts WHERE chat_jid = ?`, s.resolve(chatJID))
+	return err
+}
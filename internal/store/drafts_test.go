@@ -0,0 +1,32 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrafts(t *testing.T) {
+	store := setupTestDB(t)
+	jid := "4915112345678@s.whatsapp.net"
+	at := time.Now().UTC().Truncate(time.Second)
+
+	d, err := store.GetDraft(jid)
+	require.NoError(t, err)
+	assert.Empty(t, d.Text)
+	assert.Equal(t, jid, d.ChatJID)
+
+	require.NoError(t, store.SetDraft(jid, "Hi, about the invoice", at))
+	require.NoError(t, store.SetDraft(jid, "Hi, about the invoice —", at.Add(time.Minute)))
+	d, err = store.GetDraft(jid)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi, about the invoice —", d.Text)
+	assert.True(t, d.UpdatedAt.Equal(at.Add(time.Minute)))
+
+	require.NoError(t, store.DeleteDraft(jid))
+	d, err = store.GetDraft(jid)
+	require.NoError(t, err)
+	assert.Empty(t, d.Text)
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ContentHash returns the key used to match identical messages across chats.
+// Media is matched on the SHA-256 of the decrypted file, which survives
+// forwarding; text is matched on its whitespace-normalised content. It returns
+// "" for messages with neither.
+func ContentHash(content string, fileSHA256 []byte) string {
+	if len(fileSHA256) > 0 {
+		return "media:" + hex.EncodeToString(fileSHA256)
+	}
+	normalized := strings.Join(strings.Fields(content), " ")
+	if normalized == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return "text:" + hex.EncodeToString(sum[:])
+}
+
+// Duplicates lists the other messages sharing a message's content hash.
+type Duplicates struct {
+	Message     Message   `json:"message"`
+	ContentHash string    `json:"content_hash"`
+	Chats       int       `json:"chats"`
+	Duplicates  []Message `json:"duplicates"`
+}
+
+// FindDuplicates returns every other copy of the message, oldest first, so the
+// first entry is the earliest known sighting. chatJID disambiguates message IDs
+// that exist in several chats. Chats filtered out by includeJIDs/excludeJIDs
+// are skipped, both for the message itself and for its duplicates.
+func (s *MessageStore) FindDuplicates(id string, chatJID *string, includeJIDs, excludeJIDs []string, limit int) (Duplicates, error) {
+	query := `SELECT m.id, m.chat_jid, COALESCE(c.name, ''), m.sender, m.content, m.timestamp, m.is_from_me, m.media_type,
+		COALESCE(m.lang, ''), COALESCE(m.content_hash, '')
+		FROM messages m LEFT JOIN chats c ON m.chat_jid = c.jid WHERE m.id = ?`
+	args := []interface{}{id}
+	if chatJID != nil {
+		query += " AND m.chat_jid = ?"
+		args = append(args, s.resolve(*chatJID))
+	}
+	query, args = appendJIDFilter(query, args, "m.chat_jid", includeJIDs, excludeJIDs)
+	query += " LIMIT 2"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return Duplicates{}, err
+	}
+	var found []Message
+	var hash string
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(&m.ID, &m.ChatJID, &m.ChatName, &m.Sender, &m.Content, &m.Timestamp, &m.IsFromMe, &m.MediaType, &m.Lang, &hash); err != nil {
+			rows.Close()
+			return Duplicates{}, err
+		}
+		found = append(found, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return Duplicates{}, err
+	}
+	if len(found) == 0 {
+		return Duplicates{}, sql.ErrNoRows
+	}
+	if len(found) > 1 {
+		return Duplicates{}, fmt.Errorf("multiple messages found with ID %s; specify chat JID", id)
+	}
+	if hash == "" {
+		return Duplicates{}, fmt.Errorf("message %s has no content to compare", id)
+	}
+
+	result := Duplicates{Message: found[0], ContentHash: hash, Duplicates: []Message{}}
+
+	query = `SELECT m.id, m.chat_jid, COALESCE(c.name, ''), m.sender, m.content, m.timestamp, m.is_from_me, m.media_type, COALESCE(m.lang, '')
+		FROM messages m LEFT JOIN chats c ON m.chat_jid = c.jid
+		WHERE m.content_hash = ? AND NOT (m.id = ? AND m.chat_jid = ?)`
+	args = []interface{}{hash, found[0].ID, found[0].ChatJID}
+	query, args = appendJIDFilter(query, args, "m.chat_jid", includeJIDs, excludeJIDs)
+	query += " ORDER BY m.timestamp ASC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err = s.db.Query(query, args...)
+	if err != nil {
+		return Duplicates{}, err
+	}
+	defer rows.Close()
+
+	chats := map[string]bool{found[0].ChatJID: true}
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(&m.ID, &m.ChatJID, &m.ChatName, &m.Sender, &m.Content, &m.Timestamp, &m.IsFromMe, &m.MediaType, &m.Lang); err != nil {
+			return Duplicates{}, err
+		}
+		chats[m.ChatJID] = true
+		result.Duplicates = append(result.Duplicates, m)
+	}
+	result.Chats = len(chats)
+	return result, rows.Err()
+}
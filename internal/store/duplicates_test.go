@@ -0,0 +1,54 @@
+package store
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentHash(t *testing.T) {
+	assert.Equal(t, ContentHash("Forward this  to\n10 friends", nil), ContentHash(" Forward this to 10 friends ", nil))
+	assert.NotEqual(t, ContentHash("hello", nil), ContentHash("Hello", nil))
+	assert.Equal(t, "media:0102", ContentHash("caption", []byte{1, 2}))
+	assert.Empty(t, ContentHash("  ", nil))
+}
+
+func TestFindDuplicatesAcrossChats(t *testing.T) {
+	store := setupTestDB(t)
+	base := time.Now().UTC().Truncate(time.Second)
+	spam := "Forward this to 10 groups to win a prize"
+
+	for i, chat := range []string{"a@g.us", "b@g.us", "c@g.us"} {
+		require.NoError(t, store.StoreChat(chat, chat, base))
+		id := []string{"m1", "m2", "m3"}[i]
+		require.NoError(t, store.StoreMessage(id, chat, "111", spam, base.Add(time.Duration(i)*time.Minute), false, "", "", "", "", "", nil, nil, nil, 0))
+		require.NoError(t, store.EnrichMessage(id, chat, Enrichment{ContentHash: ContentHash(spam, nil)}))
+	}
+	require.NoError(t, store.StoreMessage("m4", "a@g.us", "111", "unrelated", base, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, store.EnrichMessage("m4", "a@g.us", Enrichment{ContentHash: ContentHash("unrelated", nil)}))
+
+	dups, err := store.FindDuplicates("m3", nil, nil, nil, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "m3", dups.Message.ID)
+	assert.Equal(t, 3, dups.Chats)
+	require.Len(t, dups.Duplicates, 2)
+	assert.Equal(t, "m1", dups.Duplicates[0].ID)
+	assert.Equal(t, "m2", dups.Duplicates[1].ID)
+
+	dups, err = store.FindDuplicates("m3", nil, nil, []string{"b@g.us"}, 10)
+	require.NoError(t, err)
+	require.Len(t, dups.Duplicates, 1)
+	assert.Equal(t, "a@g.us", dups.Duplicates[0].ChatJID)
+
+	// A message in a filtered-out chat is not found at all
+	_, err = store.FindDuplicates("m2", nil, nil, []string{"b@g.us"}, 10)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = store.FindDuplicates("m2", nil, []string{"a@g.us"}, nil, 10)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = store.FindDuplicates("missing", nil, nil, nil, 10)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
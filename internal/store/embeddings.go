@@ -0,0 +1,246 @@
+package store
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Embedding is the vector of a message's content.
+type Embedding struct {
+	MessageID string
+	ChatJID   string
+	Vector    []float32
+}
+
+// ScoredMessage is a semantic search hit; Score is the cosine similarity to
+// the query, from -1 to 1.
+type ScoredMessage struct {
+	Message
+	Score float64 `json:"score"`
+}
+
+// SemanticSearchParams selects the messages ranked by SemanticSearch. Only
+// messages embedded with Model are considered.
+type SemanticSearchParams struct {
+	Vector       []float32
+	Model        string
+	ChatJID      *string
+	After        *time.Time
+	Limit        int
+	IncludeJIDs  []string
+	ExcludeJIDs  []string
+	HideSpamFrom int
+}
+
+// UnembeddedMessages returns up to limit messages with text that have no
+// vector from model yet, newest first.
+func (s *MessageStore) UnembeddedMessages(model string, limit int) ([]Message, error) {
+	rows, err := s.db.Query(
+		`SELECT m.id, m.chat_jid, m.content FROM messages m
+		LEFT JOIN message_embeddings e ON e.message_id = m.id AND e.chat_jid = m.chat_jid AND e.model = ?
+		WHERE e.message_id IS NULL AND COALESCE(m.content, '') != ''
+		ORDER BY m.timestamp DESC LIMIT ?`,
+		model, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var messages []Message
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(&m.ID, &m.ChatJID, &m.Content); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// StoreEmbeddings saves the vectors of messages, replacing those of any
+// other model.
+func (s *MessageStore) StoreEmbeddings(model string, embeddings []Embedding) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(
+		`INSERT INTO message_embeddings (message_id, chat_jid, model, vector, created_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(message_id, chat_jid) DO UPDATE SET
+			model = excluded.model, vector = excluded.vector, created_at = excluded.created_at`,
+	)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	now := time.Now().UTC()
+	for _, e := range embeddings {
+		if _, err := stmt.Exec(e.MessageID, e.ChatJID, model, encodeVector(e.Vector), now); err != nil {
+			return fmt.Errorf("failed to store embedding of %s: %w", e.MessageID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// EmbeddingCounts returns how many messages with text have a vector from
+// model and how many are still waiting for one.
+func (s *MessageStore) EmbeddingCounts(model string) (indexed, pending int64, err error) {
+	err = s.db.QueryRow(
+		`SELECT COUNT(e.message_id), COUNT(*) - COUNT(e.message_id) FROM messages m
+		LEFT JOIN message_embeddings e ON e.message_id = m.id AND e.chat_jid = m.chat_jid AND e.model = ?
+		WHERE COALESCE(m.content, '') != ''`,
+		model,
+	).Scan(&indexed, &pending)
+	return indexed, pending, err
+}
+
+// SemanticSearch returns the messages whose vectors are closest to
+// params.Vector, best first. Vectors are compared exhaustively, which keeps
+// the index a plain table and is fast enough for personal archives of a few
+// hundred thousand messages.
+func (s *MessageStore) SemanticSearch(params SemanticSearchParams) ([]ScoredMessage, error) {
+	query := normalize(params.Vector)
+	if query == nil || params.Limit <= 0 {
+		return nil, nil
+	}
+	q := `SELECT e.message_id, e.chat_jid, e.vector FROM message_embeddings e
+	      JOIN messages m ON m.id = e.message_id AND m.chat_jid = e.chat_jid
+	      WHERE e.model = ?`
+	args := []interface{}{params.Model}
+	if params.ChatJID != nil {
+		q += " AND m.chat_jid = ?"
+		args = append(args, s.resolve(*params.ChatJID))
+	}
+	if params.After != nil {
+		q += " AND m.timestamp > ?"
+		args = append(args, params.After)
+	}
+	if params.HideSpamFrom > 0 {
+		q += " AND (COALESCE(m.spam_score, 0) < ? OR m.spam_released = 1)"
+		args = append(args, params.HideSpamFrom)
+	}
+	q, args = appendJIDFilter(q, args, "m.chat_jid", params.IncludeJIDs, params.ExcludeJIDs)
+
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	type hit struct {
+		id, chat string
+		score    float64
+	}
+	var hits []hit // best first, at most Limit
+	for rows.Next() {
+		var h hit
+		var blob []byte
+		if err := rows.Scan(&h.id, &h.chat, &blob); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		v := decodeVector(blob)
+		if len(v) != len(query) {
+			continue
+		}
+		h.score = dot(query, v)
+		if len(hits) == params.Limit && h.score <= hits[len(hits)-1].score {
+			continue
+		}
+		i := sort.Search(len(hits), func(i int) bool { return hits[i].score < h.score })
+		if len(hits) < params.Limit {
+			hits = append(hits, hit{})
+		}
+		copy(hits[i+1:], hits[i:])
+		hits[i] = h
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return []ScoredMessage{}, nil
+	}
+
+	keys := make([]string, len(hits))
+	args = args[:0]
+	for i, h := range hits {
+		keys[i] = "(?, ?)"
+		args = append(args, h.id, h.chat)
+	}
+	rows, err = s.db.Query(
+		`SELECT m.id, m.chat_jid, c.name, m.sender, m.content, m.timestamp, m.is_from_me, m.media_type, COALESCE(m.lang, ''),
+		COALESCE(m.spam_score, 0), COALESCE(m.spam_flags, ''), m.sender_device, m.identity_changed_at, ci.changed_at, ci.verified_at, m.deleted_at,
+		COALESCE(m.media_scan, ''), COALESCE(m.media_threat, ''), m.delivered_at, m.read_at, m.played_at
+		FROM messages m JOIN chats c ON m.chat_jid = c.jid
+		LEFT JOIN contact_identities ci ON ci.sender = m.sender
+		WHERE (m.id, m.chat_jid) IN (VALUES `+strings.Join(keys, ", ")+`)`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[[2]string]Message, len(messages))
+	for _, m := range messages {
+		byKey[[2]string{m.ID, m.ChatJID}] = m
+	}
+	results := make([]ScoredMessage, 0, len(hits))
+	for _, h := range hits {
+		if m, ok := byKey[[2]string{h.id, h.chat}]; ok {
+			results = append(results, ScoredMessage{Message: m, Score: h.score})
+		}
+	}
+	return results, nil
+}
+
+// encodeVector stores v normalized to unit length as little-endian float32s,
+// so comparing two vectors is a dot product.
+func encodeVector(v []float32) []byte {
+	v = normalize(v)
+	b := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(f))
+	}
+	return b
+}
+
+func decodeVector(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v
+}
+
+// normalize returns v scaled to unit length, or nil for a zero vector.
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, f := range v {
+		sum += float64(f) * float64(f)
+	}
+	if sum == 0 {
+		return nil
+	}
+	norm := math.Sqrt(sum)
+	out := make([]float32, len(v))
+	for i, f := range v {
+		out[i] = float32(float64(f) / norm)
+	}
+	return out
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
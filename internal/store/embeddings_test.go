@@ -0,0 +1,79 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemanticSearch(t *testing.T) {
+	store := setupTestDB(t)
+	now := time.Now().UTC()
+	alice, group := "111@s.whatsapp.net", "120363000000000001@g.us"
+	require.NoError(t, store.StoreChat(alice, "Alice", now))
+	require.NoError(t, store.StoreChat(group, "Climbing", now))
+	require.NoError(t, store.StoreMessage("m1", alice, alice, "see you at the crag", now, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, store.StoreMessage("m2", alice, alice, "invoice attached", now.Add(-time.Hour), false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, store.StoreMessage("m3", group, alice, "bouldering on sunday?", now.Add(-2*time.Hour), false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, store.StoreMessage("m4", group, alice, "", now, false, "image", "", "", "", "", nil, nil, nil, 0))
+
+	pending, err := store.UnembeddedMessages("m", 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 3, "messages without text are not embedded")
+	assert.Equal(t, "m1", pending[0].ID)
+
+	require.NoError(t, store.StoreEmbeddings("m", []Embedding{
+		{MessageID: "m1", ChatJID: alice, Vector: []float32{3, 1, 0}},
+		{MessageID: "m2", ChatJID: alice, Vector: []float32{0, 0, 2}},
+		{MessageID: "m3", ChatJID: group, Vector: []float32{1, 1, 0}},
+	}))
+	indexed, left, err := store.EmbeddingCounts("m")
+	require.NoError(t, err)
+	assert.Equal(t, [2]int64{3, 0}, [2]int64{indexed, left})
+	pending, err = store.UnembeddedMessages("m", 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+	pending, err = store.UnembeddedMessages("other", 10)
+	require.NoError(t, err)
+	assert.Len(t, pending, 3, "vectors of another model do not count")
+
+	hits, err := store.SemanticSearch(SemanticSearchParams{Vector: []float32{1, 0, 0}, Model: "m", Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+	assert.Equal(t, "m1", hits[0].ID)
+	assert.Equal(t, "Alice", hits[0].ChatName)
+	assert.Equal(t, "m3", hits[1].ID)
+	assert.InDelta(t, 0.9487, hits[0].Score, 0.001)
+	assert.InDelta(t, 0.7071, hits[1].Score, 0.001)
+
+	hits, err = store.SemanticSearch(SemanticSearchParams{Vector: []float32{1, 0, 0}, Model: "m", Limit: 10, ChatJID: &group})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, "m3", hits[0].ID)
+
+	hits, err = store.SemanticSearch(SemanticSearchParams{Vector: []float32{1, 0, 0}, Model: "m", Limit: 10, ExcludeJIDs: []string{"@g.us"}})
+	require.NoError(t, err)
+	assert.Len(t, hits, 2)
+
+	hits, err = store.SemanticSearch(SemanticSearchParams{Vector: []float32{1, 0, 0}, Model: "other", Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+}
+
+func TestMergeChatsMovesEmbeddings(t *testing.T) {
+	store := setupTestDB(t)
+	now := time.Now().UTC()
+	require.NoError(t, store.StoreChat("111@s.whatsapp.net", "Alice", now))
+	require.NoError(t, store.StoreMessage("m1", "111@s.whatsapp.net", "111", "hello", now, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, store.StoreEmbeddings("m", []Embedding{{MessageID: "m1", ChatJID: "111@s.whatsapp.net", Vector: []float32{1, 0}}}))
+
+	_, err := store.MergeChats("111@s.whatsapp.net", "222@s.whatsapp.net", "", now)
+	require.NoError(t, err)
+
+	hits, err := store.SemanticSearch(SemanticSearchParams{Vector: []float32{1, 0}, Model: "m", Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, "222@s.whatsapp.net", hits[0].ChatJID)
+}
@@ -0,0 +1,63 @@
+package store
+
+import "time"
+
+// Chat event types recorded in the chat_events table.
+const (
+	ChatEventJoinRequest         = "join_request"
+	ChatEventJoinRequestRevoked  = "join_request_revoked"
+	ChatEventJoinRequestApproved = "join_request_approved"
+	ChatEventJoinRequestRejected = "join_request_rejected"
+	ChatEventParticipantAdded    = "participant_added"
+	ChatEventParticipantRemoved  = "participant_removed"
+	ChatEventParticipantPromoted = "participant_promoted"
+	ChatEventParticipantDemoted  = "participant_demoted"
+)
+
+// ChatEvent is a non-message occurrence in a chat, such as a membership change.
+// Actor is who caused the event and Target who it applies to; either may be empty.
+// RequestID links events caused through the API to the request that made them.
+type ChatEvent struct {
+	ID        int64     `json:"id"`
+	ChatJID   string    `json:"chat_jid"`
+	Type      string    `json:"type"`
+	Actor     string    `json:"actor,omitempty"`
+	Target    string    `json:"target,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+// StoreChatEvent appends an event to the chat_events table.
+func (s *MessageStore) StoreChatEvent(evt ChatEvent) error {
+	_, err := s.db.Exec(
+		`INSERT INTO chat_events (chat_jid, type, actor, target, timestamp, request_id) VALUES (?, ?, ?, ?, ?, ?)`,
+		s.resolve(evt.ChatJID), evt.Type, evt.Actor, evt.Target, evt.Timestamp, evt.RequestID,
+	)
+	return err
+}
+
+// ListChatEvents returns the most recent events of a chat, newest first.
+func (s *MessageStore) ListChatEvents(chatJID string, limit int) ([]ChatEvent, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.Query(
+		`SELECT id, chat_jid, type, COALESCE(actor, ''), COALESCE(target, ''), timestamp, COALESCE(request_id, '')
+		FROM chat_events WHERE chat_jid = ? ORDER BY timestamp DESC, id DESC LIMIT ?`,
+		s.resolve(chatJID), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []ChatEvent
+	for rows.Next() {
+		var evt ChatEvent
+		if err := rows.Scan(&evt.ID, &evt.ChatJID, &evt.Type, &evt.Actor, &evt.Target, &evt.Timestamp, &evt.RequestID); err != nil {
+			return nil, err
+		}
+		events = append(events, evt)
+	}
+	return events, rows.Err()
+}
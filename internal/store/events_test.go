@@ -0,0 +1,28 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatEventsNewestFirst(t *testing.T) {
+	store := setupTestDB(t)
+	jid := "120363000000000001@g.us"
+	base := time.Now().UTC().Truncate(time.Second)
+
+	require.NoError(t, store.StoreChatEvent(ChatEvent{ChatJID: jid, Type: ChatEventJoinRequest, Target: "alice@s.whatsapp.net", Timestamp: base}))
+	require.NoError(t, store.StoreChatEvent(ChatEvent{ChatJID: jid, Type: ChatEventJoinRequestApproved, Actor: "me@s.whatsapp.net", Target: "alice@s.whatsapp.net", Timestamp: base.Add(time.Minute)}))
+	require.NoError(t, store.StoreChatEvent(ChatEvent{ChatJID: "other@g.us", Type: ChatEventJoinRequest, Timestamp: base}))
+
+	events, err := store.ListChatEvents(jid, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, ChatEventJoinRequestApproved, events[0].Type)
+	assert.Equal(t, "me@s.whatsapp.net", events[0].Actor)
+	assert.Equal(t, ChatEventJoinRequest, events[1].Type)
+	assert.Empty(t, events[1].Actor)
+	assert.True(t, events[1].Timestamp.Equal(base))
+}
@@ -0,0 +1,42 @@
+package store
+
+import "time"
+
+// HasFeedItems reports whether any item of a feed was seen for a chat, i.e.
+// whether the feed was polled for it before.
+func (s *MessageStore) HasFeedItems(feedURL, chatJID string) (bool, error) {
+	var n int
+	err := s.db.QueryRow(
+		`SELECT COUNT(*) FROM feed_items WHERE feed_url = ? AND chat_jid = ?`,
+		feedURL, chatJID,
+	).Scan(&n)
+	return n > 0, err
+}
+
+// SeeFeedItem records that an item of a feed was seen for a chat. It
+// reports true the first time, so each item is only posted once.
+func (s *MessageStore) SeeFeedItem(feedURL, chatJID, itemID string, at time.Time) (bool, error) {
+	res, err := s.db.Exec(
+		`INSERT INTO feed_items (feed_url, chat_jid, item_id, seen_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT DO NOTHING`,
+		feedURL, chatJID, itemID, at.UTC(),
+	)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return n > 0, err
+	}
+	_, err = s.db.Exec(
+		`UPDATE feed_items SET seen_at = ? WHERE feed_url = ? AND chat_jid = ? AND item_id = ?`,
+		at.UTC(), feedURL, chatJID, itemID,
+	)
+	return false, err
+}
+
+// PruneFeedItems forgets the items not seen since cutoff, which have left
+// their feed.
+func (s *MessageStore) PruneFeedItems(cutoff time.Time) error {
+	_, err := s.db.Exec(`DELETE FROM feed_items WHERE seen_at < ?`, cutoff.UTC())
+	return err
+}
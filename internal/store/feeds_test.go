@@ -0,0 +1,42 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeedItems(t *testing.T) {
+	s := setupTestDB(t)
+	now := time.Now().UTC()
+	const feed, chat = "https://blog.example.com/feed", "120363000000000000@newsletter"
+
+	polled, err := s.HasFeedItems(feed, chat)
+	require.NoError(t, err)
+	assert.False(t, polled)
+
+	seen, err := s.SeeFeedItem(feed, chat, "post-1", now)
+	require.NoError(t, err)
+	assert.True(t, seen)
+	seen, err = s.SeeFeedItem(feed, chat, "post-1", now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, seen, "seen twice")
+	seen, err = s.SeeFeedItem(feed, "34600111222@s.whatsapp.net", "post-1", now)
+	require.NoError(t, err)
+	assert.True(t, seen, "same item for another chat")
+
+	polled, err = s.HasFeedItems(feed, chat)
+	require.NoError(t, err)
+	assert.True(t, polled)
+
+	// Seeing an item again keeps it from being pruned
+	require.NoError(t, s.PruneFeedItems(now.Add(time.Minute)))
+	seen, err = s.SeeFeedItem(feed, chat, "post-1", now)
+	require.NoError(t, err)
+	assert.False(t, seen)
+	seen, err = s.SeeFeedItem(feed, "34600111222@s.whatsapp.net", "post-1", now)
+	require.NoError(t, err)
+	assert.True(t, seen, "pruned item")
+}
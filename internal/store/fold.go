@@ -0,0 +1,38 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/vicentereig/whatsapp-cli/internal/textnorm"
+)
+
+// sqliteDriver is go-sqlite3 with the SQL functions the store's queries
+// use: fold(text) returns the text folded by textnorm.Fold, and an empty
+// string for NULL. Searches compare folded text with a folded query, so they ignore
+// case, accents and the other differences textnorm folds.
+const sqliteDriver = "sqlite3_store"
+
+func init() {
+	sql.Register(sqliteDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("fold", foldValue, true)
+		},
+	})
+}
+
+func foldValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return textnorm.Fold(v)
+	case []byte:
+		return textnorm.Fold(string(v))
+	}
+	return ""
+}
+
+// containsPattern is the LIKE pattern matching folded text that contains
+// the folded query.
+func containsPattern(query string) string {
+	return "%" + textnorm.Fold(query) + "%"
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"strings"
+	"time"
+)
+
+// Followup is a chat whose latest incoming messages were not replied to.
+type Followup struct {
+	ChatJID  string `json:"chat_jid"`
+	ChatName string `json:"chat_name,omitempty"`
+	// Message is the oldest unanswered message; Unanswered counts it and
+	// the ones received after it.
+	Message    Message   `json:"message"`
+	Unanswered int       `json:"unanswered"`
+	Since      time.Time `json:"since"`
+}
+
+// FollowupParams selects the chats followed up on.
+type FollowupParams struct {
+	// Chats restricts the search to these chats. Without chats every
+	// one-to-one chat is searched.
+	Chats []string
+	// Only chats waiting for a reply since before Before, and after After
+	// if set, are returned.
+	Before       time.Time
+	After        *time.Time
+	HideSpamFrom int
+	Limit        int
+	IncludeJIDs  []string
+	ExcludeJIDs  []string
+}
+
+// Followups lists the chats with incoming messages received before
+// params.Before and not followed by a message of the account, longest
+// waiting first. A reply anywhere in the chat answers every earlier message.
+func (s *MessageStore) Followups(params FollowupParams) ([]Followup, error) {
+	// With MIN() selected, SQLite takes the bare columns from the row
+	// holding the minimum, i.e. the oldest unanswered message
+	query := `SELECT m.chat_jid, COALESCE(c.name, ''), m.id, m.sender, m.content, COALESCE(m.media_type, ''), COALESCE(m.lang, ''),
+	          m.timestamp, MIN(m.timestamp), COUNT(*)
+	          FROM messages m JOIN chats c ON m.chat_jid = c.jid
+	          WHERE m.is_from_me = 0
+	          AND m.timestamp > COALESCE((SELECT MAX(r.timestamp) FROM messages r WHERE r.chat_jid = m.chat_jid AND r.is_from_me = 1), '')`
+	args := []interface{}{}
+	if len(params.Chats) > 0 {
+		placeholders := make([]string, len(params.Chats))
+		for i, chat := range params.Chats {
+			placeholders[i] = "?"
+			args = append(args, s.resolve(chat))
+		}
+		query += " AND m.chat_jid IN (" + strings.Join(placeholders, ", ") + ")"
+	} else {
+		query += " AND m.chat_jid LIKE '%@s.whatsapp.net'"
+	}
+	if params.HideSpamFrom > 0 {
+		query += " AND (COALESCE(m.spam_score, 0) < ? OR m.spam_released = 1)"
+		args = append(args, params.HideSpamFrom)
+	}
+	query, args = appendJIDFilter(query, args, "m.chat_jid", params.IncludeJIDs, params.ExcludeJIDs)
+	query += " GROUP BY m.chat_jid HAVING MIN(m.timestamp) < ?"
+	args = append(args, params.Before.UTC())
+	if params.After != nil {
+		query += " AND MIN(m.timestamp) > ?"
+		args = append(args, params.After.UTC())
+	}
+	query += " ORDER BY MIN(m.timestamp) LIMIT ?"
+	args = append(args, params.Limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	followups := []Followup{}
+	for rows.Next() {
+		var f Followup
+		var oldest interface{} // same as m.timestamp, without its type
+		if err := rows.Scan(&f.ChatJID, &f.ChatName, &f.Message.ID, &f.Message.Sender, &f.Message.Content,
+			&f.Message.MediaType, &f.Message.Lang, &f.Since, &oldest, &f.Unanswered); err != nil {
+			return nil, err
+		}
+		f.Message.ChatJID, f.Message.ChatName, f.Message.Timestamp = f.ChatJID, f.ChatName, f.Since
+		followups = append(followups, f)
+	}
+	return followups, rows.Err()
+}
+
+// FollowupNotified records that the follow-up of a chat, waiting since
+// messageID, was notified. It reports true the first time, so each is
+// notified once.
+func (s *MessageStore) FollowupNotified(chatJID, messageID string, at time.Time) (bool, error) {
+	res, err := s.db.Exec(
+		`INSERT INTO followup_notices (chat_jid, message_id, notified_at) VALUES (?, ?, ?)
+		ON CONFLICT DO NOTHING`,
+		s.resolve(chatJID), messageID, at.UTC(),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// PruneFollowupNotices forgets the notices recorded before cutoff.
+func (s *MessageStore) PruneFollowupNotices(cutoff time.Time) error {
+	_, err := s.db.Exec(`DELETE FROM followup_notices WHERE notified_at < ?`, cutoff.UTC())
+	return err
+}
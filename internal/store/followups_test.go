@@ -0,0 +1,70 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFollowups(t *testing.T) {
+	s := setupTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	alice, bob, carol, team := "1@s.whatsapp.net", "2@s.whatsapp.net", "3@s.whatsapp.net", "120363000000000001@g.us"
+	for _, chat := range []string{alice, bob, carol, team} {
+		require.NoError(t, s.StoreChat(chat, "Chat "+chat[:1], now))
+	}
+	store := func(id, chat string, at time.Time, fromMe bool) {
+		require.NoError(t, s.StoreMessage(id, chat, "x", "text "+id, at, fromMe, "", "", "", "", "", nil, nil, nil, 0))
+	}
+	// Alice wrote twice after our reply; Bob was answered; Carol wrote
+	// recently; the group is only tracked when listed
+	store("a1", alice, now.Add(-5*time.Hour), false)
+	store("a2", alice, now.Add(-4*time.Hour), true)
+	store("a3", alice, now.Add(-3*time.Hour), false)
+	store("a4", alice, now.Add(-2*time.Hour), false)
+	store("b1", bob, now.Add(-5*time.Hour), false)
+	store("b2", bob, now.Add(-4*time.Hour), true)
+	store("c1", carol, now.Add(-10*time.Minute), false)
+	store("t1", team, now.Add(-6*time.Hour), false)
+
+	cutoff := now.Add(-time.Hour)
+	followups, err := s.Followups(FollowupParams{Before: cutoff, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, followups, 1)
+	f := followups[0]
+	assert.Equal(t, alice, f.ChatJID)
+	assert.Equal(t, "a3", f.Message.ID, "the oldest unanswered message")
+	assert.Equal(t, "text a3", f.Message.Content)
+	assert.Equal(t, 2, f.Unanswered)
+	assert.True(t, f.Since.Equal(now.Add(-3*time.Hour)), f.Since)
+
+	followups, err = s.Followups(FollowupParams{Chats: []string{team, bob}, Before: cutoff, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, followups, 1)
+	assert.Equal(t, team, followups[0].ChatJID)
+
+	followups, err = s.Followups(FollowupParams{Before: now, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, followups, 2)
+	assert.Equal(t, alice, followups[0].ChatJID, "longest waiting first")
+	assert.Equal(t, carol, followups[1].ChatJID)
+
+	after := now.Add(-time.Hour)
+	followups, err = s.Followups(FollowupParams{Before: now, After: &after, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, followups, 1)
+	assert.Equal(t, carol, followups[0].ChatJID)
+
+	first, err := s.FollowupNotified(alice, "a3", now)
+	require.NoError(t, err)
+	assert.True(t, first)
+	first, err = s.FollowupNotified(alice, "a3", now)
+	require.NoError(t, err)
+	assert.False(t, first)
+	require.NoError(t, s.PruneFollowupNotices(now.Add(time.Second)))
+	first, err = s.FollowupNotified(alice, "a3", now)
+	require.NoError(t, err)
+	assert.True(t, first, "pruned notices are forgotten")
+}
@@ -0,0 +1,277 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+)
+
+// Graph buckets: the periods interaction counts are grouped by over time.
+const (
+	GraphBucketDay   = "day"
+	GraphBucketWeek  = "week"
+	GraphBucketMonth = "month"
+)
+
+// GraphMe is the node ID of the account itself.
+const GraphMe = "me"
+
+// DefaultGraphWindow is the longest gap after which a group message still
+// counts as a reply to the one before it.
+const DefaultGraphWindow = 10 * time.Minute
+
+// GraphParams selects the group messages an interaction graph is built from.
+type GraphParams struct {
+	// ChatJID limits the graph to one group.
+	ChatJID *string
+	After   *time.Time
+	// Bucket is GraphBucketDay, GraphBucketWeek or GraphBucketMonth.
+	Bucket string
+	// Window is the reply window; DefaultGraphWindow if zero.
+	Window time.Duration
+	// MinWeight drops edges with fewer interactions.
+	MinWeight    int
+	IncludeJIDs  []string
+	ExcludeJIDs  []string
+	HideSpamFrom int
+}
+
+// Graph is who talks to whom in groups. Group chats have no addressee, so a
+// message is taken as directed at the author of the previous message in the
+// group when it follows within the reply window; consecutive messages of one
+// sender are not interactions.
+type Graph struct {
+	Bucket string      `json:"bucket"`
+	Window string      `json:"window"`
+	Groups int         `json:"groups"`
+	Nodes  []GraphNode `json:"nodes"`
+	Edges  []GraphEdge `json:"edges"`
+	// Totals counts all interactions per period, including those of edges
+	// below MinWeight.
+	Totals []GraphBucket `json:"timeline"`
+}
+
+// GraphNode is a participant; ID is their normalized JID or GraphMe.
+type GraphNode struct {
+	ID       string `json:"id"`
+	Name     string `json:"name,omitempty"`
+	Messages int    `json:"messages"`
+	Groups   int    `json:"groups"`
+}
+
+// GraphEdge counts the messages From sent right after To in shared groups.
+type GraphEdge struct {
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Weight   int           `json:"weight"`
+	Groups   []string      `json:"groups"`
+	Timeline []GraphBucket `json:"timeline"`
+}
+
+// GraphBucket is the number of interactions in one period, e.g. "2026-03" for
+// months, "2026-W09" for ISO weeks or "2026-03-01" for days.
+type GraphBucket struct {
+	Period string `json:"period"`
+	Count  int    `json:"count"`
+}
+
+// InteractionGraph builds the interaction graph of group chats, strongest
+// edges first.
+func (s *MessageStore) InteractionGraph(params GraphParams) (*Graph, error) {
+	bucket, ok := graphBuckets[params.Bucket]
+	if !ok {
+		return nil, fmt.Errorf("invalid bucket %q: must be %q, %q or %q", params.Bucket, GraphBucketDay, GraphBucketWeek, GraphBucketMonth)
+	}
+	window := params.Window
+	if window <= 0 {
+		window = DefaultGraphWindow
+	}
+
+	query := `SELECT m.chat_jid, m.sender, m.is_from_me, m.timestamp FROM messages m WHERE m.chat_jid LIKE '%@g.us'`
+	args := []interface{}{}
+	if params.ChatJID != nil {
+		query += " AND m.chat_jid = ?"
+		args = append(args, s.resolve(*params.ChatJID))
+	}
+	if params.After != nil {
+		query += " AND m.timestamp > ?"
+		args = append(args, params.After)
+	}
+	if params.HideSpamFrom > 0 {
+		query += " AND (COALESCE(m.spam_score, 0) < ? OR m.spam_released = 1)"
+		args = append(args, params.HideSpamFrom)
+	}
+	query, args = appendJIDFilter(query, args, "m.chat_jid", params.IncludeJIDs, params.ExcludeJIDs)
+	query += " ORDER BY m.chat_jid, m.timestamp"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type edgeKey struct{ from, to string }
+	type edgeAcc struct {
+		weight   int
+		groups   map[string]bool
+		timeline map[string]int
+	}
+	nodes := make(map[string]*GraphNode)
+	nodeGroups := make(map[string]map[string]bool)
+	edges := make(map[edgeKey]*edgeAcc)
+	totals := make(map[string]int)
+	groups := make(map[string]bool)
+
+	var prevChat, prevSender string
+	var prevTime time.Time
+	for rows.Next() {
+		var chat, sender string
+		var fromMe bool
+		var ts time.Time
+		if err := rows.Scan(&chat, &sender, &fromMe, &ts); err != nil {
+			return nil, err
+		}
+		if fromMe {
+			sender = GraphMe
+		} else {
+			sender = jid.Normalize(sender)
+		}
+		if sender == "" {
+			continue
+		}
+		groups[chat] = true
+		n := nodes[sender]
+		if n == nil {
+			n = &GraphNode{ID: sender}
+			nodes[sender] = n
+			nodeGroups[sender] = make(map[string]bool)
+		}
+		n.Messages++
+		nodeGroups[sender][chat] = true
+
+		if chat == prevChat && sender != prevSender && ts.Sub(prevTime) <= window {
+			k := edgeKey{sender, prevSender}
+			e := edges[k]
+			if e == nil {
+				e = &edgeAcc{groups: make(map[string]bool), timeline: make(map[string]int)}
+				edges[k] = e
+			}
+			period := bucket(ts.UTC())
+			e.weight++
+			e.groups[chat] = true
+			e.timeline[period]++
+			totals[period]++
+		}
+		prevChat, prevSender, prevTime = chat, sender, ts
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	g := &Graph{
+		Bucket: params.Bucket,
+		Window: window.String(),
+		Groups: len(groups),
+		Nodes:  []GraphNode{},
+		Edges:  []GraphEdge{},
+		Totals: sortedBuckets(totals),
+	}
+	linked := make(map[string]bool)
+	for k, e := range edges {
+		if e.weight < params.MinWeight {
+			continue
+		}
+		edge := GraphEdge{From: k.from, To: k.to, Weight: e.weight, Timeline: sortedBuckets(e.timeline)}
+		for chat := range e.groups {
+			edge.Groups = append(edge.Groups, chat)
+		}
+		sort.Strings(edge.Groups)
+		g.Edges = append(g.Edges, edge)
+		linked[k.from], linked[k.to] = true, true
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.Weight != b.Weight {
+			return a.Weight > b.Weight
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+
+	var ids []string
+	for id := range nodes {
+		if linked[id] || params.MinWeight <= 1 {
+			ids = append(ids, id)
+		}
+	}
+	names, err := s.ChatNames(ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		n := nodes[id]
+		n.Name = names[id]
+		n.Groups = len(nodeGroups[id])
+		g.Nodes = append(g.Nodes, *n)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		if g.Nodes[i].Messages != g.Nodes[j].Messages {
+			return g.Nodes[i].Messages > g.Nodes[j].Messages
+		}
+		return g.Nodes[i].ID < g.Nodes[j].ID
+	})
+	return g, nil
+}
+
+var graphBuckets = map[string]func(time.Time) string{
+	GraphBucketDay:   func(t time.Time) string { return t.Format("2006-01-02") },
+	GraphBucketMonth: func(t time.Time) string { return t.Format("2006-01") },
+	GraphBucketWeek: func(t time.Time) string {
+		y, w := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", y, w)
+	},
+}
+
+func sortedBuckets(counts map[string]int) []GraphBucket {
+	out := make([]GraphBucket, 0, len(counts))
+	for period, n := range counts {
+		out = append(out, GraphBucket{Period: period, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Period < out[j].Period })
+	return out
+}
+
+// DOT renders the graph in Graphviz format, with edge widths following their
+// weight, e.g. for `dot -Tsvg`.
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph interactions {\n")
+	for _, n := range g.Nodes {
+		label := n.Name
+		if label == "" {
+			label = jid.User(n.ID)
+		}
+		fmt.Fprintf(&b, "  %s [label=%s];\n", dotQuote(n.ID), dotQuote(label))
+	}
+	max := 1
+	for _, e := range g.Edges {
+		if e.Weight > max {
+			max = e.Weight
+		}
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s [weight=%d, label=\"%d\", penwidth=%.1f];\n",
+			dotQuote(e.From), dotQuote(e.To), e.Weight, e.Weight, 1+4*float64(e.Weight)/float64(max))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ").Replace(s) + `"`
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInteractionGraph(t *testing.T) {
+	store := setupTestDB(t)
+	group, other := "120363000000000001@g.us", "120363000000000002@g.us"
+	alice, bob := "111@s.whatsapp.net", "222@s.whatsapp.net"
+	t0 := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	require.NoError(t, store.StoreChat(group, "Team", t0))
+	require.NoError(t, store.StoreChat(other, "Family", t0))
+	require.NoError(t, store.StoreChat(alice, "Alice", t0))
+	require.NoError(t, store.StoreChat("555@s.whatsapp.net", "Direct", t0))
+
+	msg := func(id, chat, sender string, fromMe bool, at time.Time) {
+		require.NoError(t, store.StoreMessage(id, chat, sender, "hi", at, fromMe, "", "", "", "", "", nil, nil, nil, 0))
+	}
+	msg("1", group, alice, false, t0)
+	msg("2", group, bob, false, t0.Add(time.Minute))                  // bob -> alice
+	msg("3", group, bob, false, t0.Add(2*time.Minute))                // same sender
+	msg("4", group, "me", true, t0.Add(3*time.Minute))                // me -> bob
+	msg("5", group, alice, false, t0.Add(time.Hour))                  // outside the window
+	msg("6", group, bob, false, t0.Add(time.Hour+time.Minute))        // bob -> alice
+	msg("7", other, alice, false, t0.AddDate(0, 1, 0))                // new group
+	msg("8", other, bob, false, t0.AddDate(0, 1, 0).Add(time.Second)) // bob -> alice
+	msg("9", "555@s.whatsapp.net", "555@s.whatsapp.net", false, t0)   // not a group
+
+	g, err := store.InteractionGraph(GraphParams{Bucket: GraphBucketMonth})
+	require.NoError(t, err)
+	assert.Equal(t, 2, g.Groups)
+	assert.Equal(t, "10m0s", g.Window)
+	require.Len(t, g.Edges, 2)
+	assert.Equal(t, GraphEdge{
+		From: bob, To: alice, Weight: 3,
+		Groups:   []string{group, other},
+		Timeline: []GraphBucket{{"2026-03", 2}, {"2026-04", 1}},
+	}, g.Edges[0])
+	assert.Equal(t, GraphMe, g.Edges[1].From)
+	assert.Equal(t, bob, g.Edges[1].To)
+	assert.Equal(t, []GraphBucket{{"2026-03", 3}, {"2026-04", 1}}, g.Totals)
+	require.Len(t, g.Nodes, 3)
+	assert.Equal(t, GraphNode{ID: bob, Messages: 4, Groups: 2}, g.Nodes[0])
+	assert.Equal(t, GraphNode{ID: alice, Name: "Alice", Messages: 3, Groups: 2}, g.Nodes[1])
+
+	chat := group
+	after := t0.Add(30 * time.Minute)
+	g, err = store.InteractionGraph(GraphParams{ChatJID: &chat, Bucket: GraphBucketDay, Window: 2 * time.Hour, MinWeight: 2})
+	require.NoError(t, err)
+	require.Len(t, g.Edges, 1, "me -> bob and alice -> me fall below min weight")
+	assert.Equal(t, bob, g.Edges[0].From)
+	assert.Equal(t, []GraphBucket{{"2026-03-02", 2}}, g.Edges[0].Timeline)
+	assert.Equal(t, []GraphBucket{{"2026-03-02", 4}}, g.Totals, "the longer window links message 5 to 4")
+	assert.Len(t, g.Nodes, 2)
+
+	g, err = store.InteractionGraph(GraphParams{After: &after, Bucket: GraphBucketWeek})
+	require.NoError(t, err)
+	require.Len(t, g.Edges, 1)
+	assert.Equal(t, []GraphBucket{{"2026-W10", 1}, {"2026-W14", 1}}, g.Edges[0].Timeline)
+
+	_, err = store.InteractionGraph(GraphParams{Bucket: "year"})
+	assert.Error(t, err)
+}
+
+func TestGraphDOT(t *testing.T) {
+	g := &Graph{
+		Nodes: []GraphNode{{ID: "111@s.whatsapp.net", Name: `Al "the" ice`}, {ID: GraphMe}},
+		Edges: []GraphEdge{{From: GraphMe, To: "111@s.whatsapp.net", Weight: 4}},
+	}
+	dot := g.DOT()
+	assert.Contains(t, dot, "digraph interactions {")
+	assert.Contains(t, dot, `"111@s.whatsapp.net" [label="Al \"the\" ice"];`)
+	assert.Contains(t, dot, `"me" [label="me"];`)
+	assert.Contains(t, dot, `"me" -> "111@s.whatsapp.net" [weight=4, label="4", penwidth=5.0];`)
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Group struct {
+	JID         string    `json:"jid"`
+	Subject     string    `json:"subject"`
+	Description string    `json:"description"`
+	Announce    bool      `json:"announce"` // only admins can send messages
+	Locked      bool      `json:"locked"`   // only admins can edit group info
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// GroupUpdate lists group fields to change; nil fields are left untouched.
+type GroupUpdate struct {
+	Subject     *string
+	Description *string
+	Announce    *bool
+	Locked      *bool
+	UpdatedAt   time.Time
+}
+
+// UpdateGroup applies upd to the group row, creating it if needed. A subject
+// change also renames the group's chat.
+func (s *MessageStore) UpdateGroup(jid string, upd GroupUpdate) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO groups (jid, updated_at) VALUES (?, ?) ON CONFLICT(jid) DO NOTHING`, jid, upd.UpdatedAt); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		`UPDATE groups SET
+			subject = COALESCE(?, subject),
+			description = COALESCE(?, description),
+			announce = COALESCE(?, announce),
+			locked = COALESCE(?, locked),
+			updated_at = ?
+		WHERE jid = ?`,
+		nullString(upd.Subject), nullString(upd.Description), nullBool(upd.Announce), nullBool(upd.Locked), upd.UpdatedAt, jid,
+	); err != nil {
+		return err
+	}
+	if upd.Subject != nil && *upd.Subject != "" {
+		if _, err := tx.Exec(`UPDATE chats SET name = ? WHERE jid = ?`, *upd.Subject, jid); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetGroup returns the stored settings of a group, or sql.ErrNoRows.
+func (s *MessageStore) GetGroup(jid string) (Group, error) {
+	var g Group
+	var subject, description sql.NullString
+	var updatedAt sql.NullTime
+	err := s.db.QueryRow(
+		`SELECT jid, subject, description, announce, locked, updated_at FROM groups WHERE jid = ?`, jid,
+	).Scan(&g.JID, &subject, &description, &g.Announce, &g.Locked, &updatedAt)
+	if err != nil {
+		return Group{}, err
+	}
+	g.Subject = subject.String
+	g.Description = description.String
+	g.UpdatedAt = updatedAt.Time
+	return g, nil
+}
+
+func nullString(v *string) sql.NullString {
+	if v == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *v, Valid: true}
+}
+
+func nullBool(v *bool) sql.NullBool {
+	if v == nil {
+		return sql.NullBool{}
+	}
+	return sql.NullBool{Bool: *v, Valid: true}
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateGroupCreatesAndMergesFields(t *testing.T) {
+	store := setupTestDB(t)
+	jid := "120363000000000001@g.us"
+	now := time.Now().UTC().Truncate(time.Second)
+
+	subject := "Book Club"
+	announce := true
+	require.NoError(t, store.UpdateGroup(jid, GroupUpdate{Subject: &subject, Announce: &announce, UpdatedAt: now}))
+
+	description := "Monthly reads"
+	require.NoError(t, store.UpdateGroup(jid, GroupUpdate{Description: &description, UpdatedAt: now.Add(time.Minute)}))
+
+	g, err := store.GetGroup(jid)
+	require.NoError(t, err)
+	assert.Equal(t, "Book Club", g.Subject)
+	assert.Equal(t, "Monthly reads", g.Description)
+	assert.True(t, g.Announce)
+	assert.False(t, g.Locked)
+	assert.True(t, g.UpdatedAt.Equal(now.Add(time.Minute)))
+}
+
+func TestUpdateGroupSubjectRenamesChat(t *testing.T) {
+	store := setupTestDB(t)
+	jid := "120363000000000002@g.us"
+	require.NoError(t, store.StoreChat(jid, "Old Name", time.Now()))
+
+	subject := "New Name"
+	require.NoError(t, store.UpdateGroup(jid, GroupUpdate{Subject: &subject, UpdatedAt: time.Now()}))
+
+	chats, err := store.ListChats(ListChatsParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, chats, 1)
+	assert.Equal(t, "New Name", chats[0].Name)
+}
+
+func TestGetGroupNotFound(t *testing.T) {
+	store := setupTestDB(t)
+	_, err := store.GetGroup("missing@g.us")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Verification statuses of a contact's identity key. A key is verified once
+// its security code was compared with the contact's out of band, and counts
+// as changed when the contact's identity changed after that.
+const (
+	VerificationUnverified = "unverified"
+	VerificationVerified   = "verified"
+	VerificationChanged    = "changed"
+)
+
+// ContactIdentity is what the store knows about a contact's identity key.
+// Contacts are keyed by sender, the user part of their JID as stored with
+// messages.
+type ContactIdentity struct {
+	Sender string `json:"sender"`
+	// ChangedAt is when WhatsApp last reported a new identity key, e.g.
+	// after the contact reinstalled the app or moved to another phone.
+	ChangedAt  *time.Time `json:"changed_at,omitempty"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+	// SecurityCode is the code that was verified.
+	SecurityCode string `json:"security_code,omitempty"`
+}
+
+// Verification returns the verification status of the identity.
+func (c ContactIdentity) Verification() string {
+	return verificationStatus(c.ChangedAt, c.VerifiedAt)
+}
+
+func verificationStatus(changedAt, verifiedAt *time.Time) string {
+	switch {
+	case verifiedAt == nil:
+		return VerificationUnverified
+	case changedAt != nil && changedAt.After(*verifiedAt):
+		return VerificationChanged
+	default:
+		return VerificationVerified
+	}
+}
+
+// GetContactIdentity returns the identity record of a sender; senders
+// without one are unverified and have no known identity change.
+func (s *MessageStore) GetContactIdentity(sender string) (ContactIdentity, error) {
+	c := ContactIdentity{Sender: sender}
+	var changedAt, verifiedAt sql.NullTime
+	err := s.db.QueryRow(
+		`SELECT changed_at, verified_at, COALESCE(security_code, '') FROM contact_identities WHERE sender = ?`, sender,
+	).Scan(&changedAt, &verifiedAt, &c.SecurityCode)
+	if err == sql.ErrNoRows {
+		return c, nil
+	}
+	c.ChangedAt, c.VerifiedAt = nullTimePtr(changedAt), nullTimePtr(verifiedAt)
+	return c, err
+}
+
+// RecordIdentityChange notes that sender's identity key changed at at.
+// Older reports than the one recorded are ignored.
+func (s *MessageStore) RecordIdentityChange(sender string, at time.Time) error {
+	_, err := s.db.Exec(
+		`INSERT INTO contact_identities (sender, changed_at) VALUES (?, ?)
+		ON CONFLICT(sender) DO UPDATE SET changed_at = excluded.changed_at
+		WHERE changed_at IS NULL OR changed_at < excluded.changed_at`,
+		sender, at,
+	)
+	return err
+}
+
+// SetIdentityVerified marks sender's identity key, with the given security
+// code, as verified at at.
+func (s *MessageStore) SetIdentityVerified(sender, securityCode string, at time.Time) error {
+	_, err := s.db.Exec(
+		`INSERT INTO contact_identities (sender, verified_at, security_code) VALUES (?, ?, ?)
+		ON CONFLICT(sender) DO UPDATE SET verified_at = excluded.verified_at, security_code = excluded.security_code`,
+		sender, at, securityCode,
+	)
+	return err
+}
+
+// ClearIdentityVerified marks sender's identity key as unverified again.
+func (s *MessageStore) ClearIdentityVerified(sender string) error {
+	_, err := s.db.Exec(
+		`UPDATE contact_identities SET verified_at = NULL, security_code = NULL WHERE sender = ?`, sender,
+	)
+	return err
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContactIdentityVerification(t *testing.T) {
+	s := setupTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	c, err := s.GetContactIdentity("111")
+	require.NoError(t, err)
+	assert.Equal(t, VerificationUnverified, c.Verification())
+
+	require.NoError(t, s.SetIdentityVerified("111", "12345", now))
+	c, err = s.GetContactIdentity("111")
+	require.NoError(t, err)
+	assert.Equal(t, VerificationVerified, c.Verification())
+	assert.Equal(t, "12345", c.SecurityCode)
+
+	// Changes reported before the verification do not matter; older reports
+	// than the latest are ignored
+	require.NoError(t, s.RecordIdentityChange("111", now.Add(-time.Hour)))
+	c, _ = s.GetContactIdentity("111")
+	assert.Equal(t, VerificationVerified, c.Verification())
+	require.NoError(t, s.RecordIdentityChange("111", now.Add(time.Hour)))
+	require.NoError(t, s.RecordIdentityChange("111", now.Add(time.Minute)))
+	c, _ = s.GetContactIdentity("111")
+	assert.Equal(t, VerificationChanged, c.Verification())
+	assert.True(t, c.ChangedAt.Equal(now.Add(time.Hour)))
+
+	require.NoError(t, s.ClearIdentityVerified("111"))
+	c, _ = s.GetContactIdentity("111")
+	assert.Equal(t, VerificationUnverified, c.Verification())
+	assert.Empty(t, c.SecurityCode)
+	assert.NotNil(t, c.ChangedAt)
+}
+
+func TestListMessagesIdentity(t *testing.T) {
+	s := setupTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, s.StoreChat("111@s.whatsapp.net", "Alice", now))
+	require.NoError(t, s.StoreMessage("m1", "111@s.whatsapp.net", "111", "hi", now, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, s.StoreMessage("m2", "111@s.whatsapp.net", "me", "hello", now.Add(time.Second), true, "", "", "", "", "", nil, nil, nil, 0))
+	device, changed := 2, now.Add(-time.Hour)
+	require.NoError(t, s.EnrichMessage("m1", "111@s.whatsapp.net", Enrichment{SenderDevice: &device, IdentityChangedAt: &changed}))
+	require.NoError(t, s.SetIdentityVerified("111", "12345", now))
+
+	messages, err := s.ListMessages(ListMessagesParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	own, received := messages[0], messages[1]
+	require.NotNil(t, received.SenderDevice)
+	assert.Equal(t, 2, *received.SenderDevice)
+	require.NotNil(t, received.IdentityChangedAt)
+	assert.True(t, received.IdentityChangedAt.Equal(changed))
+	assert.Equal(t, VerificationVerified, received.Verification)
+	assert.Nil(t, own.SenderDevice)
+	assert.Empty(t, own.Verification)
+}
@@ -0,0 +1,263 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SchemaStatus compares the database with the schema of this version.
+// UserVersion is SQLite's user_version pragma; the store migrates by adding
+// missing tables and columns on open rather than by version number, so
+// Missing is what tells whether a database is current.
+type SchemaStatus struct {
+	UserVersion int      `json:"user_version"`
+	UpToDate    bool     `json:"up_to_date"`
+	Missing     []string `json:"missing,omitempty"`
+}
+
+// TableCount is the number of rows in a table.
+type TableCount struct {
+	Table string `json:"table"`
+	Rows  int64  `json:"rows"`
+}
+
+// ChatSize is a chat ranked by its number of messages.
+type ChatSize struct {
+	JID         string     `json:"jid"`
+	Name        string     `json:"name,omitempty"`
+	Messages    int64      `json:"messages"`
+	Media       int64      `json:"media"`
+	LastMessage *time.Time `json:"last_message,omitempty"`
+}
+
+// MediaRef is a message whose media was downloaded to Path.
+type MediaRef struct {
+	MessageID string `json:"message_id"`
+	ChatJID   string `json:"chat_jid"`
+	Path      string `json:"path"`
+}
+
+// StoreIssue is an inconsistency found by Diagnose. Examples holds up to
+// five affected keys.
+type StoreIssue struct {
+	Check       string   `json:"check"`
+	Description string   `json:"description"`
+	Count       int64    `json:"count"`
+	Examples    []string `json:"examples,omitempty"`
+	Repairable  bool     `json:"repairable"`
+	Repaired    bool     `json:"repaired"`
+}
+
+// storeCheck is one consistency check. count and examples select the
+// affected rows; repair, when set, fixes them.
+type storeCheck struct {
+	name, description string
+	count, examples   string
+	repair            string
+}
+
+// chatLastMessage is the julianday of the newest message of chats.jid.
+const chatLastMessage = `(SELECT MAX(julianday(m.timestamp)) FROM messages m WHERE m.chat_jid = chats.jid)`
+
+var storeChecks = []storeCheck{
+	{
+		name:        "missing_chats",
+		description: "messages whose chat has no row in chats; repair creates the chat",
+		count:       `SELECT COUNT(DISTINCT chat_jid) FROM messages m WHERE NOT EXISTS (SELECT 1 FROM chats c WHERE c.jid = m.chat_jid)`,
+		examples:    `SELECT DISTINCT chat_jid FROM messages m WHERE NOT EXISTS (SELECT 1 FROM chats c WHERE c.jid = m.chat_jid) LIMIT 5`,
+		repair: `INSERT INTO chats (jid, name, last_message_time)
+			SELECT chat_jid, chat_jid, MAX(timestamp) FROM messages m
+			WHERE NOT EXISTS (SELECT 1 FROM chats c WHERE c.jid = m.chat_jid) GROUP BY chat_jid`,
+	},
+	{
+		name:        "empty_chats",
+		description: "chats without any messages, other than those reconciled from the phone; repair deletes them",
+		count:       `SELECT COUNT(*) FROM chats WHERE reconciled_at IS NULL AND NOT EXISTS (SELECT 1 FROM messages m WHERE m.chat_jid = chats.jid)`,
+		examples:    `SELECT jid FROM chats WHERE reconciled_at IS NULL AND NOT EXISTS (SELECT 1 FROM messages m WHERE m.chat_jid = chats.jid) LIMIT 5`,
+		repair:      `DELETE FROM chats WHERE reconciled_at IS NULL AND NOT EXISTS (SELECT 1 FROM messages m WHERE m.chat_jid = chats.jid)`,
+	},
+	{
+		name:        "stale_last_message_time",
+		description: "chats whose last_message_time is older than their newest message, which misorders the chat list; repair updates it",
+		count:       `SELECT COUNT(*) FROM chats WHERE COALESCE(julianday(last_message_time), 0) < ` + chatLastMessage,
+		examples:    `SELECT jid FROM chats WHERE COALESCE(julianday(last_message_time), 0) < ` + chatLastMessage + ` LIMIT 5`,
+		repair: `UPDATE chats SET last_message_time = (
+				SELECT m.timestamp FROM messages m WHERE m.chat_jid = chats.jid ORDER BY julianday(m.timestamp) DESC LIMIT 1)
+			WHERE COALESCE(julianday(last_message_time), 0) < ` + chatLastMessage,
+	},
+	{
+		name:        "orphaned_embeddings",
+		description: "vectors of messages that no longer exist; repair deletes them",
+		count:       `SELECT COUNT(*) FROM message_embeddings e WHERE NOT EXISTS (SELECT 1 FROM messages m WHERE m.id = e.message_id AND m.chat_jid = e.chat_jid)`,
+		examples:    `SELECT e.chat_jid || '/' || e.message_id FROM message_embeddings e WHERE NOT EXISTS (SELECT 1 FROM messages m WHERE m.id = e.message_id AND m.chat_jid = e.chat_jid) LIMIT 5`,
+		repair:      `DELETE FROM message_embeddings WHERE NOT EXISTS (SELECT 1 FROM messages m WHERE m.id = message_embeddings.message_id AND m.chat_jid = message_embeddings.chat_jid)`,
+	},
+	{
+		name:        "orphaned_tags",
+		description: "tags of messages that no longer exist; repair deletes them",
+		count:       `SELECT COUNT(*) FROM message_tags t WHERE NOT EXISTS (SELECT 1 FROM messages m WHERE m.id = t.message_id AND m.chat_jid = t.chat_jid)`,
+		examples:    `SELECT t.chat_jid || '/' || t.message_id || ' ' || t.tag FROM message_tags t WHERE NOT EXISTS (SELECT 1 FROM messages m WHERE m.id = t.message_id AND m.chat_jid = t.chat_jid) LIMIT 5`,
+		repair:      `DELETE FROM message_tags WHERE NOT EXISTS (SELECT 1 FROM messages m WHERE m.id = message_tags.message_id AND m.chat_jid = message_tags.chat_jid)`,
+	},
+}
+
+// SchemaStatus reports the user_version pragma and any tables and columns of
+// this version the database lacks.
+func (s *MessageStore) SchemaStatus() (SchemaStatus, error) {
+	var st SchemaStatus
+	if err := s.db.QueryRow(`PRAGMA user_version`).Scan(&st.UserVersion); err != nil {
+		return st, err
+	}
+	missing, err := missingSchema(s.db)
+	if err != nil {
+		return st, err
+	}
+	st.Missing = missing
+	st.UpToDate = len(missing) == 0
+	return st, nil
+}
+
+// TableCounts returns the number of rows of every table, by table name.
+func (s *MessageStore) TableCounts() ([]TableCount, error) {
+	names, err := s.queryStrings(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]TableCount, 0, len(names))
+	for _, name := range names {
+		c := TableCount{Table: name}
+		if err := s.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %q", name)).Scan(&c.Rows); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, nil
+}
+
+// LargestChats returns the limit chats with the most messages, largest first.
+func (s *MessageStore) LargestChats(limit int) ([]ChatSize, error) {
+	rows, err := s.db.Query(
+		`SELECT m.chat_jid, COALESCE(c.name, ''), COUNT(*), COUNT(NULLIF(COALESCE(m.media_type, ''), '')), c.last_message_time
+		FROM messages m LEFT JOIN chats c ON c.jid = m.chat_jid
+		GROUP BY m.chat_jid ORDER BY COUNT(*) DESC, m.chat_jid LIMIT ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	chats := []ChatSize{}
+	for rows.Next() {
+		var c ChatSize
+		var last sql.NullTime
+		if err := rows.Scan(&c.JID, &c.Name, &c.Messages, &c.Media, &last); err != nil {
+			return nil, err
+		}
+		if c.Name == c.JID {
+			c.Name = ""
+		}
+		if last.Valid {
+			t := last.Time
+			c.LastMessage = &t
+		}
+		chats = append(chats, c)
+	}
+	return chats, rows.Err()
+}
+
+// DownloadedMedia returns every message with a local media path.
+func (s *MessageStore) DownloadedMedia() ([]MediaRef, error) {
+	rows, err := s.db.Query(`SELECT id, chat_jid, local_path FROM messages WHERE COALESCE(local_path, '') != ''`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var refs []MediaRef
+	for rows.Next() {
+		var r MediaRef
+		if err := rows.Scan(&r.MessageID, &r.ChatJID, &r.Path); err != nil {
+			return nil, err
+		}
+		refs = append(refs, r)
+	}
+	return refs, rows.Err()
+}
+
+// ClearMediaPaths forgets the downloads of refs, e.g. because their files are
+// gone, so the media can be downloaded again.
+func (s *MessageStore) ClearMediaPaths(refs []MediaRef) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, r := range refs {
+		if _, err := tx.Exec(`UPDATE messages SET local_path = NULL, downloaded_at = NULL WHERE id = ? AND chat_jid = ?`, r.MessageID, r.ChatJID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Diagnose runs SQLite's quick_check and the consistency checks, returning
+// the ones that found something. With repair, the repairable issues are
+// fixed in one transaction.
+func (s *MessageStore) Diagnose(repair bool) ([]StoreIssue, error) {
+	issues := []StoreIssue{}
+	problems, err := s.queryStrings(`PRAGMA quick_check`)
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) != 1 || problems[0] != "ok" {
+		issue := StoreIssue{
+			Check:       "integrity",
+			Description: "SQLite reports corruption; restore a backup or recover it with the sqlite3 .recover command",
+			Count:       int64(len(problems)),
+		}
+		if len(problems) > 5 {
+			problems = problems[:5]
+		}
+		issue.Examples = problems
+		issues = append(issues, issue)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	for _, c := range storeChecks {
+		issue := StoreIssue{Check: c.name, Description: c.description, Repairable: c.repair != ""}
+		if err := tx.QueryRow(c.count).Scan(&issue.Count); err != nil {
+			return nil, fmt.Errorf("check %s: %w", c.name, err)
+		}
+		if issue.Count == 0 {
+			continue
+		}
+		rows, err := tx.Query(c.examples)
+		if err != nil {
+			return nil, fmt.Errorf("check %s: %w", c.name, err)
+		}
+		for rows.Next() {
+			var key string
+			if err := rows.Scan(&key); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			issue.Examples = append(issue.Examples, key)
+		}
+		rows.Close()
+		if repair && issue.Repairable {
+			if _, err := tx.Exec(c.repair); err != nil {
+				return nil, fmt.Errorf("repair %s: %w", c.name, err)
+			}
+			issue.Repaired = true
+		}
+		issues = append(issues, issue)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectStore(t *testing.T) {
+	s := setupTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, s.StoreChat("1@s.whatsapp.net", "Alice", now))
+	require.NoError(t, s.StoreChat("2@s.whatsapp.net", "Bob", now))
+	require.NoError(t, s.StoreMessage("a1", "1@s.whatsapp.net", "1", "hi", now, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, s.StoreMessage("b1", "2@s.whatsapp.net", "2", "", now, false, "image", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, s.StoreMessage("b2", "2@s.whatsapp.net", "2", "hey", now, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, s.MarkMediaDownloaded("b1", "2@s.whatsapp.net", "/tmp/b1.jpg", now))
+
+	schema, err := s.SchemaStatus()
+	require.NoError(t, err)
+	assert.True(t, schema.UpToDate)
+	assert.Empty(t, schema.Missing)
+
+	counts, err := s.TableCounts()
+	require.NoError(t, err)
+	rows := make(map[string]int64)
+	for _, c := range counts {
+		rows[c.Table] = c.Rows
+	}
+	assert.Equal(t, int64(2), rows["chats"])
+	assert.Equal(t, int64(3), rows["messages"])
+
+	chats, err := s.LargestChats(1)
+	require.NoError(t, err)
+	require.Len(t, chats, 1)
+	assert.Equal(t, "2@s.whatsapp.net", chats[0].JID)
+	assert.Equal(t, "Bob", chats[0].Name)
+	assert.Equal(t, int64(2), chats[0].Messages)
+	assert.Equal(t, int64(1), chats[0].Media)
+	require.NotNil(t, chats[0].LastMessage)
+	assert.True(t, now.Equal(*chats[0].LastMessage))
+
+	media, err := s.DownloadedMedia()
+	require.NoError(t, err)
+	assert.Equal(t, []MediaRef{{MessageID: "b1", ChatJID: "2@s.whatsapp.net", Path: "/tmp/b1.jpg"}}, media)
+	require.NoError(t, s.ClearMediaPaths(media))
+	media, err = s.DownloadedMedia()
+	require.NoError(t, err)
+	assert.Empty(t, media)
+}
+
+func TestDiagnose(t *testing.T) {
+	s := setupTestDB(t)
+	old := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, s.StoreChat("1@s.whatsapp.net", "Alice", old))
+	require.NoError(t, s.StoreChat("2@s.whatsapp.net", "Empty", old))
+	require.NoError(t, s.StoreMessage("a1", "1@s.whatsapp.net", "1", "hi", now, false, "", "", "", "", "", nil, nil, nil, 0))
+	_, err := s.db.Exec(`PRAGMA foreign_keys = OFF; INSERT INTO messages (id, chat_jid, sender, content, timestamp, is_from_me) VALUES ('x1', '3@s.whatsapp.net', '3', 'orphan', ?, 0); PRAGMA foreign_keys = ON`, now)
+	require.NoError(t, err)
+	_, err = s.db.Exec(`UPDATE chats SET last_message_time = ? WHERE jid = ?`, old, "1@s.whatsapp.net")
+	require.NoError(t, err)
+	require.NoError(t, s.StoreEmbeddings("test", []Embedding{
+		{MessageID: "a1", ChatJID: "1@s.whatsapp.net", Vector: []float32{1}},
+		{MessageID: "gone", ChatJID: "1@s.whatsapp.net", Vector: []float32{1}},
+	}))
+
+	issues, err := s.Diagnose(false)
+	require.NoError(t, err)
+	found := make(map[string]StoreIssue)
+	for _, issue := range issues {
+		found[issue.Check] = issue
+		assert.False(t, issue.Repaired)
+	}
+	assert.Equal(t, []string{"3@s.whatsapp.net"}, found["missing_chats"].Examples)
+	assert.Equal(t, []string{"2@s.whatsapp.net"}, found["empty_chats"].Examples)
+	assert.Equal(t, []string{"1@s.whatsapp.net"}, found["stale_last_message_time"].Examples)
+	assert.Equal(t, []string{"1@s.whatsapp.net/gone"}, found["orphaned_embeddings"].Examples)
+	assert.NotContains(t, found, "integrity")
+
+	issues, err = s.Diagnose(true)
+	require.NoError(t, err)
+	require.Len(t, issues, 4)
+	for _, issue := range issues {
+		assert.True(t, issue.Repaired, issue.Check)
+	}
+
+	issues, err = s.Diagnose(false)
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+
+	chats, err := s.LargestChats(10)
+	require.NoError(t, err)
+	require.Len(t, chats, 2)
+	for _, c := range chats {
+		require.NotNil(t, c.LastMessage, c.JID)
+		assert.True(t, now.Equal(*c.LastMessage), c.JID)
+	}
+}
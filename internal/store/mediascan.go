@@ -0,0 +1,23 @@
+package store
+
+import "time"
+
+// Results of scanning a message's media for malware. Infected media is
+// quarantined: it is kept on disk but never served.
+const (
+	MediaScanClean    = "clean"
+	MediaScanInfected = "infected"
+	// MediaScanFailed means the scanner could not be run; the media is
+	// scanned again before it is served.
+	MediaScanFailed = "failed"
+)
+
+// MarkMediaScanned records the result of scanning a message's media, with
+// the threat found in infected media.
+func (s *MessageStore) MarkMediaScanned(id, chatJID, result, threat string, at time.Time) error {
+	_, err := s.db.Exec(
+		`UPDATE messages SET media_scan = ?, media_threat = NULLIF(?, ''), media_scanned_at = ? WHERE id = ? AND chat_jid = ?`,
+		result, threat, at, id, s.resolve(chatJID),
+	)
+	return err
+}
@@ -0,0 +1,34 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkMediaScanned(t *testing.T) {
+	s := setupTestDB(t)
+	now := time.Now().UTC()
+	chat := "1@s.whatsapp.net"
+	require.NoError(t, s.StoreChat(chat, "Alice", now))
+	require.NoError(t, s.StoreMessage("m1", chat, "1", "", now, false, "document", "invoice.pdf", "", "/direct", "application/pdf", []byte{1}, nil, nil, 10))
+
+	info, err := s.GetMessageForDownload("m1", nil)
+	require.NoError(t, err)
+	assert.Empty(t, info.MediaScan)
+
+	require.NoError(t, s.MarkMediaScanned("m1", chat, MediaScanInfected, "Eicar-Test-Signature", now))
+	info, err = s.GetMessageForDownload("m1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, MediaScanInfected, info.MediaScan)
+	assert.Equal(t, "Eicar-Test-Signature", info.MediaThreat)
+
+	require.NoError(t, s.MarkMediaScanned("m1", chat, MediaScanClean, "", now))
+	messages, err := s.ListMessages(ListMessagesParams{ChatJID: &chat, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, MediaScanClean, messages[0].MediaScan)
+	assert.Empty(t, messages[0].MediaThreat)
+}
@@ -0,0 +1,259 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+)
+
+// ChatEventMerged is recorded in the surviving chat when another chat's
+// history is merged into it; Target is the merged JID.
+const ChatEventMerged = "chat_merged"
+
+// ChatAlias records that AliasJID was merged into JID. Lookups by the alias
+// resolve to JID, so both identifiers reach the same history.
+type ChatAlias struct {
+	AliasJID  string    `json:"alias_jid"`
+	JID       string    `json:"jid"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MergeResult summarises a chat merge. Duplicates counts messages present
+// under both JIDs, which were kept once.
+type MergeResult struct {
+	From       string `json:"from"`
+	Into       string `json:"into"`
+	Messages   int64  `json:"messages"`
+	Duplicates int64  `json:"duplicates,omitempty"`
+	Events     int64  `json:"events"`
+}
+
+// ResolveChatJID normalizes chatJID and returns the JID it was merged into,
+// or the normalized JID when it is not an alias.
+func (s *MessageStore) ResolveChatJID(chatJID string) (string, error) {
+	chatJID = jid.Normalize(chatJID)
+	var target string
+	err := s.db.QueryRow(`SELECT jid FROM chat_aliases WHERE alias_jid = ?`, chatJID).Scan(&target)
+	if err == sql.ErrNoRows {
+		return chatJID, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// resolve is ResolveChatJID for call sites that fall back to the JID as given;
+// a failed lookup surfaces on the query that follows.
+func (s *MessageStore) resolve(chatJID string) string {
+	if target, err := s.ResolveChatJID(chatJID); err == nil {
+		return target
+	}
+	return jid.Normalize(chatJID)
+}
+
+// ListChatAliases returns the aliases of a chat, oldest first.
+func (s *MessageStore) ListChatAliases(chatJID string) ([]ChatAlias, error) {
+	rows, err := s.db.Query(
+		`SELECT alias_jid, jid, created_at FROM chat_aliases WHERE jid = ? ORDER BY created_at, alias_jid`,
+		s.resolve(chatJID),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aliases := []ChatAlias{}
+	for rows.Next() {
+		var a ChatAlias
+		if err := rows.Scan(&a.AliasJID, &a.JID, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases, rows.Err()
+}
+
+// MergeChats moves the history of chat from into chat into and records from
+// as an alias of into. Messages and their embeddings and receipts, chat events and
+// per-chat state (away opt-outs and replies, handoff mode, read state,
+// draft, tags, digests, metadata) follow; where both chats have a row the one in into
+// wins. into is created from from's chat row if it does not exist yet, and
+// aliases that pointed at from are re-pointed at into.
+func (s *MessageStore) MergeChats(from, into, requestID string, at time.Time) (MergeResult, error) {
+	return s.mergeChats(from, into, requestID, at, true)
+}
+
+// mergeChats implements MergeChats; without record, from is neither kept as
+// an alias nor mentioned in a chat event.
+func (s *MessageStore) mergeChats(from, into, requestID string, at time.Time, record bool) (MergeResult, error) {
+	into = s.resolve(into)
+	result := MergeResult{From: from, Into: into}
+	if from == into {
+		return result, fmt.Errorf("cannot merge %s into itself", from)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow(`SELECT 1 FROM chats WHERE jid = ?`, from).Scan(&exists); err == sql.ErrNoRows {
+		return result, fmt.Errorf("chat %s not found", from)
+	} else if err != nil {
+		return result, err
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO chats (jid, name, last_message_time)
+		SELECT ?, name, last_message_time FROM chats WHERE jid = ?
+		ON CONFLICT(jid) DO UPDATE SET
+			name = COALESCE(NULLIF(chats.name, ''), excluded.name),
+			last_message_time = MAX(COALESCE(chats.last_message_time, excluded.last_message_time), COALESCE(excluded.last_message_time, chats.last_message_time))`,
+		into, from,
+	); err != nil {
+		return result, fmt.Errorf("failed to create chat %s: %w", into, err)
+	}
+
+	res, err := tx.Exec(`UPDATE OR IGNORE messages SET chat_jid = ? WHERE chat_jid = ?`, into, from)
+	if err != nil {
+		return result, fmt.Errorf("failed to move messages: %w", err)
+	}
+	result.Messages, _ = res.RowsAffected()
+	if res, err = tx.Exec(`DELETE FROM messages WHERE chat_jid = ?`, from); err != nil {
+		return result, fmt.Errorf("failed to drop duplicate messages: %w", err)
+	}
+	result.Duplicates, _ = res.RowsAffected()
+
+	if res, err = tx.Exec(`UPDATE chat_events SET chat_jid = ? WHERE chat_jid = ?`, into, from); err != nil {
+		return result, fmt.Errorf("failed to move chat events: %w", err)
+	}
+	result.Events, _ = res.RowsAffected()
+
+	for _, table := range []string{"away_optouts", "away_replies", "chat_modes", "chat_reads", "drafts", "message_embeddings", "message_receipts", "message_tags", "digests", "followup_notices", "polls", "poll_votes"} {
+		if _, err := tx.Exec(fmt.Sprintf(`UPDATE OR IGNORE %s SET chat_jid = ? WHERE chat_jid = ?`, table), into, from); err != nil {
+			return result, fmt.Errorf("failed to move %s: %w", table, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE chat_jid = ?`, table), from); err != nil {
+			return result, fmt.Errorf("failed to move %s: %w", table, err)
+		}
+	}
+
+	// Metadata is keyed by jid, which for messages is their chat's
+	if _, err := tx.Exec(`UPDATE OR IGNORE metadata SET jid = ? WHERE jid = ?`, into, from); err != nil {
+		return result, fmt.Errorf("failed to move metadata: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM metadata WHERE jid = ?`, from); err != nil {
+		return result, fmt.Errorf("failed to move metadata: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM chats WHERE jid = ?`, from); err != nil {
+		return result, fmt.Errorf("failed to remove chat %s: %w", from, err)
+	}
+	if _, err := tx.Exec(`UPDATE chat_aliases SET jid = ? WHERE jid = ?`, into, from); err != nil {
+		return result, fmt.Errorf("failed to update aliases: %w", err)
+	}
+	if !record {
+		return result, tx.Commit()
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO chat_aliases (alias_jid, jid, created_at) VALUES (?, ?, ?)
+		ON CONFLICT(alias_jid) DO UPDATE SET jid = excluded.jid, created_at = excluded.created_at`,
+		from, into, at,
+	); err != nil {
+		return result, fmt.Errorf("failed to record alias: %w", err)
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO chat_events (chat_jid, type, actor, target, timestamp, request_id) VALUES (?, ?, '', ?, ?, ?)`,
+		into, ChatEventMerged, from, at, requestID,
+	); err != nil {
+		return result, err
+	}
+
+	return result, tx.Commit()
+}
+
+// LinkChatJID records alias as another identifier of chatJID, such as the
+// linked identifier (@lid) of a phone-number JID. If a chat already exists
+// under alias it is merged into jid, so the conversation is not kept twice.
+func (s *MessageStore) LinkChatJID(alias, chatJID string, at time.Time) error {
+	alias = jid.Normalize(alias)
+	target, err := s.ResolveChatJID(chatJID)
+	if err != nil {
+		return err
+	}
+	current, err := s.ResolveChatJID(alias)
+	if err != nil {
+		return err
+	}
+	if current == target || alias == target {
+		return nil
+	}
+
+	var exists int
+	err = s.db.QueryRow(`SELECT 1 FROM chats WHERE jid = ?`, alias).Scan(&exists)
+	if err == nil {
+		_, err = s.MergeChats(alias, target, "", at)
+		return err
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO chat_aliases (alias_jid, jid, created_at) VALUES (?, ?, ?)
+		ON CONFLICT(alias_jid) DO UPDATE SET jid = excluded.jid, created_at = excluded.created_at`,
+		alias, target, at,
+	)
+	return err
+}
+
+// normalizedJIDsVersion is the user_version from which chat JIDs are stored
+// normalized.
+const normalizedJIDsVersion = 1
+
+// normalizeChatJIDs folds chats stored under a non-canonical JID (with a
+// device suffix, in upper case or on the c.us server) into the chat of the
+// normalized JID. It runs once per database, when first opened by a version
+// that normalizes JIDs on write.
+func (s *MessageStore) normalizeChatJIDs() error {
+	var version int
+	if err := s.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	if version >= normalizedJIDsVersion {
+		return nil
+	}
+
+	rows, err := s.db.Query(`SELECT jid FROM chats`)
+	if err != nil {
+		return err
+	}
+	var stray []string
+	for rows.Next() {
+		var chatJID string
+		if err := rows.Scan(&chatJID); err != nil {
+			rows.Close()
+			return err
+		}
+		if jid.Normalize(chatJID) != chatJID {
+			stray = append(stray, chatJID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, from := range stray {
+		if _, err := s.mergeChats(from, from, "", now, false); err != nil {
+			return fmt.Errorf("failed to merge %s: %w", from, err)
+		}
+	}
+	_, err = s.db.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, normalizedJIDsVersion))
+	return err
+}
@@ -0,0 +1,151 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeChats(t *testing.T) {
+	store := setupTestDB(t)
+	oldJID := "4915100000001@s.whatsapp.net"
+	newJID := "4915100000002@s.whatsapp.net"
+	base := time.Now().UTC().Truncate(time.Second)
+
+	require.NoError(t, store.StoreChat(oldJID, "Alice", base))
+	require.NoError(t, store.StoreMessage("m1", oldJID, oldJID, "old number", base, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, store.StoreMessage("dup", oldJID, oldJID, "sent twice", base, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, store.SetChatMode(oldJID, ChatModeHuman, base))
+	require.NoError(t, store.StoreChat(newJID, "", base.Add(time.Hour)))
+	require.NoError(t, store.StoreMessage("m2", newJID, newJID, "new number", base.Add(time.Hour), false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, store.StoreMessage("dup", newJID, newJID, "sent twice", base, false, "", "", "", "", "", nil, nil, nil, 0))
+
+	result, err := store.MergeChats(oldJID, newJID, "req-1", base.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, MergeResult{From: oldJID, Into: newJID, Messages: 1, Duplicates: 1}, result)
+
+	exists, err := store.ChatExists(oldJID)
+	require.NoError(t, err)
+	assert.True(t, exists, "the old JID resolves to the merged chat")
+
+	var name string
+	require.NoError(t, store.db.QueryRow(`SELECT name FROM chats WHERE jid = ?`, newJID).Scan(&name))
+	assert.Equal(t, "Alice", name, "an unnamed chat takes the merged chat's name")
+	var chats int
+	require.NoError(t, store.db.QueryRow(`SELECT COUNT(*) FROM chats`).Scan(&chats))
+	assert.Equal(t, 1, chats)
+
+	for _, jid := range []string{oldJID, newJID} {
+		msgs, err := store.ListMessages(ListMessagesParams{ChatJID: &jid, Limit: 10})
+		require.NoError(t, err)
+		assert.Len(t, msgs, 3, "history is reachable through %s", jid)
+	}
+
+	// New activity under the old number joins the merged chat
+	require.NoError(t, store.StoreChat(oldJID, "Alice", base.Add(3*time.Hour)))
+	require.NoError(t, store.StoreMessage("m3", oldJID, oldJID, "still on the old SIM", base.Add(3*time.Hour), false, "", "", "", "", "", nil, nil, nil, 0))
+	st, err := store.Stats(newJID)
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Chats: 1, Messages: 4, ChatMessages: 4}, st)
+
+	mode, err := store.GetChatMode(newJID)
+	require.NoError(t, err)
+	assert.Equal(t, ChatModeHuman, mode.Mode)
+
+	events, err := store.ListChatEvents(oldJID, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, ChatEvent{ID: events[0].ID, ChatJID: newJID, Type: ChatEventMerged, Target: oldJID, Timestamp: base.Add(2 * time.Hour), RequestID: "req-1"}, events[0])
+
+	aliases, err := store.ListChatAliases(newJID)
+	require.NoError(t, err)
+	require.Len(t, aliases, 1)
+	assert.Equal(t, oldJID, aliases[0].AliasJID)
+}
+
+func TestMergeChats_Chained(t *testing.T) {
+	store := setupTestDB(t)
+	a, b, c := "1@s.whatsapp.net", "2@s.whatsapp.net", "3@s.whatsapp.net"
+	now := time.Now()
+	for _, jid := range []string{a, b, c} {
+		require.NoError(t, store.StoreChat(jid, jid, now))
+	}
+
+	_, err := store.MergeChats(a, b, "", now)
+	require.NoError(t, err)
+	_, err = store.MergeChats(b, c, "", now)
+	require.NoError(t, err)
+
+	resolved, err := store.ResolveChatJID(a)
+	require.NoError(t, err)
+	assert.Equal(t, c, resolved, "aliases of a merged chat follow it")
+
+	_, err = store.MergeChats(c, a, "", now)
+	assert.ErrorContains(t, err, "into itself")
+	_, err = store.MergeChats("9@s.whatsapp.net", c, "", now)
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestLinkChatJID(t *testing.T) {
+	store := setupTestDB(t)
+	lid, pn := "98765432109876@lid", "4915112345678@s.whatsapp.net"
+	now := time.Now()
+
+	require.NoError(t, store.StoreChat(lid, "Bob", now))
+	require.NoError(t, store.StoreMessage("l1", lid, lid, "via lid", now, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, store.StoreChat(pn, "Bob", now))
+
+	require.NoError(t, store.LinkChatJID(lid, pn, now))
+	require.NoError(t, store.LinkChatJID(lid, pn, now), "linking again is a no-op")
+
+	chats, err := store.ListChats(ListChatsParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, chats, 1, "the LID chat is folded into the phone chat")
+	assert.Equal(t, pn, chats[0].JID)
+
+	// An alias is recorded even before any chat exists under it
+	require.NoError(t, store.LinkChatJID("555@lid", "555@s.whatsapp.net", now))
+	resolved, err := store.ResolveChatJID("555@lid")
+	require.NoError(t, err)
+	assert.Equal(t, "555@s.whatsapp.net", resolved)
+}
+
+func TestNormalizeChatJIDsMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.db")
+	store, err := NewMessageStore(path)
+	require.NoError(t, err)
+	now := time.Now().UTC().Truncate(time.Second)
+	// Rows written before JIDs were normalized
+	for _, chat := range []string{"4915112345678@s.whatsapp.net", "4915112345678:12@s.whatsapp.net", "4915187654321@c.us"} {
+		_, err := store.db.Exec(`INSERT INTO chats (jid, name, last_message_time) VALUES (?, 'Carol', ?)`, chat, now)
+		require.NoError(t, err)
+		_, err = store.db.Exec(`INSERT INTO messages (id, chat_jid, sender, content, timestamp, is_from_me) VALUES (?, ?, 'x', 'hi', ?, 0)`, "m-"+chat, chat, now)
+		require.NoError(t, err)
+	}
+	_, err = store.db.Exec(`PRAGMA user_version = 0`)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	store, err = NewMessageStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	chats, err := store.ListChats(ListChatsParams{Limit: 10})
+	require.NoError(t, err)
+	var jids []string
+	for _, c := range chats {
+		jids = append(jids, c.JID)
+	}
+	assert.ElementsMatch(t, []string{"4915112345678@s.whatsapp.net", "4915187654321@s.whatsapp.net"}, jids)
+
+	st, err := store.Stats("4915112345678:3@s.whatsapp.net")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), st.ChatMessages)
+
+	events, err := store.ListChatEvents("4915112345678@s.whatsapp.net", 10)
+	require.NoError(t, err)
+	assert.Empty(t, events, "normalizing is not recorded as a merge")
+}
@@ -0,0 +1,122 @@
+package store
+
+import "time"
+
+// Kinds of records metadata can be attached to.
+const (
+	MetaChat    = "chat"
+	MetaContact = "contact"
+	MetaMessage = "message"
+)
+
+// MetaTarget is the record metadata is attached to: a chat or contact by
+// JID, or a message by its chat's JID and ID.
+type MetaTarget struct {
+	Kind      string
+	JID       string
+	MessageID string
+}
+
+// MetaEntry is one key of the metadata of a record.
+type MetaEntry struct {
+	Kind      string    `json:"kind"`
+	JID       string    `json:"jid"`
+	MessageID string    `json:"message_id,omitempty"`
+	Key       string    `json:"key"`
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MetaFilter selects metadata entries. Empty fields match everything.
+type MetaFilter struct {
+	Kind  string
+	Key   string
+	Value *string
+	// IncludeJIDs/ExcludeJIDs filter by JID suffix, as in ListMessagesParams.
+	IncludeJIDs []string
+	ExcludeJIDs []string
+	Limit       int
+	Page        int
+}
+
+// SetMeta sets key of a record to value, replacing the value it had.
+func (s *MessageStore) SetMeta(t MetaTarget, key, value string, at time.Time) error {
+	_, err := s.db.Exec(
+		`INSERT INTO metadata (kind, jid, message_id, key, value, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(kind, jid, message_id, key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+		t.Kind, s.resolve(t.JID), t.MessageID, key, value, at.UTC(),
+	)
+	return err
+}
+
+// DeleteMeta removes key from a record. It reports whether the record had
+// the key.
+func (s *MessageStore) DeleteMeta(t MetaTarget, key string) (bool, error) {
+	res, err := s.db.Exec(
+		`DELETE FROM metadata WHERE kind = ? AND jid = ? AND message_id = ? AND key = ?`,
+		t.Kind, s.resolve(t.JID), t.MessageID, key,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Meta returns the metadata of a record by key.
+func (s *MessageStore) Meta(t MetaTarget) (map[string]string, error) {
+	rows, err := s.db.Query(
+		`SELECT key, value FROM metadata WHERE kind = ? AND jid = ? AND message_id = ?`,
+		t.Kind, s.resolve(t.JID), t.MessageID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	meta := map[string]string{}
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		meta[key] = value
+	}
+	return meta, rows.Err()
+}
+
+// FindMeta lists the metadata entries matching f, most recently updated
+// first.
+func (s *MessageStore) FindMeta(f MetaFilter) ([]MetaEntry, error) {
+	query := `SELECT kind, jid, message_id, key, value, updated_at FROM metadata WHERE 1=1`
+	args := []interface{}{}
+	if f.Kind != "" {
+		query += " AND kind = ?"
+		args = append(args, f.Kind)
+	}
+	if f.Key != "" {
+		query += " AND key = ?"
+		args = append(args, f.Key)
+	}
+	if f.Value != nil {
+		query += " AND value = ?"
+		args = append(args, *f.Value)
+	}
+	query, args = appendJIDFilter(query, args, "jid", f.IncludeJIDs, f.ExcludeJIDs)
+	query += " ORDER BY updated_at DESC, kind, jid, message_id, key LIMIT ? OFFSET ?"
+	args = append(args, f.Limit, f.Page*f.Limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []MetaEntry{}
+	for rows.Next() {
+		var e MetaEntry
+		if err := rows.Scan(&e.Kind, &e.JID, &e.MessageID, &e.Key, &e.Value, &e.UpdatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeta(t *testing.T) {
+	s := setupTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	alice := MetaTarget{Kind: MetaChat, JID: "1@s.whatsapp.net"}
+	team := MetaTarget{Kind: MetaChat, JID: "120363000000000001@g.us"}
+	msg := MetaTarget{Kind: MetaMessage, JID: "1@s.whatsapp.net", MessageID: "m1"}
+
+	require.NoError(t, s.SetMeta(alice, "crm_id", "C-1", now.Add(-time.Hour)))
+	require.NoError(t, s.SetMeta(alice, "crm_id", "C-2", now), "replacing a value")
+	require.NoError(t, s.SetMeta(alice, "tier", "gold", now))
+	require.NoError(t, s.SetMeta(team, "crm_id", "C-3", now.Add(-time.Minute)))
+	require.NoError(t, s.SetMeta(msg, "ticket", "T-9", now))
+
+	meta, err := s.Meta(alice)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"crm_id": "C-2", "tier": "gold"}, meta)
+	meta, err = s.Meta(MetaTarget{Kind: MetaContact, JID: "1@s.whatsapp.net"})
+	require.NoError(t, err)
+	assert.Empty(t, meta, "kinds are kept apart")
+	meta, err = s.Meta(msg)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"ticket": "T-9"}, meta)
+
+	entries, err := s.FindMeta(MetaFilter{Key: "crm_id", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, MetaEntry{Kind: MetaChat, JID: "1@s.whatsapp.net", Key: "crm_id", Value: "C-2", UpdatedAt: now}, entries[0])
+	assert.Equal(t, "120363000000000001@g.us", entries[1].JID)
+
+	value := "C-3"
+	entries, err = s.FindMeta(MetaFilter{Kind: MetaChat, Key: "crm_id", Value: &value, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "120363000000000001@g.us", entries[0].JID)
+
+	entries, err = s.FindMeta(MetaFilter{ExcludeJIDs: []string{"@g.us"}, Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	deleted, err := s.DeleteMeta(alice, "tier")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = s.DeleteMeta(alice, "tier")
+	require.NoError(t, err)
+	assert.False(t, deleted)
+}
+
+func TestMergeChatsMovesMeta(t *testing.T) {
+	s := setupTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	oldJID := "4915100000001@s.whatsapp.net"
+	newJID := "4915100000002@s.whatsapp.net"
+	require.NoError(t, s.StoreChat(oldJID, "Alice", now))
+	require.NoError(t, s.StoreChat(newJID, "Alice", now))
+	require.NoError(t, s.SetMeta(MetaTarget{Kind: MetaChat, JID: oldJID}, "crm_id", "C-1", now))
+	require.NoError(t, s.SetMeta(MetaTarget{Kind: MetaChat, JID: oldJID}, "tier", "gold", now))
+	require.NoError(t, s.SetMeta(MetaTarget{Kind: MetaChat, JID: newJID}, "crm_id", "C-2", now))
+
+	_, err := s.MergeChats(oldJID, newJID, "", now)
+	require.NoError(t, err)
+
+	meta, err := s.Meta(MetaTarget{Kind: MetaChat, JID: oldJID})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"crm_id": "C-2", "tier": "gold"}, meta, "the merged chat's values win")
+}
@@ -0,0 +1,100 @@
+package store
+
+import "time"
+
+// OutboxItem is a notification a webhook or broker did not accept. It is
+// kept in the outbox until it is delivered or expires.
+type OutboxItem struct {
+	ID int64 `json:"id"`
+	// Sink names the backend the notification is for, e.g. "event_bus".
+	Sink string `json:"sink"`
+	// Target is where the sink delivers it: a topic, channel or URL.
+	Target    string    `json:"target"`
+	Key       string    `json:"key,omitempty"`
+	Payload   []byte    `json:"payload"`
+	CreatedAt time.Time `json:"created_at"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// OutboxStats describes the notifications waiting for a backend to recover.
+type OutboxStats struct {
+	// Pending counts queued notifications per sink.
+	Pending  map[string]int64 `json:"pending"`
+	Replayed int64            `json:"replayed"`
+	Expired  int64            `json:"expired"`
+}
+
+// EnqueueOutbox persists an undelivered notification.
+func (s *MessageStore) EnqueueOutbox(item OutboxItem) error {
+	_, err := s.db.Exec(
+		`INSERT INTO outbox (sink, target, routing_key, payload, created_at, attempts, last_error) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		item.Sink, item.Target, item.Key, item.Payload, item.CreatedAt.UTC(), item.Attempts, item.LastError,
+	)
+	return err
+}
+
+// PendingOutbox returns up to limit queued notifications of sink, oldest first.
+func (s *MessageStore) PendingOutbox(sink string, limit int) ([]OutboxItem, error) {
+	rows, err := s.db.Query(
+		`SELECT id, sink, target, routing_key, payload, created_at, attempts, COALESCE(last_error, '')
+		FROM outbox WHERE sink = ? ORDER BY id LIMIT ?`,
+		sink, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []OutboxItem
+	for rows.Next() {
+		var item OutboxItem
+		if err := rows.Scan(&item.ID, &item.Sink, &item.Target, &item.Key, &item.Payload, &item.CreatedAt, &item.Attempts, &item.LastError); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// DeleteOutbox removes a delivered notification.
+func (s *MessageStore) DeleteOutbox(id int64) error {
+	_, err := s.db.Exec(`DELETE FROM outbox WHERE id = ?`, id)
+	return err
+}
+
+// RecordOutboxFailure counts a failed replay of a queued notification.
+func (s *MessageStore) RecordOutboxFailure(id int64, reason string) error {
+	_, err := s.db.Exec(`UPDATE outbox SET attempts = attempts + 1, last_error = ? WHERE id = ?`, reason, id)
+	return err
+}
+
+// ExpireOutbox drops notifications queued before cutoff and returns how many
+// were dropped.
+func (s *MessageStore) ExpireOutbox(cutoff time.Time) (int64, error) {
+	res, err := s.db.Exec(`DELETE FROM outbox WHERE created_at < ?`, cutoff.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// OutboxDepth returns the number of queued notifications per sink.
+func (s *MessageStore) OutboxDepth() (map[string]int64, error) {
+	rows, err := s.db.Query(`SELECT sink, COUNT(*) FROM outbox GROUP BY sink`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	depth := map[string]int64{}
+	for rows.Next() {
+		var sink string
+		var n int64
+		if err := rows.Scan(&sink, &n); err != nil {
+			return nil, err
+		}
+		depth[sink] = n
+	}
+	return depth, rows.Err()
+}
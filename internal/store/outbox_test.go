@@ -0,0 +1,46 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxQueuesPerSinkInOrder(t *testing.T) {
+	s := setupTestDB(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, s.EnqueueOutbox(OutboxItem{Sink: "event_bus", Target: "wa.messages", Key: "c1", Payload: []byte("one"), CreatedAt: now}))
+	require.NoError(t, s.EnqueueOutbox(OutboxItem{Sink: "notifier", Target: "whatsapp:messages", Payload: []byte("two"), CreatedAt: now}))
+	require.NoError(t, s.EnqueueOutbox(OutboxItem{Sink: "event_bus", Target: "wa.receipts", Key: "c1", Payload: []byte("three"), CreatedAt: now.Add(time.Minute)}))
+
+	depth, err := s.OutboxDepth()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"event_bus": 2, "notifier": 1}, depth)
+
+	items, err := s.PendingOutbox("event_bus", 10)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "wa.messages", items[0].Target)
+	assert.Equal(t, "c1", items[0].Key)
+	assert.Equal(t, []byte("one"), items[0].Payload)
+	assert.Equal(t, []byte("three"), items[1].Payload)
+
+	require.NoError(t, s.RecordOutboxFailure(items[0].ID, "connection refused"))
+	items, err = s.PendingOutbox("event_bus", 1)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, 1, items[0].Attempts)
+	assert.Equal(t, "connection refused", items[0].LastError)
+
+	require.NoError(t, s.DeleteOutbox(items[0].ID))
+	n, err := s.ExpireOutbox(now.Add(30 * time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n, "only the notifier item is older than the cutoff")
+
+	depth, err = s.OutboxDepth()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"event_bus": 1}, depth)
+}
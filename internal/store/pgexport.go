@@ -0,0 +1,246 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PostgresTableSummary reports how many rows of a table were exported.
+type PostgresTableSummary struct {
+	Table string `json:"table"`
+	Rows  int64  `json:"rows"`
+}
+
+// ExportProgress is called while a table is copied, with the rows written so
+// far and the table's total.
+type ExportProgress func(table string, done, total int64)
+
+// progressEvery is how many rows are copied between progress callbacks.
+const progressEvery = 10000
+
+// ExportPostgres writes a psql script that recreates every table of the
+// message database in Postgres and loads its rows with COPY. All tables are
+// read in one transaction, so the dump is a consistent snapshot. The script
+// runs in a single transaction and ends by checking each table's row count
+// against the source, raising an error (and rolling back) on a mismatch.
+func (s *MessageStore) ExportPostgres(ctx context.Context, out io.Writer, progress ExportProgress) ([]PostgresTableSummary, error) {
+	tables, err := s.Schema()
+	if err != nil {
+		return nil, err
+	}
+	// chats first: messages reference it
+	sort.SliceStable(tables, func(i, j int) bool {
+		return tables[i].Name == "chats" && tables[j].Name != "chats"
+	})
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "-- whatsapp-cli messages.db export, %s\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintln(w, "-- Load with: psql \"$DATABASE_URL\" -v ON_ERROR_STOP=1 -f <file>")
+	fmt.Fprintln(w, "BEGIN;")
+
+	summary := make([]PostgresTableSummary, 0, len(tables))
+	for _, t := range tables {
+		var total int64
+		if err := tx.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %q", t.Name)).Scan(&total); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(w, "\n%s\n", postgresCreateTable(t))
+		n, err := copyTable(ctx, tx, w, t, total, progress)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", t.Name, err)
+		}
+		if n != total {
+			return nil, fmt.Errorf("failed to export %s: copied %d of %d rows", t.Name, n, total)
+		}
+		if pk, ok := identityColumn(t); ok {
+			fmt.Fprintf(w, "SELECT setval(pg_get_serial_sequence('%s', '%s'), COALESCE(MAX(%s), 0) + 1, false) FROM %s;\n",
+				t.Name, pk, pgIdent(pk), pgIdent(t.Name))
+		}
+		summary = append(summary, PostgresTableSummary{Table: t.Name, Rows: n})
+	}
+
+	fmt.Fprintln(w, "\n-- Verify row counts against the source database")
+	fmt.Fprintln(w, "DO $$")
+	fmt.Fprintln(w, "DECLARE n bigint;")
+	fmt.Fprintln(w, "BEGIN")
+	for _, t := range summary {
+		fmt.Fprintf(w, "  SELECT COUNT(*) INTO n FROM %s;\n", pgIdent(t.Table))
+		fmt.Fprintf(w, "  IF n <> %d THEN RAISE EXCEPTION '%s: expected %d rows, found %%', n; END IF;\n", t.Rows, t.Table, t.Rows)
+	}
+	fmt.Fprintln(w, "END $$;")
+	fmt.Fprintln(w, "\nCOMMIT;")
+
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+func copyTable(ctx context.Context, tx *sql.Tx, w *bufio.Writer, t TableSchema, total int64, progress ExportProgress) (int64, error) {
+	cols := make([]string, len(t.Columns))
+	quoted := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		cols[i] = fmt.Sprintf("%q", c.Name)
+		quoted[i] = pgIdent(c.Name)
+	}
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %q", strings.Join(cols, ", "), t.Name))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	fmt.Fprintf(w, "COPY %s (%s) FROM stdin;\n", pgIdent(t.Name), strings.Join(quoted, ", "))
+	vals := make([]interface{}, len(t.Columns))
+	ptrs := make([]interface{}, len(t.Columns))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	var n int64
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return n, err
+		}
+		for i, v := range vals {
+			if i > 0 {
+				w.WriteByte('\t')
+			}
+			w.WriteString(copyValue(v, pgType(t.Columns[i].Type)))
+		}
+		w.WriteByte('\n')
+		n++
+		if progress != nil && n%progressEvery == 0 {
+			progress(t.Name, n, total)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+	fmt.Fprintln(w, `\.`)
+	if progress != nil {
+		progress(t.Name, n, total)
+	}
+	return n, nil
+}
+
+// postgresCreateTable translates a SQLite table definition. Foreign keys are
+// not recreated, so the load never depends on the source's referential
+// integrity.
+func postgresCreateTable(t TableSchema) string {
+	var defs, pks []string
+	identity, hasIdentity := identityColumn(t)
+	for _, c := range t.Columns {
+		def := pgIdent(c.Name) + " " + pgType(c.Type)
+		if hasIdentity && c.Name == identity {
+			def += " GENERATED BY DEFAULT AS IDENTITY"
+		}
+		if c.NotNull {
+			def += " NOT NULL"
+		}
+		defs = append(defs, def)
+		if c.PrimaryKey {
+			pks = append(pks, pgIdent(c.Name))
+		}
+	}
+	if len(pks) > 0 {
+		defs = append(defs, "PRIMARY KEY ("+strings.Join(pks, ", ")+")")
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n  %s\n);", pgIdent(t.Name), strings.Join(defs, ",\n  "))
+}
+
+// identityColumn returns the single INTEGER primary key of t, which SQLite
+// treats as an auto-incrementing rowid.
+func identityColumn(t TableSchema) (string, bool) {
+	var pk []ColumnSchema
+	for _, c := range t.Columns {
+		if c.PrimaryKey {
+			pk = append(pk, c)
+		}
+	}
+	if len(pk) == 1 && strings.EqualFold(pk[0].Type, "INTEGER") {
+		return pk[0].Name, true
+	}
+	return "", false
+}
+
+// pgType maps a SQLite declared type to a Postgres column type, following
+// SQLite's type affinity rules.
+func pgType(sqliteType string) string {
+	t := strings.ToUpper(sqliteType)
+	switch {
+	case strings.Contains(t, "INT"):
+		return "bigint"
+	case strings.Contains(t, "BOOL"):
+		return "boolean"
+	case strings.Contains(t, "TIMESTAMP"), strings.Contains(t, "DATE"):
+		return "timestamptz"
+	case strings.Contains(t, "BLOB"), t == "":
+		return "bytea"
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		return "double precision"
+	default:
+		return "text"
+	}
+}
+
+func pgIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// copyValue formats v for COPY's text format.
+func copyValue(v interface{}, pgType string) string {
+	switch x := v.(type) {
+	case nil:
+		return `\N`
+	case []byte:
+		if pgType == "bytea" {
+			return `\\x` + hex.EncodeToString(x)
+		}
+		return copyEscape(string(x))
+	case string:
+		if pgType == "bytea" {
+			return `\\x` + hex.EncodeToString([]byte(x))
+		}
+		return copyEscape(x)
+	case bool:
+		if x {
+			return "t"
+		}
+		return "f"
+	case int64:
+		if pgType == "boolean" {
+			if x != 0 {
+				return "t"
+			}
+			return "f"
+		}
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case time.Time:
+		return x.UTC().Format(time.RFC3339Nano)
+	default:
+		return copyEscape(fmt.Sprint(x))
+	}
+}
+
+func copyEscape(s string) string {
+	if !strings.ContainsAny(s, "\\\t\n\r") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+	return r.Replace(s)
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportPostgres(t *testing.T) {
+	store := setupTestDB(t)
+	chatJID := "123@s.whatsapp.net"
+	ts := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, store.StoreChat(chatJID, "Alice", ts))
+	require.NoError(t, store.StoreMessage("m1", chatJID, "123", "line one\nline\ttwo \\ done", ts, false,
+		"image", "", "https://mmg.example", "/v/t62", "image/jpeg", []byte{0xde, 0xad}, nil, nil, 2048))
+	require.NoError(t, store.StoreMessage("m2", chatJID, "me", "hi", ts, true, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, store.StoreChatEvent(ChatEvent{ChatJID: chatJID, Type: ChatEventJoinRequest, Target: "456", Timestamp: ts}))
+
+	var progressed []string
+	var buf bytes.Buffer
+	summary, err := store.ExportPostgres(context.Background(), &buf, func(table string, done, total int64) {
+		progressed = append(progressed, table)
+	})
+	require.NoError(t, err)
+
+	counts := map[string]int64{}
+	for _, s := range summary {
+		counts[s.Table] = s.Rows
+	}
+	assert.Equal(t, "chats", summary[0].Table, "chats is loaded before messages")
+	assert.Equal(t, int64(1), counts["chats"])
+	assert.Equal(t, int64(2), counts["messages"])
+	assert.Equal(t, int64(1), counts["chat_events"])
+	assert.Contains(t, progressed, "messages")
+
+	dump := buf.String()
+	assert.Contains(t, dump, "BEGIN;\n")
+	assert.Contains(t, dump, `"media_key" bytea`)
+	assert.Contains(t, dump, `"timestamp" timestamptz`)
+	assert.Contains(t, dump, `"is_from_me" boolean`)
+	assert.Contains(t, dump, `PRIMARY KEY ("id", "chat_jid")`)
+	assert.Contains(t, dump, `"id" bigint GENERATED BY DEFAULT AS IDENTITY`)
+	assert.Contains(t, dump, "pg_get_serial_sequence('chat_events', 'id')")
+	assert.Contains(t, dump, "COPY \"chats\" (\"jid\", \"name\", \"last_message_time\", \"reconciled_at\") FROM stdin;\n123@s.whatsapp.net\tAlice\t2026-03-01T12:00:00Z\t\\N\n\\.\n")
+
+	// Escaped text, bytea hex, booleans and NULLs in the message row
+	assert.Contains(t, dump, "m1\t123@s.whatsapp.net\t123\tline one\\nline\\ttwo \\\\ done\t2026-03-01T12:00:00Z\tf\timage\t")
+	assert.Contains(t, dump, "\t\\\\xdead\t\\N\t")
+	assert.Contains(t, dump, "m2\t123@s.whatsapp.net\tme\thi\t2026-03-01T12:00:00Z\tt\t")
+
+	assert.Contains(t, dump, `IF n <> 2 THEN RAISE EXCEPTION 'messages: expected 2 rows, found %', n; END IF;`)
+	assert.True(t, bytes.HasSuffix(buf.Bytes(), []byte("COMMIT;\n")))
+}
+
+func TestPgType(t *testing.T) {
+	for sqliteType, want := range map[string]string{
+		"TEXT":                       "text",
+		"INTEGER":                    "bigint",
+		"BOOLEAN NOT NULL DEFAULT 0": "boolean",
+		"TIMESTAMP":                  "timestamptz",
+		"BLOB":                       "bytea",
+		"REAL":                       "double precision",
+	} {
+		assert.Equal(t, want, pgType(sqliteType), sqliteType)
+	}
+}
@@ -0,0 +1,169 @@
+package store
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Poll is a poll created in a chat, by the account or someone else.
+type Poll struct {
+	MessageID string
+	ChatJID   string
+	Question  string
+	Options   []string
+	// SelectableCount is how many options a voter may pick, 0 meaning any
+	// number.
+	SelectableCount int
+	CreatedAt       time.Time
+}
+
+// PollVote is the latest vote of a voter in a poll. Votes name options by
+// the hex SHA-256 of their name, so they can be stored before the poll is.
+type PollVote struct {
+	PollID       string
+	ChatJID      string
+	Voter        string
+	OptionHashes []string
+	VotedAt      time.Time
+}
+
+// PollResults are the votes of a poll, counted by option.
+type PollResults struct {
+	MessageID       string              `json:"message_id"`
+	ChatJID         string              `json:"chat_jid"`
+	Question        string              `json:"question"`
+	SelectableCount int                 `json:"selectable_count"`
+	CreatedAt       time.Time           `json:"created_at"`
+	Options         []PollOptionResults `json:"options"`
+	// Voters counts the voters with a vote for at least one option.
+	Voters int `json:"voters"`
+}
+
+// PollOptionResults are the votes for one option of a poll.
+type PollOptionResults struct {
+	Name   string   `json:"name"`
+	Votes  int      `json:"votes"`
+	Voters []string `json:"voters"`
+}
+
+// StorePoll records a poll; storing it again updates it.
+func (s *MessageStore) StorePoll(p Poll) error {
+	options, err := json.Marshal(p.Options)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO polls (message_id, chat_jid, question, options, selectable_count, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(message_id, chat_jid) DO UPDATE SET
+			question = excluded.question,
+			options = excluded.options,
+			selectable_count = excluded.selectable_count`,
+		p.MessageID, s.resolve(p.ChatJID), p.Question, string(options), p.SelectableCount, p.CreatedAt.UTC(),
+	)
+	return err
+}
+
+// StorePollVote records a vote, replacing the voter's earlier one unless it
+// is older. A vote without options retracts the earlier one.
+func (s *MessageStore) StorePollVote(v PollVote) error {
+	hashes, err := json.Marshal(v.OptionHashes)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO poll_votes (poll_id, chat_jid, voter, option_hashes, voted_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(poll_id, chat_jid, voter) DO UPDATE SET
+			option_hashes = excluded.option_hashes,
+			voted_at = excluded.voted_at
+		WHERE excluded.voted_at >= poll_votes.voted_at`,
+		v.PollID, s.resolve(v.ChatJID), v.Voter, string(hashes), v.VotedAt.UTC(),
+	)
+	return err
+}
+
+// GetPollResults counts the votes of the poll sent as message id. chatJID
+// is required when several chats have a message with that ID.
+func (s *MessageStore) GetPollResults(id string, chatJID *string) (PollResults, error) {
+	query := `SELECT message_id, chat_jid, question, options, selectable_count, created_at FROM polls WHERE message_id = ?`
+	args := []any{id}
+	if chatJID != nil {
+		query += " AND chat_jid = ?"
+		args = append(args, s.resolve(*chatJID))
+	}
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return PollResults{}, err
+	}
+	var polls []PollResults
+	var options []string
+	for rows.Next() {
+		var r PollResults
+		var encoded string
+		if err := rows.Scan(&r.MessageID, &r.ChatJID, &r.Question, &encoded, &r.SelectableCount, &r.CreatedAt); err != nil {
+			rows.Close()
+			return PollResults{}, err
+		}
+		if err := json.Unmarshal([]byte(encoded), &options); err != nil {
+			rows.Close()
+			return PollResults{}, fmt.Errorf("reading options of poll %s: %w", r.MessageID, err)
+		}
+		polls = append(polls, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return PollResults{}, err
+	}
+	if len(polls) == 0 {
+		return PollResults{}, sql.ErrNoRows
+	}
+	if len(polls) > 1 {
+		return PollResults{}, fmt.Errorf("multiple polls found with ID %s; specify chat JID", id)
+	}
+
+	r := polls[0]
+	byHash := map[string]int{}
+	r.Options = make([]PollOptionResults, len(options))
+	for i, name := range options {
+		sum := sha256.Sum256([]byte(name))
+		byHash[hex.EncodeToString(sum[:])] = i
+		r.Options[i] = PollOptionResults{Name: name, Voters: []string{}}
+	}
+
+	votes, err := s.db.Query(
+		`SELECT voter, option_hashes FROM poll_votes WHERE poll_id = ? AND chat_jid = ? ORDER BY voted_at, voter`,
+		r.MessageID, r.ChatJID,
+	)
+	if err != nil {
+		return PollResults{}, err
+	}
+	defer votes.Close()
+	for votes.Next() {
+		var voter, encoded string
+		if err := votes.Scan(&voter, &encoded); err != nil {
+			return PollResults{}, err
+		}
+		var hashes []string
+		if err := json.Unmarshal([]byte(encoded), &hashes); err != nil {
+			return PollResults{}, fmt.Errorf("reading vote of %s in poll %s: %w", voter, r.MessageID, err)
+		}
+		counted := false
+		for _, h := range hashes {
+			// Votes for options the poll does not have are ignored
+			if i, ok := byHash[h]; ok {
+				r.Options[i].Votes++
+				r.Options[i].Voters = append(r.Options[i].Voters, voter)
+				counted = true
+			}
+		}
+		if counted {
+			r.Voters++
+		}
+	}
+	return r, votes.Err()
+}
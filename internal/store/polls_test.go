@@ -0,0 +1,59 @@
+package store
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func optionHash(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestPollResults(t *testing.T) {
+	s := setupTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	chat := "123@g.us"
+
+	// A vote can arrive before the poll it is for
+	require.NoError(t, s.StorePollVote(PollVote{PollID: "p1", ChatJID: chat, Voter: "1", OptionHashes: []string{optionHash("Pizza")}, VotedAt: now}))
+	require.NoError(t, s.StorePoll(Poll{MessageID: "p1", ChatJID: chat, Question: "Lunch?", Options: []string{"Pizza", "Sushi", "Salad"}, CreatedAt: now.Add(-time.Minute)}))
+	require.NoError(t, s.StorePollVote(PollVote{PollID: "p1", ChatJID: chat, Voter: "2", OptionHashes: []string{optionHash("Pizza"), optionHash("Sushi")}, VotedAt: now}))
+	// Changed votes replace earlier ones, but not later ones
+	require.NoError(t, s.StorePollVote(PollVote{PollID: "p1", ChatJID: chat, Voter: "3", OptionHashes: []string{optionHash("Salad")}, VotedAt: now}))
+	require.NoError(t, s.StorePollVote(PollVote{PollID: "p1", ChatJID: chat, Voter: "3", OptionHashes: []string{optionHash("Sushi")}, VotedAt: now.Add(time.Second)}))
+	require.NoError(t, s.StorePollVote(PollVote{PollID: "p1", ChatJID: chat, Voter: "3", OptionHashes: []string{optionHash("Salad")}, VotedAt: now.Add(-time.Second)}))
+	// Retracted votes and votes for unknown options are not counted
+	require.NoError(t, s.StorePollVote(PollVote{PollID: "p1", ChatJID: chat, Voter: "4", OptionHashes: []string{optionHash("Pizza")}, VotedAt: now}))
+	require.NoError(t, s.StorePollVote(PollVote{PollID: "p1", ChatJID: chat, Voter: "4", OptionHashes: []string{}, VotedAt: now.Add(time.Second)}))
+	require.NoError(t, s.StorePollVote(PollVote{PollID: "p1", ChatJID: chat, Voter: "5", OptionHashes: []string{optionHash("Tacos")}, VotedAt: now}))
+
+	r, err := s.GetPollResults("p1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Lunch?", r.Question)
+	assert.Equal(t, chat, r.ChatJID)
+	assert.Equal(t, 3, r.Voters)
+	assert.Equal(t, []PollOptionResults{
+		{Name: "Pizza", Votes: 2, Voters: []string{"1", "2"}},
+		{Name: "Sushi", Votes: 2, Voters: []string{"2", "3"}},
+		{Name: "Salad", Votes: 0, Voters: []string{}},
+	}, r.Options)
+
+	_, err = s.GetPollResults("p2", nil)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	other := "456@g.us"
+	require.NoError(t, s.StorePoll(Poll{MessageID: "p1", ChatJID: other, Question: "Dinner?", Options: []string{"Yes", "No"}, CreatedAt: now}))
+	_, err = s.GetPollResults("p1", nil)
+	assert.ErrorContains(t, err, "specify chat JID")
+	r, err = s.GetPollResults("p1", &other)
+	require.NoError(t, err)
+	assert.Equal(t, "Dinner?", r.Question)
+	assert.Zero(t, r.Voters)
+}
@@ -0,0 +1,26 @@
+package store
+
+import "database/sql"
+
+// QuotedMessage is a stored message a reply can quote.
+type QuotedMessage struct {
+	ID        string
+	ChatJID   string
+	Sender    string
+	Content   string
+	IsFromMe  bool
+	MediaType string
+}
+
+// QuotedMessage looks up a message of a chat to quote in a reply;
+// sql.ErrNoRows means the chat has no message with that ID.
+func (s *MessageStore) QuotedMessage(messageID, chatJID string) (QuotedMessage, error) {
+	q := QuotedMessage{ID: messageID, ChatJID: s.resolve(chatJID)}
+	var mediaType sql.NullString
+	err := s.db.QueryRow(
+		`SELECT sender, COALESCE(content, ''), is_from_me, media_type FROM messages WHERE id = ? AND chat_jid = ?`,
+		messageID, q.ChatJID,
+	).Scan(&q.Sender, &q.Content, &q.IsFromMe, &mediaType)
+	q.MediaType = mediaType.String
+	return q, err
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// OpenReadOnly opens an existing message database without writing to it,
+// e.g. a snapshot or a Litestream restore served by a read-only replica.
+// The schema is not migrated, so the copy must come from a primary running
+// the same or a newer version; an older one is rejected up front rather than
+// failing query by query.
+func OpenReadOnly(dbPath string) (*MessageStore, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db, err := sql.Open(sqliteDriver, fmt.Sprintf("file:%s?mode=ro", dbPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+	missing, err := missingSchema(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if len(missing) > 0 {
+		db.Close()
+		return nil, fmt.Errorf("%s was written by an older version and lacks %s; run this version against a writable copy once to migrate it", dbPath, strings.Join(missing, ", "))
+	}
+	return &MessageStore{db: db}, nil
+}
+
+// missingSchema lists the tables and columns ("table.column") of a current
+// store that db lacks, comparing it against a freshly created one.
+func missingSchema(db *sql.DB) ([]string, error) {
+	dir, err := os.MkdirTemp("", "whatsapp-cli-schema-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	ref, err := NewMessageStore(filepath.Join(dir, "messages.db"))
+	if err != nil {
+		return nil, err
+	}
+	defer ref.Close()
+
+	want, err := schemaColumns(ref.db)
+	if err != nil {
+		return nil, err
+	}
+	have, err := schemaColumns(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database schema: %w", err)
+	}
+	var missing []string
+	for table, columns := range want {
+		got, ok := have[table]
+		if !ok {
+			missing = append(missing, table)
+			continue
+		}
+		for column := range columns {
+			if !got[column] {
+				missing = append(missing, table+"."+column)
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
+
+// schemaColumns returns the columns of every table in db.
+func schemaColumns(db *sql.DB) (map[string]map[string]bool, error) {
+	rows, err := db.Query(`SELECT m.name, p.name FROM sqlite_master m, pragma_table_info(m.name) p WHERE m.type = 'table'`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tables := make(map[string]map[string]bool)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, err
+		}
+		if tables[table] == nil {
+			tables[table] = make(map[string]bool)
+		}
+		tables[table][column] = true
+	}
+	return tables, rows.Err()
+}
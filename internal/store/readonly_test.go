@@ -0,0 +1,56 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.db")
+	primary, err := NewMessageStore(path)
+	require.NoError(t, err)
+	require.NoError(t, primary.StoreChat("111@s.whatsapp.net", "Alice", time.Now()))
+	require.NoError(t, primary.StoreMessage("m1", "111@s.whatsapp.net", "111@s.whatsapp.net", "hello", time.Now(), false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, primary.Close())
+
+	replica, err := OpenReadOnly(path)
+	require.NoError(t, err)
+	defer replica.Close()
+
+	messages, err := replica.ListMessages(ListMessagesParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "hello", messages[0].Content)
+
+	require.NoError(t, replica.Snapshot(context.Background(), filepath.Join(t.TempDir(), "copy.db")), "backups work from a replica")
+
+	err = replica.StoreChat("222@s.whatsapp.net", "Bob", time.Now())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "readonly")
+}
+
+func TestOpenReadOnly_Missing(t *testing.T) {
+	_, err := OpenReadOnly(filepath.Join(t.TempDir(), "messages.db"))
+	assert.Error(t, err)
+}
+
+func TestOpenReadOnly_OldSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.db")
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s", path))
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE chats (jid TEXT PRIMARY KEY, name TEXT, last_message_time TIMESTAMP)`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	_, err = OpenReadOnly(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "older version")
+	assert.Contains(t, err.Error(), "messages")
+}
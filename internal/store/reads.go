@@ -0,0 +1,109 @@
+package store
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// ChatRead is how far a chat was read: its incoming messages up to
+// ReadUntil are read, the Unread ones after it are not.
+type ChatRead struct {
+	ChatJID string `json:"chat_jid"`
+	// ReadUntil is nil for chats never marked read, whose incoming messages
+	// all count as unread.
+	ReadUntil *time.Time `json:"read_until,omitempty"`
+	Unread    int        `json:"unread"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// ReadRef is an incoming message a read receipt is sent for.
+type ReadRef struct {
+	ID        string
+	Sender    string
+	Timestamp time.Time
+}
+
+// unreadCondition selects the incoming messages of m.chat_jid after the
+// point its chat was read up to.
+const unreadCondition = `m.is_from_me = 0 AND julianday(m.timestamp) > COALESCE(
+	(SELECT julianday(r.read_until) FROM chat_reads r WHERE r.chat_jid = m.chat_jid), 0)`
+
+// ChatReadState returns how far a chat was read.
+func (s *MessageStore) ChatReadState(chatJID string) (ChatRead, error) {
+	r := ChatRead{ChatJID: s.resolve(chatJID)}
+	var until, updated sql.NullTime
+	err := s.db.QueryRow(`SELECT read_until, updated_at FROM chat_reads WHERE chat_jid = ?`, r.ChatJID).Scan(&until, &updated)
+	if err != nil && err != sql.ErrNoRows {
+		return r, err
+	}
+	if until.Valid {
+		r.ReadUntil, r.UpdatedAt = &until.Time, &updated.Time
+	}
+	err = s.db.QueryRow(`SELECT COUNT(*) FROM messages m WHERE m.chat_jid = ? AND `+unreadCondition, r.ChatJID).Scan(&r.Unread)
+	return r, err
+}
+
+// UnreadMessages lists the unread incoming messages of a chat, at most
+// limit of the newest.
+func (s *MessageStore) UnreadMessages(chatJID string, limit int) ([]ReadRef, error) {
+	return s.readRefs(`SELECT id, COALESCE(sender, ''), timestamp FROM messages m
+		WHERE m.chat_jid = ? AND `+unreadCondition+` ORDER BY m.timestamp DESC LIMIT ?`,
+		s.resolve(chatJID), limit)
+}
+
+// IncomingMessages returns the messages among ids that the chat received,
+// rather than the account sent.
+func (s *MessageStore) IncomingMessages(chatJID string, ids []string) ([]ReadRef, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	args := []interface{}{s.resolve(chatJID)}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return s.readRefs(`SELECT id, COALESCE(sender, ''), timestamp FROM messages
+		WHERE chat_jid = ? AND is_from_me = 0 AND id IN (?`+strings.Repeat(", ?", len(ids)-1)+`)
+		ORDER BY timestamp DESC`, args...)
+}
+
+func (s *MessageStore) readRefs(query string, args ...interface{}) ([]ReadRef, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var refs []ReadRef
+	for rows.Next() {
+		var r ReadRef
+		if err := rows.Scan(&r.ID, &r.Sender, &r.Timestamp); err != nil {
+			return nil, err
+		}
+		refs = append(refs, r)
+	}
+	return refs, rows.Err()
+}
+
+// MarkChatRead records that a chat was read up to until. The point never
+// moves back, so marking an older message read changes nothing.
+func (s *MessageStore) MarkChatRead(chatJID string, until, at time.Time) error {
+	_, err := s.db.Exec(
+		`INSERT INTO chat_reads (chat_jid, read_until, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(chat_jid) DO UPDATE SET
+			read_until = CASE WHEN julianday(excluded.read_until) > julianday(chat_reads.read_until) THEN excluded.read_until ELSE chat_reads.read_until END,
+			updated_at = excluded.updated_at`,
+		s.resolve(chatJID), until.UTC(), at.UTC(),
+	)
+	return err
+}
+
+// MarkMessagesRead records that a chat was read up to the newest of the
+// messages ids, e.g. when the account read them on another device. Unknown
+// messages are ignored.
+func (s *MessageStore) MarkMessagesRead(chatJID string, ids []string, at time.Time) error {
+	refs, err := s.IncomingMessages(chatJID, ids)
+	if err != nil || len(refs) == 0 {
+		return err
+	}
+	return s.MarkChatRead(chatJID, refs[0].Timestamp, at)
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatReadState(t *testing.T) {
+	s := setupTestDB(t)
+	chat := "1234@s.whatsapp.net"
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, s.StoreChat(chat, "John", base))
+	for i, id := range []string{"in1", "out1", "in2", "in3"} {
+		require.NoError(t, s.StoreMessage(id, chat, "1234", id, base.Add(time.Duration(i)*time.Minute), id == "out1",
+			"", "", "", "", "", nil, nil, nil, 0))
+	}
+
+	state, err := s.ChatReadState(chat)
+	require.NoError(t, err)
+	assert.Nil(t, state.ReadUntil)
+	assert.Equal(t, 3, state.Unread)
+
+	unread, err := s.UnreadMessages(chat, 2)
+	require.NoError(t, err)
+	require.Len(t, unread, 2)
+	assert.Equal(t, "in3", unread[0].ID)
+	assert.Equal(t, "1234", unread[0].Sender)
+
+	// Sent messages are not read; the newest incoming one sets the point
+	require.NoError(t, s.MarkMessagesRead(chat, []string{"out1", "in2", "in1", "missing"}, base.Add(time.Hour)))
+	state, err = s.ChatReadState(chat)
+	require.NoError(t, err)
+	require.NotNil(t, state.ReadUntil)
+	assert.True(t, state.ReadUntil.Equal(base.Add(2*time.Minute)))
+	assert.Equal(t, 1, state.Unread)
+
+	// Reading an older message does not move the point back
+	require.NoError(t, s.MarkChatRead(chat, base, base.Add(2*time.Hour)))
+	state, err = s.ChatReadState(chat)
+	require.NoError(t, err)
+	assert.True(t, state.ReadUntil.Equal(base.Add(2*time.Minute)))
+	assert.True(t, state.UpdatedAt.Equal(base.Add(2*time.Hour)))
+
+	require.NoError(t, s.MarkChatRead(chat, base.Add(3*time.Minute), base.Add(3*time.Hour)))
+	state, err = s.ChatReadState(chat)
+	require.NoError(t, err)
+	assert.Equal(t, 0, state.Unread)
+}
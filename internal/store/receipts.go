@@ -0,0 +1,155 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Receipt types recorded for messages of the account, in the order a
+// message goes through them. Played is for voice notes and videos.
+const (
+	ReceiptDelivered = "delivered"
+	ReceiptRead      = "read"
+	ReceiptPlayed    = "played"
+)
+
+// StatusSent is the status of a message of the account no receipt arrived
+// for yet; the others are named after the receipt types.
+const StatusSent = "sent"
+
+// Receipt is a delivery, read or played receipt a recipient sent for a
+// message. In groups each member sends their own.
+type Receipt struct {
+	Recipient string    `json:"recipient"`
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// MessageStatus is how far a message of the account got, with the time of
+// the first receipt of each type and all receipts, oldest first.
+type MessageStatus struct {
+	ID          string     `json:"id"`
+	ChatJID     string     `json:"chat_jid"`
+	Status      string     `json:"status"`
+	SentAt      time.Time  `json:"sent_at"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	ReadAt      *time.Time `json:"read_at,omitempty"`
+	PlayedAt    *time.Time `json:"played_at,omitempty"`
+	Receipts    []Receipt  `json:"receipts"`
+}
+
+// messageStatus names the furthest receipt a message got, or StatusSent;
+// messages the account received have no status.
+func messageStatus(isFromMe bool, deliveredAt, readAt, playedAt *time.Time) string {
+	switch {
+	case !isFromMe:
+		return ""
+	case playedAt != nil:
+		return ReceiptPlayed
+	case readAt != nil:
+		return ReceiptRead
+	case deliveredAt != nil:
+		return ReceiptDelivered
+	}
+	return StatusSent
+}
+
+// RecordReceipt stores a receipt recipient sent for messages ids of the
+// account and moves their status forward. A message read was delivered too,
+// and one played was read, even when those receipts never came. Only the
+// first receipt of each type per recipient is kept.
+func (s *MessageStore) RecordReceipt(chatJID, recipient string, ids []string, receiptType string, at time.Time) error {
+	chatJID = s.resolve(chatJID)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, id := range ids {
+		if _, err := tx.Exec(
+			`INSERT INTO message_receipts (message_id, chat_jid, recipient, type, timestamp) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(message_id, chat_jid, recipient, type) DO NOTHING`,
+			id, chatJID, recipient, receiptType, at,
+		); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(receiptStatusUpdate, at, receiptType, at, receiptType, at, id, chatJID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// receiptStatusUpdate moves the status of a message of the account forward
+// with one receipt.
+const receiptStatusUpdate = `UPDATE messages SET
+	delivered_at = COALESCE(delivered_at, ?),
+	read_at = CASE WHEN ? IN ('read', 'played') THEN COALESCE(read_at, ?) ELSE read_at END,
+	played_at = CASE WHEN ? = 'played' THEN COALESCE(played_at, ?) ELSE played_at END
+	WHERE id = ? AND chat_jid = ? AND is_from_me = 1`
+
+// applyEarlyReceipts moves the status of a message of the account forward
+// with the receipts recorded before it was stored: WhatsApp can acknowledge
+// a message before the send that created it has returned.
+func (s *MessageStore) applyEarlyReceipts(id, chatJID string) error {
+	rows, err := s.db.Query(
+		`SELECT type, timestamp FROM message_receipts WHERE message_id = ? AND chat_jid = ? ORDER BY julianday(timestamp)`,
+		id, chatJID,
+	)
+	if err != nil {
+		return err
+	}
+	var receipts []Receipt
+	for rows.Next() {
+		var r Receipt
+		if err := rows.Scan(&r.Type, &r.Timestamp); err != nil {
+			rows.Close()
+			return err
+		}
+		receipts = append(receipts, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, r := range receipts {
+		if _, err := s.db.Exec(receiptStatusUpdate, r.Timestamp, r.Type, r.Timestamp, r.Type, r.Timestamp, id, chatJID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MessageStatus returns the status of a message with its receipts;
+// sql.ErrNoRows means there is no such message.
+func (s *MessageStore) MessageStatus(messageID, chatJID string) (MessageStatus, error) {
+	st := MessageStatus{ID: messageID, ChatJID: s.resolve(chatJID), Receipts: []Receipt{}}
+	var isFromMe bool
+	var delivered, read, played sql.NullTime
+	err := s.db.QueryRow(
+		`SELECT timestamp, is_from_me, delivered_at, read_at, played_at FROM messages WHERE id = ? AND chat_jid = ?`,
+		messageID, st.ChatJID,
+	).Scan(&st.SentAt, &isFromMe, &delivered, &read, &played)
+	if err != nil {
+		return st, err
+	}
+	st.DeliveredAt, st.ReadAt, st.PlayedAt = nullTimePtr(delivered), nullTimePtr(read), nullTimePtr(played)
+	st.Status = messageStatus(isFromMe, st.DeliveredAt, st.ReadAt, st.PlayedAt)
+
+	rows, err := s.db.Query(
+		`SELECT recipient, type, timestamp FROM message_receipts WHERE message_id = ? AND chat_jid = ? ORDER BY julianday(timestamp), recipient`,
+		messageID, st.ChatJID,
+	)
+	if err != nil {
+		return st, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r Receipt
+		if err := rows.Scan(&r.Recipient, &r.Type, &r.Timestamp); err != nil {
+			return st, err
+		}
+		st.Receipts = append(st.Receipts, r)
+	}
+	return st, rows.Err()
+}
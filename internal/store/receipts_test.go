@@ -0,0 +1,68 @@
+package store
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageReceipts(t *testing.T) {
+	s := setupTestDB(t)
+	group := "120363000000000001@g.us"
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, s.StoreChat(group, "Team", base))
+	require.NoError(t, s.StoreMessage("out1", group, "me", "hello all", base, true, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, s.StoreMessage("in1", group, "111", "hi", base.Add(time.Minute), false, "", "", "", "", "", nil, nil, nil, 0))
+
+	st, err := s.MessageStatus("out1", group)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSent, st.Status)
+	assert.Empty(t, st.Receipts)
+
+	alice, bob := "111@s.whatsapp.net", "222@s.whatsapp.net"
+	require.NoError(t, s.RecordReceipt(group, alice, []string{"out1"}, ReceiptDelivered, base.Add(time.Second)))
+	require.NoError(t, s.RecordReceipt(group, alice, []string{"out1"}, ReceiptDelivered, base.Add(time.Hour)), "repeats keep the first")
+	// Bob's read arrives without a delivery receipt
+	require.NoError(t, s.RecordReceipt(group, bob, []string{"out1", "in1"}, ReceiptRead, base.Add(2*time.Second)))
+
+	st, err = s.MessageStatus("out1", group)
+	require.NoError(t, err)
+	assert.Equal(t, ReceiptRead, st.Status)
+	require.NotNil(t, st.DeliveredAt)
+	assert.True(t, st.DeliveredAt.Equal(base.Add(time.Second)))
+	require.NotNil(t, st.ReadAt)
+	assert.True(t, st.ReadAt.Equal(base.Add(2*time.Second)))
+	assert.Nil(t, st.PlayedAt)
+	require.Len(t, st.Receipts, 2)
+	assert.Equal(t, Receipt{Recipient: alice, Type: ReceiptDelivered, Timestamp: st.Receipts[0].Timestamp}, st.Receipts[0])
+	assert.Equal(t, bob, st.Receipts[1].Recipient)
+
+	msgs, err := s.ListMessages(ListMessagesParams{ChatJID: &group, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Empty(t, msgs[0].Status, "received messages have no status")
+	assert.Nil(t, msgs[0].ReadAt)
+	assert.Equal(t, ReceiptRead, msgs[1].Status)
+	require.NotNil(t, msgs[1].ReadAt)
+
+	_, err = s.MessageStatus("missing", group)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestReceiptBeforeMessageIsStored(t *testing.T) {
+	s := setupTestDB(t)
+	chat := "111@s.whatsapp.net"
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, s.StoreChat(chat, "Alice", base))
+	require.NoError(t, s.RecordReceipt(chat, chat, []string{"out1"}, ReceiptDelivered, base.Add(time.Second)))
+	require.NoError(t, s.StoreMessage("out1", chat, "me", "hi", base, true, "", "", "", "", "", nil, nil, nil, 0))
+
+	st, err := s.MessageStatus("out1", chat)
+	require.NoError(t, err)
+	assert.Equal(t, ReceiptDelivered, st.Status)
+	require.NotNil(t, st.DeliveredAt)
+	assert.True(t, st.DeliveredAt.Equal(base.Add(time.Second)))
+}
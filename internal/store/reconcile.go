@@ -0,0 +1,58 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ChatReconciliation summarises ReconcileChats.
+type ChatReconciliation struct {
+	Known   int `json:"known"`
+	Created int `json:"created"`
+	Renamed int `json:"renamed"`
+}
+
+// ReconcileChats makes the store know of the chats the phone lists, given as
+// JID to name: missing chats are created without a last message time, so
+// they sort after every chat with messages, and names that changed are
+// updated. Every listed chat is stamped with at as reconciled_at, which keeps
+// store doctor from removing it while it has no messages. Chats the phone no
+// longer lists are kept.
+func (s *MessageStore) ReconcileChats(names map[string]string, at time.Time) (ChatReconciliation, error) {
+	result := ChatReconciliation{Known: len(names)}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	for chatJID, name := range names {
+		chatJID = s.resolve(chatJID)
+		var current sql.NullString
+		err := tx.QueryRow(`SELECT name FROM chats WHERE jid = ?`, chatJID).Scan(&current)
+		switch {
+		case err == sql.ErrNoRows:
+			if name == "" {
+				name = chatJID
+			}
+			if _, err := tx.Exec(`INSERT INTO chats (jid, name, reconciled_at) VALUES (?, ?, ?)`, chatJID, name, at); err != nil {
+				return result, err
+			}
+			result.Created++
+		case err != nil:
+			return result, err
+		default:
+			renamed := name != "" && name != current.String
+			if !renamed {
+				name = current.String
+			}
+			if _, err := tx.Exec(`UPDATE chats SET name = ?, reconciled_at = ? WHERE jid = ?`, name, at, chatJID); err != nil {
+				return result, err
+			}
+			if renamed {
+				result.Renamed++
+			}
+		}
+	}
+	return result, tx.Commit()
+}
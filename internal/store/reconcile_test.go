@@ -0,0 +1,59 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcileChats(t *testing.T) {
+	store := setupTestDB(t)
+	base := time.Now().UTC().Truncate(time.Second)
+	alice := "4915100000001@s.whatsapp.net"
+	bob := "4915100000002@s.whatsapp.net"
+	group := "120363000000000001@g.us"
+
+	require.NoError(t, store.StoreChat(alice, alice, base))
+	require.NoError(t, store.StoreMessage("m1", alice, alice, "hi", base, false, "", "", "", "", "", nil, nil, nil, 0))
+
+	result, err := store.ReconcileChats(map[string]string{alice: "Alice", bob: "Bob", group: ""}, base.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, ChatReconciliation{Known: 3, Created: 2, Renamed: 1}, result)
+
+	chats, err := store.ListChats(ListChatsParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, chats, 3)
+	assert.Equal(t, alice, chats[0].JID, "chats with messages come first")
+	assert.Equal(t, "Alice", chats[0].Name)
+	require.NotNil(t, chats[0].LastMessageTime)
+	assert.True(t, base.Equal(*chats[0].LastMessageTime))
+	names := map[string]string{}
+	for _, c := range chats[1:] {
+		assert.Nil(t, c.LastMessageTime, c.JID)
+		names[c.JID] = c.Name
+	}
+	assert.Equal(t, map[string]string{bob: "Bob", group: group}, names)
+
+	// Unchanged names are not counted again
+	result, err = store.ReconcileChats(map[string]string{alice: "Alice", bob: ""}, base.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, ChatReconciliation{Known: 2}, result)
+
+	// A first message sets the last message time of a reconciled chat
+	require.NoError(t, store.StoreChat(bob, "Bob", base.Add(3*time.Hour)))
+	chats, err = store.ListChats(ListChatsParams{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, chats, 1)
+	assert.Equal(t, bob, chats[0].JID)
+	require.NotNil(t, chats[0].LastMessageTime)
+	assert.True(t, base.Add(3*time.Hour).Equal(*chats[0].LastMessageTime))
+
+	// The doctor keeps reconciled chats without messages
+	issues, err := store.Diagnose(true)
+	require.NoError(t, err)
+	for _, issue := range issues {
+		assert.NotEqual(t, "empty_chats", issue.Check)
+	}
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Reminder sends a message to its recipients ahead of each event of a
+// calendar.
+type Reminder struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	CalendarURL string    `json:"calendar_url"`
+	Recipients  []string  `json:"recipients"`
+	Template    string    `json:"template"`
+	LeadMinutes int       `json:"lead_minutes"`
+	Timezone    string    `json:"timezone"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateReminder saves a reminder and returns its ID.
+func (s *MessageStore) CreateReminder(r Reminder) (int64, error) {
+	recipients, err := json.Marshal(r.Recipients)
+	if err != nil {
+		return 0, err
+	}
+	res, err := s.db.Exec(
+		`INSERT INTO reminders (name, calendar_url, recipients, template, lead_minutes, timezone, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		r.Name, r.CalendarURL, string(recipients), r.Template, r.LeadMinutes, r.Timezone, r.CreatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// Reminders lists every reminder, oldest first.
+func (s *MessageStore) Reminders() ([]Reminder, error) {
+	rows, err := s.db.Query(`SELECT id, name, calendar_url, recipients, template, lead_minutes, timezone, created_at
+		FROM reminders ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	reminders := []Reminder{}
+	for rows.Next() {
+		r, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, r)
+	}
+	return reminders, rows.Err()
+}
+
+// GetReminder returns a reminder, or sql.ErrNoRows.
+func (s *MessageStore) GetReminder(id int64) (Reminder, error) {
+	return scanReminder(s.db.QueryRow(`SELECT id, name, calendar_url, recipients, template, lead_minutes, timezone, created_at
+		FROM reminders WHERE id = ?`, id))
+}
+
+func scanReminder(row interface{ Scan(...any) error }) (Reminder, error) {
+	var r Reminder
+	var recipients string
+	if err := row.Scan(&r.ID, &r.Name, &r.CalendarURL, &recipients, &r.Template, &r.LeadMinutes, &r.Timezone, &r.CreatedAt); err != nil {
+		return Reminder{}, err
+	}
+	if err := json.Unmarshal([]byte(recipients), &r.Recipients); err != nil {
+		return Reminder{}, err
+	}
+	return r, nil
+}
+
+// DeleteReminder removes a reminder and the record of what it sent. It
+// returns sql.ErrNoRows if the reminder does not exist.
+func (s *MessageStore) DeleteReminder(id int64) error {
+	res, err := s.db.Exec(`DELETE FROM reminders WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	_, err = s.db.Exec(`DELETE FROM reminder_sends WHERE reminder_id = ?`, id)
+	return err
+}
+
+// ClaimReminderSend records that a reminder is being sent for an occurrence
+// of an event. It reports false if it already was, so each occurrence is
+// only reminded once.
+func (s *MessageStore) ClaimReminderSend(reminderID int64, eventUID string, eventStart, at time.Time) (bool, error) {
+	res, err := s.db.Exec(
+		`INSERT INTO reminder_sends (reminder_id, event_uid, event_start, sent_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT DO NOTHING`,
+		reminderID, eventUID, eventStart.UTC(), at,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// PruneReminderSends forgets the sends for events that started before
+// cutoff, which can no longer be reminded.
+func (s *MessageStore) PruneReminderSends(cutoff time.Time) error {
+	_, err := s.db.Exec(`DELETE FROM reminder_sends WHERE event_start < ?`, cutoff.UTC())
+	return err
+}
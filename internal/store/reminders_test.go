@@ -0,0 +1,59 @@
+package store
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReminders(t *testing.T) {
+	s := setupTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	id, err := s.CreateReminder(Reminder{
+		Name:        "Appointments",
+		CalendarURL: "https://cal.example.com/salon.ics",
+		Recipients:  []string{"34600111222@s.whatsapp.net"},
+		Template:    "See you at {{.Start}}",
+		LeadMinutes: 60,
+		Timezone:    "Europe/Madrid",
+		CreatedAt:   now,
+	})
+	require.NoError(t, err)
+
+	r, err := s.GetReminder(id)
+	require.NoError(t, err)
+	assert.Equal(t, "Appointments", r.Name)
+	assert.Equal(t, []string{"34600111222@s.whatsapp.net"}, r.Recipients)
+	assert.Equal(t, 60, r.LeadMinutes)
+	assert.True(t, r.CreatedAt.Equal(now))
+
+	all, err := s.Reminders()
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, id, all[0].ID)
+
+	start := now.Add(time.Hour)
+	claimed, err := s.ClaimReminderSend(id, "ev-1", start, now)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = s.ClaimReminderSend(id, "ev-1", start, now)
+	require.NoError(t, err)
+	assert.False(t, claimed, "claimed twice")
+	claimed, err = s.ClaimReminderSend(id, "ev-1", start.AddDate(0, 0, 7), now)
+	require.NoError(t, err)
+	assert.True(t, claimed, "another occurrence of the event")
+
+	require.NoError(t, s.PruneReminderSends(start.Add(time.Minute)))
+	claimed, err = s.ClaimReminderSend(id, "ev-1", start, now)
+	require.NoError(t, err)
+	assert.True(t, claimed, "pruned send")
+
+	require.NoError(t, s.DeleteReminder(id))
+	assert.ErrorIs(t, s.DeleteReminder(id), sql.ErrNoRows)
+	_, err = s.GetReminder(id)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
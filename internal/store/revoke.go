@@ -0,0 +1,61 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RevokeTarget is a message looked up to be revoked.
+type RevokeTarget struct {
+	ID        string
+	ChatJID   string
+	IsFromMe  bool
+	DeletedAt *time.Time
+}
+
+// RevokeTarget looks up a message in the chats the JID filters allow.
+// Without a chat JID the ID must be unique; sql.ErrNoRows means no message
+// matched.
+func (s *MessageStore) RevokeTarget(messageID string, chatJID *string, includeJIDs, excludeJIDs []string) (RevokeTarget, error) {
+	query := `SELECT id, chat_jid, is_from_me, deleted_at FROM messages WHERE id = ?`
+	args := []interface{}{messageID}
+	if chatJID != nil {
+		query += " AND chat_jid = ?"
+		args = append(args, s.resolve(*chatJID))
+	}
+	query, args = appendJIDFilter(query, args, "chat_jid", includeJIDs, excludeJIDs)
+	query += " LIMIT 2"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return RevokeTarget{}, err
+	}
+	defer rows.Close()
+	var targets []RevokeTarget
+	for rows.Next() {
+		var t RevokeTarget
+		var deletedAt sql.NullTime
+		if err := rows.Scan(&t.ID, &t.ChatJID, &t.IsFromMe, &deletedAt); err != nil {
+			return RevokeTarget{}, err
+		}
+		t.DeletedAt = nullTimePtr(deletedAt)
+		targets = append(targets, t)
+	}
+	if err := rows.Err(); err != nil {
+		return RevokeTarget{}, err
+	}
+	switch len(targets) {
+	case 0:
+		return RevokeTarget{}, sql.ErrNoRows
+	case 1:
+		return targets[0], nil
+	}
+	return RevokeTarget{}, fmt.Errorf("multiple messages found with ID %s; specify chat JID", messageID)
+}
+
+// MarkMessageDeleted records that a message was revoked at the given time.
+func (s *MessageStore) MarkMessageDeleted(messageID, chatJID string, at time.Time) error {
+	_, err := s.db.Exec(`UPDATE messages SET deleted_at = ? WHERE id = ? AND chat_jid = ?`, at, messageID, s.resolve(chatJID))
+	return err
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevokeTarget(t *testing.T) {
+	s := setupTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	alice, group := "1@s.whatsapp.net", "120363000000000001@g.us"
+	require.NoError(t, s.StoreChat(alice, "Alice", now))
+	require.NoError(t, s.StoreChat(group, "Team", now))
+	require.NoError(t, s.StoreMessage("m1", alice, "me", "oops", now, true, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, s.StoreMessage("m2", alice, "1", "hi", now, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, s.StoreMessage("m2", group, "2", "hi all", now, false, "", "", "", "", "", nil, nil, nil, 0))
+
+	target, err := s.RevokeTarget("m1", nil, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, RevokeTarget{ID: "m1", ChatJID: alice, IsFromMe: true}, target)
+
+	_, err = s.RevokeTarget("m2", nil, nil, nil)
+	assert.ErrorContains(t, err, "specify chat JID")
+	target, err = s.RevokeTarget("m2", &group, nil, nil)
+	require.NoError(t, err)
+	assert.False(t, target.IsFromMe)
+	_, err = s.RevokeTarget("m2", nil, nil, []string{"@g.us"})
+	assert.NoError(t, err, "the filtered chat does not make the ID ambiguous")
+	_, err = s.RevokeTarget("m1", nil, []string{"@g.us"}, nil)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.NoError(t, s.MarkMessageDeleted("m1", alice, now))
+	target, err = s.RevokeTarget("m1", nil, nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, target.DeletedAt)
+	assert.True(t, now.Equal(*target.DeletedAt))
+
+	messages, err := s.ListMessages(ListMessagesParams{ChatJID: &alice, Limit: 10})
+	require.NoError(t, err)
+	for _, m := range messages {
+		assert.Equal(t, m.ID == "m1", m.DeletedAt != nil, m.ID)
+	}
+}
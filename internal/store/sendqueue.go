@@ -0,0 +1,70 @@
+package store
+
+import (
+	"strings"
+	"time"
+)
+
+// QueuedSend is a message held back by quiet hours until it may be sent.
+type QueuedSend struct {
+	ID             int64     `json:"id"`
+	Recipient      string    `json:"recipient"`
+	Message        string    `json:"message"`
+	SimulateTyping bool      `json:"simulate_typing"`
+	QueuedAt       time.Time `json:"queued_at"`
+	// QuotedMessageID is the message the queued one replies to, if any.
+	QuotedMessageID string `json:"quoted_message_id,omitempty"`
+	// Mentions are the JIDs of the users the queued message mentions.
+	Mentions []string `json:"mentions,omitempty"`
+}
+
+// QueueSend stores a message to be sent later and returns its queue ID.
+func (s *MessageStore) QueueSend(q QueuedSend) (int64, error) {
+	res, err := s.db.Exec(
+		`INSERT INTO send_queue (recipient, message, simulate_typing, queued_at, quoted_message_id, mentions) VALUES (?, ?, ?, ?, ?, ?)`,
+		q.Recipient, q.Message, q.SimulateTyping, q.QueuedAt.UTC(), q.QuotedMessageID, strings.Join(q.Mentions, ","),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// ListQueuedSends returns up to limit queued messages, oldest first.
+func (s *MessageStore) ListQueuedSends(limit int) ([]QueuedSend, error) {
+	rows, err := s.db.Query(
+		`SELECT id, recipient, message, simulate_typing, queued_at, COALESCE(quoted_message_id, ''), COALESCE(mentions, '') FROM send_queue ORDER BY id LIMIT ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	queued := []QueuedSend{}
+	for rows.Next() {
+		var q QueuedSend
+		var mentions string
+		if err := rows.Scan(&q.ID, &q.Recipient, &q.Message, &q.SimulateTyping, &q.QueuedAt, &q.QuotedMessageID, &mentions); err != nil {
+			return nil, err
+		}
+		if mentions != "" {
+			q.Mentions = strings.Split(mentions, ",")
+		}
+		queued = append(queued, q)
+	}
+	return queued, rows.Err()
+}
+
+// DeleteQueuedSend removes a message from the send queue once it was sent.
+func (s *MessageStore) DeleteQueuedSend(id int64) error {
+	_, err := s.db.Exec(`DELETE FROM send_queue WHERE id = ?`, id)
+	return err
+}
+
+// CountQueuedSends returns the number of messages waiting in the send queue.
+func (s *MessageStore) CountQueuedSends() (int64, error) {
+	var n int64
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM send_queue`).Scan(&n)
+	return n, err
+}
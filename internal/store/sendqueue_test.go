@@ -0,0 +1,51 @@
+package store
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendQueue(t *testing.T) {
+	s := setupTestDB(t)
+	at := time.Date(2026, 3, 2, 23, 0, 0, 0, time.UTC)
+
+	first, err := s.QueueSend(QueuedSend{Recipient: "111", Message: "one", QueuedAt: at})
+	require.NoError(t, err)
+	_, err = s.QueueSend(QueuedSend{Recipient: "222", Message: "two", SimulateTyping: true, QueuedAt: at, QuotedMessageID: "M1", Mentions: []string{"1@s.whatsapp.net", "2@s.whatsapp.net"}})
+	require.NoError(t, err)
+
+	queued, err := s.ListQueuedSends(10)
+	require.NoError(t, err)
+	require.Len(t, queued, 2)
+	assert.Equal(t, "one", queued[0].Message)
+	assert.True(t, queued[1].SimulateTyping)
+	assert.Empty(t, queued[0].QuotedMessageID)
+	assert.Equal(t, "M1", queued[1].QuotedMessageID)
+	assert.Nil(t, queued[0].Mentions)
+	assert.Equal(t, []string{"1@s.whatsapp.net", "2@s.whatsapp.net"}, queued[1].Mentions)
+	assert.True(t, at.Equal(queued[1].QueuedAt))
+
+	require.NoError(t, s.DeleteQueuedSend(first))
+	n, err := s.CountQueuedSends()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
+
+func TestQuotedMessage(t *testing.T) {
+	s := setupTestDB(t)
+	now := time.Now().UTC()
+	chat := "111@s.whatsapp.net"
+	require.NoError(t, s.StoreChat(chat, "Alice", now))
+	require.NoError(t, s.StoreMessage("M1", chat, "111", "lunch at 1?", now, false, "", "", "", "", "", nil, nil, nil, 0))
+
+	q, err := s.QuotedMessage("M1", chat)
+	require.NoError(t, err)
+	assert.Equal(t, QuotedMessage{ID: "M1", ChatJID: chat, Sender: "111", Content: "lunch at 1?"}, q)
+
+	_, err = s.QuotedMessage("M1", "222@s.whatsapp.net")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
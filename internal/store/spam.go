@@ -0,0 +1,77 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// IsNewSender reports whether sender has no earlier message in the chat.
+func (s *MessageStore) IsNewSender(chatJID, sender string, before time.Time) (bool, error) {
+	var exists int
+	err := s.db.QueryRow(
+		`SELECT 1 FROM messages WHERE chat_jid = ? AND sender = ? AND timestamp < ? LIMIT 1`,
+		chatJID, sender, before,
+	).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	return false, err
+}
+
+// ListQuarantine returns messages scoring at or above threshold that have not
+// been released, newest first.
+func (s *MessageStore) ListQuarantine(threshold, limit, page int, includeJIDs, excludeJIDs []string) ([]Message, error) {
+	query := `SELECT m.id, m.chat_jid, c.name, m.sender, m.content, m.timestamp, m.is_from_me, m.media_type, COALESCE(m.lang, ''),
+	          COALESCE(m.spam_score, 0), COALESCE(m.spam_flags, ''), m.sender_device, m.identity_changed_at, ci.changed_at, ci.verified_at, m.deleted_at,
+	          COALESCE(m.media_scan, ''), COALESCE(m.media_threat, ''), m.delivered_at, m.read_at, m.played_at
+	          FROM messages m JOIN chats c ON m.chat_jid = c.jid
+	          LEFT JOIN contact_identities ci ON ci.sender = m.sender
+	          WHERE m.spam_score >= ? AND m.spam_released = 0`
+	args := []interface{}{threshold}
+
+	query, args = appendJIDFilter(query, args, "m.chat_jid", includeJIDs, excludeJIDs)
+
+	query += " ORDER BY m.timestamp DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, page*limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMessages(rows)
+}
+
+// ReleaseQuarantined marks a message as reviewed so it shows up in default
+// listings again. It returns sql.ErrNoRows if the message does not exist.
+func (s *MessageStore) ReleaseQuarantined(id string, chatJID *string) error {
+	query := `UPDATE messages SET spam_released = 1 WHERE id = ?`
+	args := []interface{}{id}
+	if chatJID != nil {
+		query += " AND chat_jid = ?"
+		args = append(args, *chatJID)
+	} else {
+		var count int
+		if err := s.db.QueryRow(`SELECT COUNT(*) FROM messages WHERE id = ?`, id).Scan(&count); err != nil {
+			return err
+		}
+		if count > 1 {
+			return fmt.Errorf("multiple messages found with ID %s; specify chat JID", id)
+		}
+	}
+
+	res, err := s.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
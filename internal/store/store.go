@@ -20,6 +20,34 @@ type Message struct {
 	Timestamp time.Time `json:"timestamp"`
 	IsFromMe  bool      `json:"is_from_me"`
 	MediaType string    `json:"media_type,omitempty"`
+	Lang      string    `json:"lang,omitempty"`
+	SpamScore int       `json:"spam_score,omitempty"`
+	SpamFlags []string  `json:"spam_flags,omitempty"`
+	// SenderDevice is the device the message was sent from: 0 for the
+	// sender's phone, higher for linked devices. Unknown for messages from
+	// history sync.
+	SenderDevice *int `json:"sender_device,omitempty"`
+	// IdentityChangedAt is set when the sender's identity key had changed
+	// shortly before the message arrived.
+	IdentityChangedAt *time.Time `json:"identity_changed_at,omitempty"`
+	// Verification is the status of the sender's identity key, one of the
+	// Verification constants; empty for messages of the account.
+	Verification string `json:"verification,omitempty"`
+	// DeletedAt is set once the account revoked the message for everyone.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// MediaScan is the result of scanning the media for malware, one of the
+	// MediaScan constants; empty until it was scanned.
+	MediaScan string `json:"media_scan,omitempty"`
+	// MediaThreat names what the scan found in infected media.
+	MediaThreat string `json:"media_threat,omitempty"`
+	// Status is how far a message of the account got: "sent", "delivered",
+	// "read" or "played", with the time of the first receipt of each type.
+	// In groups the first member to send one counts. Empty for messages the
+	// account received.
+	Status      string     `json:"status,omitempty"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	ReadAt      *time.Time `json:"read_at,omitempty"`
+	PlayedAt    *time.Time `json:"played_at,omitempty"`
 }
 
 type Chat struct {
@@ -28,7 +56,9 @@ type Chat struct {
 	Type            string    `json:"type"`                // "individual", "group", or "lid"
 	Phone           string    `json:"phone,omitempty"`     // only for individual chats
 	GroupID         string    `json:"group_id,omitempty"`  // only for group chats
-	LastMessageTime time.Time `json:"last_message_time"`
+	// LastMessageTime is nil for chats reconciled from the phone that have
+	// no messages in the store yet.
+	LastMessageTime *time.Time `json:"last_message_time"`
 	LastMessage     *string   `json:"last_message,omitempty"`
 	LastSender      *string   `json:"last_sender,omitempty"`
 	LastIsFromMe    *bool     `json:"last_is_from_me,omitempty"`
@@ -59,6 +89,8 @@ type MessageDownloadInfo struct {
 	FileLength    uint64
 	LocalPath     *string
 	DownloadedAt  *time.Time
+	MediaScan     string
+	MediaThreat   string
 	Sender        string
 	Content       string
 	MessageTime   time.Time
@@ -71,10 +103,16 @@ type ListMessagesParams struct {
 	Sender      *string
 	ChatJID     *string
 	Query       *string
+	Lang        *string
+	// Tag restricts the listing to messages carrying this tag.
+	Tag         *string
 	Limit       int
 	Page        int
 	IncludeJIDs []string
 	ExcludeJIDs []string
+	// HideSpamFrom excludes messages with a spam score at or above this value
+	// unless they were released from quarantine. 0 disables the filter.
+	HideSpamFrom int
 }
 
 type ListChatsParams struct {
@@ -98,7 +136,7 @@ func NewMessageStore(dbPath string) (*MessageStore, error) {
 		return nil, fmt.Errorf("failed to create directory: %v", err)
 	}
 
-	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on", dbPath))
+	db, err := sql.Open(sqliteDriver, fmt.Sprintf("file:%s?_foreign_keys=on", dbPath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %v", err)
 	}
@@ -132,6 +170,220 @@ func NewMessageStore(dbPath string) (*MessageStore, error) {
 			PRIMARY KEY (id, chat_jid),
 			FOREIGN KEY (chat_jid) REFERENCES chats(jid)
 		);
+
+		CREATE TABLE IF NOT EXISTS groups (
+			jid TEXT PRIMARY KEY,
+			subject TEXT,
+			description TEXT,
+			announce BOOLEAN NOT NULL DEFAULT 0,
+			locked BOOLEAN NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS chat_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			chat_jid TEXT NOT NULL,
+			type TEXT NOT NULL,
+			actor TEXT,
+			target TEXT,
+			timestamp TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_chat_events_chat ON chat_events(chat_jid, timestamp);
+
+		CREATE TABLE IF NOT EXISTS away_config (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			enabled BOOLEAN NOT NULL DEFAULT 0,
+			message TEXT NOT NULL DEFAULT '',
+			timezone TEXT NOT NULL DEFAULT 'UTC',
+			windows TEXT NOT NULL DEFAULT '[]'
+		);
+
+		CREATE TABLE IF NOT EXISTS away_optouts (
+			chat_jid TEXT PRIMARY KEY,
+			created_at TIMESTAMP NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS away_replies (
+			chat_jid TEXT NOT NULL,
+			window_start INTEGER NOT NULL,
+			sent_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (chat_jid, window_start)
+		);
+
+		CREATE TABLE IF NOT EXISTS chat_modes (
+			chat_jid TEXT PRIMARY KEY,
+			mode TEXT NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS chat_aliases (
+			alias_jid TEXT PRIMARY KEY,
+			jid TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_chat_aliases_jid ON chat_aliases(jid);
+
+		CREATE TABLE IF NOT EXISTS outbox (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			sink TEXT NOT NULL,
+			target TEXT NOT NULL,
+			routing_key TEXT NOT NULL DEFAULT '',
+			payload BLOB NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT
+		);
+		CREATE INDEX IF NOT EXISTS idx_outbox_sink ON outbox(sink, id);
+
+		CREATE TABLE IF NOT EXISTS send_queue (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			recipient TEXT NOT NULL,
+			message TEXT NOT NULL,
+			simulate_typing BOOLEAN NOT NULL DEFAULT 0,
+			queued_at TIMESTAMP NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS drafts (
+			chat_jid TEXT PRIMARY KEY,
+			text TEXT NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS chat_reads (
+			chat_jid TEXT PRIMARY KEY,
+			read_until TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS message_receipts (
+			message_id TEXT NOT NULL,
+			chat_jid TEXT NOT NULL,
+			recipient TEXT NOT NULL,
+			type TEXT NOT NULL,
+			timestamp TIMESTAMP NOT NULL,
+			PRIMARY KEY (message_id, chat_jid, recipient, type)
+		);
+
+		CREATE TABLE IF NOT EXISTS message_chain (
+			chat_jid TEXT NOT NULL,
+			seq INTEGER NOT NULL,
+			message_id TEXT NOT NULL,
+			hash TEXT NOT NULL,
+			chained_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (chat_jid, seq),
+			UNIQUE (chat_jid, message_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS digests (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			chat_jid TEXT NOT NULL,
+			period TEXT NOT NULL,
+			period_start TIMESTAMP NOT NULL,
+			period_end TIMESTAMP NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			data TEXT NOT NULL,
+			UNIQUE (chat_jid, period, period_end)
+		);
+
+		CREATE TABLE IF NOT EXISTS message_embeddings (
+			message_id TEXT NOT NULL,
+			chat_jid TEXT NOT NULL,
+			model TEXT NOT NULL,
+			vector BLOB NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (message_id, chat_jid)
+		);
+
+		CREATE TABLE IF NOT EXISTS message_tags (
+			message_id TEXT NOT NULL,
+			chat_jid TEXT NOT NULL,
+			tag TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (message_id, chat_jid, tag)
+		);
+		CREATE INDEX IF NOT EXISTS idx_message_tags_tag ON message_tags(tag, created_at);
+
+		CREATE TABLE IF NOT EXISTS reminders (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			calendar_url TEXT NOT NULL,
+			recipients TEXT NOT NULL,
+			template TEXT NOT NULL,
+			lead_minutes INTEGER NOT NULL,
+			timezone TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS reminder_sends (
+			reminder_id INTEGER NOT NULL,
+			event_uid TEXT NOT NULL,
+			event_start TIMESTAMP NOT NULL,
+			sent_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (reminder_id, event_uid, event_start)
+		);
+
+		CREATE TABLE IF NOT EXISTS feed_items (
+			feed_url TEXT NOT NULL,
+			chat_jid TEXT NOT NULL,
+			item_id TEXT NOT NULL,
+			seen_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (feed_url, chat_jid, item_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS followup_notices (
+			chat_jid TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			notified_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (chat_jid, message_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS contact_identities (
+			sender TEXT PRIMARY KEY,
+			changed_at TIMESTAMP,
+			verified_at TIMESTAMP,
+			security_code TEXT
+		);
+
+		CREATE TABLE IF NOT EXISTS polls (
+			message_id TEXT NOT NULL,
+			chat_jid TEXT NOT NULL,
+			question TEXT NOT NULL,
+			options TEXT NOT NULL,
+			selectable_count INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (message_id, chat_jid)
+		);
+
+		CREATE TABLE IF NOT EXISTS poll_votes (
+			poll_id TEXT NOT NULL,
+			chat_jid TEXT NOT NULL,
+			voter TEXT NOT NULL,
+			option_hashes TEXT NOT NULL,
+			voted_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (poll_id, chat_jid, voter)
+		);
+
+		CREATE TABLE IF NOT EXISTS metadata (
+			kind TEXT NOT NULL,
+			jid TEXT NOT NULL,
+			message_id TEXT NOT NULL DEFAULT '',
+			key TEXT NOT NULL,
+			value TEXT NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (kind, jid, message_id, key)
+		);
+		CREATE INDEX IF NOT EXISTS idx_metadata_key ON metadata(kind, key, value);
+
+		CREATE TABLE IF NOT EXISTS account_alerts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			kind TEXT NOT NULL,
+			severity TEXT NOT NULL,
+			code TEXT NOT NULL DEFAULT '',
+			reason TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP,
+			resolved_at TIMESTAMP
+		);
 	`)
 	if err != nil {
 		db.Close()
@@ -142,25 +394,74 @@ func NewMessageStore(dbPath string) (*MessageStore, error) {
 		db.Close()
 		return nil, err
 	}
+	if err := ensureColumns(db, "chats", map[string]string{"reconciled_at": "TIMESTAMP"}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := ensureColumns(db, "chat_events", map[string]string{"request_id": "TEXT"}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := ensureColumns(db, "send_queue", map[string]string{"quoted_message_id": "TEXT", "mentions": "TEXT"}); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	store := &MessageStore{db: db}
+	if err := store.normalizeChatJIDs(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to normalize chat JIDs: %w", err)
+	}
 
-	return &MessageStore{db: db}, nil
+	// Indexes on migrated columns must be created after the columns exist
+	if _, err := db.Exec(`
+		CREATE INDEX IF NOT EXISTS idx_messages_lang ON messages(lang);
+		CREATE INDEX IF NOT EXISTS idx_messages_content_hash ON messages(content_hash);
+		CREATE INDEX IF NOT EXISTS idx_messages_spam_score ON messages(spam_score);
+		CREATE INDEX IF NOT EXISTS idx_messages_timestamp ON messages(timestamp);
+		CREATE INDEX IF NOT EXISTS idx_chats_name ON chats(name COLLATE NOCASE);
+	`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create indexes: %v", err)
+	}
+
+	return store, nil
 }
 
 func ensureMessageColumns(db *sql.DB) error {
 	required := map[string]string{
-		"direct_path":   "TEXT",
-		"mime_type":     "TEXT",
-		"local_path":    "TEXT",
-		"downloaded_at": "TIMESTAMP",
+		"direct_path":         "TEXT",
+		"mime_type":           "TEXT",
+		"local_path":          "TEXT",
+		"downloaded_at":       "TIMESTAMP",
+		"lang":                "TEXT",
+		"content_hash":        "TEXT",
+		"spam_score":          "INTEGER",
+		"spam_flags":          "TEXT",
+		"spam_released":       "BOOLEAN NOT NULL DEFAULT 0",
+		"sender_device":       "INTEGER",
+		"identity_changed_at": "TIMESTAMP",
+		"deleted_at":          "TIMESTAMP",
+		"media_scan":          "TEXT",
+		"media_threat":        "TEXT",
+		"media_scanned_at":    "TIMESTAMP",
+		"delivered_at":        "TIMESTAMP",
+		"read_at":             "TIMESTAMP",
+		"played_at":           "TIMESTAMP",
 	}
 
+	return ensureColumns(db, "messages", required)
+}
+
+// ensureColumns adds any missing columns to table.
+func ensureColumns(db *sql.DB, table string, required map[string]string) error {
 	for column, columnType := range required {
-		exists, err := columnExists(db, "messages", column)
+		exists, err := columnExists(db, table, column)
 		if err != nil {
 			return err
 		}
 		if !exists {
-			if _, err := db.Exec(fmt.Sprintf("ALTER TABLE messages ADD COLUMN %s %s", column, columnType)); err != nil {
+			if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, columnType)); err != nil {
 				// Ignore duplicate column errors for older SQLite versions that don't support IF NOT EXISTS.
 				if !strings.Contains(strings.ToLower(err.Error()), "duplicate") {
 					return fmt.Errorf("failed to add column %s: %w", column, err)
@@ -199,6 +500,7 @@ func (s *MessageStore) Close() error {
 }
 
 func (s *MessageStore) StoreChat(jid, name string, lastMessageTime time.Time) error {
+	jid = s.resolve(jid)
 	_, err := s.db.Exec(
 		`INSERT INTO chats (jid, name, last_message_time) VALUES (?, ?, ?)
 		ON CONFLICT(jid) DO UPDATE SET
@@ -207,14 +509,44 @@ func (s *MessageStore) StoreChat(jid, name string, lastMessageTime time.Time) er
 				WHEN chats.name IS NULL OR chats.name = '' THEN excluded.name
 				ELSE chats.name
 			END,
-			last_message_time = MAX(chats.last_message_time, excluded.last_message_time)`,
+			last_message_time = MAX(COALESCE(chats.last_message_time, excluded.last_message_time), excluded.last_message_time)`,
 		jid, name, lastMessageTime,
 	)
 	return err
 }
 
+// Stats summarises the archive; ChatMessages counts one chat's messages.
+type Stats struct {
+	Chats        int64 `json:"chats"`
+	Messages     int64 `json:"messages"`
+	ChatMessages int64 `json:"chat_messages"`
+}
+
+// Stats counts chats and messages, and the messages in chatJID.
+func (s *MessageStore) Stats(chatJID string) (Stats, error) {
+	chatJID = s.resolve(chatJID)
+	var st Stats
+	err := s.db.QueryRow(
+		`SELECT (SELECT COUNT(*) FROM chats), (SELECT COUNT(*) FROM messages), (SELECT COUNT(*) FROM messages WHERE chat_jid = ?)`,
+		chatJID,
+	).Scan(&st.Chats, &st.Messages, &st.ChatMessages)
+	return st, err
+}
+
+// ChatExists reports whether a chat row exists for jid.
+func (s *MessageStore) ChatExists(jid string) (bool, error) {
+	jid = s.resolve(jid)
+	var exists int
+	err := s.db.QueryRow(`SELECT 1 FROM chats WHERE jid = ?`, jid).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
 func (s *MessageStore) StoreMessage(id, chatJID, sender, content string, timestamp time.Time, isFromMe bool,
 	mediaType, filename, url, directPath, mimeType string, mediaKey, fileSHA256, fileEncSHA256 []byte, fileLength uint64) error {
+	chatJID = s.resolve(chatJID)
 	intFileLength := int64(0)
 	if fileLength > 0 {
 		intFileLength = int64(fileLength)
@@ -240,6 +572,65 @@ func (s *MessageStore) StoreMessage(id, chatJID, sender, content string, timesta
 			file_length = CASE WHEN excluded.file_length > 0 THEN excluded.file_length ELSE messages.file_length END`,
 		id, chatJID, sender, content, timestamp, isFromMe, mediaType, filename, url, directPath, mimeType, mediaKey, fileSHA256, fileEncSHA256, intFileLength,
 	)
+	if err != nil || !isFromMe {
+		return err
+	}
+	return s.applyEarlyReceipts(id, chatJID)
+}
+
+// StoreMessages inserts text messages in a single transaction. It is meant
+// for bulk loads (synthetic data, imports) where per-row commits are too slow;
+// chats must already exist.
+func (s *MessageStore) StoreMessages(msgs []Message) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(
+		`INSERT INTO messages (id, chat_jid, sender, content, timestamp, is_from_me, media_type, lang, content_hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''))
+		ON CONFLICT(id, chat_jid) DO NOTHING`,
+	)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, m := range msgs {
+		if _, err := stmt.Exec(m.ID, m.ChatJID, m.Sender, m.Content, m.Timestamp, m.IsFromMe, m.MediaType, m.Lang, ContentHash(m.Content, nil)); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Enrichment holds metadata derived from a message after it is stored.
+// Empty fields leave the stored value untouched.
+type Enrichment struct {
+	Lang              string
+	ContentHash       string
+	SpamScore         int
+	SpamFlags         []string
+	SenderDevice      *int
+	IdentityChangedAt *time.Time
+}
+
+// EnrichMessage records derived metadata for a message.
+func (s *MessageStore) EnrichMessage(id, chatJID string, e Enrichment) error {
+	_, err := s.db.Exec(
+		`UPDATE messages SET
+			lang = COALESCE(NULLIF(?, ''), lang),
+			content_hash = COALESCE(NULLIF(?, ''), content_hash),
+			spam_score = COALESCE(NULLIF(?, 0), spam_score),
+			spam_flags = COALESCE(NULLIF(?, ''), spam_flags),
+			sender_device = COALESCE(?, sender_device),
+			identity_changed_at = COALESCE(?, identity_changed_at)
+		WHERE id = ? AND chat_jid = ?`,
+		e.Lang, e.ContentHash, e.SpamScore, strings.Join(e.SpamFlags, ","), e.SenderDevice, e.IdentityChangedAt,
+		id, s.resolve(chatJID),
+	)
 	return err
 }
 
@@ -262,8 +653,11 @@ func appendJIDFilter(query string, args []interface{}, column string, includeJID
 }
 
 func (s *MessageStore) ListMessages(params ListMessagesParams) ([]Message, error) {
-	query := `SELECT m.id, m.chat_jid, c.name, m.sender, m.content, m.timestamp, m.is_from_me, m.media_type
-	          FROM messages m JOIN chats c ON m.chat_jid = c.jid WHERE 1=1`
+	query := `SELECT m.id, m.chat_jid, c.name, m.sender, m.content, m.timestamp, m.is_from_me, m.media_type, COALESCE(m.lang, ''),
+	          COALESCE(m.spam_score, 0), COALESCE(m.spam_flags, ''), m.sender_device, m.identity_changed_at, ci.changed_at, ci.verified_at, m.deleted_at,
+	          COALESCE(m.media_scan, ''), COALESCE(m.media_threat, ''), m.delivered_at, m.read_at, m.played_at
+	          FROM messages m JOIN chats c ON m.chat_jid = c.jid
+	          LEFT JOIN contact_identities ci ON ci.sender = m.sender WHERE 1=1`
 	args := []interface{}{}
 
 	if params.After != nil {
@@ -280,11 +674,23 @@ func (s *MessageStore) ListMessages(params ListMessagesParams) ([]Message, error
 	}
 	if params.ChatJID != nil {
 		query += " AND m.chat_jid = ?"
-		args = append(args, *params.ChatJID)
+		args = append(args, s.resolve(*params.ChatJID))
 	}
 	if params.Query != nil {
-		query += " AND LOWER(m.content) LIKE LOWER(?)"
-		args = append(args, "%"+*params.Query+"%")
+		query += " AND fold(m.content) LIKE ?"
+		args = append(args, containsPattern(*params.Query))
+	}
+	if params.Lang != nil {
+		query += " AND m.lang = ?"
+		args = append(args, *params.Lang)
+	}
+	if params.Tag != nil {
+		query += " AND EXISTS (SELECT 1 FROM message_tags t WHERE t.message_id = m.id AND t.chat_jid = m.chat_jid AND t.tag = ?)"
+		args = append(args, *params.Tag)
+	}
+	if params.HideSpamFrom > 0 {
+		query += " AND (COALESCE(m.spam_score, 0) < ? OR m.spam_released = 1)"
+		args = append(args, params.HideSpamFrom)
 	}
 
 	query, args = appendJIDFilter(query, args, "m.chat_jid", params.IncludeJIDs, params.ExcludeJIDs)
@@ -298,24 +704,49 @@ func (s *MessageStore) ListMessages(params ListMessagesParams) ([]Message, error
 	}
 	defer rows.Close()
 
+	return scanMessages(rows)
+}
+
+// scanMessages reads rows selected with the column list used by ListMessages,
+// joined with contact_identities for the verification status of senders.
+func scanMessages(rows *sql.Rows) ([]Message, error) {
 	var messages []Message
 	for rows.Next() {
 		var m Message
-		err := rows.Scan(&m.ID, &m.ChatJID, &m.ChatName, &m.Sender, &m.Content, &m.Timestamp, &m.IsFromMe, &m.MediaType)
+		var spamFlags string
+		var device sql.NullInt64
+		var identityChangedAt, changedAt, verifiedAt, deletedAt, deliveredAt, readAt, playedAt sql.NullTime
+		err := rows.Scan(&m.ID, &m.ChatJID, &m.ChatName, &m.Sender, &m.Content, &m.Timestamp, &m.IsFromMe, &m.MediaType, &m.Lang,
+			&m.SpamScore, &spamFlags, &device, &identityChangedAt, &changedAt, &verifiedAt, &deletedAt, &m.MediaScan, &m.MediaThreat,
+			&deliveredAt, &readAt, &playedAt)
 		if err != nil {
 			return nil, err
 		}
+		if spamFlags != "" {
+			m.SpamFlags = strings.Split(spamFlags, ",")
+		}
+		if device.Valid {
+			d := int(device.Int64)
+			m.SenderDevice = &d
+		}
+		m.IdentityChangedAt = nullTimePtr(identityChangedAt)
+		m.DeletedAt = nullTimePtr(deletedAt)
+		m.DeliveredAt, m.ReadAt, m.PlayedAt = nullTimePtr(deliveredAt), nullTimePtr(readAt), nullTimePtr(playedAt)
+		m.Status = messageStatus(m.IsFromMe, m.DeliveredAt, m.ReadAt, m.PlayedAt)
+		if !m.IsFromMe {
+			m.Verification = verificationStatus(nullTimePtr(changedAt), nullTimePtr(verifiedAt))
+		}
 		messages = append(messages, m)
 	}
 
-	return messages, nil
+	return messages, rows.Err()
 }
 
 func (s *MessageStore) SearchContacts(params SearchContactsParams) ([]Contact, error) {
 	q := `SELECT jid, name FROM chats
-		WHERE (LOWER(name) LIKE LOWER(?) OR LOWER(jid) LIKE LOWER(?))
+		WHERE (fold(name) LIKE ? OR LOWER(jid) LIKE LOWER(?))
 		AND jid NOT LIKE '%@g.us'`
-	args := []interface{}{"%" + params.Query + "%", "%" + params.Query + "%"}
+	args := []interface{}{containsPattern(params.Query), "%" + params.Query + "%"}
 
 	q, args = appendJIDFilter(q, args, "jid", params.IncludeJIDs, params.ExcludeJIDs)
 
@@ -349,8 +780,9 @@ func (s *MessageStore) SearchContacts(params SearchContactsParams) ([]Contact, e
 	return contacts, nil
 }
 
-func (s *MessageStore) GetMessageForDownload(id string, chatJID *string) (MessageDownloadInfo, error) {
-	query := `
+// downloadInfoQuery selects the columns scanDownloadInfo reads, of messages
+// m joined with their chats c.
+const downloadInfoQuery = `
 		SELECT
 			m.id,
 			m.chat_jid,
@@ -369,22 +801,14 @@ func (s *MessageStore) GetMessageForDownload(id string, chatJID *string) (Messag
 			m.file_enc_sha256,
 			COALESCE(m.file_length, 0),
 			m.local_path,
-			m.downloaded_at
+			m.downloaded_at,
+			COALESCE(m.media_scan, ''),
+			COALESCE(m.media_threat, '')
 		FROM messages m
-		LEFT JOIN chats c ON m.chat_jid = c.jid
-		WHERE m.id = ?`
-	args := []interface{}{id}
-	if chatJID != nil {
-		query += " AND m.chat_jid = ?"
-		args = append(args, *chatJID)
-	}
-
-	rows, err := s.db.Query(query, args...)
-	if err != nil {
-		return MessageDownloadInfo{}, err
-	}
-	defer rows.Close()
+		LEFT JOIN chats c ON m.chat_jid = c.jid`
 
+// scanDownloadInfo reads the rows of a downloadInfoQuery.
+func scanDownloadInfo(rows *sql.Rows) ([]MessageDownloadInfo, error) {
 	var infos []MessageDownloadInfo
 	for rows.Next() {
 		var info MessageDownloadInfo
@@ -411,8 +835,10 @@ func (s *MessageStore) GetMessageForDownload(id string, chatJID *string) (Messag
 			&fileLength,
 			&localPath,
 			&downloadedAt,
+			&info.MediaScan,
+			&info.MediaThreat,
 		); err != nil {
-			return MessageDownloadInfo{}, err
+			return nil, err
 		}
 
 		if fileLength.Valid && fileLength.Int64 > 0 {
@@ -429,8 +855,26 @@ func (s *MessageStore) GetMessageForDownload(id string, chatJID *string) (Messag
 
 		infos = append(infos, info)
 	}
+	return infos, rows.Err()
+}
+
+func (s *MessageStore) GetMessageForDownload(id string, chatJID *string) (MessageDownloadInfo, error) {
+	query := downloadInfoQuery + `
+		WHERE m.id = ?`
+	args := []interface{}{id}
+	if chatJID != nil {
+		query += " AND m.chat_jid = ?"
+		args = append(args, s.resolve(*chatJID))
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return MessageDownloadInfo{}, err
+	}
+	defer rows.Close()
 
-	if err := rows.Err(); err != nil {
+	infos, err := scanDownloadInfo(rows)
+	if err != nil {
 		return MessageDownloadInfo{}, err
 	}
 
@@ -449,7 +893,7 @@ func (s *MessageStore) MarkMediaDownloaded(id, chatJID, localPath string, downlo
 		`UPDATE messages
 		 SET local_path = ?, downloaded_at = ?
 		 WHERE id = ? AND chat_jid = ?`,
-		localPath, downloadedAt, id, chatJID,
+		localPath, downloadedAt, id, s.resolve(chatJID),
 	)
 	return err
 }
@@ -475,18 +919,47 @@ func (s *MessageStore) ListAllChatJIDs() ([]string, error) {
 func (s *MessageStore) UpdateChatName(jid, name string) error {
 	_, err := s.db.Exec(
 		`UPDATE chats SET name = ? WHERE jid = ? AND (name IS NULL OR name = '' OR name = jid)`,
-		name, jid,
+		name, s.resolve(jid),
 	)
 	return err
 }
 
+// ChatNames returns the known names of the given chats; chats without a name
+// other than their JID are left out.
+func (s *MessageStore) ChatNames(jids []string) (map[string]string, error) {
+	names := make(map[string]string)
+	if len(jids) == 0 {
+		return names, nil
+	}
+	args := make([]interface{}, len(jids))
+	for i, jid := range jids {
+		args[i] = jid
+	}
+	rows, err := s.db.Query(
+		`SELECT jid, name FROM chats WHERE jid IN (?`+strings.Repeat(", ?", len(jids)-1)+`) AND name != '' AND name != jid`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var jid, name string
+		if err := rows.Scan(&jid, &name); err != nil {
+			return nil, err
+		}
+		names[jid] = name
+	}
+	return names, rows.Err()
+}
+
 func (s *MessageStore) ListChats(params ListChatsParams) ([]Chat, error) {
 	query := "SELECT jid, name, last_message_time FROM chats WHERE 1=1"
 	args := []interface{}{}
 
 	if params.Query != nil {
-		query += " AND (LOWER(name) LIKE LOWER(?) OR jid LIKE ?)"
-		args = append(args, "%"+*params.Query+"%", "%"+*params.Query+"%")
+		query += " AND (fold(name) LIKE ? OR jid LIKE ?)"
+		args = append(args, containsPattern(*params.Query), "%"+*params.Query+"%")
 	}
 
 	query, args = appendJIDFilter(query, args, "jid", params.IncludeJIDs, params.ExcludeJIDs)
@@ -503,9 +976,11 @@ func (s *MessageStore) ListChats(params ListChatsParams) ([]Chat, error) {
 	var chats []Chat
 	for rows.Next() {
 		var c Chat
-		if err := rows.Scan(&c.JID, &c.Name, &c.LastMessageTime); err != nil {
+		var last sql.NullTime
+		if err := rows.Scan(&c.JID, &c.Name, &last); err != nil {
 			return nil, err
 		}
+		c.LastMessageTime = nullTimePtr(last)
 		if idx := strings.Index(c.JID, "@"); idx > 0 {
 			prefix := c.JID[:idx]
 			suffix := c.JID[idx+1:]
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -97,6 +98,31 @@ func TestListMessages(t *testing.T) {
 	assert.Equal(t, "Hello", messages[1].Content)
 }
 
+func TestListMessagesLangFilter(t *testing.T) {
+	store := setupTestDB(t)
+	chatJID := "1234@g.us"
+
+	store.StoreChat(chatJID, "Group", time.Now())
+	now := time.Now()
+	store.StoreMessage("msg1", chatJID, "1234", "Guten Morgen", now, false, "", "", "", "", "", nil, nil, nil, 0)
+	store.StoreMessage("msg2", chatJID, "1234", "Good morning", now.Add(time.Second), false, "", "", "", "", "", nil, nil, nil, 0)
+	store.StoreMessage("msg3", chatJID, "1234", "ok", now.Add(2*time.Second), false, "", "", "", "", "", nil, nil, nil, 0)
+	require.NoError(t, store.EnrichMessage("msg1", chatJID, Enrichment{Lang: "de"}))
+	require.NoError(t, store.EnrichMessage("msg2", chatJID, Enrichment{Lang: "en"}))
+
+	lang := "de"
+	messages, err := store.ListMessages(ListMessagesParams{Lang: &lang, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "msg1", messages[0].ID)
+	assert.Equal(t, "de", messages[0].Lang)
+
+	messages, err = store.ListMessages(ListMessagesParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, messages, 3)
+	assert.Empty(t, messages[0].Lang)
+}
+
 func TestGetMessageForDownload(t *testing.T) {
 	store := setupTestDB(t)
 	chatJID := "1234@s.whatsapp.net"
@@ -168,6 +194,47 @@ func TestSearchContacts(t *testing.T) {
 	assert.Equal(t, "John Doe", contacts[0].Name)
 }
 
+func TestSearchFoldsAccentsCaseAndScripts(t *testing.T) {
+	store := setupTestDB(t)
+	now := time.Now()
+	chatJID := "1234@s.whatsapp.net"
+	require.NoError(t, store.StoreChat(chatJID, "Jürgen Müller", now))
+	require.NoError(t, store.StoreChat("5678@s.whatsapp.net", "José", now))
+	for id, content := range map[string]string{
+		"de":   "Wir fahren mit Über zum Flughafen",
+		"fr":   "CRÈME BRÛLÉE ce soir?",
+		"ar":   "مرحبا مُحَمَّد، كيف حالك؟",
+		"he":   "שָׁלוֹם לכולם",
+		"none": "nothing to see",
+	} {
+		require.NoError(t, store.StoreMessage(id, chatJID, chatJID, content, now, false, "", "", "", "", "", nil, nil, nil, 0))
+	}
+
+	for query, want := range map[string]string{
+		"uber":         "de",
+		"ÜBER":         "de",
+		"creme brulee": "fr",
+		"محمد":         "ar",
+		"שלום":         "he",
+	} {
+		messages, err := store.ListMessages(ListMessagesParams{Query: &query, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, messages, 1, query)
+		assert.Equal(t, want, messages[0].ID, query)
+	}
+
+	contacts, err := store.SearchContacts(SearchContactsParams{Query: "jose"})
+	require.NoError(t, err)
+	require.Len(t, contacts, 1)
+	assert.Equal(t, "José", contacts[0].Name)
+
+	query := "muller"
+	chats, err := store.ListChats(ListChatsParams{Query: &query, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, chats, 1)
+	assert.Equal(t, chatJID, chats[0].JID)
+}
+
 func TestListChats(t *testing.T) {
 	store := setupTestDB(t)
 
@@ -181,6 +248,21 @@ func TestListChats(t *testing.T) {
 	assert.Equal(t, "John Doe", chats[0].Name) // Most recent first
 }
 
+func TestChatNames(t *testing.T) {
+	store := setupTestDB(t)
+
+	store.StoreChat("1234@s.whatsapp.net", "John Doe", time.Now())
+	store.StoreChat("5678@s.whatsapp.net", "5678@s.whatsapp.net", time.Now())
+
+	names, err := store.ChatNames([]string{"1234@s.whatsapp.net", "5678@s.whatsapp.net", "9999@s.whatsapp.net"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"1234@s.whatsapp.net": "John Doe"}, names)
+
+	names, err = store.ChatNames(nil)
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
+
 // --- JID suffix filtering tests ---
 
 func setupFilterTestDB(t *testing.T) *MessageStore {
@@ -345,3 +427,62 @@ func TestSearchContacts_IncludeJIDs_Multiple(t *testing.T) {
 	require.NoError(t, err)
 	assert.Len(t, contacts, 2)
 }
+
+func TestStoreMessagesBulkInsert(t *testing.T) {
+	store := setupTestDB(t)
+	chatJID := "1234@s.whatsapp.net"
+	require.NoError(t, store.StoreChat(chatJID, "John Doe", time.Now()))
+
+	now := time.Now()
+	msgs := []Message{
+		{ID: "b1", ChatJID: chatJID, Sender: "1234", Content: "first", Timestamp: now},
+		{ID: "b2", ChatJID: chatJID, Sender: "1234", Content: "second", Timestamp: now.Add(time.Second)},
+		{ID: "b1", ChatJID: chatJID, Sender: "1234", Content: "duplicate ignored", Timestamp: now},
+	}
+	require.NoError(t, store.StoreMessages(msgs))
+
+	messages, err := store.ListMessages(ListMessagesParams{ChatJID: &chatJID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "second", messages[0].Content)
+	assert.Equal(t, "first", messages[1].Content)
+}
+
+func BenchmarkListMessages(b *testing.B) {
+	store, err := NewMessageStore(filepath.Join(b.TempDir(), "bench.db"))
+	require.NoError(b, err)
+	defer store.Close()
+
+	now := time.Now()
+	var msgs []Message
+	for c := 0; c < 20; c++ {
+		chatJID := fmt.Sprintf("4900000%04d@s.whatsapp.net", c)
+		require.NoError(b, store.StoreChat(chatJID, chatJID, now))
+		for i := 0; i < 500; i++ {
+			msgs = append(msgs, Message{
+				ID:        fmt.Sprintf("m%d-%d", c, i),
+				ChatJID:   chatJID,
+				Sender:    chatJID,
+				Content:   fmt.Sprintf("message %d about topic %d", i, i%17),
+				Timestamp: now.Add(-time.Duration(i) * time.Minute),
+			})
+		}
+	}
+	require.NoError(b, store.StoreMessages(msgs))
+	query := "topic 7"
+
+	b.Run("list", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := store.ListMessages(ListMessagesParams{Limit: 100}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("search", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := store.ListMessages(ListMessagesParams{Query: &query, Limit: 100}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
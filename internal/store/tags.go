@@ -0,0 +1,148 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TagCount is a tag and the number of messages carrying it.
+type TagCount struct {
+	Tag      string `json:"tag"`
+	Messages int    `json:"messages"`
+}
+
+// TagMessage adds tag to a message; tagging it twice is a no-op.
+func (s *MessageStore) TagMessage(messageID, chatJID, tag string, at time.Time) error {
+	_, err := s.db.Exec(
+		`INSERT INTO message_tags (message_id, chat_jid, tag, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT DO NOTHING`,
+		messageID, s.resolve(chatJID), tag, at,
+	)
+	return err
+}
+
+// UntagMessage removes tag from a message. It reports whether the message
+// carried the tag.
+func (s *MessageStore) UntagMessage(messageID, chatJID, tag string) (bool, error) {
+	res, err := s.db.Exec(
+		`DELETE FROM message_tags WHERE message_id = ? AND chat_jid = ? AND tag = ?`,
+		messageID, s.resolve(chatJID), tag,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// MessageTags lists the tags of a message, alphabetically.
+func (s *MessageStore) MessageTags(messageID, chatJID string) ([]string, error) {
+	rows, err := s.db.Query(
+		`SELECT tag FROM message_tags WHERE message_id = ? AND chat_jid = ? ORDER BY tag`,
+		messageID, s.resolve(chatJID),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// MessageChat returns the chat of the message with the given ID. Without a
+// chat JID the ID must be unique; sql.ErrNoRows means no message matched.
+func (s *MessageStore) MessageChat(messageID string, chatJID *string) (string, error) {
+	query := `SELECT chat_jid FROM messages WHERE id = ?`
+	args := []interface{}{messageID}
+	if chatJID != nil {
+		query += " AND chat_jid = ?"
+		args = append(args, s.resolve(*chatJID))
+	}
+	query += " LIMIT 2"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	var chats []string
+	for rows.Next() {
+		var jid string
+		if err := rows.Scan(&jid); err != nil {
+			return "", err
+		}
+		chats = append(chats, jid)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	switch len(chats) {
+	case 0:
+		return "", sql.ErrNoRows
+	case 1:
+		return chats[0], nil
+	}
+	return "", fmt.Errorf("multiple messages found with ID %s; specify chat JID", messageID)
+}
+
+// Tags lists every tag in use, most used first.
+func (s *MessageStore) Tags(includeJIDs, excludeJIDs []string) ([]TagCount, error) {
+	query := `SELECT tag, COUNT(*) FROM message_tags WHERE 1=1`
+	args := []interface{}{}
+	query, args = appendJIDFilter(query, args, "chat_jid", includeJIDs, excludeJIDs)
+	query += " GROUP BY tag ORDER BY COUNT(*) DESC, tag"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tags := []TagCount{}
+	for rows.Next() {
+		var t TagCount
+		if err := rows.Scan(&t.Tag, &t.Messages); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// TaggedMessages returns the messages carrying tag, newest first.
+func (s *MessageStore) TaggedMessages(tag string, params ListMessagesParams) ([]Message, error) {
+	query := `SELECT m.id, m.chat_jid, c.name, m.sender, m.content, m.timestamp, m.is_from_me, m.media_type, COALESCE(m.lang, ''),
+	          COALESCE(m.spam_score, 0), COALESCE(m.spam_flags, ''), m.sender_device, m.identity_changed_at, ci.changed_at, ci.verified_at, m.deleted_at,
+	          COALESCE(m.media_scan, ''), COALESCE(m.media_threat, ''), m.delivered_at, m.read_at, m.played_at
+	          FROM message_tags t
+	          JOIN messages m ON m.id = t.message_id AND m.chat_jid = t.chat_jid
+	          JOIN chats c ON m.chat_jid = c.jid
+	          LEFT JOIN contact_identities ci ON ci.sender = m.sender
+	          WHERE t.tag = ?`
+	args := []interface{}{tag}
+	if params.After != nil {
+		query += " AND m.timestamp > ?"
+		args = append(args, params.After)
+	}
+	if params.HideSpamFrom > 0 {
+		query += " AND (COALESCE(m.spam_score, 0) < ? OR m.spam_released = 1)"
+		args = append(args, params.HideSpamFrom)
+	}
+	query, args = appendJIDFilter(query, args, "m.chat_jid", params.IncludeJIDs, params.ExcludeJIDs)
+	query += " ORDER BY m.timestamp DESC LIMIT ? OFFSET ?"
+	args = append(args, params.Limit, params.Page*params.Limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanMessages(rows)
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTags(t *testing.T) {
+	s := setupTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, s.StoreChat("1@s.whatsapp.net", "Alice", now))
+	require.NoError(t, s.StoreChat("120363000000000001@g.us", "Team", now))
+	require.NoError(t, s.StoreMessage("m1", "1@s.whatsapp.net", "1", "invoice 1", now.Add(-time.Hour), false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, s.StoreMessage("m2", "1@s.whatsapp.net", "1", "invoice 2", now, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, s.StoreMessage("m3", "120363000000000001@g.us", "2", "lunch?", now, false, "", "", "", "", "", nil, nil, nil, 0))
+
+	require.NoError(t, s.TagMessage("m1", "1@s.whatsapp.net", "invoice", now))
+	require.NoError(t, s.TagMessage("m2", "1@s.whatsapp.net", "invoice", now))
+	require.NoError(t, s.TagMessage("m2", "1@s.whatsapp.net", "invoice", now), "tagging twice")
+	require.NoError(t, s.TagMessage("m3", "120363000000000001@g.us", "team", now))
+
+	tags, err := s.Tags(nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []TagCount{{Tag: "invoice", Messages: 2}, {Tag: "team", Messages: 1}}, tags)
+
+	tags, err = s.Tags(nil, []string{"@g.us"})
+	require.NoError(t, err)
+	assert.Equal(t, []TagCount{{Tag: "invoice", Messages: 2}}, tags)
+
+	messages, err := s.TaggedMessages("invoice", ListMessagesParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "m2", messages[0].ID, "newest first")
+	assert.Equal(t, "Alice", messages[0].ChatName)
+
+	messages, err = s.TaggedMessages("team", ListMessagesParams{Limit: 10, ExcludeJIDs: []string{"@g.us"}})
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+}
+
+func TestMessageTags(t *testing.T) {
+	s := setupTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, s.StoreChat("1@s.whatsapp.net", "Alice", now))
+	require.NoError(t, s.StoreChat("2@s.whatsapp.net", "Bob", now))
+	require.NoError(t, s.StoreMessage("m1", "1@s.whatsapp.net", "1", "invoice", now, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, s.StoreMessage("m2", "1@s.whatsapp.net", "1", "hi", now, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, s.StoreMessage("m2", "2@s.whatsapp.net", "2", "hi", now, false, "", "", "", "", "", nil, nil, nil, 0))
+
+	chat, err := s.MessageChat("m1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "1@s.whatsapp.net", chat)
+	_, err = s.MessageChat("m2", nil)
+	assert.ErrorContains(t, err, "specify chat JID")
+	other := "2@s.whatsapp.net"
+	chat, err = s.MessageChat("m2", &other)
+	require.NoError(t, err)
+	assert.Equal(t, other, chat)
+	_, err = s.MessageChat("m3", nil)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.NoError(t, s.TagMessage("m1", "1@s.whatsapp.net", "needs-reply", now))
+	require.NoError(t, s.TagMessage("m1", "1@s.whatsapp.net", "invoice", now))
+	tags, err := s.MessageTags("m1", "1@s.whatsapp.net")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"invoice", "needs-reply"}, tags)
+
+	tag := "needs-reply"
+	messages, err := s.ListMessages(ListMessagesParams{Tag: &tag, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "m1", messages[0].ID)
+
+	removed, err := s.UntagMessage("m1", "1@s.whatsapp.net", "needs-reply")
+	require.NoError(t, err)
+	assert.True(t, removed)
+	removed, err = s.UntagMessage("m1", "1@s.whatsapp.net", "needs-reply")
+	require.NoError(t, err)
+	assert.False(t, removed)
+	messages, err = s.ListMessages(ListMessagesParams{Tag: &tag, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+}
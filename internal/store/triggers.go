@@ -0,0 +1,173 @@
+package store
+
+import (
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/vicentereig/whatsapp-cli/internal/jid"
+)
+
+// NewMessagesParams selects the messages a polling trigger returns.
+type NewMessagesParams struct {
+	// SinceID returns only messages stored after the one with this ID; zero
+	// returns the latest.
+	SinceID int64
+	ChatJID *string
+	// FromMe limits the messages to sent (true) or received (false) ones.
+	FromMe       *bool
+	Limit        int
+	IncludeJIDs  []string
+	ExcludeJIDs  []string
+	After        *time.Time
+	HideSpamFrom int
+}
+
+// TriggerMessage is a message as polling automation platforms expect it:
+// flat, with a numeric ID that grows with every message stored.
+type TriggerMessage struct {
+	// ID is the message's rowid. Updates of a stored message, such as a
+	// history sync delivering it again, keep it, so each message triggers
+	// once.
+	ID        int64     `json:"id"`
+	MessageID string    `json:"message_id"`
+	ChatJID   string    `json:"chat_jid"`
+	ChatName  string    `json:"chat_name"`
+	Sender    string    `json:"sender"`
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+	IsFromMe  bool      `json:"is_from_me"`
+	IsGroup   bool      `json:"is_group"`
+	MediaType string    `json:"media_type"`
+}
+
+// NewMessages returns messages in the order they were stored, newest first.
+// With SinceID, it returns the Limit messages stored right after SinceID, so
+// a client polling with the highest ID it has seen never skips a message
+// even when more than Limit arrived in between.
+func (s *MessageStore) NewMessages(params NewMessagesParams) ([]TriggerMessage, error) {
+	query := triggerMessagesSelect + ` WHERE m.rowid > ?`
+	args := []interface{}{params.SinceID}
+	if params.ChatJID != nil {
+		query += " AND m.chat_jid = ?"
+		args = append(args, s.resolve(*params.ChatJID))
+	}
+	if params.FromMe != nil {
+		query += " AND m.is_from_me = ?"
+		args = append(args, *params.FromMe)
+	}
+	if params.After != nil {
+		query += " AND m.timestamp > ?"
+		args = append(args, params.After)
+	}
+	if params.HideSpamFrom > 0 {
+		query += " AND (COALESCE(m.spam_score, 0) < ? OR m.spam_released = 1)"
+		args = append(args, params.HideSpamFrom)
+	}
+	query, args = appendJIDFilter(query, args, "m.chat_jid", params.IncludeJIDs, params.ExcludeJIDs)
+	if params.SinceID > 0 {
+		query += " ORDER BY m.rowid ASC LIMIT ?"
+	} else {
+		query += " ORDER BY m.rowid DESC LIMIT ?"
+	}
+	args = append(args, params.Limit)
+
+	messages, err := s.queryTriggerMessages(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if params.SinceID > 0 {
+		slices.Reverse(messages)
+	}
+	return messages, nil
+}
+
+// MessagesSince returns up to limit messages timestamped at or after since,
+// oldest first, so event streams can replay what a client missed while
+// disconnected. Messages with the same timestamp keep the order they were
+// stored in.
+func (s *MessageStore) MessagesSince(since time.Time, limit int, includeJIDs, excludeJIDs []string, hideSpamFrom int) ([]TriggerMessage, error) {
+	query := triggerMessagesSelect + ` WHERE m.timestamp >= ?`
+	args := []interface{}{since}
+	if hideSpamFrom > 0 {
+		query += " AND (COALESCE(m.spam_score, 0) < ? OR m.spam_released = 1)"
+		args = append(args, hideSpamFrom)
+	}
+	query, args = appendJIDFilter(query, args, "m.chat_jid", includeJIDs, excludeJIDs)
+	query += " ORDER BY m.timestamp ASC, m.rowid ASC LIMIT ?"
+	args = append(args, limit)
+	return s.queryTriggerMessages(query, args...)
+}
+
+const triggerMessagesSelect = `SELECT m.rowid, m.id, m.chat_jid, COALESCE(c.name, ''), COALESCE(m.sender, ''), COALESCE(m.content, ''),
+		m.timestamp, m.is_from_me, COALESCE(m.media_type, '')
+		FROM messages m LEFT JOIN chats c ON c.jid = m.chat_jid`
+
+func (s *MessageStore) queryTriggerMessages(query string, args ...interface{}) ([]TriggerMessage, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	messages := []TriggerMessage{}
+	for rows.Next() {
+		var m TriggerMessage
+		if err := rows.Scan(&m.ID, &m.MessageID, &m.ChatJID, &m.ChatName, &m.Sender, &m.Content,
+			&m.Timestamp, &m.IsFromMe, &m.MediaType); err != nil {
+			return nil, err
+		}
+		m.IsGroup = jid.IsGroup(m.ChatJID)
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// TriggerChat is a chat as polling automation platforms expect it; like
+// TriggerMessage.ID, ID grows with every chat stored.
+type TriggerChat struct {
+	ID              int64     `json:"id"`
+	JID             string    `json:"jid"`
+	Name            string    `json:"name"`
+	IsGroup         bool      `json:"is_group"`
+	LastMessageTime time.Time `json:"last_message_time"`
+}
+
+// NewChats returns chats in the order they were first stored, newest first,
+// with the same SinceID semantics as NewMessages. Chats reconciled from the
+// phone are left out while they have no messages: they are not new, only
+// newly listed.
+func (s *MessageStore) NewChats(sinceID int64, limit int, includeJIDs, excludeJIDs []string) ([]TriggerChat, error) {
+	query := `SELECT rowid, jid, COALESCE(name, ''), last_message_time FROM chats WHERE rowid > ? AND last_message_time IS NOT NULL`
+	args := []interface{}{sinceID}
+	query, args = appendJIDFilter(query, args, "jid", includeJIDs, excludeJIDs)
+	if sinceID > 0 {
+		query += " ORDER BY rowid ASC LIMIT ?"
+	} else {
+		query += " ORDER BY rowid DESC LIMIT ?"
+	}
+	args = append(args, limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	chats := []TriggerChat{}
+	for rows.Next() {
+		var c TriggerChat
+		var last sql.NullTime
+		if err := rows.Scan(&c.ID, &c.JID, &c.Name, &last); err != nil {
+			return nil, err
+		}
+		c.IsGroup = jid.IsGroup(c.JID)
+		c.LastMessageTime = last.Time
+		chats = append(chats, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if sinceID > 0 {
+		slices.Reverse(chats)
+	}
+	return chats, nil
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMessages(t *testing.T) {
+	s := setupTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, s.StoreChat("1@s.whatsapp.net", "Alice", now))
+	require.NoError(t, s.StoreChat("120363000000000001@g.us", "Team", now))
+	// Stored out of timestamp order, as history sync does
+	require.NoError(t, s.StoreMessage("m1", "1@s.whatsapp.net", "1", "one", now, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, s.StoreMessage("m2", "120363000000000001@g.us", "2", "two", now.Add(-time.Hour), false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, s.StoreMessage("m3", "1@s.whatsapp.net", "me", "three", now.Add(time.Minute), true, "", "", "", "", "", nil, nil, nil, 0))
+
+	latest, err := s.NewMessages(NewMessagesParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, latest, 3)
+	assert.Equal(t, []string{"m3", "m2", "m1"}, []string{latest[0].MessageID, latest[1].MessageID, latest[2].MessageID})
+	assert.Greater(t, latest[0].ID, latest[1].ID)
+	assert.True(t, latest[1].IsGroup)
+	assert.Equal(t, "Team", latest[1].ChatName)
+
+	// Polling from the oldest returns the next page without gaps, newest
+	// first
+	page, err := s.NewMessages(NewMessagesParams{SinceID: latest[2].ID, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "m2", page[0].MessageID)
+
+	// Updating a stored message keeps its ID, so it does not trigger again
+	require.NoError(t, s.StoreMessage("m1", "1@s.whatsapp.net", "1", "one (edited)", now, false, "", "", "", "", "", nil, nil, nil, 0))
+	after, err := s.NewMessages(NewMessagesParams{SinceID: latest[0].ID, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, after)
+
+	fromMe := false
+	chat := "1@s.whatsapp.net"
+	received, err := s.NewMessages(NewMessagesParams{ChatJID: &chat, FromMe: &fromMe, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, received, 1)
+	assert.Equal(t, "one (edited)", received[0].Content)
+}
+
+func TestNewChats(t *testing.T) {
+	s := setupTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, s.StoreChat("1@s.whatsapp.net", "Alice", now))
+	require.NoError(t, s.StoreChat("120363000000000001@g.us", "Team", now))
+
+	chats, err := s.NewChats(0, 10, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, chats, 2)
+	assert.Equal(t, "120363000000000001@g.us", chats[0].JID)
+	assert.True(t, chats[0].IsGroup)
+	assert.True(t, now.Equal(chats[1].LastMessageTime))
+
+	// Renaming a chat does not make it new
+	require.NoError(t, s.StoreChat("1@s.whatsapp.net", "Alice Smith", now.Add(time.Minute)))
+	newer, err := s.NewChats(chats[0].ID, 10, nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, newer)
+
+	// Nor does listing it from the phone, without messages
+	_, err = s.ReconcileChats(map[string]string{"2@s.whatsapp.net": "Bob"}, now)
+	require.NoError(t, err)
+	newer, err = s.NewChats(chats[0].ID, 10, nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, newer)
+	chats, err = s.NewChats(0, 10, nil, nil)
+	require.NoError(t, err)
+	assert.Len(t, chats, 2)
+}
+
+func TestMessagesSince(t *testing.T) {
+	s := setupTestDB(t)
+	now := time.Now().Truncate(time.Second)
+	require.NoError(t, s.StoreChat("1@s.whatsapp.net", "Alice", now))
+	require.NoError(t, s.StoreChat("2@s.whatsapp.net", "Bob", now))
+	require.NoError(t, s.StoreMessage("m1", "1@s.whatsapp.net", "1", "one", now.Add(-time.Hour), false, "", "", "", "", "", nil, nil, nil, 0))
+	// Stored after m3, as history sync does, but sent before it
+	require.NoError(t, s.StoreMessage("m3", "1@s.whatsapp.net", "1", "three", now.Add(time.Minute), false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, s.StoreMessage("m2", "2@s.whatsapp.net", "2", "two", now, false, "", "", "", "", "", nil, nil, nil, 0))
+	require.NoError(t, s.StoreMessage("m4", "1@s.whatsapp.net", "me", "four", now.Add(time.Minute), true, "", "", "", "", "", nil, nil, nil, 0))
+
+	messages, err := s.MessagesSince(now, 10, nil, nil, 0)
+	require.NoError(t, err)
+	ids := []string{}
+	for _, m := range messages {
+		ids = append(ids, m.MessageID)
+	}
+	assert.Equal(t, []string{"m2", "m3", "m4"}, ids)
+	assert.Equal(t, "Bob", messages[0].ChatName)
+
+	messages, err = s.MessagesSince(now, 1, nil, []string{"2@s.whatsapp.net"}, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "m3", messages[0].MessageID)
+}
@@ -0,0 +1,108 @@
+// Package storelock keeps two processes from using the same store directory
+// at once. Both would write to messages.db and connect with the same
+// WhatsApp device, each replacing the other's session. The lock is an
+// advisory OS file lock, so it is released when the process exits, however
+// it exits; the lock file itself is left behind and only records the holder.
+package storelock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileName is the lock file created in the store directory.
+const FileName = "whatsapp-cli.lock"
+
+// errBusy is returned by tryLock when another process holds the lock.
+var errBusy = errors.New("lock is held by another process")
+
+// Lock is a held store directory lock.
+type Lock struct {
+	f *os.File
+}
+
+// LockedError is returned by Acquire when another process holds the lock.
+// PID and Since are zero when the holder could not be read.
+type LockedError struct {
+	Dir     string
+	PID     int
+	Command string
+	Since   time.Time
+}
+
+func (e *LockedError) Error() string {
+	holder := "another whatsapp-cli process"
+	if e.PID > 0 {
+		holder = fmt.Sprintf("another whatsapp-cli process (pid %d", e.PID)
+		if e.Command != "" {
+			holder += ", " + e.Command
+		}
+		if !e.Since.IsZero() {
+			holder += ", since " + e.Since.Format(time.RFC3339)
+		}
+		holder += ")"
+	}
+	return fmt.Sprintf("store directory %s is in use by %s; stop it first or use a different store directory", e.Dir, holder)
+}
+
+// Acquire locks dir, creating it if needed, on behalf of command (e.g.
+// "sync"). It fails immediately with a *LockedError if another process holds
+// the lock.
+func Acquire(dir, command string) (*Lock, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, FileName)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := tryLock(f); err != nil {
+		f.Close()
+		if errors.Is(err, errBusy) {
+			locked := &LockedError{Dir: dir}
+			if data, err := os.ReadFile(path); err == nil {
+				locked.PID, locked.Command, locked.Since = parseHolder(string(data))
+			}
+			return nil, locked
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	holder := fmt.Sprintf("%d\n%s\n%s\n", os.Getpid(), command, time.Now().UTC().Format(time.RFC3339))
+	if err := f.Truncate(0); err == nil {
+		f.WriteAt([]byte(holder), 0)
+	}
+	return &Lock{f: f}, nil
+}
+
+// Release unlocks the store directory.
+func (l *Lock) Release() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	err := unlock(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
+
+// parseHolder reads the lines written by Acquire: pid, command and start time.
+func parseHolder(data string) (pid int, command string, since time.Time) {
+	lines := strings.Split(data, "\n")
+	pid, _ = strconv.Atoi(strings.TrimSpace(lines[0]))
+	if len(lines) > 1 {
+		command = strings.TrimSpace(lines[1])
+	}
+	if len(lines) > 2 {
+		since, _ = time.Parse(time.RFC3339, strings.TrimSpace(lines[2]))
+	}
+	return pid, command, since
+}
@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package storelock
+
+import "os"
+
+// Platforms without file locks run unguarded.
+func tryLock(f *os.File) error { return nil }
+
+func unlock(f *os.File) error { return nil }
@@ -0,0 +1,44 @@
+package storelock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquire(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "store")
+
+	lock, err := Acquire(dir, "sync")
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, FileName))
+
+	_, err = Acquire(dir, "serve")
+	var locked *LockedError
+	require.True(t, errors.As(err, &locked), "got %v", err)
+	assert.Equal(t, os.Getpid(), locked.PID)
+	assert.Equal(t, "sync", locked.Command)
+	assert.WithinDuration(t, time.Now(), locked.Since, time.Minute)
+	assert.Contains(t, err.Error(), "is in use by another whatsapp-cli process (pid ")
+
+	require.NoError(t, lock.Release())
+	require.NoError(t, lock.Release(), "releasing twice is harmless")
+
+	again, err := Acquire(dir, "serve")
+	require.NoError(t, err)
+	defer again.Release()
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	require.NoError(t, err)
+	_, command, _ := parseHolder(string(data))
+	assert.Equal(t, "serve", command)
+}
+
+func TestLockedErrorWithoutHolder(t *testing.T) {
+	err := &LockedError{Dir: "/data"}
+	assert.Equal(t, "store directory /data is in use by another whatsapp-cli process; stop it first or use a different store directory", err.Error())
+}
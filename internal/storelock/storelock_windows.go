@@ -0,0 +1,47 @@
+//go:build windows
+
+package storelock
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	errorLockViolation      = syscall.Errno(33)
+)
+
+// lockOffset places the locked byte far past the end of the file: Windows
+// locks are mandatory, and locking the holder information itself would keep
+// a second process from reading it.
+const lockOffset = 0x7fffffff
+
+func tryLock(f *os.File) error {
+	ol := syscall.Overlapped{OffsetHigh: lockOffset}
+	r, _, err := procLockFileEx.Call(f.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0, uintptr(unsafe.Pointer(&ol)))
+	if r != 0 {
+		return nil
+	}
+	if err == errorLockViolation {
+		return errBusy
+	}
+	return err
+}
+
+func unlock(f *os.File) error {
+	ol := syscall.Overlapped{OffsetHigh: lockOffset}
+	r, _, err := procUnlockFileEx.Call(f.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(&ol)))
+	if r != 0 {
+		return nil
+	}
+	return err
+}
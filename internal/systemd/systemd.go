@@ -0,0 +1,116 @@
+// Package systemd implements the sd_notify protocol so the daemon can run as
+// a Type=notify service with a watchdog.
+package systemd
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Notification states understood by systemd.
+const (
+	Ready    = "READY=1"
+	Stopping = "STOPPING=1"
+	Watchdog = "WATCHDOG=1"
+)
+
+// Status returns a STATUS= notification shown by systemctl status.
+func Status(s string) string {
+	return "STATUS=" + s
+}
+
+// ExtendTimeout returns an EXTEND_TIMEOUT_USEC= notification, which keeps
+// systemd from failing a start that is still in progress for d from now.
+func ExtendTimeout(d time.Duration) string {
+	return "EXTEND_TIMEOUT_USEC=" + strconv.FormatInt(d.Microseconds(), 10)
+}
+
+// startExtension is how far each ExtendTimeout sent while waiting to be
+// ready pushes back the start timeout.
+const startExtension = 30 * time.Second
+
+// Notify sends state to the socket in $NOTIFY_SOCKET. It reports false
+// without error when the process was not started by systemd.
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	// A leading '@' denotes an abstract socket
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// WatchdogInterval returns the watchdog timeout systemd expects pings
+// within, or 0 if the watchdog is disabled for this process.
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// Supervise reports READY=1 once ready returns true, then pings the watchdog
+// at half its interval for as long as healthy returns true. A missed ping
+// lets systemd restart a daemon whose sync loop has stalled. STOPPING=1 is
+// sent when ctx is done. Until it is ready, the start timeout is extended,
+// so a first run can wait for its QR code to be scanned.
+func Supervise(ctx context.Context, ready, healthy func() bool, onError func(error)) {
+	report := func(state string) {
+		if _, err := Notify(state); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+	defer report(Stopping)
+
+	poll := time.NewTicker(time.Second)
+	defer poll.Stop()
+	var extended time.Time
+	for !ready() {
+		if now := time.Now(); now.Sub(extended) >= startExtension/3 {
+			report(ExtendTimeout(startExtension) + "\n" + Status("waiting to be authenticated and syncing"))
+			extended = now
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+		}
+	}
+	report(Ready + "\n" + Status("authenticated and syncing"))
+
+	interval := WatchdogInterval()
+	if interval <= 0 {
+		<-ctx.Done()
+		return
+	}
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if healthy() {
+				report(Watchdog)
+			}
+		}
+	}
+}
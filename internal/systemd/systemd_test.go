@@ -0,0 +1,107 @@
+package systemd
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listen creates a notify socket and returns a channel of received states.
+func listen(t *testing.T) <-chan string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	ch := make(chan string, 16)
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			ch <- string(buf[:n])
+		}
+	}()
+	return ch
+}
+
+func TestNotifyWithoutSocket(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	sent, err := Notify(Ready)
+	assert.False(t, sent)
+	assert.NoError(t, err)
+}
+
+func TestNotify(t *testing.T) {
+	ch := listen(t)
+	sent, err := Notify(Ready)
+	require.NoError(t, err)
+	assert.True(t, sent)
+	assert.Equal(t, "READY=1", <-ch)
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "")
+	assert.Zero(t, WatchdogInterval())
+
+	t.Setenv("WATCHDOG_USEC", "30000000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	assert.Equal(t, 30*time.Second, WatchdogInterval())
+
+	t.Setenv("WATCHDOG_PID", "1")
+	assert.Zero(t, WatchdogInterval(), "watchdog meant for another process")
+}
+
+func TestSupervise(t *testing.T) {
+	ch := listen(t)
+	t.Setenv("WATCHDOG_USEC", "100000") // ping every 50ms
+	t.Setenv("WATCHDOG_PID", "")
+
+	var ready, healthy atomic.Bool
+	healthy.Store(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Supervise(ctx, ready.Load, healthy.Load, nil)
+		close(done)
+	}()
+
+	// Waiting, e.g. for a QR scan, keeps pushing back the start timeout
+	assert.Equal(t, "EXTEND_TIMEOUT_USEC=30000000\nSTATUS=waiting to be authenticated and syncing", <-ch)
+	select {
+	case s := <-ch:
+		t.Fatalf("notified %q before ready", s)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	ready.Store(true)
+	assert.Equal(t, "READY=1\nSTATUS=authenticated and syncing", <-ch)
+	assert.Equal(t, "WATCHDOG=1", <-ch)
+
+	// A stalled sync loop stops the pings
+	healthy.Store(false)
+	for len(ch) > 0 {
+		<-ch
+	}
+	select {
+	case s := <-ch:
+		t.Fatalf("unexpected %q while unhealthy", s)
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	cancel()
+	<-done
+	assert.Equal(t, "STOPPING=1", <-ch)
+}
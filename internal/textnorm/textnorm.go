@@ -0,0 +1,77 @@
+// Package textnorm folds text for matching, so a search finds a message
+// however its words were accented, cased, encoded or typed: "uber" matches
+// "Über", "istanbul" matches "İstanbul", and Arabic or Hebrew words match
+// with or without their vowel marks.
+package textnorm
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// diacriticBlocks are the blocks whose combining marks are diacritics that
+// can be dropped without changing the letter: accents of the Latin, Greek
+// and Cyrillic alphabets, and the vowel points and cantillation of Arabic
+// and Hebrew. Marks of other scripts, such as the dakuten of Japanese kana
+// or the vowel signs of Indic scripts, tell letters apart and are kept.
+var diacriticBlocks = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x0300, Hi: 0x036f, Stride: 1}, // Combining Diacritical Marks
+		{Lo: 0x0591, Hi: 0x05c7, Stride: 1}, // Hebrew points and cantillation
+		{Lo: 0x0610, Hi: 0x061a, Stride: 1}, // Arabic honorifics
+		{Lo: 0x064b, Hi: 0x065f, Stride: 1}, // Arabic harakat and hamza
+		{Lo: 0x0670, Hi: 0x0670, Stride: 1}, // Arabic superscript alef
+		{Lo: 0x06d6, Hi: 0x06ed, Stride: 1}, // Arabic Quranic marks
+		{Lo: 0x1ab0, Hi: 0x1aff, Stride: 1}, // Combining Diacritical Marks Extended
+		{Lo: 0x1dc0, Hi: 0x1dff, Stride: 1}, // Combining Diacritical Marks Supplement
+		{Lo: 0x20d0, Hi: 0x20ff, Stride: 1}, // Combining Diacritical Marks for Symbols
+		{Lo: 0xfe20, Hi: 0xfe2f, Stride: 1}, // Combining Half Marks
+	},
+}
+
+// letterVariants maps letters written differently by keyboard or region to
+// one form.
+var letterVariants = map[rune]rune{
+	'ٱ': 'ا', // alef wasla
+	'ی': 'ي', // Farsi yeh
+	'ک': 'ك', // keheh
+}
+
+// tatweel stretches Arabic words for justification; it is not a letter.
+const tatweel = 'ـ'
+
+// Fold returns s in the form text is matched in: compatibility characters
+// (ligatures, full-width and presentation forms) replaced by the ones they
+// stand for, diacritics and invisible formatting characters removed, and
+// case folded. Folding both sides of a comparison makes it insensitive to
+// all of these.
+func Fold(s string) string {
+	if isASCII(s) {
+		return strings.ToLower(s)
+	}
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == tatweel, unicode.Is(unicode.Cf, r):
+			return -1
+		case unicode.Is(unicode.Mn, r) && unicode.Is(diacriticBlocks, r):
+			return -1
+		}
+		if v, ok := letterVariants[r]; ok {
+			return v
+		}
+		return r
+	}, norm.NFKD.String(s))
+	return norm.NFC.String(cases.Fold().String(s))
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
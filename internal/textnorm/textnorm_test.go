@@ -0,0 +1,35 @@
+package textnorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFold(t *testing.T) {
+	for in, want := range map[string]string{
+		"Hello World":       "hello world",
+		"Über":              "uber",
+		"Crème Brûlée":      "creme brulee",
+		"İstanbul":          "istanbul",
+		"Straße":            "strasse",
+		"ΣΟΦΟΣ σοφος":       "σοφοσ σοφοσ",
+		"Ｗｈａｔｓａｐｐ":          "whatsapp",
+		"ﬁnal":              "final",
+		"مُحَمَّد":          "محمد",
+		"أحمد إبراهيم آمنة": "احمد ابراهيم امنة",
+		"كتـــاب":           "كتاب",
+		"کتاب ی":            "كتاب ي",
+		"שָׁלוֹם":           "שלום",
+		"\u200fשלום\u200e":  "שלום",
+		"がっこう":              "がっこう",
+		"हिन्दी":            "हिन्दी",
+	} {
+		assert.Equal(t, want, Fold(in), in)
+	}
+}
+
+func TestFoldMatchesComposedAndDecomposed(t *testing.T) {
+	assert.Equal(t, Fold("caf\u00e9"), Fold("cafe\u0301"))
+	assert.Equal(t, "cafe", Fold("CAFÉ"))
+}
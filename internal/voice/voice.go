@@ -0,0 +1,153 @@
+// Package voice turns audio into WhatsApp voice notes: mono Ogg/Opus, with
+// the duration and waveform the client draws next to the play button.
+// Transcoding runs ffmpeg, as Go has no Opus encoder.
+package voice
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// MIMEType is the MIME type of a converted voice note.
+const MIMEType = "audio/ogg; codecs=opus"
+
+// WaveformLength is the number of amplitudes in a waveform.
+const WaveformLength = 64
+
+// MaxDuration bounds the voice notes converted.
+const MaxDuration = time.Hour
+
+// demuxers are the input formats ffmpeg may read: the audio containers
+// phones, browsers and recorders produce. Playlists such as HLS or concat
+// lists are left out, as they make ffmpeg open other files and URLs.
+const demuxers = "aac,aiff,amr,caf,flac,matroska,mov,mp3,ogg,wav,webm"
+
+// sampleRate is the rate audio is decoded at to measure it; the waveform
+// needs no more.
+const sampleRate = 8000
+
+// Note is a converted voice note.
+type Note struct {
+	Data    []byte
+	Seconds uint32
+	// Waveform holds WaveformLength amplitudes from 0 to 100.
+	Waveform []byte
+}
+
+// Convert transcodes audio in any format ffmpeg reads into a voice note,
+// running the ffmpeg binary found at, or in PATH under, ffmpeg; empty means
+// "ffmpeg".
+func Convert(ctx context.Context, ffmpeg string, data []byte) (Note, error) {
+	if ffmpeg == "" {
+		ffmpeg = "ffmpeg"
+	}
+	path, err := exec.LookPath(ffmpeg)
+	if err != nil {
+		return Note{}, fmt.Errorf("sending voice notes needs ffmpeg, which was not found (install it or set FFMPEG_PATH)")
+	}
+
+	// ffmpeg reads the input from a file: MP4 and M4A recordings often keep
+	// their index at the end, which it cannot seek to in a pipe
+	f, err := os.CreateTemp("", "voice-*")
+	if err != nil {
+		return Note{}, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return Note{}, err
+	}
+
+	// Decoding one second past the limit tells notes that exceed it apart
+	limit := fmt.Sprintf("%d", int(MaxDuration/time.Second)+1)
+	pcm, err := run(ctx, path, input(f.Name(), "-t", limit, "-vn", "-ac", "1", "-ar", fmt.Sprint(sampleRate), "-f", "s16le", "pipe:1")...)
+	if err != nil {
+		return Note{}, fmt.Errorf("decoding audio: %v", err)
+	}
+	samples := make([]int16, len(pcm)/2)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(pcm[2*i:]))
+	}
+	switch {
+	case len(samples) == 0:
+		return Note{}, fmt.Errorf("audio has no sound track")
+	case len(samples) > int(MaxDuration/time.Second)*sampleRate:
+		return Note{}, fmt.Errorf("voice notes are limited to %v", MaxDuration)
+	}
+
+	ogg, err := run(ctx, path, input(f.Name(), "-vn", "-map_metadata", "-1", "-ac", "1", "-ar", "48000",
+		"-c:a", "libopus", "-b:a", "32k", "-application", "voip", "-f", "ogg", "pipe:1")...)
+	if err != nil {
+		return Note{}, fmt.Errorf("encoding voice note: %v", err)
+	}
+	return Note{
+		Data:     ogg,
+		Seconds:  uint32(max(1, (len(samples)+sampleRate/2)/sampleRate)),
+		Waveform: Waveform(samples),
+	}, nil
+}
+
+// input returns the arguments that read the uploaded file name, followed by
+// output. ffmpeg may only open local files and pipes, and only read the
+// formats in demuxers, so an upload cannot make it fetch URLs or read other
+// files.
+func input(name string, output ...string) []string {
+	args := []string{"-protocol_whitelist", "file,pipe", "-format_whitelist", demuxers, "-i", name}
+	return append(args, output...)
+}
+
+// run runs ffmpeg with args and returns what it wrote to its output, or the
+// last line of its errors.
+func run(ctx context.Context, ffmpeg string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, ffmpeg, append([]string{"-hide_banner", "-loglevel", "error", "-nostdin"}, args...)...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+		if msg := strings.TrimSpace(lines[len(lines)-1]); msg != "" {
+			return nil, fmt.Errorf("%s", msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// Waveform sums samples up as WaveformLength amplitudes: the loudness of
+// each stretch of the audio, from 0 to 100 for the loudest one.
+func Waveform(samples []int16) []byte {
+	levels := make([]float64, WaveformLength)
+	var loudest float64
+	for i := range levels {
+		stretch := samples[i*len(samples)/WaveformLength : (i+1)*len(samples)/WaveformLength]
+		if len(stretch) == 0 {
+			continue
+		}
+		var sum float64
+		for _, s := range stretch {
+			sum += float64(s) * float64(s)
+		}
+		levels[i] = math.Sqrt(sum / float64(len(stretch)))
+		loudest = max(loudest, levels[i])
+	}
+	waveform := make([]byte, WaveformLength)
+	if loudest == 0 {
+		return waveform
+	}
+	for i, l := range levels {
+		waveform[i] = byte(math.Round(l / loudest * 100))
+	}
+	return waveform
+}
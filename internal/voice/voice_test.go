@@ -0,0 +1,94 @@
+package voice
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFFmpeg writes a script standing in for ffmpeg: it decodes any input to
+// pcmBytes bytes of PCM, a square wave, and encodes it to a fixed Ogg page.
+func fakeFFmpeg(t *testing.T, pcmBytes int) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	script := `#!/bin/sh
+case "$*" in
+*s16le*) i=0; while [ $i -lt ` + strconv.Itoa(pcmBytes/4) + ` ]; do printf '\000\100\000\300'; i=$((i+1)); done ;;
+*libopus*) printf 'OggS fake' ;;
+esac
+`
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o755))
+	return path
+}
+
+func TestConvert(t *testing.T) {
+	// 1.5 seconds of 8 kHz 16-bit audio
+	note, err := Convert(context.Background(), fakeFFmpeg(t, 24000), []byte("RIFF audio"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("OggS fake"), note.Data)
+	assert.Equal(t, uint32(2), note.Seconds)
+	require.Len(t, note.Waveform, WaveformLength)
+	for _, a := range note.Waveform {
+		assert.Equal(t, byte(100), a)
+	}
+}
+
+func TestConvertFailures(t *testing.T) {
+	_, err := Convert(context.Background(), filepath.Join(t.TempDir(), "missing"), []byte("x"))
+	assert.ErrorContains(t, err, "FFMPEG_PATH")
+
+	_, err = Convert(context.Background(), fakeFFmpeg(t, 0), []byte("x"))
+	assert.ErrorContains(t, err, "no sound")
+
+	failing := filepath.Join(t.TempDir(), "ffmpeg")
+	require.NoError(t, os.WriteFile(failing, []byte("#!/bin/sh\necho 'pipe:0: Invalid data found when processing input' >&2\nexit 1\n"), 0o755))
+	_, err = Convert(context.Background(), failing, []byte("x"))
+	assert.EqualError(t, err, "decoding audio: pipe:0: Invalid data found when processing input")
+}
+
+func TestWaveform(t *testing.T) {
+	// Silence, then a quiet stretch, then a loud one
+	samples := make([]int16, 6400)
+	for i := 3200; i < 4800; i++ {
+		samples[i] = 1000
+	}
+	for i := 4800; i < 6400; i++ {
+		samples[i] = -4000
+	}
+	w := Waveform(samples)
+	require.Len(t, w, WaveformLength)
+	assert.Equal(t, byte(0), w[0])
+	assert.Equal(t, byte(25), w[40])
+	assert.Equal(t, byte(100), w[63])
+
+	assert.Equal(t, make([]byte, WaveformLength), Waveform(make([]int16, 100)))
+	// Fewer samples than amplitudes leaves the stretches between them empty
+	assert.Len(t, Waveform([]int16{5, 10}), WaveformLength)
+}
+
+func TestConvertRestrictsInput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "args")
+	path := filepath.Join(dir, "ffmpeg")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\necho \"$*\" >> "+log+"\nexit 1\n"), 0o755))
+
+	_, err := Convert(context.Background(), path, []byte("#EXTM3U\nhttp://169.254.169.254/\n"))
+	require.Error(t, err)
+	args, err := os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "-protocol_whitelist file,pipe -format_whitelist "+demuxers+" -i ")
+	assert.NotContains(t, demuxers, "hls")
+	assert.NotContains(t, demuxers, "concat")
+}
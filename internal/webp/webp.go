@@ -0,0 +1,612 @@
+// Package webp encodes images as lossless WebP (VP8L). It applies the
+// subtract-green and predictor transforms, finds LZ77 backward references
+// and codes the whole image with one set of prefix codes: far simpler than
+// libwebp and larger output, but enough for stickers.
+package webp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math/bits"
+	"sort"
+)
+
+// MaxDimension is the largest width or height VP8L can describe.
+const MaxDimension = 1 << 14
+
+const (
+	// predictorBits is the log2 of the tile size the predictor transform
+	// picks a mode for.
+	predictorBits = 4
+
+	minMatch = 3
+	maxMatch = 4096
+	hashBits = 16
+	maxChain = 32
+	// maxDistance keeps distance codes within the 40 prefix symbols.
+	maxDistance = 1<<20 - 120
+
+	numLiteralCodes = 256
+	numLengthCodes  = 24
+	numDistCodes    = 40
+)
+
+// codeLengthOrder is the order the lengths of the code length code are
+// written in.
+var codeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// Encode writes img to w as a lossless WebP.
+func Encode(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width < 1 || height < 1 || width > MaxDimension || height > MaxDimension {
+		return fmt.Errorf("webp: cannot encode a %dx%d image", width, height)
+	}
+
+	argb := make([]uint32, 0, width*height)
+	alpha := false
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			argb = append(argb, uint32(c.A)<<24|uint32(c.R)<<16|uint32(c.G)<<8|uint32(c.B))
+			alpha = alpha || c.A != 0xff
+		}
+	}
+
+	bw := &bitWriter{}
+	bw.write(0x2f, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	bw.write(boolBit(alpha), 1)
+	bw.write(0, 3)
+
+	// The decoder undoes transforms in reverse: first the predictor, then
+	// subtract green
+	subtractGreen(argb)
+	bw.write(1, 1)
+	bw.write(2, 2)
+
+	residuals, modes, tilesWide := predict(argb, width, height)
+	bw.write(1, 1)
+	bw.write(0, 2)
+	bw.write(predictorBits-2, 3)
+	encodeImageData(bw, modes, tilesWide, false)
+	bw.write(0, 1)
+
+	encodeImageData(bw, residuals, width, true)
+
+	data := bw.bytes()
+	pad := len(data) & 1
+	header := make([]byte, 20)
+	copy(header, "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(12+len(data)+pad))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if pad == 1 {
+		data = append(data, 0)
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+func boolBit(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// subtractGreen subtracts the green channel from red and blue.
+func subtractGreen(argb []uint32) {
+	for i, p := range argb {
+		g := p >> 8 & 0xff
+		r := (p>>16 - g) & 0xff
+		bl := (p - g) & 0xff
+		argb[i] = p&0xff00ff00 | r<<16 | bl
+	}
+}
+
+// Predictor modes used: the pixel to the left, the one above, and whichever
+// of them is closer to their gradient.
+const (
+	predictLeft   = 1
+	predictTop    = 2
+	predictSelect = 11
+)
+
+// predict applies the predictor transform, choosing per tile the mode that
+// leaves the smallest residuals. It returns the residuals and the modes, one
+// pixel per tile in tile rows tilesWide wide.
+func predict(argb []uint32, width, height int) (residuals, modes []uint32, tilesWide int) {
+	tileSize := 1 << predictorBits
+	tilesWide = (width + tileSize - 1) >> predictorBits
+	tilesHigh := (height + tileSize - 1) >> predictorBits
+	modes = make([]uint32, tilesWide*tilesHigh)
+	for ty := 0; ty < tilesHigh; ty++ {
+		for tx := 0; tx < tilesWide; tx++ {
+			best, bestCost := predictLeft, -1
+			for _, mode := range []int{predictLeft, predictTop, predictSelect} {
+				cost := 0
+				for y := max(ty*tileSize, 1); y < min((ty+1)*tileSize, height); y++ {
+					for x := max(tx*tileSize, 1); x < min((tx+1)*tileSize, width); x++ {
+						i := y*width + x
+						cost += residualCost(subPixels(argb[i], predictWith(mode, argb, i, width)))
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+			modes[ty*tilesWide+tx] = uint32(best) << 8
+		}
+	}
+
+	residuals = make([]uint32, len(argb))
+	for i, p := range argb {
+		x, y := i%width, i/width
+		var pred uint32
+		switch {
+		case i == 0:
+			pred = 0xff000000
+		case y == 0:
+			pred = argb[i-1]
+		case x == 0:
+			pred = argb[i-width]
+		default:
+			mode := int(modes[(y>>predictorBits)*tilesWide+x>>predictorBits] >> 8)
+			pred = predictWith(mode, argb, i, width)
+		}
+		residuals[i] = subPixels(p, pred)
+	}
+	return residuals, modes, tilesWide
+}
+
+// predictWith predicts pixel i, which has neighbours left and above.
+func predictWith(mode int, argb []uint32, i, width int) uint32 {
+	left, top := argb[i-1], argb[i-width]
+	switch mode {
+	case predictTop:
+		return top
+	case predictSelect:
+		topLeft := argb[i-width-1]
+		pLeft, pTop := 0, 0
+		for s := 0; s < 32; s += 8 {
+			l, t, tl := int(left>>s&0xff), int(top>>s&0xff), int(topLeft>>s&0xff)
+			pLeft += abs(t - tl)
+			pTop += abs(l - tl)
+		}
+		if pLeft < pTop {
+			return left
+		}
+		return top
+	default:
+		return left
+	}
+}
+
+// subPixels subtracts b from a channel by channel, modulo 256.
+func subPixels(a, b uint32) uint32 {
+	var out uint32
+	for s := 0; s < 32; s += 8 {
+		out |= ((a>>s - b>>s) & 0xff) << s
+	}
+	return out
+}
+
+// residualCost estimates how many bits a residual costs: small differences
+// in either direction are cheap.
+func residualCost(p uint32) int {
+	cost := 0
+	for s := 0; s < 32; s += 8 {
+		v := int(p >> s & 0xff)
+		cost += min(v, 256-v)
+	}
+	return cost
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// token is a literal pixel or, with a length, a copy of earlier pixels.
+type token struct {
+	pixel  uint32
+	length int
+	// distCode is the distance of a copy as VP8L codes it.
+	distCode int
+}
+
+// encodeImageData writes an entropy-coded image: the main image when level0,
+// else a transform's sub-image.
+func encodeImageData(bw *bitWriter, pixels []uint32, width int, level0 bool) {
+	bw.write(0, 1) // no color cache
+	if level0 {
+		bw.write(0, 1) // one prefix code group for the whole image
+	}
+
+	tokens := backwardReferences(pixels, width)
+	green := make([]uint32, numLiteralCodes+numLengthCodes)
+	red := make([]uint32, numLiteralCodes)
+	blue := make([]uint32, numLiteralCodes)
+	alpha := make([]uint32, numLiteralCodes)
+	dist := make([]uint32, numDistCodes)
+	for _, t := range tokens {
+		if t.length == 0 {
+			green[t.pixel>>8&0xff]++
+			red[t.pixel>>16&0xff]++
+			blue[t.pixel&0xff]++
+			alpha[t.pixel>>24]++
+			continue
+		}
+		lc, _, _ := prefixEncode(t.length)
+		green[numLiteralCodes+lc]++
+		dc, _, _ := prefixEncode(t.distCode)
+		dist[dc]++
+	}
+
+	codes := make([]prefixCode, 5)
+	for i, hist := range [][]uint32{green, red, blue, alpha, dist} {
+		codes[i] = newPrefixCode(hist, 15)
+		codes[i].writeTo(bw)
+	}
+	for _, t := range tokens {
+		if t.length == 0 {
+			codes[0].writeSymbol(bw, int(t.pixel>>8&0xff))
+			codes[1].writeSymbol(bw, int(t.pixel>>16&0xff))
+			codes[2].writeSymbol(bw, int(t.pixel&0xff))
+			codes[3].writeSymbol(bw, int(t.pixel>>24))
+			continue
+		}
+		lc, n, extra := prefixEncode(t.length)
+		codes[0].writeSymbol(bw, numLiteralCodes+lc)
+		bw.write(uint32(extra), n)
+		dc, n, extra := prefixEncode(t.distCode)
+		codes[4].writeSymbol(bw, dc)
+		bw.write(uint32(extra), n)
+	}
+}
+
+// backwardReferences splits pixels into literals and copies, greedily
+// taking the longest match found on a hash chain of pixel pairs.
+func backwardReferences(pixels []uint32, width int) []token {
+	n := len(pixels)
+	head := make([]int32, 1<<hashBits)
+	for i := range head {
+		head[i] = -1
+	}
+	prev := make([]int32, n)
+	insert := func(i int) {
+		if i+1 >= n {
+			return
+		}
+		h := (pixels[i]*0x9e3779b1 + pixels[i+1]*0x85ebca6b) >> (32 - hashBits)
+		prev[i] = head[h]
+		head[h] = int32(i)
+	}
+	matchLen := func(i, j int) int {
+		limit := min(maxMatch, n-i)
+		l := 0
+		for l < limit && pixels[i+l] == pixels[j+l] {
+			l++
+		}
+		return l
+	}
+
+	var tokens []token
+	for i := 0; i < n; {
+		bestLen, bestDist := 0, 0
+		// The pixel to the left and the one above have the shortest codes
+		for _, d := range []int{1, width} {
+			if d <= i {
+				if l := matchLen(i, i-d); l > bestLen {
+					bestLen, bestDist = l, d
+				}
+			}
+		}
+		if i+1 < n {
+			h := (pixels[i]*0x9e3779b1 + pixels[i+1]*0x85ebca6b) >> (32 - hashBits)
+			for j, chain := head[h], 0; j >= 0 && chain < maxChain && bestLen < maxMatch; j, chain = prev[j], chain+1 {
+				d := i - int(j)
+				if d > maxDistance {
+					break
+				}
+				if l := matchLen(i, int(j)); l > bestLen {
+					bestLen, bestDist = l, d
+				}
+			}
+		}
+
+		if bestLen < minMatch {
+			tokens = append(tokens, token{pixel: pixels[i]})
+			insert(i)
+			i++
+			continue
+		}
+		tokens = append(tokens, token{length: bestLen, distCode: distanceCode(bestDist, width)})
+		for k := i; k < i+bestLen; k++ {
+			insert(k)
+		}
+		i += bestLen
+	}
+	return tokens
+}
+
+// distanceCode maps a distance in pixels to VP8L's distance code, whose
+// first 120 values are short codes for nearby pixels in 2D.
+func distanceCode(dist, width int) int {
+	switch dist {
+	case width:
+		return 1
+	case 1:
+		return 2
+	default:
+		return dist + 120
+	}
+}
+
+// prefixEncode splits a length or distance code into the prefix symbol and
+// the extra bits that follow it.
+func prefixEncode(v int) (symbol int, extraBits uint, extra int) {
+	d := v - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+	h := bits.Len(uint(d)) - 1
+	second := (d >> (h - 1)) & 1
+	extraBits = uint(h - 1)
+	return 2*h + second, extraBits, d & (1<<extraBits - 1)
+}
+
+// prefixCode is a canonical Huffman code.
+type prefixCode struct {
+	lengths []uint8
+	// codes are bit-reversed, as the bit writer sends the low bit first.
+	codes []uint16
+	// used are the symbols with a code, in ascending order.
+	used []int
+}
+
+func newPrefixCode(hist []uint32, maxLength int) prefixCode {
+	p := prefixCode{lengths: huffmanLengths(hist, maxLength)}
+	for s, l := range p.lengths {
+		if l > 0 {
+			p.used = append(p.used, s)
+		}
+	}
+	p.codes = canonicalCodes(p.lengths)
+	return p
+}
+
+// writeSymbol writes the code of s. A code with a single symbol takes no
+// bits.
+func (p prefixCode) writeSymbol(bw *bitWriter, s int) {
+	if len(p.used) > 1 {
+		bw.write(uint32(p.codes[s]), uint(p.lengths[s]))
+	}
+}
+
+// writeTo writes the code lengths, as a simple code if there are at most
+// two symbols that fit one, else Huffman-coded themselves.
+func (p prefixCode) writeTo(bw *bitWriter) {
+	if len(p.used) <= 2 && (len(p.used) == 0 || p.used[len(p.used)-1] < 256) {
+		bw.write(1, 1)
+		if len(p.used) == 0 {
+			// No symbol is ever written; declare symbol 0
+			bw.write(0, 3)
+			return
+		}
+		bw.write(uint32(len(p.used)-1), 1)
+		if first := p.used[0]; first < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(first), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(first), 8)
+		}
+		if len(p.used) == 2 {
+			bw.write(uint32(p.used[1]), 8)
+		}
+		return
+	}
+
+	bw.write(0, 1)
+	tokens := codeLengthTokens(p.lengths)
+	hist := make([]uint32, len(codeLengthOrder))
+	for _, t := range tokens {
+		hist[t.symbol]++
+	}
+	cl := newPrefixCode(hist, 7)
+	num := 4
+	for i, s := range codeLengthOrder {
+		if cl.lengths[s] > 0 {
+			num = max(num, i+1)
+		}
+	}
+	bw.write(uint32(num-4), 4)
+	for _, s := range codeLengthOrder[:num] {
+		bw.write(uint32(cl.lengths[s]), 3)
+	}
+	bw.write(0, 1) // code lengths for the whole alphabet follow
+	for _, t := range tokens {
+		cl.writeSymbol(bw, t.symbol)
+		switch t.symbol {
+		case 16:
+			bw.write(uint32(t.extra), 2)
+		case 17:
+			bw.write(uint32(t.extra), 3)
+		case 18:
+			bw.write(uint32(t.extra), 7)
+		}
+	}
+}
+
+// codeLengthToken is a code length, or a run of them: 16 repeats the
+// previous length 3-6 times, 17 zero 3-10 times and 18 zero 11-138 times.
+type codeLengthToken struct {
+	symbol int
+	extra  int
+}
+
+func codeLengthTokens(lengths []uint8) []codeLengthToken {
+	var tokens []codeLengthToken
+	for i := 0; i < len(lengths); {
+		v := lengths[i]
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == v {
+			run++
+		}
+		i += run
+		if v == 0 {
+			for run >= 11 {
+				r := min(run, 138)
+				tokens = append(tokens, codeLengthToken{18, r - 11})
+				run -= r
+			}
+			if run >= 3 {
+				tokens = append(tokens, codeLengthToken{17, run - 3})
+				run = 0
+			}
+		} else {
+			tokens = append(tokens, codeLengthToken{symbol: int(v)})
+			run--
+			for run >= 3 {
+				r := min(run, 6)
+				tokens = append(tokens, codeLengthToken{16, r - 3})
+				run -= r
+			}
+		}
+		for ; run > 0; run-- {
+			tokens = append(tokens, codeLengthToken{symbol: int(v)})
+		}
+	}
+	return tokens
+}
+
+// huffmanLengths returns the code lengths of a Huffman code for hist, at
+// most maxLength long. Should the tree get too deep, rare symbols are
+// counted as more frequent until it fits, which keeps the code complete.
+func huffmanLengths(hist []uint32, maxLength int) []uint8 {
+	lengths := make([]uint8, len(hist))
+	var symbols []int
+	for s, c := range hist {
+		if c > 0 {
+			symbols = append(symbols, s)
+		}
+	}
+	if len(symbols) == 1 {
+		lengths[symbols[0]] = 1
+	}
+	if len(symbols) <= 1 {
+		return lengths
+	}
+
+	type node struct {
+		count       uint64
+		symbol      int
+		left, right int
+	}
+	for minCount := uint64(1); ; minCount *= 2 {
+		nodes := make([]node, 0, 2*len(symbols))
+		for _, s := range symbols {
+			nodes = append(nodes, node{count: max(uint64(hist[s]), minCount), symbol: s, left: -1, right: -1})
+		}
+		sort.Slice(nodes, func(i, j int) bool {
+			if nodes[i].count != nodes[j].count {
+				return nodes[i].count < nodes[j].count
+			}
+			return nodes[i].symbol < nodes[j].symbol
+		})
+		// Two queues: the sorted leaves and the merged nodes, which are
+		// created in ascending order
+		leaf, merged := 0, len(nodes)
+		numLeaves := len(nodes)
+		pop := func() int {
+			if leaf < numLeaves && (merged >= len(nodes) || nodes[leaf].count <= nodes[merged].count) {
+				leaf++
+				return leaf - 1
+			}
+			merged++
+			return merged - 1
+		}
+		for k := 1; k < numLeaves; k++ {
+			a := pop()
+			b := pop()
+			nodes = append(nodes, node{count: nodes[a].count + nodes[b].count, symbol: -1, left: a, right: b})
+		}
+
+		depths := make([]int, len(nodes))
+		deepest := 0
+		for i := len(nodes) - 1; i >= 0; i-- {
+			if n := nodes[i]; n.left >= 0 {
+				depths[n.left] = depths[i] + 1
+				depths[n.right] = depths[i] + 1
+			} else {
+				deepest = max(deepest, depths[i])
+			}
+		}
+		if deepest > maxLength {
+			continue
+		}
+		for i := 0; i < numLeaves; i++ {
+			lengths[nodes[i].symbol] = uint8(depths[i])
+		}
+		return lengths
+	}
+}
+
+// canonicalCodes assigns codes in order of length, then symbol.
+func canonicalCodes(lengths []uint8) []uint16 {
+	var count, next [16]int
+	for _, l := range lengths {
+		if l > 0 {
+			count[l]++
+		}
+	}
+	code := 0
+	for l := 1; l < 16; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+	codes := make([]uint16, len(lengths))
+	for s, l := range lengths {
+		if l > 0 {
+			codes[s] = uint16(bits.Reverse16(uint16(next[l])) >> (16 - l))
+			next[l]++
+		}
+	}
+	return codes
+}
+
+// bitWriter packs bits low bit first, as VP8L reads them.
+type bitWriter struct {
+	buf []byte
+	acc uint64
+	n   uint
+}
+
+func (b *bitWriter) write(v uint32, n uint) {
+	b.acc |= uint64(v) << b.n
+	b.n += n
+	for b.n >= 8 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc >>= 8
+		b.n -= 8
+	}
+}
+
+func (b *bitWriter) bytes() []byte {
+	if b.n > 0 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc, b.n = 0, 0
+	}
+	return b.buf
+}
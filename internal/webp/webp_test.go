@@ -0,0 +1,79 @@
+package webp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/webp"
+)
+
+func TestEncodeRoundTrips(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	images := map[string]*image.NRGBA{}
+
+	flat := image.NewNRGBA(image.Rect(0, 0, 64, 48))
+	for i := range flat.Pix {
+		flat.Pix[i] = 0xff
+	}
+	images["flat"] = flat
+
+	gradient := image.NewNRGBA(image.Rect(0, 0, 300, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 300; x++ {
+			gradient.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(y), uint8(x + y), uint8(255 - y)})
+		}
+	}
+	images["gradient"] = gradient
+
+	noise := image.NewNRGBA(image.Rect(0, 0, 37, 19))
+	rng.Read(noise.Pix)
+	images["noise"] = noise
+
+	// Few colors in shapes, with a transparent background, as stickers are
+	shapes := image.NewNRGBA(image.Rect(0, 0, 128, 128))
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 128; x++ {
+			if (x-64)*(x-64)+(y-64)*(y-64) < 40*40 {
+				shapes.SetNRGBA(x, y, color.NRGBA{200, 30, 60, 255})
+			}
+		}
+	}
+	images["shapes"] = shapes
+
+	images["pixel"] = image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	images["column"] = noise.SubImage(image.Rect(3, 0, 4, 19)).(*image.NRGBA)
+
+	for name, img := range images {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Encode(&buf, img))
+			decoded, err := webp.Decode(&buf)
+			require.NoError(t, err)
+			b := img.Bounds()
+			require.Equal(t, b.Size(), decoded.Bounds().Size())
+			for y := 0; y < b.Dy(); y++ {
+				for x := 0; x < b.Dx(); x++ {
+					want := img.NRGBAAt(b.Min.X+x, b.Min.Y+y)
+					got := color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+					require.Equal(t, want, got, "pixel %d,%d", x, y)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeCompressesFlatImages(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 512, 512))
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, img))
+	assert.Less(t, buf.Len(), 1000)
+}
+
+func TestEncodeRejectsEmptyImages(t *testing.T) {
+	assert.Error(t, Encode(&bytes.Buffer{}, image.NewNRGBA(image.Rect(0, 0, 0, 5))))
+}
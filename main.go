@@ -7,18 +7,45 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/vicentereig/whatsapp-cli/internal/api"
+	"github.com/vicentereig/whatsapp-cli/internal/avscan"
+	"github.com/vicentereig/whatsapp-cli/internal/client"
 	"github.com/vicentereig/whatsapp-cli/internal/commands"
+	"github.com/vicentereig/whatsapp-cli/internal/embeddings"
+	"github.com/vicentereig/whatsapp-cli/internal/eventbus"
+	"github.com/vicentereig/whatsapp-cli/internal/logging"
+	"github.com/vicentereig/whatsapp-cli/internal/mediacrypt"
+	"github.com/vicentereig/whatsapp-cli/internal/mediaurl"
+	"github.com/vicentereig/whatsapp-cli/internal/mqtt"
+	"github.com/vicentereig/whatsapp-cli/internal/netguard"
+	"github.com/vicentereig/whatsapp-cli/internal/output"
+	"github.com/vicentereig/whatsapp-cli/internal/selfupdate"
+	"github.com/vicentereig/whatsapp-cli/internal/sentry"
+	"github.com/vicentereig/whatsapp-cli/internal/storelock"
+	"github.com/vicentereig/whatsapp-cli/internal/systemd"
 )
 
 var (
 	// version is overridden at build time via -ldflags "-X main.version=X.Y.Z"
 	version = "1.3.1"
+	// commit is overridden at build time via -ldflags "-X main.commit=SHA"
+	commit = ""
+	// releaseKey is the base64 ed25519 key release checksums are signed
+	// with, set at build time via -ldflags "-X main.releaseKey=KEY"
+	releaseKey = ""
 )
 
+// exclusiveCommands connect to WhatsApp. Two of them on one store directory
+// would keep replacing each other's session, so they lock it; commands that
+// only read the store may run next to them.
+var exclusiveCommands = map[string]bool{"auth": true, "sync": true, "send": true, "media": true, "polls": true}
+
 const usage = `WhatsApp CLI - Command line interface for WhatsApp
 
 Usage:
@@ -27,16 +54,50 @@ Usage:
 Commands:
   auth                              Authenticate with WhatsApp (scan QR code)
   sync                              Sync messages continuously (run until Ctrl+C)
-  messages list [--chat JID]        List messages
+  messages list [--chat JID] [--tag TAG]  List messages
   messages search --query TEXT      Search messages
   contacts search --query TEXT      Search contacts
   chats list                        List chats
+  chats merge --from OLD --into NEW   Merge a renumbered contact's old chat into the new one
+  chats reconcile                   Add the chats and contacts the phone knows of to the store
+  session export --output FILE      Export the linked device session, encrypted with $SESSION_KEY,
+                                    and remove it from this store
+  session import --input FILE [--force]   Install an exported session on this host
   send --to RECIPIENT --message TEXT    Send a message
+  polls send --to RECIPIENT --question TEXT --option A --option B [--selectable N]   Send a poll
+  polls results --message-id ID [--chat JID]   Show the votes of a poll by option
   media download --message-id ID [--chat JID] [--output PATH]   Download media for a message
+  media encrypt                     Encrypt the downloaded media not encrypted yet (needs MEDIA_ENCRYPTION_KEY or _VAULT_KEY)
+  loadgen [--chats N] [--messages N] [--days N] [--seed N]     Write synthetic messages into the store and time queries
+  export-postgres [--output FILE]   Export the message store as a verified psql load script
+  anonymize --output FILE [--salt S] [--drop-content]   Write a copy of the store with phone numbers and names pseudonymized
+  archive export --output FILE      Write the whole store to a compressed protobuf archive
+  archive import --input FILE       Load an archive into the store, keeping rows already present
+  store inspect [--top N]           Show schema, rows per table, the largest chats and media on disk
+  store doctor [--repair]           Check the store for inconsistencies and optionally fix them
+  fakeserver [--chats N] [--messages N] [--seed N] [--qr] [--incoming-every DUR]   Serve the API on a fake account with seeded data
+  self-update [--check] [--force]   Replace this binary with the latest GitHub release
   version                           Print CLI version information
 
 Global Options:
-  --store DIR    Storage directory (default: ./store)
+  --store DIR                    Storage directory (default: ./store)
+  --credential-backend BACKEND   Where device keys are kept: file (default) or keychain
+                                 (macOS Keychain / Windows Credential Manager); env CREDENTIAL_BACKEND
+  --systemd                      Report readiness and watchdog pings to systemd (sync and serve)
+  --history-sync MODE            History requested when pairing: recent (default, fast) or full
+                                 (complete archive); env HISTORY_SYNC_MODE
+  --history-sync-days N          Limit the history requested when pairing to the last N days; env HISTORY_SYNC_DAYS
+  --send-delivery-receipts       Show senders their messages as delivered while syncing;
+                                 env SEND_DELIVERY_RECEIPTS
+  --send-read-receipts           Mark incoming messages as read while syncing; env SEND_READ_RECEIPTS
+  --hash-chain                   Chain stored messages into a per-chat hash chain for tamper evidence; env HASH_CHAIN
+  --reconcile-chats              On connect, add the chats and contacts the phone knows of to the store,
+                                 even without new messages; env RECONCILE_CHATS
+  --log-level LEVEL              WhatsApp client log level on stderr: debug, info, warn or error (default);
+                                 env LOG_LEVEL, LOG_LEVEL_WHATSAPP and LOG_LEVEL_STORE override per subsystem
+  --log-format FORMAT            Log format: text (default) or json; env LOG_FORMAT
+  --replica                      Serve a copy of the store read-only without connecting to WhatsApp:
+                                 read commands and the API's read endpoints only; env REPLICA
 
 Examples:
   whatsapp-cli auth
@@ -56,6 +117,17 @@ func main() {
 
 	// Global flags
 	storeDir := flag.String("store", "./store", "storage directory")
+	credentialBackend := flag.String("credential-backend", os.Getenv("CREDENTIAL_BACKEND"), "where device keys are stored: file or keychain")
+	useSystemd := flag.Bool("systemd", false, "notify systemd of readiness and send watchdog pings")
+	historySync := flag.String("history-sync", os.Getenv("HISTORY_SYNC_MODE"), "history requested when pairing: recent or full")
+	historySyncDays := flag.Int("history-sync-days", envInt("HISTORY_SYNC_DAYS"), "limit the history requested when pairing to the last N days")
+	sendDeliveryReceipts := flag.Bool("send-delivery-receipts", envBool("SEND_DELIVERY_RECEIPTS", false), "show senders their messages as delivered while syncing")
+	sendReadReceipts := flag.Bool("send-read-receipts", envBool("SEND_READ_RECEIPTS", false), "mark incoming messages as read while syncing")
+	hashChain := flag.Bool("hash-chain", envBool("HASH_CHAIN", false), "chain stored messages into a per-chat hash chain")
+	reconcileChats := flag.Bool("reconcile-chats", envBool("RECONCILE_CHATS", false), "on connect, add the chats and contacts the phone knows of to the store")
+	logLevel := flag.String("log-level", os.Getenv("LOG_LEVEL"), "WhatsApp client log level: debug, info, warn or error")
+	logFormat := flag.String("log-format", os.Getenv("LOG_FORMAT"), "log output format: text or json")
+	replica := flag.Bool("replica", envBool("REPLICA", false), "serve a copy of the store read-only, without connecting to WhatsApp")
 	flag.Parse()
 
 	// Get command
@@ -72,25 +144,58 @@ func main() {
 	}
 
 	if command == "version" {
-		fmt.Printf(`{"success":true,"data":{"version":"%s"},"error":null}
-`, version)
+		fmt.Printf(`{"success":true,"data":{"version":"%s","commit":"%s"},"error":null}
+`, version, buildCommit())
 		return
 	}
 
-	// For serve, parse config and override store dir
-	if command == "serve" {
+	if command == "self-update" {
+		updateCmd := flag.NewFlagSet("self-update", flag.ExitOnError)
+		checkOnly := updateCmd.Bool("check", false, "only report whether an update is available")
+		force := updateCmd.Bool("force", false, "install the latest release even if it is not newer")
+		updateCmd.Parse(args[1:])
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		fmt.Println(selfUpdate(ctx, *checkOnly, *force))
+		return
+	}
+
+	// fakeserver runs the API on a fake WhatsApp account and a throwaway
+	// store, for developing clients without a real account
+	if command == "fakeserver" {
+		fakeCmd := flag.NewFlagSet("fakeserver", flag.ExitOnError)
+		chats := fakeCmd.Int("chats", 8, "number of seeded chats")
+		messages := fakeCmd.Int("messages", 200, "number of seeded messages")
+		seed := fakeCmd.Int64("seed", 1, "random seed (same seed yields the same data)")
+		qr := fakeCmd.Bool("qr", false, "start unpaired and walk through the QR flow")
+		pairAfter := fakeCmd.Duration("pair-after", 10*time.Second, "with --qr, how long until the QR code counts as scanned")
+		incomingEvery := fakeCmd.Duration("incoming-every", 0, "deliver a synthetic incoming message this often (0 = never)")
+		fakeCmd.Parse(args[1:])
+
+		if os.Getenv("API_KEY") == "" {
+			os.Setenv("API_KEY", "fake-api-key")
+			fmt.Fprintln(os.Stderr, "API_KEY not set — using \"fake-api-key\"")
+		}
 		cfg, err := api.ParseConfig()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: %v"}`+"\n", err)
 			os.Exit(1)
 		}
-		serveStoreDir, _ := filepath.Abs(cfg.StoreDir)
-		app, err := commands.NewApp(serveStoreDir, version)
+		fakeStoreDir, err := os.MkdirTemp("", "whatsapp-cli-fake-")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Failed to initialize: %v"}`+"\n", err)
+			os.Exit(1)
+		}
+		defer os.RemoveAll(fakeStoreDir)
+		opts := commands.FakeServerOptions{Chats: *chats, Messages: *messages, Seed: *seed, Paired: !*qr}
+		app, fake, err := commands.NewFakeApp(fakeStoreDir, version, opts)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Failed to initialize: %v"}`+"\n", err)
 			os.Exit(1)
 		}
 		defer app.Close()
+		app.SetBuildInfo(buildCommit())
 
 		ctx, cancel := context.WithCancel(context.Background())
 		sigChan := make(chan os.Signal, 1)
@@ -102,18 +207,295 @@ func main() {
 		defer cancel()
 
 		srv := api.NewServer(cfg, app)
-
-		// Handle authentication state
 		if app.IsAuthenticated() {
 			srv.SetAuthenticated(true)
-			fmt.Fprintln(os.Stderr, "Already authenticated")
 		} else {
-			fmt.Fprintln(os.Stderr, "Not authenticated — starting QR auth flow")
+			fmt.Fprintf(os.Stderr, "Fake account unpaired — the QR code counts as scanned after %s\n", *pairAfter)
 			srv.StartQRAuth(ctx, app)
+			time.AfterFunc(*pairAfter, fake.Pair)
 		}
-
-		// Start background sync (waits for authentication before syncing)
 		srv.StartBackgroundSync(ctx)
+		if *incomingEvery > 0 {
+			go commands.RunFakeIncoming(ctx, fake, opts, *incomingEvery)
+		}
+
+		fmt.Fprintf(os.Stderr, "Starting fake API server on port %d (%d chats, %d messages, seed %d)\n", cfg.Port, *chats, *messages, *seed)
+		if err := srv.Start(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Server error: %v"}`+"\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// session moves the linked device to another host. It replaces or copies
+	// whatsapp.db, so it runs without an App holding it open, and locks the
+	// store so sync or serve cannot be running on it
+	if command == "session" {
+		sessionCmd := flag.NewFlagSet("session", flag.ExitOnError)
+		outputPath := sessionCmd.String("output", "", "file to write the encrypted session to (export)")
+		inputPath := sessionCmd.String("input", "", "encrypted session to install (import)")
+		force := sessionCmd.Bool("force", false, "replace a session already paired in the store (import)")
+		if len(args) > 2 {
+			sessionCmd.Parse(args[2:])
+		}
+
+		var key mediacrypt.KeyWrapper
+		if v := os.Getenv("SESSION_KEY"); v != "" {
+			k, err := mediacrypt.ParseLocalKey(v)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: invalid SESSION_KEY: %v"}`+"\n", err)
+				os.Exit(1)
+			}
+			key = k
+		}
+		absStoreDir, _ := filepath.Abs(*storeDir)
+		lock, err := storelock.Acquire(absStoreDir, command)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"%v"}`+"\n", err)
+			os.Exit(1)
+		}
+		defer lock.Release()
+
+		var result string
+		switch subcommand {
+		case "export":
+			result = commands.ExportSession(context.Background(), absStoreDir, *credentialBackend, *outputPath, key)
+		case "import":
+			result = commands.ImportSession(context.Background(), absStoreDir, *inputPath, key, *force)
+		default:
+			fmt.Fprintf(os.Stderr, "{\"success\":false,\"data\":null,\"error\":\"Unknown session subcommand: %s\"}\n", subcommand)
+			os.Exit(1)
+		}
+		fmt.Println(result)
+		return
+	}
+
+	// For serve, parse config and override store dir
+	if command == "serve" {
+		cfg, err := api.ParseConfig()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: %v"}`+"\n", err)
+			os.Exit(1)
+		}
+		if err := logging.Configure(logging.Config{
+			Level:  cfg.LogLevel,
+			Levels: logLevels(cfg.LogLevelWhatsApp, cfg.LogLevelStore),
+			Format: cfg.LogFormat,
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: %v"}`+"\n", err)
+			os.Exit(1)
+		}
+		if err := client.ConfigureHistorySync(cfg.HistorySyncMode, cfg.HistorySyncDays); err != nil {
+			fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: %v"}`+"\n", err)
+			os.Exit(1)
+		}
+		cfg.Replica = cfg.Replica || *replica
+		serveStoreDir, _ := filepath.Abs(cfg.StoreDir)
+		var app *commands.App
+		if cfg.Replica {
+			// Replicas don't connect, so any number may share a copy
+			app, err = commands.NewReplicaApp(serveStoreDir, version)
+		} else {
+			lock, lockErr := storelock.Acquire(serveStoreDir, command)
+			if lockErr != nil {
+				fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"%v"}`+"\n", lockErr)
+				os.Exit(1)
+			}
+			defer lock.Release()
+			app, err = commands.NewApp(serveStoreDir, version, cfg.CredentialBackend)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Failed to initialize: %v"}`+"\n", err)
+			os.Exit(1)
+		}
+		defer app.Close()
+		app.SetBuildInfo(buildCommit())
+		app.SetUpdateCheck(cfg.UpdateCheck)
+		app.SetSpamPolicy(commands.SpamPolicy{QuarantineThreshold: cfg.SpamThreshold})
+		app.SetWebhookSecret(cfg.WebhookSecret)
+		app.SetMessageWebhook(cfg.WebhookURL)
+		if cfg.PublicURL != "" {
+			app.SetMediaURLs(mediaurl.New(cfg.APIKey, cfg.PublicURL, cfg.MediaURLTTL))
+		}
+		setMediaEncryption(app)
+		setMediaScanner(app)
+		app.SetMediaTypePolicy(commands.MediaTypePolicy{
+			Blocked:        cfg.MediaBlockedTypes,
+			RejectMismatch: cfg.MediaRejectMismatch,
+		})
+		app.SetFFmpeg(cfg.FFmpegPath)
+		if err := app.SetGreeter(commands.GreeterConfig{
+			Template:   cfg.GreetingMessage,
+			WebhookURL: cfg.GreetingWebhookURL,
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: %v"}`+"\n", err)
+			os.Exit(1)
+		}
+		app.SetBot(commands.BotConfig{
+			Prefix:       cfg.BotPrefix,
+			AllowedChats: cfg.BotAllowedChats,
+			Webhooks:     cfg.BotCommands,
+		})
+		app.SetChatOps(cfg.ChatOpsAdmins)
+		phoneFilter := api.NewPhoneFilter(cfg.PhoneWhitelist, cfg.PhoneBlacklist)
+		phoneFilter.SetLIDResolver(app.ResolvePhoneJID)
+		app.SetChatFilter(phoneFilter.IsAllowed)
+		var mqttCommands *mqtt.Client
+		if cfg.EventBus != "" {
+			bus, err := eventbus.New(eventbus.Config{
+				Broker:      cfg.EventBus,
+				URL:         cfg.EventBusURL,
+				Format:      cfg.EventBusFormat,
+				TopicPrefix: cfg.EventBusTopicPrefix,
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: %v"}`+"\n", err)
+				os.Exit(1)
+			}
+			app.SetEventBus(bus)
+			if cfg.EventBusCommandTopic != "" {
+				mqttCommands = bus.Publisher().(*eventbus.MQTT).Client()
+			}
+		}
+		if cfg.RedisURL != "" {
+			notifier, err := eventbus.NewRedisNotifier(cfg.RedisURL, cfg.RedisChannel, cfg.RedisChannelPerChat)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: %v"}`+"\n", err)
+				os.Exit(1)
+			}
+			app.SetNotifier(notifier)
+		}
+		if cfg.EmbeddingsURL != "" {
+			embedder, err := embeddings.New(cfg.EmbeddingsURL, cfg.EmbeddingsModel, cfg.EmbeddingsAPIKey)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: %v"}`+"\n", err)
+				os.Exit(1)
+			}
+			app.SetEmbedder(embedder)
+		}
+		if err := app.SetDigests(commands.DigestConfig{
+			Chats:      cfg.DigestChats,
+			Periods:    cfg.DigestPeriods,
+			At:         cfg.DigestTime,
+			Timezone:   cfg.DigestTimezone,
+			WebhookURL: cfg.DigestWebhookURL,
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: %v"}`+"\n", err)
+			os.Exit(1)
+		}
+		if err := app.SetFollowups(commands.FollowupConfig{
+			Chats:      cfg.FollowupChats,
+			After:      cfg.FollowupAfter,
+			WebhookURL: cfg.FollowupWebhookURL,
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: %v"}`+"\n", err)
+			os.Exit(1)
+		}
+		if err := app.SetRecipes(cfg.RecipesFile); err != nil {
+			fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: %v"}`+"\n", err)
+			os.Exit(1)
+		}
+		if err := app.SetHomeAssistant(commands.HomeAssistantConfig{
+			MQTTURL:         cfg.HomeAssistantMQTTURL,
+			DiscoveryPrefix: cfg.HomeAssistantDiscoveryPrefix,
+			BaseTopic:       cfg.HomeAssistantTopic,
+			Chats:           cfg.HomeAssistantChats,
+			Allow:           phoneFilter.IsAllowed,
+			Version:         version,
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: %v"}`+"\n", err)
+			os.Exit(1)
+		}
+		if err := app.SetFeeds(commands.FeedConfig{
+			Feeds:    cfg.Feeds,
+			Interval: cfg.FeedInterval,
+			Images:   cfg.FeedImages,
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: %v"}`+"\n", err)
+			os.Exit(1)
+		}
+		if err := app.SetCanary(commands.CanaryConfig{
+			Interval: cfg.CanaryInterval,
+			Timeout:  cfg.CanaryTimeout,
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: %v"}`+"\n", err)
+			os.Exit(1)
+		}
+		app.SetOutboxMaxAge(cfg.OutboxMaxAge)
+		app.SetOutboundAllowedNetworks(cfg.OutboundAllowedNetworks)
+		app.SetReceiptPolicy(commands.ReceiptPolicy{
+			Delivery: cfg.SendDeliveryReceipts,
+			Read:     cfg.SendReadReceipts,
+		})
+		app.SetSimulateTyping(cfg.SimulateTyping)
+		app.SetHashChain(cfg.HashChain)
+		app.SetChainSigningKey(cfg.HashChainSigningKey)
+		app.SetReconcileChats(cfg.ReconcileChats)
+		if err := app.SetSendShaping(commands.SendShaping{
+			MinDelay:   cfg.SendDelayMin,
+			MaxDelay:   cfg.SendDelayMax,
+			PerMinute:  cfg.SendPerMinute,
+			QuietHours: cfg.QuietHours,
+			Timezone:   cfg.QuietHoursTimezone,
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: %v"}`+"\n", err)
+			os.Exit(1)
+		}
+		if err := app.SetSendDedup(cfg.SendDedupWindow, cfg.SendDedupMode); err != nil {
+			fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: %v"}`+"\n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sigChan
+			cancel()
+		}()
+		defer cancel()
+
+		srv := api.NewServer(cfg, app)
+		if err := srv.SetCloudTemplates(cfg.CloudAPITemplates); err != nil {
+			fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: %v"}`+"\n", err)
+			os.Exit(1)
+		}
+		if cfg.SentryDSN != "" {
+			reporter, err := sentry.New(cfg.SentryDSN, version, cfg.SentryEnvironment)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: %v"}`+"\n", err)
+				os.Exit(1)
+			}
+			srv.SetErrorReporter(reporter)
+			defer reporter.Flush(5 * time.Second)
+		}
+
+		if cfg.Replica {
+			fmt.Fprintln(os.Stderr, "Read-only replica — serving the store without connecting to WhatsApp")
+			if *useSystemd {
+				go systemd.Supervise(ctx, func() bool { return true }, func() bool { return true }, func(err error) {
+					fmt.Fprintf(os.Stderr, "⚠ systemd notify failed: %v\n", err)
+				})
+			}
+		} else {
+			// Handle authentication state
+			if app.IsAuthenticated() {
+				srv.SetAuthenticated(true)
+				fmt.Fprintln(os.Stderr, "Already authenticated")
+			} else {
+				fmt.Fprintln(os.Stderr, "Not authenticated — starting QR auth flow")
+				srv.StartQRAuth(ctx, app)
+			}
+
+			// Start background sync (waits for authentication before syncing)
+			srv.StartBackgroundSync(ctx)
+			if mqttCommands != nil {
+				go srv.RunMQTTCommands(ctx, mqttCommands, cfg.EventBusCommandTopic)
+			}
+			if *useSystemd {
+				go superviseSystemd(ctx, srv.Ready, app)
+			}
+		}
 
 		fmt.Fprintf(os.Stderr, "Starting API server on port %d\n", cfg.Port)
 		if err := srv.Start(ctx); err != nil {
@@ -123,21 +505,65 @@ func main() {
 		return
 	}
 
+	if *logLevel == "" {
+		*logLevel = logging.DefaultConfig.Level
+	}
+	if err := logging.Configure(logging.Config{
+		Level:  *logLevel,
+		Levels: logLevels(os.Getenv("LOG_LEVEL_WHATSAPP"), os.Getenv("LOG_LEVEL_STORE")),
+		Format: *logFormat,
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"%v"}
+`, err)
+		os.Exit(1)
+	}
+
+	if err := client.ConfigureHistorySync(*historySync, *historySyncDays); err != nil {
+		fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"%v"}
+`, err)
+		os.Exit(1)
+	}
+
 	// Create app
 	absStoreDir, _ := filepath.Abs(*storeDir)
-	app, err := commands.NewApp(absStoreDir, version)
+	var app *commands.App
+	var err error
+	if *replica {
+		app, err = commands.NewReplicaApp(absStoreDir, version)
+	} else {
+		if exclusiveCommands[command] {
+			lock, lockErr := storelock.Acquire(absStoreDir, command)
+			if lockErr != nil {
+				fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"%v"}
+`, lockErr)
+				os.Exit(1)
+			}
+			defer lock.Release()
+		}
+		app, err = commands.NewApp(absStoreDir, version, *credentialBackend)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Failed to initialize: %v"}
 `, err)
 		os.Exit(1)
 	}
 	defer app.Close()
+	app.SetReceiptPolicy(commands.ReceiptPolicy{
+		Delivery: *sendDeliveryReceipts,
+		Read:     *sendReadReceipts,
+	})
+	app.SetHashChain(*hashChain)
+	app.SetReconcileChats(*reconcileChats)
+	setMediaEncryption(app)
+	setMediaScanner(app)
+	setOutboundAllowedNetworks(app)
 
 	// Use different timeout for sync command
 	var ctx context.Context
 	var cancel context.CancelFunc
-	if command == "sync" {
-		// For sync, use signal-based cancellation
+	if command == "sync" || command == "archive" {
+		// For sync, and archives that can take longer than any timeout, use
+		// signal-based cancellation
 		ctx, cancel = context.WithCancel(context.Background())
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -157,6 +583,9 @@ func main() {
 		result = app.Auth(ctx)
 
 	case "sync":
+		if *useSystemd {
+			go superviseSystemd(ctx, func() bool { return app.IsAuthenticated() && app.SyncAlive() }, app)
+		}
 		result = app.Sync(ctx, nil)
 
 	case "messages":
@@ -165,20 +594,30 @@ func main() {
 		query := messagesCmd.String("query", "", "search query")
 		limit := messagesCmd.Int("limit", 20, "limit")
 		page := messagesCmd.Int("page", 0, "page")
+		lang := messagesCmd.String("lang", "", "language code (e.g. de)")
+		tag := messagesCmd.String("tag", "", "only messages with this tag")
 		// Parse from args[2:] to skip subcommand ("list"/"search") —
 		// Go's flag parser stops at the first non-flag argument.
 		if len(args) > 2 {
 			messagesCmd.Parse(args[2:])
 		}
 
+		var langPtr *string
+		if *lang != "" {
+			langPtr = lang
+		}
+		var tagPtr *string
+		if *tag != "" {
+			tagPtr = tag
+		}
 		if subcommand == "search" || *query != "" {
-			result = app.ListMessages(nil, query, *limit, *page, nil, nil, nil)
+			result = app.ListMessages(nil, query, *limit, *page, nil, nil, nil, langPtr, tagPtr)
 		} else {
 			var chatPtr *string
 			if *chatJID != "" {
 				chatPtr = chatJID
 			}
-			result = app.ListMessages(chatPtr, nil, *limit, *page, nil, nil, nil)
+			result = app.ListMessages(chatPtr, nil, *limit, *page, nil, nil, nil, langPtr, tagPtr)
 		}
 
 	case "contacts":
@@ -201,12 +640,27 @@ func main() {
 		query := chatsCmd.String("query", "", "search query")
 		limit := chatsCmd.Int("limit", 20, "limit")
 		page := chatsCmd.Int("page", 0, "page")
-		// Parse from args[2:] to skip subcommand ("list") —
+		from := chatsCmd.String("from", "", "old chat JID or phone number (merge)")
+		into := chatsCmd.String("into", "", "new chat JID or phone number (merge)")
+		// Parse from args[2:] to skip subcommand ("list"/"merge"/"reconcile") —
 		// Go's flag parser stops at the first non-flag argument.
 		if len(args) > 2 {
 			chatsCmd.Parse(args[2:])
 		}
 
+		if subcommand == "merge" {
+			if *from == "" || *into == "" {
+				fmt.Fprintln(os.Stderr, `{"success":false,"data":null,"error":"--from and --into required"}`)
+				os.Exit(1)
+			}
+			result = app.MergeChats(ctx, *from, *into)
+			break
+		}
+		if subcommand == "reconcile" {
+			result = app.ReconcileChats(ctx)
+			break
+		}
+
 		var queryPtr *string
 		if *query != "" {
 			queryPtr = query
@@ -217,15 +671,76 @@ func main() {
 		sendCmd := flag.NewFlagSet("send", flag.ExitOnError)
 		to := sendCmd.String("to", "", "recipient")
 		message := sendCmd.String("message", "", "message text")
+		replyTo := sendCmd.String("reply-to", "", "ID of a message in the chat to quote")
+		mention := sendCmd.String("mention", "", "comma-separated phone numbers or JIDs of group members to mention")
+		simulateTyping := sendCmd.Bool("simulate-typing", false, "show the typing indicator for a time proportional to the message length before sending")
 		sendCmd.Parse(args[1:])
 
 		if *to == "" || *message == "" {
 			fmt.Fprintln(os.Stderr, `{"success":false,"data":null,"error":"--to and --message required"}`)
 			os.Exit(1)
 		}
-		result = app.SendMessage(ctx, *to, *message)
+		var typing *bool
+		sendCmd.Visit(func(f *flag.Flag) {
+			if f.Name == "simulate-typing" {
+				typing = simulateTyping
+			}
+		})
+		var mentions []string
+		for _, m := range strings.Split(*mention, ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				mentions = append(mentions, m)
+			}
+		}
+		result = app.SendMessage(ctx, *to, *message, client.SendOptions{
+			QuotedMessageID: *replyTo,
+			Mentions:        mentions,
+			SimulateTyping:  typing,
+		})
+
+	case "polls":
+		pollsCmd := flag.NewFlagSet("polls", flag.ExitOnError)
+		to := pollsCmd.String("to", "", "recipient (send)")
+		question := pollsCmd.String("question", "", "poll question (send)")
+		var options []string
+		pollsCmd.Func("option", "an option to vote for; repeat for each (send)", func(o string) error {
+			options = append(options, o)
+			return nil
+		})
+		selectable := pollsCmd.Int("selectable", 0, "how many options a voter may pick, 0 for any number (send)")
+		messageID := pollsCmd.String("message-id", "", "ID of the poll's message (results)")
+		chatJID := pollsCmd.String("chat", "", "chat JID (results, optional)")
+		if len(args) > 2 {
+			pollsCmd.Parse(args[2:])
+		}
+
+		switch subcommand {
+		case "send":
+			if *to == "" || *question == "" || len(options) == 0 {
+				fmt.Fprintln(os.Stderr, `{"success":false,"data":null,"error":"--to, --question and --option required"}`)
+				os.Exit(1)
+			}
+			result = app.SendPoll(ctx, *to, *question, options, *selectable)
+		case "results":
+			if *messageID == "" {
+				fmt.Fprintln(os.Stderr, `{"success":false,"data":null,"error":"--message-id required"}`)
+				os.Exit(1)
+			}
+			var chatPtr *string
+			if *chatJID != "" {
+				chatPtr = chatJID
+			}
+			result = app.PollResults(*messageID, chatPtr)
+		default:
+			fmt.Fprintf(os.Stderr, "{\"success\":false,\"data\":null,\"error\":\"Unknown polls subcommand: %s\"}\n", subcommand)
+			os.Exit(1)
+		}
 
 	case "media":
+		if subcommand == "encrypt" {
+			result = app.EncryptMedia(ctx)
+			break
+		}
 		if subcommand != "download" {
 			fmt.Fprintf(os.Stderr, "{\"success\":false,\"data\":null,\"error\":\"Unknown media subcommand: %s\"}\n", subcommand)
 			os.Exit(1)
@@ -246,6 +761,73 @@ func main() {
 		}
 		result = app.DownloadMedia(ctx, *messageID, chatPtr, *outputPath)
 
+	case "loadgen":
+		loadCmd := flag.NewFlagSet("loadgen", flag.ExitOnError)
+		chats := loadCmd.Int("chats", 50, "number of synthetic chats")
+		messages := loadCmd.Int("messages", 100000, "number of synthetic messages")
+		days := loadCmd.Int("days", 30, "spread message timestamps over the N days before 2025-01-01")
+		seed := loadCmd.Int64("seed", 1, "random seed (same seed yields the same data)")
+		loadCmd.Parse(args[1:])
+
+		result = app.LoadGen(commands.LoadGenOptions{
+			Chats:    *chats,
+			Messages: *messages,
+			Days:     *days,
+			Seed:     *seed,
+		})
+
+	case "export-postgres":
+		exportCmd := flag.NewFlagSet("export-postgres", flag.ExitOnError)
+		outputPath := exportCmd.String("output", "messages.pg.sql", "file to write the load script to")
+		exportCmd.Parse(args[1:])
+
+		result = app.ExportPostgres(ctx, *outputPath)
+
+	case "anonymize":
+		anonCmd := flag.NewFlagSet("anonymize", flag.ExitOnError)
+		outputPath := anonCmd.String("output", "", "file to write the anonymized database to")
+		salt := anonCmd.String("salt", "", "secret for stable pseudonyms across exports (random if empty)")
+		dropContent := anonCmd.Bool("drop-content", false, "also replace message text with placeholders")
+		anonCmd.Parse(args[1:])
+
+		result = app.Anonymize(ctx, *outputPath, *salt, *dropContent)
+
+	case "archive":
+		archiveCmd := flag.NewFlagSet("archive", flag.ExitOnError)
+		outputPath := archiveCmd.String("output", "", "file to write the archive to (export)")
+		inputPath := archiveCmd.String("input", "", "archive to load into the store (import)")
+		if len(args) > 2 {
+			archiveCmd.Parse(args[2:])
+		}
+
+		switch subcommand {
+		case "export":
+			result = app.ExportArchive(ctx, *outputPath)
+		case "import":
+			result = app.ImportArchive(ctx, *inputPath)
+		default:
+			fmt.Fprintf(os.Stderr, "{\"success\":false,\"data\":null,\"error\":\"Unknown archive subcommand: %s\"}\n", subcommand)
+			os.Exit(1)
+		}
+
+	case "store":
+		storeCmd := flag.NewFlagSet("store", flag.ExitOnError)
+		top := storeCmd.Int("top", commands.DefaultInspectTop, "number of largest chats to list (inspect)")
+		repair := storeCmd.Bool("repair", false, "fix the issues found (doctor)")
+		if len(args) > 2 {
+			storeCmd.Parse(args[2:])
+		}
+
+		switch subcommand {
+		case "inspect":
+			result = app.InspectStore(*top)
+		case "doctor":
+			result = app.StoreDoctor(*repair)
+		default:
+			fmt.Fprintf(os.Stderr, "{\"success\":false,\"data\":null,\"error\":\"Unknown store subcommand: %s\"}\n", subcommand)
+			os.Exit(1)
+		}
+
 	default:
 		fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Unknown command: %s"}
 `, command)
@@ -254,3 +836,133 @@ func main() {
 
 	fmt.Println(result)
 }
+
+// setMediaEncryption enables media encryption as configured by the
+// MEDIA_ENCRYPTION_* variables, exiting if they are invalid.
+func setMediaEncryption(app *commands.App) {
+	keys, err := mediacrypt.FromEnv(os.Getenv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: %v"}`+"\n", err)
+		os.Exit(1)
+	}
+	app.SetMediaEncryption(keys)
+}
+
+// setMediaScanner enables malware scanning of downloaded media as configured
+// by the MEDIA_SCAN_* variables, exiting if they are invalid.
+func setMediaScanner(app *commands.App) {
+	scanner, err := avscan.FromEnv(os.Getenv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: %v"}`+"\n", err)
+		os.Exit(1)
+	}
+	app.SetMediaScanner(scanner)
+}
+
+// setOutboundAllowedNetworks applies OUTBOUND_ALLOWED_NETWORKS to the CLI,
+// as ParseConfig does for serve.
+func setOutboundAllowedNetworks(app *commands.App) {
+	nets, err := netguard.ParseNetworks(os.Getenv("OUTBOUND_ALLOWED_NETWORKS"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, `{"success":false,"data":null,"error":"Config error: invalid OUTBOUND_ALLOWED_NETWORKS value: %v"}`+"\n", err)
+		os.Exit(1)
+	}
+	app.SetOutboundAllowedNetworks(nets)
+}
+
+// envInt reads an integer environment variable, treating unset or invalid
+// values as 0.
+func envInt(key string) int {
+	n, _ := strconv.Atoi(os.Getenv(key))
+	return n
+}
+
+// envBool reads a boolean environment variable, returning def when it is
+// unset or invalid.
+func envBool(key string, def bool) bool {
+	if b, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return b
+	}
+	return def
+}
+
+// logLevels builds the per-subsystem level overrides, leaving out the
+// unset ones.
+func logLevels(whatsapp, store string) map[string]string {
+	levels := map[string]string{}
+	if whatsapp != "" {
+		levels[logging.SubsystemWhatsApp] = whatsapp
+	}
+	if store != "" {
+		levels[logging.SubsystemStore] = store
+	}
+	return levels
+}
+
+// superviseSystemd reports readiness once ready returns true, then keeps the
+// watchdog fed while the sync loop stays connected. serve is ready in the
+// states /readyz accepts; sync once authenticated and connected.
+func superviseSystemd(ctx context.Context, ready func() bool, app *commands.App) {
+	systemd.Supervise(ctx, ready, app.SyncAlive, func(err error) {
+		fmt.Fprintf(os.Stderr, "⚠ systemd notify failed: %v\n", err)
+	})
+}
+
+// buildCommit returns the commit set at link time, falling back to the VCS
+// revision Go stamps into binaries built from a checkout.
+func buildCommit() string {
+	if commit != "" {
+		return commit
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				return s.Value
+			}
+		}
+	}
+	return "unknown"
+}
+
+// selfUpdate checks GitHub for a newer release and, unless checkOnly is set,
+// replaces the running executable with it after verifying its signed checksum.
+func selfUpdate(ctx context.Context, checkOnly, force bool) string {
+	updater := selfupdate.New()
+	key, err := selfupdate.ParsePublicKey(releaseKey)
+	if err != nil {
+		return output.Error(err)
+	}
+	updater.PublicKey = key
+	status, rel, err := updater.Check(ctx, version)
+	if err != nil {
+		return output.Error(err)
+	}
+	if checkOnly || (!status.UpdateAvailable && !force) {
+		return output.Success(map[string]interface{}{
+			"current":          status.Current,
+			"latest":           status.Latest,
+			"update_available": status.UpdateAvailable,
+			"updated":          false,
+		})
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return output.Error(err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return output.Error(err)
+	}
+	fmt.Fprintf(os.Stderr, "Updating %s from %s to %s...\n", exe, status.Current, status.Latest)
+	if err := updater.Apply(ctx, rel, exe); err != nil {
+		return output.Error(err)
+	}
+	return output.Success(map[string]interface{}{
+		"current":          status.Latest,
+		"previous":         status.Current,
+		"latest":           status.Latest,
+		"update_available": false,
+		"updated":          true,
+		"path":             exe,
+	})
+}
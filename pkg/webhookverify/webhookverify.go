@@ -0,0 +1,212 @@
+// Package webhookverify signs and verifies the webhooks whatsapp-cli sends
+// (received messages, first-contact greetings, digests and bot commands)
+// when WEBHOOK_SECRET is set.
+//
+// Every signed request carries three headers:
+//
+//	X-Webhook-Id:        a delivery ID, the same for every retry of a delivery
+//	X-Webhook-Timestamp: the Unix time in seconds the request was signed at
+//	X-Webhook-Signature: v1=<hex HMAC-SHA256 of "<id>.<timestamp>.<body>">
+//
+// A receiver should recompute the signature over the raw body, reject
+// timestamps outside a few minutes of its clock, and reject delivery IDs it
+// has already accepted. Verifier does all three:
+//
+//	v := webhookverify.NewVerifier([]byte(os.Getenv("WEBHOOK_SECRET")))
+//	http.Handle("/hook", v.Middleware(handler))
+package webhookverify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Header names of signed webhook requests.
+const (
+	IDHeader        = "X-Webhook-Id"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+// DefaultTolerance is how far a request's timestamp may be from the
+// receiver's clock.
+const DefaultTolerance = 5 * time.Minute
+
+// MaxBodyBytes caps the body VerifyRequest reads.
+const MaxBodyBytes = 1 << 20
+
+// signaturePrefix versions the signing scheme.
+const signaturePrefix = "v1="
+
+// Verification errors. Verify wraps them, so test with errors.Is.
+var (
+	ErrMissingHeaders   = errors.New("webhook signature headers missing")
+	ErrInvalidTimestamp = errors.New("webhook timestamp invalid")
+	ErrExpired          = errors.New("webhook timestamp outside tolerance")
+	ErrInvalidSignature = errors.New("webhook signature mismatch")
+	ErrReplayed         = errors.New("webhook delivery already received")
+)
+
+// Sign returns the X-Webhook-Signature value for a delivery.
+func Sign(secret []byte, id string, ts time.Time, body []byte) string {
+	return signaturePrefix + hex.EncodeToString(mac(secret, id, ts.Unix(), body))
+}
+
+// SignRequest sets the signature headers of req, whose body is body.
+func SignRequest(req *http.Request, secret []byte, id string, ts time.Time, body []byte) {
+	req.Header.Set(IDHeader, id)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(ts.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(secret, id, ts, body))
+}
+
+// DeliveryID derives a delivery ID from a payload, so resending the same
+// payload keeps its ID. Payloads that include their event time are unique.
+func DeliveryID(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:16])
+}
+
+func mac(secret []byte, id string, unix int64, body []byte) []byte {
+	m := hmac.New(sha256.New, secret)
+	fmt.Fprintf(m, "%s.%d.", id, unix)
+	m.Write(body)
+	return m.Sum(nil)
+}
+
+// Verifier checks signed webhook requests. It remembers the delivery IDs it
+// accepted for as long as their timestamps are within Tolerance, so a
+// captured request cannot be replayed. A Verifier is safe for concurrent use;
+// use one per endpoint and keep it for the life of the process.
+type Verifier struct {
+	// Secrets are the accepted signing secrets; list the old and the new
+	// one while rotating WEBHOOK_SECRET.
+	Secrets [][]byte
+	// Tolerance defaults to DefaultTolerance.
+	Tolerance time.Duration
+	// Now defaults to time.Now.
+	Now func() time.Time
+
+	mu   sync.Mutex
+	seen map[string]time.Time // delivery ID -> when it can be forgotten
+}
+
+// NewVerifier returns a Verifier accepting requests signed with any of
+// secrets.
+func NewVerifier(secrets ...[]byte) *Verifier {
+	return &Verifier{Secrets: secrets}
+}
+
+// Verify checks the signature headers against body, which must be the raw
+// request body, and records the delivery ID.
+func (v *Verifier) Verify(header http.Header, body []byte) error {
+	id := header.Get(IDHeader)
+	tsHeader := header.Get(TimestampHeader)
+	sigHeader := header.Get(SignatureHeader)
+	if id == "" || tsHeader == "" || sigHeader == "" {
+		return ErrMissingHeaders
+	}
+	unix, err := strconv.ParseInt(tsHeader, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidTimestamp, tsHeader)
+	}
+
+	tolerance := v.Tolerance
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	now := time.Now()
+	if v.Now != nil {
+		now = v.Now()
+	}
+	ts := time.Unix(unix, 0)
+	if ts.Before(now.Add(-tolerance)) || ts.After(now.Add(tolerance)) {
+		return fmt.Errorf("%w: signed at %s", ErrExpired, ts.UTC().Format(time.RFC3339))
+	}
+
+	if !v.validSignature(sigHeader, id, unix, body) {
+		return ErrInvalidSignature
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for seenID, until := range v.seen {
+		if now.After(until) {
+			delete(v.seen, seenID)
+		}
+	}
+	if _, ok := v.seen[id]; ok {
+		return fmt.Errorf("%w: %s", ErrReplayed, id)
+	}
+	if v.seen == nil {
+		v.seen = make(map[string]time.Time)
+	}
+	// A request is accepted until tolerance after its timestamp, so its ID
+	// must be remembered that long
+	v.seen[id] = ts.Add(tolerance)
+	return nil
+}
+
+// validSignature compares every v1 signature in the header, which may list
+// several separated by commas, with the expected one for every secret.
+func (v *Verifier) validSignature(header, id string, unix int64, body []byte) bool {
+	for _, sig := range strings.Split(header, ",") {
+		sig = strings.TrimSpace(sig)
+		if !strings.HasPrefix(sig, signaturePrefix) {
+			continue
+		}
+		got, err := hex.DecodeString(strings.TrimPrefix(sig, signaturePrefix))
+		if err != nil {
+			continue
+		}
+		for _, secret := range v.Secrets {
+			if hmac.Equal(got, mac(secret, id, unix, body)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// VerifyRequest reads and verifies the body of r, leaving a copy in r.Body
+// for the handler, and returns the body.
+func (v *Verifier) VerifyRequest(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodyBytes+1))
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > MaxBodyBytes {
+		return nil, fmt.Errorf("webhook body exceeds %d bytes", MaxBodyBytes)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err := v.Verify(r.Header, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// Middleware passes verified requests to next and answers others with 401,
+// or 409 for replays, which tells the sender not to retry.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := v.VerifyRequest(r); err != nil {
+			status := http.StatusUnauthorized
+			if errors.Is(err, ErrReplayed) {
+				status = http.StatusConflict
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
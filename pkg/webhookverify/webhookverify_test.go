@@ -0,0 +1,134 @@
+package webhookverify
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var secret = []byte("0123456789abcdef")
+
+func signedRequest(t *testing.T, key []byte, id string, ts time.Time, body string) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/hook", bytes.NewReader([]byte(body)))
+	SignRequest(req, key, id, ts, []byte(body))
+	return req
+}
+
+func TestSignKnownValue(t *testing.T) {
+	// Computed independently with:
+	// printf 'd1.1700000000.{}' | openssl dgst -sha256 -hmac 0123456789abcdef
+	sig := Sign(secret, "d1", time.Unix(1700000000, 0), []byte("{}"))
+	assert.Equal(t, "v1=43fb973a34cf7141e8af55893d63760efaadd2f330eab457e3cc02508ce07d78", sig)
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	v := NewVerifier(secret)
+	v.Now = func() time.Time { return now }
+
+	req := signedRequest(t, secret, "d1", now.Add(-time.Minute), `{"event":"digest"}`)
+	body, err := v.VerifyRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, `{"event":"digest"}`, string(body))
+	rest, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, body, rest, "body not restored for the handler")
+
+	// The same delivery again is a replay
+	_, err = v.VerifyRequest(signedRequest(t, secret, "d1", now.Add(-time.Minute), `{"event":"digest"}`))
+	assert.ErrorIs(t, err, ErrReplayed)
+
+	tampered := signedRequest(t, secret, "d2", now, `{"event":"digest"}`)
+	tampered.Body = io.NopCloser(bytes.NewReader([]byte(`{"event":"other"}`)))
+	_, err = v.VerifyRequest(tampered)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = v.VerifyRequest(signedRequest(t, []byte("another-secret-value"), "d3", now, `{}`))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = v.VerifyRequest(signedRequest(t, secret, "d4", now.Add(-10*time.Minute), `{}`))
+	assert.ErrorIs(t, err, ErrExpired)
+	_, err = v.VerifyRequest(signedRequest(t, secret, "d5", now.Add(10*time.Minute), `{}`))
+	assert.ErrorIs(t, err, ErrExpired)
+
+	unsigned := httptest.NewRequest(http.MethodPost, "/hook", bytes.NewReader([]byte(`{}`)))
+	_, err = v.VerifyRequest(unsigned)
+	assert.ErrorIs(t, err, ErrMissingHeaders)
+
+	badTS := signedRequest(t, secret, "d6", now, `{}`)
+	badTS.Header.Set(TimestampHeader, "yesterday")
+	_, err = v.VerifyRequest(badTS)
+	assert.ErrorIs(t, err, ErrInvalidTimestamp)
+
+	// Changing the ID invalidates the signature, so IDs cannot be swapped
+	// to get around replay detection
+	swapped := signedRequest(t, secret, "d1", now, `{}`)
+	swapped.Header.Set(IDHeader, "d7")
+	_, err = v.VerifyRequest(swapped)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestVerifyForgetsExpiredDeliveries(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	v := NewVerifier(secret)
+	v.Tolerance = time.Minute
+	v.Now = func() time.Time { return now }
+
+	require.NoError(t, v.Verify(signedRequest(t, secret, "d1", now, `{}`).Header, []byte(`{}`)))
+	assert.Len(t, v.seen, 1)
+
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, v.Verify(signedRequest(t, secret, "d2", now, `{}`).Header, []byte(`{}`)))
+	assert.Len(t, v.seen, 1, "expired delivery ID kept")
+}
+
+func TestVerifySecretRotation(t *testing.T) {
+	oldSecret, newSecret := []byte("old-secret-value-1"), []byte("new-secret-value-2")
+	v := NewVerifier(newSecret, oldSecret)
+	now := time.Now()
+	assert.NoError(t, v.Verify(signedRequest(t, oldSecret, "d1", now, `{}`).Header, []byte(`{}`)))
+	assert.NoError(t, v.Verify(signedRequest(t, newSecret, "d2", now, `{}`).Header, []byte(`{}`)))
+
+	// A header may carry several signatures
+	h := http.Header{}
+	h.Set(IDHeader, "d3")
+	h.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	h.Set(SignatureHeader, "v1=00ff, "+Sign(oldSecret, "d3", now, []byte(`{}`)))
+	assert.NoError(t, v.Verify(h, []byte(`{}`)))
+}
+
+func TestMiddleware(t *testing.T) {
+	v := NewVerifier(secret)
+	var handled int
+	srv := httptest.NewServer(v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, `{"ok":true}`, string(body))
+		handled++
+	})))
+	defer srv.Close()
+
+	post := func(sign bool) int {
+		body := []byte(`{"ok":true}`)
+		req, err := http.NewRequest(http.MethodPost, srv.URL, bytes.NewReader(body))
+		require.NoError(t, err)
+		if sign {
+			SignRequest(req, secret, DeliveryID(body), time.Now(), body)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusOK, post(true))
+	assert.Equal(t, http.StatusConflict, post(true), "resent payload keeps its delivery ID")
+	assert.Equal(t, http.StatusUnauthorized, post(false))
+	assert.Equal(t, 1, handled)
+}